@@ -84,7 +84,14 @@ type Action struct {
 // are successfully executed, or none of them are. For that, it's fundamental
 // that all actions are really small and atomic.
 type Pipeline struct {
-	actions []*Action
+	actions  []*Action
+	recorder Recorder
+}
+
+// Recorder is notified about every action run by a pipeline, both in the
+// forward and in the backward phase.
+type Recorder interface {
+	RecordAction(name string, rollback bool, err error)
 }
 
 var (
@@ -112,6 +119,12 @@ func NewPipeline(actions ...*Action) *Pipeline {
 
 }
 
+// SetRecorder sets the recorder notified about the actions run by the
+// pipeline.
+func (p *Pipeline) SetRecorder(r Recorder) {
+	p.recorder = r
+}
+
 // Result returns the result of the last action.
 func (p *Pipeline) Result() Result {
 	action := p.actions[len(p.actions)-1]
@@ -154,6 +167,9 @@ func (p *Pipeline) Execute(params ...interface{}) error {
 			a.rMutex.Unlock()
 			fwCtx.Previous = r
 		}
+		if p.recorder != nil {
+			p.recorder.RecordAction(a.Name, false, err)
+		}
 		if err != nil {
 			log.Debugf("[pipeline] error running the Forward for the %s action - %s", a.Name, err)
 			if a.OnError != nil {
@@ -173,6 +189,9 @@ func (p *Pipeline) rollback(index int, params []interface{}) {
 		if p.actions[i].Backward != nil {
 			bwCtx.FWResult = p.actions[i].result
 			p.actions[i].Backward(bwCtx)
+			if p.recorder != nil {
+				p.recorder.RecordAction(p.actions[i].Name, true, nil)
+			}
 		}
 	}
 }
@@ -173,3 +173,30 @@ func (s *S) TestActionOnError(c *check.C) {
 	c.Assert(err, check.Equals, returnedErr)
 	c.Assert(called, check.Equals, true)
 }
+
+type recordedAction struct {
+	name     string
+	rollback bool
+	err      error
+}
+
+type fakeRecorder struct {
+	actions []recordedAction
+}
+
+func (r *fakeRecorder) RecordAction(name string, rollback bool, err error) {
+	r.actions = append(r.actions, recordedAction{name: name, rollback: rollback, err: err})
+}
+
+func (s *S) TestExecuteWithRecorder(c *check.C) {
+	recorder := fakeRecorder{}
+	pipeline := NewPipeline(&helloAction, &errorAction)
+	pipeline.SetRecorder(&recorder)
+	err := pipeline.Execute()
+	c.Assert(err, check.NotNil)
+	c.Assert(recorder.actions, check.DeepEquals, []recordedAction{
+		{name: "hello"},
+		{name: "error", err: err},
+		{name: "hello", rollback: true},
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/tsuru/app/alert"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// title: list team alert rules
+// path: /teams/{name}/alerts/rules
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func listAlertRules(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	teamName := r.URL.Query().Get(":name")
+	allowed := permission.Check(t, permission.PermTeamReadAlert, permission.Context(permission.CtxTeam, teamName))
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	rules, err := alert.ListRules(teamName)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(rules)
+}
+
+// title: set team alert rule
+// path: /teams/{name}/alerts/rules/{rule}
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Rule set
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Team not found
+func setAlertRule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	teamName := r.URL.Query().Get(":name")
+	ctx := permission.Context(permission.CtxTeam, teamName)
+	allowed := permission.Check(t, permission.PermTeamUpdateAlert, ctx)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	_, err = auth.GetTeam(teamName)
+	if err == auth.ErrTeamNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	rule := alert.Rule{
+		Team:          teamName,
+		Name:          r.URL.Query().Get(":rule"),
+		Condition:     r.FormValue("condition"),
+		Apps:          r.Form["app"],
+		Webhook:       r.FormValue("webhook"),
+		WebhookSecret: r.FormValue("webhook-secret"),
+		Emails:        r.Form["email"],
+		Enabled:       true,
+	}
+	delete(r.Form, "webhook-secret")
+	if threshold := r.FormValue("threshold"); threshold != "" {
+		rule.Threshold, err = strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for threshold: " + threshold}
+		}
+	}
+	window, err := parseUintForm(r, "window")
+	if err != nil {
+		return err
+	}
+	rule.Window = time.Duration(window) * time.Second
+	if enabled := r.FormValue("enabled"); enabled != "" {
+		rule.Enabled, err = strconv.ParseBool(enabled)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for enabled: " + enabled}
+		}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: teamName},
+		Kind:       permission.PermTeamUpdateAlert,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = alert.SetRule(&rule)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+// title: remove team alert rule
+// path: /teams/{name}/alerts/rules/{rule}
+// method: DELETE
+// responses:
+//   200: Rule removed
+//   401: Unauthorized
+//   404: Rule not found
+func removeAlertRule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	teamName := r.URL.Query().Get(":name")
+	ctx := permission.Context(permission.CtxTeam, teamName)
+	allowed := permission.Check(t, permission.PermTeamUpdateAlert, ctx)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: teamName},
+		Kind:       permission.PermTeamUpdateAlert,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = alert.RemoveRule(teamName, r.URL.Query().Get(":rule"))
+	if err == alert.ErrRuleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: list team alerts
+// path: /teams/{name}/alerts
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func listAlerts(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	teamName := r.URL.Query().Get(":name")
+	allowed := permission.Check(t, permission.PermTeamReadAlert, permission.Context(permission.CtxTeam, teamName))
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	alerts, err := alert.ListAlerts(teamName)
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(alerts)
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/app/alert"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSetAlertRule(c *check.C) {
+	body := strings.NewReader("condition=unit-restarts&threshold=5&window=600&app=myapp&email=a@example.com&email=b@example.com")
+	request, err := http.NewRequest("PUT", "/teams/"+s.team.Name+"/alerts/rules/restarts", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	rule, err := alert.GetRule(s.team.Name, "restarts")
+	c.Assert(err, check.IsNil)
+	c.Assert(rule.Condition, check.Equals, alert.ConditionUnitRestarts)
+	c.Assert(rule.Threshold, check.Equals, 5.0)
+	c.Assert(rule.Window, check.Equals, 10*time.Minute)
+	c.Assert(rule.Apps, check.DeepEquals, []string{"myapp"})
+	c.Assert(rule.Emails, check.DeepEquals, []string{"a@example.com", "b@example.com"})
+	c.Assert(rule.Enabled, check.Equals, true)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeTeam, Value: s.team.Name},
+		Owner:  s.token.GetUserName(),
+		Kind:   "team.update.alert",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestSetAlertRuleWebhookSecret(c *check.C) {
+	body := strings.NewReader("condition=cpu&threshold=80&webhook=http://hooks.example.com&webhook-secret=s3cr3t")
+	request, err := http.NewRequest("PUT", "/teams/"+s.team.Name+"/alerts/rules/cpu", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	rule, err := alert.GetRule(s.team.Name, "cpu")
+	c.Assert(err, check.IsNil)
+	c.Assert(rule.WebhookSecret, check.Equals, "s3cr3t")
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeTeam, Value: s.team.Name},
+		Owner:  s.token.GetUserName(),
+		Kind:   "team.update.alert",
+		StartCustomData: []map[string]interface{}{
+			{"name": "condition", "value": "cpu"},
+			{"name": "threshold", "value": "80"},
+			{"name": "webhook", "value": "http://hooks.example.com"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestSetAlertRuleInvalid(c *check.C) {
+	body := strings.NewReader("condition=disk&webhook=http://hooks.example.com")
+	request, err := http.NewRequest("PUT", "/teams/"+s.team.Name+"/alerts/rules/disk", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Matches, "condition must be one of .*\n")
+}
+
+func (s *S) TestSetAlertRuleTeamNotFound(c *check.C) {
+	body := strings.NewReader("condition=cpu&threshold=80&webhook=http://hooks.example.com")
+	request, err := http.NewRequest("PUT", "/teams/unknown/alerts/rules/cpu", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestSetAlertRuleUnauthorized(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermTeamReadAlert,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	body := strings.NewReader("condition=cpu&threshold=80&webhook=http://hooks.example.com")
+	request, err := http.NewRequest("PUT", "/teams/"+s.team.Name+"/alerts/rules/cpu", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestListAlertRules(c *check.C) {
+	err := alert.SetRule(&alert.Rule{Team: s.team.Name, Name: "cpu", Condition: alert.ConditionCPU, Threshold: 80, Webhook: "http://hooks.example.com"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/teams/"+s.team.Name+"/alerts/rules", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var rules []alert.Rule
+	err = json.NewDecoder(recorder.Body).Decode(&rules)
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 1)
+	c.Assert(rules[0].Name, check.Equals, "cpu")
+	c.Assert(rules[0].Team, check.Equals, s.team.Name)
+}
+
+func (s *S) TestListAlertRulesEmpty(c *check.C) {
+	request, err := http.NewRequest("GET", "/teams/"+s.team.Name+"/alerts/rules", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestRemoveAlertRule(c *check.C) {
+	err := alert.SetRule(&alert.Rule{Team: s.team.Name, Name: "cpu", Condition: alert.ConditionCPU, Threshold: 80, Webhook: "http://hooks.example.com"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/teams/"+s.team.Name+"/alerts/rules/cpu", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = alert.GetRule(s.team.Name, "cpu")
+	c.Assert(err, check.Equals, alert.ErrRuleNotFound)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestListAlertsEmpty(c *check.C) {
+	request, err := http.NewRequest("GET", "/teams/"+s.team.Name+"/alerts", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
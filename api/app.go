@@ -5,18 +5,23 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ajg/form"
 	"github.com/tsuru/tsuru/api/context"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/errors"
@@ -63,6 +68,7 @@ func getApp(name string) (*app.App, error) {
 // produce: application/x-json-stream
 // responses:
 //   200: App removed
+//   202: App removal started
 //   401: Unauthorized
 //   404: Not found
 func appDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
@@ -77,9 +83,13 @@ func appDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if !canDelete {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(a.Name),
 		Kind:       permission.PermAppDelete,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -87,6 +97,11 @@ func appDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if err != nil {
 		return err
 	}
+	if isAsyncRequest(r) {
+		return runJob(w, r, evt, a.Name, func(writer io.Writer) error {
+			return app.Delete(&a, writer)
+		})
+	}
 	defer func() { evt.Done(err) }()
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
 	defer keepAliveWriter.Stop()
@@ -98,11 +113,13 @@ func appDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 // miniApp is a minimal representation of the app, created to make appList
 // faster and transmit less data.
 type miniApp struct {
-	Name  string            `json:"name"`
-	Units []provision.Unit  `json:"units"`
-	CName []string          `json:"cname"`
-	Ip    string            `json:"ip"`
-	Lock  provision.AppLock `json:"lock"`
+	Name   string            `json:"name"`
+	Units  []provision.Unit  `json:"units"`
+	CName  []string          `json:"cname"`
+	Ip     string            `json:"ip"`
+	Lock   provision.AppLock `json:"lock"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
 }
 
 func minifyApp(app app.App) (miniApp, error) {
@@ -111,11 +128,13 @@ func minifyApp(app app.App) (miniApp, error) {
 		return miniApp{}, err
 	}
 	return miniApp{
-		Name:  app.GetName(),
-		Units: units,
-		CName: app.GetCname(),
-		Ip:    app.GetIp(),
-		Lock:  app.GetLock(),
+		Name:   app.GetName(),
+		Units:  units,
+		CName:  app.GetCname(),
+		Ip:     app.GetIp(),
+		Lock:   app.GetLock(),
+		Labels: app.Labels,
+		Tags:   app.Tags,
 	}, nil
 }
 
@@ -158,6 +177,14 @@ func appList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	}
 	if teamOwner := r.URL.Query().Get("teamOwner"); teamOwner != "" {
 		filter.TeamOwner = teamOwner
+		if subTeams, _ := strconv.ParseBool(r.URL.Query().Get("subTeams")); subTeams {
+			teams, err := auth.SubTeams(teamOwner)
+			if err != nil {
+				return err
+			}
+			filter.TeamOwner = ""
+			filter.TeamOwners = append([]string{teamOwner}, teams...)
+		}
 	}
 	if owner := r.URL.Query().Get("owner"); owner != "" {
 		filter.UserOwner = owner
@@ -172,6 +199,19 @@ func appList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	if status, ok := r.URL.Query()["status"]; ok {
 		filter.Statuses = status
 	}
+	if labels, ok := r.URL.Query()["label"]; ok {
+		var err error
+		filter.Labels, err = parseMetadata(labels)
+		if err != nil {
+			return err
+		}
+	}
+	if tags, ok := r.URL.Query()["tag"]; ok {
+		filter.Tags = tags
+	}
+	if description := r.URL.Query().Get("description"); description != "" {
+		filter.Description = description
+	}
 	contexts := permission.ContextsForPermission(t, permission.PermAppRead)
 	if len(contexts) == 0 {
 		w.WriteHeader(http.StatusNoContent)
@@ -227,6 +267,40 @@ type inputApp struct {
 	Description string
 	Pool        string
 	RouterOpts  map[string]string
+	Tag         []string
+	Bind        []string
+}
+
+// createAppError converts errors returned by app.CreateApp to HTTP errors.
+func createAppError(err error) error {
+	log.Errorf("Got error while creating app: %s", err)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Message}
+	}
+	if _, ok := err.(app.NoTeamsError); ok {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: "In order to create an app, you should be member of at least one team",
+		}
+	}
+	if e, ok := err.(*app.AppCreationError); ok {
+		if e.Err == app.ErrAppAlreadyExists {
+			return &errors.HTTP{Code: http.StatusConflict, Message: e.Error()}
+		}
+		if _, ok := e.Err.(*quota.QuotaExceededError); ok {
+			return &errors.HTTP{
+				Code:    http.StatusForbidden,
+				Message: "Quota exceeded",
+			}
+		}
+	}
+	if err == app.InvalidPlatformError {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if _, ok := err.(*app.PoolAccessError); ok {
+		return &errors.HTTP{Code: http.StatusForbidden, Message: err.Error()}
+	}
+	return err
 }
 
 // title: app create
@@ -258,6 +332,7 @@ func createApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 		Description: ia.Description,
 		Pool:        ia.Pool,
 		RouterOpts:  ia.RouterOpts,
+		Tags:        ia.Tag,
 	}
 	if a.TeamOwner == "" {
 		a.TeamOwner, err = permission.TeamForPermission(t, permission.PermAppCreate)
@@ -286,9 +361,14 @@ func createApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 			return &errors.HTTP{Code: http.StatusBadRequest, Message: app.InvalidPlatformError.Error()}
 		}
 	}
+	instances, err := instancesToBind(ia.Bind, &a, t)
+	if err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(a.Name),
 		Kind:       permission.PermAppCreate,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -299,31 +379,13 @@ func createApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	defer func() { evt.Done(err) }()
 	err = app.CreateApp(&a, u)
 	if err != nil {
-		log.Errorf("Got error while creating app: %s", err)
-		if e, ok := err.(*errors.ValidationError); ok {
-			return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Message}
-		}
-		if _, ok := err.(app.NoTeamsError); ok {
-			return &errors.HTTP{
-				Code:    http.StatusBadRequest,
-				Message: "In order to create an app, you should be member of at least one team",
-			}
-		}
-		if e, ok := err.(*app.AppCreationError); ok {
-			if e.Err == app.ErrAppAlreadyExists {
-				return &errors.HTTP{Code: http.StatusConflict, Message: e.Error()}
-			}
-			if _, ok := e.Err.(*quota.QuotaExceededError); ok {
-				return &errors.HTTP{
-					Code:    http.StatusForbidden,
-					Message: "Quota exceeded",
-				}
-			}
-		}
-		if err == app.InvalidPlatformError {
-			return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+		return createAppError(err)
+	}
+	for _, instance := range instances {
+		err = instance.BindApp(&a, false, evt)
+		if err != nil {
+			return fmt.Errorf("app %q was created, but binding the service instance %q failed: %s", a.Name, instance.Name, err)
 		}
-		return err
 	}
 	repo, err := repository.Manager().GetRepository(a.Name)
 	if err != nil {
@@ -344,6 +406,152 @@ func createApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	return nil
 }
 
+// instancesToBind returns the service instances, given as service/instance,
+// that should be bound to an app right after it's created, checking the user
+// is allowed to bind each one of them to the app.
+func instancesToBind(binds []string, a *app.App, t auth.Token) ([]*service.ServiceInstance, error) {
+	if len(binds) == 0 {
+		return nil, nil
+	}
+	if !permission.Check(t, permission.PermAppUpdateBind, contextsForApp(a)...) {
+		return nil, permission.ErrUnauthorized
+	}
+	instances := make([]*service.ServiceInstance, 0, len(binds))
+	for _, b := range binds {
+		parts := strings.SplitN(b, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			msg := fmt.Sprintf("invalid service instance %q, it must be in the form <service>/<instance>", b)
+			return nil, &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+		}
+		instance, err := getServiceInstanceOrError(parts[0], parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if !canBindServiceInstance(t, instance) {
+			return nil, permission.ErrUnauthorized
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func canBindServiceInstance(t auth.Token, instance *service.ServiceInstance) bool {
+	return permission.Check(t, permission.PermServiceInstanceUpdateBind,
+		append(permission.Contexts(permission.CtxTeam, instance.Teams),
+			permission.Context(permission.CtxServiceInstance, instance.Name),
+		)...,
+	)
+}
+
+// title: app clone
+// path: /apps/{app}/clone
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: App cloned
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Quota exceeded
+//   404: App not found
+//   409: App already exists
+func cloneApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	src, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	name := r.FormValue("name")
+	if name == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide the name of the new app."}
+	}
+	excludeSecrets, _ := strconv.ParseBool(r.FormValue("excludeSecrets"))
+	allowed := permission.Check(t, permission.PermAppReadEnv, contextsForApp(&src)...) &&
+		permission.Check(t, permission.PermAppCreate, permission.Context(permission.CtxTeam, src.TeamOwner))
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &src); err != nil {
+		return err
+	}
+	newApp := app.App{Name: name, TeamOwner: src.TeamOwner, Pool: src.Pool}
+	instances, err := service.GetServicesInstancesByTeamsAndNames(nil, nil, src.Name, "")
+	if err != nil {
+		return err
+	}
+	if len(instances) > 0 && !permission.Check(t, permission.PermAppUpdateBind, contextsForApp(&newApp)...) {
+		return permission.ErrUnauthorized
+	}
+	for i := range instances {
+		if !canBindServiceInstance(t, &instances[i]) {
+			return permission.ErrUnauthorized
+		}
+	}
+	u, err := t.User()
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(name),
+		Kind:       permission.PermAppCreate,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&newApp)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	evt.SetLogWriter(writer)
+	clone, err := src.Clone(app.CloneOptions{
+		Name:             name,
+		ExcludeSecrets:   excludeSecrets,
+		User:             u,
+		Writer:           evt,
+		ServiceInstances: instances,
+	})
+	if clone == nil {
+		return createAppError(err)
+	}
+	if err != nil {
+		return err
+	}
+	srcImage, imgErr := image.AppCurrentImageName(src.Name)
+	if imgErr != nil || srcImage == "" {
+		fmt.Fprintf(evt, "\n---- App %q has no deployed image, skipping deploy ----\n", src.Name)
+		return nil
+	}
+	deployEvt, err := event.New(&event.Opts{
+		Target:     appTarget(clone.Name),
+		Kind:       permission.PermAppDeploy,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: map[string]string{"image": srcImage, "origin": "clone"},
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(clone)...),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = app.Deploy(app.DeployOptions{
+		App:          clone,
+		Image:        srcImage,
+		User:         t.GetUserName(),
+		Origin:       "image",
+		OutputStream: writer,
+		Event:        deployEvt,
+	})
+	if err == nil {
+		err = clone.CopyUnitsFrom(&src, deployEvt)
+	}
+	deployEvt.Done(err)
+	return err
+}
+
 // title: app update
 // path: /apps/{name}
 // method: PUT
@@ -360,6 +568,9 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 		Pool:        r.FormValue("pool"),
 		Description: r.FormValue("description"),
 	}
+	if tags, ok := r.Form["tag"]; ok {
+		updateData.Tags = tags
+	}
 	appName := r.URL.Query().Get(":appname")
 	a, err := getAppFromContext(appName, r)
 	if err != nil {
@@ -369,6 +580,9 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if updateData.Description != "" {
 		wantedPerms = append(wantedPerms, permission.PermAppUpdateDescription)
 	}
+	if updateData.Tags != nil {
+		wantedPerms = append(wantedPerms, permission.PermAppUpdateTags)
+	}
 	if updateData.Plan.Name != "" {
 		wantedPerms = append(wantedPerms, permission.PermAppUpdatePlan)
 	}
@@ -379,7 +593,7 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 		wantedPerms = append(wantedPerms, permission.PermAppUpdateTeamowner)
 	}
 	if len(wantedPerms) == 0 {
-		msg := "Neither the description, plan, pool or team owner were set. You must define at least one."
+		msg := "Neither the description, tags, plan, pool or team owner were set. You must define at least one."
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
 	}
 	for _, perm := range wantedPerms {
@@ -390,9 +604,13 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 			return permission.ErrUnauthorized
 		}
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdate,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -409,6 +627,12 @@ func updateApp(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if err == app.ErrPlanNotFound {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
 	}
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if _, ok := err.(*app.PoolAccessError); ok {
+		return &errors.HTTP{Code: http.StatusForbidden, Message: err.Error()}
+	}
 	return err
 }
 
@@ -437,10 +661,14 @@ func numberOfUnits(r *http.Request) (uint, error) {
 // produce: application/x-json-stream
 // responses:
 //   200: Units added
+//   202: Units addition started
 //   400: Invalid data
 //   401: Unauthorized
 //   404: App not found
 func addUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if r.FormValue("total") != "" {
+		return setUnits(w, r, t)
+	}
 	n, err := numberOfUnits(r)
 	if err != nil {
 		return err
@@ -457,9 +685,13 @@ func addUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateUnitAdd,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -467,6 +699,11 @@ func addUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if err != nil {
 		return err
 	}
+	if isAsyncRequest(r) {
+		return runJob(w, r, evt, a.Name, func(writer io.Writer) error {
+			return a.AddUnits(n, processName, writer)
+		})
+	}
 	defer func() { evt.Done(err) }()
 	w.Header().Set("Content-Type", "application/x-json-stream")
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
@@ -475,12 +712,85 @@ func addUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	return a.AddUnits(n, processName, writer)
 }
 
+// setUnits handles PUT requests to /apps/{name}/units with the total form
+// value, converging the number of units of the process to the desired total.
+// The units are counted again once the event locks the app, so the add or
+// remove permission is checked against the actual direction of the change.
+func setUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	total, err := strconv.ParseUint(r.FormValue("total"), 10, 32)
+	if err != nil {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid number of units: the total must be a non-negative integer.",
+		}
+	}
+	processName := r.FormValue("process")
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	err = a.ValidateUnitsProcess(processName)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	current, err := a.CountUnits(processName)
+	if err != nil {
+		return err
+	}
+	canAdd := permission.Check(t, permission.PermAppUpdateUnitAdd, contextsForApp(&a)...)
+	canRemove := permission.Check(t, permission.PermAppUpdateUnitRemove, contextsForApp(&a)...)
+	perm := permission.PermAppUpdateUnitAdd
+	if uint(total) < current {
+		perm = permission.PermAppUpdateUnitRemove
+	}
+	if (perm == permission.PermAppUpdateUnitAdd && !canAdd) || (perm == permission.PermAppUpdateUnitRemove && !canRemove) {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       perm,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	run := func(writer io.Writer) error {
+		current, err := a.CountUnits(processName)
+		if err != nil {
+			return err
+		}
+		if (uint(total) > current && !canAdd) || (uint(total) < current && !canRemove) {
+			return permission.ErrUnauthorized
+		}
+		return a.SetUnits(uint(total), processName, writer)
+	}
+	if isAsyncRequest(r) {
+		return runJob(w, r, evt, a.Name, run)
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	return run(writer)
+}
+
 // title: remove units
 // path: /apps/{name}/units
 // method: DELETE
 // produce: application/x-json-stream
 // responses:
 //   200: Units removed
+//   202: Units removal started
 //   400: Invalid data
 //   401: Unauthorized
 //   404: App not found
@@ -501,9 +811,13 @@ func removeUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err erro
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateUnitRemove,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -511,6 +825,11 @@ func removeUnits(w http.ResponseWriter, r *http.Request, t auth.Token) (err erro
 	if err != nil {
 		return err
 	}
+	if isAsyncRequest(r) {
+		return runJob(w, r, evt, a.Name, func(writer io.Writer) error {
+			return a.RemoveUnits(n, processName, writer)
+		})
+	}
 	defer func() { evt.Done(err) }()
 	w.Header().Set("Content-Type", "application/x-json-stream")
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
@@ -595,6 +914,33 @@ func setNodeStatus(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return json.NewEncoder(w).Encode(result)
 }
 
+// title: list app access
+// path: /apps/{app}/teams
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App not found
+func listAppAccess(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	canRead := permission.Check(t, permission.PermAppRead,
+		contextsForApp(&a)...,
+	)
+	if !canRead {
+		return permission.ErrUnauthorized
+	}
+	access, err := a.Access()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(access)
+}
+
 // title: grant access to app
 // path: /apps/{app}/teams/{team}
 // method: PUT
@@ -618,6 +964,9 @@ func grantAppAccess(w http.ResponseWriter, r *http.Request, t auth.Token) (err e
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateGrant,
@@ -668,6 +1017,9 @@ func revokeAppAccess(w http.ResponseWriter, r *http.Request, t auth.Token) (err
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateRevoke,
@@ -719,8 +1071,24 @@ func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
 	}
 	appName := r.URL.Query().Get(":app")
-	once := r.FormValue("once")
-	isolated := r.FormValue("isolated")
+	onceBool, _ := strconv.ParseBool(r.FormValue("once"))
+	isolatedBool, _ := strconv.ParseBool(r.FormValue("isolated"))
+	args := provision.RunArgs{Once: onceBool, Isolated: isolatedBool, Units: r.Form["unit"]}
+	if percentage := r.FormValue("units-percentage"); percentage != "" {
+		args.UnitsPercentage, err = strconv.Atoi(percentage)
+		if err != nil || args.UnitsPercentage < 1 || args.UnitsPercentage > 100 {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "units-percentage must be a number between 1 and 100"}
+		}
+	}
+	var targets int
+	for _, set := range []bool{args.Once, args.Isolated, len(args.Units) > 0, args.UnitsPercentage > 0} {
+		if set {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "only one of once, isolated, unit and units-percentage may be used"}
+	}
 	a, err := getAppFromContext(appName, r)
 	if err != nil {
 		return err
@@ -731,9 +1099,13 @@ func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppRun,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -746,9 +1118,6 @@ func runCommand(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
 	defer keepAliveWriter.Stop()
 	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
-	onceBool, _ := strconv.ParseBool(once)
-	isolatedBool, _ := strconv.ParseBool(isolated)
-	args := provision.RunArgs{Once: onceBool, Isolated: isolatedBool}
 	return a.Run(command, writer, args)
 }
 
@@ -784,14 +1153,15 @@ func getEnv(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 func writeEnvVars(w http.ResponseWriter, a *app.App, variables ...string) error {
 	var result []bind.EnvVar
 	w.Header().Set("Content-Type", "application/json")
+	envs := app.MaskSecretEnvs(a.Env)
 	if len(variables) > 0 {
 		for _, variable := range variables {
-			if v, ok := a.Env[variable]; ok {
+			if v, ok := envs[variable]; ok {
 				result = append(result, v)
 			}
 		}
 	} else {
-		for _, v := range a.Env {
+		for _, v := range envs {
 			result = append(result, v)
 		}
 	}
@@ -804,6 +1174,7 @@ type Envs struct {
 	Envs      []struct{ Name, Value string }
 	NoRestart bool
 	Private   bool
+	Secret    bool
 }
 
 // title: set envs
@@ -843,9 +1214,21 @@ func setEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	if e.Secret {
+		if err = app.CheckSecretEnvs(); err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+		for i := range e.Envs {
+			r.Form.Set(fmt.Sprintf("Envs.%d.Value", i), app.SecretEnvMask)
+		}
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateEnvSet,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -858,7 +1241,7 @@ func setEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	variables := []bind.EnvVar{}
 	for _, v := range e.Envs {
 		envs[v.Name] = v.Value
-		variables = append(variables, bind.EnvVar{Name: v.Name, Value: v.Value, Public: !e.Private})
+		variables = append(variables, bind.EnvVar{Name: v.Name, Value: v.Value, Public: !e.Private && !e.Secret, Secret: e.Secret})
 	}
 	w.Header().Set("Content-Type", "application/x-json-stream")
 	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
@@ -869,66 +1252,293 @@ func setEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 			Envs:          variables,
 			PublicOnly:    true,
 			ShouldRestart: !e.NoRestart,
+			Owner:         t.GetUserName(),
 		}, writer,
 	)
 }
 
-// title: unset envs
-// path: /apps/{app}/env
-// method: DELETE
-// produce: application/x-json-stream
+// title: export envs
+// path: /apps/{app}/env/export
+// method: GET
+// produce: text/plain, application/json
 // responses:
-//   200: Envs removed
+//   200: OK
 //   400: Invalid data
 //   401: Unauthorized
 //   404: App not found
-func unsetEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
-	msg := "You must provide the list of environment variables."
-	if r.FormValue("env") == "" {
-		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
-	}
-	var variables []string
-	if envs, ok := r.Form["env"]; ok {
-		variables = envs
-	} else {
-		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
-	}
-	appName := r.URL.Query().Get(":app")
-	a, err := getAppFromContext(appName, r)
+func exportEnv(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
 	if err != nil {
 		return err
 	}
-	allowed := permission.Check(t, permission.PermAppUpdateEnvUnset,
+	allowed := permission.Check(t, permission.PermAppReadEnv,
 		contextsForApp(&a)...,
 	)
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
-	evt, err := event.New(&event.Opts{
-		Target:     appTarget(appName),
-		Kind:       permission.PermAppUpdateEnvUnset,
-		Owner:      t,
-		CustomData: event.FormToCustomData(r.Form),
-		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
-	})
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = app.EnvFormatDotenv
+	}
+	redact, _ := strconv.ParseBool(r.URL.Query().Get("redact"))
+	var buf bytes.Buffer
+	err = a.ExportEnvs(&buf, format, redact)
 	if err != nil {
 		return err
 	}
-	defer func() { evt.Done(err) }()
-	w.Header().Set("Content-Type", "application/x-json-stream")
-	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
-	defer keepAliveWriter.Stop()
-	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
-	noRestart, _ := strconv.ParseBool(r.FormValue("noRestart"))
-	return a.UnsetEnvs(
-		bind.UnsetEnvApp{
-			VariableNames: variables,
-			PublicOnly:    true,
+	if format == app.EnvFormatJSON {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain")
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// title: import envs
+// path: /apps/{app}/env/import
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: Envs imported
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func importEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	err = r.ParseForm()
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	format := r.FormValue("format")
+	if format == "" {
+		format = app.EnvFormatDotenv
+	}
+	variables, err := app.ParseEnvs([]byte(r.FormValue("envs")), format)
+	if err != nil {
+		return err
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateEnvSet,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	private, _ := strconv.ParseBool(r.FormValue("private"))
+	secret, _ := strconv.ParseBool(r.FormValue("secret"))
+	noRestart, _ := strconv.ParseBool(r.FormValue("noRestart"))
+	names := make([]string, len(variables))
+	for i := range variables {
+		variables[i].Public = !private && !secret
+		variables[i].Secret = secret
+		names[i] = variables[i].Name
+	}
+	r.Form.Del("envs")
+	r.Form["env"] = names
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateEnvSet,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	return a.ImportEnvs(
+		bind.SetEnvApp{
+			Envs:          variables,
+			PublicOnly:    true,
+			ShouldRestart: !noRestart,
+			Owner:         t.GetUserName(),
+		}, writer,
+	)
+}
+
+// title: unset envs
+// path: /apps/{app}/env
+// method: DELETE
+// produce: application/x-json-stream
+// responses:
+//   200: Envs removed
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func unsetEnv(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	msg := "You must provide the list of environment variables."
+	if r.FormValue("env") == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	var variables []string
+	if envs, ok := r.Form["env"]; ok {
+		variables = envs
+	} else {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateEnvUnset,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateEnvUnset,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	noRestart, _ := strconv.ParseBool(r.FormValue("noRestart"))
+	return a.UnsetEnvs(
+		bind.UnsetEnvApp{
+			VariableNames: variables,
+			PublicOnly:    true,
 			ShouldRestart: !noRestart,
+			Owner:         t.GetUserName(),
 		}, writer,
 	)
 }
 
+// title: app env history
+// path: /apps/{app}/env/history
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func envHistory(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppReadEnv,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	var limit int
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for limit: " + l}
+		}
+	}
+	changes, err := a.EnvHistory(limit)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(changes)
+}
+
+func parseTimeParam(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for " + name + ", expected RFC 3339 time: " + value}
+	}
+	return t, nil
+}
+
+// title: app env diff
+// path: /apps/{app}/env/diff
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func envDiff(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppReadEnv,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	var diffs []app.EnvDiff
+	if otherName := r.URL.Query().Get("app"); otherName != "" {
+		var other *app.App
+		other, err = getApp(otherName)
+		if err != nil {
+			return err
+		}
+		allowed = permission.Check(t, permission.PermAppReadEnv,
+			contextsForApp(other)...,
+		)
+		if !allowed {
+			return permission.ErrUnauthorized
+		}
+		diffs = a.EnvDiffTo(other)
+	} else {
+		var from, to time.Time
+		if from, err = parseTimeParam(r, "from", time.Time{}); err != nil {
+			return err
+		}
+		if to, err = parseTimeParam(r, "to", time.Now()); err != nil {
+			return err
+		}
+		diffs, err = a.EnvDiffBetween(from, to)
+		if err != nil {
+			return err
+		}
+	}
+	if len(diffs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(diffs)
+}
+
 // title: set cname
 // path: /apps/{app}/cname
 // method: POST
@@ -959,6 +1569,9 @@ func setCName(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateCnameAdd,
@@ -973,7 +1586,7 @@ func setCName(w http.ResponseWriter, r *http.Request, t auth.Token) (err error)
 	if err = a.AddCName(cnames...); err == nil {
 		return nil
 	}
-	if err.Error() == "Invalid cname" {
+	if err == app.ErrInvalidCName {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
 	}
 	return err
@@ -1005,6 +1618,9 @@ func unsetCName(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateCnameRemove,
@@ -1019,12 +1635,313 @@ func unsetCName(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if err = a.RemoveCName(cnames...); err == nil {
 		return nil
 	}
-	if err.Error() == "Invalid cname" {
+	if err == app.ErrInvalidCName {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
 	}
 	return err
 }
 
+// title: move cname
+// path: /apps/{app}/cname/move
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App or cname not found
+//   409: Destination app is locked
+func moveCName(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	cname := r.FormValue("cname")
+	dstName := r.FormValue("destination")
+	if cname == "" || dstName == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide the cname and the destination app."}
+	}
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if a.Name == dstName {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "cname source and destination apps must be different"}
+	}
+	dst, err := getApp(dstName)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateCnameRemove, contextsForApp(&a)...) &&
+		permission.Check(t, permission.PermAppUpdateCnameAdd, contextsForApp(dst)...)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	if err = checkFrozen(t, dst); err != nil {
+		return err
+	}
+	locked, err := app.AcquireApplicationLockWait(dstName, t.GetUserName(), "/apps/"+a.Name+"/cname/move", lockWaitDuration)
+	if err != nil {
+		return err
+	}
+	if locked {
+		defer app.ReleaseApplicationLock(dstName)
+	}
+	dst, err = getApp(dstName)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return &errors.HTTP{Code: http.StatusConflict, Message: fmt.Sprintf("%s: %s", dst.Name, &dst.Lock)}
+	}
+	evt1, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateCnameRemove,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	evt2, err := event.New(&event.Opts{
+		Target:     appTarget(dst.Name),
+		Kind:       permission.PermAppUpdateCnameAdd,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(dst)...),
+	})
+	if err != nil {
+		evt1.Done(err)
+		return err
+	}
+	defer func() { evt1.Done(err); evt2.Done(err) }()
+	err = a.MoveCName(cname, dst)
+	if err == app.ErrCNameNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err == app.ErrInvalidCName {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return err
+}
+
+// title: add app dependency
+// path: /apps/{app}/dependencies
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+//   409: Dependency cycle
+func addAppDependency(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	depName := r.FormValue("dependency")
+	if depName == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide the dependency app."}
+	}
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	dep, err := getApp(depName)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateDependency, contextsForApp(&a)...) &&
+		permission.Check(t, permission.PermAppRead, contextsForApp(dep)...)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateDependency,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.AddDependency(dep.Name)
+	if e, ok := err.(*app.DependencyCycleError); ok {
+		return &errors.HTTP{Code: http.StatusConflict, Message: e.Error()}
+	}
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+// title: remove app dependency
+// path: /apps/{app}/dependencies/{dependency}
+// method: DELETE
+// responses:
+//   200: Ok
+//   401: Unauthorized
+//   404: Not found
+func removeAppDependency(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	depName := r.URL.Query().Get(":dependency")
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateDependency, contextsForApp(&a)...)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateDependency,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.RemoveDependency(depName)
+	if err == app.ErrDependencyNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+func parseMetadata(values []string) (map[string]string, error) {
+	metadata := make(map[string]string, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, &errors.HTTP{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Invalid metadata %q, it should be in the format key=value.", value),
+			}
+		}
+		if err := app.ValidateMetadataKey(parts[0]); err != nil {
+			return nil, &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+		metadata[parts[0]] = parts[1]
+	}
+	return metadata, nil
+}
+
+// title: set app metadata
+// path: /apps/{app}/metadata
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func setAppMetadata(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	labels, err := parseMetadata(r.Form["label"])
+	if err != nil {
+		return err
+	}
+	annotations, err := parseMetadata(r.Form["annotation"])
+	if err != nil {
+		return err
+	}
+	if len(labels) == 0 && len(annotations) == 0 {
+		msg := "You must provide at least one label or annotation."
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateMetadata,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateMetadata,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.SetMetadata(labels, annotations)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Message}
+	}
+	return err
+}
+
+// title: unset app metadata
+// path: /apps/{app}/metadata
+// method: DELETE
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func unsetAppMetadata(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	labels := r.Form["label"]
+	annotations := r.Form["annotation"]
+	if len(labels) == 0 && len(annotations) == 0 {
+		msg := "You must provide at least one label or annotation."
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateMetadata,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateMetadata,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.UnsetMetadata(labels, annotations)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Message}
+	}
+	return err
+}
+
 // title: app log
 // path: /apps/{app}/log
 // method: GET
@@ -1049,9 +1966,10 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	w.Header().Set("Content-Type", "application/x-json-stream")
 	source := r.URL.Query().Get("source")
 	unit := r.URL.Query().Get("unit")
+	level := r.URL.Query().Get("level")
 	follow := r.URL.Query().Get("follow")
 	appName := r.URL.Query().Get(":app")
-	filterLog := app.Applog{Source: source, Unit: unit}
+	filterLog := app.Applog{Source: source, Unit: unit, Level: level}
 	a, err := getAppFromContext(appName, r)
 	if err != nil {
 		return err
@@ -1062,7 +1980,46 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
-	logs, err := a.LastLogs(lines, filterLog)
+	since, err := parseTimeParam(r, "since", time.Time{})
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeParam(r, "until", time.Time{})
+	if err != nil {
+		return err
+	}
+	if follow == "1" && !until.IsZero() {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: `Parameters "follow" and "until" can't be used together.`}
+	}
+	after, err := parseTimeParam(r, "after", time.Time{})
+	if err != nil {
+		return err
+	}
+	if !after.IsZero() {
+		// Dates are stored with millisecond precision, so the line with the
+		// date given in after, the resume cursor, is never returned again.
+		since = after.Truncate(time.Millisecond).Add(time.Millisecond)
+	}
+	ignoreCase, _ := strconv.ParseBool(r.URL.Query().Get("ignore-case"))
+	message, err := app.LogMessagePattern(r.URL.Query().Get("message"), r.URL.Query().Get("regex"), ignoreCase)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	var l *app.LogListener
+	if follow == "1" {
+		// The listener is created before searching the logs, so lines
+		// written in between aren't lost by clients resuming the stream.
+		l, err = app.NewLogSearchListener(&a, filterLog, message)
+		if err != nil {
+			return err
+		}
+		logTracker.add(l)
+		defer func() {
+			logTracker.remove(l)
+			l.Close()
+		}()
+	}
+	logs, err := a.SearchLogs(lines, filterLog, message, since, until)
 	if err != nil {
 		return err
 	}
@@ -1071,35 +2028,47 @@ func appLog(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	if err != nil {
 		return err
 	}
-	if follow != "1" {
+	if l == nil {
 		return nil
 	}
+	var lastDate time.Time
+	if len(logs) > 0 {
+		lastDate = logs[len(logs)-1].Date
+	}
 	var closeChan <-chan bool
 	if notifier, ok := w.(http.CloseNotifier); ok {
 		closeChan = notifier.CloseNotify()
 	} else {
 		closeChan = make(chan bool)
 	}
-	l, err := app.NewLogListener(&a, filterLog)
-	if err != nil {
-		return err
-	}
-	logTracker.add(l)
-	defer func() {
-		logTracker.remove(l)
-		l.Close()
-	}()
+	flusher, _ := w.(http.Flusher)
+	keepAlive := time.NewTicker(logKeepAliveInterval)
+	defer keepAlive.Stop()
 	logChan := l.ListenChan()
 	for {
 		var logMsg app.Applog
 		select {
 		case <-closeChan:
 			return nil
+		case <-keepAlive.C:
+			// Empty batches let clients tell an idle stream from a broken
+			// connection, reconnecting with the date of the last line.
+			err = encoder.Encode([]app.Applog{})
+			if err != nil {
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			continue
 		case logMsg = <-logChan:
 		}
 		if logMsg == (app.Applog{}) {
 			break
 		}
+		if !lastDate.IsZero() && !logMsg.Date.Truncate(time.Millisecond).After(lastDate) {
+			continue
+		}
 		err := encoder.Encode([]app.Applog{logMsg})
 		if err != nil {
 			break
@@ -1160,6 +2129,9 @@ func bindServiceInstance(w http.ResponseWriter, r *http.Request, t auth.Token) (
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateBind,
@@ -1222,6 +2194,9 @@ func unbindServiceInstance(w http.ResponseWriter, r *http.Request, t auth.Token)
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateUnbind,
@@ -1267,9 +2242,13 @@ func restart(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateRestart,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -1285,6 +2264,116 @@ func restart(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	return a.Restart(process, writer)
 }
 
+// findAppUnit returns the unit of the app with the given id or name, or an
+// error when the app has no such unit.
+func findAppUnit(a *app.App, unitName string) (*provision.Unit, error) {
+	units, err := a.Units()
+	if err != nil {
+		return nil, err
+	}
+	for i, u := range units {
+		if u.ID == unitName || (u.Name != "" && u.Name == unitName) {
+			return &units[i], nil
+		}
+	}
+	return nil, &errors.HTTP{Code: http.StatusNotFound, Message: fmt.Sprintf("unit %q not found in app %q", unitName, a.Name)}
+}
+
+// title: app unit info
+// path: /apps/{app}/units/{unit}
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App or unit not found
+func unitInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppRead,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	unit, err := findAppUnit(&a, r.URL.Query().Get(":unit"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(unit)
+}
+
+// title: app unit restart
+// path: /apps/{app}/units/{unit}/restart
+// method: POST
+// produce: application/x-json-stream
+// responses:
+//   200: Ok
+//   401: Unauthorized
+//   404: App or unit not found
+func restartUnit(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	return runUnitAction(w, r, t, func(a *app.App, unitName string, w io.Writer) error {
+		return a.RestartUnit(unitName, w)
+	})
+}
+
+// title: app unit replace
+// path: /apps/{app}/units/{unit}/replace
+// method: POST
+// produce: application/x-json-stream
+// responses:
+//   200: Ok
+//   401: Unauthorized
+//   404: App or unit not found
+func replaceUnit(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	return runUnitAction(w, r, t, func(a *app.App, unitName string, w io.Writer) error {
+		return a.ReplaceUnit(unitName, w)
+	})
+}
+
+func runUnitAction(w http.ResponseWriter, r *http.Request, t auth.Token, action func(*app.App, string, io.Writer) error) (err error) {
+	r.ParseForm()
+	appName := r.URL.Query().Get(":app")
+	unitName := r.URL.Query().Get(":unit")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateRestart,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	_, err = findAppUnit(&a, unitName)
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateRestart,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	return action(&a, unitName, writer)
+}
+
 // title: app sleep
 // path: /apps/{app}/sleep
 // method: POST
@@ -1317,9 +2406,13 @@ func sleep(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateSleep,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -1335,6 +2428,62 @@ func sleep(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	return a.Sleep(writer, process, proxyURL)
 }
 
+// title: app change pool
+// path: /apps/{app}/pool
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Forbidden
+//   404: App or pool not found
+func changeAppPool(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	poolName := r.FormValue("pool")
+	if poolName == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide the pool name."}
+	}
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdatePool,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdatePool,
+		RequestID:  requestID(r),
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	err = a.ChangePool(poolName, writer)
+	if err == provision.ErrPoolNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if _, ok := err.(*app.PoolAccessError); ok {
+		return &errors.HTTP{Code: http.StatusForbidden, Message: err.Error()}
+	}
+	return err
+}
+
 // title: app log
 // path: /apps/{app}/log
 // method: POST
@@ -1428,9 +2577,16 @@ func swap(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed1 || !allowed2 {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, app1); err != nil {
+		return err
+	}
+	if err = checkFrozen(t, app2); err != nil {
+		return err
+	}
 	evt1, err := event.New(&event.Opts{
 		Target:     appTarget(app1Name),
 		Kind:       permission.PermAppUpdateSwap,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(app1)...),
@@ -1441,6 +2597,7 @@ func swap(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	evt2, err := event.New(&event.Opts{
 		Target:     appTarget(app2Name),
 		Kind:       permission.PermAppUpdateSwap,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(app2)...),
@@ -1497,9 +2654,13 @@ func start(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateStart,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -1537,9 +2698,13 @@ func stop(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(appName),
 		Kind:       permission.PermAppUpdateStop,
+		RequestID:  requestID(r),
 		Owner:      t,
 		CustomData: event.FormToCustomData(r.Form),
 		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
@@ -1561,8 +2726,10 @@ func stop(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 // produce: application/json
 // responses:
 //   200: Ok
+//   400: Invalid data
 //   401: Unauthorized
 //   404: App not found
+//   409: Lock is not stale
 func forceDeleteLock(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	r.ParseForm()
 	appName := r.URL.Query().Get(":app")
@@ -1587,6 +2754,22 @@ func forceDeleteLock(w http.ResponseWriter, r *http.Request, t auth.Token) (err
 		return err
 	}
 	defer func() { evt.Done(err) }()
+	if olderThan := r.FormValue("older-than"); olderThan != "" {
+		var maxAge time.Duration
+		maxAge, err = time.ParseDuration(olderThan)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid older-than value: %s", err)}
+		}
+		var released bool
+		released, err = app.ReleaseStaleApplicationLock(a.Name, maxAge)
+		if err != nil {
+			return err
+		}
+		if !released && a.Lock.Locked {
+			return &errors.HTTP{Code: http.StatusConflict, Message: fmt.Sprintf("lock is not stale: %s", &a.Lock)}
+		}
+		return nil
+	}
 	app.ReleaseApplicationLock(a.Name)
 	return nil
 }
@@ -1667,6 +2850,154 @@ func appMetricEnvs(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return json.NewEncoder(w).Encode(metricMap)
 }
 
+// title: app metrics
+// path: /apps/{app}/metrics
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func appMetrics(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadMetric, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	since, err := parseTimeParam(r, "since", time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeParam(r, "until", time.Time{})
+	if err != nil {
+		return err
+	}
+	samples, err := metrics.List(a.Name, since, until)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(samples)
+}
+
+// title: app request stats
+// path: /apps/{app}/requests
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func appRequestsStats(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadMetric, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	since, err := parseTimeParam(r, "since", time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeParam(r, "until", time.Time{})
+	if err != nil {
+		return err
+	}
+	stats, err := metrics.RequestsStats(a.Name, since, until)
+	if err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(stats)
+}
+
+// title: app uptime
+// path: /uptime
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func appsUptime(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	contexts := permission.ContextsForPermission(t, permission.PermAppReadMetric)
+	if len(contexts) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	apps, err := app.List(appFilterByContext(contexts, nil))
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, name := range r.URL.Query()["app"] {
+		names[name] = true
+	}
+	now := time.Now().UTC()
+	var result []metrics.Uptime
+	for _, a := range apps {
+		if len(names) > 0 && !names[a.Name] {
+			continue
+		}
+		uptime, err := metrics.AppUptime(a.Name, now)
+		if err != nil {
+			return err
+		}
+		result = append(result, uptime)
+	}
+	if len(result) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(result)
+}
+
+// title: add app request samples
+// path: /apps/{app}/requests
+// method: POST
+// consume: application/json
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func addAppRequests(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppUpdateRequests, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	defer r.Body.Close()
+	var samples []metrics.RequestSample
+	err = json.NewDecoder(r.Body).Decode(&samples)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "unable to parse request samples: " + err.Error()}
+	}
+	err = metrics.RecordRequests(&a, samples)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
 // title: rebuild routes
 // path: /apps/{app}/routes
 // method: POST
@@ -1686,6 +3017,9 @@ func appRebuildRoutes(w http.ResponseWriter, r *http.Request, t auth.Token) (err
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     appTarget(a.Name),
 		Kind:       permission.PermAppAdminRoutes,
@@ -6,6 +6,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,10 +24,14 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/event/eventtest"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/queue"
@@ -34,6 +39,7 @@ import (
 	"github.com/tsuru/tsuru/repository"
 	"github.com/tsuru/tsuru/repository/repositorytest"
 	"github.com/tsuru/tsuru/router/rebuild"
+	"github.com/tsuru/tsuru/router/routertest"
 	"github.com/tsuru/tsuru/service"
 	"gopkg.in/check.v1"
 	"gopkg.in/mgo.v2/bson"
@@ -73,6 +79,85 @@ func (s *S) TestAppListFilteringByPlatform(c *check.C) {
 	}
 }
 
+func (s *S) TestAppListFilteringByInvalidLabel(c *check.C) {
+	for _, label := range []string{"a.b=1", "$where=1"} {
+		request, err := http.NewRequest("GET", "/apps?"+url.Values{"label": {label}}.Encode(), nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("label %q", label))
+	}
+}
+
+func (s *S) TestAppListFilteringByLabel(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	err = app1.SetMetadata(map[string]string{"tier": "1"}, nil)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps?label=tier=1", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var apps []miniApp
+	err = json.Unmarshal(recorder.Body.Bytes(), &apps)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 1)
+	c.Assert(apps[0].Name, check.Equals, app1.Name)
+	c.Assert(apps[0].Labels, check.DeepEquals, map[string]string{"tier": "1"})
+}
+
+func (s *S) TestAppListFilteringByTag(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Tags: []string{"frontend", "critical"}}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", Platform: "zend", TeamOwner: s.team.Name, Tags: []string{"frontend"}}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps?tag=frontend&tag=critical", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var apps []miniApp
+	err = json.Unmarshal(recorder.Body.Bytes(), &apps)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 1)
+	c.Assert(apps[0].Name, check.Equals, app1.Name)
+	c.Assert(apps[0].Tags, check.DeepEquals, []string{"frontend", "critical"})
+}
+
+func (s *S) TestAppListFilteringByDescription(c *check.C) {
+	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name, Description: "Payments API"}
+	err := app.CreateApp(&app1, s.user)
+	c.Assert(err, check.IsNil)
+	app2 := app.App{Name: "app2", Platform: "zend", TeamOwner: s.team.Name, Description: "blog"}
+	err = app.CreateApp(&app2, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps?description=payments", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var apps []miniApp
+	err = json.Unmarshal(recorder.Body.Bytes(), &apps)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 1)
+	c.Assert(apps[0].Name, check.Equals, app1.Name)
+}
+
 func (s *S) TestAppListFilteringByTeamOwner(c *check.C) {
 	app1 := app.App{Name: "app1", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&app1, s.user)
@@ -107,6 +192,39 @@ func (s *S) TestAppListFilteringByTeamOwner(c *check.C) {
 	}
 }
 
+func (s *S) TestAppListFilteringByTeamOwnerWithSubTeams(c *check.C) {
+	subTeam := auth.Team{Name: "angra", Parent: s.team.Name}
+	err := s.conn.Teams().Insert(subTeam)
+	c.Assert(err, check.IsNil)
+	otherTeam := auth.Team{Name: "shaman"}
+	err = s.conn.Teams().Insert(otherTeam)
+	c.Assert(err, check.IsNil)
+	for _, a := range []app.App{
+		{Name: "app1", Platform: "zend", TeamOwner: s.team.Name},
+		{Name: "app2", Platform: "zend", TeamOwner: subTeam.Name},
+		{Name: "app3", Platform: "zend", TeamOwner: otherTeam.Name},
+	} {
+		err = app.CreateApp(&a, s.user)
+		c.Assert(err, check.IsNil)
+	}
+	request, err := http.NewRequest("GET", fmt.Sprintf("/apps?teamOwner=%s&subTeams=true", s.team.Name), nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var apps []miniApp
+	err = json.Unmarshal(recorder.Body.Bytes(), &apps)
+	c.Assert(err, check.IsNil)
+	var names []string
+	for _, a := range apps {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	c.Assert(names, check.DeepEquals, []string{"app1", "app2"})
+}
+
 func (s *S) TestAppListFilteringByOwner(c *check.C) {
 	token := userWithPermission(c, permission.Permission{
 		Scheme:  permission.PermAppRead,
@@ -812,6 +930,96 @@ func (s *S) TestCreateAppWithDescription(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestCreateAppWithTags(c *check.C) {
+	data := "name=someapp&platform=zend&tag=frontend&tag=critical&tag=frontend"
+	request, err := http.NewRequest("POST", "/apps", strings.NewReader(data))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppCreate,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	var gotApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "someapp"}).One(&gotApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(gotApp.Tags, check.DeepEquals, []string{"frontend", "critical"})
+}
+
+func (s *S) TestCreateAppWithBind(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"DATABASE_USER":"root"}`))
+	}))
+	defer ts.Close()
+	srvc := service.Service{Name: "mysql", Endpoint: map[string]string{"production": ts.URL}}
+	err := srvc.Create()
+	c.Assert(err, check.IsNil)
+	instance := service.ServiceInstance{Name: "mydb", ServiceName: "mysql", Teams: []string{s.team.Name}}
+	err = instance.Create()
+	c.Assert(err, check.IsNil)
+	data := "name=someapp&platform=zend&bind=mysql/mydb"
+	request, err := http.NewRequest("POST", "/apps", strings.NewReader(data))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	err = s.conn.ServiceInstances().Find(bson.M{"name": instance.Name}).One(&instance)
+	c.Assert(err, check.IsNil)
+	c.Assert(instance.Apps, check.DeepEquals, []string{"someapp"})
+	var gotApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "someapp"}).One(&gotApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(gotApp.Env["DATABASE_USER"], check.DeepEquals, bind.EnvVar{Name: "DATABASE_USER", Value: "root", InstanceName: "mydb"})
+}
+
+func (s *S) TestCreateAppWithInvalidBind(c *check.C) {
+	data := "name=someapp&platform=zend&bind=mydb"
+	request, err := http.NewRequest("POST", "/apps", strings.NewReader(data))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "invalid service instance \"mydb\", it must be in the form <service>/<instance>\n")
+	count, err := s.conn.Apps().Find(bson.M{"name": "someapp"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
+
+func (s *S) TestCreateAppWithBindWithoutPermission(c *check.C) {
+	instance := service.ServiceInstance{Name: "mydb", ServiceName: "mysql", Teams: []string{"otherteam"}}
+	err := instance.Create()
+	c.Assert(err, check.IsNil)
+	data := "name=someapp&platform=zend&bind=mysql/mydb"
+	request, err := http.NewRequest("POST", "/apps", strings.NewReader(data))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppCreate,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppUpdateBind,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	count, err := s.conn.Apps().Find(bson.M{"name": "someapp"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
+
 func (s *S) TestCreateAppWithPool(c *check.C) {
 	err := s.conn.Pools().Insert(bson.M{"_id": "mypool1", "public": true})
 	c.Assert(err, check.IsNil)
@@ -949,6 +1157,26 @@ func (s *S) TestCreateAppQuotaExceeded(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestCreateAppPoolWithoutAccess(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "prod"})
+	c.Assert(err, check.IsNil)
+	defer provision.RemovePool("prod")
+	err = provision.AddTeamsToPool("prod", []string{"otherteam"})
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("name=someapp&platform=zend&pool=prod&teamOwner=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/apps", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	c.Assert(recorder.Body.String(), check.Equals, `App team owner "`+s.team.Name+`" has no access to pool "prod"`+"\n")
+	_, err = app.GetByName("someapp")
+	c.Assert(err, check.Equals, app.ErrAppNotFound)
+}
+
 func (s *S) TestCreateAppInvalidName(c *check.C) {
 	b := strings.NewReader("name=123myapp&platform=zend")
 	request, err := http.NewRequest("POST", "/apps", b)
@@ -1116,6 +1344,29 @@ func (s *S) TestUpdateAppWithDescriptionOnly(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestUpdateAppWithTagsOnly(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name, Tags: []string{"old"}}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateTags,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	b := strings.NewReader("tag=frontend&tag=critical")
+	request, err := http.NewRequest("PUT", "/apps/myapp", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var gotApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "myapp"}).One(&gotApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(gotApp.Tags, check.DeepEquals, []string{"frontend", "critical"})
+}
+
 func (s *S) TestUpdateAppWithPoolOnly(c *check.C) {
 	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
@@ -1243,7 +1494,7 @@ func (s *S) TestUpdateAppWithoutFlag(c *check.C) {
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
-	errorMessage := "Neither the description, plan, pool or team owner were set. You must define at least one.\n"
+	errorMessage := "Neither the description, tags, plan, pool or team owner were set. You must define at least one.\n"
 	c.Check(recorder.Code, check.Equals, http.StatusBadRequest)
 	c.Check(recorder.Body.String(), check.Equals, errorMessage)
 }
@@ -1372,6 +1623,102 @@ func (s *S) TestAddUnits(c *check.C) {
 	c.Assert(recorder.Body.String(), check.Equals, `{"Message":"added 3 units"}`+"\n")
 }
 
+func (s *S) TestAddUnitsTotal(c *check.C) {
+	a := app.App{Name: "armorandsword", Platform: "zend", TeamOwner: s.team.Name, Quota: quota.Unlimited}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 1, "web", nil)
+	s.provisioner.AddUnits(&a, 2, "worker", nil)
+	body := strings.NewReader("total=3&process=web")
+	request, err := http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, `{"Message":"added 2 units"}`+"\n")
+	n, err := a.CountUnits("web")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(3))
+	c.Assert(eventtest.EventDesc{
+		Target:          appTarget("armorandsword"),
+		Owner:           s.token.GetUserName(),
+		Kind:            "app.update.unit.add",
+		StartCustomData: []map[string]interface{}{{"name": "total", "value": "3"}, {"name": "process", "value": "web"}, {"name": ":app", "value": "armorandsword"}},
+	}, eventtest.HasEvent)
+	body = strings.NewReader("total=1&process=worker")
+	request, err = http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	n, err = a.CountUnits("worker")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(1))
+	c.Assert(eventtest.EventDesc{
+		Target:          appTarget("armorandsword"),
+		Owner:           s.token.GetUserName(),
+		Kind:            "app.update.unit.remove",
+		StartCustomData: []map[string]interface{}{{"name": "total", "value": "1"}, {"name": "process", "value": "worker"}, {"name": ":app", "value": "armorandsword"}},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddUnitsTotalNothingToDo(c *check.C) {
+	a := app.App{Name: "armorandsword", Platform: "zend", TeamOwner: s.team.Name, Quota: quota.Unlimited}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 2, "web", nil)
+	body := strings.NewReader("total=2&process=web")
+	request, err := http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, `{"Message":"App already has 2 units, nothing to do.\n"}`+"\n")
+}
+
+func (s *S) TestAddUnitsTotalInvalid(c *check.C) {
+	body := strings.NewReader("total=-1&process=web")
+	request, err := http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Invalid number of units: the total must be a non-negative integer.\n")
+}
+
+func (s *S) TestAddUnitsTotalWithoutProcessInMultiProcessApp(c *check.C) {
+	a := app.App{Name: "armorandsword", Platform: "zend", TeamOwner: s.team.Name, Quota: quota.Unlimited}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "tsuru/app-armorandsword:v1")
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("tsuru/app-armorandsword:v1", map[string]interface{}{
+		"processes": map[string]interface{}{"web": "python web.py", "worker": "python worker.py"},
+	})
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("total=3")
+	request, err := http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "the process is required, app \"armorandsword\" has multiple processes\n")
+}
+
 func (s *S) TestAddUnitsReturns404IfAppDoesNotExist(c *check.C) {
 	body := strings.NewReader("units=1&process=web")
 	request, err := http.NewRequest("PUT", "/apps/armorandsword/units?:app=armorandsword", body)
@@ -1477,7 +1824,12 @@ func (s *S) TestAddUnitsQuotaExceeded(c *check.C) {
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
-	c.Assert(recorder.Body.String(), check.Equals, `Quota exceeded. Available: 2. Requested: 3.`+"\n")
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	var msg tsuruIo.SimpleJsonMessage
+	err = json.Unmarshal(recorder.Body.Bytes(), &msg)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Error, check.Equals, `Quota exceeded. Available: 2. Requested: 3.`)
+	c.Assert(msg.Quota, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 2})
 	c.Assert(eventtest.EventDesc{
 		Target:          appTarget("armorandsword"),
 		Owner:           s.token.GetUserName(),
@@ -1810,13 +2162,70 @@ func (s *S) TestAddTeamToTheApp(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
-func (s *S) TestGrantAccessToTeamReturn404IfTheAppDoesNotExist(c *check.C) {
-	request, err := http.NewRequest("PUT", "/apps/a/teams/b", nil)
+func (s *S) TestListAppAccess(c *check.C) {
+	t := auth.Team{Name: "itshardteam"}
+	err := s.conn.Teams().Insert(t)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Teams().RemoveAll(bson.M{"_id": t.Name})
+	parent := auth.Team{Name: "itshardparent"}
+	err = s.conn.Teams().Insert(parent)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Teams().RemoveAll(bson.M{"_id": parent.Name})
+	err = auth.SetTeamParent(s.team.Name, parent.Name)
+	c.Assert(err, check.IsNil)
+	a := app.App{Name: "itshard", Platform: "zend", TeamOwner: t.Name}
+	err = app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	recorder := httptest.NewRecorder()
-	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	handler := RunServer(true)
-	handler.ServeHTTP(recorder, request)
+	url := fmt.Sprintf("/apps/%s/teams/%s", a.Name, s.team.Name)
+	request, err := http.NewRequest("PUT", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	request, err = http.NewRequest("GET", "/apps/"+a.Name+"/teams", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var access []app.TeamAccess
+	err = json.NewDecoder(recorder.Body).Decode(&access)
+	c.Assert(err, check.IsNil)
+	c.Assert(access, check.HasLen, 3)
+	c.Assert(access[0], check.DeepEquals, app.TeamAccess{Team: t.Name, Owner: true})
+	c.Assert(access[1].Team, check.Equals, s.team.Name)
+	c.Assert(access[1].GrantedBy, check.Equals, s.token.GetUserName())
+	c.Assert(access[1].GrantedAt.IsZero(), check.Equals, false)
+	c.Assert(access[2], check.DeepEquals, app.TeamAccess{Team: parent.Name, InheritedFrom: s.team.Name})
+}
+
+func (s *S) TestListAppAccessForbidden(c *check.C) {
+	a := app.App{Name: "itshard", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppRead,
+		Context: permission.Context(permission.CtxApp, "-invalid-"),
+	})
+	request, err := http.NewRequest("GET", "/apps/"+a.Name+"/teams", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	handler := RunServer(true)
+	handler.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestGrantAccessToTeamReturn404IfTheAppDoesNotExist(c *check.C) {
+	request, err := http.NewRequest("PUT", "/apps/a/teams/b", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	handler := RunServer(true)
+	handler.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
 	c.Assert(recorder.Body.String(), check.Equals, "App a not found.\n")
 }
@@ -2129,6 +2538,74 @@ func (s *S) TestRunOnce(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestRunInUnits(c *check.C) {
+	s.provisioner.PrepareOutput([]byte("lots of files"))
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 3, "web", nil)
+	url := fmt.Sprintf("/apps/%s/run", a.Name)
+	request, err := http.NewRequest("POST", url, strings.NewReader("command=ls&unit=secrets-1"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, `{"Message":"lots of files"}`+"\n")
+	cmds := s.provisioner.GetCmds("", &a)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Units, check.DeepEquals, []string{"secrets-1"})
+}
+
+func (s *S) TestRunUnitsPercentage(c *check.C) {
+	s.provisioner.PrepareOutput([]byte("lots of files"))
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 2, "web", nil)
+	url := fmt.Sprintf("/apps/%s/run", a.Name)
+	request, err := http.NewRequest("POST", url, strings.NewReader("command=ls&units-percentage=50"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	cmds := s.provisioner.GetCmds("", &a)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Units, check.HasLen, 1)
+}
+
+func (s *S) TestRunInvalidUnitsSelection(c *check.C) {
+	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		body string
+		msg  string
+	}{
+		{"command=ls&units-percentage=0", "units-percentage must be a number between 1 and 100\n"},
+		{"command=ls&units-percentage=abc", "units-percentage must be a number between 1 and 100\n"},
+		{"command=ls&once=true&unit=secrets-0", "only one of once, isolated, unit and units-percentage may be used\n"},
+		{"command=ls&unit=secrets-0&units-percentage=10", "only one of once, isolated, unit and units-percentage may be used\n"},
+	}
+	url := fmt.Sprintf("/apps/%s/run", a.Name)
+	for _, tt := range tests {
+		request, err := http.NewRequest("POST", url, strings.NewReader(tt.body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+		c.Assert(recorder.Body.String(), check.Equals, tt.msg)
+	}
+}
+
 func (s *S) TestRun(c *check.C) {
 	s.provisioner.PrepareOutput([]byte("lots of\nfiles"))
 	a := app.App{Name: "secrets", Platform: "zend", TeamOwner: s.team.Name}
@@ -2748,6 +3225,66 @@ func (s *S) TestSetEnvHandlerNoRestart(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestSetEnvHandlerSecret(c *check.C) {
+	config.Set("envs:secret-key", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	defer config.Unset("envs:secret-key")
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("Envs.0.Name=DATABASE_PASSWORD&Envs.0.Value=secret&Secret=true")
+	request, err := http.NewRequest("POST", "/apps/black-dog/env", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName("black-dog")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Env["DATABASE_PASSWORD"].Secret, check.Equals, true)
+	c.Assert(dbApp.Envs()["DATABASE_PASSWORD"].Value, check.Equals, "secret")
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.env.set",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":app", "value": a.Name},
+			{"name": "Envs.0.Name", "value": "DATABASE_PASSWORD"},
+			{"name": "Envs.0.Value", "value": app.SecretEnvMask},
+			{"name": "Secret", "value": "true"},
+		},
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("GET", "/apps/black-dog/env?env=DATABASE_PASSWORD", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var envs []bind.EnvVar
+	err = json.NewDecoder(recorder.Body).Decode(&envs)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, []bind.EnvVar{
+		{Name: "DATABASE_PASSWORD", Value: app.SecretEnvMask, Public: false, Secret: true},
+	})
+}
+
+func (s *S) TestSetEnvHandlerSecretWithoutKey(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("Envs.0.Name=DATABASE_PASSWORD&Envs.0.Value=secret&Secret=true")
+	request, err := http.NewRequest("POST", "/apps/black-dog/env", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, app.ErrSecretEnvsDisabled.Error()+"\n")
+}
+
 func (s *S) TestSetEnvMissingFormBody(c *check.C) {
 	a := app.App{Name: "rock", Platform: "zend"}
 	err := s.conn.Apps().Insert(a)
@@ -3046,155 +3583,650 @@ func (s *S) TestUnsetEnvUserDoesNotHaveAccessToTheApp(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
 }
 
-func (s *S) TestAddCName(c *check.C) {
-	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+func (s *S) TestEnvHistory(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	url := fmt.Sprintf("/apps/%s/cname", a.Name)
-	b := strings.NewReader("cname=leper.secretcompany.com&cname=blog.tsuru.com")
-	request, err := http.NewRequest("POST", url, b)
+	b := strings.NewReader("Envs.0.Name=DATABASE_HOST&Envs.0.Value=localhost&NoRestart=true")
+	request, err := http.NewRequest("POST", "/apps/black-dog/env", b)
 	c.Assert(err, check.IsNil)
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusOK)
-	app, err := app.GetByName(a.Name)
+	request, err = http.NewRequest("GET", "/apps/black-dog/env/history", nil)
 	c.Assert(err, check.IsNil)
-	c.Assert(app.CName, check.DeepEquals, []string{"leper.secretcompany.com", "blog.tsuru.com"})
-	c.Assert(eventtest.EventDesc{
-		Target: appTarget(a.Name),
-		Owner:  s.token.GetUserName(),
-		Kind:   "app.update.cname.add",
-		StartCustomData: []map[string]interface{}{
-			{"name": "cname", "value": []interface{}{"leper.secretcompany.com", "blog.tsuru.com"}},
-			{"name": ":app", "value": "leper"},
-		},
-	}, eventtest.HasEvent)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Not(check.Matches), "(?s).*localhost.*")
+	var changes []app.EnvChange
+	err = json.NewDecoder(recorder.Body).Decode(&changes)
+	c.Assert(err, check.IsNil)
+	c.Assert(changes, check.HasLen, 1)
+	c.Assert(changes[0].Name, check.Equals, "DATABASE_HOST")
+	c.Assert(changes[0].Action, check.Equals, app.EnvChangeSet)
+	c.Assert(changes[0].Owner, check.Equals, s.token.GetUserName())
+	c.Assert(changes[0].NewHash, check.Not(check.Equals), "")
 }
 
-func (s *S) TestAddCNameAcceptsWildCard(c *check.C) {
-	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+func (s *S) TestEnvHistoryInvalidLimit(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	url := fmt.Sprintf("/apps/%s/cname", a.Name)
-	b := strings.NewReader("cname=*.leper.secretcompany.com")
-	request, err := http.NewRequest("POST", url, b)
+	request, err := http.NewRequest("GET", "/apps/black-dog/env/history?limit=x", nil)
 	c.Assert(err, check.IsNil)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
-	request.Header.Set("Authorization", "b "+s.token.GetValue())
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
-	c.Assert(recorder.Code, check.Equals, http.StatusOK)
-	app, err := app.GetByName(a.Name)
-	c.Assert(err, check.IsNil)
-	c.Assert(app.CName, check.DeepEquals, []string{"*.leper.secretcompany.com"})
-	c.Assert(eventtest.EventDesc{
-		Target: appTarget(a.Name),
-		Owner:  s.token.GetUserName(),
-		Kind:   "app.update.cname.add",
-		StartCustomData: []map[string]interface{}{
-			{"name": "cname", "value": "*.leper.secretcompany.com"},
-			{"name": ":app", "value": "leper"},
-		},
-	}, eventtest.HasEvent)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
 }
 
-func (s *S) TestAddCNameErrsOnInvalidCName(c *check.C) {
-	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+func (s *S) TestEnvDiffBetweenApps(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name, Env: map[string]bind.EnvVar{
+		"DATABASE_HOST": {Name: "DATABASE_HOST", Value: "localhost"},
+	}}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	url := fmt.Sprintf("/apps/%s/cname", a.Name)
-	b := strings.NewReader("cname=_leper.secretcompany.com")
-	request, err := http.NewRequest("POST", url, b)
+	other := app.App{Name: "white-dog", Platform: "zend", TeamOwner: s.team.Name, Env: map[string]bind.EnvVar{
+		"DATABASE_HOST": {Name: "DATABASE_HOST", Value: "db.tsuru.io"},
+	}}
+	err = app.CreateApp(&other, s.user)
 	c.Assert(err, check.IsNil)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request, err := http.NewRequest("GET", "/apps/black-dog/env/diff?app=white-dog", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
-	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
-	c.Assert(recorder.Body.String(), check.Equals, "Invalid cname\n")
-	c.Assert(eventtest.EventDesc{
-		Target:       appTarget(a.Name),
-		Owner:        s.token.GetUserName(),
-		Kind:         "app.update.cname.add",
-		ErrorMatches: "Invalid cname",
-		StartCustomData: []map[string]interface{}{
-			{"name": "cname", "value": "_leper.secretcompany.com"},
-			{"name": ":app", "value": "leper"},
-		},
-	}, eventtest.HasEvent)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var diffs []app.EnvDiff
+	err = json.NewDecoder(recorder.Body).Decode(&diffs)
+	c.Assert(err, check.IsNil)
+	c.Assert(diffs, check.HasLen, 1)
+	c.Assert(diffs[0].Name, check.Equals, "DATABASE_HOST")
+	c.Assert(diffs[0].Status, check.Equals, app.EnvDiffChanged)
 }
 
-func (s *S) TestAddCNameHandlerReturnsBadRequestWhenCNameIsEmpty(c *check.C) {
-	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+func (s *S) TestEnvDiffInvalidTime(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	request, err := http.NewRequest("POST", "/apps/leper/cname", strings.NewReader("cname="))
+	request, err := http.NewRequest("GET", "/apps/black-dog/env/diff?from=yesterday", nil)
 	c.Assert(err, check.IsNil)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
-	c.Assert(recorder.Body.String(), check.Equals, "Invalid cname\n")
-	c.Assert(eventtest.EventDesc{
-		Target:       appTarget(a.Name),
-		Owner:        s.token.GetUserName(),
-		Kind:         "app.update.cname.add",
-		ErrorMatches: "Invalid cname",
-		StartCustomData: []map[string]interface{}{
-			{"name": "cname", "value": ""},
-			{"name": ":app", "value": "leper"},
-		},
-	}, eventtest.HasEvent)
+	c.Assert(recorder.Body.String(), check.Equals, "invalid value for from, expected RFC 3339 time: yesterday\n")
 }
 
-func (s *S) TestAddCNameHandlerReturnsBadRequestWhenCNameIsMissing(c *check.C) {
-	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+func (s *S) TestExportEnv(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name, Env: map[string]bind.EnvVar{
+		"DATABASE_HOST": {Name: "DATABASE_HOST", Value: "localhost", Public: true},
+		"TOKEN":         {Name: "TOKEN", Value: "abc"},
+	}}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	bodies := []io.Reader{nil, strings.NewReader("")}
-	for _, b := range bodies {
-		request, err := http.NewRequest("POST", "/apps/leper/cname", b)
-		c.Assert(err, check.IsNil)
-		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		request.Header.Set("Authorization", "b "+s.token.GetValue())
-		recorder := httptest.NewRecorder()
-		m := RunServer(true)
-		m.ServeHTTP(recorder, request)
-		c.Check(recorder.Code, check.Equals, http.StatusBadRequest)
-		c.Check(recorder.Body.String(), check.Equals, "You must provide the cname.\n")
-	}
-}
-
-func (s *S) TestAddCNameHandlerUnknownApp(c *check.C) {
-	b := strings.NewReader("cname=leper.secretcompany.com")
-	request, err := http.NewRequest("POST", "/apps/unknown/cname", b)
+	request, err := http.NewRequest("GET", "/apps/black-dog/env/export?redact=true", nil)
 	c.Assert(err, check.IsNil)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
 	m := RunServer(true)
 	m.ServeHTTP(recorder, request)
-	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "text/plain")
+	c.Assert(recorder.Body.String(), check.Equals, "DATABASE_HOST=localhost\nTOKEN=*****\n")
+	request, err = http.NewRequest("GET", "/apps/black-dog/env/export?format=json", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var envs map[string]string
+	err = json.NewDecoder(recorder.Body).Decode(&envs)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, map[string]string{"DATABASE_HOST": "localhost", "TOKEN": "abc"})
 }
 
-func (s *S) TestAddCNameHandlerUserWithoutAccessToTheApp(c *check.C) {
-	a := app.App{Name: "lost", Platform: "vougan", TeamOwner: s.team.Name}
+func (s *S) TestExportEnvInvalidFormat(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
-	defer s.logConn.Logs(a.Name).DropCollection()
-	url := fmt.Sprintf("/apps/%s/cname", a.Name)
-	b := strings.NewReader("cname=lost.secretcompany.com")
-	request, err := http.NewRequest("POST", url, b)
+	request, err := http.NewRequest("GET", "/apps/black-dog/env/export?format=yaml", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestImportEnv(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := url.Values{"envs": []string{"DATABASE_HOST=localhost\nDATABASE_USER=root\n"}, "noRestart": []string{"true"}}
+	request, err := http.NewRequest("POST", "/apps/black-dog/env/import", strings.NewReader(body.Encode()))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Env["DATABASE_HOST"], check.DeepEquals, bind.EnvVar{Name: "DATABASE_HOST", Value: "localhost", Public: true})
+	c.Assert(dbApp.Env["DATABASE_USER"], check.DeepEquals, bind.EnvVar{Name: "DATABASE_USER", Value: "root", Public: true})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.env.set",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":app", "value": a.Name},
+			{"name": "noRestart", "value": "true"},
+			{"name": "env", "value": []interface{}{"DATABASE_HOST", "DATABASE_USER"}},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestImportEnvInvalidFile(c *check.C) {
+	a := app.App{Name: "black-dog", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := url.Values{"envs": []string{"DATABASE_HOST=localhost\nINVALID\n"}}
+	request, err := http.NewRequest("POST", "/apps/black-dog/env/import", strings.NewReader(body.Encode()))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "line 2: expected NAME=value\n")
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	_, ok := dbApp.Env["DATABASE_HOST"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestAddCName(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/cname", a.Name)
+	b := strings.NewReader("cname=leper.secretcompany.com&cname=blog.tsuru.com")
+	request, err := http.NewRequest("POST", url, b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	app, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(app.CName, check.DeepEquals, []string{"leper.secretcompany.com", "blog.tsuru.com"})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.cname.add",
+		StartCustomData: []map[string]interface{}{
+			{"name": "cname", "value": []interface{}{"leper.secretcompany.com", "blog.tsuru.com"}},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestMoveCName(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-new", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddCName("leper.secretcompany.com", "blog.tsuru.com")
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("cname=leper.secretcompany.com&destination=leper-new")
+	request, err := http.NewRequest("POST", "/apps/leper/cname/move", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	src, err := app.GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(src.CName, check.DeepEquals, []string{"blog.tsuru.com"})
+	dst, err := app.GetByName(a2.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dst.CName, check.DeepEquals, []string{"leper.secretcompany.com"})
+	c.Assert(routertest.FakeRouter.HasCNameFor(a2.Name, "leper.secretcompany.com"), check.Equals, true)
+	c.Assert(routertest.FakeRouter.HasCNameFor(a1.Name, "leper.secretcompany.com"), check.Equals, false)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a1.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.cname.remove",
+	}, eventtest.HasEvent)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a2.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.cname.add",
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestMoveCNameNotFound(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-new", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("cname=leper.secretcompany.com&destination=leper-new")
+	request, err := http.NewRequest("POST", "/apps/leper/cname/move", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestMoveCNameRequiresPermissionInDestination(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-new", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddCName("leper.secretcompany.com")
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateCnameRemove,
+		Context: permission.Context(permission.CtxApp, a1.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppUpdateCnameAdd,
+		Context: permission.Context(permission.CtxApp, a1.Name),
+	})
+	b := strings.NewReader("cname=leper.secretcompany.com&destination=leper-new")
+	request, err := http.NewRequest("POST", "/apps/leper/cname/move", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	src, err := app.GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(src.CName, check.DeepEquals, []string{"leper.secretcompany.com"})
+}
+
+func (s *S) TestMoveCNameWithoutPermissionDoesNotLockDestination(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-new", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddCName("leper.secretcompany.com")
+	c.Assert(err, check.IsNil)
+	locked, err := app.AcquireApplicationLock(a2.Name, "someone", "deploy")
+	c.Assert(err, check.IsNil)
+	c.Assert(locked, check.Equals, true)
+	defer app.ReleaseApplicationLock(a2.Name)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateCnameRemove,
+		Context: permission.Context(permission.CtxApp, a1.Name),
+	})
+	b := strings.NewReader("cname=leper.secretcompany.com&destination=leper-new")
+	request, err := http.NewRequest("POST", "/apps/leper/cname/move", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestAddAppDependency(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-db", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("dependency=leper-db")
+	request, err := http.NewRequest("POST", "/apps/leper/dependencies", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Dependencies, check.DeepEquals, []string{"leper-db"})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a1.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.dependency",
+		StartCustomData: []map[string]interface{}{
+			{"name": "dependency", "value": "leper-db"},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddAppDependencyCycle(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-db", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a2.AddDependency(a1.Name)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("dependency=leper-db")
+	request, err := http.NewRequest("POST", "/apps/leper/dependencies", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), check.Matches, "dependency cycle detected: leper -> leper-db -> leper\n")
+}
+
+func (s *S) TestAddAppDependencyNotFound(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("dependency=leper-db")
+	request, err := http.NewRequest("POST", "/apps/leper/dependencies", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestRemoveAppDependency(c *check.C) {
+	a1 := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "leper-db", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddDependency(a2.Name)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/apps/leper/dependencies/leper-db", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Dependencies, check.HasLen, 0)
+	request, err = http.NewRequest("DELETE", "/apps/leper/dependencies/leper-db", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestCloneApp(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs: []bind.EnvVar{{Name: "DATABASE_HOST", Value: "localhost", Public: true}},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("name=leper-clone")
+	request, err := http.NewRequest("POST", "/apps/leper/clone", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	clone, err := app.GetByName("leper-clone")
+	c.Assert(err, check.IsNil)
+	c.Assert(clone.Platform, check.Equals, "zend")
+	c.Assert(clone.TeamOwner, check.Equals, s.team.Name)
+	c.Assert(clone.Envs()["DATABASE_HOST"].Value, check.Equals, "localhost")
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("leper-clone"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.create",
+		StartCustomData: []map[string]interface{}{
+			{"name": "name", "value": "leper-clone"},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestCloneAppAlreadyExists(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("name=leper")
+	request, err := http.NewRequest("POST", "/apps/leper/clone", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestCloneAppWithoutName(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/apps/leper/clone", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "You must provide the name of the new app.\n")
+}
+
+func (s *S) TestCloneAppRequiresCreatePermission(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadEnv,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	b := strings.NewReader("name=leper-clone")
+	request, err := http.NewRequest("POST", "/apps/leper/clone", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	_, err = app.GetByName("leper-clone")
+	c.Assert(err, check.Equals, app.ErrAppNotFound)
+}
+
+func (s *S) TestCloneAppRequiresBindPermission(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	instance := service.ServiceInstance{Name: "mydb", ServiceName: "mysql", Teams: []string{"otherteam"}, Apps: []string{a.Name}}
+	err = instance.Create()
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadEnv,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppCreate,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppUpdateBind,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	b := strings.NewReader("name=leper-clone")
+	request, err := http.NewRequest("POST", "/apps/leper/clone", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	_, err = app.GetByName("leper-clone")
+	c.Assert(err, check.Equals, app.ErrAppNotFound)
+}
+
+func (s *S) TestAddCNameAcceptsWildCard(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/cname", a.Name)
+	b := strings.NewReader("cname=*.leper.secretcompany.com")
+	request, err := http.NewRequest("POST", url, b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	app, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(app.CName, check.DeepEquals, []string{"*.leper.secretcompany.com"})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.cname.add",
+		StartCustomData: []map[string]interface{}{
+			{"name": "cname", "value": "*.leper.secretcompany.com"},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddCNameErrsOnInvalidCName(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/cname", a.Name)
+	b := strings.NewReader("cname=_leper.secretcompany.com")
+	request, err := http.NewRequest("POST", url, b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Invalid cname\n")
+	c.Assert(eventtest.EventDesc{
+		Target:       appTarget(a.Name),
+		Owner:        s.token.GetUserName(),
+		Kind:         "app.update.cname.add",
+		ErrorMatches: "Invalid cname",
+		StartCustomData: []map[string]interface{}{
+			{"name": "cname", "value": "_leper.secretcompany.com"},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddCNameHandlerReturnsBadRequestWhenCNameIsEmpty(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/apps/leper/cname", strings.NewReader("cname="))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Invalid cname\n")
+	c.Assert(eventtest.EventDesc{
+		Target:       appTarget(a.Name),
+		Owner:        s.token.GetUserName(),
+		Kind:         "app.update.cname.add",
+		ErrorMatches: "Invalid cname",
+		StartCustomData: []map[string]interface{}{
+			{"name": "cname", "value": ""},
+			{"name": ":app", "value": "leper"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddCNameHandlerReturnsBadRequestWhenCNameIsMissing(c *check.C) {
+	a := app.App{Name: "leper", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	bodies := []io.Reader{nil, strings.NewReader("")}
+	for _, b := range bodies {
+		request, err := http.NewRequest("POST", "/apps/leper/cname", b)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Check(recorder.Code, check.Equals, http.StatusBadRequest)
+		c.Check(recorder.Body.String(), check.Equals, "You must provide the cname.\n")
+	}
+}
+
+func (s *S) TestAddCNameHandlerUnknownApp(c *check.C) {
+	b := strings.NewReader("cname=leper.secretcompany.com")
+	request, err := http.NewRequest("POST", "/apps/unknown/cname", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestAddCNameHandlerUserWithoutAccessToTheApp(c *check.C) {
+	a := app.App{Name: "lost", Platform: "vougan", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
+	defer s.logConn.Logs(a.Name).DropCollection()
+	url := fmt.Sprintf("/apps/%s/cname", a.Name)
+	b := strings.NewReader("cname=lost.secretcompany.com")
+	request, err := http.NewRequest("POST", url, b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	token := userWithPermission(c, permission.Permission{
 		Scheme:  permission.PermAppUpdateCname,
 		Context: permission.Context(permission.CtxApp, "-invalid-"),
@@ -3487,37 +4519,91 @@ func (s *S) TestAppLogSelectByLines(c *check.C) {
 	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	for i := 0; i < 15; i++ {
-		a.Log(strconv.Itoa(i), "source", "")
-	}
+	for i := 0; i < 15; i++ {
+		a.Log(strconv.Itoa(i), "source", "")
+	}
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadLog,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=10", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = appLog(recorder, request, token)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	logs := []app.Applog{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 10)
+}
+
+func (s *S) TestAppLogSelectBySource(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Log("mars log", "mars", "")
+	a.Log("earth log", "earth", "")
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadLog,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&source=mars&lines=10", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	request.Header.Set("Content-Type", "application/json")
+	err = appLog(recorder, request, token)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	logs := []app.Applog{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "mars log")
+	c.Assert(logs[0].Source, check.Equals, "mars")
+}
+
+func (s *S) TestAppLogSelectByUnit(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Log("mars log", "mars", "prospero")
+	a.Log("earth log", "earth", "caliban")
 	token := userWithPermission(c, permission.Permission{
 		Scheme:  permission.PermAppReadLog,
 		Context: permission.Context(permission.CtxTeam, s.team.Name),
 	})
-	url := fmt.Sprintf("/apps/%s/log/?:app=%s&lines=10", a.Name, a.Name)
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&unit=caliban&lines=10", a.Name, a.Name)
 	request, err := http.NewRequest("GET", url, nil)
 	c.Assert(err, check.IsNil)
 	recorder := httptest.NewRecorder()
+	request.Header.Set("Content-Type", "application/json")
 	err = appLog(recorder, request, token)
 	c.Assert(err, check.IsNil)
 	c.Assert(recorder.Code, check.Equals, http.StatusOK)
 	logs := []app.Applog{}
 	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
 	c.Assert(err, check.IsNil)
-	c.Assert(logs, check.HasLen, 10)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "earth log")
+	c.Assert(logs[0].Source, check.Equals, "earth")
+	c.Assert(logs[0].Unit, check.Equals, "caliban")
 }
 
-func (s *S) TestAppLogSelectBySource(c *check.C) {
+func (s *S) TestAppLogSelectByLevel(c *check.C) {
 	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	a.Log("mars log", "mars", "")
-	a.Log("earth log", "earth", "")
+	a.Log(`{"level":"info","msg":"started"}`, "app", "prospero")
+	a.Log(`{"level":"ERROR","msg":"failed"}`, "app", "prospero")
+	a.Log("plain text", "app", "prospero")
 	token := userWithPermission(c, permission.Permission{
 		Scheme:  permission.PermAppReadLog,
 		Context: permission.Context(permission.CtxTeam, s.team.Name),
 	})
-	url := fmt.Sprintf("/apps/%s/log/?:app=%s&source=mars&lines=10", a.Name, a.Name)
+	url := fmt.Sprintf("/apps/%s/log/?:app=%s&level=error&lines=10", a.Name, a.Name)
 	request, err := http.NewRequest("GET", url, nil)
 	c.Assert(err, check.IsNil)
 	recorder := httptest.NewRecorder()
@@ -3529,35 +4615,169 @@ func (s *S) TestAppLogSelectBySource(c *check.C) {
 	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
 	c.Assert(err, check.IsNil)
 	c.Assert(logs, check.HasLen, 1)
-	c.Assert(logs[0].Message, check.Equals, "mars log")
-	c.Assert(logs[0].Source, check.Equals, "mars")
+	c.Assert(logs[0].Message, check.Equals, `{"level":"ERROR","msg":"failed"}`)
+	c.Assert(logs[0].Level, check.Equals, "error")
 }
 
-func (s *S) TestAppLogSelectByUnit(c *check.C) {
+func (s *S) TestAppLogSelectByTime(c *check.C) {
 	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
 	err := app.CreateApp(&a, s.user)
 	c.Assert(err, check.IsNil)
-	a.Log("mars log", "mars", "prospero")
-	a.Log("earth log", "earth", "caliban")
-	token := userWithPermission(c, permission.Permission{
-		Scheme:  permission.PermAppReadLog,
-		Context: permission.Context(permission.CtxTeam, s.team.Name),
-	})
-	url := fmt.Sprintf("/apps/%s/log/?:app=%s&unit=caliban&lines=10", a.Name, a.Name)
+	logConn, err := db.LogConn()
+	c.Assert(err, check.IsNil)
+	defer logConn.Close()
+	base := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	for i, msg := range []string{"first", "second", "third"} {
+		err = logConn.Logs(a.Name).Insert(app.Applog{
+			Date:    base.Add(time.Duration(i) * time.Hour),
+			Message: msg,
+			Source:  "web",
+			AppName: a.Name,
+			Unit:    "caliban",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	url := fmt.Sprintf("/apps/%s/log?lines=10&since=2016-12-20T11:00:00Z&until=2016-12-20T11:30:00Z", a.Name)
 	request, err := http.NewRequest("GET", url, nil)
 	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
 	recorder := httptest.NewRecorder()
-	request.Header.Set("Content-Type", "application/json")
-	err = appLog(recorder, request, token)
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	logs := []app.Applog{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "second")
+}
+
+func (s *S) TestAppLogAfter(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	logConn, err := db.LogConn()
+	c.Assert(err, check.IsNil)
+	defer logConn.Close()
+	base := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	for i, msg := range []string{"first", "second", "third"} {
+		err = logConn.Logs(a.Name).Insert(app.Applog{
+			Date:    base.Add(time.Duration(i) * time.Minute),
+			Message: msg,
+			Source:  "web",
+			AppName: a.Name,
+			Unit:    "caliban",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	url := fmt.Sprintf("/apps/%s/log?lines=10&after=2016-12-20T10:01:00Z", a.Name)
+	request, err := http.NewRequest("GET", url, nil)
 	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusOK)
 	logs := []app.Applog{}
 	err = json.Unmarshal(recorder.Body.Bytes(), &logs)
 	c.Assert(err, check.IsNil)
 	c.Assert(logs, check.HasLen, 1)
-	c.Assert(logs[0].Message, check.Equals, "earth log")
-	c.Assert(logs[0].Source, check.Equals, "earth")
-	c.Assert(logs[0].Unit, check.Equals, "caliban")
+	c.Assert(logs[0].Message, check.Equals, "third")
+}
+
+func (s *S) TestAppLogFollowKeepAlive(c *check.C) {
+	oldInterval := logKeepAliveInterval
+	logKeepAliveInterval = 100 * time.Millisecond
+	defer func() {
+		logKeepAliveInterval = oldInterval
+	}()
+	a := app.App{Name: "lost3", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/something/log/?:app="+a.Name+"&lines=10&follow=1", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logErr := appLog(recorder, request, s.token)
+		c.Assert(logErr, check.IsNil)
+	}()
+	time.Sleep(350 * time.Millisecond)
+	logTracker.Lock()
+	for l := range logTracker.conn {
+		l.Close()
+	}
+	logTracker.Unlock()
+	<-done
+	splitted := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	c.Assert(len(splitted) > 2, check.Equals, true)
+	for _, line := range splitted {
+		c.Assert(line, check.Equals, "[]")
+	}
+}
+
+func (s *S) TestAppLogInvalidTime(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/log?lines=10&since=yesterday", a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "invalid value for since, expected RFC 3339 time: yesterday\n")
+}
+
+func (s *S) TestAppLogSelectByMessage(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	a.Log("GET /healthcheck 200", "app", "prospero")
+	a.Log("GET /users 500", "app", "prospero")
+	a.Log("POST /users 201", "app", "prospero")
+	m := RunServer(true)
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"message=%2Fusers", []string{"GET /users 500", "POST /users 201"}},
+		{"message=get&ignore-case=true", []string{"GET /healthcheck 200", "GET /users 500"}},
+		{"regex=%5EGET.%2A5%5Cd%5Cd%24", []string{"GET /users 500"}},
+	}
+	for _, tt := range tests {
+		request, err := http.NewRequest("GET", fmt.Sprintf("/apps/%s/log?lines=10&source=app&%s", a.Name, tt.query), nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+		logs := []app.Applog{}
+		err = json.Unmarshal(recorder.Body.Bytes(), &logs)
+		c.Assert(err, check.IsNil)
+		var messages []string
+		for _, l := range logs {
+			messages = append(messages, l.Message)
+		}
+		c.Check(messages, check.DeepEquals, tt.expected, check.Commentf("query: %s", tt.query))
+	}
+}
+
+func (s *S) TestAppLogSelectByMessageInvalid(c *check.C) {
+	a := app.App{Name: "lost", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", fmt.Sprintf("/apps/%s/log?lines=10&regex=%%28unclosed", a.Name), nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Matches, "invalid regular expression: .*\n")
 }
 
 func (s *S) TestAppLogSelectByLinesShouldReturnTheLastestEntries(c *check.C) {
@@ -4378,6 +5598,33 @@ func (s *S) TestRestartHandler(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestRestartHandlerRequestID(c *check.C) {
+	config.Set("docker:router", "fake")
+	defer config.Unset("docker:router")
+	config.Set("request-id-header", "Request-ID")
+	defer config.Unset("request-id-header")
+	a := app.App{
+		Name:      "stress",
+		Platform:  "zend",
+		TeamOwner: s.team.Name,
+	}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/restart", a.Name)
+	request, err := http.NewRequest("POST", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Request-ID", "req-restart")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	evts, err := event.List(&event.Filter{RequestID: "req-restart"})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	c.Assert(evts[0].Kind.Name, check.Equals, "app.update.restart")
+}
+
 func (s *S) TestRestartHandlerSingleProcess(c *check.C) {
 	config.Set("docker:router", "fake")
 	defer config.Unset("docker:router")
@@ -4415,6 +5662,110 @@ func (s *S) TestRestartHandlerSingleProcess(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestRestartUnitHandler(c *check.C) {
+	a := app.App{Name: "stress", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units := s.provisioner.GetUnits(&a)
+	url := fmt.Sprintf("/apps/%s/units/%s/restart", a.Name, units[0].ID)
+	request, err := http.NewRequest("POST", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	c.Assert(s.provisioner.UnitRestarts(&a, units[0].ID), check.Equals, 1)
+	c.Assert(s.provisioner.UnitRestarts(&a, units[1].ID), check.Equals, 0)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.restart",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":app", "value": a.Name},
+			{"name": ":unit", "value": units[0].ID},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestRestartUnitHandlerUnitNotFound(c *check.C) {
+	a := app.App{Name: "stress", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/units/notfound/restart", a.Name)
+	request, err := http.NewRequest("POST", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+	c.Assert(recorder.Body.String(), check.Equals, "unit \"notfound\" not found in app \"stress\"\n")
+}
+
+func (s *S) TestUnitInfoHandler(c *check.C) {
+	a := app.App{Name: "stress", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units := s.provisioner.GetUnits(&a)
+	url := fmt.Sprintf("/apps/%s/units/%s", a.Name, units[1].ID)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var unit provision.Unit
+	err = json.NewDecoder(recorder.Body).Decode(&unit)
+	c.Assert(err, check.IsNil)
+	c.Assert(unit.ID, check.Equals, units[1].ID)
+	c.Assert(unit.ProcessName, check.Equals, "web")
+}
+
+func (s *S) TestUnitInfoHandlerUnitNotFound(c *check.C) {
+	a := app.App{Name: "stress", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/units/notfound", a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+	c.Assert(recorder.Body.String(), check.Equals, "unit \"notfound\" not found in app \"stress\"\n")
+}
+
+func (s *S) TestReplaceUnitHandler(c *check.C) {
+	a := app.App{Name: "stress", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units := s.provisioner.GetUnits(&a)
+	url := fmt.Sprintf("/apps/%s/units/%s/replace", a.Name, units[1].ID)
+	request, err := http.NewRequest("POST", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	newUnits := s.provisioner.GetUnits(&a)
+	c.Assert(newUnits, check.HasLen, 2)
+	c.Assert(newUnits[0].ID, check.Equals, units[0].ID)
+	c.Assert(newUnits[1].ID, check.Not(check.Equals), units[1].ID)
+	c.Assert(newUnits[1].ProcessName, check.Equals, "web")
+}
+
 func (s *S) TestRestartHandlerReturns404IfTheAppDoesNotExist(c *check.C) {
 	request, err := http.NewRequest("GET", "/apps/unknown/restart?:app=unknown", nil)
 	c.Assert(err, check.IsNil)
@@ -4433,18 +5784,85 @@ func (s *S) TestRestartHandlerReturns403IfTheUserDoesNotHaveAccessToTheApp(c *ch
 	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
 	defer s.logConn.Logs(a.Name).DropCollection()
 	token := userWithPermission(c, permission.Permission{
-		Scheme:  permission.PermAppUpdateRestart,
-		Context: permission.Context(permission.CtxApp, "-invalid-"),
+		Scheme:  permission.PermAppUpdateRestart,
+		Context: permission.Context(permission.CtxApp, "-invalid-"),
+	})
+	url := fmt.Sprintf("/apps/%s/restart?:app=%s", a.Name, a.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = restart(recorder, request, token)
+	c.Assert(err, check.NotNil)
+	e, ok := err.(*errors.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestChangeAppPool(c *check.C) {
+	for _, name := range []string{"dev", "prod"} {
+		err := provision.AddPool(provision.AddPoolOptions{Name: name, Public: true})
+		c.Assert(err, check.IsNil)
+	}
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name, Pool: "dev"}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 1, "web", nil)
+	request, err := http.NewRequest("POST", "/apps/myapp/pool", strings.NewReader("pool=prod"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Pool, check.Equals, "prod")
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.pool",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":app", "value": a.Name},
+			{"name": "pool", "value": "prod"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestChangeAppPoolNotFound(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "dev", Public: true})
+	c.Assert(err, check.IsNil)
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name, Pool: "dev"}
+	err = app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/apps/myapp/pool", strings.NewReader("pool=prod"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestChangeAppPoolWithoutPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppRead,
+		Context: permission.Context(permission.CtxApp, a.Name),
 	})
-	url := fmt.Sprintf("/apps/%s/restart?:app=%s", a.Name, a.Name)
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequest("POST", "/apps/myapp/pool", strings.NewReader("pool=prod"))
 	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	recorder := httptest.NewRecorder()
-	err = restart(recorder, request, token)
-	c.Assert(err, check.NotNil)
-	e, ok := err.(*errors.HTTP)
-	c.Assert(ok, check.Equals, true)
-	c.Assert(e.Code, check.Equals, http.StatusForbidden)
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
 }
 
 func (s *S) TestSleepHandler(c *check.C) {
@@ -4901,6 +6319,100 @@ func (s *S) TestStopHandler(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestSetAppMetadata(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("label=cost-center=42&label=tier=1&annotation=repo=https://github.com/tsuru/myapp")
+	request, err := http.NewRequest("POST", "/apps/myapp/metadata", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.DeepEquals, map[string]string{"cost-center": "42", "tier": "1"})
+	c.Assert(dbApp.Annotations, check.DeepEquals, map[string]string{"repo": "https://github.com/tsuru/myapp"})
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.metadata",
+		StartCustomData: []map[string]interface{}{
+			{"name": "label", "value": []interface{}{"cost-center=42", "tier=1"}},
+			{"name": "annotation", "value": "repo=https://github.com/tsuru/myapp"},
+			{"name": ":app", "value": a.Name},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestSetAppMetadataInvalid(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	for _, body := range []string{"", "label=tier", "label=a.b=1"} {
+		request, err := http.NewRequest("POST", "/apps/myapp/metadata", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("body %q", body))
+	}
+}
+
+func (s *S) TestSetAppMetadataWithoutPermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c)
+	request, err := http.NewRequest("POST", "/apps/myapp/metadata", strings.NewReader("label=tier=1"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestUnsetAppMetadata(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetMetadata(map[string]string{"tier": "1", "team": "a"}, map[string]string{"repo": "git"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/apps/myapp/metadata?label=tier&annotation=repo", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.DeepEquals, map[string]string{"team": "a"})
+	c.Assert(dbApp.Annotations, check.DeepEquals, map[string]string{})
+}
+
+func (s *S) TestUnsetAppMetadataInvalidKey(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	for _, query := range []url.Values{{"label": {"a.b"}}, {"annotation": {"$set"}}} {
+		request, err := http.NewRequest("DELETE", "/apps/myapp/metadata?"+query.Encode(), nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("query %v", query))
+	}
+}
+
 func (s *S) TestForceDeleteLock(c *check.C) {
 	a := app.App{Name: "locked", Lock: app.AppLock{Locked: true}}
 	err := s.conn.Apps().Insert(a)
@@ -4928,6 +6440,59 @@ func (s *S) TestForceDeleteLock(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *S) TestForceDeleteLockOlderThan(c *check.C) {
+	lock := app.AppLock{Locked: true, Owner: "someone", Reason: "POST /apps/locked/deploy", AcquireDate: time.Now().Add(-2 * time.Hour)}
+	a := app.App{Name: "locked", Lock: lock}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("DELETE", "/apps/locked/lock?older-than=1h", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var dbApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "locked"}).One(&dbApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, false)
+}
+
+func (s *S) TestForceDeleteLockOlderThanNotStale(c *check.C) {
+	lock := app.AppLock{Locked: true, Owner: "someone", Reason: "POST /apps/locked/deploy", AcquireDate: time.Now()}
+	a := app.App{Name: "locked", Lock: lock}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("DELETE", "/apps/locked/lock?older-than=1h", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), check.Matches, "lock is not stale: App locked by someone, .*\n")
+	var dbApp app.App
+	err = s.conn.Apps().Find(bson.M{"name": "locked"}).One(&dbApp)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, true)
+}
+
+func (s *S) TestForceDeleteLockOlderThanInvalid(c *check.C) {
+	a := app.App{Name: "locked", Lock: app.AppLock{Locked: true}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("DELETE", "/apps/locked/lock?older-than=xyz", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
 func (s *S) TestForceDeleteLockOnlyWithPermission(c *check.C) {
 	a := app.App{Name: "locked", Lock: app.AppLock{Locked: true}, Teams: []string{s.team.Name}}
 	err := s.conn.Apps().Insert(a)
@@ -5099,6 +6664,192 @@ func (s *S) TestMEtricEnvsWhenAppDoesNotExist(c *check.C) {
 	c.Assert(recorder.Body.String(), check.Matches, "^App .* not found.\n$")
 }
 
+func (s *S) TestAppMetrics(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC().Truncate(time.Second)
+	samples := []metrics.Sample{
+		{App: "myappx", Unit: "unit1", Process: "web", Date: now.Add(-2 * time.Hour), CPU: 10},
+		{App: "myappx", Unit: "unit1", Process: "web", Date: now.Add(-time.Minute), CPU: 20, Memory: 1024},
+	}
+	for _, sample := range samples {
+		err = s.conn.AppMetrics().Insert(sample)
+		c.Assert(err, check.IsNil)
+	}
+	request, err := http.NewRequest("GET", "/apps/myappx/metrics", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result []metrics.Sample
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].CPU, check.Equals, 20.0)
+	c.Assert(result[0].Memory, check.Equals, uint64(1024))
+	since := url.QueryEscape(now.Add(-3 * time.Hour).Format(time.RFC3339))
+	request, err = http.NewRequest("GET", "/apps/myappx/metrics?since="+since, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 2)
+}
+
+func (s *S) TestAppMetricsWithoutSamples(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/myappx/metrics?until=invalid", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	request, err = http.NewRequest("GET", "/apps/myappx/metrics", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestAppRequests(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 1, "web", nil)
+	c.Assert(err, check.IsNil)
+	units, err := a.Units()
+	c.Assert(err, check.IsNil)
+	body := fmt.Sprintf(`[{"backend":%q,"latency":200000000,"status":200},{"backend":%q,"latency":100000000,"status":500}]`,
+		units[0].Address.Host, units[0].Address.Host)
+	request, err := http.NewRequest("POST", "/apps/myappx/requests", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	request, err = http.NewRequest("GET", "/apps/myappx/requests", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result []metrics.RequestStats
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.DeepEquals, []metrics.RequestStats{
+		{Unit: units[0].ID, Backend: units[0].Address.Host, Count: 2, Errors: 1, P50: 100 * time.Millisecond, P95: 200 * time.Millisecond, P99: 200 * time.Millisecond},
+	})
+}
+
+func (s *S) TestAppRequestsWithoutSamples(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/apps/myappx/requests", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestAddAppRequestsInvalid(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	m := RunServer(true)
+	for _, body := range []string{`{"backend":`, `[{"latency":100}]`} {
+		request, err := http.NewRequest("POST", "/apps/myappx/requests", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	}
+}
+
+func (s *S) TestAddAppRequestsUnauthorized(c *check.C) {
+	a := app.App{Name: "myappx", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadMetric,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	request, err := http.NewRequest("POST", "/apps/myappx/requests", strings.NewReader(`[]`))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestAppsUptime(c *check.C) {
+	for _, name := range []string{"myappx", "otherapp"} {
+		a := app.App{Name: name, Platform: "zend", TeamOwner: s.team.Name}
+		err := app.CreateApp(&a, s.user)
+		c.Assert(err, check.IsNil)
+	}
+	now := time.Now().UTC()
+	for _, status := range []provision.Status{provision.StatusStarted, provision.StatusError} {
+		err := s.conn.AppUnitStatuses().Insert(metrics.StatusSample{App: "myappx", Unit: "unit1", Date: now.Add(-time.Hour), Status: status.String()})
+		c.Assert(err, check.IsNil)
+	}
+	request, err := http.NewRequest("GET", "/uptime?app=myappx", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result []metrics.Uptime
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].App, check.Equals, "myappx")
+	c.Assert(*result[0].Last24h, check.Equals, 50.0)
+	c.Assert(*result[0].Last7d, check.Equals, 50.0)
+	request, err = http.NewRequest("GET", "/uptime", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 2)
+}
+
+func (s *S) TestAppsUptimeWithoutPermission(c *check.C) {
+	token := userWithPermission(c)
+	request, err := http.NewRequest("GET", "/uptime", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
 func (s *S) TestRebuildRoutes(c *check.C) {
 	config.Set("docker:router", "fake")
 	defer config.Unset("docker:router")
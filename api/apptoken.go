@@ -0,0 +1,179 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// errScopedTokenManagement is returned when a scoped token is used to manage
+// scoped tokens, which would allow it to mint tokens that outlive it.
+var errScopedTokenManagement = &errors.HTTP{Code: http.StatusForbidden, Message: "scoped tokens can't be used to manage scoped tokens"}
+
+// title: create app scoped token
+// path: /apps/{app}/tokens
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   201: Token created
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Forbidden
+//   404: App not found
+func createScopedToken(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if _, ok := t.(*auth.ScopedToken); ok {
+		return errScopedTokenManagement
+	}
+	allowed := permission.Check(t, permission.PermAppTokenCreate,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	var expiration time.Duration
+	if expires := r.FormValue("expires"); expires != "" {
+		expiration, err = time.ParseDuration(expires)
+		if err != nil || expiration <= 0 {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid expires value: %q", expires)}
+		}
+	}
+	schemes := make([]*permission.PermissionScheme, len(r.Form["permission"]))
+	for i, name := range r.Form["permission"] {
+		schemes[i], err = permission.SafeGet(name)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid permission %q: %s", name, err)}
+		}
+		if !permission.Check(t, schemes[i], contextsForApp(&a)...) {
+			return &errors.HTTP{Code: http.StatusForbidden, Message: fmt.Sprintf("you don't have the permission %q in app %q", name, appName)}
+		}
+	}
+	u, err := t.User()
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppTokenCreate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	token, err := auth.CreateScopedToken(u, appName, schemes, expiration)
+	if err == auth.ErrScopedTokenNoScopes || err == auth.ErrScopedTokenBadScheme || err == auth.ErrScopedTokenTokenPerm {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(token)
+}
+
+// title: list app scoped tokens
+// path: /apps/{app}/tokens
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+//   404: App not found
+func listScopedTokens(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppTokenRead,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	tokens, err := auth.ListScopedTokens(appName)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(tokens)
+}
+
+// title: revoke app scoped token
+// path: /apps/{app}/tokens/{id}
+// method: DELETE
+// responses:
+//   200: Token revoked
+//   400: Invalid id
+//   401: Unauthorized
+//   404: App or token not found
+func removeScopedToken(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	appName := r.URL.Query().Get(":app")
+	id := r.URL.Query().Get(":id")
+	if !bson.IsObjectIdHex(id) {
+		msg := fmt.Sprintf("id parameter is not ObjectId: %s", id)
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if _, ok := t.(*auth.ScopedToken); ok {
+		return errScopedTokenManagement
+	}
+	allowed := permission.Check(t, permission.PermAppTokenDelete,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppTokenDelete,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = auth.RemoveScopedToken(appName, bson.ObjectIdHex(id))
+	if err == auth.ErrScopedTokenNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
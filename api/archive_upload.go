@@ -0,0 +1,137 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// archiveUploadFromRequest returns the upload in the request, checking whether
+// the user is allowed to upload archives to its app.
+func archiveUploadFromRequest(r *http.Request, t auth.Token) (*app.ArchiveUpload, error) {
+	appName := r.URL.Query().Get(":appname")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return nil, err
+	}
+	if !permission.Check(t, permission.PermAppDeployUpload, contextsForApp(&a)...) {
+		return nil, permission.ErrUnauthorized
+	}
+	upload, err := app.GetArchiveUpload(appName, r.URL.Query().Get(":upload"))
+	if err == app.ErrArchiveUploadNotFound {
+		return nil, &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return upload, err
+}
+
+// title: deploy upload create
+// path: /apps/{appname}/deploy/uploads
+// method: POST
+// produce: application/json
+// responses:
+//   201: Upload created
+//   401: Unauthorized
+//   404: App not found
+//   409: Too many uploads
+func createArchiveUpload(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":appname"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppDeployUpload, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	upload, err := app.NewArchiveUpload(&a, t.GetUserName())
+	if err == app.ErrTooManyArchiveUploads {
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(upload)
+}
+
+// title: deploy upload info
+// path: /apps/{appname}/deploy/uploads/{upload}
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: Not found
+func archiveUploadInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	upload, err := archiveUploadFromRequest(r, t)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(upload)
+}
+
+// title: deploy upload chunk
+// path: /apps/{appname}/deploy/uploads/{upload}
+// method: PUT
+// consume: application/octet-stream
+// produce: application/json
+// responses:
+//   200: Chunk received
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Not found
+//   409: Invalid offset
+//   413: Chunk or upload too large
+func archiveUploadChunk(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	upload, err := archiveUploadFromRequest(r, t)
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "offset must be a non-negative integer"}
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, app.MaxArchiveUploadChunkSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > app.MaxArchiveUploadChunkSize {
+		return &errors.HTTP{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("chunk too large, the maximum size is %d bytes", app.MaxArchiveUploadChunkSize),
+		}
+	}
+	if len(data) == 0 {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "empty chunk"}
+	}
+	err = upload.AppendChunk(offset, data)
+	if err != nil {
+		if _, ok := err.(*app.ArchiveUploadOffsetError); ok {
+			return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+		}
+		if err == app.ErrArchiveUploadNotFound {
+			return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		}
+		if err == app.ErrArchiveUploadTooLarge {
+			return &errors.HTTP{Code: http.StatusRequestEntityTooLarge, Message: err.Error()}
+		}
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(upload)
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/app"
+	"gopkg.in/check.v1"
+)
+
+func (s *DeploySuite) createUploadApp(c *check.C) app.App {
+	user, _ := s.token.User()
+	a := app.App{Name: "otherapp", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, user)
+	c.Assert(err, check.IsNil)
+	return a
+}
+
+func (s *DeploySuite) TestCreateArchiveUpload(c *check.C) {
+	a := s.createUploadApp(c)
+	request, err := http.NewRequest("POST", fmt.Sprintf("/apps/%s/deploy/uploads", a.Name), nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	RunServer(true).ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var upload app.ArchiveUpload
+	err = json.Unmarshal(recorder.Body.Bytes(), &upload)
+	c.Assert(err, check.IsNil)
+	c.Assert(upload.App, check.Equals, a.Name)
+	c.Assert(upload.User, check.Equals, s.token.GetUserName())
+	c.Assert(upload.Size, check.Equals, int64(0))
+}
+
+func (s *DeploySuite) TestArchiveUploadChunkAndInfo(c *check.C) {
+	a := s.createUploadApp(c)
+	upload, err := app.NewArchiveUpload(&a, s.token.GetUserName())
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/deploy/uploads/%s", a.Name, upload.ID.Hex())
+	server := RunServer(true)
+	for _, chunk := range []struct {
+		offset int
+		data   string
+		code   int
+	}{
+		{0, "hello ", http.StatusOK},
+		{0, "hello ", http.StatusOK},
+		{10, "world", http.StatusConflict},
+		{6, "world", http.StatusOK},
+	} {
+		request, err := http.NewRequest("PUT", fmt.Sprintf("%s?offset=%d", url, chunk.offset), strings.NewReader(chunk.data))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/octet-stream")
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, chunk.code, check.Commentf("offset %d: %s", chunk.offset, recorder.Body.String()))
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var result app.ArchiveUpload
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Size, check.Equals, int64(11))
+}
+
+func (s *DeploySuite) TestArchiveUploadChunkInvalidOffset(c *check.C) {
+	a := s.createUploadApp(c)
+	upload, err := app.NewArchiveUpload(&a, s.token.GetUserName())
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/apps/%s/deploy/uploads/%s?offset=abc", a.Name, upload.ID.Hex())
+	request, err := http.NewRequest("PUT", url, strings.NewReader("data"))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	RunServer(true).ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *DeploySuite) TestArchiveUploadInfoNotFound(c *check.C) {
+	a := s.createUploadApp(c)
+	request, err := http.NewRequest("GET", fmt.Sprintf("/apps/%s/deploy/uploads/57f3dd3ea3d5e1c2b1a4e3f0", a.Name), nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	RunServer(true).ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *DeploySuite) TestDeployArchiveUpload(c *check.C) {
+	a := s.createUploadApp(c)
+	upload, err := app.NewArchiveUpload(&a, s.token.GetUserName())
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello world!"))
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("upload=" + upload.ID.Hex())
+	request, err := http.NewRequest("POST", fmt.Sprintf("/apps/%s/deploy", a.Name), body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	RunServer(true).ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, "Upload deploy called\nOK\n")
+	_, err = app.GetArchiveUpload(a.Name, upload.ID.Hex())
+	c.Assert(err, check.Equals, app.ErrArchiveUploadNotFound)
+}
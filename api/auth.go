@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
@@ -135,9 +137,152 @@ func login(w http.ResponseWriter, r *http.Request) (err error) {
 // responses:
 //   200: Ok
 func logout(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if _, ok := t.(*auth.ImpersonationToken); ok {
+		return auth.RemoveImpersonationToken(t.GetValue())
+	}
 	return app.AuthScheme.Logout(t.GetValue())
 }
 
+// title: refresh token
+// path: /users/tokens/refresh
+// method: POST
+// produce: application/json
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+func refreshToken(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	refresher, ok := app.AuthScheme.(auth.TokenRefresher)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: nonManagedSchemeMsg}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  userTarget(t.GetUserName()),
+		Kind:    permission.PermUserUpdateToken,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, t.GetUserName())),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	newToken, err := refresher.RefreshToken(t)
+	if err != nil {
+		return handleAuthError(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]string{"token": newToken.GetValue()})
+}
+
+// title: revoke token
+// path: /users/tokens/revoke
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Token revoked
+//   400: Invalid data
+//   401: Unauthorized
+func revokeToken(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	value := r.FormValue("token")
+	if id := r.FormValue("id"); value == "" && id != "" {
+		value, err = auth.TokenValueByID(id)
+		if err == auth.ErrInvalidToken {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid token"}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if value == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "you must provide the token or the id of the token to revoke"}
+	}
+	revoked, err := validate("bearer "+value, r)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid token"}
+	}
+	if revoked.GetUserName() != t.GetUserName() {
+		allowed := permission.Check(t, permission.PermUserUpdateToken,
+			permission.Context(permission.CtxUser, revoked.GetUserName()),
+		)
+		if !allowed {
+			return permission.ErrUnauthorized
+		}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  userTarget(revoked.GetUserName()),
+		Kind:    permission.PermUserUpdateToken,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, revoked.GetUserName())),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = auth.RevokeToken(revoked)
+	if err != nil {
+		return err
+	}
+	if logoutErr := app.AuthScheme.Logout(value); logoutErr != nil {
+		log.Debugf("Unable to logout revoked token: %s", logoutErr)
+	}
+	return nil
+}
+
+// title: token list
+// path: /users/tokens
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func listTokens(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	usage, err := auth.ListTokenUsage(t.GetUserName())
+	if err != nil {
+		return err
+	}
+	if len(usage) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(usage)
+}
+
+// title: unused tokens report
+// path: /users/tokens/unused
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Forbidden
+func unusedTokens(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	if !permission.Check(t, permission.PermUserReadTokens) {
+		return permission.ErrUnauthorized
+	}
+	days := 30
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "days must be a positive integer"}
+		}
+	}
+	usage, err := auth.UnusedTokens(time.Now().UTC().AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+	if len(usage) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(usage)
+}
+
 // title: change password
 // path: /users/password
 // method: PUT
@@ -228,6 +373,179 @@ func resetPassword(w http.ResponseWriter, r *http.Request) (err error) {
 	return managed.ResetPassword(u, token)
 }
 
+// title: verify email
+// path: /users/{email}/verify
+// method: POST
+// responses:
+//   200: Ok
+//   400: Invalid data
+func verifyEmail(w http.ResponseWriter, r *http.Request) (err error) {
+	verifier, ok := app.AuthScheme.(auth.EmailVerifier)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "email verification is not supported by the current auth scheme"}
+	}
+	r.ParseForm()
+	email := r.URL.Query().Get(":email")
+	token := r.FormValue("token")
+	u, err := auth.GetUserByEmail(email)
+	if token == "" {
+		// The response doesn't depend on the user existing or having
+		// confirmed the address, so the endpoint can't be used to find out
+		// which users exist.
+		if err == nil {
+			err = verifier.ResendEmailVerification(u)
+		}
+		if _, ok := err.(*errors.ValidationError); ok || err == auth.ErrUserNotFound {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "If the email address is pending confirmation, a new confirmation link was sent to it.")
+		return nil
+	}
+	if err == auth.ErrUserNotFound {
+		err = auth.ErrInvalidToken
+	}
+	if err == nil {
+		err = verifier.VerifyEmail(u, token)
+	}
+	if err == auth.ErrInvalidToken {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if err != nil {
+		return handleAuthError(err)
+	}
+	// The event is only recorded once the token proves the request was
+	// made by the owner of the email address.
+	evt, err := event.New(&event.Opts{
+		Target:   userTarget(email),
+		Kind:     permission.PermUserUpdateVerify,
+		RawOwner: event.Owner{Type: event.OwnerTypeUser, Name: email},
+		Allowed:  event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	evt.Done(nil)
+	fmt.Fprintln(w, "Email address confirmed, you can now login.")
+	return nil
+}
+
+// title: enroll two-factor authentication
+// path: /users/{email}/2fa
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   201: Enrollment created
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Not found
+//   409: Two-factor authentication already enabled
+func enrollTwoFactor(w http.ResponseWriter, r *http.Request) (err error) {
+	scheme, ok := app.AuthScheme.(auth.TwoFactorScheme)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: nonManagedSchemeMsg}
+	}
+	r.ParseForm()
+	email := r.URL.Query().Get(":email")
+	password := r.FormValue("password")
+	delete(r.Form, "password")
+	evt, err := event.New(&event.Opts{
+		Target:     userTarget(email),
+		Kind:       permission.PermUserUpdateTwoFactor,
+		RawOwner:   event.Owner{Type: event.OwnerTypeUser, Name: email},
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	enrollment, err := scheme.EnrollTwoFactor(email, password)
+	if err != nil {
+		return handleAuthError(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(enrollment)
+}
+
+// title: confirm two-factor authentication
+// path: /users/{email}/2fa/confirm
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   200: Two-factor authentication enabled
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Not found
+//   409: Two-factor authentication already enabled
+func confirmTwoFactor(w http.ResponseWriter, r *http.Request) (err error) {
+	scheme, ok := app.AuthScheme.(auth.TwoFactorScheme)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: nonManagedSchemeMsg}
+	}
+	r.ParseForm()
+	email := r.URL.Query().Get(":email")
+	password := r.FormValue("password")
+	code := r.FormValue("code")
+	delete(r.Form, "password")
+	delete(r.Form, "code")
+	evt, err := event.New(&event.Opts{
+		Target:     userTarget(email),
+		Kind:       permission.PermUserUpdateTwoFactor,
+		RawOwner:   event.Owner{Type: event.OwnerTypeUser, Name: email},
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	codes, err := scheme.ConfirmTwoFactor(email, password, code)
+	if err != nil {
+		return handleAuthError(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string][]string{"recoveryCodes": codes})
+}
+
+// title: disable two-factor authentication
+// path: /users/2fa
+// method: DELETE
+// responses:
+//   200: Two-factor authentication disabled
+//   400: Invalid data
+//   401: Unauthorized
+func disableTwoFactor(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	scheme, ok := app.AuthScheme.(auth.TwoFactorScheme)
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: nonManagedSchemeMsg}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  userTarget(t.GetUserName()),
+		Kind:    permission.PermUserUpdateTwoFactor,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, t.GetUserName())),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	u, err := t.User()
+	if err != nil {
+		return err
+	}
+	err = scheme.DisableTwoFactor(u, r.URL.Query().Get("code"))
+	if err != nil {
+		return handleAuthError(err)
+	}
+	return nil
+}
+
 // title: team create
 // path: /teams
 // method: POST
@@ -246,6 +564,15 @@ func createTeam(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if name == "" {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: auth.ErrInvalidTeamName.Error()}
 	}
+	parent := r.FormValue("parent")
+	if parent != "" {
+		allowed = permission.Check(t, permission.PermTeamUpdateParent,
+			permission.Context(permission.CtxTeam, parent),
+		)
+		if !allowed {
+			return permission.ErrUnauthorized
+		}
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     teamTarget(name),
 		Kind:       permission.PermTeamCreate,
@@ -261,6 +588,15 @@ func createTeam(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	if err != nil {
 		return err
 	}
+	if parent != "" {
+		_, err = auth.GetTeam(parent)
+		if err == auth.ErrTeamNotFound {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: auth.ErrTeamParentNotFound.Error()}
+		}
+		if err != nil {
+			return err
+		}
+	}
 	err = auth.CreateTeam(name, u)
 	switch err {
 	case auth.ErrInvalidTeamName:
@@ -268,10 +604,17 @@ func createTeam(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	case auth.ErrTeamAlreadyExists:
 		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
 	}
-	if err == nil {
-		w.WriteHeader(http.StatusCreated)
+	if err != nil {
+		return err
 	}
-	return err
+	if parent != "" {
+		err = auth.SetTeamParent(name, parent)
+		if err != nil {
+			return err
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
 }
 
 // title: remove team
@@ -316,6 +659,51 @@ func removeTeam(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	return nil
 }
 
+// title: change team parent
+// path: /teams/{name}/parent
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Parent changed
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Team not found
+func changeTeamParent(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	name := r.URL.Query().Get(":name")
+	parent := r.FormValue("parent")
+	allowed := permission.Check(t, permission.PermTeamUpdateParent,
+		permission.Context(permission.CtxTeam, name),
+	)
+	if allowed && parent != "" {
+		allowed = permission.Check(t, permission.PermTeamUpdateParent,
+			permission.Context(permission.CtxTeam, parent),
+		)
+	}
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     teamTarget(name),
+		Kind:       permission.PermTeamUpdateParent,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, permission.Context(permission.CtxTeam, name)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = auth.SetTeamParent(name, parent)
+	switch err {
+	case auth.ErrTeamNotFound:
+		return &errors.HTTP{Code: http.StatusNotFound, Message: fmt.Sprintf(`Team "%s" not found.`, name)}
+	case auth.ErrTeamParentNotFound, auth.ErrTeamParentCycle:
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	return err
+}
+
 // title: team list
 // path: /teams
 // method: GET
@@ -331,11 +719,13 @@ func teamList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 		return err
 	}
 	teamsMap := map[string][]string{}
+	parents := map[string]string{}
 	perms, err := t.Permissions()
 	if err != nil {
 		return err
 	}
 	for _, team := range teams {
+		parents[team.Name] = team.Parent
 		teamCtx := permission.Context(permission.CtxTeam, team.Name)
 		var parent *permission.PermissionScheme
 		for _, p := range permsForTeam {
@@ -354,10 +744,14 @@ func teamList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	}
 	var result []map[string]interface{}
 	for name, permissions := range teamsMap {
-		result = append(result, map[string]interface{}{
+		entry := map[string]interface{}{
 			"name":        name,
 			"permissions": permissions,
-		})
+		}
+		if parent := parents[name]; parent != "" {
+			entry["parent"] = parent
+		}
+		result = append(result, entry)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	return json.NewEncoder(w).Encode(result)
@@ -412,7 +806,11 @@ func addKeyToUser(w http.ResponseWriter, r *http.Request, t auth.Token) (err err
 	if err == repository.ErrKeyAlreadyExists {
 		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	syncAuthorizedKeys(u)
+	return nil
 }
 
 // title: remove key
@@ -459,7 +857,21 @@ func removeKeyFromUser(w http.ResponseWriter, r *http.Request, t auth.Token) (er
 	if err == repository.ErrKeyNotFound {
 		return &errors.HTTP{Code: http.StatusNotFound, Message: "User does not have this key"}
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	syncAuthorizedKeys(u)
+	return nil
+}
+
+// syncAuthorizedKeys propagates the keys of the user to the units of the apps
+// they can open a shell in. Failures are only logged, as the key change has
+// already been stored in the repository manager.
+func syncAuthorizedKeys(u *auth.User) {
+	err := app.SyncUserAuthorizedKeys(u)
+	if err != nil {
+		log.Errorf("unable to sync authorized keys of user %q: %s", u.Email, err)
+	}
 }
 
 // title: list keys
@@ -534,6 +946,177 @@ func removeUser(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	return app.AuthScheme.Remove(u)
 }
 
+// title: deactivate user
+// path: /users/{email}/deactivate
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   200: User deactivated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: User not found
+func deactivateUser(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	email := r.URL.Query().Get(":email")
+	allowed := permission.Check(t, permission.PermUserUpdateDeactivate,
+		permission.Context(permission.CtxUser, email),
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     userTarget(email),
+		Kind:       permission.PermUserUpdateDeactivate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	u, err := auth.GetUserByEmail(email)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	var newOwner *auth.User
+	if owner := r.FormValue("owner"); owner != "" {
+		newOwner, err = auth.GetUserByEmail(owner)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("new owner %q: %s", owner, err)}
+		}
+		if newOwner.Deactivated || newOwner.Email == u.Email {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("user %q cannot own the transferred apps", owner)}
+		}
+	}
+	apps, err := app.TransferUserApps(u.Email, newOwner, r.FormValue("team"))
+	if err == app.ErrTransferTargetRequired || err == auth.ErrTeamNotFound {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	err = u.Deactivate()
+	if err != nil {
+		return err
+	}
+	if revoker, ok := app.AuthScheme.(auth.UserTokenRevoker); ok {
+		err = revoker.RevokeUserTokens(u.Email)
+		if err != nil {
+			return err
+		}
+	}
+	appNames, err := deployableApps(u, make(map[string]*permission.Role))
+	if err != nil {
+		return err
+	}
+	manager := repository.Manager()
+	for _, name := range appNames {
+		manager.RevokeAccess(name, u.Email)
+	}
+	if apps == nil {
+		apps = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string][]string{"apps": apps})
+}
+
+// title: activate user
+// path: /users/{email}/activate
+// method: POST
+// responses:
+//   200: User activated
+//   401: Unauthorized
+//   404: User not found
+func activateUser(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	email := r.URL.Query().Get(":email")
+	allowed := permission.Check(t, permission.PermUserUpdateDeactivate,
+		permission.Context(permission.CtxUser, email),
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     userTarget(email),
+		Kind:       permission.PermUserUpdateDeactivate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	u, err := auth.GetUserByEmail(email)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	err = u.Activate()
+	if err != nil {
+		return err
+	}
+	appNames, err := deployableApps(u, make(map[string]*permission.Role))
+	if err != nil {
+		return err
+	}
+	manager := repository.Manager()
+	for _, name := range appNames {
+		manager.GrantAccess(name, u.Email)
+	}
+	return nil
+}
+
+// title: impersonate user
+// path: /users/{email}/impersonate
+// method: POST
+// produce: application/json
+// responses:
+//   200: OK
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Forbidden
+//   404: User not found
+func impersonateUser(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	email := r.URL.Query().Get(":email")
+	allowed := permission.Check(t, permission.PermUserImpersonate,
+		permission.Context(permission.CtxUser, email),
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  userTarget(email),
+		Kind:    permission.PermUserImpersonate,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermUserReadEvents, permission.Context(permission.CtxUser, email)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	u, err := auth.GetUserByEmail(email)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if u.Deactivated {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: auth.ErrUserDeactivated.Error()}
+	}
+	token, err := auth.Impersonate(t, u)
+	if err == auth.ErrImpersonateSelf {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if err == auth.ErrImpersonationChaining || err == auth.ErrImpersonationEscalate {
+		return &errors.HTTP{Code: http.StatusForbidden, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(token)
+}
+
 type schemeData struct {
 	Name string          `json:"name"`
 	Data auth.SchemeInfo `json:"data"`
@@ -639,6 +1222,7 @@ type apiUser struct {
 	Email       string
 	Roles       []rolePermissionData
 	Permissions []rolePermissionData
+	Deactivated bool `json:",omitempty"`
 }
 
 func createAPIUser(perms []permission.Permission, user *auth.User, roleMap map[string]*permission.Role, includeAll bool) (*apiUser, error) {
@@ -695,6 +1279,7 @@ func createAPIUser(perms []permission.Permission, user *auth.User, roleMap map[s
 		Email:       user.Email,
 		Roles:       roleData,
 		Permissions: permData,
+		Deactivated: user.Deactivated,
 	}, nil
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/dbtest"
 	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/event/eventtest"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision"
@@ -79,6 +80,7 @@ func (s *AuthSuite) SetUpTest(c *check.C) {
 	conn, err := db.Conn()
 	c.Assert(err, check.IsNil)
 	defer conn.Close()
+	auth.FlushTokenUsage()
 	dbtest.ClearAllCollections(conn.Apps().Database)
 	s.createUserAndTeam(c)
 	conn.Platforms().Insert(app.Platform{Name: "python"})
@@ -413,6 +415,93 @@ func (s *AuthSuite) TestCreateTeam(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *AuthSuite) TestCreateTeamWithParent(c *check.C) {
+	b := strings.NewReader("name=timeredbull&parent=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/teams", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	team, err := auth.GetTeam("timeredbull")
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, s.team.Name)
+}
+
+func (s *AuthSuite) TestCreateTeamWithParentNotFound(c *check.C) {
+	b := strings.NewReader("name=timeredbull&parent=unknown")
+	request, err := http.NewRequest("POST", "/teams", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, auth.ErrTeamParentNotFound.Error()+"\n")
+	_, err = auth.GetTeam("timeredbull")
+	c.Assert(err, check.Equals, auth.ErrTeamNotFound)
+}
+
+func (s *AuthSuite) TestChangeTeamParent(c *check.C) {
+	b := strings.NewReader("parent=" + s.team.Name)
+	request, err := http.NewRequest("PUT", "/teams/"+s.team2.Name+"/parent", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	team, err := auth.GetTeam(s.team2.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, s.team.Name)
+	c.Assert(eventtest.EventDesc{
+		Target: teamTarget(s.team2.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "team.update.parent",
+		StartCustomData: []map[string]interface{}{
+			{"name": "parent", "value": s.team.Name},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *AuthSuite) TestChangeTeamParentCycle(c *check.C) {
+	err := auth.SetTeamParent(s.team2.Name, s.team.Name)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("parent=" + s.team2.Name)
+	request, err := http.NewRequest("PUT", "/teams/"+s.team.Name+"/parent", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, auth.ErrTeamParentCycle.Error()+"\n")
+}
+
+func (s *AuthSuite) TestChangeTeamParentRequiresPermissionInParent(c *check.C) {
+	token := customUserWithPermission(c, "teamchanger", permission.Permission{
+		Scheme:  permission.PermTeamUpdateParent,
+		Context: permission.Context(permission.CtxTeam, s.team2.Name),
+	})
+	b := strings.NewReader("parent=" + s.team.Name)
+	request, err := http.NewRequest("PUT", "/teams/"+s.team2.Name+"/parent", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	team, err := auth.GetTeam(s.team2.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, "")
+}
+
 func (s *AuthSuite) TestCreateTeamNameIsEmpty(c *check.C) {
 	b := strings.NewReader("ble=bla")
 	request, err := http.NewRequest("POST", "/teams", b)
@@ -934,6 +1023,220 @@ func (s *AuthSuite) TestRemoveUser(c *check.C) {
 	c.Assert(users, check.DeepEquals, []string{s.user.Email})
 }
 
+func (s *AuthSuite) TestDeactivateUser(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err = nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	token, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	err = conn.Apps().Insert(app.App{Name: "myapp", Owner: u.Email, TeamOwner: s.team.Name, Teams: []string{s.team.Name}})
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("team=" + s.team2.Name + "&owner=" + s.user.Email)
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/deactivate", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result map[string][]string
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.DeepEquals, map[string][]string{"apps": {"myapp"}})
+	dbUser, err := auth.GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.Deactivated, check.Equals, true)
+	_, err = nativeScheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+	a, err := app.GetByName("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Owner, check.Equals, s.user.Email)
+	c.Assert(a.TeamOwner, check.Equals, s.team2.Name)
+	c.Assert(eventtest.EventDesc{
+		Target: userTarget(u.Email),
+		Owner:  s.token.GetUserName(),
+		Kind:   "user.update.deactivate",
+		StartCustomData: []map[string]interface{}{
+			{"name": "team", "value": s.team2.Name},
+			{"name": "owner", "value": s.user.Email},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *AuthSuite) TestDeactivateUserOwningAppsWithoutTarget(c *check.C) {
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err = nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	err = conn.Apps().Insert(app.App{Name: "myapp", Owner: u.Email, TeamOwner: s.team.Name, Teams: []string{s.team.Name}})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/deactivate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, app.ErrTransferTargetRequired.Error()+"\n")
+	dbUser, err := auth.GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.Deactivated, check.Equals, false)
+}
+
+func (s *AuthSuite) TestDeactivateUserNotFound(c *check.C) {
+	request, err := http.NewRequest("POST", "/users/unknown@tsuru.io/deactivate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *AuthSuite) TestActivateUser(c *check.C) {
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err := nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	err = u.Deactivate()
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/activate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	dbUser, err := auth.GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.Deactivated, check.Equals, false)
+	_, err = nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *AuthSuite) TestImpersonateUser(c *check.C) {
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err := nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/impersonate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var token auth.ImpersonationToken
+	err = json.NewDecoder(recorder.Body).Decode(&token)
+	c.Assert(err, check.IsNil)
+	c.Assert(token.UserEmail, check.Equals, u.Email)
+	c.Assert(token.Impersonator, check.Equals, s.token.GetUserName())
+	c.Assert(eventtest.EventDesc{
+		Target: userTarget(u.Email),
+		Owner:  s.token.GetUserName(),
+		Kind:   "user.impersonate",
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("GET", "/users/info", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.Token)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var info apiUser
+	err = json.NewDecoder(recorder.Body).Decode(&info)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Email, check.Equals, u.Email)
+	request, err = http.NewRequest("POST", "/users/"+s.user.Email+"/impersonate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.Token)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *AuthSuite) TestImpersonateUserRecordsImpersonatorInEvents(c *check.C) {
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err := nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	token, err := auth.Impersonate(s.token, &u)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("key=my-key&name=mykey")
+	request, err := http.NewRequest("POST", "/users/keys", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.Token)
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	evts, err := event.All()
+	c.Assert(err, check.IsNil)
+	var found bool
+	for i := range evts {
+		evt := &evts[i]
+		if evt.Kind.Name == "user.update.key.add" {
+			found = true
+			c.Assert(evt.Owner.Name, check.Equals, u.Email)
+			c.Assert(evt.Owner.Impersonator, check.Equals, s.token.GetUserName())
+		}
+	}
+	c.Assert(found, check.Equals, true)
+}
+
+func (s *AuthSuite) TestImpersonateUserRequiresPermission(c *check.C) {
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err := nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c)
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/impersonate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *AuthSuite) TestImpersonateUserWithMorePermissions(c *check.C) {
+	u := auth.User{Email: "her-voices@painofsalvation.com", Password: "123456"}
+	_, err := nativeScheme.Create(&u)
+	c.Assert(err, check.IsNil)
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	err = u.AddRole(role.Name, s.team.Name)
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermUserImpersonate,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	request, err := http.NewRequest("POST", "/users/"+u.Email+"/impersonate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	c.Assert(recorder.Body.String(), check.Equals, auth.ErrImpersonationEscalate.Error()+"\n")
+}
+
+func (s *AuthSuite) TestImpersonateUserNotFound(c *check.C) {
+	request, err := http.NewRequest("POST", "/users/unknown@tsuru.io/impersonate", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
 func (s *AuthSuite) TestRemoveUserProvidingOwnEmail(c *check.C) {
 	conn, _ := db.Conn()
 	defer conn.Close()
@@ -1207,6 +1510,99 @@ func (s *AuthSuite) TestResetPasswordStep2(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *AuthSuite) TestVerifyEmail(c *check.C) {
+	config.Set("auth:email-verification:enabled", true)
+	defer config.Unset("auth:email-verification")
+	defer s.server.Reset()
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	user := &auth.User{Email: "unverified@alanis.com", Password: "145678"}
+	_, err = nativeScheme.Create(user)
+	c.Assert(err, check.IsNil)
+	defer conn.Users().Remove(bson.M{"email": user.Email})
+	var t map[string]interface{}
+	err = conn.EmailVerificationTokens().Find(bson.M{"useremail": user.Email}).One(&t)
+	c.Assert(err, check.IsNil)
+	url := fmt.Sprintf("/users/%s/verify?token=%s", user.Email, t["_id"])
+	request, _ := http.NewRequest("GET", url, nil)
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, "Email address confirmed, you can now login.\n")
+	u, err := auth.GetUserByEmail(user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(u.VerificationPending, check.Equals, false)
+	c.Assert(eventtest.EventDesc{
+		Target: userTarget(user.Email),
+		Owner:  user.Email,
+		Kind:   "user.update.verify",
+	}, eventtest.HasEvent)
+}
+
+func (s *AuthSuite) TestVerifyEmailInvalidToken(c *check.C) {
+	url := fmt.Sprintf("/users/%s/verify?token=abc", s.user.Email)
+	request, _ := http.NewRequest("POST", url, nil)
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Invalid token\n")
+}
+
+func (s *AuthSuite) TestVerifyEmailResend(c *check.C) {
+	defer s.server.Reset()
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	user := auth.User{Email: "unverified@alanis.com", Password: "145678", VerificationPending: true}
+	err = user.Create()
+	c.Assert(err, check.IsNil)
+	defer conn.Users().Remove(bson.M{"email": user.Email})
+	url := fmt.Sprintf("/users/%s/verify", user.Email)
+	request, _ := http.NewRequest("POST", url, nil)
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	n, err := conn.EmailVerificationTokens().Find(bson.M{"useremail": user.Email}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 1)
+	err = tsurutest.WaitCondition(time.Second, func() bool {
+		s.server.RLock()
+		defer s.server.RUnlock()
+		return len(s.server.MailBox) == 1
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *AuthSuite) TestVerifyEmailResendIsUniform(c *check.C) {
+	m := RunServer(true)
+	var bodies []string
+	for _, email := range []string{s.user.Email, "unknown@alanis.com"} {
+		url := fmt.Sprintf("/users/%s/verify", email)
+		request, _ := http.NewRequest("POST", url, nil)
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+		bodies = append(bodies, recorder.Body.String())
+	}
+	c.Assert(bodies[0], check.Equals, bodies[1])
+	evts, err := event.List(&event.Filter{KindName: "user.update.verify"})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 0)
+}
+
+func (s *AuthSuite) TestVerifyEmailUnknownUser(c *check.C) {
+	request, _ := http.NewRequest("POST", "/users/unknown@alanis.com/verify?token=abc", nil)
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Invalid token\n")
+}
+
 type TestScheme native.NativeScheme
 
 func (t TestScheme) AppLogin(appName string) (auth.Token, error) {
@@ -1737,3 +2133,311 @@ func (s *AuthSuite) BenchmarkListUsersManyUsers(c *check.C) {
 	sort.Strings(expectedNames)
 	c.Assert(names, check.DeepEquals, expectedNames)
 }
+
+func (s *AuthSuite) TestEnrollTwoFactor(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("password=123456")
+	request, err := http.NewRequest("POST", "/users/nobody@globo.com/2fa", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var enrollment auth.TwoFactorEnrollment
+	err = json.NewDecoder(recorder.Body).Decode(&enrollment)
+	c.Assert(err, check.IsNil)
+	c.Assert(enrollment.Secret, check.Not(check.Equals), "")
+	c.Assert(enrollment.URI, check.Matches, "otpauth://totp/tsuru:nobody@globo.com\\?.*secret="+enrollment.Secret+".*")
+	c.Assert(eventtest.EventDesc{
+		Target:          userTarget(u.Email),
+		Owner:           u.Email,
+		Kind:            "user.update.two-factor",
+		StartCustomData: []map[string]interface{}{{"name": ":email", "value": u.Email}},
+	}, eventtest.HasEvent)
+}
+
+func (s *AuthSuite) TestEnrollTwoFactorWrongPassword(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("password=1234567")
+	request, err := http.NewRequest("POST", "/users/nobody@globo.com/2fa", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+}
+
+func (s *AuthSuite) TestConfirmTwoFactorInvalidCode(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	_, err = native.NativeScheme{}.EnrollTwoFactor(u.Email, "123456")
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("password=123456&code=abc")
+	request, err := http.NewRequest("POST", "/users/nobody@globo.com/2fa/confirm", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+	c.Assert(recorder.Body.String(), check.Equals, native.ErrTwoFactorInvalidCode.Error()+"\n")
+}
+
+func (s *AuthSuite) TestDisableTwoFactorNotEnrolled(c *check.C) {
+	request, err := http.NewRequest("DELETE", "/users/2fa?code=123456", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, native.ErrTwoFactorNotEnrolled.Error()+"\n")
+}
+
+func (s *AuthSuite) TestRefreshToken(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	token, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/users/tokens/refresh", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var result map[string]string
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result["token"], check.Not(check.Equals), "")
+	c.Assert(result["token"], check.Not(check.Equals), token.GetValue())
+	_, err = nativeScheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+	t, err := nativeScheme.Auth("bearer " + result["token"])
+	c.Assert(err, check.IsNil)
+	c.Assert(t.GetUserName(), check.Equals, u.Email)
+}
+
+func (s *AuthSuite) TestRevokeOwnToken(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	token, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	leaked, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("token=" + leaked.GetValue())
+	request, err := http.NewRequest("POST", "/users/tokens/revoke", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	revoked, err := auth.IsTokenRevoked("bearer " + leaked.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, true)
+	request, err = http.NewRequest("GET", "/users/info", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+leaked.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+}
+
+func (s *AuthSuite) TestRevokeAPIKey(c *check.C) {
+	apiKey, err := s.user.RegenerateAPIKey()
+	c.Assert(err, check.IsNil)
+	b := strings.NewReader("token=" + apiKey)
+	request, err := http.NewRequest("POST", "/users/tokens/revoke", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	request, err = http.NewRequest("GET", "/users/info", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+apiKey)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+}
+
+func (s *AuthSuite) TestListTokensRecordsUsage(c *check.C) {
+	token := userWithPermission(c)
+	request, err := http.NewRequest("GET", "/users/tokens", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("User-Agent", "tsuru-client/1.1")
+	request.RemoteAddr = "10.0.0.1:31234"
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var usage []auth.TokenUsage
+	err = json.NewDecoder(recorder.Body).Decode(&usage)
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.HasLen, 1)
+	c.Assert(usage[0].User, check.Equals, token.GetUserName())
+	c.Assert(usage[0].Kind, check.Equals, "session")
+	c.Assert(usage[0].LastIP, check.Equals, "10.0.0.1")
+	c.Assert(usage[0].LastUserAgent, check.Equals, "tsuru-client/1.1")
+}
+
+func (s *AuthSuite) TestUnusedTokens(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	u.APIKey = "old-token"
+	err = u.Update()
+	c.Assert(err, check.IsNil)
+	auth.RecordTokenUsage(&auth.APIToken{Token: "old-token", UserEmail: u.Email}, "10.0.0.1", "curl/7.50")
+	err = auth.FlushTokenUsage()
+	c.Assert(err, check.IsNil)
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	_, err = conn.TokenUsage().UpdateAll(nil, bson.M{"$set": bson.M{"lastusedat": time.Now().AddDate(0, 0, -100)}})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermUserReadTokens,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	request, err := http.NewRequest("GET", "/users/tokens/unused?days=90", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var usage []auth.TokenUsage
+	err = json.NewDecoder(recorder.Body).Decode(&usage)
+	c.Assert(err, check.IsNil)
+	byUser := map[string]auth.TokenUsage{}
+	for _, u := range usage {
+		byUser[u.User] = u
+	}
+	c.Assert(byUser["nobody@globo.com"].Kind, check.Equals, "api-key")
+	c.Assert(byUser["nobody@globo.com"].LastIP, check.Equals, "10.0.0.1")
+	c.Assert(byUser[s.user.Email].Kind, check.Equals, "session")
+	c.Assert(byUser[s.user.Email].LastUsedAt.IsZero(), check.Equals, true)
+	_, used := byUser[token.GetUserName()]
+	c.Assert(used, check.Equals, false)
+}
+
+func (s *AuthSuite) TestUnusedTokensInvalidDays(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermUserReadTokens,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	request, err := http.NewRequest("GET", "/users/tokens/unused?days=-1", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *AuthSuite) TestUnusedTokensRequiresPermission(c *check.C) {
+	token := userWithPermission(c)
+	request, err := http.NewRequest("GET", "/users/tokens/unused", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *AuthSuite) TestRevokeTokenByID(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	other, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermUserReadTokens,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	}, permission.Permission{
+		Scheme:  permission.PermUserUpdateToken,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	m := RunServer(true)
+	request, err := http.NewRequest("GET", "/users/tokens/unused?days=1", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var usage []auth.TokenUsage
+	err = json.NewDecoder(recorder.Body).Decode(&usage)
+	c.Assert(err, check.IsNil)
+	var id string
+	for _, u := range usage {
+		if u.User == "nobody@globo.com" {
+			id = u.ID
+		}
+	}
+	c.Assert(id, check.Not(check.Equals), "")
+	b := strings.NewReader("id=" + id)
+	request, err = http.NewRequest("POST", "/users/tokens/revoke", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = nativeScheme.Auth("bearer " + other.GetValue())
+	c.Assert(err, check.NotNil)
+	revoked, err := auth.IsTokenRevoked("bearer " + other.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, true)
+}
+
+func (s *AuthSuite) TestRevokeTokenByUnknownID(c *check.C) {
+	token := userWithPermission(c)
+	b := strings.NewReader("id=unknown")
+	request, err := http.NewRequest("POST", "/users/tokens/revoke", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *AuthSuite) TestRevokeTokenOfOtherUserRequiresPermission(c *check.C) {
+	u := &auth.User{Email: "nobody@globo.com", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	other, err := nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c)
+	b := strings.NewReader("token=" + other.GetValue())
+	request, err := http.NewRequest("POST", "/users/tokens/revoke", b)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	revoked, err := auth.IsTokenRevoked("bearer " + other.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, false)
+}
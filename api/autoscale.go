@@ -0,0 +1,314 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/tsuru/app/autoscale"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// title: list app autoscale rules
+// path: /apps/{app}/autoscale
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+//   404: App not found
+func listAppAutoScaleRules(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppReadAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	rules, err := autoscale.ListRules(a.Name)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(rules)
+}
+
+func parseUintForm(r *http.Request, name string) (uint, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for " + name + ": " + value}
+	}
+	return uint(n), nil
+}
+
+// title: set app autoscale rule
+// path: /apps/{app}/autoscale
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Rule set
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func setAppAutoScaleRule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	rule := autoscale.Rule{
+		App:     a.Name,
+		Process: r.FormValue("process"),
+		Metric:  r.FormValue("metric"),
+		Enabled: true,
+	}
+	rule.Target, err = strconv.ParseFloat(r.FormValue("target"), 64)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for target: " + r.FormValue("target")}
+	}
+	if rule.MinUnits, err = parseUintForm(r, "min"); err != nil {
+		return err
+	}
+	if rule.MaxUnits, err = parseUintForm(r, "max"); err != nil {
+		return err
+	}
+	cooldown, err := parseUintForm(r, "cooldown")
+	if err != nil {
+		return err
+	}
+	rule.Cooldown = time.Duration(cooldown) * time.Second
+	err = a.ValidateProcess(rule.Process)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	if enabled := r.FormValue("enabled"); enabled != "" {
+		rule.Enabled, err = strconv.ParseBool(enabled)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for enabled: " + enabled}
+		}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateAutoscale,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = autoscale.SetRule(&rule)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+// title: remove app autoscale rule
+// path: /apps/{app}/autoscale
+// method: DELETE
+// responses:
+//   200: Rule removed
+//   401: Unauthorized
+//   404: App or rule not found
+func removeAppAutoScaleRule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateAutoscale,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = autoscale.RemoveRule(a.Name, r.FormValue("process"))
+	if err == autoscale.ErrRuleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
+
+// title: list app scaling schedules
+// path: /apps/{app}/autoscale/schedules
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+//   404: App not found
+func listAppScaleSchedules(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppReadAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	schedules, err := autoscale.ListSchedules(a.Name)
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(schedules)
+}
+
+// title: add app scaling schedule
+// path: /apps/{app}/autoscale/schedules
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   201: Schedule created
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func addAppScaleSchedule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	schedule := autoscale.Schedule{
+		App:      a.Name,
+		Process:  r.FormValue("process"),
+		Cron:     r.FormValue("cron"),
+		Timezone: r.FormValue("timezone"),
+	}
+	if schedule.Units, err = parseUintForm(r, "units"); err != nil {
+		return err
+	}
+	err = a.ValidateProcess(schedule.Process)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateAutoscale,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = autoscale.AddSchedule(&schedule)
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(schedule)
+}
+
+// title: remove app scaling schedule
+// path: /apps/{app}/autoscale/schedules/{id}
+// method: DELETE
+// responses:
+//   200: Schedule removed
+//   401: Unauthorized
+//   404: App or schedule not found
+func removeAppScaleSchedule(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermAppUpdateAutoscale,
+		contextsForApp(&a)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	id := r.URL.Query().Get(":id")
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(a.Name),
+		Kind:       permission.PermAppUpdateAutoscale,
+		Owner:      t,
+		CustomData: []map[string]interface{}{{"name": "schedule", "value": id}},
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = autoscale.RemoveSchedule(a.Name, id)
+	if err == autoscale.ErrScheduleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	tsuruIo "github.com/tsuru/tsuru/io"
+	"github.com/tsuru/tsuru/permission"
+)
+
+type bulkAction struct {
+	perm *permission.PermissionScheme
+	run  func(a *app.App, process string, w io.Writer) error
+	// checkServices makes the action wait for the service instances bound to
+	// the app to report they're healthy before running.
+	checkServices bool
+}
+
+var bulkActions = map[string]bulkAction{
+	"restart": {
+		perm: permission.PermAppUpdateRestart,
+		run: func(a *app.App, process string, w io.Writer) error {
+			return a.Restart(process, w)
+		},
+		checkServices: true,
+	},
+	"start": {
+		perm: permission.PermAppUpdateStart,
+		run: func(a *app.App, process string, w io.Writer) error {
+			return a.Start(w, process)
+		},
+		checkServices: true,
+	},
+	"stop": {
+		perm: permission.PermAppUpdateStop,
+		run: func(a *app.App, process string, w io.Writer) error {
+			return a.Stop(w, process)
+		},
+	},
+}
+
+// title: apps bulk action
+// path: /apps/{action}
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: Ok
+//   400: Invalid data
+//   401: Unauthorized
+func appsBulkAction(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	r.ParseForm()
+	actionName := r.URL.Query().Get(":action")
+	action, ok := bulkActions[actionName]
+	if !ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid action %q", actionName)}
+	}
+	filter := &app.Filter{
+		TeamOwner: r.FormValue("teamOwner"),
+		Pool:      r.FormValue("pool"),
+		Platform:  r.FormValue("platform"),
+	}
+	if filter.TeamOwner == "" && filter.Pool == "" && filter.Platform == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "at least one filter is required: teamOwner, pool or platform"}
+	}
+	process := r.FormValue("process")
+	contexts := permission.ContextsForPermission(t, action.perm)
+	if len(contexts) == 0 {
+		return permission.ErrUnauthorized
+	}
+	apps, err := app.List(appFilterByContext(contexts, filter))
+	if err != nil {
+		return err
+	}
+	apps, err = app.SortByDependencies(apps)
+	if e, ok := err.(*app.DependencyCycleError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	var failed []string
+	failedSet := make(map[string]bool)
+	for i := range apps {
+		a := &apps[i]
+		fmt.Fprintf(writer, "==== %s app %q (%d/%d) ====\n", actionName, a.Name, i+1, len(apps))
+		if dep := failedDependency(a, failedSet); dep != "" {
+			fmt.Fprintf(writer, "ERROR: skipped, dependency %q failed\n", dep)
+			failed = append(failed, a.Name)
+			failedSet[a.Name] = true
+			continue
+		}
+		var evt *event.Event
+		evt, err = event.New(&event.Opts{
+			Target:     appTarget(a.Name),
+			Kind:       action.perm,
+			Owner:      t,
+			CustomData: event.FormToCustomData(r.Form),
+			Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(a)...),
+		})
+		if err == nil {
+			err = runBulkAction(a, action, process, t, r, writer)
+			evt.Done(err)
+		}
+		if err != nil {
+			fmt.Fprintf(writer, "ERROR: %s\n", err)
+			failed = append(failed, a.Name)
+			failedSet[a.Name] = true
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to %s %d of %d apps: %s", actionName, len(failed), len(apps), strings.Join(failed, ", "))
+	}
+	fmt.Fprintf(writer, "==== %s finished for %d apps ====\n", actionName, len(apps))
+	return nil
+}
+
+// runBulkAction runs the action in the app holding its lock, skipping apps
+// locked by other operations.
+func runBulkAction(a *app.App, action bulkAction, process string, t auth.Token, r *http.Request, w io.Writer) error {
+	locked, err := app.AcquireApplicationLock(a.Name, t.GetUserName(), fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return fmt.Errorf("app %q is locked", a.Name)
+	}
+	appLocks.add(a.Name)
+	defer appLocks.release(a.Name)
+	if action.checkServices {
+		err = a.CheckServicesHealth(requestID(r))
+		if err != nil {
+			return err
+		}
+	}
+	return action.run(a, process, w)
+}
+
+// failedDependency returns the name of a dependency of the app that failed
+// earlier in the bulk action, if any.
+func failedDependency(a *app.App, failed map[string]bool) string {
+	for _, dep := range a.Dependencies {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAppsBulkActionRestart(c *check.C) {
+	config.Set("docker:router", "fake")
+	defer config.Unset("docker:router")
+	a1 := app.App{Name: "app1", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "app2", Platform: "python", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	a3 := app.App{Name: "app3", Platform: "zend", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a3, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("platform=python&process=web")
+	request, err := http.NewRequest("POST", "/apps/restart", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*==== restart app \\"app1\\" \(1/2\) ====.*`)
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*==== restart finished for 2 apps ====.*`)
+	c.Assert(s.provisioner.Restarts(&a1, "web"), check.Equals, 1)
+	c.Assert(s.provisioner.Restarts(&a2, "web"), check.Equals, 1)
+	c.Assert(s.provisioner.Restarts(&a3, "web"), check.Equals, 0)
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget(a1.Name),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.update.restart",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":action", "value": "restart"},
+			{"name": "platform", "value": "python"},
+			{"name": "process", "value": "web"},
+		},
+	}, eventtest.HasEvent)
+	dbApp, err := app.GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, false)
+}
+
+func (s *S) TestAppsBulkActionSkipsLockedApps(c *check.C) {
+	config.Set("docker:router", "fake")
+	defer config.Unset("docker:router")
+	a := app.App{Name: "app1", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	locked, err := app.AcquireApplicationLock(a.Name, "someone", "deploy")
+	c.Assert(err, check.IsNil)
+	c.Assert(locked, check.Equals, true)
+	body := strings.NewReader("pool=" + a.Pool)
+	request, err := http.NewRequest("POST", "/apps/stop", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*app \\"app1\\" is locked.*failed to stop 1 of 1 apps: app1.*`)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Owner, check.Equals, "someone")
+}
+
+func (s *S) TestAppsBulkActionRequiresFilter(c *check.C) {
+	request, err := http.NewRequest("POST", "/apps/start", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestAppsBulkActionRestartFollowsDependencies(c *check.C) {
+	config.Set("docker:router", "fake")
+	defer config.Unset("docker:router")
+	a1 := app.App{Name: "app1", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "app2", Platform: "python", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddDependency(a2.Name)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("platform=python")
+	request, err := http.NewRequest("POST", "/apps/restart", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*==== restart app \\"app2\\" \(1/2\) ====.*==== restart app \\"app1\\" \(2/2\) ====.*`)
+}
+
+func (s *S) TestAppsBulkActionSkipsAppsWithFailedDependencies(c *check.C) {
+	config.Set("docker:router", "fake")
+	defer config.Unset("docker:router")
+	a1 := app.App{Name: "app1", Platform: "python", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := app.App{Name: "app2", Platform: "python", TeamOwner: s.team.Name}
+	err = app.CreateApp(&a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddDependency(a2.Name)
+	c.Assert(err, check.IsNil)
+	locked, err := app.AcquireApplicationLock(a2.Name, "someone", "deploy")
+	c.Assert(err, check.IsNil)
+	c.Assert(locked, check.Equals, true)
+	body := strings.NewReader("platform=python")
+	request, err := http.NewRequest("POST", "/apps/restart", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*skipped, dependency \\"app2\\" failed.*failed to restart 2 of 2 apps: app2, app1.*`)
+	c.Assert(s.provisioner.Restarts(&a1, ""), check.Equals, 0)
+}
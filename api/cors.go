@@ -0,0 +1,79 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/config"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE"}
+	defaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key"}
+	corsExposedHeaders = []string{
+		"Idempotency-Replayed",
+		"Location",
+		"RateLimit-Limit",
+		"RateLimit-Remaining",
+		"RateLimit-Reset",
+		"Retry-After",
+		"Supported-Crane",
+		"Supported-Tsuru",
+		"Supported-Tsuru-Admin",
+	}
+)
+
+// corsAllowedOrigin reports whether origin is listed in the
+// server:cors:allowed-origins config entry. The wildcard never matches when
+// credentials are allowed, as that would let any site make authenticated
+// requests.
+func corsAllowedOrigin(origin string, credentials bool) bool {
+	origins, _ := config.GetList("server:cors:allowed-origins")
+	for _, o := range origins {
+		if (o == "*" && !credentials) || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func corsList(key string, defaultValue []string) string {
+	values, err := config.GetList(key)
+	if err != nil || len(values) == 0 {
+		values = defaultValue
+	}
+	return strings.Join(values, ", ")
+}
+
+// corsMiddleware adds CORS headers to requests coming from the origins listed
+// in the server:cors:allowed-origins config entry, answering preflight
+// requests without reaching the handlers.
+func corsMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	origin := r.Header.Get("Origin")
+	credentials, _ := config.GetBool("server:cors:allow-credentials")
+	if origin == "" || !corsAllowedOrigin(origin, credentials) {
+		next(w, r)
+		return
+	}
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	if credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		header.Set("Access-Control-Allow-Methods", corsList("server:cors:allowed-methods", defaultCORSMethods))
+		header.Set("Access-Control-Allow-Headers", corsList("server:cors:allowed-headers", defaultCORSHeaders))
+		if maxAge, _ := config.GetString("server:cors:max-age"); maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	header.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+	next(w, r)
+}
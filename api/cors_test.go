@@ -0,0 +1,88 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/tsuru/config"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCORSMiddlewareDisabled(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Origin", "http://dashboard.tsuru.io")
+	h, log := doHandler()
+	corsMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Origin"), check.Equals, "")
+}
+
+func (s *S) TestCORSMiddlewareAllowedOrigin(c *check.C) {
+	config.Set("server:cors:allowed-origins", []interface{}{"http://dashboard.tsuru.io"})
+	config.Set("server:cors:allow-credentials", true)
+	defer config.Unset("server:cors")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Origin", "http://dashboard.tsuru.io")
+	h, log := doHandler()
+	corsMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Origin"), check.Equals, "http://dashboard.tsuru.io")
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Credentials"), check.Equals, "true")
+	c.Assert(recorder.Header().Get("Access-Control-Expose-Headers"), check.Matches, ".*Location.*")
+}
+
+func (s *S) TestCORSMiddlewareOriginNotAllowed(c *check.C) {
+	config.Set("server:cors:allowed-origins", []interface{}{"http://dashboard.tsuru.io"})
+	defer config.Unset("server:cors")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Origin", "http://evil.example.com")
+	h, log := doHandler()
+	corsMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Origin"), check.Equals, "")
+}
+
+func (s *S) TestCORSMiddlewareWildcardWithCredentials(c *check.C) {
+	config.Set("server:cors:allowed-origins", []interface{}{"*"})
+	config.Set("server:cors:allow-credentials", true)
+	defer config.Unset("server:cors")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Origin", "http://evil.example.com")
+	h, log := doHandler()
+	corsMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Origin"), check.Equals, "")
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Credentials"), check.Equals, "")
+}
+
+func (s *S) TestCORSMiddlewarePreflight(c *check.C) {
+	config.Set("server:cors:allowed-origins", []interface{}{"*"})
+	config.Set("server:cors:allowed-methods", []interface{}{"GET", "POST"})
+	config.Set("server:cors:max-age", 600)
+	defer config.Unset("server:cors")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("OPTIONS", "/apps", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Origin", "http://dashboard.tsuru.io")
+	request.Header.Set("Access-Control-Request-Method", "POST")
+	h, log := doHandler()
+	corsMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, false)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Origin"), check.Equals, "http://dashboard.tsuru.io")
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Methods"), check.Equals, "GET, POST")
+	c.Assert(recorder.Header().Get("Access-Control-Allow-Headers"), check.Equals, "Accept, Authorization, Content-Type, Idempotency-Key")
+	c.Assert(recorder.Header().Get("Access-Control-Max-Age"), check.Equals, "600")
+}
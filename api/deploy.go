@@ -50,9 +50,21 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 		}
 		file.Seek(0, os.SEEK_SET)
 	}
+	appName := r.URL.Query().Get(":appname")
+	var upload *app.ArchiveUpload
+	if uploadID := r.FormValue("upload"); uploadID != "" && file == nil {
+		upload, err = app.GetArchiveUpload(appName, uploadID)
+		if err != nil {
+			if err == app.ErrArchiveUploadNotFound {
+				return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+			}
+			return err
+		}
+		fileSize = upload.Size
+	}
 	archiveURL := r.FormValue("archive-url")
 	image := r.FormValue("image")
-	if image == "" && archiveURL == "" && file == nil {
+	if image == "" && archiveURL == "" && file == nil && upload == nil {
 		return &tsuruErrors.HTTP{
 			Code:    http.StatusBadRequest,
 			Message: "you must specify either the archive-url, a image url or upload a file.",
@@ -60,7 +72,6 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	}
 	commit := r.FormValue("commit")
 	w.Header().Set("Content-Type", "text")
-	appName := r.URL.Query().Get(":appname")
 	origin := r.FormValue("origin")
 	if image != "" {
 		origin = "image"
@@ -124,6 +135,9 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 		Build:      build,
 		Message:    message,
 	}
+	if upload != nil {
+		opts.File = upload.Open()
+	}
 	opts.GetKind()
 	if t.GetAppName() != app.InternalAppName {
 		canDeploy := permission.Check(t, permSchemeForDeploy(opts), contextsForApp(instance)...)
@@ -131,10 +145,14 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 			return &tsuruErrors.HTTP{Code: http.StatusForbidden, Message: "User does not have permission to do this action in this app"}
 		}
 	}
+	if err = checkFrozen(t, instance); err != nil {
+		return err
+	}
 	var imageID string
 	evt, err := event.New(&event.Opts{
 		Target:        appTarget(appName),
 		Kind:          permission.PermAppDeploy,
+		RequestID:     requestID(r),
 		RawOwner:      event.Owner{Type: event.OwnerTypeUser, Name: userName},
 		CustomData:    opts,
 		Allowed:       event.Allowed(permission.PermAppReadEvents, contextsForApp(instance)...),
@@ -149,8 +167,14 @@ func deploy(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	writer := io.NewKeepAliveWriter(w, 30*time.Second, "please wait...")
 	defer writer.Stop()
 	opts.OutputStream = writer
+	if r.Header.Get(io.PhaseMarkersHeader) == "true" {
+		opts.OutputStream = io.NewPhaseMarkerWriter(writer)
+	}
 	imageID, err = app.Deploy(opts)
 	if err == nil {
+		if upload != nil {
+			upload.Remove()
+		}
 		fmt.Fprintln(w, "\nOK")
 	}
 	return err
@@ -258,10 +282,14 @@ func deployRollback(w http.ResponseWriter, r *http.Request, t auth.Token) error
 	if !canRollback {
 		return &tsuruErrors.HTTP{Code: http.StatusForbidden, Message: permission.ErrUnauthorized.Error()}
 	}
+	if err = checkFrozen(t, instance); err != nil {
+		return err
+	}
 	var imageID string
 	evt, err := event.New(&event.Opts{
 		Target:        appTarget(appName),
 		Kind:          permission.PermAppDeploy,
+		RequestID:     requestID(r),
 		Owner:         t,
 		CustomData:    opts,
 		Allowed:       event.Allowed(permission.PermAppReadEvents, contextsForApp(instance)...),
@@ -311,6 +339,69 @@ func deploysList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return json.NewEncoder(w).Encode(deploys)
 }
 
+// title: deploy stats
+// path: /deploys/stats
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+func deployStats(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	contexts := permission.ContextsForPermission(t, permission.PermAppReadDeploy)
+	if len(contexts) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	query := r.URL.Query()
+	filter := appFilterByContext(contexts, nil)
+	filter.Name = query.Get("app")
+	filter.Platform = query.Get("platform")
+	opts := app.DeployStatsOptions{
+		Filter:   filter,
+		GroupBy:  query.Get("groupBy"),
+		Since:    time.Now().UTC().Add(-30 * 24 * time.Hour),
+		Interval: 24 * time.Hour,
+	}
+	var err error
+	if since := query.Get("since"); since != "" {
+		opts.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for since: " + since}
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		opts.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for until: " + until}
+		}
+	}
+	switch interval := query.Get("interval"); interval {
+	case "", "day":
+	case "week":
+		opts.Interval = 7 * 24 * time.Hour
+	default:
+		seconds, err := strconv.Atoi(interval)
+		if err != nil || seconds <= 0 {
+			return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for interval: " + interval}
+		}
+		opts.Interval = time.Duration(seconds) * time.Second
+	}
+	if opts.GroupBy != "" && opts.GroupBy != app.DeployStatsByApp && opts.GroupBy != app.DeployStatsByPlatform {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "invalid value for groupBy: " + opts.GroupBy}
+	}
+	stats, err := app.GetDeployStats(opts)
+	if err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Add("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(stats)
+}
+
 // title: deploy info
 // path: /deploys/{deploy}
 // method: GET
@@ -828,6 +828,71 @@ func (s *DeploySuite) TestDeployListAppWithNoDeploys(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
 }
 
+func (s *DeploySuite) TestDeployStats(c *check.C) {
+	user, _ := s.token.User()
+	for _, name := range []string{"g1", "ge"} {
+		a := app.App{Name: name, Platform: "python", TeamOwner: s.team.Name}
+		err := app.CreateApp(&a, user)
+		c.Assert(err, check.IsNil)
+	}
+	timestamp := time.Now().UTC().Add(-time.Hour)
+	insertDeploysAsEvents([]app.DeployData{
+		{App: "g1", Timestamp: timestamp},
+		{App: "g1", Timestamp: timestamp.Add(time.Minute)},
+		{App: "ge", Timestamp: timestamp},
+		{App: "ge", Timestamp: timestamp.Add(-60 * 24 * time.Hour)},
+	}, c)
+	request, err := http.NewRequest("GET", "/deploys/stats?groupBy=platform", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result []app.DeployStats
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "python")
+	c.Assert(result[0].Count, check.Equals, 3)
+	c.Assert(result[0].Failures, check.Equals, 0)
+	request, err = http.NewRequest("GET", "/deploys/stats?app=g1&interval=week", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "g1")
+	c.Assert(result[0].Count, check.Equals, 2)
+	c.Assert(result[0].Periods, check.HasLen, 1)
+}
+
+func (s *DeploySuite) TestDeployStatsNoDeploys(c *check.C) {
+	request, err := http.NewRequest("GET", "/deploys/stats", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *DeploySuite) TestDeployStatsInvalidParams(c *check.C) {
+	server := RunServer(true)
+	for _, query := range []string{"since=yesterday", "until=1", "interval=month", "interval=-10", "groupBy=pool"} {
+		request, err := http.NewRequest("GET", "/deploys/stats?"+query, nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("query: %s", query))
+	}
+}
+
 func (s *DeploySuite) TestDeployInfoByAdminUser(c *check.C) {
 	a := app.App{Name: "g1", Platform: "python", TeamOwner: s.team.Name}
 	user, _ := s.token.User()
@@ -10,6 +10,7 @@ import (
 	"net/http"
 
 	"github.com/ajg/form"
+	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
@@ -103,6 +104,63 @@ func eventInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return json.NewEncoder(w).Encode(e)
 }
 
+// correlation is the result of looking up a correlation ID: the events
+// linked by it and the log lines stamped with it.
+type correlation struct {
+	Events []event.Event `json:"events"`
+	Logs   []app.Applog  `json:"logs"`
+}
+
+// title: correlation info
+// path: /correlations/{id}
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func correlationInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	id := r.URL.Query().Get(":id")
+	perms, err := t.Permissions()
+	if err != nil {
+		return err
+	}
+	events, err := event.List(&event.Filter{CorrelationID: id, Permissions: perms})
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	result := correlation{Events: events, Logs: []app.Applog{}}
+	seen := map[string]bool{}
+	for i := range events {
+		target := events[i].Target
+		if target.Type != event.TargetTypeApp || seen[target.Value] {
+			continue
+		}
+		seen[target.Value] = true
+		a, err := app.GetByName(target.Value)
+		if err == app.ErrAppNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !permission.Check(t, permission.PermAppReadLog, contextsForApp(a)...) {
+			continue
+		}
+		logs, err := a.LastLogs(a.GetLogRetention().Lines, app.Applog{CorrelationID: id})
+		if err != nil {
+			return err
+		}
+		result.Logs = append(result.Logs, logs...)
+	}
+	w.Header().Add("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(result)
+}
+
 // title: event cancel
 // path: /events/{uuid}/cancel
 // method: POST
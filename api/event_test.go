@@ -18,6 +18,7 @@ import (
 	"github.com/tsuru/tsuru/db/dbtest"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/repository/repositorytest"
 	"github.com/tsuru/tsuru/router/routertest"
 	"gopkg.in/check.v1"
@@ -443,3 +444,68 @@ func (s *EventSuite) TestEventCancelWithoutPermission(c *check.C) {
 	server.ServeHTTP(recorder, request)
 	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
 }
+
+func (s *EventSuite) TestCorrelationInfo(c *check.C) {
+	provision.DefaultProvisioner = "fake"
+	a := app.App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	allowed := event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxTeam, s.team.Name))
+	deployEvt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Owner:   s.token,
+		Kind:    permission.PermAppDeploy,
+		Allowed: allowed,
+	})
+	c.Assert(err, check.IsNil)
+	correlationID := deployEvt.CorrelationID
+	_, err = event.New(&event.Opts{
+		Target:        event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Owner:         s.token,
+		Kind:          permission.PermAppUpdateRestart,
+		Allowed:       allowed,
+		CorrelationID: correlationID,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = event.New(&event.Opts{
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "other-app"},
+		Owner:   s.token,
+		Kind:    permission.PermAppDeploy,
+		Allowed: allowed,
+	})
+	c.Assert(err, check.IsNil)
+	err = a.LogWithCorrelationID("building image", "tsuru", "api", correlationID)
+	c.Assert(err, check.IsNil)
+	err = a.Log("unrelated", "app", "unit1")
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/correlations/"+correlationID, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result struct {
+		Events []event.Event
+		Logs   []app.Applog
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Events, check.HasLen, 2)
+	for i := range result.Events {
+		c.Assert(result.Events[i].CorrelationID, check.Equals, correlationID)
+	}
+	c.Assert(result.Logs, check.HasLen, 1)
+	c.Assert(result.Logs[0].Message, check.Equals, "building image")
+}
+
+func (s *EventSuite) TestCorrelationInfoNotFound(c *check.C) {
+	request, err := http.NewRequest("GET", "/correlations/unknown", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ajg/form"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var eventStreamInterval = 2 * time.Second
+
+type eventStreamTracker struct {
+	once      sync.Once
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (t *eventStreamTracker) doneChan() <-chan struct{} {
+	t.once.Do(func() {
+		t.done = make(chan struct{})
+	})
+	return t.done
+}
+
+func (t *eventStreamTracker) String() string {
+	return "event streams"
+}
+
+func (t *eventStreamTracker) Shutdown() {
+	t.doneChan()
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+var eventStreams eventStreamTracker
+
+// eventStreamer polls the events collection, writing new events and the
+// completion of running events as Server-Sent Events. Each event is flushed
+// to the client as soon as it's written.
+type eventStreamer struct {
+	filter  *event.Filter
+	seen    map[bson.ObjectId]time.Time
+	running map[bson.ObjectId]struct{}
+}
+
+func newEventStreamer(filter *event.Filter) *eventStreamer {
+	return &eventStreamer{
+		filter:  filter,
+		seen:    make(map[bson.ObjectId]time.Time),
+		running: make(map[bson.ObjectId]struct{}),
+	}
+}
+
+func (s *eventStreamer) poll(w io.Writer) error {
+	evts, err := event.List(s.filter)
+	if err != nil {
+		return err
+	}
+	for i := range evts {
+		evt := &evts[i]
+		if _, ok := s.seen[evt.UniqueID]; ok {
+			continue
+		}
+		s.seen[evt.UniqueID] = evt.StartTime
+		if evt.StartTime.After(s.filter.Since) {
+			s.filter.Since = evt.StartTime
+		}
+		name := "end"
+		if evt.Running {
+			name = "start"
+			s.running[evt.UniqueID] = struct{}{}
+		}
+		err = writeServerSentEvent(w, name, evt)
+		if err != nil {
+			return err
+		}
+	}
+	for id, startTime := range s.seen {
+		if startTime.Before(s.filter.Since) {
+			delete(s.seen, id)
+		}
+	}
+	for id := range s.running {
+		evt, err := event.GetByID(id)
+		if err == event.ErrEventNotFound {
+			delete(s.running, id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if evt.Running {
+			continue
+		}
+		delete(s.running, id)
+		err = writeServerSentEvent(w, "end", evt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeServerSentEvent(w io.Writer, name string, evt *event.Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", evt.UniqueID.Hex(), name, data)
+	if err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// title: event stream
+// path: /events/stream
+// method: GET
+// produce: text/event-stream
+// responses:
+//   200: OK
+//   400: Invalid filters
+//   401: Unauthorized
+func eventStream(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	r.ParseForm()
+	filter := &event.Filter{}
+	dec := form.NewDecoder(nil)
+	dec.IgnoreUnknownKeys(true)
+	dec.IgnoreCase(true)
+	err := dec.DecodeValues(&filter, r.Form)
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: fmt.Sprintf("unable to parse event filters: %s", err)}
+	}
+	filter.PruneUserValues()
+	filter.Permissions, err = t.Permissions()
+	if err != nil {
+		return err
+	}
+	filter.Sort = "starttime"
+	if filter.Since.IsZero() {
+		filter.Since = time.Now().UTC()
+	}
+	var closeChan <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closeChan = notifier.CloseNotify()
+	} else {
+		closeChan = make(chan bool)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	streamer := newEventStreamer(filter)
+	for {
+		err = streamer.poll(w)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-closeChan:
+			return nil
+		case <-eventStreams.doneChan():
+			return nil
+		case <-time.After(eventStreamInterval):
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/event"
+	"gopkg.in/check.v1"
+)
+
+func (s *EventSuite) TestEventStreamerPoll(c *check.C) {
+	evts, err := s.insertEvents("app", c)
+	c.Assert(err, check.IsNil)
+	perms, err := s.token.Permissions()
+	c.Assert(err, check.IsNil)
+	streamer := newEventStreamer(&event.Filter{Permissions: perms, Sort: "starttime"})
+	var buf bytes.Buffer
+	err = streamer.poll(&buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Count(buf.String(), "event: start\n"), check.Equals, 9)
+	c.Assert(strings.Count(buf.String(), "event: end\n"), check.Equals, 1)
+	c.Assert(buf.String(), check.Matches, "(?s).*id: "+evts[1].UniqueID.Hex()+"\nevent: end\n.*")
+	buf.Reset()
+	err = evts[0].Done(nil)
+	c.Assert(err, check.IsNil)
+	err = streamer.poll(&buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Matches, "id: "+evts[0].UniqueID.Hex()+"\nevent: end\ndata: .*\n\n")
+	buf.Reset()
+	err = streamer.poll(&buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *EventSuite) TestEventStreamInvalidFilter(c *check.C) {
+	request, err := http.NewRequest("GET", "/events/stream?running=xyz", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *EventSuite) TestEventStreamerPollFlushes(c *check.C) {
+	_, err := s.insertEvents("app", c)
+	c.Assert(err, check.IsNil)
+	perms, err := s.token.Permissions()
+	c.Assert(err, check.IsNil)
+	streamer := newEventStreamer(&event.Filter{Permissions: perms, Sort: "starttime"})
+	recorder := httptest.NewRecorder()
+	err = streamer.poll(recorder)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Flushed, check.Equals, true)
+}
+
+func (s *EventSuite) TestEventStreamTrackerShutdownTwice(c *check.C) {
+	var tracker eventStreamTracker
+	tracker.Shutdown()
+	tracker.Shutdown()
+	select {
+	case <-tracker.doneChan():
+	default:
+		c.Fatal("done channel should be closed")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// freezeContext returns the target and the permission contexts of the freeze
+// of the app with the given name, or of the global freeze when it's empty.
+func freezeContext(appName string, r *http.Request) (event.Target, []permission.PermissionContext, error) {
+	if appName == "" {
+		return event.Target{Type: event.TargetTypeGlobal, Value: "freeze"}, nil, nil
+	}
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return event.Target{}, nil, err
+	}
+	return appTarget(appName), contextsForApp(&a), nil
+}
+
+// checkFrozen returns an error when the app is frozen, unless the user is
+// allowed to manage the freeze, in which case the change goes through.
+func checkFrozen(t auth.Token, a *app.App) error {
+	err := a.CheckFreeze()
+	frozenErr, ok := err.(*app.AppFrozenError)
+	if !ok {
+		return err
+	}
+	var contexts []permission.PermissionContext
+	if !frozenErr.Freeze.IsGlobal() {
+		contexts = contextsForApp(a)
+	}
+	if permission.Check(t, permission.PermAppAdminFreeze, contexts...) {
+		return nil
+	}
+	return &errors.HTTP{Code: http.StatusConflict, Message: frozenErr.Error()}
+}
+
+// title: freeze list
+// path: /freezes
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func listFreezes(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	if !permission.Check(t, permission.PermAppAdminFreeze) {
+		return permission.ErrUnauthorized
+	}
+	freezes, err := app.ListFreezes()
+	if err != nil {
+		return err
+	}
+	if len(freezes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(freezes)
+}
+
+// title: freeze add
+// path: /freezes
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Frozen
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func addFreeze(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	appName := r.FormValue("app")
+	reason := r.FormValue("reason")
+	if reason == "" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide the reason of the freeze."}
+	}
+	target, contexts, err := freezeContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppAdminFreeze, contexts...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     target,
+		Kind:       permission.PermAppAdminFreeze,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return app.AddFreeze(app.Freeze{App: appName, Reason: reason, Owner: t.GetUserName()})
+}
+
+// title: freeze remove
+// path: /freezes
+// method: DELETE
+// responses:
+//   200: Unfrozen
+//   401: Unauthorized
+//   404: Freeze not found
+func removeFreeze(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	appName := r.URL.Query().Get("app")
+	target, contexts, err := freezeContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppAdminFreeze, contexts...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     target,
+		Kind:       permission.PermAppAdminFreeze,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.URL.Query()),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contexts...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = app.RemoveFreeze(appName)
+	if err == app.ErrFreezeNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,292 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAddFreeze(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("app=myapp&reason=black+friday")
+	request, err := http.NewRequest("POST", "/freezes", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	freezes, err := app.ListFreezes()
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 1)
+	c.Assert(freezes[0].App, check.Equals, "myapp")
+	c.Assert(freezes[0].Reason, check.Equals, "black friday")
+	c.Assert(freezes[0].Owner, check.Equals, s.token.GetUserName())
+	c.Assert(eventtest.EventDesc{
+		Target: appTarget("myapp"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "app.admin.freeze",
+		StartCustomData: []map[string]interface{}{
+			{"name": "app", "value": "myapp"},
+			{"name": "reason", "value": "black friday"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestAddFreezeGlobalRequiresGlobalPermission(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppAdminFreeze,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	body := strings.NewReader("reason=holidays")
+	request, err := http.NewRequest("POST", "/freezes", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestAddFreezeWithoutReason(c *check.C) {
+	request, err := http.NewRequest("POST", "/freezes", strings.NewReader(""))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestListFreezes(c *check.C) {
+	err := app.AddFreeze(app.Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/freezes", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var freezes []app.Freeze
+	err = json.NewDecoder(recorder.Body).Decode(&freezes)
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 1)
+	c.Assert(freezes[0].Reason, check.Equals, "holidays")
+}
+
+func (s *S) TestRemoveFreeze(c *check.C) {
+	err := app.AddFreeze(app.Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/freezes", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	freezes, err := app.ListFreezes()
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 0)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestSetEnvFrozenApp(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: "myapp", Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateEnvSet,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	body := strings.NewReader("Envs.0.Name=DATABASE_HOST&Envs.0.Value=localhost&NoRestart=true")
+	request, err := http.NewRequest("POST", "/apps/myapp/env", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), check.Matches, `app "myapp" is frozen by admin@tsuru.io since .*: black friday\n`)
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	_, ok := dbApp.Env["DATABASE_HOST"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestSetEnvFrozenAppAllowedWithFreezePermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: "myapp", Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateEnvSet,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppAdminFreeze,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	body := strings.NewReader("Envs.0.Name=DATABASE_HOST&Envs.0.Value=localhost&NoRestart=true")
+	request, err := http.NewRequest("POST", "/apps/myapp/env", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+}
+
+func (s *S) TestAddUnitsGlobalFreeze(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdateUnitAdd,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	}, permission.Permission{
+		Scheme:  permission.PermAppAdminFreeze,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	body := strings.NewReader("units=1&process=web")
+	request, err := http.NewRequest("PUT", "/apps/myapp/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), check.Matches, `all apps are frozen by admin@tsuru.io since .*: holidays\n`)
+	c.Assert(s.provisioner.GetUnits(&a), check.HasLen, 0)
+}
+
+func (s *S) TestRestartFrozenAppWithAppUpdatePermission(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: "myapp", Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppUpdate,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	request, err := http.NewRequest("POST", "/apps/myapp/restart", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), check.Matches, `app "myapp" is frozen by admin@tsuru.io since .*: black friday\n`)
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 0)
+	request, err = http.NewRequest("DELETE", "/freezes?app=myapp", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) serveFrozenApp(c *check.C, scheme *permission.PermissionScheme, method, url, body string) *httptest.ResponseRecorder {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: "myapp", Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  scheme,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (s *S) TestAppDeleteFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppDelete, "DELETE", "/apps/myapp", "")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	_, err := app.GetByName("myapp")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestSetCNameFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateCnameAdd, "POST", "/apps/myapp/cname", "cname=web.example.com")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	dbApp, err := app.GetByName("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.CName, check.HasLen, 0)
+}
+
+func (s *S) TestRunCommandFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppRun, "POST", "/apps/myapp/run", "command=ls")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestGrantAppAccessFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateGrant, "PUT", "/apps/myapp/teams/"+s.team.Name, "")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestSetAppMetadataFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateMetadata, "POST", "/apps/myapp/metadata", "label=tier=1")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestChangeAppQuotaFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppAdminQuota, "PUT", "/apps/myapp/quota", "limit=5")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestAddLogDrainFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateLogDrain, "POST", "/apps/myapp/log-drains", "url=syslog%3A%2F%2Flogs.example.com%3A514")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestSetLogRetentionFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateLogRetention, "PUT", "/apps/myapp/log/retention", "lines=100&max-age=3600")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestSetSLOFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppUpdateSlo, "PUT", "/apps/myapp/slo", "availability=99.5")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestCreateArchiveUploadFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppDeployUpload, "POST", "/apps/myapp/deploy/uploads", "")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestCreateScopedTokenFrozenApp(c *check.C) {
+	recorder := s.serveFrozenApp(c, permission.PermAppTokenCreate, "POST", "/apps/myapp/tokens", "permission=app.deploy")
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+}
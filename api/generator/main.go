@@ -0,0 +1,118 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var fileTpl = `// AUTOMATICALLY GENERATED FILE - DO NOT EDIT!
+// Please run 'go generate' to update this file.
+//
+// Copyright {{.Time.Year}} tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+var handlerDocs = []handlerDoc{
+{{range .Handlers}} \
+	{
+		Title: {{printf "%q" .Title}},
+		Path: {{printf "%q" .Path}},
+		Method: {{printf "%q" .Method}},
+{{if .Consume}} \
+		Consume: {{printf "%q" .Consume}},
+{{end}} \
+{{if .Produce}} \
+		Produce: {{printf "%q" .Produce}},
+{{end}} \
+		Responses: map[string]string{
+{{range $code, $desc := .Responses}} \
+			{{printf "%q" $code}}: {{printf "%q" $desc}},
+{{end}} \
+		},
+	},
+{{end}} \
+}
+`
+
+type handler struct {
+	Title     string
+	Path      string
+	Method    string
+	Consume   string
+	Produce   string
+	Responses map[string]string
+}
+
+type handlerList []handler
+
+func (l handlerList) Len() int      { return len(l) }
+func (l handlerList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l handlerList) Less(i, j int) bool {
+	if l[i].Path == l[j].Path {
+		return l[i].Method < l[j].Method
+	}
+	return l[i].Path < l[j].Path
+}
+
+type context struct {
+	Time     time.Time
+	Handlers handlerList
+}
+
+func main() {
+	in := flag.String("i", "", "handlers yaml file")
+	out := flag.String("o", "", "output file")
+	flag.Parse()
+	tmpl, err := template.New("tpl").Parse(fileTpl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawYaml, err := ioutil.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var docs struct {
+		Handlers handlerList
+	}
+	err = yaml.Unmarshal(rawYaml, &docs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Stable(docs.Handlers)
+	data := context{
+		Time:     time.Now(),
+		Handlers: docs.Handlers,
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawFile := buf.Bytes()
+	rawFile = bytes.Replace(rawFile, []byte("\\\n"), []byte{}, -1)
+	formatedFile, err := format.Source(rawFile)
+	if err != nil {
+		log.Fatalf("unable to format code: %s\n%s", err, rawFile)
+	}
+	file, err := os.OpenFile(*out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	file.Write(formatedFile)
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/tsuru/tsuru/io"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGzipMiddlewareJSON(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Accept-Encoding", "deflate, gzip;q=1.0")
+	gzipMiddleware(recorder, request, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"version": "1.0"})
+	})
+	c.Assert(recorder.Header().Get("Content-Encoding"), check.Equals, "gzip")
+	c.Assert(recorder.Header().Get("Vary"), check.Equals, "Accept-Encoding")
+	reader, err := gzip.NewReader(recorder.Body)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadAll(reader)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "{\"version\":\"1.0\"}\n")
+}
+
+func (s *S) TestGzipMiddlewareNotAccepted(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	gzipMiddleware(recorder, request, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	c.Assert(recorder.Header().Get("Content-Encoding"), check.Equals, "")
+	c.Assert(recorder.Body.String(), check.Equals, "{}")
+}
+
+func (s *S) TestGzipMiddlewareStreamingIsFlushed(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	gzipMiddleware(recorder, request, func(w http.ResponseWriter, r *http.Request) {
+		fw := io.FlushingWriter{ResponseWriter: w}
+		fw.Header().Set("Content-Type", "application/x-json-stream")
+		fw.Write([]byte("step 1\n"))
+		c.Assert(recorder.Flushed, check.Equals, true)
+		c.Assert(recorder.Body.String(), check.Equals, "step 1\n")
+	})
+	c.Assert(recorder.Header().Get("Content-Encoding"), check.Equals, "")
+}
+
+func (s *S) TestGzipMiddlewareIgnoresErrors(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	gzipMiddleware(recorder, request, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	c.Assert(recorder.Header().Get("Content-Encoding"), check.Equals, "")
+	c.Assert(recorder.Body.String(), check.Equals, "not found\n")
+}
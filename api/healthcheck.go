@@ -6,18 +6,55 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/tsuru/tsuru/hc"
 )
 
+type componentStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+type healthcheckStatus struct {
+	Status     string            `json:"status"`
+	Components []componentStatus `json:"components"`
+}
+
+// readinessTracker reports the API as not ready once the server starts
+// shutting down, so load balancers stop sending new requests to it.
+type readinessTracker struct {
+	shuttingDown int32
+}
+
+func (t *readinessTracker) String() string {
+	return "readiness"
+}
+
+func (t *readinessTracker) Shutdown() {
+	atomic.StoreInt32(&t.shuttingDown, 1)
+}
+
+func (t *readinessTracker) isShuttingDown() bool {
+	return atomic.LoadInt32(&t.shuttingDown) == 1
+}
+
+var apiReadiness readinessTracker
+
 // title: healthcheck
 // path: /healthcheck
 // method: GET
 // responses:
 //   200: OK
 //   500: Internal server error
+//
+// healthcheck is the liveness check of the API server. Unless check=all is
+// given, it doesn't check any external component.
 func healthcheck(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Query().Get("check") == "all" {
 		fullHealthcheck(w, r)
@@ -27,15 +64,76 @@ func healthcheck(w http.ResponseWriter, r *http.Request) {
 }
 
 func fullHealthcheck(w http.ResponseWriter, r *http.Request) {
-	var buf bytes.Buffer
 	results := hc.Check()
 	status := http.StatusOK
 	for _, result := range results {
-		fmt.Fprintf(&buf, "%s: %s (%s)\n", result.Name, result.Status, result.Duration)
 		if result.Status != hc.HealthCheckOK {
 			status = http.StatusInternalServerError
 		}
 	}
+	if r.URL.Query().Get("format") == "json" {
+		jsonHealthcheck(w, status, results)
+		return
+	}
+	var buf bytes.Buffer
+	for _, result := range results {
+		fmt.Fprintf(&buf, "%s: %s (%s)\n", result.Name, result.Status, result.Duration)
+	}
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func jsonHealthcheck(w http.ResponseWriter, status int, results []hc.Result) {
+	data := healthcheckStatus{
+		Status:     hc.HealthCheckOK,
+		Components: make([]componentStatus, len(results)),
+	}
+	if status != http.StatusOK {
+		data.Status = "FAIL"
+	}
+	for i, result := range results {
+		data.Components[i] = componentStatus{
+			Name:      result.Name,
+			Status:    result.Status,
+			LatencyMs: float64(result.Duration) / float64(time.Millisecond),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// title: readiness
+// path: /readiness
+// method: GET
+// responses:
+//   200: OK
+//   503: Service unavailable
+//
+// readinessCheck checks all components the API depends on, like the database,
+// routers and queue, returning 503 when any of them is failing or when the
+// server is shutting down.
+func readinessCheck(w http.ResponseWriter, r *http.Request) {
+	if apiReadiness.isShuttingDown() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("shutting down\n"))
+		return
+	}
+	results := hc.Check()
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Status != hc.HealthCheckOK {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if r.URL.Query().Get("format") == "json" {
+		jsonHealthcheck(w, status, results)
+		return
+	}
+	var buf bytes.Buffer
+	for _, result := range results {
+		fmt.Fprintf(&buf, "%s: %s (%s)\n", result.Name, result.Status, result.Duration)
+	}
 	w.WriteHeader(status)
 	w.Write(buf.Bytes())
 }
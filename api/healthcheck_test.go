@@ -5,9 +5,11 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/tsuru/tsuru/hc"
 	"gopkg.in/check.v1"
 )
 
@@ -23,3 +25,64 @@ func (s *HealthCheckSuite) TestHealthCheck(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusOK)
 	c.Assert(recorder.Body.String(), check.Equals, "WORKING")
 }
+
+func (s *HealthCheckSuite) TestFullHealthCheckJSON(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/healthcheck?check=all&format=json", nil)
+	c.Assert(err, check.IsNil)
+	healthcheck(recorder, request)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result healthcheckStatus
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(result.Components) > 0, check.Equals, true)
+	expectedStatus := hc.HealthCheckOK
+	for _, component := range result.Components {
+		c.Assert(component.Name, check.Not(check.Equals), "")
+		if component.Status != hc.HealthCheckOK {
+			expectedStatus = "FAIL"
+		}
+	}
+	c.Assert(result.Status, check.Equals, expectedStatus)
+	if expectedStatus == hc.HealthCheckOK {
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	} else {
+		c.Assert(recorder.Code, check.Equals, http.StatusInternalServerError)
+	}
+}
+
+func (s *HealthCheckSuite) TestReadinessCheck(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/readiness?format=json", nil)
+	c.Assert(err, check.IsNil)
+	readinessCheck(recorder, request)
+	var result healthcheckStatus
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	if result.Status == hc.HealthCheckOK {
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	} else {
+		c.Assert(recorder.Code, check.Equals, http.StatusServiceUnavailable)
+	}
+}
+
+func (s *HealthCheckSuite) TestReadinessCheckShuttingDown(c *check.C) {
+	defer func() { apiReadiness = readinessTracker{} }()
+	apiReadiness.Shutdown()
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/readiness", nil)
+	c.Assert(err, check.IsNil)
+	readinessCheck(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusServiceUnavailable)
+	c.Assert(recorder.Body.String(), check.Equals, "shutting down\n")
+}
+
+func (s *HealthCheckSuite) TestHealthCheckIgnoresShutdown(c *check.C) {
+	defer func() { apiReadiness = readinessTracker{} }()
+	apiReadiness.Shutdown()
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/healthcheck", nil)
+	c.Assert(err, check.IsNil)
+	healthcheck(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+}
@@ -0,0 +1,184 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/context"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotency-Replayed"
+	idempotencyMaxBodySize    = 1024 * 1024
+
+	// statusUnprocessableEntity is not defined in net/http before Go 1.7.
+	statusUnprocessableEntity = 422
+)
+
+// idempotentResult is the stored response of a request sent with an
+// Idempotency-Key header. While the request is running, Done is false.
+type idempotentResult struct {
+	ID          string `bson:"_id"`
+	Method      string
+	Path        string
+	Done        bool
+	Status      int
+	ContentType string
+	Location    string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// idempotencyRecorder keeps a copy of the response written by a handler, up
+// to idempotencyMaxBodySize bytes, while still sending it to the client.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotencyRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := idempotencyMaxBodySize - w.body.Len(); room > 0 {
+		if len(data) < room {
+			room = len(data)
+		}
+		w.body.Write(data[:room])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *idempotencyRecorder) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+func idempotencyWindow() time.Duration {
+	window, _ := config.GetInt("server:idempotency-window")
+	if window <= 0 {
+		window = 24 * 60 * 60
+	}
+	return time.Duration(window) * time.Second
+}
+
+// idempotencyMiddleware replays the stored response of POST and PUT requests
+// retried with the same Idempotency-Key header by the same client, instead of
+// running them again. Failed requests are not stored, so they can be retried.
+// Concurrent requests with a key in use are rejected.
+func idempotencyMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	t := context.GetAuthToken(r)
+	if key == "" || t == nil || (r.Method != "POST" && r.Method != "PUT") {
+		next(w, r)
+		return
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		context.AddRequestError(r, err)
+		return
+	}
+	defer conn.Close()
+	coll := conn.IdempotencyKeys()
+	id := fmt.Sprintf("%x", sha256.Sum256([]byte(t.GetAppName()+"\x00"+t.GetUserName()+"\x00"+key)))
+	result := idempotentResult{
+		ID:        id,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		ExpiresAt: time.Now().UTC().Add(idempotencyWindow()),
+	}
+	err = coll.Insert(result)
+	if mgo.IsDup(err) {
+		err = coll.FindId(id).One(&result)
+		if err == nil {
+			replayIdempotentResult(w, r, &result)
+			return
+		}
+	}
+	if err != nil {
+		context.AddRequestError(r, err)
+		return
+	}
+	recorder := &idempotencyRecorder{ResponseWriter: w}
+	done := false
+	defer func() {
+		if !done {
+			err := coll.RemoveId(id)
+			if err != nil {
+				log.Errorf("unable to remove idempotency key %q: %s", key, err)
+			}
+		}
+	}()
+	next(recorder, r)
+	if context.GetRequestError(r) != nil || recorder.status == 0 || recorder.status >= 400 {
+		return
+	}
+	err = coll.UpdateId(id, bson.M{"$set": bson.M{
+		"done":        true,
+		"status":      recorder.status,
+		"contenttype": recorder.Header().Get("Content-Type"),
+		"location":    recorder.Header().Get("Location"),
+		"body":        recorder.body.Bytes(),
+	}})
+	if err != nil {
+		log.Errorf("unable to store result for idempotency key %q: %s", key, err)
+		return
+	}
+	done = true
+}
+
+func replayIdempotentResult(w http.ResponseWriter, r *http.Request, result *idempotentResult) {
+	if result.Method != r.Method || result.Path != r.URL.Path {
+		context.AddRequestError(r, &tsuruErrors.HTTP{
+			Code:    statusUnprocessableEntity,
+			Message: fmt.Sprintf("idempotency key already used for %s %s", result.Method, result.Path),
+		})
+		return
+	}
+	if !result.Done {
+		context.AddRequestError(r, &tsuruErrors.HTTP{
+			Code:    http.StatusConflict,
+			Message: "a request with the same idempotency key is still running",
+		})
+		return
+	}
+	if result.ContentType != "" {
+		w.Header().Set("Content-Type", result.ContentType)
+	}
+	if result.Location != "" {
+		w.Header().Set("Location", result.Location)
+	}
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(result.Status)
+	w.Write(result.Body)
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/api/context"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestIdempotencyMiddlewareReplaysResult(c *check.C) {
+	m := RunServer(true)
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest("POST", "/apps", strings.NewReader("name=someapp&platform=zend"))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		request.Header.Set("Idempotency-Key", "create-someapp")
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+		c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+		bodies = append(bodies, recorder.Body.String())
+		if i == 1 {
+			c.Assert(recorder.Header().Get("Idempotency-Replayed"), check.Equals, "true")
+		} else {
+			c.Assert(recorder.Header().Get("Idempotency-Replayed"), check.Equals, "")
+		}
+	}
+	c.Assert(bodies[0], check.Equals, bodies[1])
+	count, err := s.conn.Apps().Find(bson.M{"name": "someapp"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 1)
+	evts, err := event.List(&event.Filter{KindName: permission.PermAppCreate.FullName()})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+}
+
+func (s *S) TestIdempotencyMiddlewareDoesNotStoreFailures(c *check.C) {
+	m := RunServer(true)
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest("POST", "/apps", strings.NewReader("name=invalid_app_name&platform=zend"))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		request.Header.Set("Idempotency-Key", "create-someapp")
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+		c.Assert(recorder.Header().Get("Idempotency-Replayed"), check.Equals, "")
+	}
+	count, err := s.conn.IdempotencyKeys().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
+
+func (s *S) TestIdempotencyMiddlewareKeyUsedInOtherRequest(c *check.C) {
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	m := RunServer(true)
+	request, err := http.NewRequest("POST", "/apps/myapp/restart", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Idempotency-Key", "mykey")
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	request, err = http.NewRequest("POST", "/apps/myapp/stop", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	request.Header.Set("Idempotency-Key", "mykey")
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, statusUnprocessableEntity)
+}
+
+func (s *S) TestReplayIdempotentResultStillRunning(c *check.C) {
+	request, err := http.NewRequest("POST", "/apps", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	replayIdempotentResult(recorder, request, &idempotentResult{ID: "running", Method: "POST", Path: "/apps"})
+	err = context.GetRequestError(request)
+	c.Assert(err, check.NotNil)
+	e, ok := err.(*errors.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Code, check.Equals, http.StatusConflict)
+}
+
+func (s *S) TestIdempotencyMiddlewareIgnoresGet(c *check.C) {
+	request, err := http.NewRequest("GET", "/apps", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Idempotency-Key", "mykey")
+	context.SetAuthToken(request, s.token)
+	h, log := doHandler()
+	idempotencyMiddleware(httptest.NewRecorder(), request, h)
+	c.Assert(log.called, check.Equals, true)
+	count, err := s.conn.IdempotencyKeys().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
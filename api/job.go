@@ -0,0 +1,131 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/api/context"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var jobLogUpdateInterval = time.Second
+
+// jobLogWriter writes the output of an asynchronous job in its event,
+// persisting the log at most once every jobLogUpdateInterval so clients
+// polling the job can follow its progress. It's safe for concurrent use, as
+// jobs may write from multiple goroutines.
+type jobLogWriter struct {
+	mu         sync.Mutex
+	evt        *event.Event
+	lastUpdate time.Time
+}
+
+func (w *jobLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.evt.Write(p)
+	if time.Since(w.lastUpdate) >= jobLogUpdateInterval {
+		w.lastUpdate = time.Now()
+		if updateErr := w.evt.UpdateLog(); updateErr != nil {
+			log.Errorf("[job %s] unable to update log: %s", w.evt.UniqueID.Hex(), updateErr)
+		}
+	}
+	return n, err
+}
+
+// done finishes the job event, waiting for any write in progress.
+func (w *jobLogWriter) done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.evt.Done(err)
+}
+
+type jobStatus struct {
+	ID        string       `json:"id"`
+	Kind      string       `json:"kind"`
+	Target    event.Target `json:"target"`
+	Running   bool         `json:"running"`
+	Error     string       `json:"error,omitempty"`
+	Log       string       `json:"log"`
+	StartTime time.Time    `json:"startTime"`
+	EndTime   time.Time    `json:"endTime,omitempty"`
+}
+
+func isAsyncRequest(r *http.Request) bool {
+	async, _ := strconv.ParseBool(r.FormValue("async"))
+	return async
+}
+
+// runJob runs fn in background, recording its output and result in evt, and
+// responds with 202 and the job id, to be polled in /jobs/{id}. The app lock
+// acquired by the request is held until the job finishes, and the API server
+// waits for running jobs when shutting down.
+func runJob(w http.ResponseWriter, r *http.Request, evt *event.Event, appName string, fn func(io.Writer) error) error {
+	context.SetPreventUnlock(r)
+	appLocks.jobs.Add(1)
+	go func() {
+		defer appLocks.jobs.Done()
+		defer appLocks.release(appName)
+		writer := &jobLogWriter{evt: evt}
+		writer.done(fn(writer))
+	}()
+	jobID := evt.UniqueID.Hex()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+jobID)
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(map[string]string{"id": jobID})
+}
+
+// title: job info
+// path: /jobs/{id}
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   400: Invalid id
+//   401: Unauthorized
+//   404: Not found
+func jobInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	id := r.URL.Query().Get(":id")
+	if !bson.IsObjectIdHex(id) {
+		msg := fmt.Sprintf("id parameter is not ObjectId: %s", id)
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: msg}
+	}
+	e, err := event.GetByID(bson.ObjectIdHex(id))
+	if err != nil {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	scheme, err := permission.SafeGet(e.Allowed.Scheme)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, scheme, e.Allowed.Contexts...)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(jobStatus{
+		ID:        id,
+		Kind:      e.Kind.Name,
+		Target:    e.Target,
+		Running:   e.Running,
+		Error:     e.Error,
+		Log:       e.Log,
+		StartTime: e.StartTime,
+		EndTime:   e.EndTime,
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/quota"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) waitJob(c *check.C, id string) jobStatus {
+	m := RunServer(true)
+	timeout := time.After(5 * time.Second)
+	for {
+		request, err := http.NewRequest("GET", "/jobs/"+id, nil)
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusOK)
+		var status jobStatus
+		err = json.NewDecoder(recorder.Body).Decode(&status)
+		c.Assert(err, check.IsNil)
+		if !status.Running {
+			return status
+		}
+		select {
+		case <-timeout:
+			c.Fatalf("timeout waiting for job %s", id)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (s *S) TestAddUnitsAsync(c *check.C) {
+	a := app.App{Name: "armorandsword", Platform: "zend", TeamOwner: s.team.Name, Quota: quota.Unlimited}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("units=3&process=web&async=true")
+	request, err := http.NewRequest("PUT", "/apps/armorandsword/units", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusAccepted)
+	var result map[string]string
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Header().Get("Location"), check.Equals, "/jobs/"+result["id"])
+	status := s.waitJob(c, result["id"])
+	c.Assert(status.Error, check.Equals, "")
+	c.Assert(status.Kind, check.Equals, permission.PermAppUpdateUnitAdd.FullName())
+	c.Assert(status.Target, check.DeepEquals, appTarget(a.Name))
+	c.Assert(status.Log, check.Equals, "added 3 units")
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, false)
+	units, err := dbApp.Units()
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.HasLen, 3)
+}
+
+func (s *S) TestJobInfoInvalidID(c *check.C) {
+	request, err := http.NewRequest("GET", "/jobs/xyz", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestJobInfoNotFound(c *check.C) {
+	request, err := http.NewRequest("GET", "/jobs/5808cbb7c8d5d3a1df42b5d5", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestJobLogWriterConcurrentWrites(c *check.C) {
+	evt, err := event.New(&event.Opts{
+		Target:  event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Kind:    permission.PermAppUpdateUnitAdd,
+		Owner:   s.token,
+		Allowed: event.Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	writer := &jobLogWriter{evt: evt}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				writer.Write([]byte("x"))
+			}
+		}()
+	}
+	wg.Wait()
+	writer.done(nil)
+	evts, err := event.All()
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	c.Assert(evts[0].Log, check.Equals, strings.Repeat("x", 1000))
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+)
+
+// appLockTracker keeps track of the app locks held by this API instance and
+// of the background jobs holding them, so they can be released when the
+// server shuts down.
+type appLockTracker struct {
+	sync.Mutex
+	locks map[string]int
+	jobs  sync.WaitGroup
+}
+
+func (t *appLockTracker) add(appName string) {
+	t.Lock()
+	defer t.Unlock()
+	if t.locks == nil {
+		t.locks = make(map[string]int)
+	}
+	t.locks[appName]++
+}
+
+func (t *appLockTracker) release(appName string) {
+	app.ReleaseApplicationLock(appName)
+	t.Lock()
+	defer t.Unlock()
+	if t.locks[appName] > 1 {
+		t.locks[appName]--
+	} else {
+		delete(t.locks, appName)
+	}
+}
+
+func (t *appLockTracker) held() []string {
+	t.Lock()
+	defer t.Unlock()
+	names := make([]string, 0, len(t.locks))
+	for name := range t.locks {
+		names = append(names, name)
+	}
+	return names
+}
+
+// drain waits up to timeout for the running background jobs to finish and
+// then releases all locks still held, returning their app names.
+func (t *appLockTracker) drain(timeout time.Duration) []string {
+	done := make(chan struct{})
+	go func() {
+		t.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	names := t.held()
+	for _, name := range names {
+		t.release(name)
+	}
+	return names
+}
+
+var appLocks appLockTracker
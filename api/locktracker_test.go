@@ -0,0 +1,66 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAppLockTrackerRelease(c *check.C) {
+	a := app.App{Name: "locked", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	ok, err := app.AcquireApplicationLock(a.Name, "me", "deploy")
+	c.Assert(err, check.IsNil)
+	c.Assert(ok, check.Equals, true)
+	var tracker appLockTracker
+	tracker.add(a.Name)
+	tracker.add(a.Name)
+	c.Assert(tracker.held(), check.DeepEquals, []string{a.Name})
+	tracker.release(a.Name)
+	c.Assert(tracker.held(), check.DeepEquals, []string{a.Name})
+	tracker.release(a.Name)
+	c.Assert(tracker.held(), check.DeepEquals, []string{})
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, false)
+}
+
+func (s *S) TestAppLockTrackerDrain(c *check.C) {
+	a := app.App{Name: "locked", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	ok, err := app.AcquireApplicationLock(a.Name, "me", "deploy")
+	c.Assert(err, check.IsNil)
+	c.Assert(ok, check.Equals, true)
+	var tracker appLockTracker
+	tracker.add(a.Name)
+	tracker.jobs.Add(1)
+	finished := false
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		finished = true
+		tracker.jobs.Done()
+	}()
+	released := tracker.drain(time.Minute)
+	c.Assert(finished, check.Equals, true)
+	c.Assert(released, check.DeepEquals, []string{a.Name})
+	dbApp, err := app.GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Lock.Locked, check.Equals, false)
+}
+
+func (s *S) TestAppLockTrackerDrainTimeout(c *check.C) {
+	var tracker appLockTracker
+	tracker.add("myapp")
+	tracker.jobs.Add(1)
+	defer tracker.jobs.Done()
+	released := tracker.drain(50 * time.Millisecond)
+	c.Assert(released, check.DeepEquals, []string{"myapp"})
+	c.Assert(tracker.held(), check.DeepEquals, []string{})
+}
@@ -0,0 +1,120 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// title: app log retention
+// path: /apps/{app}/log/retention
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: App not found
+func getLogRetention(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	a, err := getAppFromContext(r.URL.Query().Get(":app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppReadLog, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(a.GetLogRetention())
+}
+
+// title: app log retention set
+// path: /apps/{app}/log/retention
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Log retention set
+//   400: Invalid data
+//   401: Unauthorized
+//   404: App not found
+func setLogRetention(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppUpdateLogRetention, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	lines, err := parseUintForm(r, "lines")
+	if err != nil {
+		return err
+	}
+	maxAge, err := parseUintForm(r, "max-age")
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     appTarget(appName),
+		Kind:       permission.PermAppUpdateLogRetention,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = a.SetLogRetention(app.LogRetention{
+		Lines:  int(lines),
+		MaxAge: time.Duration(maxAge) * time.Second,
+	})
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+// title: app log purge
+// path: /apps/{app}/log
+// method: DELETE
+// responses:
+//   200: Logs removed
+//   401: Unauthorized
+//   404: App not found
+func purgeLogs(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	appName := r.URL.Query().Get(":app")
+	a, err := getAppFromContext(appName, r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, permission.PermAppUpdateLogPurge, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  appTarget(appName),
+		Kind:    permission.PermAppUpdateLogPurge,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermAppReadEvents, contextsForApp(&a)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return a.PurgeLogs()
+}
@@ -6,6 +6,7 @@ package api
 
 import (
 	"sync"
+	"time"
 
 	"github.com/tsuru/tsuru/app"
 )
@@ -46,3 +47,7 @@ func (t *logStreamTracker) Shutdown() {
 }
 
 var logTracker logStreamTracker
+
+// logKeepAliveInterval is how often an empty batch of lines is sent to
+// clients following the log of an app.
+var logKeepAliveInterval = 30 * time.Second
@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/codegangsta/negroni"
@@ -19,24 +21,45 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/api/context"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/prom"
+	"github.com/tsuru/tsuru/quota"
 )
 
 const (
 	tsuruMin      = "1.0.1"
 	craneMin      = "1.0.0"
 	tsuruAdminMin = "1.0.0"
+
+	defaultRequestIDHeader = "X-Request-ID"
 )
 
 func validate(token string, r *http.Request) (auth.Token, error) {
+	revoked, err := auth.IsTokenRevoked(token)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, auth.ErrInvalidToken
+	}
 	t, err := app.AuthScheme.Auth(token)
 	if err != nil {
 		t, err = auth.APIAuth(token)
 		if err != nil {
-			return nil, err
+			t, err = auth.ScopedAuth(token)
+			if err != nil {
+				t, err = auth.ServiceAccountAuth(token)
+				if err != nil {
+					t, err = auth.ImpersonationAuth(token)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
 		}
 	}
 	if t.IsAppToken() {
@@ -72,13 +95,19 @@ func flushingWriterMiddleware(w http.ResponseWriter, r *http.Request, next http.
 	next(&fw, r)
 }
 
-func setRequestIDHeaderMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	requestIDHeader, _ := config.GetString("request-id-header")
-	if requestIDHeader == "" {
-		next(w, r)
-		return
+// requestIDHeader returns the name of the header used to identify requests,
+// which is X-Request-ID unless request-id-header is set.
+func requestIDHeader() string {
+	header, _ := config.GetString("request-id-header")
+	if header == "" {
+		return defaultRequestIDHeader
 	}
-	requestID := r.Header.Get(requestIDHeader)
+	return header
+}
+
+func setRequestIDHeaderMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	header := requestIDHeader()
+	requestID := r.Header.Get(header)
 	if requestID == "" {
 		unparsedID, err := uuid.NewV4()
 		if err != nil {
@@ -88,10 +117,25 @@ func setRequestIDHeaderMiddleware(w http.ResponseWriter, r *http.Request, next h
 		}
 		requestID = unparsedID.String()
 	}
-	context.SetRequestID(r, requestIDHeader, requestID)
+	context.SetRequestID(r, header, requestID)
+	w.Header().Set(header, requestID)
 	next(w, r)
 }
 
+// requestID returns the ID assigned to the request by
+// setRequestIDHeaderMiddleware.
+func requestID(r *http.Request) string {
+	return context.GetRequestID(r, requestIDHeader())
+}
+
+func requestIDLogSuffix(r *http.Request) string {
+	id := requestID(r)
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%s: %s]", requestIDHeader(), id)
+}
+
 func setVersionHeadersMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	w.Header().Set("Supported-Tsuru", tsuruMin)
 	w.Header().Set("Supported-Crane", craneMin)
@@ -107,10 +151,19 @@ func errorHandlingMiddleware(w http.ResponseWriter, r *http.Request, next http.H
 		if e, ok := err.(*tsuruErrors.HTTP); ok {
 			code = e.Code
 		}
+		quotaErr, isQuotaErr := errors.Cause(err).(*quota.QuotaExceededError)
+		if isQuotaErr {
+			code = http.StatusForbidden
+		}
+		isStream := w.Header().Get("Content-Type") == "application/x-json-stream"
 		flushing, ok := w.(*io.FlushingWriter)
-		if ok && flushing.Wrote() {
-			if w.Header().Get("Content-Type") == "application/x-json-stream" {
-				data, marshalErr := json.Marshal(io.SimpleJsonMessage{Error: err.Error()})
+		wrote := ok && flushing.Wrote()
+		if wrote || (isStream && isQuotaErr) {
+			if isStream {
+				if !wrote {
+					w.WriteHeader(code)
+				}
+				data, marshalErr := json.Marshal(io.SimpleJsonMessage{Error: err.Error(), Quota: quotaErr})
 				if marshalErr == nil {
 					w.Write(append(data, "\n"...))
 				}
@@ -120,7 +173,7 @@ func errorHandlingMiddleware(w http.ResponseWriter, r *http.Request, next http.H
 		} else {
 			http.Error(w, err.Error(), code)
 		}
-		log.Errorf("failure running HTTP request %s %s (%d): %s", r.Method, r.URL.Path, code, err)
+		log.Errorf("failure running HTTP request %s %s (%d): %s%s", r.Method, r.URL.Path, code, err, requestIDLogSuffix(r))
 	}
 }
 
@@ -136,6 +189,7 @@ func authTokenMiddleware(w http.ResponseWriter, r *http.Request, next http.Handl
 			log.Debugf("Ignored invalid token for %s: %s", r.URL.Path, err.Error())
 		} else {
 			context.SetAuthToken(r, t)
+			auth.RecordTokenUsage(t, requestIP(r), r.UserAgent())
 		}
 	}
 	next(w, r)
@@ -190,9 +244,10 @@ func (m *appLockMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, ne
 		return
 	}
 	if ok {
+		appLocks.add(appName)
 		defer func() {
 			if !context.IsPreventUnlock(r) {
-				app.ReleaseApplicationLock(appName)
+				appLocks.release(appName)
 			}
 		}()
 		next(w, r)
@@ -243,13 +298,88 @@ func (l *loggerMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, ne
 		statusCode = 200
 	}
 	nowFormatted := time.Now().Format(time.RFC3339Nano)
-	requestIDHeader, _ := config.GetString("request-id-header")
-	var requestID string
-	if requestIDHeader != "" {
-		requestID = context.GetRequestID(r, requestIDHeader)
-		if requestID != "" {
-			requestID = fmt.Sprintf(" [%s: %s]", requestIDHeader, requestID)
+	durationMs := float64(duration) / float64(time.Millisecond)
+	if format, _ := config.GetString("server:access-log-format"); format == "json" {
+		entry := accessLogEntry{
+			Time:       nowFormatted,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     statusCode,
+			DurationMs: durationMs,
+			RemoteAddr: r.RemoteAddr,
+		}
+		entry.RequestID = requestID(r)
+		if t := context.GetAuthToken(r); t != nil {
+			entry.User = t.GetUserName()
 		}
+		data, err := json.Marshal(entry)
+		if err == nil {
+			l.logger.Print(string(data))
+			return
+		}
+	}
+	l.logger.Printf("%s %s %s %d in %0.6fms%s", nowFormatted, r.Method, r.URL.Path, statusCode, durationMs, requestIDLogSuffix(r))
+}
+
+var apiRequestDuration = prom.NewHistogramVec(
+	"tsuru_api_request_duration_seconds",
+	"Duration of the requests handled by the API, by method and status code.",
+	prom.DefBuckets, "method", "status",
+)
+
+// knownMethods are the HTTP methods used as labels in the request metrics.
+// Any other method is reported as "other", as the method comes from the
+// client and would otherwise create an unbounded number of series.
+var knownMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"PATCH":   true,
+	"DELETE":  true,
+	"OPTIONS": true,
+}
+
+func metricsMethod(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "other"
+}
+
+// metricsMiddleware records the duration of requests in the Prometheus
+// metrics and reports the number of requests by status code and the response
+// time by method to the external metrics sinks.
+func metricsMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	next(w, r)
+	duration := time.Since(start)
+	statusCode := w.(negroni.ResponseWriter).Status()
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	method := metricsMethod(r.Method)
+	apiRequestDuration.Observe(duration.Seconds(), method, strconv.Itoa(statusCode))
+	if !metrics.Reporting() {
+		return
 	}
-	l.logger.Printf("%s %s %s %d in %0.6fms%s", nowFormatted, r.Method, r.URL.Path, statusCode, float64(duration)/float64(time.Millisecond), requestID)
+	metrics.Report(
+		metrics.Point{Name: fmt.Sprintf("api.requests.%d", statusCode), Value: 1, Type: metrics.Counter},
+		metrics.Point{
+			Name:  "api.response-time." + strings.ToLower(method),
+			Value: float64(duration) / float64(time.Millisecond),
+			Type:  metrics.Timing,
+		},
+	)
+}
+
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	DurationMs float64 `json:"durationMs"`
+	RemoteAddr string  `json:"remoteAddr,omitempty"`
+	RequestID  string  `json:"requestID,omitempty"`
+	User       string  `json:"user,omitempty"`
 }
@@ -6,8 +6,10 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -17,9 +19,11 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/api/context"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/io"
+	"github.com/tsuru/tsuru/quota"
 	"gopkg.in/check.v1"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -83,6 +87,19 @@ func (s *S) TestSetRequestIDHeaderMiddleware(c *check.C) {
 	c.Assert(reqID, check.Not(check.Equals), "")
 }
 
+func (s *S) TestSetRequestIDHeaderMiddlewareSetsResponseHeader(c *check.C) {
+	config.Set("request-id-header", "Request-ID")
+	defer config.Unset("request-id-header")
+	rec := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Request-ID", "test")
+	h, log := doHandler()
+	setRequestIDHeaderMiddleware(rec, req, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(rec.Header().Get("Request-ID"), check.Equals, "test")
+}
+
 func (s *S) TestSetRequestIDHeaderAlreadySet(c *check.C) {
 	config.Set("request-id-header", "Request-ID")
 	defer config.Unset("request-id-header")
@@ -105,8 +122,23 @@ func (s *S) TestSetRequestIDHeaderMiddlewareNoConfig(c *check.C) {
 	h, log := doHandler()
 	setRequestIDHeaderMiddleware(rec, req, h)
 	c.Assert(log.called, check.Equals, true)
-	reqID := context.GetRequestID(req, "")
-	c.Assert(reqID, check.Equals, "")
+	reqID := context.GetRequestID(req, "X-Request-ID")
+	c.Assert(reqID, check.Not(check.Equals), "")
+	c.Assert(rec.Header().Get("X-Request-ID"), check.Equals, reqID)
+	c.Assert(requestID(req), check.Equals, reqID)
+}
+
+func (s *S) TestSetRequestIDHeaderMiddlewareNoConfigAlreadySet(c *check.C) {
+	config.Unset("request-id-header")
+	rec := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("X-Request-ID", "test")
+	h, log := doHandler()
+	setRequestIDHeaderMiddleware(rec, req, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(requestID(req), check.Equals, "test")
+	c.Assert(rec.Header().Get("X-Request-ID"), check.Equals, "test")
 }
 
 func (s *S) TestSetVersionHeadersMiddleware(c *check.C) {
@@ -153,6 +185,35 @@ func (s *S) TestErrorHandlingMiddlewareWithHTTPError(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, 403)
 }
 
+func (s *S) TestErrorHandlingMiddlewareWithQuotaExceededError(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	h, log := doHandler()
+	context.AddRequestError(request, &quota.QuotaExceededError{Requested: 3, Available: 1})
+	errorHandlingMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	c.Assert(recorder.Body.String(), check.Equals, "Quota exceeded. Available: 1. Requested: 3.\n")
+}
+
+func (s *S) TestErrorHandlingMiddlewareWithQuotaExceededErrorStream(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("PUT", "/", nil)
+	c.Assert(err, check.IsNil)
+	h, log := doHandler()
+	recorder.Header().Set("Content-Type", "application/x-json-stream")
+	context.AddRequestError(request, &quota.QuotaExceededError{Requested: 3, Available: 1})
+	errorHandlingMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	var msg io.SimpleJsonMessage
+	err = json.Unmarshal(recorder.Body.Bytes(), &msg)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Error, check.Equals, "Quota exceeded. Available: 1. Requested: 3.")
+	c.Assert(msg.Quota, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 1})
+}
+
 func (s *S) TestAuthTokenMiddlewareWithoutToken(c *check.C) {
 	recorder := httptest.NewRecorder()
 	request, err := http.NewRequest("GET", "/", nil)
@@ -511,6 +572,62 @@ func (s *S) TestLoggerMiddlewareWithoutStatusCode(c *check.C) {
 	c.Assert(out.String(), check.Matches, fmt.Sprintf(`%s\..+? PUT /my/path 200 in 1\d{2}\.\d+ms`+"\n", timePart))
 }
 
+func (s *S) TestMetricsMiddleware(c *check.C) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	config.Set("metrics:sinks", []string{"statsd"})
+	config.Set("metrics:statsd:address", listener.LocalAddr().String())
+	defer config.Unset("metrics")
+	err = metrics.StartReporter()
+	c.Assert(err, check.IsNil)
+	defer metrics.StopReporter()
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("PUT", "/my/path", nil)
+	c.Assert(err, check.IsNil)
+	h, handlerLog := doHandler()
+	handlerLog.response = http.StatusNotFound
+	metricsMiddleware(negroni.NewResponseWriter(recorder), request, h)
+	c.Assert(handlerLog.called, check.Equals, true)
+	metrics.StopReporter()
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, _, err := listener.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(buf[:n]), check.Matches, `tsuru\.api\.requests\.404:1\|c\ntsuru\.api\.response-time\.put:[\d.e+-]+\|ms\n`)
+}
+
+func (s *S) TestMetricsMiddlewareNormalizesMethod(c *check.C) {
+	requests := apiRequestDuration.Count("other", "200")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("FOOBAR", "/my/path", nil)
+	c.Assert(err, check.IsNil)
+	h, _ := doHandler()
+	metricsMiddleware(negroni.NewResponseWriter(recorder), request, h)
+	c.Assert(apiRequestDuration.Count("other", "200"), check.Equals, requests+1)
+	c.Assert(apiRequestDuration.Count("FOOBAR", "200"), check.Equals, uint64(0))
+}
+
+func (s *S) TestMetricsMiddlewareObservesRequestDuration(c *check.C) {
+	requests := apiRequestDuration.Count("PUT", "404")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("PUT", "/my/path", nil)
+	c.Assert(err, check.IsNil)
+	h, handlerLog := doHandler()
+	handlerLog.response = http.StatusNotFound
+	metricsMiddleware(negroni.NewResponseWriter(recorder), request, h)
+	c.Assert(handlerLog.called, check.Equals, true)
+	c.Assert(apiRequestDuration.Count("PUT", "404"), check.Equals, requests+1)
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest("GET", "/metrics", nil)
+	c.Assert(err, check.IsNil)
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "text/plain; version=0.0.4")
+	c.Assert(recorder.Body.String(), check.Matches, `(?s).*tsuru_api_request_duration_seconds_count\{method="PUT",status="404"\} \d+\n.*`)
+}
+
 func (s *S) TestLoggerMiddlewareWithRequestID(c *check.C) {
 	config.Set("request-id-header", "Request-ID")
 	defer config.Unset("request-id-header")
@@ -530,3 +647,33 @@ func (s *S) TestLoggerMiddlewareWithRequestID(c *check.C) {
 	timePart := time.Now().Format(time.RFC3339Nano)[:19]
 	c.Assert(out.String(), check.Matches, fmt.Sprintf(`%s\..+? PUT /my/path 200 in 1\d{2}\.\d+ms \[Request-ID: my-rid\]`+"\n", timePart))
 }
+
+func (s *S) TestLoggerMiddlewareJSON(c *check.C) {
+	config.Set("server:access-log-format", "json")
+	defer config.Unset("server:access-log-format")
+	config.Set("request-id-header", "Request-ID")
+	defer config.Unset("request-id-header")
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("PUT", "/my/path", nil)
+	c.Assert(err, check.IsNil)
+	request.RemoteAddr = "10.0.0.1:3333"
+	context.SetRequestID(request, "Request-ID", "my-request")
+	context.SetAuthToken(request, s.token)
+	h, handlerLog := doHandler()
+	handlerLog.response = http.StatusCreated
+	var out bytes.Buffer
+	middle := loggerMiddleware{
+		logger: log.New(&out, "", 0),
+	}
+	middle.ServeHTTP(negroni.NewResponseWriter(recorder), request, h)
+	c.Assert(handlerLog.called, check.Equals, true)
+	var entry accessLogEntry
+	err = json.Unmarshal(out.Bytes(), &entry)
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.Method, check.Equals, "PUT")
+	c.Assert(entry.Path, check.Equals, "/my/path")
+	c.Assert(entry.Status, check.Equals, http.StatusCreated)
+	c.Assert(entry.RemoteAddr, check.Equals, "10.0.0.1:3333")
+	c.Assert(entry.RequestID, check.Equals, "my-request")
+	c.Assert(entry.User, check.Equals, s.token.GetUserName())
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ajg/form"
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
@@ -430,6 +431,53 @@ func listUnitsByNode(w http.ResponseWriter, r *http.Request, t auth.Token) error
 	return json.NewEncoder(w).Encode(units)
 }
 
+// title: node metrics
+// path: /{provisioner}/node/{address}/metrics
+// method: GET
+// produce: application/json
+// responses:
+//   200: Ok
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Not found
+func nodeMetrics(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	address := r.URL.Query().Get(":address")
+	_, node, err := provision.FindNode(address)
+	if err != nil {
+		if err == provision.ErrNodeNotFound {
+			return &tsuruErrors.HTTP{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			}
+		}
+		return err
+	}
+	hasAccess := permission.Check(t, permission.PermNodeRead,
+		permission.Context(permission.CtxPool, node.Pool()))
+	if !hasAccess {
+		return permission.ErrUnauthorized
+	}
+	since, err := parseTimeParam(r, "since", time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeParam(r, "until", time.Time{})
+	if err != nil {
+		return err
+	}
+	samples, err := metrics.ListNodeSamples(node.Address(), since, until)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(samples)
+}
+
 // title: list units by app
 // path: /docker/node/apps/{appname}/containers
 // method: GET
@@ -586,3 +634,121 @@ func nodeHealingDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (er
 	}
 	return nil
 }
+
+// title: unit healing info
+// path: /healing/unit
+// method: GET
+// produce: application/json
+// responses:
+//   200: Ok
+//   401: Unauthorized
+func unitHealingRead(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	pools, err := permission.ListContextValues(t, permission.PermHealingRead, true)
+	if err != nil {
+		return err
+	}
+	configMap, err := healer.GetUnitConfig()
+	if err != nil {
+		return err
+	}
+	if len(pools) > 0 {
+		allowedPoolSet := map[string]struct{}{}
+		for _, p := range pools {
+			allowedPoolSet[p] = struct{}{}
+		}
+		for k := range configMap {
+			if k == "" {
+				continue
+			}
+			if _, ok := allowedPoolSet[k]; !ok {
+				delete(configMap, k)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(configMap)
+}
+
+// title: unit healing update
+// path: /healing/unit
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Ok
+//   401: Unauthorized
+func unitHealingUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	err = r.ParseForm()
+	if err != nil {
+		return err
+	}
+	poolName := r.FormValue("pool")
+	var ctxs []permission.PermissionContext
+	if poolName != "" {
+		ctxs = append(ctxs, permission.Context(permission.CtxPool, poolName))
+	}
+	if !permission.Check(t, permission.PermHealingUpdate, ctxs...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:      event.Target{Type: event.TargetTypePool, Value: poolName},
+		Kind:        permission.PermHealingUpdate,
+		Owner:       t,
+		CustomData:  event.FormToCustomData(r.Form),
+		DisableLock: true,
+		Allowed:     event.Allowed(permission.PermPoolReadEvents, ctxs...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	var config healer.UnitHealerConfig
+	delete(r.Form, "pool")
+	dec := form.NewDecoder(nil)
+	dec.IgnoreUnknownKeys(true)
+	err = dec.DecodeValues(&config, r.Form)
+	if err != nil {
+		return err
+	}
+	return healer.UpdateUnitConfig(poolName, config)
+}
+
+// title: remove unit healing
+// path: /healing/unit
+// method: DELETE
+// produce: application/json
+// responses:
+//   200: Ok
+//   401: Unauthorized
+func unitHealingDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	poolName := r.URL.Query().Get("pool")
+	var ctxs []permission.PermissionContext
+	if poolName != "" {
+		ctxs = append(ctxs, permission.Context(permission.CtxPool, poolName))
+	}
+	if !permission.Check(t, permission.PermHealingDelete, ctxs...) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:      event.Target{Type: event.TargetTypePool, Value: poolName},
+		Kind:        permission.PermHealingDelete,
+		Owner:       t,
+		CustomData:  event.FormToCustomData(r.Form),
+		DisableLock: true,
+		Allowed:     event.Allowed(permission.PermPoolReadEvents, ctxs...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	if len(r.URL.Query()["name"]) == 0 {
+		return healer.RemoveUnitConfig(poolName, "")
+	}
+	for _, v := range r.URL.Query()["name"] {
+		err := healer.RemoveUnitConfig(poolName, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
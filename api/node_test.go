@@ -13,9 +13,11 @@ import (
 	"net/http/httptest"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/ajg/form"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/event"
 	"github.com/tsuru/tsuru/event/eventtest"
@@ -267,6 +269,62 @@ func (s *S) TestRemoveNodeHandlerNotFound(c *check.C) {
 	c.Assert(rec.Code, check.Equals, http.StatusNotFound)
 }
 
+func (s *S) TestNodeMetrics(c *check.C) {
+	err := s.provisioner.AddNode(provision.AddNodeOptions{
+		Address:  "http://node1:2375",
+		Metadata: map[string]string{"pool": "pool1"},
+	})
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC()
+	err = s.conn.NodeMetrics().Insert(
+		metrics.NodeSample{Node: "http://node1:2375", Date: now.Add(-2 * time.Hour), CPU: 10},
+		metrics.NodeSample{Node: "http://node1:2375", Date: now.Add(-time.Minute), CPU: 20},
+	)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("GET", "/docker/node/http://node1:2375/metrics", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	rec := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), check.Equals, "application/json")
+	var samples []metrics.NodeSample
+	err = json.NewDecoder(rec.Body).Decode(&samples)
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 1)
+	c.Assert(samples[0].CPU, check.Equals, 20.0)
+}
+
+func (s *S) TestNodeMetricsNotFound(c *check.C) {
+	req, err := http.NewRequest("GET", "/docker/node/http://node1:2375/metrics", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	rec := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestNodeMetricsUnauthorized(c *check.C) {
+	err := s.provisioner.AddNode(provision.AddNodeOptions{
+		Address:  "http://node1:2375",
+		Metadata: map[string]string{"pool": "pool1"},
+	})
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermNodeRead,
+		Context: permission.Context(permission.CtxPool, "pool2"),
+	})
+	req, err := http.NewRequest("GET", "/node/http://node1:2375/metrics", nil)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Authorization", "bearer "+token.GetValue())
+	rec := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, http.StatusForbidden)
+}
+
 func (s *S) TestRemoveNodeHandler(c *check.C) {
 	err := s.provisioner.AddNode(provision.AddNodeOptions{
 		Address: "host.com:2375",
@@ -881,6 +939,52 @@ func (s *S) TestNodeHealingUpdateRead(c *check.C) {
 	})
 }
 
+func (s *S) TestUnitHealingUpdateRead(c *check.C) {
+	server := RunServer(true)
+	body := bytes.NewBufferString("pool=p1&Enabled=true&MaxStuckTime=120&MaxHealsPerHour=2")
+	request, err := http.NewRequest("POST", "/healing/unit", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypePool, Value: "p1"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "healing.update",
+		StartCustomData: []map[string]interface{}{
+			{"name": "pool", "value": "p1"},
+			{"name": "Enabled", "value": "true"},
+			{"name": "MaxStuckTime", "value": "120"},
+			{"name": "MaxHealsPerHour", "value": "2"},
+		},
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("GET", "/healing/unit", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var configMap map[string]healer.UnitHealerConfig
+	err = json.Unmarshal(recorder.Body.Bytes(), &configMap)
+	c.Assert(err, check.IsNil)
+	c.Assert(configMap, check.DeepEquals, map[string]healer.UnitHealerConfig{
+		"":   {},
+		"p1": {Enabled: boolPtr(true), MaxStuckTime: intPtr(120), MaxHealsPerHour: intPtr(2)},
+	})
+	request, err = http.NewRequest("DELETE", "/healing/unit?pool=p1", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	configMap, err = healer.GetUnitConfig()
+	c.Assert(err, check.IsNil)
+	_, ok := configMap["p1"]
+	c.Assert(ok, check.Equals, false)
+}
+
 func (s *S) TestNodeHealingConfigUpdateReadLimited(c *check.C) {
 	doRequest := func(t auth.Token, code int, str string) map[string]healer.NodeHealerConfig {
 		body := bytes.NewBufferString(str)
@@ -104,6 +104,57 @@ func removeRole(w http.ResponseWriter, r *http.Request, t auth.Token) (err error
 	return err
 }
 
+// title: role update
+// path: /roles/{name}
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Role updated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Role not found
+//   409: Role already exists or referenced in config
+func updateRole(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	if !permission.Check(t, permission.PermRoleUpdate) {
+		return permission.ErrUnauthorized
+	}
+	roleName := r.URL.Query().Get(":name")
+	newName := r.FormValue("newName")
+	description := r.FormValue("description")
+	if newName == "" && description == "" {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: "You must provide the new name or description of the role.",
+		}
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeRole, Value: roleName},
+		Kind:       permission.PermRoleUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermRoleReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	_, err = auth.RenameRole(roleName, newName, description)
+	switch err.(type) {
+	case nil:
+		return nil
+	case *auth.ErrRoleReferencedInConfig:
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if err == permission.ErrRoleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if err == permission.ErrRoleAlreadyExists {
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	return err
+}
+
 // title: role list
 // path: /roles
 // method: GET
@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"sort"
 
+	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/event"
@@ -155,6 +156,78 @@ func (s *S) TestRemoveRoleUnauthorized(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
 }
 
+func (s *S) TestUpdateRole(c *check.C) {
+	_, err := permission.NewRole("test", "app", "")
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermRoleUpdate,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	user, err := token.User()
+	c.Assert(err, check.IsNil)
+	err = user.AddRole("test", "myapp")
+	c.Assert(err, check.IsNil)
+	body := bytes.NewBufferString("newName=test2&description=my+role")
+	req, err := http.NewRequest("PUT", "/roles/test", body)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, req)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	role, err := permission.FindRole("test2")
+	c.Assert(err, check.IsNil)
+	c.Assert(role.Description, check.Equals, "my role")
+	_, err = permission.FindRole("test")
+	c.Assert(err, check.Equals, permission.ErrRoleNotFound)
+	user, err = token.User()
+	c.Assert(err, check.IsNil)
+	c.Assert(user.Roles, check.DeepEquals, []auth.RoleInstance{
+		{Name: "majortomrole.update", ContextValue: ""},
+		{Name: "test2", ContextValue: "myapp"},
+	})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeRole, Value: "test"},
+		Owner:  token.GetUserName(),
+		Kind:   "role.update",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":name", "value": "test"},
+			{"name": "newName", "value": "test2"},
+			{"name": "description", "value": "my role"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestUpdateRoleInvalidData(c *check.C) {
+	config.Set("auth:saml:team-role", "other")
+	defer config.Unset("auth:saml:team-role")
+	_, err := permission.NewRole("test", "app", "")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("other", "app", "")
+	c.Assert(err, check.IsNil)
+	tests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/roles/test", "", http.StatusBadRequest},
+		{"/roles/unknown", "newName=x", http.StatusNotFound},
+		{"/roles/test", "newName=other", http.StatusConflict},
+		{"/roles/other", "newName=x", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("PUT", tt.path, bytes.NewBufferString(tt.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "bearer "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		server := RunServer(true)
+		server.ServeHTTP(recorder, req)
+		c.Assert(recorder.Code, check.Equals, tt.code, check.Commentf("%s %s", tt.path, tt.body))
+	}
+}
+
 func (s *S) TestListRoles(c *check.C) {
 	s.conn.Roles().DropCollection()
 	rec := httptest.NewRecorder()
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/auth"
@@ -39,6 +40,14 @@ func addPlan(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 		Default:  isDefault,
 		Router:   r.FormValue("router"),
 	}
+	logLines, _ := strconv.Atoi(r.FormValue("log-lines"))
+	logMaxAge, _ := strconv.Atoi(r.FormValue("log-max-age"))
+	if logLines != 0 || logMaxAge != 0 {
+		plan.LogRetention = &app.LogRetention{
+			Lines:  logLines,
+			MaxAge: time.Duration(logMaxAge) * time.Second,
+		}
+	}
 	allowed := permission.Check(t, permission.PermPlanCreate)
 	if !allowed {
 		return permission.ErrUnauthorized
@@ -99,6 +108,80 @@ func listPlans(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 	return json.NewEncoder(w).Encode(plans)
 }
 
+// title: plan update
+// path: /plans/{name}
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Plan updated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Plan not found
+func updatePlan(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	allowed := permission.Check(t, permission.PermPlanUpdate)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	planName := r.URL.Query().Get(":planname")
+	plan, err := app.GetPlanByName(planName)
+	if err == app.ErrPlanNotFound {
+		return &errors.HTTP{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	r.ParseForm()
+	if memory := r.FormValue("memory"); memory != "" {
+		plan.Memory = getSize(memory)
+	}
+	if swap := r.FormValue("swap"); swap != "" {
+		plan.Swap = getSize(swap)
+	}
+	if cpuShare := r.FormValue("cpushare"); cpuShare != "" {
+		plan.CpuShare, _ = strconv.Atoi(cpuShare)
+	}
+	if isDefault := r.FormValue("default"); isDefault != "" {
+		plan.Default, _ = strconv.ParseBool(isDefault)
+	}
+	if _, ok := r.Form["router"]; ok {
+		plan.Router = r.FormValue("router")
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypePlan, Value: planName},
+		Kind:       permission.PermPlanUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermPlanReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = app.PlanUpdate(*plan)
+	if _, ok := err.(app.PlanValidationError); ok {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+	if err == app.ErrLimitOfMemory || err == app.ErrLimitOfCpuShare {
+		return &errors.HTTP{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+	if err == app.ErrPlanNotFound {
+		return &errors.HTTP{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		}
+	}
+	return err
+}
+
 // title: remove plan
 // path: /plans/{name}
 // method: DELETE
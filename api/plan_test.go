@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
@@ -107,6 +108,24 @@ func (s *S) TestPlanAddWithGigabyteAsMemoryUnit(c *check.C) {
 	})
 }
 
+func (s *S) TestPlanAddWithLogRetention(c *check.C) {
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("name=xyz&memory=512M&swap=1024&cpushare=100&router=fake&log-lines=1000&log-max-age=86400")
+	request, err := http.NewRequest("POST", "/plans", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	defer s.conn.Plans().RemoveAll(nil)
+	var plans []app.Plan
+	err = s.conn.Plans().Find(nil).All(&plans)
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.HasLen, 1)
+	c.Assert(plans[0].LogRetention, check.DeepEquals, &app.LogRetention{Lines: 1000, MaxAge: 24 * time.Hour})
+}
+
 func (s *S) TestPlanAddWithNoPermission(c *check.C) {
 	token := userWithPermission(c)
 	recorder := httptest.NewRecorder()
@@ -252,6 +271,81 @@ func (s *S) TestPlanRemoveInvalid(c *check.C) {
 	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
 }
 
+func (s *S) TestPlanUpdate(c *check.C) {
+	plan := app.Plan{Name: "plan1", Memory: 4194304, Swap: 2, CpuShare: 3}
+	err := s.conn.Plans().Insert(plan)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Plans().RemoveAll(nil)
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("memory=512M&cpushare=100")
+	request, err := http.NewRequest("PUT", "/plans/plan1", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var dbPlan app.Plan
+	err = s.conn.Plans().FindId("plan1").One(&dbPlan)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbPlan, check.DeepEquals, app.Plan{Name: "plan1", Memory: 536870912, Swap: 2, CpuShare: 100})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypePlan, Value: "plan1"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "plan.update",
+		StartCustomData: []map[string]interface{}{
+			{"name": "memory", "value": "512M"},
+			{"name": "cpushare", "value": "100"},
+			{"name": ":planname", "value": "plan1"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestPlanUpdateNoPermission(c *check.C) {
+	token := userWithPermission(c)
+	plan := app.Plan{Name: "plan1", Memory: 4194304, Swap: 2, CpuShare: 3}
+	err := s.conn.Plans().Insert(plan)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Plans().RemoveAll(nil)
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("cpushare=100")
+	request, err := http.NewRequest("PUT", "/plans/plan1", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestPlanUpdateNotFound(c *check.C) {
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("cpushare=100")
+	request, err := http.NewRequest("PUT", "/plans/plan999", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestPlanUpdateInvalidCpuShare(c *check.C) {
+	plan := app.Plan{Name: "plan1", Memory: 4194304, Swap: 2, CpuShare: 3}
+	err := s.conn.Plans().Insert(plan)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Plans().RemoveAll(nil)
+	recorder := httptest.NewRecorder()
+	body := strings.NewReader("cpushare=1")
+	request, err := http.NewRequest("PUT", "/plans/plan1", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
 func (s *S) TestRoutersListNoContent(c *check.C) {
 	err := config.Unset("routers")
 	c.Assert(err, check.IsNil)
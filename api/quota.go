@@ -25,11 +25,11 @@ import (
 //   401: Unauthorized
 //   404: User not found
 func getUserQuota(w http.ResponseWriter, r *http.Request, t auth.Token) error {
-	allowed := permission.Check(t, permission.PermUserUpdateQuota)
+	email := r.URL.Query().Get(":email")
+	allowed := email == t.GetUserName() || permission.Check(t, permission.PermUserUpdateQuota)
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
-	email := r.URL.Query().Get(":email")
 	user, err := auth.GetUserByEmail(email)
 	if err == auth.ErrUserNotFound {
 		return &errors.HTTP{
@@ -131,6 +131,9 @@ func changeAppQuota(w http.ResponseWriter, r *http.Request, t auth.Token) (err e
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
+	if err = checkFrozen(t, &a); err != nil {
+		return err
+	}
 	evt, err := event.New(&event.Opts{
 		Target:     event.Target{Type: event.TargetTypeApp, Value: appName},
 		Kind:       permission.PermAppAdminQuota,
@@ -151,3 +154,102 @@ func changeAppQuota(w http.ResponseWriter, r *http.Request, t auth.Token) (err e
 	}
 	return app.ChangeQuota(&a, limit)
 }
+
+type teamQuota struct {
+	Limit auth.TeamQuota `json:"limit"`
+	InUse app.TeamUsage  `json:"inuse"`
+}
+
+// title: team quota
+// path: /teams/{name}/quota
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   401: Unauthorized
+//   404: Team not found
+func getTeamQuota(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	name := r.URL.Query().Get(":name")
+	allowed := permission.Check(t, permission.PermTeamReadQuota, permission.Context(permission.CtxTeam, name))
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	team, err := auth.GetTeam(name)
+	if err == auth.ErrTeamNotFound {
+		return &errors.HTTP{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	usage, err := app.GetTeamUsage(team.Name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(teamQuota{Limit: team.Quota, InUse: usage})
+}
+
+// title: update team quota
+// path: /teams/{name}/quota
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Quota updated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Team not found
+func changeTeamQuota(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	name := r.URL.Query().Get(":name")
+	// Team admins must not be able to raise the limits of their own teams,
+	// so only global permissions are accepted.
+	allowed := permission.Check(t, permission.PermTeamUpdateQuota)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	team, err := auth.GetTeam(name)
+	if err == auth.ErrTeamNotFound {
+		return &errors.HTTP{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		}
+	} else if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: name},
+		Kind:       permission.PermTeamUpdateQuota,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, permission.Context(permission.CtxTeam, name)),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	quota := team.Quota
+	for field, limit := range map[string]*int{"apps": &quota.Apps, "units": &quota.Units} {
+		if value := r.FormValue(field); value != "" {
+			*limit, err = strconv.Atoi(value)
+			if err != nil {
+				return &errors.HTTP{
+					Code:    http.StatusBadRequest,
+					Message: "Invalid " + field + " limit",
+				}
+			}
+		}
+	}
+	if value := r.FormValue("memory"); value != "" {
+		quota.Memory, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return &errors.HTTP{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid memory limit",
+			}
+		}
+	}
+	return auth.ChangeTeamQuota(team.Name, quota)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/context"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const maxRateLimitRetries = 5
+
+// rateLimit describes a token bucket: up to Burst requests may be issued at
+// once, and the bucket is refilled at Rate requests per second.
+type rateLimit struct {
+	Rate  float64
+	Burst float64
+}
+
+func (l rateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+func rateLimitFromConfig(kind string) rateLimit {
+	rate, _ := config.GetFloat("server:rate-limit:" + kind + ":rate")
+	burst, _ := config.GetFloat("server:rate-limit:" + kind + ":burst")
+	if burst < 1 {
+		burst = math.Max(1, rate)
+	}
+	return rateLimit{Rate: rate, Burst: burst}
+}
+
+type rateLimitBucket struct {
+	Key        string `bson:"_id"`
+	Tokens     float64
+	LastUpdate time.Time
+}
+
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration
+}
+
+// take removes one token from the bucket identified by key, refilling it
+// according to the time elapsed since its last use. Buckets are stored in
+// MongoDB so that every API instance shares the same limits, updates use the
+// last update time as a version to avoid lost updates between instances.
+func (l rateLimit) take(key string) (rateLimitResult, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	defer conn.Close()
+	coll := conn.RateLimit()
+	for i := 0; i < maxRateLimitRetries; i++ {
+		now := time.Now().UTC()
+		var bucket rateLimitBucket
+		err = coll.FindId(key).One(&bucket)
+		if err == mgo.ErrNotFound {
+			err = coll.Insert(rateLimitBucket{Key: key, Tokens: l.Burst - 1, LastUpdate: now})
+			if mgo.IsDup(err) {
+				continue
+			}
+			if err != nil {
+				return rateLimitResult{}, err
+			}
+			return l.result(l.Burst-1, true), nil
+		}
+		if err != nil {
+			return rateLimitResult{}, err
+		}
+		elapsed := now.Sub(bucket.LastUpdate).Seconds()
+		tokens := math.Min(l.Burst, bucket.Tokens+math.Max(0, elapsed)*l.Rate)
+		if tokens < 1 {
+			return l.result(tokens, false), nil
+		}
+		err = coll.Update(
+			bson.M{"_id": key, "lastupdate": bucket.LastUpdate},
+			bson.M{"$set": bson.M{"tokens": tokens - 1, "lastupdate": now}},
+		)
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return rateLimitResult{}, err
+		}
+		return l.result(tokens-1, true), nil
+	}
+	return rateLimitResult{}, fmt.Errorf("unable to update rate limit bucket %q after %d retries", key, maxRateLimitRetries)
+}
+
+func (l rateLimit) result(tokens float64, allowed bool) rateLimitResult {
+	result := rateLimitResult{allowed: allowed, remaining: int(math.Floor(tokens))}
+	missing := 1 - tokens
+	if allowed {
+		missing = l.Burst - tokens
+	}
+	if missing > 0 {
+		result.reset = time.Duration(math.Ceil(missing/l.Rate)) * time.Second
+	}
+	return result
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, l rateLimit, result rateLimitResult) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(int(l.Burst)))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(result.reset.Seconds())))
+}
+
+func requestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitMiddleware enforces the per-token and per-IP limits configured in
+// server:rate-limit. Requests carrying a valid token are limited by token,
+// anonymous requests are limited by the client IP address. Buckets of tokens
+// are keyed by the token hash, so the collection doesn't hold credentials.
+func rateLimitMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var key string
+	var limit rateLimit
+	if t := context.GetAuthToken(r); t != nil {
+		limit = rateLimitFromConfig("token")
+		key = fmt.Sprintf("token:%x", sha256.Sum256([]byte(t.GetValue())))
+	} else {
+		limit = rateLimitFromConfig("ip")
+		key = "ip:" + requestIP(r)
+	}
+	if !limit.enabled() {
+		next(w, r)
+		return
+	}
+	result, err := limit.take(key)
+	if err != nil {
+		log.Errorf("unable to check rate limit for %s %s: %s", r.Method, r.URL.Path, err)
+		next(w, r)
+		return
+	}
+	setRateLimitHeaders(w, limit, result)
+	if !result.allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(result.reset.Seconds())))
+		context.AddRequestError(r, &tsuruErrors.HTTP{
+			Code:    http.StatusTooManyRequests,
+			Message: "rate limit exceeded, please try again later",
+		})
+		return
+	}
+	next(w, r)
+}
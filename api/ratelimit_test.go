@@ -0,0 +1,99 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/context"
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestRateLimitMiddlewareDisabled(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	h, log := doHandler()
+	rateLimitMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, true)
+	c.Assert(recorder.Header().Get("RateLimit-Limit"), check.Equals, "")
+}
+
+func (s *S) TestRateLimitMiddlewareByToken(c *check.C) {
+	config.Set("server:rate-limit:token:rate", 0.001)
+	config.Set("server:rate-limit:token:burst", 2)
+	defer config.Unset("server:rate-limit")
+	for i, remaining := range []string{"1", "0"} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest("GET", "/", nil)
+		c.Assert(err, check.IsNil)
+		context.SetAuthToken(request, s.token)
+		h, log := doHandler()
+		rateLimitMiddleware(recorder, request, h)
+		c.Assert(log.called, check.Equals, true, check.Commentf("request %d", i))
+		c.Assert(recorder.Header().Get("RateLimit-Limit"), check.Equals, "2")
+		c.Assert(recorder.Header().Get("RateLimit-Remaining"), check.Equals, remaining)
+	}
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	context.SetAuthToken(request, s.token)
+	h, log := doHandler()
+	rateLimitMiddleware(recorder, request, h)
+	c.Assert(log.called, check.Equals, false)
+	c.Assert(recorder.Header().Get("RateLimit-Remaining"), check.Equals, "0")
+	c.Assert(recorder.Header().Get("Retry-After"), check.Not(check.Equals), "")
+	e, ok := context.GetRequestError(request).(*errors.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Code, check.Equals, http.StatusTooManyRequests)
+	n, err := s.conn.RateLimit().Find(bson.M{"_id": bson.RegEx{Pattern: s.token.GetValue()}}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+	n, err = s.conn.RateLimit().FindId(fmt.Sprintf("token:%x", sha256.Sum256([]byte(s.token.GetValue())))).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 1)
+}
+
+func (s *S) TestRateLimitMiddlewareByIP(c *check.C) {
+	config.Set("server:rate-limit:ip:rate", 0.001)
+	config.Set("server:rate-limit:ip:burst", 1)
+	defer config.Unset("server:rate-limit")
+	request, err := http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.RemoteAddr = "10.0.0.1:3333"
+	h, log := doHandler()
+	rateLimitMiddleware(httptest.NewRecorder(), request, h)
+	c.Assert(log.called, check.Equals, true)
+	request, err = http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.RemoteAddr = "10.0.0.1:4444"
+	h, log = doHandler()
+	rateLimitMiddleware(httptest.NewRecorder(), request, h)
+	c.Assert(log.called, check.Equals, false)
+	request, err = http.NewRequest("GET", "/", nil)
+	c.Assert(err, check.IsNil)
+	request.RemoteAddr = "10.0.0.2:3333"
+	h, log = doHandler()
+	rateLimitMiddleware(httptest.NewRecorder(), request, h)
+	c.Assert(log.called, check.Equals, true)
+}
+
+func (s *S) TestRateLimitResultReset(c *check.C) {
+	limit := rateLimit{Rate: 1, Burst: 10}
+	result := limit.result(5, true)
+	c.Assert(result.allowed, check.Equals, true)
+	c.Assert(result.remaining, check.Equals, 5)
+	c.Assert(result.reset.Seconds(), check.Equals, float64(5))
+	result = limit.result(0.5, false)
+	c.Assert(result.allowed, check.Equals, false)
+	c.Assert(result.remaining, check.Equals, 0)
+	c.Assert(result.reset.Seconds(), check.Equals, float64(1))
+}
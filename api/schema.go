@@ -0,0 +1,114 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+//go:generate bash -c "rm -f schemadocs.go && go run ./generator/main.go -i ../docs/handlers.yml -o schemadocs.go"
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var pathParamRegexp = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// handlerDoc holds the documentation of an API handler, as described in
+// docs/handlers.yml.
+type handlerDoc struct {
+	Title     string
+	Path      string
+	Method    string
+	Consume   string
+	Produce   string
+	Responses map[string]string
+}
+
+type schemaParameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+}
+
+type schemaResponse struct {
+	Description string `json:"description"`
+}
+
+type schemaOperation struct {
+	Summary    string                    `json:"summary"`
+	Consumes   []string                  `json:"consumes,omitempty"`
+	Produces   []string                  `json:"produces,omitempty"`
+	Parameters []schemaParameter         `json:"parameters,omitempty"`
+	Responses  map[string]schemaResponse `json:"responses"`
+}
+
+type schemaInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type apiSchema struct {
+	Swagger  string                                `json:"swagger"`
+	Info     schemaInfo                            `json:"info"`
+	BasePath string                                `json:"basePath"`
+	Paths    map[string]map[string]schemaOperation `json:"paths"`
+}
+
+// buildSchema builds a Swagger 2.0 document describing the given handlers.
+func buildSchema(docs []handlerDoc) apiSchema {
+	schema := apiSchema{
+		Swagger:  "2.0",
+		Info:     schemaInfo{Title: "tsuru", Version: Version},
+		BasePath: "/",
+		Paths:    make(map[string]map[string]schemaOperation),
+	}
+	for _, doc := range docs {
+		op := schemaOperation{
+			Summary:   doc.Title,
+			Responses: make(map[string]schemaResponse, len(doc.Responses)),
+		}
+		if doc.Consume != "" {
+			op.Consumes = []string{doc.Consume}
+		}
+		if doc.Produce != "" {
+			op.Produces = []string{doc.Produce}
+		}
+		for _, match := range pathParamRegexp.FindAllStringSubmatch(doc.Path, -1) {
+			op.Parameters = append(op.Parameters, schemaParameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Type:     "string",
+			})
+		}
+		for code, description := range doc.Responses {
+			op.Responses[code] = schemaResponse{Description: description}
+		}
+		path := pathParamRegexp.ReplaceAllString(doc.Path, "{$1}")
+		if schema.Paths[path] == nil {
+			schema.Paths[path] = make(map[string]schemaOperation)
+		}
+		methods := []string{doc.Method}
+		if doc.Method == "*" {
+			methods = []string{"GET", "POST", "PUT", "DELETE"}
+		}
+		for _, method := range methods {
+			schema.Paths[path][strings.ToLower(method)] = op
+		}
+	}
+	return schema
+}
+
+// title: api schema
+// path: /schema
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+func schema(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(buildSchema(handlerDocs))
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestBuildSchema(c *check.C) {
+	docs := []handlerDoc{
+		{
+			Title:     "app info",
+			Path:      "/apps/{app}",
+			Method:    "GET",
+			Produce:   "application/json",
+			Responses: map[string]string{"200": "OK", "404": "Not found"},
+		},
+		{
+			Title:     "remove cname",
+			Path:      "/apps/{app}/cname/{cname:.*}",
+			Method:    "DELETE",
+			Consume:   "application/x-www-form-urlencoded",
+			Responses: map[string]string{"200": "OK"},
+		},
+	}
+	schema := buildSchema(docs)
+	c.Assert(schema.Swagger, check.Equals, "2.0")
+	c.Assert(schema.Info, check.Equals, schemaInfo{Title: "tsuru", Version: Version})
+	c.Assert(schema.Paths, check.DeepEquals, map[string]map[string]schemaOperation{
+		"/apps/{app}": {
+			"get": {
+				Summary:  "app info",
+				Produces: []string{"application/json"},
+				Parameters: []schemaParameter{
+					{Name: "app", In: "path", Required: true, Type: "string"},
+				},
+				Responses: map[string]schemaResponse{
+					"200": {Description: "OK"},
+					"404": {Description: "Not found"},
+				},
+			},
+		},
+		"/apps/{app}/cname/{cname}": {
+			"delete": {
+				Summary:  "remove cname",
+				Consumes: []string{"application/x-www-form-urlencoded"},
+				Parameters: []schemaParameter{
+					{Name: "app", In: "path", Required: true, Type: "string"},
+					{Name: "cname", In: "path", Required: true, Type: "string"},
+				},
+				Responses: map[string]schemaResponse{
+					"200": {Description: "OK"},
+				},
+			},
+		},
+	})
+}
+
+func (s *S) TestSchema(c *check.C) {
+	request, err := http.NewRequest("GET", "/schema", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	server := RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var result apiSchema
+	err = json.NewDecoder(recorder.Body).Decode(&result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result.Swagger, check.Equals, "2.0")
+	c.Assert(result.Paths["/schema"]["get"].Summary, check.Equals, "api schema")
+	c.Assert(result.Paths["/apps/{name}"]["get"].Parameters, check.DeepEquals, []schemaParameter{
+		{Name: "name", In: "path", Required: true, Type: "string"},
+	})
+	c.Assert(result.Paths["/services/proxy/service/{service}"], check.HasLen, 4)
+}
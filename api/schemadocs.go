@@ -0,0 +1,2743 @@
+// AUTOMATICALLY GENERATED FILE - DO NOT EDIT!
+// Please run 'go generate' to update this file.
+//
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+var handlerDocs = []handlerDoc{
+	{
+		Title:  "index",
+		Path:   "/",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "OK",
+		},
+	},
+	{
+		Title:   "app list",
+		Path:    "/apps",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List apps",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "app create",
+		Path:    "/apps",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "App created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Quota exceeded",
+			"409": "App already exists",
+		},
+	},
+	{
+		Title:   "apps bulk action",
+		Path:    "/apps/{action}",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "app deploy",
+		Path:    "/apps/{appname}/deploy",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "rollback",
+		Path:    "/apps/{appname}/deploy/rollback",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "deploy upload create",
+		Path:    "/apps/{appname}/deploy/uploads",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Upload created",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "Too many uploads",
+		},
+	},
+	{
+		Title:   "deploy upload info",
+		Path:    "/apps/{appname}/deploy/uploads/{upload}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "deploy upload chunk",
+		Path:    "/apps/{appname}/deploy/uploads/{upload}",
+		Method:  "PUT",
+		Consume: "application/octet-stream",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Chunk received",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+			"409": "Invalid offset",
+			"413": "Chunk or upload too large",
+		},
+	},
+	{
+		Title:   "deploy diff",
+		Path:    "/apps/{appname}/diff",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "application quota",
+		Path:    "/apps/{appname}/quota",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Application not found",
+		},
+	},
+	{
+		Title:   "update application quota",
+		Path:    "/apps/{appname}/quota",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Quota updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Application not found",
+		},
+	},
+	{
+		Title:  "remove app autoscale rule",
+		Path:   "/apps/{app}/autoscale",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Rule removed",
+			"401": "Unauthorized",
+			"404": "App or rule not found",
+		},
+	},
+	{
+		Title:   "list app autoscale rules",
+		Path:    "/apps/{app}/autoscale",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "set app autoscale rule",
+		Path:    "/apps/{app}/autoscale",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Rule set",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "list app scaling schedules",
+		Path:    "/apps/{app}/autoscale/schedules",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "add app scaling schedule",
+		Path:    "/apps/{app}/autoscale/schedules",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Schedule created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "remove app scaling schedule",
+		Path:   "/apps/{app}/autoscale/schedules/{id}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Schedule removed",
+			"401": "Unauthorized",
+			"404": "App or schedule not found",
+		},
+	},
+	{
+		Title:   "app clone",
+		Path:    "/apps/{app}/clone",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "App cloned",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Quota exceeded",
+			"404": "App not found",
+			"409": "App already exists",
+		},
+	},
+	{
+		Title:  "unset cname",
+		Path:   "/apps/{app}/cname",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "set cname",
+		Path:    "/apps/{app}/cname",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "move cname",
+		Path:    "/apps/{app}/cname/move",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App or cname not found",
+			"409": "Destination app is locked",
+		},
+	},
+	{
+		Title:   "add app dependency",
+		Path:    "/apps/{app}/dependencies",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "Dependency cycle",
+		},
+	},
+	{
+		Title:  "remove app dependency",
+		Path:   "/apps/{app}/dependencies/{dependency}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "unset envs",
+		Path:    "/apps/{app}/env",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Envs removed",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "get envs",
+		Path:    "/apps/{app}/env",
+		Method:  "GET",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "set envs",
+		Path:    "/apps/{app}/env",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Envs updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app env diff",
+		Path:    "/apps/{app}/env/diff",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "export envs",
+		Path:    "/apps/{app}/env/export",
+		Method:  "GET",
+		Produce: "text/plain, application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app env history",
+		Path:    "/apps/{app}/env/history",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "import envs",
+		Path:    "/apps/{app}/env/import",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Envs imported",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "list app jobs",
+		Path:    "/apps/{app}/jobs",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "add app job",
+		Path:    "/apps/{app}/jobs",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Job created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "Job already exists",
+		},
+	},
+	{
+		Title:  "remove app job",
+		Path:   "/apps/{app}/jobs/{job}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Job removed",
+			"401": "Unauthorized",
+			"404": "App or job not found",
+		},
+	},
+	{
+		Title:   "list app job runs",
+		Path:    "/apps/{app}/jobs/{job}/runs",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid limit",
+			"401": "Unauthorized",
+			"404": "App or job not found",
+		},
+	},
+	{
+		Title:   "app unlock",
+		Path:    "/apps/{app}/lock",
+		Method:  "DELETE",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "Lock is not stale",
+		},
+	},
+	{
+		Title:  "app log purge",
+		Path:   "/apps/{app}/log",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Logs removed",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app log",
+		Path:    "/apps/{app}/log",
+		Method:  "GET",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app log",
+		Path:    "/apps/{app}/log",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "app log drain remove",
+		Path:   "/apps/{app}/log-drains",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Log drain removed",
+			"401": "Unauthorized",
+			"404": "App or log drain not found",
+		},
+	},
+	{
+		Title:   "app log drain list",
+		Path:    "/apps/{app}/log-drains",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app log drain add",
+		Path:    "/apps/{app}/log-drains",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Log drain added",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "Log drain already exists",
+		},
+	},
+	{
+		Title:   "app log retention",
+		Path:    "/apps/{app}/log/retention",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app log retention set",
+		Path:    "/apps/{app}/log/retention",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Log retention set",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "unset app metadata",
+		Path:   "/apps/{app}/metadata",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "set app metadata",
+		Path:    "/apps/{app}/metadata",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "metric envs",
+		Path:    "/apps/{app}/metric/envs",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app metrics",
+		Path:    "/apps/{app}/metrics",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app change pool",
+		Path:    "/apps/{app}/pool",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "App or pool not found",
+		},
+	},
+	{
+		Title:   "app request stats",
+		Path:    "/apps/{app}/requests",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "add app request samples",
+		Path:    "/apps/{app}/requests",
+		Method:  "POST",
+		Consume: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app restart",
+		Path:    "/apps/{app}/restart",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "rebuild routes",
+		Path:    "/apps/{app}/routes",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "run commands",
+		Path:    "/apps/{app}/run",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app sleep",
+		Path:    "/apps/{app}/sleep",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app slo",
+		Path:    "/apps/{app}/slo",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app slo set",
+		Path:    "/apps/{app}/slo",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "SLO set",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app start",
+		Path:    "/apps/{app}/start",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app stop",
+		Path:    "/apps/{app}/stop",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "list app access",
+		Path:    "/apps/{app}/teams",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "revoke access to app",
+		Path:   "/apps/{app}/teams/{team}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Access revoked",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "App or team not found",
+		},
+	},
+	{
+		Title:  "grant access to app",
+		Path:   "/apps/{app}/teams/{team}",
+		Method: "PUT",
+		Responses: map[string]string{
+			"200": "Access granted",
+			"401": "Unauthorized",
+			"404": "App or team not found",
+			"409": "Grant already exists",
+		},
+	},
+	{
+		Title:   "list app scoped tokens",
+		Path:    "/apps/{app}/tokens",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "create app scoped token",
+		Path:    "/apps/{app}/tokens",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Token created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "revoke app scoped token",
+		Path:   "/apps/{app}/tokens/{id}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Token revoked",
+			"400": "Invalid id",
+			"401": "Unauthorized",
+			"404": "App or token not found",
+		},
+	},
+	{
+		Title:   "register unit",
+		Path:    "/apps/{app}/units/register",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "app unit info",
+		Path:    "/apps/{app}/units/{unit}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "App or unit not found",
+		},
+	},
+	{
+		Title:   "set unit status",
+		Path:    "/apps/{app}/units/{unit}",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App or unit not found",
+		},
+	},
+	{
+		Title:   "app unit replace",
+		Path:    "/apps/{app}/units/{unit}/replace",
+		Method:  "POST",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App or unit not found",
+		},
+	},
+	{
+		Title:   "app unit restart",
+		Path:    "/apps/{app}/units/{unit}/restart",
+		Method:  "POST",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "App or unit not found",
+		},
+	},
+	{
+		Title:   "remove app",
+		Path:    "/apps/{name}",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "App removed",
+			"202": "App removal started",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "app info",
+		Path:    "/apps/{name}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "app update",
+		Path:    "/apps/{name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "App updated",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "remove units",
+		Path:    "/apps/{name}/units",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Units removed",
+			"202": "Units removal started",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "add units",
+		Path:    "/apps/{name}/units",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Units added",
+			"202": "Units addition started",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "login",
+		Path:    "/auth/login",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "saml metadata",
+		Path:    "/auth/saml",
+		Method:  "GET",
+		Produce: "application/xml",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+		},
+	},
+	{
+		Title:  "saml callback",
+		Path:   "/auth/saml",
+		Method: "POST",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+		},
+	},
+	{
+		Title:   "get auth scheme",
+		Path:    "/auth/scheme",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+		},
+	},
+	{
+		Title:   "service broker list",
+		Path:    "/brokers",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List service brokers",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "service broker add",
+		Path:    "/brokers",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Service broker created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Service broker already exists",
+		},
+	},
+	{
+		Title:  "service broker delete",
+		Path:   "/brokers/{broker}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Service broker removed",
+			"401": "Unauthorized",
+			"404": "Service broker not found",
+			"409": "Service broker has service instances",
+		},
+	},
+	{
+		Title:   "service broker update",
+		Path:    "/brokers/{broker}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Service broker updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Service broker not found",
+		},
+	},
+	{
+		Title:   "correlation info",
+		Path:    "/correlations/{id}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "dump goroutines",
+		Path:   "/debug/goroutines",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "Ok",
+		},
+	},
+	{
+		Title:  "profile index handler",
+		Path:   "/debug/pprof",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "profile cmdline handler",
+		Path:   "/debug/pprof/cmdline",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "profile handler",
+		Path:   "/debug/pprof/profile",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "profile symbol handler",
+		Path:   "/debug/pprof/symbol",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "deploy list",
+		Path:    "/deploys",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+		},
+	},
+	{
+		Title:   "deploy stats",
+		Path:    "/deploys/stats",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+		},
+	},
+	{
+		Title:   "deploy info",
+		Path:    "/deploys/{deploy}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "get autoscale config",
+		Path:    "/docker/autoscale/config",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "autoscale rules list",
+		Path:    "/docker/autoscale/rules",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "autoscale set rule",
+		Path:    "/docker/autoscale/rules",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "delete autoscale rule",
+		Path:   "/docker/autoscale/rules/{id}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "autoscale run",
+		Path:    "/docker/autoscale/run",
+		Method:  "POST",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "move container",
+		Path:    "/docker/container/{id}/move",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "move containers",
+		Path:    "/docker/containers/move",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "rebalance containers",
+		Path:    "/docker/containers/rebalance",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list healing history",
+		Path:    "/docker/healing",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list autoscale history",
+		Path:    "/docker/healing",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "remove node healing",
+		Path:    "/docker/healing/node",
+		Method:  "DELETE",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "node healing info",
+		Path:    "/docker/healing/node",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "node healing update",
+		Path:    "/docker/healing/node",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "logs config",
+		Path:    "/docker/logs",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "logs config set",
+		Path:    "/docker/logs",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list nodes",
+		Path:    "/docker/node",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+		},
+	},
+	{
+		Title:   "add node",
+		Path:    "/docker/node",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"201": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "update nodes",
+		Path:    "/docker/node",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "list containers by app",
+		Path:    "/docker/node/apps/{appname}/containers",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:  "remove node",
+		Path:   "/docker/node/{address}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "list containers by node",
+		Path:    "/docker/node/{address}/containers",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "drain node",
+		Path:    "/docker/node/{address}/drain",
+		Method:  "POST",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "node metrics",
+		Path:    "/docker/node/{address}/metrics",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "remove node container list",
+		Path:    "/docker/nodecontainers",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "node container create",
+		Path:    "/docker/nodecontainers",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invald data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "remove node container",
+		Path:   "/docker/nodecontainers/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "node container info",
+		Path:    "/docker/nodecontainers/{name}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "node container update",
+		Path:    "/docker/nodecontainers/{name}",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invald data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "node container upgrade",
+		Path:    "/docker/nodecontainers/{name}/upgrade",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invald data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "event stream",
+		Path:    "/events/stream",
+		Method:  "GET",
+		Produce: "text/event-stream",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid filters",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "freeze remove",
+		Path:   "/freezes",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Unfrozen",
+			"401": "Unauthorized",
+			"404": "Freeze not found",
+		},
+	},
+	{
+		Title:   "freeze list",
+		Path:    "/freezes",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "freeze add",
+		Path:    "/freezes",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Frozen",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "remove unit healing",
+		Path:    "/healing/unit",
+		Method:  "DELETE",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "unit healing info",
+		Path:    "/healing/unit",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "unit healing update",
+		Path:    "/healing/unit",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "healthcheck",
+		Path:   "/healthcheck",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "OK",
+			"500": "Internal server error",
+		},
+	},
+	{
+		Title:   "machine list",
+		Path:    "/iaas/machines",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "machine destroy",
+		Path:   "/iaas/machines/{machine_id}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "machine template list",
+		Path:    "/iaas/templates",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "template create",
+		Path:    "/iaas/templates",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Template created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "template destroy",
+		Path:   "/iaas/templates/{template_name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "template update",
+		Path:    "/iaas/templates/{template_name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "api info",
+		Path:    "/info",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+		},
+	},
+	{
+		Title:   "job info",
+		Path:    "/jobs/{id}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid id",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "set node status",
+		Path:    "/node/status",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App or unit not found",
+		},
+	},
+	{
+		Title:   "list permissions",
+		Path:    "/permissions",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "plan list",
+		Path:    "/plans",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+		},
+	},
+	{
+		Title:   "plan create",
+		Path:    "/plans",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Plan created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Plan already exists",
+		},
+	},
+	{
+		Title:   "router list",
+		Path:    "/plans/routers",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+		},
+	},
+	{
+		Title:  "remove plan",
+		Path:   "/plans/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Plan removed",
+			"401": "Unauthorized",
+			"404": "Plan not found",
+		},
+	},
+	{
+		Title:   "plan update",
+		Path:    "/plans/{name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Plan updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Plan not found",
+		},
+	},
+	{
+		Title:   "platform list",
+		Path:    "/platforms",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List platforms",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "add platform",
+		Path:    "/platforms",
+		Method:  "POST",
+		Consume: "multipart/form-data",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Platform created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "remove platform",
+		Path:   "/platforms/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Platform removed",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "update platform",
+		Path:    "/platforms/{name}",
+		Method:  "PUT",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Platform updated",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "pool list",
+		Path:    "/pools",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "pool create",
+		Path:    "/pools",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Pool created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Pool already exists",
+		},
+	},
+	{
+		Title:  "remove pool",
+		Path:   "/pools/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Pool removed",
+			"401": "Unauthorized",
+			"404": "Pool not found",
+		},
+	},
+	{
+		Title:   "pool update",
+		Path:    "/pools/{name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Pool updated",
+			"401": "Unauthorized",
+			"404": "Pool not found",
+			"409": "Default pool already defined",
+		},
+	},
+	{
+		Title:  "remove team from pool",
+		Path:   "/pools/{name}/team",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Pool updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Pool not found",
+		},
+	},
+	{
+		Title:   "add team too pool",
+		Path:    "/pools/{name}/team",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Pool updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Pool not found",
+		},
+	},
+	{
+		Title:  "readiness",
+		Path:   "/readiness",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "OK",
+			"503": "Service unavailable",
+		},
+	},
+	{
+		Title:   "usage report",
+		Path:    "/reports/usage",
+		Method:  "GET",
+		Produce: "application/json, text/csv",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "remove default role",
+		Path:   "/role/default",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list default roles",
+		Path:    "/role/default",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "add default role",
+		Path:   "/role/default",
+		Method: "POST",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "role list",
+		Path:    "/roles",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "role create",
+		Path:    "/roles",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Role created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Role already exists",
+		},
+	},
+	{
+		Title:  "remove role",
+		Path:   "/roles/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Role removed",
+			"401": "Unauthorized",
+			"404": "Role not found",
+		},
+	},
+	{
+		Title:   "role info",
+		Path:    "/roles/{name}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Role not found",
+		},
+	},
+	{
+		Title:   "role update",
+		Path:    "/roles/{name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Role updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Role not found",
+			"409": "Role already exists or referenced in config",
+		},
+	},
+	{
+		Title:   "add permissions",
+		Path:    "/roles/{name}/permissions",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Permission not allowed",
+		},
+	},
+	{
+		Title:  "remove permission",
+		Path:   "/roles/{name}/permissions/{permission}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Permission removed",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "assign role to user",
+		Path:    "/roles/{name}/user",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Role not found",
+		},
+	},
+	{
+		Title:  "dissociate role from user",
+		Path:   "/roles/{name}/user/{email}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Role not found",
+		},
+	},
+	{
+		Title:   "api schema",
+		Path:    "/schema",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+		},
+	},
+	{
+		Title:   "service list",
+		Path:    "/services",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List services",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "service create",
+		Path:    "/services",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Service created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Service already exists",
+		},
+	},
+	{
+		Title:   "service instance list",
+		Path:    "/services/instances",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List services instances",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "service proxy",
+		Path:   "/services/proxy/service/{service}",
+		Method: "*",
+		Responses: map[string]string{
+			"401": "Unauthorized",
+			"404": "Service not found",
+		},
+	},
+	{
+		Title:  "service delete",
+		Path:   "/services/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Service removed",
+			"401": "Unauthorized",
+			"403": "Forbidden (team is not the owner or service with instances)",
+			"404": "Service not found",
+		},
+	},
+	{
+		Title:   "service info",
+		Path:    "/services/{name}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+		},
+	},
+	{
+		Title:   "service update",
+		Path:    "/services/{name}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Service updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden (team is not the owner)",
+			"404": "Service not found",
+		},
+	},
+	{
+		Title:  "service doc",
+		Path:   "/services/{name}/doc",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "change service documentation",
+		Path:    "/services/{name}/doc",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Documentation updated",
+			"401": "Unauthorized",
+			"403": "Forbidden (team is not the owner or service with instances)",
+		},
+	},
+	{
+		Title:   "remove service instance",
+		Path:    "/services/{name}/instances/{instance}",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Service removed",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "service plans",
+		Path:    "/services/{name}/plans",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Service not found",
+		},
+	},
+	{
+		Title:  "service instance dashboard",
+		Path:   "/services/{service}/dashboard/{instance}/{path}",
+		Method: "*",
+		Responses: map[string]string{
+			"401": "Unauthorized",
+			"404": "Instance not found",
+		},
+	},
+	{
+		Title:   "service instance create",
+		Path:    "/services/{service}/instances",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Service created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Service already exists",
+		},
+	},
+	{
+		Title:  "revoke access to service instance",
+		Path:   "/services/{service}/instances/permission/{instance}/{team}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Access revoked",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "grant access to service instance",
+		Path:    "/services/{service}/instances/permission/{instance}/{team}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Access granted",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "service instance info",
+		Path:    "/services/{service}/instances/{instance}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "service instance update",
+		Path:    "/services/{service}/instances/{instance}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Service instance updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:  "service instance status",
+		Path:   "/services/{service}/instances/{instance}/status",
+		Method: "GET",
+		Responses: map[string]string{
+			"200": "List services instances",
+			"401": "Unauthorized",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "service instance status callback",
+		Path:    "/services/{service}/instances/{instance}/status",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Status updated",
+			"400": "Invalid data",
+			"401": "Invalid signature",
+			"403": "Callbacks not enabled for the service",
+			"404": "Service instance not found",
+		},
+	},
+	{
+		Title:   "unbind service instance",
+		Path:    "/services/{service}/instances/{instance}/{app}",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:   "bind service instance",
+		Path:    "/services/{service}/instances/{instance}/{app}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+		},
+	},
+	{
+		Title:  "service instance proxy",
+		Path:   "/services/{service}/proxy/{instance}",
+		Method: "*",
+		Responses: map[string]string{
+			"401": "Unauthorized",
+			"404": "Instance not found",
+		},
+	},
+	{
+		Title:  "revoke access to a service",
+		Path:   "/services/{service}/team/{team}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Access revoked",
+			"400": "Team not found",
+			"401": "Unauthorized",
+			"404": "Service not found",
+			"409": "Team does not has access to this service",
+		},
+	},
+	{
+		Title:  "grant access to a service",
+		Path:   "/services/{service}/team/{team}",
+		Method: "PUT",
+		Responses: map[string]string{
+			"200": "Service updated",
+			"400": "Team not found",
+			"401": "Unauthorized",
+			"404": "Service not found",
+			"409": "Team already has access to this service",
+		},
+	},
+	{
+		Title:   "app swap",
+		Path:    "/swap",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "App not found",
+			"409": "App locked",
+			"412": "Number of units or platform don't match",
+		},
+	},
+	{
+		Title:   "team list",
+		Path:    "/teams",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List teams",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "team create",
+		Path:    "/teams",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Team created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Team already exists",
+		},
+	},
+	{
+		Title:  "remove team",
+		Path:   "/teams/{name}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Team removed",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "list team alerts",
+		Path:    "/teams/{name}/alerts",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list team alert rules",
+		Path:    "/teams/{name}/alerts/rules",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "remove team alert rule",
+		Path:   "/teams/{name}/alerts/rules/{rule}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Rule removed",
+			"401": "Unauthorized",
+			"404": "Rule not found",
+		},
+	},
+	{
+		Title:   "set team alert rule",
+		Path:    "/teams/{name}/alerts/rules/{rule}",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Rule set",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Team not found",
+		},
+	},
+	{
+		Title:   "change team parent",
+		Path:    "/teams/{name}/parent",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Parent changed",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Team not found",
+		},
+	},
+	{
+		Title:   "team quota",
+		Path:    "/teams/{name}/quota",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "Team not found",
+		},
+	},
+	{
+		Title:   "update team quota",
+		Path:    "/teams/{name}/quota",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Quota updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Team not found",
+		},
+	},
+	{
+		Title:   "list service accounts",
+		Path:    "/teams/{name}/serviceaccounts",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "create service account",
+		Path:    "/teams/{name}/serviceaccounts",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Service account created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "Team or role not found",
+			"409": "Service account already exists",
+		},
+	},
+	{
+		Title:  "remove service account",
+		Path:   "/teams/{name}/serviceaccounts/{account}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Service account removed",
+			"401": "Unauthorized",
+			"404": "Service account not found",
+		},
+	},
+	{
+		Title:   "rotate service account token",
+		Path:    "/teams/{name}/serviceaccounts/{account}/token",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Token rotated",
+			"401": "Unauthorized",
+			"404": "Service account not found",
+		},
+	},
+	{
+		Title:   "app uptime",
+		Path:    "/uptime",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:  "remove user",
+		Path:   "/users",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "User removed",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "user list",
+		Path:    "/users",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "user create",
+		Path:    "/users",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "User created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"409": "User already exists",
+		},
+	},
+	{
+		Title:  "disable two-factor authentication",
+		Path:   "/users/2fa",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Two-factor authentication disabled",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "show token",
+		Path:    "/users/api-key",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "regenerate token",
+		Path:    "/users/api-key",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "user info",
+		Path:    "/users/info",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "list keys",
+		Path:    "/users/keys",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "add key",
+		Path:    "/users/keys",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Key already exists",
+		},
+	},
+	{
+		Title:  "remove key",
+		Path:   "/users/keys/{key}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "change password",
+		Path:    "/users/password",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:  "logout",
+		Path:   "/users/tokens",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Ok",
+		},
+	},
+	{
+		Title:   "token list",
+		Path:    "/users/tokens",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "refresh token",
+		Path:    "/users/tokens/refresh",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "revoke token",
+		Path:    "/users/tokens/revoke",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Token revoked",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "unused tokens report",
+		Path:    "/users/tokens/unused",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"204": "No content",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+		},
+	},
+	{
+		Title:   "enroll two-factor authentication",
+		Path:    "/users/{email}/2fa",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"201": "Enrollment created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+			"409": "Two-factor authentication already enabled",
+		},
+	},
+	{
+		Title:   "confirm two-factor authentication",
+		Path:    "/users/{email}/2fa/confirm",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Two-factor authentication enabled",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Not found",
+			"409": "Two-factor authentication already enabled",
+		},
+	},
+	{
+		Title:  "activate user",
+		Path:   "/users/{email}/activate",
+		Method: "POST",
+		Responses: map[string]string{
+			"200": "User activated",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "deactivate user",
+		Path:    "/users/{email}/deactivate",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "User deactivated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "impersonate user",
+		Path:    "/users/{email}/impersonate",
+		Method:  "POST",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:  "reset password",
+		Path:   "/users/{email}/password",
+		Method: "POST",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"403": "Forbidden",
+			"404": "Not found",
+		},
+	},
+	{
+		Title:   "user quota",
+		Path:    "/users/{email}/quota",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "OK",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:   "update user quota",
+		Path:    "/users/{email}/quota",
+		Method:  "PUT",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"200": "Quota updated",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "User not found",
+		},
+	},
+	{
+		Title:  "verify email",
+		Path:   "/users/{email}/verify",
+		Method: "POST",
+		Responses: map[string]string{
+			"200": "Ok",
+			"400": "Invalid data",
+		},
+	},
+	{
+		Title:   "volume plan list",
+		Path:    "/volumeplans",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List volume plans",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "volume list",
+		Path:    "/volumes",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "List volumes",
+			"204": "No content",
+			"401": "Unauthorized",
+		},
+	},
+	{
+		Title:   "volume create",
+		Path:    "/volumes",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Responses: map[string]string{
+			"201": "Volume created",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"409": "Volume already exists",
+		},
+	},
+	{
+		Title:  "volume delete",
+		Path:   "/volumes/{volume}",
+		Method: "DELETE",
+		Responses: map[string]string{
+			"200": "Volume removed",
+			"401": "Unauthorized",
+			"404": "Volume not found",
+			"409": "Volume is bound to apps",
+		},
+	},
+	{
+		Title:   "volume info",
+		Path:    "/volumes/{volume}",
+		Method:  "GET",
+		Produce: "application/json",
+		Responses: map[string]string{
+			"200": "Show volume",
+			"401": "Unauthorized",
+			"404": "Volume not found",
+		},
+	},
+	{
+		Title:   "volume unbind",
+		Path:    "/volumes/{volume}/bind",
+		Method:  "DELETE",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Volume unbound",
+			"401": "Unauthorized",
+			"404": "Volume, app or bind not found",
+		},
+	},
+	{
+		Title:   "volume bind",
+		Path:    "/volumes/{volume}/bind",
+		Method:  "POST",
+		Consume: "application/x-www-form-urlencoded",
+		Produce: "application/x-json-stream",
+		Responses: map[string]string{
+			"200": "Volume bound",
+			"400": "Invalid data",
+			"401": "Unauthorized",
+			"404": "Volume or app not found",
+			"409": "Volume already bound",
+		},
+	},
+}
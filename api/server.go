@@ -19,14 +19,22 @@ import (
 	apiRouter "github.com/tsuru/tsuru/api/router"
 	"github.com/tsuru/tsuru/api/shutdown"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/alert"
+	"github.com/tsuru/tsuru/app/autoscale"
+	"github.com/tsuru/tsuru/app/job"
+	"github.com/tsuru/tsuru/app/metrics"
 	"github.com/tsuru/tsuru/auth"
+	_ "github.com/tsuru/tsuru/auth/ldap"
 	_ "github.com/tsuru/tsuru/auth/native"
 	_ "github.com/tsuru/tsuru/auth/oauth"
 	_ "github.com/tsuru/tsuru/auth/saml"
 	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/event/audit"
+	"github.com/tsuru/tsuru/event/authz"
 	"github.com/tsuru/tsuru/hc"
 	"github.com/tsuru/tsuru/healer"
 	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/prom"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/router"
 	"github.com/tsuru/tsuru/router/rebuild"
@@ -93,6 +101,7 @@ func RunServer(dry bool) http.Handler {
 		m.Add("1.0", "Get", "/", Handler(index))
 	}
 	m.Add("1.0", "Get", "/info", Handler(info))
+	m.Add("1.4", "Get", "/schema", Handler(schema))
 
 	m.Add("1.0", "Get", "/services/instances", AuthorizationRequiredHandler(serviceInstances))
 	m.Add("1.0", "Get", "/services/{service}/instances/{instance}", AuthorizationRequiredHandler(serviceInstance))
@@ -102,12 +111,19 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Put", "/services/{service}/instances/{instance}/{app}", AuthorizationRequiredHandler(bindServiceInstance))
 	m.Add("1.0", "Delete", "/services/{service}/instances/{instance}/{app}", AuthorizationRequiredHandler(unbindServiceInstance))
 	m.Add("1.0", "Get", "/services/{service}/instances/{instance}/status", AuthorizationRequiredHandler(serviceInstanceStatus))
+	m.Add("1.4", "Post", "/services/{service}/instances/{instance}/status", Handler(serviceInstanceStatusCallback))
 	m.Add("1.0", "Put", "/services/{service}/instances/permission/{instance}/{team}", AuthorizationRequiredHandler(serviceInstanceGrantTeam))
 	m.Add("1.0", "Delete", "/services/{service}/instances/permission/{instance}/{team}", AuthorizationRequiredHandler(serviceInstanceRevokeTeam))
 
 	m.AddAll("1.0", "/services/{service}/proxy/{instance}", AuthorizationRequiredHandler(serviceInstanceProxy))
+	m.AddAll("1.4", "/services/{service}/dashboard/{instance}/{path:.*}", AuthorizationRequiredHandler(serviceInstanceDashboard))
 	m.AddAll("1.0", "/services/proxy/service/{service}", AuthorizationRequiredHandler(serviceProxy))
 
+	m.Add("1.4", "Get", "/brokers", AuthorizationRequiredHandler(serviceBrokerList))
+	m.Add("1.4", "Post", "/brokers", AuthorizationRequiredHandler(serviceBrokerAdd))
+	m.Add("1.4", "Put", "/brokers/{broker}", AuthorizationRequiredHandler(serviceBrokerUpdate))
+	m.Add("1.4", "Delete", "/brokers/{broker}", AuthorizationRequiredHandler(serviceBrokerDelete))
+
 	m.Add("1.0", "Get", "/services", AuthorizationRequiredHandler(serviceList))
 	m.Add("1.0", "Post", "/services", AuthorizationRequiredHandler(serviceCreate))
 	m.Add("1.0", "Put", "/services/{name}", AuthorizationRequiredHandler(serviceUpdate))
@@ -123,46 +139,96 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Get", "/apps/{app}", AuthorizationRequiredHandler(appInfo))
 	m.Add("1.0", "Post", "/apps/{app}/cname", AuthorizationRequiredHandler(setCName))
 	m.Add("1.0", "Delete", "/apps/{app}/cname", AuthorizationRequiredHandler(unsetCName))
+	m.Add("1.4", "Post", "/apps/{app}/cname/move", AuthorizationRequiredHandler(moveCName))
+	m.Add("1.4", "Post", "/apps/{app}/dependencies", AuthorizationRequiredHandler(addAppDependency))
+	m.Add("1.4", "Delete", "/apps/{app}/dependencies/{dependency}", AuthorizationRequiredHandler(removeAppDependency))
+	m.Add("1.4", "Post", "/apps/{app}/metadata", AuthorizationRequiredHandler(setAppMetadata))
+	m.Add("1.4", "Delete", "/apps/{app}/metadata", AuthorizationRequiredHandler(unsetAppMetadata))
+	m.Add("1.4", "Post", "/apps/{app}/tokens", AuthorizationRequiredHandler(createScopedToken))
+	m.Add("1.4", "Get", "/apps/{app}/tokens", AuthorizationRequiredHandler(listScopedTokens))
+	m.Add("1.4", "Delete", "/apps/{app}/tokens/{id}", AuthorizationRequiredHandler(removeScopedToken))
 	runHandler := AuthorizationRequiredHandler(runCommand)
 	m.Add("1.0", "Post", "/apps/{app}/run", runHandler)
 	m.Add("1.0", "Post", "/apps/{app}/restart", AuthorizationRequiredHandler(restart))
 	m.Add("1.0", "Post", "/apps/{app}/start", AuthorizationRequiredHandler(start))
 	m.Add("1.0", "Post", "/apps/{app}/stop", AuthorizationRequiredHandler(stop))
 	m.Add("1.0", "Post", "/apps/{app}/sleep", AuthorizationRequiredHandler(sleep))
+	m.Add("1.4", "Post", "/apps/{app}/pool", AuthorizationRequiredHandler(changeAppPool))
+	m.Add("1.4", "Get", "/apps/{app}/log-drains", AuthorizationRequiredHandler(listLogDrains))
+	m.Add("1.4", "Post", "/apps/{app}/log-drains", AuthorizationRequiredHandler(addLogDrain))
+	m.Add("1.4", "Delete", "/apps/{app}/log-drains", AuthorizationRequiredHandler(removeLogDrain))
+	m.Add("1.4", "Get", "/freezes", AuthorizationRequiredHandler(listFreezes))
+	m.Add("1.4", "Post", "/freezes", AuthorizationRequiredHandler(addFreeze))
+	m.Add("1.4", "Delete", "/freezes", AuthorizationRequiredHandler(removeFreeze))
+	m.Add("1.4", "Post", "/apps/{action:restart|start|stop}", AuthorizationRequiredHandler(appsBulkAction))
 	m.Add("1.0", "Get", "/apps/{appname}/quota", AuthorizationRequiredHandler(getAppQuota))
 	m.Add("1.0", "Put", "/apps/{appname}/quota", AuthorizationRequiredHandler(changeAppQuota))
 	m.Add("1.0", "Put", "/apps/{appname}", AuthorizationRequiredHandler(updateApp))
 	m.Add("1.0", "Get", "/apps/{app}/env", AuthorizationRequiredHandler(getEnv))
 	m.Add("1.0", "Post", "/apps/{app}/env", AuthorizationRequiredHandler(setEnv))
 	m.Add("1.0", "Delete", "/apps/{app}/env", AuthorizationRequiredHandler(unsetEnv))
+	m.Add("1.4", "Get", "/apps/{app}/env/history", AuthorizationRequiredHandler(envHistory))
+	m.Add("1.4", "Get", "/apps/{app}/env/diff", AuthorizationRequiredHandler(envDiff))
+	m.Add("1.4", "Get", "/apps/{app}/env/export", AuthorizationRequiredHandler(exportEnv))
+	m.Add("1.4", "Post", "/apps/{app}/env/import", AuthorizationRequiredHandler(importEnv))
 	m.Add("1.0", "Get", "/apps", AuthorizationRequiredHandler(appList))
 	m.Add("1.0", "Post", "/apps", AuthorizationRequiredHandler(createApp))
+	m.Add("1.4", "Post", "/apps/{app}/clone", AuthorizationRequiredHandler(cloneApp))
 	forceDeleteLockHandler := AuthorizationRequiredHandler(forceDeleteLock)
 	m.Add("1.0", "Delete", "/apps/{app}/lock", forceDeleteLockHandler)
 	m.Add("1.0", "Put", "/apps/{app}/units", AuthorizationRequiredHandler(addUnits))
 	m.Add("1.0", "Delete", "/apps/{app}/units", AuthorizationRequiredHandler(removeUnits))
+	m.Add("1.4", "Get", "/apps/{app}/autoscale", AuthorizationRequiredHandler(listAppAutoScaleRules))
+	m.Add("1.4", "Put", "/apps/{app}/autoscale", AuthorizationRequiredHandler(setAppAutoScaleRule))
+	m.Add("1.4", "Delete", "/apps/{app}/autoscale", AuthorizationRequiredHandler(removeAppAutoScaleRule))
+	m.Add("1.4", "Get", "/apps/{app}/autoscale/schedules", AuthorizationRequiredHandler(listAppScaleSchedules))
+	m.Add("1.4", "Post", "/apps/{app}/autoscale/schedules", AuthorizationRequiredHandler(addAppScaleSchedule))
+	m.Add("1.4", "Delete", "/apps/{app}/autoscale/schedules/{id}", AuthorizationRequiredHandler(removeAppScaleSchedule))
+	m.Add("1.4", "Get", "/apps/{app}/jobs", AuthorizationRequiredHandler(listAppJobs))
+	m.Add("1.4", "Post", "/apps/{app}/jobs", AuthorizationRequiredHandler(addAppJob))
+	m.Add("1.4", "Delete", "/apps/{app}/jobs/{job}", AuthorizationRequiredHandler(removeAppJob))
+	m.Add("1.4", "Get", "/apps/{app}/jobs/{job}/runs", AuthorizationRequiredHandler(listAppJobRuns))
 	registerUnitHandler := AuthorizationRequiredHandler(registerUnit)
 	m.Add("1.0", "Post", "/apps/{app}/units/register", registerUnitHandler)
 	setUnitStatusHandler := AuthorizationRequiredHandler(setUnitStatus)
 	m.Add("1.0", "Post", "/apps/{app}/units/{unit}", setUnitStatusHandler)
+	m.Add("1.4", "Get", "/apps/{app}/units/{unit}", AuthorizationRequiredHandler(unitInfo))
+	m.Add("1.4", "Post", "/apps/{app}/units/{unit}/restart", AuthorizationRequiredHandler(restartUnit))
+	m.Add("1.4", "Post", "/apps/{app}/units/{unit}/replace", AuthorizationRequiredHandler(replaceUnit))
+	m.Add("1.4", "Get", "/apps/{app}/teams", AuthorizationRequiredHandler(listAppAccess))
 	m.Add("1.0", "Put", "/apps/{app}/teams/{team}", AuthorizationRequiredHandler(grantAppAccess))
 	m.Add("1.0", "Delete", "/apps/{app}/teams/{team}", AuthorizationRequiredHandler(revokeAppAccess))
 	m.Add("1.0", "Get", "/apps/{app}/log", AuthorizationRequiredHandler(appLog))
 	logPostHandler := AuthorizationRequiredHandler(addLog)
 	m.Add("1.0", "Post", "/apps/{app}/log", logPostHandler)
+	m.Add("1.4", "Delete", "/apps/{app}/log", AuthorizationRequiredHandler(purgeLogs))
+	m.Add("1.4", "Get", "/apps/{app}/log/retention", AuthorizationRequiredHandler(getLogRetention))
+	m.Add("1.4", "Put", "/apps/{app}/log/retention", AuthorizationRequiredHandler(setLogRetention))
+	m.Add("1.4", "Get", "/apps/{app}/slo", AuthorizationRequiredHandler(getSLO))
+	m.Add("1.4", "Put", "/apps/{app}/slo", AuthorizationRequiredHandler(setSLO))
 	m.Add("1.0", "Post", "/apps/{appname}/deploy/rollback", AuthorizationRequiredHandler(deployRollback))
 	m.Add("1.0", "Get", "/apps/{app}/metric/envs", AuthorizationRequiredHandler(appMetricEnvs))
+	m.Add("1.4", "Get", "/apps/{app}/metrics", AuthorizationRequiredHandler(appMetrics))
+	m.Add("1.4", "Get", "/apps/{app}/requests", AuthorizationRequiredHandler(appRequestsStats))
+	m.Add("1.4", "Post", "/apps/{app}/requests", AuthorizationRequiredHandler(addAppRequests))
+	m.Add("1.4", "Get", "/uptime", AuthorizationRequiredHandler(appsUptime))
+	m.Add("1.4", "Get", "/reports/usage", AuthorizationRequiredHandler(usageReport))
 	m.Add("1.0", "Post", "/apps/{app}/routes", AuthorizationRequiredHandler(appRebuildRoutes))
 
 	m.Add("1.0", "Post", "/node/status", AuthorizationRequiredHandler(setNodeStatus))
 
 	m.Add("1.0", "Get", "/deploys", AuthorizationRequiredHandler(deploysList))
+	m.Add("1.4", "Get", "/deploys/stats", AuthorizationRequiredHandler(deployStats))
 	m.Add("1.0", "Get", "/deploys/{deploy}", AuthorizationRequiredHandler(deployInfo))
 
 	m.Add("1.1", "Get", "/events", AuthorizationRequiredHandler(eventList))
 	m.Add("1.1", "Get", "/events/kinds", AuthorizationRequiredHandler(kindList))
+	m.Add("1.4", "Get", "/events/stream", AuthorizationRequiredHandler(eventStream))
 	m.Add("1.1", "Get", "/events/{uuid}", AuthorizationRequiredHandler(eventInfo))
 	m.Add("1.1", "Post", "/events/{uuid}/cancel", AuthorizationRequiredHandler(eventCancel))
+	m.Add("1.4", "Get", "/correlations/{id}", AuthorizationRequiredHandler(correlationInfo))
+
+	m.Add("1.4", "Get", "/jobs/{id}", AuthorizationRequiredHandler(jobInfo))
 
 	m.Add("1.0", "Get", "/platforms", AuthorizationRequiredHandler(platformList))
 	m.Add("1.0", "Post", "/platforms", AuthorizationRequiredHandler(platformAdd))
@@ -174,6 +240,9 @@ func RunServer(dry bool) http.Handler {
 	// use a token generated for Gandalf.
 	m.Add("1.0", "Post", "/apps/{appname}/repository/clone", AuthorizationRequiredHandler(deploy))
 	m.Add("1.0", "Post", "/apps/{appname}/deploy", AuthorizationRequiredHandler(deploy))
+	m.Add("1.4", "Post", "/apps/{appname}/deploy/uploads", AuthorizationRequiredHandler(createArchiveUpload))
+	m.Add("1.4", "Get", "/apps/{appname}/deploy/uploads/{upload}", AuthorizationRequiredHandler(archiveUploadInfo))
+	m.Add("1.4", "Put", "/apps/{appname}/deploy/uploads/{upload}", AuthorizationRequiredHandler(archiveUploadChunk))
 	diffDeployHandler := AuthorizationRequiredHandler(diffDeploy)
 	m.Add("1.0", "Post", "/apps/{appname}/diff", diffDeployHandler)
 
@@ -191,12 +260,24 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Get", "/auth/saml", Handler(samlMetadata))
 
 	m.Add("1.0", "Post", "/users/{email}/password", Handler(resetPassword))
+	m.Add("1.4", "Get", "/users/{email}/verify", Handler(verifyEmail))
+	m.Add("1.4", "Post", "/users/{email}/verify", Handler(verifyEmail))
 	m.Add("1.0", "Post", "/users/{email}/tokens", Handler(login))
 	m.Add("1.0", "Get", "/users/{email}/quota", AuthorizationRequiredHandler(getUserQuota))
 	m.Add("1.0", "Put", "/users/{email}/quota", AuthorizationRequiredHandler(changeUserQuota))
 	m.Add("1.0", "Delete", "/users/tokens", AuthorizationRequiredHandler(logout))
+	m.Add("1.4", "Post", "/users/tokens/refresh", AuthorizationRequiredHandler(refreshToken))
+	m.Add("1.4", "Post", "/users/tokens/revoke", AuthorizationRequiredHandler(revokeToken))
+	m.Add("1.4", "Get", "/users/tokens", AuthorizationRequiredHandler(listTokens))
+	m.Add("1.4", "Get", "/users/tokens/unused", AuthorizationRequiredHandler(unusedTokens))
 	m.Add("1.0", "Put", "/users/password", AuthorizationRequiredHandler(changePassword))
+	m.Add("1.4", "Post", "/users/{email}/2fa", Handler(enrollTwoFactor))
+	m.Add("1.4", "Post", "/users/{email}/2fa/confirm", Handler(confirmTwoFactor))
+	m.Add("1.4", "Delete", "/users/2fa", AuthorizationRequiredHandler(disableTwoFactor))
 	m.Add("1.0", "Delete", "/users", AuthorizationRequiredHandler(removeUser))
+	m.Add("1.4", "Post", "/users/{email}/deactivate", AuthorizationRequiredHandler(deactivateUser))
+	m.Add("1.4", "Post", "/users/{email}/activate", AuthorizationRequiredHandler(activateUser))
+	m.Add("1.4", "Post", "/users/{email}/impersonate", AuthorizationRequiredHandler(impersonateUser))
 	m.Add("1.0", "Get", "/users/keys", AuthorizationRequiredHandler(listKeys))
 	m.Add("1.0", "Post", "/users/keys", AuthorizationRequiredHandler(addKeyToUser))
 	m.Add("1.0", "Delete", "/users/keys/{key}", AuthorizationRequiredHandler(removeKeyFromUser))
@@ -208,11 +289,24 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Get", "/teams", AuthorizationRequiredHandler(teamList))
 	m.Add("1.0", "Post", "/teams", AuthorizationRequiredHandler(createTeam))
 	m.Add("1.0", "Delete", "/teams/{name}", AuthorizationRequiredHandler(removeTeam))
+	m.Add("1.4", "Put", "/teams/{name}/parent", AuthorizationRequiredHandler(changeTeamParent))
+	m.Add("1.4", "Get", "/teams/{name}/quota", AuthorizationRequiredHandler(getTeamQuota))
+	m.Add("1.4", "Put", "/teams/{name}/quota", AuthorizationRequiredHandler(changeTeamQuota))
+	m.Add("1.4", "Get", "/teams/{name}/serviceaccounts", AuthorizationRequiredHandler(listServiceAccounts))
+	m.Add("1.4", "Post", "/teams/{name}/serviceaccounts", AuthorizationRequiredHandler(createServiceAccount))
+	m.Add("1.4", "Post", "/teams/{name}/serviceaccounts/{account}/token", AuthorizationRequiredHandler(rotateServiceAccountToken))
+	m.Add("1.4", "Delete", "/teams/{name}/serviceaccounts/{account}", AuthorizationRequiredHandler(removeServiceAccount))
+	m.Add("1.4", "Get", "/teams/{name}/alerts", AuthorizationRequiredHandler(listAlerts))
+	m.Add("1.4", "Get", "/teams/{name}/alerts/rules", AuthorizationRequiredHandler(listAlertRules))
+	m.Add("1.4", "Put", "/teams/{name}/alerts/rules/{rule}", AuthorizationRequiredHandler(setAlertRule))
+	m.Add("1.4", "Delete", "/teams/{name}/alerts/rules/{rule}", AuthorizationRequiredHandler(removeAlertRule))
 
 	m.Add("1.0", "Post", "/swap", AuthorizationRequiredHandler(swap))
 
 	m.Add("1.0", "Get", "/healthcheck/", http.HandlerFunc(healthcheck))
 	m.Add("1.0", "Get", "/healthcheck", http.HandlerFunc(healthcheck))
+	m.Add("1.4", "Get", "/readiness", http.HandlerFunc(readinessCheck))
+	m.Add("1.4", "Get", "/metrics", prom.Handler())
 
 	m.Add("1.0", "Get", "/iaas/machines", AuthorizationRequiredHandler(machinesList))
 	m.Add("1.0", "Delete", "/iaas/machines/{machine_id}", AuthorizationRequiredHandler(machineDestroy))
@@ -223,9 +317,18 @@ func RunServer(dry bool) http.Handler {
 
 	m.Add("1.0", "Get", "/plans", AuthorizationRequiredHandler(listPlans))
 	m.Add("1.0", "Post", "/plans", AuthorizationRequiredHandler(addPlan))
+	m.Add("1.4", "Put", "/plans/{planname}", AuthorizationRequiredHandler(updatePlan))
 	m.Add("1.0", "Delete", "/plans/{planname}", AuthorizationRequiredHandler(removePlan))
 	m.Add("1.0", "Get", "/plans/routers", AuthorizationRequiredHandler(listRouters))
 
+	m.Add("1.4", "Get", "/volumeplans", AuthorizationRequiredHandler(volumePlanList))
+	m.Add("1.4", "Get", "/volumes", AuthorizationRequiredHandler(volumeList))
+	m.Add("1.4", "Post", "/volumes", AuthorizationRequiredHandler(volumeCreate))
+	m.Add("1.4", "Get", "/volumes/{volume}", AuthorizationRequiredHandler(volumeInfo))
+	m.Add("1.4", "Delete", "/volumes/{volume}", AuthorizationRequiredHandler(volumeDelete))
+	m.Add("1.4", "Post", "/volumes/{volume}/bind", AuthorizationRequiredHandler(volumeBind))
+	m.Add("1.4", "Delete", "/volumes/{volume}/bind", AuthorizationRequiredHandler(volumeUnbind))
+
 	m.Add("1.0", "Get", "/pools", AuthorizationRequiredHandler(poolList))
 	m.Add("1.0", "Post", "/pools", AuthorizationRequiredHandler(addPoolHandler))
 	m.Add("1.0", "Delete", "/pools/{name}", AuthorizationRequiredHandler(removePoolHandler))
@@ -237,6 +340,7 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.0", "Post", "/roles", AuthorizationRequiredHandler(addRole))
 	m.Add("1.0", "Get", "/roles/{name}", AuthorizationRequiredHandler(roleInfo))
 	m.Add("1.0", "Delete", "/roles/{name}", AuthorizationRequiredHandler(removeRole))
+	m.Add("1.4", "Put", "/roles/{name}", AuthorizationRequiredHandler(updateRole))
 	m.Add("1.0", "Post", "/roles/{name}/permissions", AuthorizationRequiredHandler(addPermissions))
 	m.Add("1.0", "Delete", "/roles/{name}/permissions/{permission}", AuthorizationRequiredHandler(removePermissions))
 	m.Add("1.0", "Post", "/roles/{name}/user", AuthorizationRequiredHandler(assignRole))
@@ -259,6 +363,7 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.2", "GET", "/node", AuthorizationRequiredHandler(listNodesHandler))
 	m.Add("1.2", "GET", "/node/apps/{appname}/containers", AuthorizationRequiredHandler(listUnitsByApp))
 	m.Add("1.2", "GET", "/node/{address:.*}/containers", AuthorizationRequiredHandler(listUnitsByNode))
+	m.Add("1.4", "GET", "/node/{address:.*}/metrics", AuthorizationRequiredHandler(nodeMetrics))
 	m.Add("1.2", "POST", "/node", AuthorizationRequiredHandler(addNodeHandler))
 	m.Add("1.2", "PUT", "/node", AuthorizationRequiredHandler(updateNodeHandler))
 	m.Add("1.2", "DELETE", "/node/{address:.*}", AuthorizationRequiredHandler(removeNodeHandler))
@@ -277,11 +382,15 @@ func RunServer(dry bool) http.Handler {
 	m.Add("1.3", "GET", "/healing/node", AuthorizationRequiredHandler(nodeHealingRead))
 	m.Add("1.3", "POST", "/healing/node", AuthorizationRequiredHandler(nodeHealingUpdate))
 	m.Add("1.3", "DELETE", "/healing/node", AuthorizationRequiredHandler(nodeHealingDelete))
+	m.Add("1.4", "GET", "/healing/unit", AuthorizationRequiredHandler(unitHealingRead))
+	m.Add("1.4", "POST", "/healing/unit", AuthorizationRequiredHandler(unitHealingUpdate))
+	m.Add("1.4", "DELETE", "/healing/unit", AuthorizationRequiredHandler(unitHealingDelete))
 
 	// Handlers for compatibility reasons, should be removed on tsuru 2.0.
 	m.Add("1.0", "GET", "/docker/node", AuthorizationRequiredHandler(listNodesHandler))
 	m.Add("1.0", "GET", "/docker/node/apps/{appname}/containers", AuthorizationRequiredHandler(listUnitsByApp))
 	m.Add("1.0", "GET", "/docker/node/{address:.*}/containers", AuthorizationRequiredHandler(listUnitsByNode))
+	m.Add("1.4", "GET", "/docker/node/{address:.*}/metrics", AuthorizationRequiredHandler(nodeMetrics))
 	m.Add("1.0", "POST", "/docker/node", AuthorizationRequiredHandler(addNodeHandler))
 	m.Add("1.0", "PUT", "/docker/node", AuthorizationRequiredHandler(updateNodeHandler))
 	m.Add("1.0", "DELETE", "/docker/node/{address:.*}", AuthorizationRequiredHandler(removeNodeHandler))
@@ -303,12 +412,17 @@ func RunServer(dry bool) http.Handler {
 	if !dry {
 		n.Use(newLoggerMiddleware())
 	}
+	n.Use(negroni.HandlerFunc(metricsMiddleware))
+	n.Use(negroni.HandlerFunc(corsMiddleware))
 	n.UseHandler(m)
+	n.Use(negroni.HandlerFunc(gzipMiddleware))
 	n.Use(negroni.HandlerFunc(flushingWriterMiddleware))
 	n.Use(negroni.HandlerFunc(setRequestIDHeaderMiddleware))
 	n.Use(negroni.HandlerFunc(errorHandlingMiddleware))
 	n.Use(negroni.HandlerFunc(setVersionHeadersMiddleware))
 	n.Use(negroni.HandlerFunc(authTokenMiddleware))
+	n.Use(negroni.HandlerFunc(rateLimitMiddleware))
+	n.Use(negroni.HandlerFunc(idempotencyMiddleware))
 	n.Use(&appLockMiddleware{excludedHandlers: []http.Handler{
 		logPostHandler,
 		runHandler,
@@ -339,9 +453,12 @@ func startServer(handler http.Handler) {
 	if shutdownTimeout == 0 {
 		shutdownTimeout = 10 * 60
 	}
+	var shutdownDeadline time.Time
 	idleTracker := newIdleTracker()
 	shutdown.Register(idleTracker)
 	shutdown.Register(&logTracker)
+	shutdown.Register(&eventStreams)
+	shutdown.Register(&apiReadiness)
 	readTimeout, _ := config.GetInt("server:read-timeout")
 	writeTimeout, _ := config.GetInt("server:write-timeout")
 	listen, err := config.GetString("listen")
@@ -362,6 +479,7 @@ func startServer(handler http.Handler) {
 		NoSignalHandling: true,
 		ShutdownInitiated: func() {
 			fmt.Println("tsuru is shutting down, waiting for pending connections to finish.")
+			shutdownDeadline = time.Now().Add(time.Duration(shutdownTimeout) * time.Second)
 			handlers := shutdown.All()
 			wg := sync.WaitGroup{}
 			for _, h := range handlers {
@@ -428,10 +546,52 @@ func startServer(handler http.Handler) {
 	if err != nil {
 		fatal(err)
 	}
+	auditSinks, err := audit.Initialize()
+	if err != nil {
+		fatal(err)
+	}
+	for _, sink := range auditSinks {
+		fmt.Printf("Mirroring events to %q audit sink.\n", sink)
+	}
+	authzURL, err := authz.Initialize()
+	if err != nil {
+		fatal(err)
+	}
+	if authzURL != "" {
+		fmt.Printf("Consulting authorization hook at %s before operations.\n", authzURL)
+	}
 	_, err = healer.Initialize()
 	if err != nil {
 		fatal(err)
 	}
+	autoScaler, err := autoscale.Initialize()
+	if err != nil {
+		fatal(err)
+	}
+	if autoScaler != nil {
+		fmt.Println("App autoscale controller started.")
+	}
+	err = metrics.StartReporter()
+	if err != nil {
+		fatal(err)
+	}
+	metricsCollector, err := metrics.Initialize()
+	if err != nil {
+		fatal(err)
+	}
+	if metricsCollector != nil {
+		fmt.Println("App metrics collector started.")
+	}
+	alerts, err := alert.Initialize()
+	if err != nil {
+		fatal(err)
+	}
+	if alerts != nil {
+		fmt.Println("Alerts controller started.")
+	}
+	autoscale.StartScheduler()
+	job.StartScheduler()
+	auth.StartTokenUsageRecorder()
 	fmt.Println("Checking components status:")
 	results := hc.Check()
 	for _, result := range results {
@@ -469,4 +629,8 @@ func startServer(handler http.Handler) {
 		}
 	}
 	<-shutdownChan
+	fmt.Println("waiting for background jobs to finish.")
+	for _, appName := range appLocks.drain(shutdownDeadline.Sub(time.Now())) {
+		fmt.Printf("released lock for app %q.\n", appName)
+	}
 }
@@ -0,0 +1,172 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/service"
+)
+
+func serviceBrokerTarget(name string) event.Target {
+	return event.Target{Type: event.TargetTypeServiceBroker, Value: name}
+}
+
+func serviceBrokerError(err error) error {
+	switch err {
+	case service.ErrBrokerNotFound:
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	case service.ErrBrokerAlreadyExists, service.ErrBrokerHasInstances:
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+// title: service broker list
+// path: /brokers
+// method: GET
+// produce: application/json
+// responses:
+//   200: List service brokers
+//   204: No content
+//   401: Unauthorized
+func serviceBrokerList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	if !permission.Check(t, permission.PermServiceBrokerRead) {
+		return permission.ErrUnauthorized
+	}
+	brokers, err := service.ListBrokers()
+	if err != nil {
+		return err
+	}
+	if len(brokers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(brokers)
+}
+
+// title: service broker add
+// path: /brokers
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   201: Service broker created
+//   400: Invalid data
+//   401: Unauthorized
+//   409: Service broker already exists
+func serviceBrokerAdd(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	if !permission.Check(t, permission.PermServiceBrokerCreate) {
+		return permission.ErrUnauthorized
+	}
+	broker := service.Broker{
+		Name:          r.FormValue("name"),
+		URL:           r.FormValue("url"),
+		Username:      r.FormValue("username"),
+		Password:      r.FormValue("password"),
+		SigningSecret: r.FormValue("signing-secret"),
+		Team:          r.FormValue("team"),
+	}
+	delete(r.Form, "password")
+	delete(r.Form, "signing-secret")
+	evt, err := event.New(&event.Opts{
+		Target:     serviceBrokerTarget(broker.Name),
+		Kind:       permission.PermServiceBrokerCreate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermServiceBrokerReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = service.AddBroker(&broker)
+	if err != nil {
+		return serviceBrokerError(err)
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// title: service broker update
+// path: /brokers/{broker}
+// method: PUT
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Service broker updated
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Service broker not found
+func serviceBrokerUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	if !permission.Check(t, permission.PermServiceBrokerUpdate) {
+		return permission.ErrUnauthorized
+	}
+	broker, err := service.GetBroker(r.URL.Query().Get(":broker"))
+	if err != nil {
+		return serviceBrokerError(err)
+	}
+	if url := r.FormValue("url"); url != "" {
+		broker.URL = url
+	}
+	if username := r.FormValue("username"); username != "" {
+		broker.Username = username
+	}
+	if password := r.FormValue("password"); password != "" {
+		broker.Password = password
+	}
+	if secret := r.FormValue("signing-secret"); secret != "" {
+		broker.SigningSecret = secret
+	}
+	delete(r.Form, "password")
+	delete(r.Form, "signing-secret")
+	evt, err := event.New(&event.Opts{
+		Target:     serviceBrokerTarget(broker.Name),
+		Kind:       permission.PermServiceBrokerUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermServiceBrokerReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return serviceBrokerError(service.UpdateBroker(broker))
+}
+
+// title: service broker delete
+// path: /brokers/{broker}
+// method: DELETE
+// responses:
+//   200: Service broker removed
+//   401: Unauthorized
+//   404: Service broker not found
+//   409: Service broker has service instances
+func serviceBrokerDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	if !permission.Check(t, permission.PermServiceBrokerDelete) {
+		return permission.ErrUnauthorized
+	}
+	name := r.URL.Query().Get(":broker")
+	evt, err := event.New(&event.Opts{
+		Target:  serviceBrokerTarget(name),
+		Kind:    permission.PermServiceBrokerDelete,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermServiceBrokerReadEvents),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return serviceBrokerError(service.RemoveBroker(name))
+}
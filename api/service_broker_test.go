@@ -0,0 +1,124 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/service"
+	"gopkg.in/check.v1"
+)
+
+func fakeServiceBroker() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"services": [{"id": "redis-id", "name": "redis", "description": "Redis", "plans": [{"id": "p1", "name": "small"}]}]}`))
+	}))
+}
+
+func (s *S) TestServiceBrokerAdd(c *check.C) {
+	ts := fakeServiceBroker()
+	defer ts.Close()
+	body := strings.NewReader("name=mybroker&url=" + ts.URL + "&username=user&password=secret&signing-secret=s3cr3t&team=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/brokers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	broker, err := service.GetBroker("mybroker")
+	c.Assert(err, check.IsNil)
+	c.Assert(broker.Password, check.Equals, "secret")
+	c.Assert(broker.SigningSecret, check.Equals, "s3cr3t")
+	c.Assert(broker.Catalog, check.HasLen, 1)
+	srv := service.Service{Name: "redis"}
+	err = srv.Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(srv.Broker, check.Equals, "mybroker")
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeServiceBroker, Value: "mybroker"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "service-broker.create",
+		StartCustomData: []map[string]interface{}{
+			{"name": "name", "value": "mybroker"},
+			{"name": "url", "value": ts.URL},
+			{"name": "username", "value": "user"},
+			{"name": "team", "value": s.team.Name},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestServiceBrokerAddInvalidName(c *check.C) {
+	body := strings.NewReader("name=My_Broker&url=http://localhost&team=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/brokers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestServiceBrokerAddUnauthorized(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermServiceBrokerRead,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	body := strings.NewReader("name=mybroker&url=http://localhost&team=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/brokers", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
+func (s *S) TestServiceBrokerListAndDelete(c *check.C) {
+	ts := fakeServiceBroker()
+	defer ts.Close()
+	err := service.AddBroker(&service.Broker{Name: "mybroker", URL: ts.URL, Password: "secret", Team: s.team.Name})
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/brokers", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Not(check.Matches), "(?s).*secret.*")
+	var brokers []service.Broker
+	err = json.NewDecoder(recorder.Body).Decode(&brokers)
+	c.Assert(err, check.IsNil)
+	c.Assert(brokers, check.HasLen, 1)
+	c.Assert(brokers[0].Name, check.Equals, "mybroker")
+	request, err = http.NewRequest("DELETE", "/brokers/mybroker", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = service.GetBroker("mybroker")
+	c.Assert(err, check.Equals, service.ErrBrokerNotFound)
+}
+
+func (s *S) TestServiceBrokerDeleteNotFound(c *check.C) {
+	request, err := http.NewRequest("DELETE", "/brokers/unknown", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
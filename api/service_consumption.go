@@ -9,18 +9,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/tsuru/config"
-	"github.com/tsuru/tsuru/api/context"
 	"github.com/tsuru/tsuru/auth"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	tsuruIo "github.com/tsuru/tsuru/io"
+	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/service"
 )
@@ -28,6 +29,10 @@ import (
 const (
 	httpMethodGet  = "GET"
 	httpMethodHead = "HEAD"
+
+	// callbackMaxAge is how old the signature of a callback sent by a
+	// service may be before the callback is rejected as a replay.
+	callbackMaxAge = 5 * time.Minute
 )
 
 func serviceInstanceTarget(name, instance string) event.Target {
@@ -97,9 +102,7 @@ func createServiceInstance(w http.ResponseWriter, r *http.Request, t auth.Token)
 		return err
 	}
 	defer func() { evt.Done(err) }()
-	requestIDHeader, _ := config.GetString("request-id-header")
-	requestID := context.GetRequestID(r, requestIDHeader)
-	err = service.CreateServiceInstance(instance, &srv, user, requestID)
+	err = service.CreateServiceInstance(instance, &srv, user, requestID(r))
 	if err == service.ErrInstanceNameAlreadyExists {
 		return &tsuruErrors.HTTP{
 			Code:    http.StatusConflict,
@@ -223,9 +226,7 @@ func removeServiceInstance(w http.ResponseWriter, r *http.Request, t auth.Token)
 			}
 		}
 	}
-	requestIDHeader, _ := config.GetString("request-id-header")
-	requestID := context.GetRequestID(r, requestIDHeader)
-	err = service.DeleteInstance(serviceInstance, requestID)
+	err = service.DeleteInstance(serviceInstance, requestID(r))
 	if err != nil {
 		if err == service.ErrServiceInstanceBound {
 			writer.Write([]byte(strings.Join(serviceInstance.Apps, ",")))
@@ -352,15 +353,62 @@ func serviceInstanceStatus(w http.ResponseWriter, r *http.Request, t auth.Token)
 		return permission.ErrUnauthorized
 	}
 	var b string
-	requestIDHeader, _ := config.GetString("request-id-header")
-	requestID := context.GetRequestID(r, requestIDHeader)
-	if b, err = serviceInstance.Status(requestID); err != nil {
+	if b, err = serviceInstance.Status(requestID(r)); err != nil {
 		return errors.Wrap(err, "Could not retrieve status of service instance, error")
 	}
 	_, err = fmt.Fprintf(w, `Service instance "%s" is %s`, instanceName, b)
 	return err
 }
 
+// title: service instance status callback
+// path: /services/{service}/instances/{instance}/status
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   200: Status updated
+//   400: Invalid data
+//   401: Invalid signature
+//   403: Callbacks not enabled for the service
+//   404: Service instance not found
+func serviceInstanceStatusCallback(w http.ResponseWriter, r *http.Request) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	serviceName := r.URL.Query().Get(":service")
+	s, err := getService(serviceName)
+	if err != nil {
+		return err
+	}
+	secret, err := s.CallbackSecret()
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return &tsuruErrors.HTTP{
+			Code:    http.StatusForbidden,
+			Message: "callbacks are not enabled for this service, a signing secret is required",
+		}
+	}
+	err = net.VerifyRequest(r, body, secret, callbackMaxAge)
+	if err != nil {
+		return &tsuruErrors.HTTP{Code: http.StatusUnauthorized, Message: err.Error()}
+	}
+	serviceInstance, err := getServiceInstanceOrError(serviceName, r.URL.Query().Get(":instance"))
+	if err != nil {
+		return err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	status := form.Get("status")
+	if status == "" {
+		return &tsuruErrors.HTTP{Code: http.StatusBadRequest, Message: "status is required"}
+	}
+	return serviceInstance.SetStatus(status)
+}
+
 type serviceInstanceInfo struct {
 	Apps            []string
 	Teams           []string
@@ -369,6 +417,9 @@ type serviceInstanceInfo struct {
 	PlanName        string
 	PlanDescription string
 	CustomInfo      map[string]string
+	CreatedAt       time.Time
+	LastStatus      string
+	LastStatusAt    time.Time
 }
 
 // title: service instance info
@@ -392,13 +443,11 @@ func serviceInstance(w http.ResponseWriter, r *http.Request, t auth.Token) error
 	if !allowed {
 		return permission.ErrUnauthorized
 	}
-	requestIDHeader, _ := config.GetString("request-id-header")
-	requestID := context.GetRequestID(r, requestIDHeader)
-	info, err := serviceInstance.Info(requestID)
+	info, err := serviceInstance.Info(requestID(r))
 	if err != nil {
 		return err
 	}
-	plan, err := service.GetPlanByServiceNameAndPlanName(serviceName, serviceInstance.PlanName, requestID)
+	plan, err := service.GetPlanByServiceNameAndPlanName(serviceName, serviceInstance.PlanName, requestID(r))
 	if err != nil {
 		return err
 	}
@@ -410,6 +459,9 @@ func serviceInstance(w http.ResponseWriter, r *http.Request, t auth.Token) error
 		PlanName:        plan.Name,
 		PlanDescription: plan.Description,
 		CustomInfo:      info,
+		CreatedAt:       serviceInstance.CreatedAt,
+		LastStatus:      serviceInstance.LastStatus,
+		LastStatusAt:    serviceInstance.LastStatusAt,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	return json.NewEncoder(w).Encode(sInfo)
@@ -498,9 +550,7 @@ func servicePlans(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 			return permission.ErrUnauthorized
 		}
 	}
-	requestIDHeader, _ := config.GetString("request-id-header")
-	requestID := context.GetRequestID(r, requestIDHeader)
-	plans, err := service.GetPlansByServiceName(serviceName, requestID)
+	plans, err := service.GetPlansByServiceName(serviceName, requestID(r))
 	if err != nil {
 		return err
 	}
@@ -566,6 +616,56 @@ func serviceInstanceProxy(w http.ResponseWriter, r *http.Request, t auth.Token)
 	return service.Proxy(serviceInstance.Service(), path, w, r)
 }
 
+// title: service instance dashboard
+// path: /services/{service}/dashboard/{instance}/{path}
+// method: "*"
+// responses:
+//   401: Unauthorized
+//   404: Instance not found
+func serviceInstanceDashboard(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	parseFormPreserveBody(r)
+	serviceName := r.URL.Query().Get(":service")
+	instanceName := r.URL.Query().Get(":instance")
+	serviceInstance, err := getServiceInstanceOrError(serviceName, instanceName)
+	if err != nil {
+		return err
+	}
+	allowed := permission.Check(t, permission.PermServiceInstanceUpdateProxy,
+		contextsForServiceInstance(serviceInstance, serviceName)...,
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	path := r.URL.Query().Get(":path")
+	query := r.URL.Query()
+	for key := range query {
+		if strings.HasPrefix(key, ":") {
+			query.Del(key)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	if r.Method != httpMethodGet && r.Method != httpMethodHead {
+		evt, err := event.New(&event.Opts{
+			Target: serviceInstanceTarget(serviceName, instanceName),
+			Kind:   permission.PermServiceInstanceUpdateProxy,
+			Owner:  t,
+			CustomData: append(event.FormToCustomData(r.Form), map[string]interface{}{
+				"name":  "method",
+				"value": r.Method,
+			}),
+			Allowed: event.Allowed(permission.PermServiceInstanceReadEvents,
+				contextsForServiceInstance(serviceInstance, serviceName)...),
+		})
+		if err != nil {
+			return err
+		}
+		defer func() { evt.Done(err) }()
+	}
+	return serviceInstance.ProxyDashboard(path, w, r)
+}
+
 // title: grant access to service instance
 // path: /services/{service}/instances/permission/{instance}/{team}
 // consume: application/x-www-form-urlencoded
@@ -12,8 +12,10 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/api/context"
@@ -24,6 +26,7 @@ import (
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event/eventtest"
 	"github.com/tsuru/tsuru/io"
+	tsuruNet "github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/provisiontest"
@@ -1238,6 +1241,105 @@ func (s *ConsumptionSuite) TestServiceInstanceStatusHandlerShouldReturnForbidden
 	c.Assert(e.Code, check.Equals, http.StatusForbidden)
 }
 
+func makeStatusCallbackRequest(serviceName, instanceName, body string, c *check.C) *http.Request {
+	url := fmt.Sprintf("/services/%s/instances/%s/status", serviceName, instanceName)
+	request, err := http.NewRequest("POST", url, strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceStatusCallback(c *check.C) {
+	srv := service.Service{Name: "mongodb", OwnerTeams: []string{s.team.Name}, SigningSecret: "s3cr3t"}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	defer srv.Delete()
+	si := service.ServiceInstance{Name: "my_nosql", ServiceName: srv.Name, Teams: []string{s.team.Name}}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer service.DeleteInstance(&si, "")
+	request := makeStatusCallbackRequest("mongodb", "my_nosql", "status=up", c)
+	tsuruNet.SignRequest(request, []byte("status=up"), "s3cr3t")
+	recorder := httptest.NewRecorder()
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	instance, err := service.GetServiceInstance("mongodb", "my_nosql")
+	c.Assert(err, check.IsNil)
+	c.Assert(instance.LastStatus, check.Equals, "up")
+	c.Assert(instance.LastStatusAt.IsZero(), check.Equals, false)
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceStatusCallbackBroker(c *check.C) {
+	ts := fakeServiceBroker()
+	defer ts.Close()
+	err := service.AddBroker(&service.Broker{Name: "mybroker", URL: ts.URL, SigningSecret: "s3cr3t", Team: s.team.Name})
+	c.Assert(err, check.IsNil)
+	defer service.RemoveBroker("mybroker")
+	si := service.ServiceInstance{Name: "my_redis", ServiceName: "redis", Teams: []string{s.team.Name}}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer s.conn.ServiceInstances().Remove(bson.M{"name": si.Name, "service_name": si.ServiceName})
+	request := makeStatusCallbackRequest("redis", "my_redis", "status=up", c)
+	tsuruNet.SignRequest(request, []byte("status=up"), "s3cr3t")
+	recorder := httptest.NewRecorder()
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	instance, err := service.GetServiceInstance("redis", "my_redis")
+	c.Assert(err, check.IsNil)
+	c.Assert(instance.LastStatus, check.Equals, "up")
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceStatusCallbackTamperedBody(c *check.C) {
+	srv := service.Service{Name: "mongodb", OwnerTeams: []string{s.team.Name}, SigningSecret: "s3cr3t"}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	defer srv.Delete()
+	si := service.ServiceInstance{Name: "my_nosql", ServiceName: srv.Name, Teams: []string{s.team.Name}}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer service.DeleteInstance(&si, "")
+	request := makeStatusCallbackRequest("mongodb", "my_nosql", "status=down", c)
+	tsuruNet.SignRequest(request, []byte("status=up"), "s3cr3t")
+	recorder := httptest.NewRecorder()
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+	c.Assert(recorder.Body.String(), check.Equals, tsuruNet.ErrInvalidSignature.Error()+"\n")
+	instance, err := service.GetServiceInstance("mongodb", "my_nosql")
+	c.Assert(err, check.IsNil)
+	c.Assert(instance.LastStatus, check.Equals, "")
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceStatusCallbackStaleTimestamp(c *check.C) {
+	srv := service.Service{Name: "mongodb", OwnerTeams: []string{s.team.Name}, SigningSecret: "s3cr3t"}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	defer srv.Delete()
+	si := service.ServiceInstance{Name: "my_nosql", ServiceName: srv.Name, Teams: []string{s.team.Name}}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer service.DeleteInstance(&si, "")
+	request := makeStatusCallbackRequest("mongodb", "my_nosql", "status=up", c)
+	timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	request.Header.Set(tsuruNet.TimestampHeader, timestamp)
+	request.Header.Set(tsuruNet.SignatureHeader, tsuruNet.Signature("s3cr3t", timestamp, "POST", request.URL.RequestURI(), []byte("status=up")))
+	recorder := httptest.NewRecorder()
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusUnauthorized)
+	c.Assert(recorder.Body.String(), check.Equals, tsuruNet.ErrExpiredSignature.Error()+"\n")
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceStatusCallbackWithoutSigningSecret(c *check.C) {
+	srv := service.Service{Name: "mongodb", OwnerTeams: []string{s.team.Name}}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	defer srv.Delete()
+	request := makeStatusCallbackRequest("mongodb", "my_nosql", "status=up", c)
+	tsuruNet.SignRequest(request, []byte("status=up"), "")
+	recorder := httptest.NewRecorder()
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
 func makeRequestToInfoHandler(service, instance, token string, c *check.C) (*httptest.ResponseRecorder, *http.Request) {
 	url := fmt.Sprintf("/services/%s/instances/%s", service, instance)
 	request, err := http.NewRequest("GET", url, nil)
@@ -1336,6 +1438,40 @@ func (s *ConsumptionSuite) TestServiceInstanceInfoHandlerNoPlanAndNoCustomInfo(c
 	c.Assert(instances, check.DeepEquals, expected)
 }
 
+func (s *ConsumptionSuite) TestServiceInstanceInfoHandlerCreationDateAndLastStatus(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	srv := service.Service{Name: "mongodb", Teams: []string{s.team.Name}, Endpoint: map[string]string{"production": ts.URL}}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	defer srv.Delete()
+	createdAt := time.Date(2016, time.October, 10, 14, 30, 0, 0, time.UTC)
+	checkedAt := time.Date(2016, time.October, 12, 9, 15, 0, 0, time.UTC)
+	si := service.ServiceInstance{
+		Name:         "my_nosql",
+		ServiceName:  srv.Name,
+		Teams:        []string{s.team.Name},
+		TeamOwner:    s.team.Name,
+		CreatedAt:    createdAt,
+		LastStatus:   "up",
+		LastStatusAt: checkedAt,
+	}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer service.DeleteInstance(&si, "")
+	recorder, request := makeRequestToInfoHandler("mongodb", "my_nosql", s.token.GetValue(), c)
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var info serviceInstanceInfo
+	err = json.Unmarshal(recorder.Body.Bytes(), &info)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.CreatedAt.Equal(createdAt), check.Equals, true)
+	c.Assert(info.LastStatus, check.Equals, "up")
+	c.Assert(info.LastStatusAt.Equal(checkedAt), check.Equals, true)
+}
+
 func (s *ConsumptionSuite) TestServiceInstanceInfoHandlerShouldReturnErrorWhenServiceInstanceNotExists(c *check.C) {
 	recorder, request := makeRequestToInfoHandler("mongodb", "inexistent-instance", s.token.GetValue(), c)
 	s.m.ServeHTTP(recorder, request)
@@ -1731,6 +1867,59 @@ func (s *ConsumptionSuite) TestServiceInstanceProxyError(c *check.C) {
 	c.Assert(recorder.Body.Bytes(), check.DeepEquals, []byte("some error"))
 }
 
+func (s *ConsumptionSuite) TestServiceInstanceDashboard(c *check.C) {
+	var proxyedRequest *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyedRequest = r
+		w.Write([]byte("<html>queues</html>"))
+	}))
+	defer ts.Close()
+	se := service.Service{Name: "foo", Endpoint: map[string]string{"production": ts.URL}}
+	err := se.Create()
+	c.Assert(err, check.IsNil)
+	defer s.conn.Services().Remove(bson.M{"_id": se.Name})
+	si := service.ServiceInstance{Name: "foo-instance", ServiceName: "foo", Teams: []string{s.team.Name}}
+	err = si.Create()
+	c.Assert(err, check.IsNil)
+	defer service.DeleteInstance(&si, "")
+	url := fmt.Sprintf("/services/%s/dashboard/%s/queues/main?page=2", si.ServiceName, si.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	reqAuth := "bearer " + s.token.GetValue()
+	request.Header.Set("Authorization", reqAuth)
+	m := RunServer(true)
+	recorder := &closeNotifierResponseRecorder{httptest.NewRecorder()}
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Body.String(), check.Equals, "<html>queues</html>")
+	c.Assert(proxyedRequest, check.NotNil)
+	c.Assert(proxyedRequest.Header.Get("Authorization"), check.Not(check.Equals), reqAuth)
+	c.Assert(proxyedRequest.URL.String(), check.Equals, "/resources/foo-instance/dashboard/queues/main?page=2")
+}
+
+func (s *ConsumptionSuite) TestServiceInstanceDashboardNoPermission(c *check.C) {
+	se := service.Service{Name: "foo", Endpoint: map[string]string{"production": "http://localhost:1"}}
+	err := se.Create()
+	c.Assert(err, check.IsNil)
+	defer s.conn.Services().Remove(bson.M{"_id": se.Name})
+	si := service.ServiceInstance{Name: "foo-instance", ServiceName: "foo", Teams: []string{s.team.Name}}
+	err = s.conn.ServiceInstances().Insert(si)
+	c.Assert(err, check.IsNil)
+	defer s.conn.ServiceInstances().Remove(bson.M{"name": si.Name})
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermServiceInstanceRead,
+		Context: permission.Context(permission.CtxServiceInstance, serviceIntancePermName(si.ServiceName, si.Name)),
+	})
+	url := fmt.Sprintf("/services/%s/dashboard/%s/queues", si.ServiceName, si.Name)
+	request, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	m := RunServer(true)
+	recorder := &closeNotifierResponseRecorder{httptest.NewRecorder()}
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
+
 func (s *ConsumptionSuite) TestGrantRevokeServiceToTeam(c *check.C) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("{'AA': 2}"))
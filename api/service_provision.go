@@ -27,6 +27,9 @@ func serviceValidate(s service.Service) error {
 	if s.Password == "" {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: "Service password is required"}
 	}
+	if s.SigningSecret != "" && s.SigningSecret == s.Password {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "Service signing secret must not be the password"}
+	}
 	if endpoint, ok := s.Endpoint["production"]; !ok || endpoint == "" {
 		return &errors.HTTP{Code: http.StatusBadRequest, Message: "Service production endpoint is required"}
 	}
@@ -84,10 +87,11 @@ func serviceList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
 //   409: Service already exists
 func serviceCreate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	s := service.Service{
-		Name:     r.FormValue("id"),
-		Username: r.FormValue("username"),
-		Endpoint: map[string]string{"production": r.FormValue("endpoint")},
-		Password: r.FormValue("password"),
+		Name:          r.FormValue("id"),
+		Username:      r.FormValue("username"),
+		Endpoint:      map[string]string{"production": r.FormValue("endpoint")},
+		Password:      r.FormValue("password"),
+		SigningSecret: r.FormValue("signing-secret"),
 	}
 	team := r.FormValue("team")
 	if team == "" {
@@ -114,6 +118,7 @@ func serviceCreate(w http.ResponseWriter, r *http.Request, t auth.Token) (err er
 		return permission.ErrUnauthorized
 	}
 	delete(r.Form, "password")
+	delete(r.Form, "signing-secret")
 	evt, err := event.New(&event.Opts{
 		Target:     serviceTarget(s.Name),
 		Kind:       permission.PermServiceCreate,
@@ -150,10 +155,11 @@ func serviceCreate(w http.ResponseWriter, r *http.Request, t auth.Token) (err er
 //   404: Service not found
 func serviceUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
 	d := service.Service{
-		Username: r.FormValue("username"),
-		Endpoint: map[string]string{"production": r.FormValue("endpoint")},
-		Password: r.FormValue("password"),
-		Name:     r.URL.Query().Get(":name"),
+		Username:      r.FormValue("username"),
+		Endpoint:      map[string]string{"production": r.FormValue("endpoint")},
+		Password:      r.FormValue("password"),
+		SigningSecret: r.FormValue("signing-secret"),
+		Name:          r.URL.Query().Get(":name"),
 	}
 	err = serviceValidate(d)
 	if err != nil {
@@ -170,6 +176,7 @@ func serviceUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) (err er
 		return permission.ErrUnauthorized
 	}
 	delete(r.Form, "password")
+	delete(r.Form, "signing-secret")
 	evt, err := event.New(&event.Opts{
 		Target:     serviceTarget(s.Name),
 		Kind:       permission.PermServiceUpdate,
@@ -183,6 +190,7 @@ func serviceUpdate(w http.ResponseWriter, r *http.Request, t auth.Token) (err er
 	defer func() { evt.Done(err) }()
 	s.Endpoint = d.Endpoint
 	s.Password = d.Password
+	s.SigningSecret = d.SigningSecret
 	s.Username = d.Username
 	return s.Update()
 }
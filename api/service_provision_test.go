@@ -275,6 +275,49 @@ func (s *ProvisionSuite) TestServiceUpdate(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *ProvisionSuite) TestServiceUpdateSigningSecret(c *check.C) {
+	service := service.Service{
+		Name:       "mysqlapi",
+		Endpoint:   map[string]string{"production": "sqlapi.com"},
+		OwnerTeams: []string{s.team.Name},
+		Password:   "oldold",
+	}
+	err := service.Create()
+	c.Assert(err, check.IsNil)
+	defer s.conn.Services().Remove(bson.M{"_id": service.Name})
+	v := url.Values{}
+	v.Set("password", "yyyy")
+	v.Set("signing-secret", "s3cr3t")
+	v.Set("endpoint", "mysqlapi.com")
+	recorder, request := s.makeRequest("PUT", "/services/mysqlapi", v.Encode(), c)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	err = s.conn.Services().Find(bson.M{"_id": service.Name}).One(&service)
+	c.Assert(err, check.IsNil)
+	c.Assert(service.SigningSecret, check.Equals, "s3cr3t")
+	c.Assert(eventtest.EventDesc{
+		Target: serviceTarget("mysqlapi"),
+		Owner:  s.token.GetUserName(),
+		Kind:   "service.update",
+		StartCustomData: []map[string]interface{}{
+			{"name": "endpoint", "value": "mysqlapi.com"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *ProvisionSuite) TestUpdateHandlerReturnsBadRequestWithSigningSecretEqualToPassword(c *check.C) {
+	v := url.Values{}
+	v.Set("password", "zzzz")
+	v.Set("signing-secret", "zzzz")
+	v.Set("endpoint", "mysqlapi.com")
+	recorder, request := s.makeRequest("PUT", "/services/mysqlapi", v.Encode(), c)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), check.Equals, "Service signing secret must not be the password\n")
+}
+
 func (s *ProvisionSuite) TestUpdateHandlerReturnsBadRequestWithoutPassword(c *check.C) {
 	v := url.Values{}
 	v.Set("id", "some_service")
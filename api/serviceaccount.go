@@ -0,0 +1,184 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+)
+
+func serviceAccountTeamContext(r *http.Request) permission.PermissionContext {
+	return permission.Context(permission.CtxTeam, r.URL.Query().Get(":name"))
+}
+
+// title: create service account
+// path: /teams/{name}/serviceaccounts
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/json
+// responses:
+//   201: Service account created
+//   400: Invalid data
+//   401: Unauthorized
+//   403: Forbidden
+//   404: Team or role not found
+//   409: Service account already exists
+func createServiceAccount(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	teamName := r.URL.Query().Get(":name")
+	ctx := serviceAccountTeamContext(r)
+	allowed := permission.Check(t, permission.PermTeamServiceAccountCreate, ctx)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	roleName := r.FormValue("role")
+	role, err := permission.FindRole(roleName)
+	if err == permission.ErrRoleNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	for _, perm := range role.PermissionsFor(teamName) {
+		if !permission.Check(t, perm.Scheme, perm.Context) {
+			return &errors.HTTP{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("you don't have the permission %q in team %q", perm.Scheme.FullName(), teamName),
+			}
+		}
+	}
+	u, err := t.User()
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: teamName},
+		Kind:       permission.PermTeamServiceAccountCreate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	account, err := auth.CreateServiceAccount(u, teamName, r.FormValue("name"), r.FormValue("description"), roleName)
+	switch err {
+	case nil:
+	case auth.ErrTeamNotFound:
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	case auth.ErrInvalidServiceAccountName, auth.ErrServiceAccountInvalidRole:
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: err.Error()}
+	case auth.ErrServiceAccountAlreadyExists:
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	default:
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(account)
+}
+
+// title: list service accounts
+// path: /teams/{name}/serviceaccounts
+// method: GET
+// produce: application/json
+// responses:
+//   200: OK
+//   204: No content
+//   401: Unauthorized
+func listServiceAccounts(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	allowed := permission.Check(t, permission.PermTeamServiceAccountRead, serviceAccountTeamContext(r))
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	accounts, err := auth.ListServiceAccounts(r.URL.Query().Get(":name"))
+	if err != nil {
+		return err
+	}
+	if len(accounts) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(accounts)
+}
+
+// title: rotate service account token
+// path: /teams/{name}/serviceaccounts/{account}/token
+// method: POST
+// produce: application/json
+// responses:
+//   200: Token rotated
+//   401: Unauthorized
+//   404: Service account not found
+func rotateServiceAccountToken(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	teamName := r.URL.Query().Get(":name")
+	ctx := serviceAccountTeamContext(r)
+	allowed := permission.Check(t, permission.PermTeamServiceAccountUpdate, ctx)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: teamName},
+		Kind:       permission.PermTeamServiceAccountUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	account, err := auth.RotateServiceAccountToken(teamName, r.URL.Query().Get(":account"))
+	if err == auth.ErrServiceAccountNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(account)
+}
+
+// title: remove service account
+// path: /teams/{name}/serviceaccounts/{account}
+// method: DELETE
+// responses:
+//   200: Service account removed
+//   401: Unauthorized
+//   404: Service account not found
+func removeServiceAccount(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	teamName := r.URL.Query().Get(":name")
+	ctx := serviceAccountTeamContext(r)
+	allowed := permission.Check(t, permission.PermTeamServiceAccountDelete, ctx)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeTeam, Value: teamName},
+		Kind:       permission.PermTeamServiceAccountDelete,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermTeamReadEvents, ctx),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = auth.RemoveServiceAccount(teamName, r.URL.Query().Get(":account"))
+	if err == auth.ErrServiceAccountNotFound {
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCreateServiceAccount(c *check.C) {
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("name=ci&description=builds&role=deployer")
+	request, err := http.NewRequest("POST", "/teams/"+s.team.Name+"/serviceaccounts", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var account auth.ServiceAccount
+	err = json.NewDecoder(recorder.Body).Decode(&account)
+	c.Assert(err, check.IsNil)
+	c.Assert(account.Token, check.Not(check.Equals), "")
+	c.Assert(account.Name, check.Equals, "ci")
+	c.Assert(account.Team, check.Equals, s.team.Name)
+	c.Assert(account.Role, check.Equals, "deployer")
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeTeam, Value: s.team.Name},
+		Owner:  s.token.GetUserName(),
+		Kind:   "team.service-account.create",
+		StartCustomData: []map[string]interface{}{
+			{"name": "name", "value": "ci"},
+			{"name": "description", "value": "builds"},
+			{"name": "role", "value": "deployer"},
+			{"name": ":name", "value": s.team.Name},
+		},
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("GET", "/apps", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+account.Token)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Not(check.Equals), http.StatusUnauthorized)
+}
+
+func (s *S) TestCreateServiceAccountWithoutRolePermissions(c *check.C) {
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermTeamServiceAccountCreate,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	body := strings.NewReader("name=ci&role=deployer")
+	request, err := http.NewRequest("POST", "/teams/"+s.team.Name+"/serviceaccounts", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	accounts, err := auth.ListServiceAccounts(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(accounts, check.HasLen, 0)
+}
+
+func (s *S) TestCreateServiceAccountInvalidData(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("appdeployer", "app", "")
+	c.Assert(err, check.IsNil)
+	for _, body := range []string{"name=In valid&role=deployer", "name=ci&role=appdeployer"} {
+		request, err := http.NewRequest("POST", "/teams/"+s.team.Name+"/serviceaccounts", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Authorization", "b "+s.token.GetValue())
+		recorder := httptest.NewRecorder()
+		m := RunServer(true)
+		m.ServeHTTP(recorder, request)
+		c.Assert(recorder.Code, check.Equals, http.StatusBadRequest, check.Commentf("body: %q", body))
+	}
+}
+
+func (s *S) TestListServiceAccounts(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = auth.CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("GET", "/teams/"+s.team.Name+"/serviceaccounts", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var accounts []auth.ServiceAccount
+	err = json.NewDecoder(recorder.Body).Decode(&accounts)
+	c.Assert(err, check.IsNil)
+	c.Assert(accounts, check.HasLen, 1)
+	c.Assert(accounts[0].Name, check.Equals, "ci")
+	c.Assert(accounts[0].Token, check.Equals, "")
+}
+
+func (s *S) TestListServiceAccountsEmpty(c *check.C) {
+	request, err := http.NewRequest("GET", "/teams/"+s.team.Name+"/serviceaccounts", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+}
+
+func (s *S) TestRotateServiceAccountToken(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	account, err := auth.CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("POST", "/teams/"+s.team.Name+"/serviceaccounts/ci/token", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var rotated auth.ServiceAccount
+	err = json.NewDecoder(recorder.Body).Decode(&rotated)
+	c.Assert(err, check.IsNil)
+	c.Assert(rotated.Token, check.Not(check.Equals), "")
+	c.Assert(rotated.Token, check.Not(check.Equals), account.Token)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeTeam, Value: s.team.Name},
+		Owner:  s.token.GetUserName(),
+		Kind:   "team.service-account.update",
+		StartCustomData: []map[string]interface{}{
+			{"name": ":name", "value": s.team.Name},
+			{"name": ":account", "value": "ci"},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestRemoveServiceAccount(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = auth.CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/teams/"+s.team.Name+"/serviceaccounts/ci", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	accounts, err := auth.ListServiceAccounts(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(accounts, check.HasLen, 0)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestRemoveServiceAccountRequiresPermission(c *check.C) {
+	token := userWithPermission(c)
+	request, err := http.NewRequest("DELETE", "/teams/"+s.team.Name+"/serviceaccounts/ci", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "b "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
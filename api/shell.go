@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"strconv"
 	"sync"
 	"unicode"
@@ -68,6 +69,55 @@ func (l *cmdLogger) Close() error {
 	return l.base.Close()
 }
 
+// resizeSequence is the xterm control sequence clients send to report the
+// new size of their terminal: CSI 8 ; rows ; columns t.
+var resizeSequence = regexp.MustCompile(`\x1b\[8;(\d+);(\d+)t`)
+
+// resizeReader removes the resize sequences from the input of a remote
+// shell, sending the new sizes of the terminal of the client to resize.
+type resizeReader struct {
+	io.ReadWriteCloser
+	resize chan provision.TerminalSize
+}
+
+func (r *resizeReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.ReadWriteCloser.Read(p)
+		if n == 0 {
+			return n, err
+		}
+		matches := resizeSequence.FindAllSubmatch(p[:n], -1)
+		if len(matches) == 0 {
+			return n, err
+		}
+		for _, m := range matches {
+			height, _ := strconv.Atoi(string(m[1]))
+			width, _ := strconv.Atoi(string(m[2]))
+			r.sendResize(provision.TerminalSize{Width: width, Height: height})
+		}
+		n = copy(p, resizeSequence.ReplaceAll(p[:n], nil))
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// sendResize keeps only the latest size when the provisioner is late
+// applying the previous one.
+func (r *resizeReader) sendResize(size provision.TerminalSize) {
+	for {
+		select {
+		case r.resize <- size:
+			return
+		default:
+		}
+		select {
+		case <-r.resize:
+		default:
+		}
+	}
+}
+
 type optionalWriterCloser struct {
 	bytes.Buffer
 	disableWrite bool
@@ -164,12 +214,14 @@ func remoteShellHandler(ws *websocket.Conn) {
 		evt.Done(finalErr)
 	}()
 	term = terminal.NewTerminal(buf, "")
+	resize := make(chan provision.TerminalSize, 1)
 	opts := provision.ShellOptions{
-		Conn:   &cmdLogger{base: ws, term: term},
+		Conn:   &cmdLogger{base: &resizeReader{ReadWriteCloser: ws, resize: resize}, term: term},
 		Width:  width,
 		Height: height,
 		Unit:   unitID,
 		Term:   clientTerm,
+		Resize: resize,
 	}
 	err = a.Shell(opts)
 	if err != nil {
@@ -5,6 +5,7 @@
 package api
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net/http/httptest"
@@ -209,3 +210,31 @@ func (s *S) TestAppShellGenericError(c *check.C) {
 	})
 	c.Assert(err, check.IsNil)
 }
+
+type bufferConn struct {
+	*bytes.Buffer
+}
+
+func (c bufferConn) Close() error {
+	return nil
+}
+
+func (s *S) TestResizeReader(c *check.C) {
+	base := bufferConn{Buffer: bytes.NewBufferString("ls\x1b[8;40;120t -l")}
+	resize := make(chan provision.TerminalSize, 1)
+	r := &resizeReader{ReadWriteCloser: base, resize: resize}
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "ls -l")
+	c.Assert(<-resize, check.Equals, provision.TerminalSize{Width: 120, Height: 40})
+}
+
+func (s *S) TestResizeReaderKeepsLatestSize(c *check.C) {
+	base := bufferConn{Buffer: bytes.NewBufferString("\x1b[8;10;20t\x1b[8;30;40t")}
+	resize := make(chan provision.TerminalSize, 1)
+	r := &resizeReader{ReadWriteCloser: base, resize: resize}
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "")
+	c.Assert(<-resize, check.Equals, provision.TerminalSize{Width: 40, Height: 30})
+}
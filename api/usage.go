@@ -0,0 +1,86 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/tsuru/app/metrics"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/permission"
+)
+
+// title: usage report
+// path: /reports/usage
+// method: GET
+// produce: application/json, text/csv
+// responses:
+//   200: OK
+//   204: No content
+//   400: Invalid data
+//   401: Unauthorized
+func usageReport(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	contexts := permission.ContextsForPermission(t, permission.PermTeamReadUsage)
+	if len(contexts) == 0 {
+		return permission.ErrUnauthorized
+	}
+	month := time.Now().UTC()
+	if value := r.URL.Query().Get("month"); value != "" {
+		var err error
+		month, err = time.Parse("2006-01", value)
+		if err != nil {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "month must be in the format YYYY-MM"}
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: "format must be json or csv"}
+	}
+	report, err := metrics.UsageReport(month)
+	if err != nil {
+		return err
+	}
+	var allowed []metrics.TeamUsage
+	for _, u := range report {
+		if permission.Check(t, permission.PermTeamReadUsage, permission.Context(permission.CtxTeam, u.Team)) {
+			allowed = append(allowed, u)
+		}
+	}
+	if len(allowed) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	if format == "csv" {
+		return writeUsageCSV(w, month.Format("2006-01"), allowed)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(allowed)
+}
+
+func writeUsageCSV(w http.ResponseWriter, month string, report []metrics.TeamUsage) error {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=usage-"+month+".csv")
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"month", "team", "app", "unit_hours", "memory_gb_hours", "deploys"})
+	for _, team := range report {
+		for _, u := range team.Apps {
+			writer.Write([]string{
+				month,
+				team.Team,
+				u.App,
+				strconv.FormatFloat(u.UnitHours, 'f', 2, 64),
+				strconv.FormatFloat(u.MemoryHours, 'f', 2, 64),
+				strconv.Itoa(u.Deploys),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
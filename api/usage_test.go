@@ -0,0 +1,90 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) insertUsageSamples(c *check.C) {
+	for _, name := range []string{"myappx", "otherapp"} {
+		a := app.App{Name: name, Platform: "zend", TeamOwner: s.team.Name}
+		err := app.CreateApp(&a, s.user)
+		c.Assert(err, check.IsNil)
+		err = s.conn.AppMetrics().Insert(metrics.Sample{
+			App:    name,
+			Unit:   "unit1",
+			Date:   time.Date(2016, time.November, 2, 0, 0, 0, 0, time.UTC),
+			Memory: 1024 * 1024 * 1024,
+		})
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *S) TestUsageReport(c *check.C) {
+	s.insertUsageSamples(c)
+	request, err := http.NewRequest("GET", "/reports/usage?month=2016-11", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/json")
+	var report []metrics.TeamUsage
+	err = json.NewDecoder(recorder.Body).Decode(&report)
+	c.Assert(err, check.IsNil)
+	c.Assert(report, check.HasLen, 1)
+	c.Assert(report[0].Team, check.Equals, s.team.Name)
+	c.Assert(report[0].Apps, check.HasLen, 2)
+	c.Assert(report[0].UnitHours, check.Equals, 2/60.0)
+}
+
+func (s *S) TestUsageReportCSV(c *check.C) {
+	s.insertUsageSamples(c)
+	request, err := http.NewRequest("GET", "/reports/usage?month=2016-11&format=csv", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "text/csv")
+	c.Assert(recorder.Body.String(), check.Equals, "month,team,app,unit_hours,memory_gb_hours,deploys\n"+
+		"2016-11,"+s.team.Name+",myappx,0.02,0.02,0\n"+
+		"2016-11,"+s.team.Name+",otherapp,0.02,0.02,0\n")
+}
+
+func (s *S) TestUsageReportInvalidMonth(c *check.C) {
+	request, err := http.NewRequest("GET", "/reports/usage?month=november", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestUsageReportUnauthorized(c *check.C) {
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermAppReadMetric,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	request, err := http.NewRequest("GET", "/reports/usage", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+}
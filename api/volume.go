@@ -0,0 +1,274 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	tsuruIo "github.com/tsuru/tsuru/io"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/volume"
+)
+
+func volumeTarget(name string) event.Target {
+	return event.Target{Type: event.TargetTypeVolume, Value: name}
+}
+
+func contextsForVolume(v *volume.Volume) []permission.PermissionContext {
+	return []permission.PermissionContext{
+		permission.Context(permission.CtxVolume, v.Name),
+		permission.Context(permission.CtxTeam, v.TeamOwner),
+	}
+}
+
+func volumeError(err error) error {
+	switch err {
+	case volume.ErrVolumeNotFound, volume.ErrBindNotFound:
+		return &errors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+	case volume.ErrVolumeAlreadyExists, volume.ErrBindAlreadyExists, volume.ErrVolumeBound:
+		return &errors.HTTP{Code: http.StatusConflict, Message: err.Error()}
+	}
+	if e, ok := err.(*errors.ValidationError); ok {
+		return &errors.HTTP{Code: http.StatusBadRequest, Message: e.Error()}
+	}
+	return err
+}
+
+func getVolume(name string, t auth.Token, perm *permission.PermissionScheme) (*volume.Volume, error) {
+	v, err := volume.Get(name)
+	if err != nil {
+		return nil, volumeError(err)
+	}
+	if !permission.Check(t, perm, contextsForVolume(v)...) {
+		return nil, permission.ErrUnauthorized
+	}
+	return v, nil
+}
+
+// title: volume plan list
+// path: /volumeplans
+// method: GET
+// produce: application/json
+// responses:
+//   200: List volume plans
+//   204: No content
+//   401: Unauthorized
+func volumePlanList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	if len(permission.ContextsForPermission(t, permission.PermVolumeCreate)) == 0 {
+		return permission.ErrUnauthorized
+	}
+	plans, err := volume.ListPlans()
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(plans)
+}
+
+// title: volume list
+// path: /volumes
+// method: GET
+// produce: application/json
+// responses:
+//   200: List volumes
+//   204: No content
+//   401: Unauthorized
+func volumeList(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	volumes, err := volume.List(nil)
+	if err != nil {
+		return err
+	}
+	var allowed []volume.Volume
+	for _, v := range volumes {
+		if permission.Check(t, permission.PermVolumeRead, contextsForVolume(&v)...) {
+			allowed = append(allowed, v)
+		}
+	}
+	if len(allowed) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(allowed)
+}
+
+// title: volume info
+// path: /volumes/{volume}
+// method: GET
+// produce: application/json
+// responses:
+//   200: Show volume
+//   401: Unauthorized
+//   404: Volume not found
+func volumeInfo(w http.ResponseWriter, r *http.Request, t auth.Token) error {
+	v, err := getVolume(r.URL.Query().Get(":volume"), t, permission.PermVolumeRead)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(v)
+}
+
+// title: volume create
+// path: /volumes
+// method: POST
+// consume: application/x-www-form-urlencoded
+// responses:
+//   201: Volume created
+//   400: Invalid data
+//   401: Unauthorized
+//   409: Volume already exists
+func volumeCreate(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	v := volume.Volume{
+		Name:      r.FormValue("name"),
+		Plan:      r.FormValue("plan"),
+		TeamOwner: r.FormValue("team"),
+	}
+	if v.TeamOwner == "" {
+		v.TeamOwner, err = permission.TeamForPermission(t, permission.PermVolumeCreate)
+		if err == permission.ErrTooManyTeams {
+			return &errors.HTTP{Code: http.StatusBadRequest, Message: "You must provide a team to execute this action."}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	allowed := permission.Check(t, permission.PermVolumeCreate,
+		permission.Context(permission.CtxTeam, v.TeamOwner),
+	)
+	if !allowed {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     volumeTarget(v.Name),
+		Kind:       permission.PermVolumeCreate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermVolumeReadEvents, contextsForVolume(&v)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	err = v.Create()
+	if err != nil {
+		return volumeError(err)
+	}
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// title: volume delete
+// path: /volumes/{volume}
+// method: DELETE
+// responses:
+//   200: Volume removed
+//   401: Unauthorized
+//   404: Volume not found
+//   409: Volume is bound to apps
+func volumeDelete(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	v, err := getVolume(r.URL.Query().Get(":volume"), t, permission.PermVolumeDelete)
+	if err != nil {
+		return err
+	}
+	evt, err := event.New(&event.Opts{
+		Target:  volumeTarget(v.Name),
+		Kind:    permission.PermVolumeDelete,
+		Owner:   t,
+		Allowed: event.Allowed(permission.PermVolumeReadEvents, contextsForVolume(v)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	return volumeError(v.Delete())
+}
+
+// title: volume bind
+// path: /volumes/{volume}/bind
+// method: POST
+// consume: application/x-www-form-urlencoded
+// produce: application/x-json-stream
+// responses:
+//   200: Volume bound
+//   400: Invalid data
+//   401: Unauthorized
+//   404: Volume or app not found
+//   409: Volume already bound
+func volumeBind(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	return volumeBindOrUnbind(w, r, t, true)
+}
+
+// title: volume unbind
+// path: /volumes/{volume}/bind
+// method: DELETE
+// produce: application/x-json-stream
+// responses:
+//   200: Volume unbound
+//   401: Unauthorized
+//   404: Volume, app or bind not found
+func volumeUnbind(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	return volumeBindOrUnbind(w, r, t, false)
+}
+
+func volumeBindOrUnbind(w http.ResponseWriter, r *http.Request, t auth.Token, bind bool) (err error) {
+	r.ParseForm()
+	volumePerm, appPerm := permission.PermVolumeUpdateBind, permission.PermAppUpdateVolumeBind
+	if !bind {
+		volumePerm, appPerm = permission.PermVolumeUpdateUnbind, permission.PermAppUpdateVolumeUnbind
+	}
+	v, err := getVolume(r.URL.Query().Get(":volume"), t, volumePerm)
+	if err != nil {
+		return err
+	}
+	a, err := getAppFromContext(r.FormValue("app"), r)
+	if err != nil {
+		return err
+	}
+	if !permission.Check(t, appPerm, contextsForApp(&a)...) {
+		return permission.ErrUnauthorized
+	}
+	readOnly, _ := strconv.ParseBool(r.FormValue("readOnly"))
+	noRestart, _ := strconv.ParseBool(r.FormValue("noRestart"))
+	evt, err := event.New(&event.Opts{
+		Target:     volumeTarget(v.Name),
+		Kind:       volumePerm,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermVolumeReadEvents, contextsForVolume(v)...),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	if bind {
+		err = v.BindApp(a.Name, r.FormValue("mountPoint"), readOnly)
+	} else {
+		err = v.UnbindApp(a.Name, r.FormValue("mountPoint"))
+	}
+	if err != nil {
+		return volumeError(err)
+	}
+	if noRestart {
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 30*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	evt.SetLogWriter(writer)
+	return a.Restart("", evt)
+}
@@ -0,0 +1,193 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/volume"
+	"gopkg.in/check.v1"
+)
+
+func setVolumePlans() {
+	config.Set("volume-plans", map[interface{}]interface{}{
+		"local": map[interface{}]interface{}{
+			"type": "host",
+			"path": "/var/lib/tsuru/volumes",
+		},
+	})
+}
+
+func (s *S) TestVolumePlanList(c *check.C) {
+	setVolumePlans()
+	defer config.Unset("volume-plans")
+	request, err := http.NewRequest("GET", "/volumeplans", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var plans []volume.Plan
+	err = json.NewDecoder(recorder.Body).Decode(&plans)
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.DeepEquals, []volume.Plan{{Name: "local", Type: "host", Path: "/var/lib/tsuru/volumes"}})
+}
+
+func (s *S) TestVolumeCreate(c *check.C) {
+	setVolumePlans()
+	defer config.Unset("volume-plans")
+	body := strings.NewReader("name=data&plan=local&team=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/volumes", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusCreated)
+	v, err := volume.Get("data")
+	c.Assert(err, check.IsNil)
+	c.Assert(v.TeamOwner, check.Equals, s.team.Name)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeVolume, Value: "data"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "volume.create",
+		StartCustomData: []map[string]interface{}{
+			{"name": "name", "value": "data"},
+			{"name": "plan", "value": "local"},
+			{"name": "team", "value": s.team.Name},
+		},
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestVolumeCreateInvalidPlan(c *check.C) {
+	body := strings.NewReader("name=data&plan=unknown&team=" + s.team.Name)
+	request, err := http.NewRequest("POST", "/volumes", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusBadRequest)
+}
+
+func (s *S) TestVolumeListAndInfo(c *check.C) {
+	setVolumePlans()
+	defer config.Unset("volume-plans")
+	for _, v := range []volume.Volume{
+		{Name: "data", Plan: "local", TeamOwner: s.team.Name},
+		{Name: "other", Plan: "local", TeamOwner: "otherteam"},
+	} {
+		err := v.Create()
+		c.Assert(err, check.IsNil)
+	}
+	token := userWithPermission(c, permission.Permission{
+		Scheme:  permission.PermVolumeRead,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	request, err := http.NewRequest("GET", "/volumes", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	var volumes []volume.Volume
+	err = json.NewDecoder(recorder.Body).Decode(&volumes)
+	c.Assert(err, check.IsNil)
+	c.Assert(volumes, check.HasLen, 1)
+	c.Assert(volumes[0].Name, check.Equals, "data")
+	request, err = http.NewRequest("GET", "/volumes/other", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusForbidden)
+	request, err = http.NewRequest("GET", "/volumes/unknown", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
+func (s *S) TestVolumeBindAndUnbind(c *check.C) {
+	setVolumePlans()
+	defer config.Unset("volume-plans")
+	a := app.App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := app.CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	v := volume.Volume{Name: "data", Plan: "local", TeamOwner: s.team.Name}
+	err = v.Create()
+	c.Assert(err, check.IsNil)
+	body := strings.NewReader("app=myapp&mountPoint=/mnt/data&readOnly=true")
+	request, err := http.NewRequest("POST", "/volumes/data/bind", body)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+	stored, err := volume.Get("data")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Binds, check.DeepEquals, []volume.Bind{{App: "myapp", MountPoint: "/mnt/data", ReadOnly: true}})
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeVolume, Value: "data"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "volume.update.bind",
+		StartCustomData: []map[string]interface{}{
+			{"name": "app", "value": "myapp"},
+			{"name": "mountPoint", "value": "/mnt/data"},
+			{"name": "readOnly", "value": "true"},
+		},
+	}, eventtest.HasEvent)
+	request, err = http.NewRequest("DELETE", "/volumes/data/bind?app=myapp&mountPoint=/mnt/data&noRestart=true", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+	stored, err = volume.Get("data")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Binds, check.HasLen, 0)
+}
+
+func (s *S) TestVolumeDeleteBound(c *check.C) {
+	setVolumePlans()
+	defer config.Unset("volume-plans")
+	v := volume.Volume{Name: "data", Plan: "local", TeamOwner: s.team.Name}
+	err := v.Create()
+	c.Assert(err, check.IsNil)
+	err = v.BindApp("myapp", "/mnt/data", false)
+	c.Assert(err, check.IsNil)
+	request, err := http.NewRequest("DELETE", "/volumes/data", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	recorder := httptest.NewRecorder()
+	m := RunServer(true)
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusConflict)
+	err = v.UnbindApp("myapp", "/mnt/data")
+	c.Assert(err, check.IsNil)
+	recorder = httptest.NewRecorder()
+	m.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	_, err = volume.Get("data")
+	c.Assert(err, check.Equals, volume.ErrVolumeNotFound)
+}
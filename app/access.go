@@ -0,0 +1,78 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TeamAccess describes how a team is able to access an app: either as its
+// owner, through an explicit grant or inherited from one of its sub-teams
+// having access to the app.
+type TeamAccess struct {
+	Team          string    `json:"team"`
+	Owner         bool      `json:"owner"`
+	GrantedBy     string    `json:"grantedBy,omitempty"`
+	GrantedAt     time.Time `json:"grantedAt,omitempty"`
+	InheritedFrom string    `json:"inheritedFrom,omitempty"`
+}
+
+// Access returns the teams with access to the app. Information about who
+// granted the access and when is taken from the app.update.grant events.
+// Parent teams of the teams with access are also listed, because
+// permissions granted in the context of a team apply to its sub-teams.
+func (app *App) Access() ([]TeamAccess, error) {
+	var result []TeamAccess
+	seen := make(map[string]bool)
+	for _, team := range app.Teams {
+		seen[team] = true
+		access := TeamAccess{Team: team, Owner: team == app.TeamOwner}
+		evt, err := lastGrantEvent(app.Name, team)
+		if err != nil {
+			return nil, err
+		}
+		if evt != nil {
+			access.GrantedBy = evt.Owner.Name
+			access.GrantedAt = evt.StartTime
+		}
+		result = append(result, access)
+	}
+	for _, team := range app.Teams {
+		ancestors, err := auth.TeamAncestors(team)
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestor := range ancestors {
+			if seen[ancestor] {
+				continue
+			}
+			seen[ancestor] = true
+			result = append(result, TeamAccess{Team: ancestor, InheritedFrom: team})
+		}
+	}
+	return result, nil
+}
+
+func lastGrantEvent(appName, team string) (*event.Event, error) {
+	evts, err := event.List(&event.Filter{
+		Target:   event.Target{Type: event.TargetTypeApp, Value: appName},
+		KindName: permission.PermAppUpdateGrant.FullName(),
+		Raw: bson.M{
+			"error":           "",
+			"running":         false,
+			"startcustomdata": bson.M{"$elemMatch": bson.M{"name": ":team", "value": team}},
+		},
+		Limit: 1,
+	})
+	if err != nil || len(evts) == 0 {
+		return nil, err
+	}
+	return &evts[0], nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAccess(c *check.C) {
+	for _, name := range []string{"acid-rain", "zito", "parent"} {
+		err := s.conn.Teams().Insert(auth.Team{Name: name})
+		c.Assert(err, check.IsNil)
+	}
+	err := auth.SetTeamParent("zito", "parent")
+	c.Assert(err, check.IsNil)
+	a := App{Name: "app-name", Platform: "django", TeamOwner: "acid-rain", Teams: []string{"acid-rain", "zito"}}
+	err = s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:       permission.PermAppUpdateGrant,
+		RawOwner:   event.Owner{Type: event.OwnerTypeUser, Name: "admin@tsuru.io"},
+		CustomData: []map[string]interface{}{{"name": ":team", "value": "zito"}},
+		Allowed:    event.Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	access, err := a.Access()
+	c.Assert(err, check.IsNil)
+	c.Assert(access, check.HasLen, 3)
+	c.Assert(access[0], check.DeepEquals, TeamAccess{Team: "acid-rain", Owner: true})
+	c.Assert(access[1].Team, check.Equals, "zito")
+	c.Assert(access[1].GrantedBy, check.Equals, "admin@tsuru.io")
+	c.Assert(access[1].GrantedAt.IsZero(), check.Equals, false)
+	c.Assert(access[2], check.DeepEquals, TeamAccess{Team: "parent", InheritedFrom: "zito"})
+}
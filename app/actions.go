@@ -113,6 +113,18 @@ var insertApp = action.Action{
 	MinParams: 1,
 }
 
+// checkTeamQuota checks the app limit of the team owning the new app. It runs
+// after the app is inserted, so apps created concurrently count against each
+// other.
+var checkTeamQuota = action.Action{
+	Name: "check-team-quota",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		app := ctx.Params[0].(*App)
+		return nil, checkTeamAppQuota(app.TeamOwner)
+	},
+	MinParams: 1,
+}
+
 // exportEnvironmentsAction exports tsuru's default environment variables in a
 // new app. It requires a pointer to an App instance as the first parameter.
 var exportEnvironmentsAction = action.Action{
@@ -228,6 +240,10 @@ var addRouterBackend = action.Action{
 		} else {
 			err = r.AddBackend(app.GetName())
 		}
+		if err != nil {
+			routerName, _ := app.GetRouter()
+			router.ObserveFailure(routerName, "add-backend")
+		}
 		return app, err
 	},
 	Backward: func(ctx action.BWContext) {
@@ -352,6 +368,13 @@ var reserveUnitsToAdd = action.Action{
 		if err != nil {
 			return nil, err
 		}
+		err = checkTeamUnitsQuota(app, n)
+		if err != nil {
+			if releaseErr := releaseUnits(app, n); releaseErr != nil {
+				log.Errorf("Failed to release units after exceeding the team quota: %s", releaseErr)
+			}
+			return nil, err
+		}
 		return n, nil
 	},
 	Backward: func(ctx action.BWContext) {
@@ -534,6 +557,84 @@ var removeOldBackend = action.Action{
 	},
 }
 
+type changePoolPipelineResult struct {
+	oldPool    string
+	app        *App
+	unitsMoved bool
+}
+
+var saveAppPool = action.Action{
+	Name: "change-pool-save-app",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		app, ok := ctx.Params[0].(*App)
+		if !ok {
+			return nil, errors.New("first parameter must be an *App")
+		}
+		oldPool, ok := ctx.Params[1].(string)
+		if !ok {
+			return nil, errors.New("second parameter must be a string")
+		}
+		conn, err := db.Conn()
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$set": bson.M{"pool": app.Pool}})
+		if err != nil {
+			return nil, err
+		}
+		return &changePoolPipelineResult{oldPool: oldPool, app: app}, nil
+	},
+	Backward: func(ctx action.BWContext) {
+		result := ctx.FWResult.(*changePoolPipelineResult)
+		result.app.Pool = result.oldPool
+		conn, err := db.Conn()
+		if err != nil {
+			log.Errorf("BACKWARD save app pool - failed to get database connection: %s", err)
+			return
+		}
+		defer conn.Close()
+		err = conn.Apps().Update(bson.M{"name": result.app.Name}, bson.M{"$set": bson.M{"pool": result.oldPool}})
+		if err != nil {
+			log.Errorf("BACKWARD save app pool - failed to update app: %s", err)
+			return
+		}
+		if result.unitsMoved {
+			// Some units may already be running in the new pool, so they're
+			// replaced again to get all of them back to the old one.
+			w, _ := ctx.Params[2].(io.Writer)
+			err = result.app.Restart("", w)
+			if err != nil {
+				log.Errorf("BACKWARD save app pool - failed to move units back to pool %s: %s", result.oldPool, err)
+			}
+		}
+	},
+}
+
+// moveAppUnits restarts the app, replacing its units so they're scheduled on
+// nodes of the new pool and the router points to them. When it fails, the
+// backward of saveAppPool restarts the app again in the old pool.
+var moveAppUnits = action.Action{
+	Name: "change-pool-move-units",
+	Forward: func(ctx action.FWContext) (action.Result, error) {
+		w, ok := ctx.Params[2].(io.Writer)
+		if !ok {
+			return nil, errors.New("third parameter must be an io.Writer")
+		}
+		result, ok := ctx.Previous.(*changePoolPipelineResult)
+		if !ok {
+			return nil, errors.New("invalid previous result, should be changePoolPipelineResult")
+		}
+		result.unitsMoved = true
+		err := result.app.Restart("", w)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	},
+	MinParams: 3,
+}
+
 var validateNewCNames = action.Action{
 	Name: "validate-new-cnames",
 	Forward: func(ctx action.FWContext) (action.Result, error) {
@@ -546,7 +647,7 @@ var validateNewCNames = action.Action{
 		defer conn.Close()
 		for _, cname := range cnames {
 			if !cnameRegexp.MatchString(cname) {
-				return nil, errors.New("Invalid cname")
+				return nil, ErrInvalidCName
 			}
 			cs, err := conn.Apps().Find(bson.M{"cname": cname}).Count()
 			if err != nil {
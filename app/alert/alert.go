@@ -0,0 +1,311 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package alert implements alerting rules defined by teams, checked
+// periodically against the events and metrics of their apps. Teams are
+// notified through webhooks or email when an alert fires and when it is
+// resolved.
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/metrics"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const defaultInterval = time.Minute
+
+// restartKinds are the kinds of the events created when a unit stops
+// unexpectedly.
+var restartKinds = []string{"unit-crash", "unit-oom", "healer"}
+
+var errNoData = errors.New("no data available")
+
+type alertID struct {
+	Team string
+	Rule string
+	App  string
+}
+
+// Alert is a rule firing for an app. It's kept until the app stops matching
+// the condition of the rule.
+type Alert struct {
+	ID        alertID   `bson:"_id" json:"-"`
+	Team      string    `bson:"-" json:"team"`
+	Rule      string    `bson:"-" json:"rule"`
+	App       string    `bson:"-" json:"app"`
+	Condition string    `json:"condition"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	FiredAt   time.Time `json:"firedAt"`
+}
+
+func (a *Alert) fillID() {
+	a.Team = a.ID.Team
+	a.Rule = a.ID.Rule
+	a.App = a.ID.App
+}
+
+// ListAlerts returns the alerts currently firing for the rules of a team.
+func ListAlerts(team string) ([]Alert, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var alerts []Alert
+	err = conn.Alerts().Find(bson.M{"_id.team": team}).Sort("_id.rule", "_id.app").All(&alerts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range alerts {
+		alerts[i].fillID()
+	}
+	return alerts, nil
+}
+
+// Controller periodically checks the alerting rules.
+type Controller struct {
+	interval time.Duration
+	notifier notifier
+	quit     chan bool
+	wg       sync.WaitGroup
+}
+
+// Initialize starts the alerts controller if alerts:enabled is set. It
+// returns nil when alerting is disabled.
+func Initialize() (*Controller, error) {
+	enabled, _ := config.GetBool("alerts:enabled")
+	if !enabled {
+		return nil, nil
+	}
+	interval := defaultInterval
+	if seconds, _ := config.GetInt("alerts:run-interval"); seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	c := newController(interval, newNotifier())
+	c.start()
+	shutdown.Register(c)
+	return c, nil
+}
+
+func newController(interval time.Duration, n notifier) *Controller {
+	return &Controller{
+		interval: interval,
+		notifier: n,
+		quit:     make(chan bool),
+	}
+}
+
+func (c *Controller) start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			c.runOnce()
+			select {
+			case <-c.quit:
+				return
+			case <-time.After(c.interval):
+			}
+		}
+	}()
+}
+
+func (c *Controller) Shutdown() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+func (c *Controller) String() string {
+	return "alerts"
+}
+
+func (c *Controller) runOnce() {
+	rules, err := ListRules("")
+	if err != nil {
+		log.Errorf("[alerts] unable to list rules: %s", err)
+		return
+	}
+	for i := range rules {
+		if !rules[i].Enabled {
+			continue
+		}
+		err = c.check(&rules[i])
+		if err != nil {
+			log.Errorf("[alerts] unable to check rule %q of team %s: %s", rules[i].Name, rules[i].Team, err)
+		}
+	}
+}
+
+func (c *Controller) check(r *Rule) error {
+	apps, err := ruleApps(r)
+	if err != nil {
+		return err
+	}
+	for i := range apps {
+		value, firing, err := evaluate(r, &apps[i])
+		if err == errNoData {
+			continue
+		}
+		if err != nil {
+			log.Errorf("[alerts] unable to evaluate rule %q of team %s for app %s: %s", r.Name, r.Team, apps[i].Name, err)
+			continue
+		}
+		if firing {
+			err = c.fire(r, apps[i].Name, value)
+		} else {
+			err = c.resolve(r, apps[i].Name)
+		}
+		if err != nil {
+			log.Errorf("[alerts] unable to update alert %q of team %s for app %s: %s", r.Name, r.Team, apps[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// ruleApps returns the apps the team of the rule has access to, restricted
+// to the apps listed in the rule, if any.
+func ruleApps(r *Rule) ([]app.App, error) {
+	filter := &app.Filter{}
+	filter.ExtraIn("teams", r.Team)
+	apps, err := app.List(filter)
+	if err != nil || len(r.Apps) == 0 {
+		return apps, err
+	}
+	names := make(map[string]bool, len(r.Apps))
+	for _, name := range r.Apps {
+		names[name] = true
+	}
+	var result []app.App
+	for _, a := range apps {
+		if names[a.Name] {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+// evaluate returns the current value of the condition of the rule for the
+// app, and whether the rule should fire.
+func evaluate(r *Rule, a *app.App) (float64, bool, error) {
+	switch r.Condition {
+	case ConditionUnitRestarts:
+		events, err := event.List(&event.Filter{
+			Since: time.Now().Add(-r.Window),
+			Raw: bson.M{
+				"kind.name":        bson.M{"$in": restartKinds},
+				"allowed.contexts": bson.M{"$elemMatch": bson.M{"ctxtype": permission.CtxApp, "value": a.Name}},
+			},
+		})
+		if err != nil {
+			return 0, false, err
+		}
+		value := float64(len(events))
+		return value, value > r.Threshold, nil
+	case ConditionNoStartedUnits:
+		units, err := a.Units()
+		if err != nil {
+			return 0, false, err
+		}
+		var started int
+		for _, u := range units {
+			if u.Status == provision.StatusStarted {
+				started++
+			}
+		}
+		return float64(started), len(units) > 0 && started == 0, nil
+	case ConditionCPU, ConditionMemory:
+		samples, err := metrics.List(a.Name, time.Now().Add(-r.Window), time.Time{})
+		if err != nil {
+			return 0, false, err
+		}
+		if len(samples) == 0 {
+			return 0, false, errNoData
+		}
+		var sum float64
+		for _, s := range samples {
+			if r.Condition == ConditionCPU {
+				sum += s.CPU
+			} else {
+				sum += float64(s.Memory)
+			}
+		}
+		value := sum / float64(len(samples))
+		return value, value > r.Threshold, nil
+	}
+	return 0, false, errors.Errorf("unknown condition %q", r.Condition)
+}
+
+func message(r *Rule, appName string, value float64) string {
+	switch r.Condition {
+	case ConditionUnitRestarts:
+		return fmt.Sprintf("units of app %s stopped unexpectedly %g times in the last %s (threshold: %g)", appName, value, r.Window, r.Threshold)
+	case ConditionNoStartedUnits:
+		return fmt.Sprintf("app %s has no started units", appName)
+	}
+	return fmt.Sprintf("average %s usage of app %s in the last %s is %.2f (threshold: %g)", r.Condition, appName, r.Window, value, r.Threshold)
+}
+
+// fire stores the alert of the rule for the app and notifies the team,
+// unless the alert is already firing.
+func (c *Controller) fire(r *Rule, appName string, value float64) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	alert := Alert{
+		ID:        alertID{Team: r.Team, Rule: r.Name, App: appName},
+		Condition: r.Condition,
+		Value:     value,
+		Threshold: r.Threshold,
+		Message:   message(r, appName, value),
+		FiredAt:   time.Now().UTC(),
+	}
+	err = conn.Alerts().Insert(alert)
+	if mgo.IsDup(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	alert.fillID()
+	return c.notifier.notify(r, notification{Status: StatusFiring, Alert: alert, Date: alert.FiredAt})
+}
+
+// resolve removes the alert of the rule for the app, notifying the team if
+// it was firing.
+func (c *Controller) resolve(r *Rule, appName string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var alert Alert
+	_, err = conn.Alerts().FindId(alertID{Team: r.Team, Rule: r.Name, App: appName}).Apply(mgo.Change{Remove: true}, &alert)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	alert.fillID()
+	alert.Message = fmt.Sprintf("resolved: %s", alert.Message)
+	return c.notifier.notify(r, notification{Status: StatusResolved, Alert: alert, Date: time.Now().UTC()})
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/tsuru/tsuru/app/metrics"
+	"github.com/tsuru/tsuru/event"
+	tsuruNet "github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+)
+
+type fakeNotifier struct {
+	notifications []notification
+}
+
+func (f *fakeNotifier) notify(r *Rule, n notification) error {
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+func (s *S) TestNoStartedUnitsFiresAndResolves(c *check.C) {
+	a := s.newApp(c, "myapp", 1)
+	r := Rule{Team: "myteam", Name: "down", Condition: ConditionNoStartedUnits, Emails: []string{"a@example.com"}, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	unit := s.provisioner.GetUnits(a)[0]
+	err = s.provisioner.SetUnitStatus(unit, provision.StatusError)
+	c.Assert(err, check.IsNil)
+	notifier := &fakeNotifier{}
+	ctrl := newController(time.Minute, notifier)
+	ctrl.runOnce()
+	ctrl.runOnce()
+	c.Assert(notifier.notifications, check.HasLen, 1)
+	c.Assert(notifier.notifications[0].Status, check.Equals, StatusFiring)
+	c.Assert(notifier.notifications[0].Alert.App, check.Equals, "myapp")
+	c.Assert(notifier.notifications[0].Alert.Message, check.Equals, "app myapp has no started units")
+	alerts, err := ListAlerts("myteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(alerts, check.HasLen, 1)
+	c.Assert(alerts[0].Rule, check.Equals, "down")
+	err = s.provisioner.SetUnitStatus(unit, provision.StatusStarted)
+	c.Assert(err, check.IsNil)
+	ctrl.runOnce()
+	c.Assert(notifier.notifications, check.HasLen, 2)
+	c.Assert(notifier.notifications[1].Status, check.Equals, StatusResolved)
+	alerts, err = ListAlerts("myteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(alerts, check.HasLen, 0)
+}
+
+func (s *S) TestUnitRestarts(c *check.C) {
+	a := s.newApp(c, "myapp", 1)
+	for i := 0; i < 3; i++ {
+		evt, err := event.NewInternal(&event.Opts{
+			Target:       event.Target{Type: event.TargetTypeContainer, Value: "cont"},
+			InternalKind: "unit-crash",
+			Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxApp, a.Name)),
+		})
+		c.Assert(err, check.IsNil)
+		err = evt.Done(nil)
+		c.Assert(err, check.IsNil)
+	}
+	r := Rule{Team: "myteam", Name: "restarts", Condition: ConditionUnitRestarts, Threshold: 2}
+	value, firing, err := evaluate(&r, a)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 3.0)
+	c.Assert(firing, check.Equals, true)
+	r.Threshold = 3
+	_, firing, err = evaluate(&r, a)
+	c.Assert(err, check.IsNil)
+	c.Assert(firing, check.Equals, false)
+}
+
+func (s *S) TestCPUUsage(c *check.C) {
+	a := s.newApp(c, "myapp", 1)
+	now := time.Now().UTC()
+	err := s.conn.AppMetrics().Insert(
+		metrics.Sample{App: a.Name, Unit: "u1", Date: now.Add(-time.Minute), CPU: 60},
+		metrics.Sample{App: a.Name, Unit: "u2", Date: now.Add(-time.Minute), CPU: 80},
+		metrics.Sample{App: a.Name, Unit: "u1", Date: now.Add(-time.Hour), CPU: 0},
+	)
+	c.Assert(err, check.IsNil)
+	r := Rule{Team: "myteam", Name: "cpu", Condition: ConditionCPU, Threshold: 50, Window: 10 * time.Minute}
+	value, firing, err := evaluate(&r, a)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 70.0)
+	c.Assert(firing, check.Equals, true)
+	b := s.newApp(c, "otherapp", 1)
+	_, _, err = evaluate(&r, b)
+	c.Assert(err, check.Equals, errNoData)
+}
+
+func (s *S) TestRuleApps(c *check.C) {
+	s.newApp(c, "app1", 0)
+	s.newApp(c, "app2", 0)
+	r := Rule{Team: "myteam"}
+	apps, err := ruleApps(&r)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 2)
+	r.Apps = []string{"app2"}
+	apps, err = ruleApps(&r)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 1)
+	c.Assert(apps[0].Name, check.Equals, "app2")
+	r.Team = "otherteam"
+	apps, err = ruleApps(&r)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.HasLen, 0)
+}
+
+func (s *S) TestNotifyWebhook(c *check.C) {
+	var received notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Content-Type"), check.Equals, "application/json")
+		err := json.NewDecoder(r.Body).Decode(&received)
+		c.Check(err, check.IsNil)
+	}))
+	defer server.Close()
+	r := Rule{Team: "myteam", Name: "down", Webhook: server.URL}
+	n := notification{
+		Status: StatusFiring,
+		Alert:  Alert{Team: "myteam", Rule: "down", App: "myapp", Message: "app myapp has no started units"},
+		Date:   time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC),
+	}
+	err := newNotifier().notify(&r, n)
+	c.Assert(err, check.IsNil)
+	c.Assert(received, check.DeepEquals, n)
+}
+
+func (s *S) TestNotifyWebhookSigned(c *check.C) {
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		verifyErr = tsuruNet.VerifyRequest(r, body, "s3cr3t", time.Minute)
+	}))
+	defer server.Close()
+	r := Rule{Team: "myteam", Name: "down", Webhook: server.URL, WebhookSecret: "s3cr3t"}
+	err := newNotifier().notify(&r, notification{Status: StatusFiring})
+	c.Assert(err, check.IsNil)
+	c.Assert(verifyErr, check.IsNil)
+}
+
+func (s *S) TestNotifyWebhookError(c *check.C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	r := Rule{Team: "myteam", Name: "down", Webhook: server.URL}
+	err := newNotifier().notify(&r, notification{Status: StatusFiring})
+	c.Assert(err, check.ErrorMatches, `unable to notify alert: unexpected status code 500 from webhook .*`)
+}
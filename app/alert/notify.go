@@ -0,0 +1,117 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	tsuruNet "github.com/tsuru/tsuru/net"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// notification is sent to the webhook of a rule, as JSON, and to its emails
+// whenever an alert fires or is resolved.
+type notification struct {
+	Status string    `json:"status"`
+	Alert  Alert     `json:"alert"`
+	Date   time.Time `json:"date"`
+}
+
+type notifier interface {
+	notify(r *Rule, n notification) error
+}
+
+func newNotifier() notifier {
+	return &defaultNotifier{client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// defaultNotifier posts notifications to the webhook of the rule and sends
+// them by email using the smtp settings of tsuru. A failure in one of the
+// destinations doesn't prevent the others from being notified.
+type defaultNotifier struct {
+	client *http.Client
+}
+
+func (d *defaultNotifier) notify(r *Rule, n notification) error {
+	var errs []string
+	if r.Webhook != "" {
+		if err := d.postWebhook(r.Webhook, r.WebhookSecret, n); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, email := range r.Emails {
+		if err := sendEmail(email, n); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("unable to notify alert: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (d *defaultNotifier) postWebhook(url, secret string, n notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		tsuruNet.SignRequest(req, data, secret)
+	}
+	rsp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return errors.Errorf("unexpected status code %d from webhook %s", rsp.StatusCode, url)
+	}
+	return nil
+}
+
+func sendEmail(email string, n notification) error {
+	server, _ := config.GetString("smtp:server")
+	if server == "" {
+		return errors.New(`Setting "smtp:server" is not defined`)
+	}
+	if !strings.Contains(server, ":") {
+		server += ":25"
+	}
+	user, err := config.GetString("smtp:user")
+	if err != nil {
+		return errors.New(`Setting "smtp:user" is not defined`)
+	}
+	var auth smtp.Auth
+	password, _ := config.GetString("smtp:password")
+	if password != "" {
+		host, _, _ := net.SplitHostPort(server)
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Subject: [tsuru] [%s] %s: %s\r\n\r\n", n.Status, n.Alert.Rule, n.Alert.App)
+	fmt.Fprintf(&body, "%s\r\n\r\nTeam: %s\r\nRule: %s\r\nApp: %s\r\nDate: %s\r\n",
+		n.Alert.Message, n.Alert.Team, n.Alert.Rule, n.Alert.App, n.Date.Format(time.RFC1123))
+	return smtp.SendMail(server, auth, user, []string{email}, body.Bytes())
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// ConditionUnitRestarts fires when the units of an app crashed, were
+	// killed by OOM or were healed more than Threshold times during Window.
+	ConditionUnitRestarts = "unit-restarts"
+	// ConditionNoStartedUnits fires when an app has units but none of them
+	// is started.
+	ConditionNoStartedUnits = "no-started-units"
+	// ConditionCPU fires when the average CPU usage of the units of an app
+	// during Window, in percent, is greater than Threshold.
+	ConditionCPU = "cpu"
+	// ConditionMemory fires when the average memory usage of the units of
+	// an app during Window, in bytes, is greater than Threshold.
+	ConditionMemory = "memory"
+
+	defaultWindow = 10 * time.Minute
+)
+
+var ErrRuleNotFound = errors.New("alert rule not found")
+
+type ruleID struct {
+	Team string
+	Name string
+}
+
+// Rule defines a condition checked periodically against the apps of a team,
+// or against a subset of them, and who is notified when an app matches the
+// condition and when it stops matching it.
+type Rule struct {
+	ID        ruleID        `bson:"_id" json:"-"`
+	Team      string        `bson:"-" json:"team"`
+	Name      string        `bson:"-" json:"name"`
+	Condition string        `json:"condition"`
+	Threshold float64       `json:"threshold"`
+	Window    time.Duration `json:"window"`
+	Apps      []string      `json:"apps"`
+	Webhook   string        `json:"webhook"`
+	// WebhookSecret, when set, is used to sign the notifications posted to
+	// the webhook.
+	WebhookSecret string   `bson:"webhook_secret,omitempty" json:"-"`
+	Emails        []string `json:"emails"`
+	Enabled       bool     `json:"enabled"`
+}
+
+func (r *Rule) validate() error {
+	if r.Team == "" {
+		return &tsuruErrors.ValidationError{Message: "team is required"}
+	}
+	if r.Name == "" {
+		return &tsuruErrors.ValidationError{Message: "name is required"}
+	}
+	switch r.Condition {
+	case ConditionUnitRestarts, ConditionNoStartedUnits, ConditionCPU, ConditionMemory:
+	default:
+		return &tsuruErrors.ValidationError{Message: "condition must be one of unit-restarts, no-started-units, cpu or memory"}
+	}
+	if r.Threshold < 0 {
+		return &tsuruErrors.ValidationError{Message: "threshold must not be negative"}
+	}
+	if r.Window < 0 {
+		return &tsuruErrors.ValidationError{Message: "window must not be negative"}
+	}
+	if r.Window == 0 {
+		r.Window = defaultWindow
+	}
+	if r.Webhook == "" && len(r.Emails) == 0 {
+		return &tsuruErrors.ValidationError{Message: "either a webhook or an email is required"}
+	}
+	if r.Webhook != "" {
+		u, err := url.Parse(r.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &tsuruErrors.ValidationError{Message: "webhook must be an http or https URL"}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) fillID() {
+	r.Team = r.ID.Team
+	r.Name = r.ID.Name
+}
+
+// SetRule creates or replaces an alerting rule of a team.
+func SetRule(r *Rule) error {
+	err := r.validate()
+	if err != nil {
+		return err
+	}
+	r.ID = ruleID{Team: r.Team, Name: r.Name}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.AlertRules().UpsertId(r.ID, r)
+	return err
+}
+
+// GetRule returns an alerting rule of a team.
+func GetRule(team, name string) (*Rule, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var r Rule
+	err = conn.AlertRules().FindId(ruleID{Team: team, Name: name}).One(&r)
+	if err == mgo.ErrNotFound {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.fillID()
+	return &r, nil
+}
+
+// ListRules returns the alerting rules of the given team, or the rules of
+// every team if team is empty.
+func ListRules(team string) ([]Rule, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var query bson.M
+	if team != "" {
+		query = bson.M{"_id.team": team}
+	}
+	var rules []Rule
+	err = conn.AlertRules().Find(query).Sort("_id.team", "_id.name").All(&rules)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		rules[i].fillID()
+	}
+	return rules, nil
+}
+
+// RemoveRule removes an alerting rule of a team, along with its alerts.
+func RemoveRule(team, name string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.AlertRules().RemoveId(ruleID{Team: team, Name: name})
+	if err == mgo.ErrNotFound {
+		return ErrRuleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = conn.Alerts().RemoveAll(bson.M{"_id.team": team, "_id.rule": name})
+	return err
+}
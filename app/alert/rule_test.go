@@ -0,0 +1,87 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSetRule(c *check.C) {
+	r := Rule{Team: "myteam", Name: "restarts", Condition: ConditionUnitRestarts, Threshold: 5, Webhook: "http://hooks.example.com", Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	dbRule, err := GetRule("myteam", "restarts")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbRule.Team, check.Equals, "myteam")
+	c.Assert(dbRule.Name, check.Equals, "restarts")
+	c.Assert(dbRule.Window, check.Equals, defaultWindow)
+	r.Threshold = 10
+	err = SetRule(&r)
+	c.Assert(err, check.IsNil)
+	rules, err := ListRules("myteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 1)
+	c.Assert(rules[0].Threshold, check.Equals, 10.0)
+}
+
+func (s *S) TestSetRuleValidation(c *check.C) {
+	tests := []struct {
+		rule Rule
+		msg  string
+	}{
+		{Rule{Name: "r", Condition: ConditionCPU, Webhook: "http://a"}, "team is required"},
+		{Rule{Team: "t", Condition: ConditionCPU, Webhook: "http://a"}, "name is required"},
+		{Rule{Team: "t", Name: "r", Condition: "disk", Webhook: "http://a"}, "condition must be one of unit-restarts, no-started-units, cpu or memory"},
+		{Rule{Team: "t", Name: "r", Condition: ConditionCPU, Threshold: -1, Webhook: "http://a"}, "threshold must not be negative"},
+		{Rule{Team: "t", Name: "r", Condition: ConditionCPU, Window: -time.Second, Webhook: "http://a"}, "window must not be negative"},
+		{Rule{Team: "t", Name: "r", Condition: ConditionCPU}, "either a webhook or an email is required"},
+		{Rule{Team: "t", Name: "r", Condition: ConditionCPU, Webhook: "ftp://a"}, "webhook must be an http or https URL"},
+	}
+	for _, tt := range tests {
+		err := SetRule(&tt.rule)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+}
+
+func (s *S) TestListRules(c *check.C) {
+	for _, r := range []Rule{
+		{Team: "b", Name: "cpu", Condition: ConditionCPU, Threshold: 80, Emails: []string{"b@example.com"}},
+		{Team: "a", Name: "restarts", Condition: ConditionUnitRestarts, Threshold: 5, Emails: []string{"a@example.com"}},
+		{Team: "a", Name: "down", Condition: ConditionNoStartedUnits, Emails: []string{"a@example.com"}},
+	} {
+		err := SetRule(&r)
+		c.Assert(err, check.IsNil)
+	}
+	rules, err := ListRules("")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 3)
+	c.Assert(rules[0].Team+"/"+rules[0].Name, check.Equals, "a/down")
+	c.Assert(rules[1].Team+"/"+rules[1].Name, check.Equals, "a/restarts")
+	c.Assert(rules[2].Team+"/"+rules[2].Name, check.Equals, "b/cpu")
+	rules, err = ListRules("b")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 1)
+}
+
+func (s *S) TestRemoveRule(c *check.C) {
+	r := Rule{Team: "myteam", Name: "down", Condition: ConditionNoStartedUnits, Emails: []string{"a@example.com"}}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Alerts().Insert(Alert{ID: alertID{Team: "myteam", Rule: "down", App: "myapp"}})
+	c.Assert(err, check.IsNil)
+	err = RemoveRule("myteam", "down")
+	c.Assert(err, check.IsNil)
+	_, err = GetRule("myteam", "down")
+	c.Assert(err, check.Equals, ErrRuleNotFound)
+	alerts, err := ListAlerts("myteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(alerts, check.HasLen, 0)
+	err = RemoveRule("myteam", "down")
+	c.Assert(err, check.Equals, ErrRuleNotFound)
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/url"
 	"regexp"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/action"
 	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	tsuruErrors "github.com/tsuru/tsuru/errors"
@@ -37,12 +39,15 @@ import (
 var AuthScheme auth.Scheme
 
 var (
-	nameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+	nameRegexp        = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+	metadataKeyRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_/-]{0,61}[a-zA-Z0-9])?$`)
 
 	ErrAlreadyHaveAccess = errors.New("team already have access to this app")
 	ErrNoAccess          = errors.New("team does not have access to this app")
 	ErrCannotOrphanApp   = errors.New("cannot revoke access from this team, as it's the unique team with access to the app")
 	ErrDisabledPlatform  = errors.New("Disabled Platform, only admin users can create applications with the platform")
+	ErrCNameNotFound     = errors.New("cname not found in app")
+	ErrInvalidCName      = errors.New("Invalid cname")
 )
 
 const (
@@ -123,6 +128,12 @@ type App struct {
 	Pool           string
 	Description    string
 	RouterOpts     map[string]string
+	Labels         map[string]string
+	Annotations    map[string]string
+	Dependencies   []string
+	Tags           []string
+	LogRetention   *LogRetention
+	SLO            *SLO
 
 	quota.Quota
 	provisioner provision.Provisioner
@@ -180,16 +191,41 @@ func (app *App) MarshalJSON() ([]byte, error) {
 	result["teamowner"] = app.TeamOwner
 	result["plan"] = app.Plan
 	result["lock"] = app.Lock
+	result["quota"] = app.Quota
+	routerName, _ := app.GetRouter()
+	result["router"] = routerName
+	instances, err := app.serviceInstances()
+	if err != nil {
+		return nil, err
+	}
+	binds := make([]map[string]string, len(instances))
+	for i, instance := range instances {
+		binds[i] = map[string]string{
+			"service":  instance.ServiceName,
+			"instance": instance.Name,
+		}
+	}
+	result["serviceInstanceBinds"] = binds
+	result["labels"] = app.Labels
+	result["annotations"] = app.Annotations
+	result["dependencies"] = app.Dependencies
+	result["tags"] = app.Tags
+	if app.SLO != nil {
+		result["slo"] = sloStatus(app.SLO, units)
+	}
 	return json.Marshal(&result)
 }
 
 // Applog represents a log entry.
 type Applog struct {
-	Date    time.Time
-	Message string
-	Source  string
-	AppName string
-	Unit    string
+	Date          time.Time
+	Message       string
+	Source        string
+	AppName       string
+	Unit          string
+	Level         string    `json:",omitempty" bson:",omitempty"`
+	Timestamp     time.Time `bson:",omitempty"`
+	CorrelationID string    `json:",omitempty" bson:",omitempty"`
 }
 
 // AcquireApplicationLock acquires an application lock by setting the lock
@@ -245,6 +281,25 @@ func ReleaseApplicationLock(appName string) {
 	}
 }
 
+// ReleaseStaleApplicationLock releases the lock hold on an app only if it was
+// acquired more than maxAge ago, returning whether the lock was released.
+func ReleaseStaleApplicationLock(appName string, maxAge time.Duration) (bool, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{
+		"name":             appName,
+		"lock.locked":      true,
+		"lock.acquiredate": bson.M{"$lt": time.Now().In(time.UTC).Add(-maxAge)},
+	}, bson.M{"$set": bson.M{"lock": AppLock{}}})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 // GetByName queries the database to find an app identified by the given
 // name.
 func GetByName(name string) (*App, error) {
@@ -274,7 +329,7 @@ func CreateApp(app *App, user *auth.User) error {
 	if app.Plan.Name == "" {
 		plan, err = DefaultPlan()
 	} else {
-		plan, err = findPlanByName(app.Plan.Name)
+		plan, err = GetPlanByName(app.Plan.Name)
 	}
 	if err != nil {
 		return err
@@ -290,6 +345,7 @@ func CreateApp(app *App, user *auth.User) error {
 	}
 	app.Teams = []string{app.TeamOwner}
 	app.Owner = user.Email
+	app.Tags = processTags(app.Tags)
 	err = app.validate()
 	if err != nil {
 		return err
@@ -297,6 +353,7 @@ func CreateApp(app *App, user *auth.User) error {
 	actions := []*action.Action{
 		&reserveUserApp,
 		&insertApp,
+		&checkTeamQuota,
 		&exportEnvironmentsAction,
 		&createRepository,
 		&addRouterBackend,
@@ -308,10 +365,15 @@ func CreateApp(app *App, user *auth.User) error {
 	if err != nil {
 		return &AppCreationError{app: app.Name, Err: err}
 	}
+	err = app.createLogs()
+	if err != nil {
+		log.Errorf("unable to create logs collection for app %s: %s", app.Name, err)
+	}
 	return nil
 }
 
-// Update changes informations of the application.
+// Update changes informations of the application. Pool changes are done by
+// ChangePool, moving the units of the app to the new pool.
 func (app *App) Update(updateData App, w io.Writer) error {
 	description := updateData.Description
 	planName := updateData.Plan.Name
@@ -320,9 +382,33 @@ func (app *App) Update(updateData App, w io.Writer) error {
 	if description != "" {
 		app.Description = description
 	}
-	if poolName != "" {
-		app.Pool = poolName
-		_, err := app.getPoolForApp(app.Pool)
+	if updateData.Tags != nil {
+		app.Tags = processTags(updateData.Tags)
+	}
+	var team *auth.Team
+	if teamOwner != "" {
+		var err error
+		team, err = auth.GetTeam(teamOwner)
+		if err != nil {
+			return err
+		}
+		app.TeamOwner = team.Name
+	}
+	var plan *Plan
+	if planName != "" {
+		var err error
+		plan, err = GetPlanByName(planName)
+		if err != nil {
+			return err
+		}
+	}
+	if poolName != "" && poolName != app.Pool {
+		err := app.ChangePool(poolName, w)
+		if err != nil {
+			return err
+		}
+	} else if team != nil {
+		err := app.checkTeamOwnerPool()
 		if err != nil {
 			return err
 		}
@@ -332,12 +418,9 @@ func (app *App) Update(updateData App, w io.Writer) error {
 		return err
 	}
 	defer conn.Close()
-	if planName != "" {
-		plan, err := findPlanByName(planName)
-		if err != nil {
-			return err
-		}
+	if plan != nil {
 		var oldPlan Plan
+		oldLines := app.GetLogRetention().Lines
 		oldPlan, app.Plan = app.Plan, *plan
 		actions := []*action.Action{
 			&moveRouterUnits,
@@ -349,22 +432,63 @@ func (app *App) Update(updateData App, w io.Writer) error {
 		if err != nil {
 			return err
 		}
-	}
-	if teamOwner != "" {
-		team, err := auth.GetTeam(teamOwner)
-		if err != nil {
-			return err
-		}
-		app.TeamOwner = team.Name
-		err = app.validateTeamOwner()
-		if err != nil {
-			return err
+		if lines := app.GetLogRetention().Lines; lines != oldLines {
+			err = app.resizeLogs(lines)
+			if err != nil {
+				log.Errorf("unable to resize logs collection for app %s: %s", app.Name, err)
+			}
 		}
+	}
+	if team != nil {
 		app.Grant(team)
 	}
 	return conn.Apps().Update(bson.M{"name": app.Name}, app)
 }
 
+// ChangePool moves the app to another pool, saving it and then replacing its
+// units so they run on nodes of the new pool. The app stays in the old pool
+// if its units can't be moved.
+func (app *App) ChangePool(poolName string, w io.Writer) error {
+	if poolName == app.Pool {
+		return &tsuruErrors.ValidationError{Message: fmt.Sprintf("app %q is already in pool %q", app.Name, poolName)}
+	}
+	if _, err := app.getPoolForApp(poolName); err != nil {
+		return err
+	}
+	oldProv, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	pool, err := provision.GetPoolByName(poolName)
+	if err != nil {
+		return err
+	}
+	newProv, err := pool.GetProvisioner()
+	if err != nil {
+		return err
+	}
+	if oldProv.GetName() != newProv.GetName() {
+		return &tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("pool %q uses the provisioner %q, apps can't be moved between provisioners", poolName, newProv.GetName()),
+		}
+	}
+	units, err := app.Units()
+	if err != nil {
+		return err
+	}
+	oldPool := app.Pool
+	app.Pool = poolName
+	actions := []*action.Action{&saveAppPool}
+	if len(units) > 0 {
+		actions = append(actions, &moveAppUnits)
+	}
+	err = action.NewPipeline(actions...).Execute(app, oldPool, w)
+	if err != nil {
+		app.Pool = oldPool
+	}
+	return err
+}
+
 // unbind takes all service instances that are bound to the app, and unbind
 // them. This method is used by Destroy (before destroying the app, it unbinds
 // all service instances). Refer to Destroy docs for more details.
@@ -463,9 +587,37 @@ func Delete(app *App, w io.Writer) error {
 	if err != nil {
 		logErr("Unable to remove logs collection", err)
 	}
+	err = RemoveFreeze(appName)
+	if err != nil && err != ErrFreezeNotFound {
+		logErr("Unable to remove app freeze", err)
+	}
 	conn, err := db.Conn()
 	if err == nil {
 		defer conn.Close()
+		_, err = conn.LogDrains().RemoveAll(bson.M{"app": appName})
+		if err != nil {
+			logErr("Unable to remove log drains", err)
+		}
+		_, err = conn.ScopedTokens().RemoveAll(bson.M{"appname": appName})
+		if err != nil {
+			logErr("Unable to remove scoped tokens", err)
+		}
+		_, err = conn.AppJobs().RemoveAll(bson.M{"app": appName})
+		if err != nil {
+			logErr("Unable to remove app jobs", err)
+		}
+		_, err = conn.AppJobRuns().RemoveAll(bson.M{"app": appName})
+		if err != nil {
+			logErr("Unable to remove app job runs", err)
+		}
+		_, err = conn.AppAutoScaleRules().RemoveAll(bson.M{"_id.app": appName})
+		if err != nil {
+			logErr("Unable to remove autoscale rules", err)
+		}
+		_, err = conn.AppScheduledScales().RemoveAll(bson.M{"app": appName})
+		if err != nil {
+			logErr("Unable to remove scaling schedules", err)
+		}
 		err = conn.Apps().Remove(bson.M{"name": appName})
 	}
 	if err != nil {
@@ -581,6 +733,70 @@ func (app *App) RemoveUnits(n uint, process string, writer io.Writer) error {
 	)
 }
 
+// CountUnits returns the number of units of the given process. An empty
+// process counts every unit of the app.
+func (app *App) CountUnits(process string) (uint, error) {
+	units, err := app.Units()
+	if err != nil {
+		return 0, err
+	}
+	var n uint
+	for _, u := range units {
+		if process == "" || u.ProcessName == process {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ValidateUnitsProcess checks the process given to SetUnits. It must be one
+// of the processes of the app, and may only be empty when the app has a
+// single process, as the units of every process would be counted otherwise.
+func (app *App) ValidateUnitsProcess(process string) error {
+	if process != "" {
+		return app.ValidateProcess(process)
+	}
+	imageName, err := image.AppCurrentImageName(app.Name)
+	if err == image.ErrNoImagesAvailable {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := image.GetImageCustomData(imageName)
+	if err != nil {
+		return err
+	}
+	if len(data.Processes) > 1 {
+		return &tsuruErrors.ValidationError{Message: fmt.Sprintf("the process is required, app %q has multiple processes", app.Name)}
+	}
+	return nil
+}
+
+// SetUnits converges the number of units of the given process to total,
+// adding or removing the difference between the current number of units and
+// the desired one. The process is checked by ValidateUnitsProcess.
+func (app *App) SetUnits(total uint, process string, writer io.Writer) error {
+	err := app.ValidateUnitsProcess(process)
+	if err != nil {
+		return err
+	}
+	current, err := app.CountUnits(process)
+	if err != nil {
+		return err
+	}
+	switch {
+	case total > current:
+		return app.AddUnits(total-current, process, writer)
+	case total < current:
+		return app.RemoveUnits(current-total, process, writer)
+	}
+	if writer != nil {
+		fmt.Fprintf(writer, "App already has %d units, nothing to do.\n", total)
+	}
+	return nil
+}
+
 // SetUnitStatus changes the status of the given unit.
 func (app *App) SetUnitStatus(unitName string, status provision.Status) error {
 	units, err := app.Units()
@@ -781,6 +997,23 @@ func (app *App) SetPool() error {
 	return nil
 }
 
+// checkTeamOwnerPool ensures the team owner is still allowed to use the pool
+// of the app after changing the owner. The default pool is open to all teams.
+func (app *App) checkTeamOwnerPool() error {
+	if app.Pool == "" {
+		return nil
+	}
+	pool, err := provision.GetPoolByName(app.Pool)
+	if err != nil {
+		return err
+	}
+	if pool.Default {
+		return nil
+	}
+	_, err = app.getPoolForApp(app.Pool)
+	return err
+}
+
 func (app *App) getPoolForApp(poolName string) (string, error) {
 	var pools []provision.Pool
 	var err error
@@ -811,7 +1044,7 @@ func (app *App) getPoolForApp(poolName string) (string, error) {
 		}
 	}
 	if !pools[0].Public && !poolTeam {
-		return "", errors.Errorf("App team owner %q has no access to pool %q", app.TeamOwner, poolName)
+		return "", &PoolAccessError{Team: app.TeamOwner, Pool: pools[0].Name}
 	}
 	return pools[0].Name, nil
 }
@@ -841,6 +1074,112 @@ func (app *App) getEnv(name string) (bind.EnvVar, error) {
 	return env, err
 }
 
+// ValidateMetadataKey returns a validation error if the key can't be used as
+// a label or annotation. Keys are stored as fields of the app document, so
+// dots and a leading $ are always refused.
+func ValidateMetadataKey(key string) error {
+	if strings.Contains(key, ".") || strings.HasPrefix(key, "$") {
+		msg := fmt.Sprintf("Invalid metadata key %q, keys must not contain dots or start with $.", key)
+		return &tsuruErrors.ValidationError{Message: msg}
+	}
+	if !metadataKeyRegexp.MatchString(key) {
+		msg := fmt.Sprintf("Invalid metadata key %q, keys should have at most 63 "+
+			"characters, containing only letters, numbers, dashes, underscores "+
+			"or slashes, starting and ending with a letter or number.", key)
+		return &tsuruErrors.ValidationError{Message: msg}
+	}
+	return nil
+}
+
+func validateMetadataKeys(metadata map[string]string) error {
+	for key := range metadata {
+		if err := ValidateMetadataKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMetadata adds or replaces the given labels and annotations of the app.
+func (app *App) SetMetadata(labels, annotations map[string]string) error {
+	if err := validateMetadataKeys(labels); err != nil {
+		return err
+	}
+	if err := validateMetadataKeys(annotations); err != nil {
+		return err
+	}
+	update := bson.M{}
+	if app.Labels == nil {
+		app.Labels = make(map[string]string)
+	}
+	for key, value := range labels {
+		app.Labels[key] = value
+		update["labels."+key] = value
+	}
+	if app.Annotations == nil {
+		app.Annotations = make(map[string]string)
+	}
+	for key, value := range annotations {
+		app.Annotations[key] = value
+		update["annotations."+key] = value
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$set": update})
+}
+
+// UnsetMetadata removes the labels and annotations with the given keys from
+// the app.
+func (app *App) UnsetMetadata(labels, annotations []string) error {
+	for _, key := range append(append([]string{}, labels...), annotations...) {
+		if err := ValidateMetadataKey(key); err != nil {
+			return err
+		}
+	}
+	update := bson.M{}
+	for _, key := range labels {
+		delete(app.Labels, key)
+		update["labels."+key] = ""
+	}
+	for _, key := range annotations {
+		delete(app.Annotations, key)
+		update["annotations."+key] = ""
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$unset": update})
+}
+
+// processTags trims the tags, dropping empty and duplicated ones.
+func processTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	processed := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		processed = append(processed, tag)
+	}
+	return processed
+}
+
 // validate checks app name format
 func (app *App) validate() error {
 	if app.Name == InternalAppName || !nameRegexp.MatchString(app.Name) {
@@ -865,9 +1204,10 @@ func (app *App) InstanceEnv(name string) map[string]bind.EnvVar {
 }
 
 // Run executes the command in app units, sourcing apprc before running the
-// command.
+// command. Isolated commands run in a new container from the app image, so
+// they don't require the app to have available units.
 func (app *App) Run(cmd string, w io.Writer, args provision.RunArgs) error {
-	if !app.available() {
+	if !args.Isolated && !app.available() {
 		return errors.New("App must be available to run commands")
 	}
 	app.Log(fmt.Sprintf("running '%s'", cmd), "tsuru", "api")
@@ -896,19 +1236,91 @@ func (app *App) run(cmd string, w io.Writer, args provision.RunArgs) error {
 	if args.Isolated {
 		return execProv.ExecuteCommandIsolated(w, w, app, cmd)
 	}
+	if len(args.Units) > 0 || args.UnitsPercentage > 0 {
+		unitProv, ok := prov.(provision.UnitExecutableProvisioner)
+		if !ok {
+			return provision.ProvisionerNotSupported{Prov: prov, Action: "running commands in some units"}
+		}
+		units := args.Units
+		if len(units) == 0 {
+			units, err = app.sampleUnits(args.UnitsPercentage)
+			if err != nil {
+				return err
+			}
+		}
+		return unitProv.ExecuteCommandInUnits(w, w, app, units, cmd)
+	}
 	if args.Once {
 		return execProv.ExecuteCommandOnce(w, w, app, cmd)
 	}
 	return execProv.ExecuteCommand(w, w, app, cmd)
 }
 
-// Restart runs the restart hook for the app, writing its output to w.
+// sampleUnits returns the ids of a random sample with the given percentage of
+// the available units of the app, rounded up.
+func (app *App) sampleUnits(percentage int) ([]string, error) {
+	if percentage < 1 || percentage > 100 {
+		return nil, &tsuruErrors.ValidationError{Message: "units percentage must be between 1 and 100"}
+	}
+	units, err := app.Units()
+	if err != nil {
+		return nil, err
+	}
+	var available []string
+	for i := range units {
+		if units[i].Available() {
+			available = append(available, units[i].ID)
+		}
+	}
+	if len(available) == 0 {
+		return nil, provision.ErrEmptyApp
+	}
+	count := (len(available)*percentage + 99) / 100
+	sample := make([]string, count)
+	for i, j := range rand.Perm(len(available))[:count] {
+		sample[i] = available[j]
+	}
+	return sample, nil
+}
+
+// ValidateProcess returns an error when process is set but isn't one of the
+// processes of the image currently deployed in the app.
+func (app *App) ValidateProcess(process string) error {
+	if process == "" {
+		return nil
+	}
+	imageName, err := image.AppCurrentImageName(app.Name)
+	if err == image.ErrNoImagesAvailable {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := image.GetImageCustomData(imageName)
+	if err != nil {
+		return err
+	}
+	if len(data.Processes) == 0 {
+		return nil
+	}
+	if _, ok := data.Processes[process]; !ok {
+		return &tsuruErrors.ValidationError{Message: fmt.Sprintf("process %q not found in app %q", process, app.Name)}
+	}
+	return nil
+}
+
+// Restart runs the restart hook for the app, writing its output to w. When
+// process is not empty, only the units of that process are restarted.
 func (app *App) Restart(process string, w io.Writer) error {
+	err := app.ValidateProcess(process)
+	if err != nil {
+		return err
+	}
 	msg := fmt.Sprintf("---- Restarting process %q ----\n", process)
 	if process == "" {
 		msg = fmt.Sprintf("---- Restarting the app %q ----\n", app.Name)
 	}
-	err := log.Write(w, []byte(msg))
+	err = log.Write(w, []byte(msg))
 	if err != nil {
 		log.Errorf("[restart] error on write app log for the app %s - %s", app.Name, err)
 		return err
@@ -926,7 +1338,52 @@ func (app *App) Restart(process string, w io.Writer) error {
 	return nil
 }
 
+// RestartUnit restarts a single unit of the app, identified by its id or
+// name, leaving the other units untouched.
+func (app *App) RestartUnit(unit string, w io.Writer) error {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	unitProv, ok := prov.(provision.UnitRestarterProvisioner)
+	if !ok {
+		return provision.ProvisionerNotSupported{Prov: prov, Action: "restarting units"}
+	}
+	err = unitProv.RestartUnit(app, unit, w)
+	if err != nil {
+		log.Errorf("[restart unit] error on restart unit %s of the app %s - %s", unit, app.Name, err)
+		return err
+	}
+	rebuild.RoutesRebuildOrEnqueue(app.Name)
+	return nil
+}
+
+// ReplaceUnit replaces a single unit of the app, identified by its id or
+// name, with a new unit of the same process.
+func (app *App) ReplaceUnit(unit string, w io.Writer) error {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	unitProv, ok := prov.(provision.UnitRestarterProvisioner)
+	if !ok {
+		return provision.ProvisionerNotSupported{Prov: prov, Action: "replacing units"}
+	}
+	err = unitProv.ReplaceUnit(app, unit, w)
+	if err != nil {
+		log.Errorf("[replace unit] error on replace unit %s of the app %s - %s", unit, app.Name, err)
+		return err
+	}
+	rebuild.RoutesRebuildOrEnqueue(app.Name)
+	return nil
+}
+
+// Stop stops the app units, or only the units of process when it's not
+// empty.
 func (app *App) Stop(w io.Writer, process string) error {
+	if err := app.ValidateProcess(process); err != nil {
+		return err
+	}
 	msg := fmt.Sprintf("\n ---> Stopping the process %q\n", process)
 	if process == "" {
 		msg = fmt.Sprintf("\n ---> Stopping the app %q\n", app.Name)
@@ -1090,7 +1547,7 @@ func (app *App) GetDeploys() uint {
 
 // Envs returns a map representing the apps environment variables.
 func (app *App) Envs() map[string]bind.EnvVar {
-	return app.Env
+	return app.decryptedEnvs()
 }
 
 // SetEnvs saves a list of environment variables in the app. The publicOnly
@@ -1124,6 +1581,7 @@ func (app *App) setEnvsToApp(setEnvs bind.SetEnvApp, w io.Writer) error {
 	if w != nil {
 		fmt.Fprintf(w, "---- Setting %d new environment variables ----\n", len(setEnvs.Envs))
 	}
+	var changes []EnvChange
 	for _, env := range setEnvs.Envs {
 		set := true
 		if setEnvs.PublicOnly {
@@ -1133,6 +1591,25 @@ func (app *App) setEnvsToApp(setEnvs bind.SetEnvApp, w io.Writer) error {
 			}
 		}
 		if set {
+			old, existed := app.Env[env.Name]
+			if existed {
+				if plain, err := plainEnv(old); err == nil {
+					old = plain
+				}
+			}
+			if env.Secret {
+				env.Public = false
+			}
+			if change, ok := envSetChange(old, existed, env, setEnvs.Owner); ok {
+				changes = append(changes, change)
+			}
+			if env.Secret {
+				encrypted, err := encryptEnvValue(env.Value)
+				if err != nil {
+					return err
+				}
+				env.Value = encrypted
+			}
 			app.setEnv(env)
 		}
 	}
@@ -1145,6 +1622,7 @@ func (app *App) setEnvsToApp(setEnvs bind.SetEnvApp, w io.Writer) error {
 	if err != nil {
 		return err
 	}
+	app.recordEnvChanges(changes)
 	if !setEnvs.ShouldRestart {
 		return nil
 	}
@@ -1180,6 +1658,7 @@ func (app *App) unsetEnvsToApp(unsetEnvs bind.UnsetEnvApp, w io.Writer) error {
 	if w != nil {
 		fmt.Fprintf(w, "---- Unsetting %d environment variables ----\n", len(unsetEnvs.VariableNames))
 	}
+	var changes []EnvChange
 	for _, name := range unsetEnvs.VariableNames {
 		var unset bool
 		e, err := app.getEnv(name)
@@ -1187,6 +1666,19 @@ func (app *App) unsetEnvsToApp(unsetEnvs bind.UnsetEnvApp, w io.Writer) error {
 			unset = true
 		}
 		if unset {
+			if err == nil {
+				if plain, plainErr := plainEnv(e); plainErr == nil {
+					e = plain
+				}
+				changes = append(changes, EnvChange{
+					Name:         name,
+					Action:       EnvChangeUnset,
+					Public:       e.Public,
+					InstanceName: e.InstanceName,
+					OldHash:      hashEnvValue(e),
+					Owner:        unsetEnvs.Owner,
+				})
+			}
 			delete(app.Env, name)
 		}
 	}
@@ -1199,6 +1691,7 @@ func (app *App) unsetEnvsToApp(unsetEnvs bind.UnsetEnvApp, w io.Writer) error {
 	if err != nil {
 		return err
 	}
+	app.recordEnvChanges(changes)
 	if !unsetEnvs.ShouldRestart {
 		return nil
 	}
@@ -1237,6 +1730,36 @@ func (app *App) RemoveCName(cnames ...string) error {
 	return err
 }
 
+// MoveCName moves the cname from the app to dst. The cname is added back to
+// the app when it can't be added to dst.
+func (app *App) MoveCName(cname string, dst *App) error {
+	if app.Name == dst.Name {
+		return &tsuruErrors.ValidationError{Message: "cname source and destination apps must be different"}
+	}
+	found := false
+	for _, c := range app.CName {
+		if c == cname {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrCNameNotFound
+	}
+	err := app.RemoveCName(cname)
+	if err != nil {
+		return err
+	}
+	err = dst.AddCName(cname)
+	if err != nil {
+		if rollbackErr := app.AddCName(cname); rollbackErr != nil {
+			log.Errorf("[move cname] unable to add cname %s back to app %s: %s", cname, app.Name, rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
 func (app *App) parsedTsuruServices() map[string][]bind.ServiceInstance {
 	var tsuruServices map[string][]bind.ServiceInstance
 	if servicesEnv, ok := app.Env[TsuruServicesEnvVar]; ok {
@@ -1256,9 +1779,6 @@ func (app *App) AddInstance(instanceApp bind.InstanceApp, writer io.Writer) erro
 	if err != nil {
 		return err
 	}
-	if len(instanceApp.Instance.Envs) == 0 {
-		return nil
-	}
 	envVars := make([]bind.EnvVar, 0, len(instanceApp.Instance.Envs)+1)
 	for k, v := range instanceApp.Instance.Envs {
 		envVars = append(envVars, bind.EnvVar{
@@ -1338,7 +1858,7 @@ func (app *App) RemoveInstance(instanceApp bind.InstanceApp, writer io.Writer) e
 	for _, varName := range toUnsetEnvs {
 		instanceName, envValue := findServiceEnv(tsuruServices, varName)
 		if envValue == "" || instanceName == "" {
-			break
+			continue
 		}
 		envsToSet = append(envsToSet, bind.EnvVar{
 			Name:         varName,
@@ -1384,17 +1904,25 @@ func (app *App) RemoveInstance(instanceApp bind.InstanceApp, writer io.Writer) e
 // Log adds a log message to the app. Specifying a good source is good so the
 // user can filter where the message come from.
 func (app *App) Log(message, source, unit string) error {
+	return app.LogWithCorrelationID(message, source, unit, "")
+}
+
+// LogWithCorrelationID works like Log, stamping the log lines with the
+// correlation ID of the event that produced them.
+func (app *App) LogWithCorrelationID(message, source, unit, correlationID string) error {
 	messages := strings.Split(message, "\n")
 	logs := make([]interface{}, 0, len(messages))
 	for _, msg := range messages {
 		if msg != "" {
 			l := Applog{
-				Date:    time.Now().In(time.UTC),
-				Message: msg,
-				Source:  source,
-				AppName: app.Name,
-				Unit:    unit,
+				Date:          time.Now().In(time.UTC),
+				Message:       msg,
+				Source:        source,
+				AppName:       app.Name,
+				Unit:          unit,
+				CorrelationID: correlationID,
 			}
+			parseStructuredLog(&l)
 			logs = append(logs, l)
 		}
 	}
@@ -1413,6 +1941,20 @@ func (app *App) Log(message, source, unit string) error {
 // LastLogs returns a list of the last `lines` log of the app, matching the
 // fields in the log instance received as an example.
 func (app *App) LastLogs(lines int, filterLog Applog) ([]Applog, error) {
+	return app.LastLogsBetween(lines, filterLog, time.Time{}, time.Time{})
+}
+
+// LastLogsBetween returns the last lines of logs of the app written in the
+// given interval. A zero since or until leaves that end of the interval open.
+// Lines older than the max age in the log retention of the app are skipped.
+func (app *App) LastLogsBetween(lines int, filterLog Applog, since, until time.Time) ([]Applog, error) {
+	return app.SearchLogs(lines, filterLog, nil, since, until)
+}
+
+// SearchLogs works like LastLogsBetween, also skipping lines whose message
+// doesn't match the given pattern, built with LogMessagePattern. The search
+// is done by the database, so only matching lines are returned.
+func (app *App) SearchLogs(lines int, filterLog Applog, message *regexp.Regexp, since, until time.Time) ([]Applog, error) {
 	prov, err := app.getProvisioner()
 	if err != nil {
 		return nil, err
@@ -1442,7 +1984,35 @@ func (app *App) LastLogs(lines int, filterLog Applog) ([]Applog, error) {
 	if filterLog.Unit != "" {
 		q["unit"] = filterLog.Unit
 	}
-	err = conn.Logs(app.Name).Find(q).Sort("-$natural").Limit(lines).All(&logs)
+	if filterLog.Level != "" {
+		q["level"] = normalizeLogLevel(filterLog.Level)
+	}
+	if filterLog.CorrelationID != "" {
+		q["correlationid"] = filterLog.CorrelationID
+	}
+	if message != nil {
+		q["message"] = bson.RegEx{Pattern: message.String()}
+	}
+	if maxAge := app.GetLogRetention().MaxAge; maxAge > 0 {
+		oldest := time.Now().Add(-maxAge)
+		if since.Before(oldest) {
+			since = oldest
+		}
+	}
+	if !since.IsZero() || !until.IsZero() {
+		date := bson.M{}
+		if !since.IsZero() {
+			date["$gte"] = since
+		}
+		if !until.IsZero() {
+			date["$lte"] = until
+		}
+		q["date"] = date
+	}
+	err = conn.Logs(app.Name).Find(q).Sort("-$natural").Limit(lines).SetMaxTime(logSearchTimeout).All(&logs)
+	if qErr, ok := err.(*mgo.QueryError); ok && qErr.Code == mongoExceededTimeLimit {
+		return nil, &tsuruErrors.ValidationError{Message: "log search took too long, try a more specific pattern or a shorter interval"}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1458,11 +2028,15 @@ type Filter struct {
 	NameMatches string
 	Platform    string
 	TeamOwner   string
+	TeamOwners  []string
 	UserOwner   string
 	Pool        string
 	Pools       []string
 	Statuses    []string
 	Locked      bool
+	Labels      map[string]string
+	Tags        []string
+	Description string
 	Extra       map[string][]string
 }
 
@@ -1496,6 +2070,9 @@ func (f *Filter) Query() bson.M {
 	if f.TeamOwner != "" {
 		query["teamowner"] = f.TeamOwner
 	}
+	if len(f.TeamOwners) > 0 {
+		query["teamowner"] = bson.M{"$in": f.TeamOwners}
+	}
 	if f.Platform != "" {
 		query["framework"] = f.Platform
 	}
@@ -1511,6 +2088,15 @@ func (f *Filter) Query() bson.M {
 	if len(f.Pools) > 0 {
 		query["pool"] = bson.M{"$in": f.Pools}
 	}
+	for key, value := range f.Labels {
+		query["labels."+key] = value
+	}
+	if len(f.Tags) > 0 {
+		query["tags"] = bson.M{"$all": f.Tags}
+	}
+	if f.Description != "" {
+		query["description"] = bson.M{"$regex": regexp.QuoteMeta(f.Description), "$options": "i"}
+	}
 	return query
 }
 
@@ -1600,6 +2186,9 @@ func Swap(app1, app2 *App, cnameOnly bool) error {
 // Start starts the app calling the provisioner.Start method and
 // changing the units state to StatusStarted.
 func (app *App) Start(w io.Writer, process string) error {
+	if err := app.ValidateProcess(process); err != nil {
+		return err
+	}
 	msg := fmt.Sprintf("\n ---> Starting the process %q\n", process)
 	if process == "" {
 		msg = fmt.Sprintf("\n ---> Starting the app %q\n", app.Name)
@@ -1667,6 +2256,19 @@ func (app *App) MetricEnvs() (map[string]string, error) {
 	}
 }
 
+// UnitsMetrics samples the resources currently used by the units of the app.
+func (app *App) UnitsMetrics() ([]provision.UnitMetrics, error) {
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return nil, err
+	}
+	metricsProv, ok := prov.(provision.UnitMetricsProvisioner)
+	if !ok {
+		return nil, provision.ProvisionerNotSupported{Prov: prov, Action: "unit metrics"}
+	}
+	return metricsProv.UnitsMetrics(app)
+}
+
 func (app *App) Shell(opts provision.ShellOptions) error {
 	opts.App = app
 	prov, err := app.getProvisioner()
@@ -19,6 +19,7 @@ import (
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
@@ -132,6 +133,55 @@ func (s *S) TestDeleteWithoutUnits(c *check.C) {
 	c.Assert(err.Error(), check.Equals, "repository not found")
 }
 
+func (s *S) TestDeleteRemovesScopedTokens(c *check.C) {
+	a := App{Name: "x5", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	_, err = auth.CreateScopedToken(s.user, a.Name, []*permission.PermissionScheme{permission.PermAppDeploy}, 0)
+	c.Assert(err, check.IsNil)
+	err = Delete(&a, nil)
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.ScopedTokens().Find(bson.M{"appname": a.Name}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestDeleteRemovesJobs(c *check.C) {
+	a := App{Name: "x6", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.conn.AppJobs().Insert(bson.M{"_id": bson.NewObjectId(), "app": a.Name, "name": "report"})
+	c.Assert(err, check.IsNil)
+	err = s.conn.AppJobRuns().Insert(bson.M{"_id": bson.NewObjectId(), "app": a.Name, "job": "report"})
+	c.Assert(err, check.IsNil)
+	err = Delete(&a, nil)
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.AppJobs().Find(bson.M{"app": a.Name}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+	n, err = s.conn.AppJobRuns().Find(bson.M{"app": a.Name}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestDeleteRemovesAutoScale(c *check.C) {
+	a := App{Name: "x7", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.conn.AppAutoScaleRules().Insert(bson.M{"_id": bson.M{"app": a.Name, "process": "web"}})
+	c.Assert(err, check.IsNil)
+	err = s.conn.AppScheduledScales().Insert(bson.M{"_id": bson.NewObjectId(), "app": a.Name, "process": "web"})
+	c.Assert(err, check.IsNil)
+	err = Delete(&a, nil)
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.AppAutoScaleRules().Find(bson.M{"_id.app": a.Name}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+	n, err = s.conn.AppScheduledScales().Find(bson.M{"app": a.Name}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
 func (s *S) TestDeleteSwappedApp(c *check.C) {
 	a := App{
 		Name:      "ritual",
@@ -627,6 +677,52 @@ func (s *S) TestRemoveUnitsWithQuota(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *S) TestSetUnits(c *check.C) {
+	app := App{Name: "chemistry", Platform: "python", Quota: quota.Unlimited, TeamOwner: s.team.Name}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	err = app.AddUnits(1, "worker", nil)
+	c.Assert(err, check.IsNil)
+	err = app.SetUnits(3, "web", nil)
+	c.Assert(err, check.IsNil)
+	n, err := app.CountUnits("web")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(3))
+	err = app.SetUnits(1, "web", nil)
+	c.Assert(err, check.IsNil)
+	n, err = app.CountUnits("web")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(1))
+	buf := bytes.NewBuffer(nil)
+	err = app.SetUnits(1, "worker", buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "App already has 1 units, nothing to do.\n")
+	n, err = app.CountUnits("")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(2))
+}
+
+func (s *S) TestSetUnitsRequiresProcessWithMultipleProcesses(c *check.C) {
+	a := App{Name: "chemistry", Platform: "python", Quota: quota.Unlimited, TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "tsuru/app-chemistry:v1")
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("tsuru/app-chemistry:v1", map[string]interface{}{
+		"processes": map[string]interface{}{"web": "python web.py", "worker": "python worker.py"},
+	})
+	c.Assert(err, check.IsNil)
+	err = a.SetUnits(2, "", nil)
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	err = a.SetUnits(2, "cron", nil)
+	c.Assert(err, check.ErrorMatches, `process "cron" not found in app "chemistry"`)
+	err = a.SetUnits(2, "worker", nil)
+	c.Assert(err, check.IsNil)
+	n, err := a.CountUnits("worker")
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, uint(2))
+}
+
 func (s *S) TestRemoveUnits(c *check.C) {
 	var calls int32
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1527,6 +1623,40 @@ func (s *S) TestRemoveCNameRollback(c *check.C) {
 	c.Assert(a.CName, check.DeepEquals, []string{"ktulu2.mycompany.com", "ktulu3.mycompany.com", "ktulu.mycompany.com"})
 }
 
+func (s *S) TestMoveCName(c *check.C) {
+	a1 := &App{Name: "ktulu", TeamOwner: s.team.Name}
+	err := CreateApp(a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := &App{Name: "ktulu-new", TeamOwner: s.team.Name}
+	err = CreateApp(a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.AddCName("ktulu.mycompany.com", "ktulu2.mycompany.com")
+	c.Assert(err, check.IsNil)
+	err = a1.MoveCName("ktulu.mycompany.com", a2)
+	c.Assert(err, check.IsNil)
+	a1, err = GetByName(a1.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(a1.CName, check.DeepEquals, []string{"ktulu2.mycompany.com"})
+	a2, err = GetByName(a2.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(a2.CName, check.DeepEquals, []string{"ktulu.mycompany.com"})
+	c.Assert(routertest.FakeRouter.HasCNameFor(a2.Name, "ktulu.mycompany.com"), check.Equals, true)
+	c.Assert(routertest.FakeRouter.HasCNameFor(a1.Name, "ktulu.mycompany.com"), check.Equals, false)
+}
+
+func (s *S) TestMoveCNameNotFound(c *check.C) {
+	a1 := &App{Name: "ktulu", TeamOwner: s.team.Name}
+	err := CreateApp(a1, s.user)
+	c.Assert(err, check.IsNil)
+	a2 := &App{Name: "ktulu-new", TeamOwner: s.team.Name}
+	err = CreateApp(a2, s.user)
+	c.Assert(err, check.IsNil)
+	err = a1.MoveCName("ktulu.mycompany.com", a2)
+	c.Assert(err, check.Equals, ErrCNameNotFound)
+	err = a1.MoveCName("ktulu.mycompany.com", a1)
+	c.Assert(err, check.ErrorMatches, "cname source and destination apps must be different")
+}
+
 func (s *S) TestRemoveCNameRemovesFromDatabase(c *check.C) {
 	a := &App{Name: "ktulu", TeamOwner: s.team.Name}
 	err := CreateApp(a, s.user)
@@ -1630,6 +1760,27 @@ func (s *S) TestAddInstanceFirst(c *check.C) {
 	c.Assert(s.provisioner.Restarts(a, ""), check.Equals, 0)
 }
 
+func (s *S) TestAddInstanceWithoutEnvs(c *check.C) {
+	a := &App{Name: "dark", TeamOwner: s.team.Name}
+	err := CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	instance := bind.ServiceInstance{Name: "myinstance", Envs: map[string]string{}}
+	err = a.AddInstance(
+		bind.InstanceApp{
+			ServiceName:   "myservice",
+			Instance:      instance,
+			ShouldRestart: true,
+		}, nil)
+	c.Assert(err, check.IsNil)
+	a, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(a.parsedTsuruServices(), check.DeepEquals, map[string][]bind.ServiceInstance{
+		"myservice": {instance},
+	})
+	delete(a.Env, TsuruServicesEnvVar)
+	c.Assert(a.Env, check.DeepEquals, map[string]bind.EnvVar{})
+}
+
 func (s *S) TestAddInstanceDuplicated(c *check.C) {
 	a := &App{Name: "sith", TeamOwner: s.team.Name}
 	err := CreateApp(a, s.user)
@@ -1872,6 +2023,37 @@ func (s *S) TestAddInstanceAndRemoveInstanceMultipleServices(c *check.C) {
 	})
 }
 
+func (s *S) TestRemoveInstanceRestoresEnvsFromOtherInstances(c *check.C) {
+	a := &App{Name: "fuchsia", TeamOwner: s.team.Name}
+	err := CreateApp(a, s.user)
+	c.Assert(err, check.IsNil)
+	instance1 := bind.ServiceInstance{
+		Name: "myinstance",
+		Envs: map[string]string{"DATABASE_HOST": "myhost", "DATABASE_NAME": "myinstance"},
+	}
+	err = a.AddInstance(bind.InstanceApp{ServiceName: "mysql", Instance: instance1}, nil)
+	c.Assert(err, check.IsNil)
+	instance2 := bind.ServiceInstance{
+		Name: "yourinstance",
+		Envs: map[string]string{"DATABASE_NAME": "supermongo"},
+	}
+	err = a.AddInstance(bind.InstanceApp{ServiceName: "mongodb", Instance: instance2}, nil)
+	c.Assert(err, check.IsNil)
+	err = a.RemoveInstance(bind.InstanceApp{ServiceName: "mysql", Instance: instance1}, nil)
+	c.Assert(err, check.IsNil)
+	a, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	delete(a.Env, TsuruServicesEnvVar)
+	c.Assert(a.Env, check.DeepEquals, map[string]bind.EnvVar{
+		"DATABASE_NAME": {
+			Name:         "DATABASE_NAME",
+			Value:        "supermongo",
+			Public:       false,
+			InstanceName: "yourinstance",
+		},
+	})
+}
+
 func (s *S) TestRemoveInstance(c *check.C) {
 	a := &App{
 		Name: "dark",
@@ -2163,6 +2345,76 @@ func (s *S) TestRestart(c *check.C) {
 	c.Assert(restarts, check.Equals, 1)
 }
 
+func (s *S) TestRestartProcess(c *check.C) {
+	a := App{Name: "someapp", Platform: "django", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "tsuru/app-someapp:v1")
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("tsuru/app-someapp:v1", map[string]interface{}{
+		"processes": map[string]interface{}{"web": "python web.py", "worker": "python worker.py"},
+	})
+	c.Assert(err, check.IsNil)
+	var b bytes.Buffer
+	err = a.Restart("worker", &b)
+	c.Assert(err, check.IsNil)
+	c.Assert(b.String(), check.Matches, `(?s).*---- Restarting process "worker" ----.*`)
+	c.Assert(s.provisioner.Restarts(&a, "worker"), check.Equals, 1)
+	c.Assert(s.provisioner.Restarts(&a, "web"), check.Equals, 0)
+}
+
+func (s *S) TestRestartUnknownProcess(c *check.C) {
+	a := App{Name: "someapp", Platform: "django", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = image.AppendAppImageName(a.Name, "tsuru/app-someapp:v1")
+	c.Assert(err, check.IsNil)
+	err = image.SaveImageCustomData("tsuru/app-someapp:v1", map[string]interface{}{
+		"processes": map[string]interface{}{"web": "python web.py"},
+	})
+	c.Assert(err, check.IsNil)
+	err = a.Restart("worker", nil)
+	c.Assert(err, check.ErrorMatches, `process "worker" not found in app "someapp"`)
+	err = a.Start(nil, "worker")
+	c.Assert(err, check.ErrorMatches, `process "worker" not found in app "someapp"`)
+	err = a.Stop(nil, "worker")
+	c.Assert(err, check.ErrorMatches, `process "worker" not found in app "someapp"`)
+	c.Assert(s.provisioner.Restarts(&a, "worker"), check.Equals, 0)
+}
+
+func (s *S) TestRestartUnit(c *check.C) {
+	a := App{Name: "someapp", Platform: "django", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "web", nil)
+	c.Assert(err, check.IsNil)
+	units := s.provisioner.GetUnits(&a)
+	var b bytes.Buffer
+	err = a.RestartUnit(units[1].ID, &b)
+	c.Assert(err, check.IsNil)
+	c.Assert(b.String(), check.Equals, "restarting unit "+units[1].ID)
+	c.Assert(s.provisioner.UnitRestarts(&a, units[0].ID), check.Equals, 0)
+	c.Assert(s.provisioner.UnitRestarts(&a, units[1].ID), check.Equals, 1)
+	err = a.RestartUnit("notfound", nil)
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+}
+
+func (s *S) TestReplaceUnit(c *check.C) {
+	a := App{Name: "someapp", Platform: "django", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.AddUnits(&a, 2, "worker", nil)
+	c.Assert(err, check.IsNil)
+	units := s.provisioner.GetUnits(&a)
+	err = a.ReplaceUnit(units[0].ID, nil)
+	c.Assert(err, check.IsNil)
+	newUnits := s.provisioner.GetUnits(&a)
+	c.Assert(newUnits, check.HasLen, 2)
+	c.Assert(newUnits[0].ID, check.Not(check.Equals), units[0].ID)
+	c.Assert(newUnits[0].ProcessName, check.Equals, "worker")
+	c.Assert(newUnits[1].ID, check.Equals, units[1].ID)
+}
+
 func (s *S) TestStop(c *check.C) {
 	a := App{Name: "app", TeamOwner: s.team.Name}
 	err := CreateApp(&a, s.user)
@@ -2357,6 +2609,37 @@ func (s *S) TestLastLogsUnitFilter(c *check.C) {
 	}
 }
 
+func (s *S) TestLastLogsBetween(c *check.C) {
+	app := App{
+		Name:     "app3",
+		Platform: "vougan",
+		Teams:    []string{s.team.Name},
+	}
+	err := s.conn.Apps().Insert(app)
+	c.Assert(err, check.IsNil)
+	base := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		err = s.logConn.Logs(app.Name).Insert(Applog{
+			Date:    base.Add(time.Duration(i) * time.Minute),
+			Message: strconv.Itoa(i),
+			Source:  "tsuru",
+			AppName: app.Name,
+			Unit:    "rdaneel",
+		})
+		c.Assert(err, check.IsNil)
+	}
+	logs, err := app.LastLogsBetween(10, Applog{}, base.Add(time.Minute), base.Add(3*time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 3)
+	for i := range logs {
+		c.Check(logs[i].Message, check.Equals, strconv.Itoa(i+1))
+	}
+	logs, err = app.LastLogsBetween(10, Applog{}, base.Add(3*time.Minute), time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Message, check.Equals, "3")
+}
+
 func (s *S) TestLastLogsEmpty(c *check.C) {
 	app := App{
 		Name:     "app33",
@@ -2418,23 +2701,144 @@ func (s *S) TestGetUnits(c *check.C) {
 	c.Assert(units[1].Ip, check.Equals, bindUnits[1].GetIp())
 }
 
+func (s *S) TestAppSetMetadata(c *check.C) {
+	a := App{Name: "myapp", Labels: map[string]string{"team": "a"}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = a.SetMetadata(map[string]string{"team": "b", "cost-center": "42"}, map[string]string{"owner/email": "me@me.com"})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Labels, check.DeepEquals, map[string]string{"team": "b", "cost-center": "42"})
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.DeepEquals, map[string]string{"team": "b", "cost-center": "42"})
+	c.Assert(dbApp.Annotations, check.DeepEquals, map[string]string{"owner/email": "me@me.com"})
+}
+
+func (s *S) TestAppSetMetadataInvalidKey(c *check.C) {
+	a := App{Name: "myapp"}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	for _, key := range []string{"", "a.b", "$set", "-a", "a b"} {
+		err = a.SetMetadata(map[string]string{key: "x"}, nil)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{}, check.Commentf("key %q", key))
+	}
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.IsNil)
+}
+
+func (s *S) TestAppUnsetMetadata(c *check.C) {
+	a := App{
+		Name:        "myapp",
+		Labels:      map[string]string{"team": "a", "tier": "1"},
+		Annotations: map[string]string{"repo": "git"},
+	}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = a.UnsetMetadata([]string{"team"}, []string{"repo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Labels, check.DeepEquals, map[string]string{"tier": "1"})
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.DeepEquals, map[string]string{"tier": "1"})
+	c.Assert(dbApp.Annotations, check.DeepEquals, map[string]string{})
+}
+
+func (s *S) TestAppUnsetMetadataInvalidKey(c *check.C) {
+	a := App{Name: "myapp", Labels: map[string]string{"team": "a"}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	for _, key := range []string{"team.x", "$team", "a."} {
+		err = a.UnsetMetadata([]string{key}, nil)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{}, check.Commentf("key %q", key))
+		err = a.UnsetMetadata(nil, []string{key})
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{}, check.Commentf("key %q", key))
+	}
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Labels, check.DeepEquals, map[string]string{"team": "a"})
+}
+
+func (s *S) TestValidateMetadataKey(c *check.C) {
+	c.Assert(ValidateMetadataKey("cost-center"), check.IsNil)
+	c.Assert(ValidateMetadataKey("owner/email"), check.IsNil)
+	c.Assert(ValidateMetadataKey("a.b"), check.ErrorMatches, `Invalid metadata key "a.b", keys must not contain dots or start with \$.`)
+	c.Assert(ValidateMetadataKey("$where"), check.ErrorMatches, `Invalid metadata key "\$where", keys must not contain dots or start with \$.`)
+	c.Assert(ValidateMetadataKey("-a"), check.FitsTypeOf, &errors.ValidationError{})
+}
+
+func (s *S) TestListFilteringByLabels(c *check.C) {
+	apps := []App{
+		{Name: "app1", Labels: map[string]string{"team": "a", "tier": "1"}},
+		{Name: "app2", Labels: map[string]string{"team": "a", "tier": "2"}},
+		{Name: "app3"},
+	}
+	for _, a := range apps {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	result, err := List(&Filter{Labels: map[string]string{"team": "a", "tier": "2"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "app2")
+}
+
+func (s *S) TestListFilteringByTags(c *check.C) {
+	apps := []App{
+		{Name: "app1", Tags: []string{"frontend", "critical"}},
+		{Name: "app2", Tags: []string{"frontend"}},
+		{Name: "app3"},
+	}
+	for _, a := range apps {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	result, err := List(&Filter{Tags: []string{"critical", "frontend"}})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "app1")
+}
+
+func (s *S) TestListFilteringByDescription(c *check.C) {
+	apps := []App{
+		{Name: "app1", Description: "Payments API (v2)"},
+		{Name: "app2", Description: "payments worker"},
+		{Name: "app3", Description: "blog"},
+	}
+	for _, a := range apps {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	result, err := List(&Filter{Description: "PAYMENTS"})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 2)
+	result, err = List(&Filter{Description: "(v2)"})
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.HasLen, 1)
+	c.Assert(result[0].Name, check.Equals, "app1")
+}
+
 func (s *S) TestAppMarshalJSON(c *check.C) {
 	repository.Manager().CreateRepository("name", nil)
 	opts := provision.AddPoolOptions{Name: "test", Default: false}
 	err := provision.AddPool(opts)
 	c.Assert(err, check.IsNil)
 	app := App{
-		Name:        "name",
-		Platform:    "Framework",
-		Teams:       []string{"team1"},
-		Ip:          "10.10.10.1",
-		CName:       []string{"name.mycompany.com"},
-		Owner:       "appOwner",
-		Deploys:     7,
-		Pool:        "test",
-		Description: "description",
-		Plan:        Plan{Name: "myplan", Memory: 64, Swap: 128, CpuShare: 100},
-		TeamOwner:   "myteam",
+		Name:         "name",
+		Platform:     "Framework",
+		Teams:        []string{"team1"},
+		Ip:           "10.10.10.1",
+		CName:        []string{"name.mycompany.com"},
+		Owner:        "appOwner",
+		Deploys:      7,
+		Pool:         "test",
+		Description:  "description",
+		Plan:         Plan{Name: "myplan", Memory: 64, Swap: 128, CpuShare: 100},
+		TeamOwner:    "myteam",
+		Labels:       map[string]string{"cost-center": "42"},
+		Annotations:  map[string]string{"repo": "https://github.com/tsuru/name"},
+		Dependencies: []string{"db"},
+		Tags:         []string{"frontend", "critical"},
 	}
 	expected := map[string]interface{}{
 		"name":        "name",
@@ -2450,6 +2854,16 @@ func (s *S) TestAppMarshalJSON(c *check.C) {
 		"description": "description",
 		"teamowner":   "myteam",
 		"lock":        s.zeroLock,
+		"quota": map[string]interface{}{
+			"Limit": float64(0),
+			"InUse": float64(0),
+		},
+		"router":               "fake",
+		"serviceInstanceBinds": []interface{}{},
+		"labels":               map[string]interface{}{"cost-center": "42"},
+		"annotations":          map[string]interface{}{"repo": "https://github.com/tsuru/name"},
+		"dependencies":         []interface{}{"db"},
+		"tags":                 []interface{}{"frontend", "critical"},
 		"plan": map[string]interface{}{
 			"name":     "myplan",
 			"memory":   float64(64),
@@ -2493,6 +2907,16 @@ func (s *S) TestAppMarshalJSONWithoutRepository(c *check.C) {
 		"description": "description",
 		"teamowner":   "myteam",
 		"lock":        s.zeroLock,
+		"quota": map[string]interface{}{
+			"Limit": float64(0),
+			"InUse": float64(0),
+		},
+		"router":               "fake",
+		"serviceInstanceBinds": []interface{}{},
+		"labels":               nil,
+		"annotations":          nil,
+		"dependencies":         nil,
+		"tags":                 nil,
 		"plan": map[string]interface{}{
 			"name":     "myplan",
 			"memory":   float64(64),
@@ -2566,6 +2990,64 @@ func (s *S) TestRunOnce(c *check.C) {
 	c.Assert(cmds, check.HasLen, 1)
 }
 
+func (s *S) TestRunInUnits(c *check.C) {
+	s.provisioner.PrepareOutput([]byte("unit 1 files"))
+	s.provisioner.PrepareOutput([]byte("unit 2 files"))
+	app := App{
+		Name:      "myapp",
+		TeamOwner: s.team.Name,
+	}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&app, 3, "web", nil)
+	var buf bytes.Buffer
+	args := provision.RunArgs{Units: []string{"myapp-1", "myapp-2"}}
+	err = app.Run("ls -lh", &buf, args)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "unit 1 filesunit 2 files")
+	expected := "[ -f /home/application/apprc ] && source /home/application/apprc;"
+	expected += " [ -d /home/application/current ] && cd /home/application/current;"
+	expected += " ls -lh"
+	cmds := s.provisioner.GetCmds(expected, &app)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Units, check.DeepEquals, []string{"myapp-1", "myapp-2"})
+}
+
+func (s *S) TestRunInUnitsNotFound(c *check.C) {
+	app := App{
+		Name:      "myapp",
+		TeamOwner: s.team.Name,
+	}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&app, 1, "web", nil)
+	var buf bytes.Buffer
+	err = app.Run("ls -lh", &buf, provision.RunArgs{Units: []string{"other-unit"}})
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+}
+
+func (s *S) TestRunUnitsPercentage(c *check.C) {
+	for i := 0; i < 2; i++ {
+		s.provisioner.PrepareOutput([]byte("files"))
+	}
+	app := App{
+		Name:      "myapp",
+		TeamOwner: s.team.Name,
+	}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&app, 4, "web", nil)
+	var buf bytes.Buffer
+	err = app.Run("ls -lh", &buf, provision.RunArgs{UnitsPercentage: 30})
+	c.Assert(err, check.IsNil)
+	cmds := s.provisioner.GetCmds("", &app)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Units, check.HasLen, 2)
+	c.Assert(cmds[0].Units[0], check.Not(check.Equals), cmds[0].Units[1])
+	err = app.Run("ls -lh", &buf, provision.RunArgs{UnitsPercentage: 101})
+	c.Assert(err, check.ErrorMatches, "units percentage must be between 1 and 100")
+}
+
 func (s *S) TestRunIsolated(c *check.C) {
 	s.provisioner.PrepareOutput([]byte("a lot of files"))
 	app := App{
@@ -2587,6 +3069,22 @@ func (s *S) TestRunIsolated(c *check.C) {
 	c.Assert(cmds, check.HasLen, 1)
 }
 
+func (s *S) TestRunIsolatedWithoutUnits(c *check.C) {
+	s.provisioner.PrepareOutput([]byte("migrated"))
+	app := App{
+		Name:      "myapp",
+		TeamOwner: s.team.Name,
+	}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	var buf bytes.Buffer
+	err = app.Run("./migrate", &buf, provision.RunArgs{Isolated: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "migrated")
+	err = app.Run("./migrate", &buf, provision.RunArgs{})
+	c.Assert(err, check.ErrorMatches, "App must be available to run commands")
+}
+
 func (s *S) TestRunWithoutEnv(c *check.C) {
 	s.provisioner.PrepareOutput([]byte("a lot of files"))
 	app := App{
@@ -3421,6 +3919,24 @@ func (s *S) TestAppAcquireApplicationLockWaitWithoutRelease(c *check.C) {
 	c.Assert(app.Lock.AcquireDate, check.NotNil)
 }
 
+func (s *S) TestReleaseStaleApplicationLock(c *check.C) {
+	a := App{Name: "test-lock-app", Lock: AppLock{Locked: true, Owner: "foo", AcquireDate: time.Now().Add(-time.Hour)}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	released, err := ReleaseStaleApplicationLock(a.Name, 2*time.Hour)
+	c.Assert(err, check.IsNil)
+	c.Assert(released, check.Equals, false)
+	app, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(app.Lock.Locked, check.Equals, true)
+	released, err = ReleaseStaleApplicationLock(a.Name, 30*time.Minute)
+	c.Assert(err, check.IsNil)
+	c.Assert(released, check.Equals, true)
+	app, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(app.Lock.Locked, check.Equals, false)
+}
+
 func (s *S) TestAppLockStringUnlocked(c *check.C) {
 	lock := AppLock{Locked: false}
 	c.Assert(lock.String(), check.Equals, "Not locked")
@@ -3699,6 +4215,18 @@ func (s *S) TestUpdateDescription(c *check.C) {
 	c.Assert(dbApp.Description, check.Equals, "bleble")
 }
 
+func (s *S) TestUpdateTags(c *check.C) {
+	app := App{Name: "example", Platform: "python", TeamOwner: s.team.Name, Tags: []string{"a"}}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	updateData := App{Name: "example", Tags: []string{" b ", "c", "b", ""}}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(app.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Tags, check.DeepEquals, []string{"b", "c"})
+}
+
 func (s *S) TestUpdateTeamOwner(c *check.C) {
 	app := App{Name: "example", Platform: "python", TeamOwner: s.team.Name, Description: "blabla"}
 	err := CreateApp(&app, s.user)
@@ -3766,6 +4294,141 @@ func (s *S) TestUpdatePoolNotExists(c *check.C) {
 	c.Assert(dbApp.Pool, check.Equals, "test")
 }
 
+func (s *S) TestChangePool(c *check.C) {
+	for _, name := range []string{"dev", "prod"} {
+		err := provision.AddPool(provision.AddPoolOptions{Name: name})
+		c.Assert(err, check.IsNil)
+		err = provision.AddTeamsToPool(name, []string{s.team.Name})
+		c.Assert(err, check.IsNil)
+	}
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Pool: "dev"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 2, "web", nil)
+	err = a.ChangePool("prod", new(bytes.Buffer))
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Pool, check.Equals, "prod")
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Pool, check.Equals, "prod")
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+}
+
+func (s *S) TestChangePoolRestartFailure(c *check.C) {
+	for _, name := range []string{"dev", "prod"} {
+		err := provision.AddPool(provision.AddPoolOptions{Name: name})
+		c.Assert(err, check.IsNil)
+		err = provision.AddTeamsToPool(name, []string{s.team.Name})
+		c.Assert(err, check.IsNil)
+	}
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Pool: "dev"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 1, "web", nil)
+	s.provisioner.PrepareFailure("Restart", fmt.Errorf("no nodes available"))
+	err = a.ChangePool("prod", new(bytes.Buffer))
+	c.Assert(err, check.ErrorMatches, "no nodes available")
+	c.Assert(a.Pool, check.Equals, "dev")
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Pool, check.Equals, "dev")
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+}
+
+func (s *S) TestUpdatePoolMovesUnits(c *check.C) {
+	for _, name := range []string{"dev", "prod"} {
+		err := provision.AddPool(provision.AddPoolOptions{Name: name})
+		c.Assert(err, check.IsNil)
+		err = provision.AddTeamsToPool(name, []string{s.team.Name})
+		c.Assert(err, check.IsNil)
+	}
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Pool: "dev"}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnits(&a, 2, "web", nil)
+	err = a.Update(App{Pool: "prod", Description: "moved"}, new(bytes.Buffer))
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Pool, check.Equals, "prod")
+	c.Assert(dbApp.Description, check.Equals, "moved")
+	c.Assert(s.provisioner.Restarts(&a, ""), check.Equals, 1)
+}
+
+func (s *S) TestChangePoolSamePool(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "dev", Public: true})
+	c.Assert(err, check.IsNil)
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Pool: "dev"}
+	err = CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.ChangePool("dev", new(bytes.Buffer))
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+}
+
+func (s *S) TestChangePoolNotFound(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "dev", Public: true})
+	c.Assert(err, check.IsNil)
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Pool: "dev"}
+	err = CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.ChangePool("prod", new(bytes.Buffer))
+	c.Assert(err, check.Equals, provision.ErrPoolNotFound)
+}
+
+func (s *S) TestUpdateTeamOwnerWithoutPoolAccess(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "prod"})
+	c.Assert(err, check.IsNil)
+	err = provision.AddTeamsToPool("prod", []string{s.team.Name})
+	c.Assert(err, check.IsNil)
+	app := App{Name: "test", TeamOwner: s.team.Name, Pool: "prod"}
+	err = CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	team := &auth.Team{Name: "newowner"}
+	err = s.conn.Teams().Insert(team)
+	c.Assert(err, check.IsNil)
+	updateData := App{Name: "test", TeamOwner: team.Name}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.DeepEquals, &PoolAccessError{Team: team.Name, Pool: "prod"})
+	dbApp, err := GetByName(app.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TeamOwner, check.Equals, s.team.Name)
+}
+
+func (s *S) TestUpdatePoolAndTeamOwner(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "prod"})
+	c.Assert(err, check.IsNil)
+	err = provision.AddTeamsToPool("prod", []string{s.team.Name})
+	c.Assert(err, check.IsNil)
+	err = provision.AddPool(provision.AddPoolOptions{Name: "staging"})
+	c.Assert(err, check.IsNil)
+	err = provision.AddTeamsToPool("staging", []string{"newowner"})
+	c.Assert(err, check.IsNil)
+	app := App{Name: "test", TeamOwner: s.team.Name, Pool: "prod"}
+	err = CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Teams().Insert(&auth.Team{Name: "newowner"})
+	c.Assert(err, check.IsNil)
+	updateData := App{Name: "test", TeamOwner: "newowner", Pool: "staging"}
+	err = app.Update(updateData, new(bytes.Buffer))
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(app.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.TeamOwner, check.Equals, "newowner")
+	c.Assert(dbApp.Pool, check.Equals, "staging")
+}
+
+func (s *S) TestCreateAppWithoutPoolAccess(c *check.C) {
+	err := provision.AddPool(provision.AddPoolOptions{Name: "prod"})
+	c.Assert(err, check.IsNil)
+	err = provision.AddTeamsToPool("prod", []string{"otherteam"})
+	c.Assert(err, check.IsNil)
+	app := App{Name: "test", Platform: "python", TeamOwner: s.team.Name, Pool: "prod"}
+	err = CreateApp(&app, s.user)
+	c.Assert(err, check.DeepEquals, &PoolAccessError{Team: s.team.Name, Pool: "prod"})
+	_, err = GetByName(app.Name)
+	c.Assert(err, check.Equals, ErrAppNotFound)
+}
+
 func (s *S) TestUpdatePlan(c *check.C) {
 	plan := Plan{Name: "something", Router: "fake-hc", CpuShare: 100, Memory: 268435456}
 	err := s.conn.Plans().Insert(plan)
@@ -3956,7 +4619,7 @@ func (s *S) TestUpdateDescriptionPoolAndPlan(c *check.C) {
 	c.Assert(dbApp.Plan, check.DeepEquals, plan)
 	c.Assert(dbApp.Description, check.Equals, "bleble")
 	c.Assert(dbApp.Pool, check.Equals, "test2")
-	c.Assert(s.provisioner.Restarts(dbApp, ""), check.Equals, 1)
+	c.Assert(s.provisioner.Restarts(dbApp, ""), check.Equals, 2)
 	c.Assert(routertest.FakeRouter.HasBackend(dbApp.Name), check.Equals, false)
 	c.Assert(routertest.HCRouter.HasBackend(dbApp.Name), check.Equals, true)
 	routes, err := routertest.HCRouter.Routes(dbApp.Name)
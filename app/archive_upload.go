@@ -0,0 +1,278 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// MaxArchiveUploadChunkSize is the maximum size of each chunk sent to a
+	// deploy upload.
+	MaxArchiveUploadChunkSize = 8 << 20
+
+	archiveUploadTTL = 24 * time.Hour
+
+	defaultArchiveUploadMaxSize   = 1 << 30
+	defaultArchiveUploadMaxPerApp = 5
+)
+
+var (
+	ErrArchiveUploadNotFound = errors.New("archive upload not found")
+	ErrArchiveUploadTooLarge = errors.New("archive upload too large")
+	ErrTooManyArchiveUploads = errors.New("too many archive uploads in progress for the app")
+)
+
+// archiveUploadMaxSize returns the maximum size of a whole upload, in bytes.
+func archiveUploadMaxSize() int64 {
+	size, _ := config.GetInt("server:deploy-uploads:max-size")
+	if size <= 0 {
+		size = defaultArchiveUploadMaxSize
+	}
+	return int64(size)
+}
+
+// archiveUploadMaxPerApp returns the maximum number of unexpired uploads of a
+// single app.
+func archiveUploadMaxPerApp() int {
+	max, _ := config.GetInt("server:deploy-uploads:max-per-app")
+	if max <= 0 {
+		max = defaultArchiveUploadMaxPerApp
+	}
+	return max
+}
+
+// ArchiveUploadOffsetError is returned when a chunk doesn't start where the
+// previous one ended, either because the client lost track of the upload or
+// because the chunk was already received.
+type ArchiveUploadOffsetError struct {
+	Offset   int64
+	Expected int64
+}
+
+func (e *ArchiveUploadOffsetError) Error() string {
+	return fmt.Sprintf("invalid chunk offset %d, expected %d", e.Offset, e.Expected)
+}
+
+// ArchiveUpload is an archive uploaded in chunks, allowing clients on
+// unreliable links to resume the upload from the last chunk received instead
+// of sending the whole archive again. Once complete, it may be used in deploys
+// as an uploaded file. Uploads expire one day after the last chunk received.
+type ArchiveUpload struct {
+	ID        bson.ObjectId `bson:"_id" json:"id"`
+	App       string        `json:"app"`
+	User      string        `json:"user"`
+	Size      int64         `json:"size"`
+	CreatedAt time.Time     `json:"createdAt"`
+	ExpiresAt time.Time     `json:"expiresAt"`
+}
+
+type archiveUploadChunk struct {
+	Upload    bson.ObjectId
+	Offset    int64
+	Data      []byte
+	ExpiresAt time.Time
+}
+
+// NewArchiveUpload starts a new empty upload for the app. It returns
+// ErrTooManyArchiveUploads when the app already has the maximum number of
+// uploads that haven't expired yet.
+func NewArchiveUpload(app *App, user string) (*ArchiveUpload, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	now := time.Now().UTC()
+	upload := ArchiveUpload{
+		ID:        bson.NewObjectId(),
+		App:       app.Name,
+		User:      user,
+		CreatedAt: now,
+		ExpiresAt: now.Add(archiveUploadTTL),
+	}
+	err = conn.DeployUploads().Insert(upload)
+	if err != nil {
+		return nil, err
+	}
+	// The upload is counted after being inserted, so concurrent requests
+	// can't all pass the check before any of them is stored.
+	n, err := conn.DeployUploads().Find(bson.M{"app": app.Name, "expiresat": bson.M{"$gt": now}}).Count()
+	if err == nil && n > archiveUploadMaxPerApp() {
+		err = ErrTooManyArchiveUploads
+	}
+	if err != nil {
+		conn.DeployUploads().RemoveId(upload.ID)
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// GetArchiveUpload returns the upload with the given id, which must belong to
+// the app.
+func GetArchiveUpload(appName, id string) (*ArchiveUpload, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, ErrArchiveUploadNotFound
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var upload ArchiveUpload
+	err = conn.DeployUploads().Find(bson.M{"_id": bson.ObjectIdHex(id), "app": appName}).One(&upload)
+	if err == mgo.ErrNotFound {
+		return nil, ErrArchiveUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// AppendChunk stores data at the end of the upload. The offset must be the
+// current size of the upload, otherwise a *ArchiveUploadOffsetError is
+// returned. Sending the last chunk again with the same content is allowed, so
+// clients that didn't get the response for a chunk may safely retry it.
+//
+// The new size is claimed before the chunk is stored, so only one of
+// concurrent requests sending a chunk at the same offset succeeds.
+func (u *ArchiveUpload) AppendChunk(offset int64, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty chunk")
+	}
+	if len(data) > MaxArchiveUploadChunkSize {
+		return errors.Errorf("chunk too large, the maximum size is %d bytes", MaxArchiveUploadChunkSize)
+	}
+	size := offset + int64(len(data))
+	if size > archiveUploadMaxSize() {
+		return ErrArchiveUploadTooLarge
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	expiresAt := time.Now().UTC().Add(archiveUploadTTL)
+	err = conn.DeployUploads().Update(
+		bson.M{"_id": u.ID, "size": offset},
+		bson.M{"$set": bson.M{"size": size, "expiresat": expiresAt}},
+	)
+	if err == mgo.ErrNotFound {
+		var current ArchiveUpload
+		err = conn.DeployUploads().FindId(u.ID).One(&current)
+		if err == mgo.ErrNotFound {
+			return ErrArchiveUploadNotFound
+		}
+		if err != nil {
+			return err
+		}
+		u.Size = current.Size
+		if u.isLastChunk(conn.DeployUploadChunks(), offset, data) {
+			return nil
+		}
+		return &ArchiveUploadOffsetError{Offset: offset, Expected: current.Size}
+	}
+	if err != nil {
+		return err
+	}
+	chunk := archiveUploadChunk{Upload: u.ID, Offset: offset, Data: data, ExpiresAt: expiresAt}
+	err = conn.DeployUploadChunks().Insert(chunk)
+	if err != nil {
+		conn.DeployUploads().Update(
+			bson.M{"_id": u.ID, "size": size},
+			bson.M{"$set": bson.M{"size": offset}},
+		)
+		return err
+	}
+	// Chunks expire along with the upload, otherwise the first chunks of a
+	// long upload could be removed before it's complete.
+	_, err = conn.DeployUploadChunks().UpdateAll(
+		bson.M{"upload": u.ID},
+		bson.M{"$set": bson.M{"expiresat": expiresAt}},
+	)
+	if err != nil {
+		return err
+	}
+	u.Size = size
+	u.ExpiresAt = expiresAt
+	return nil
+}
+
+func (u *ArchiveUpload) isLastChunk(coll *storage.Collection, offset int64, data []byte) bool {
+	if offset+int64(len(data)) != u.Size {
+		return false
+	}
+	var chunk archiveUploadChunk
+	err := coll.Find(bson.M{"upload": u.ID, "offset": offset}).One(&chunk)
+	return err == nil && bytes.Equal(chunk.Data, data)
+}
+
+// Open returns a reader for the content of the upload, reading one chunk at a
+// time.
+func (u *ArchiveUpload) Open() io.ReadCloser {
+	return &archiveUploadReader{upload: u}
+}
+
+// Remove removes the upload and its chunks.
+func (u *ArchiveUpload) Remove() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.DeployUploadChunks().RemoveAll(bson.M{"upload": u.ID})
+	if err != nil {
+		return err
+	}
+	err = conn.DeployUploads().RemoveId(u.ID)
+	if err == mgo.ErrNotFound {
+		return ErrArchiveUploadNotFound
+	}
+	return err
+}
+
+type archiveUploadReader struct {
+	upload *ArchiveUpload
+	offset int64
+	buf    []byte
+}
+
+func (r *archiveUploadReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.offset >= r.upload.Size {
+			return 0, io.EOF
+		}
+		conn, err := db.Conn()
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		var chunk archiveUploadChunk
+		err = conn.DeployUploadChunks().Find(bson.M{"upload": r.upload.ID, "offset": r.offset}).One(&chunk)
+		if err != nil {
+			return 0, errors.Wrapf(err, "unable to read upload chunk at offset %d", r.offset)
+		}
+		r.buf = chunk.Data
+		r.offset += int64(len(chunk.Data))
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *archiveUploadReader) Close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"io/ioutil"
+
+	"github.com/tsuru/config"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestArchiveUploadAppendChunks(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	c.Assert(upload.Size, check.Equals, int64(0))
+	err = upload.AppendChunk(0, []byte("hello "))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(6, []byte("world"))
+	c.Assert(err, check.IsNil)
+	c.Assert(upload.Size, check.Equals, int64(11))
+	dbUpload, err := GetArchiveUpload("myapp", upload.ID.Hex())
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUpload.Size, check.Equals, int64(11))
+	c.Assert(dbUpload.User, check.Equals, "admin@tsuru.io")
+	data, err := ioutil.ReadAll(dbUpload.Open())
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "hello world")
+}
+
+func (s *S) TestArchiveUploadAppendChunkInvalidOffset(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello "))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(10, []byte("world"))
+	c.Assert(err, check.DeepEquals, &ArchiveUploadOffsetError{Offset: 10, Expected: 6})
+	stale, err := GetArchiveUpload("myapp", upload.ID.Hex())
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(6, []byte("world"))
+	c.Assert(err, check.IsNil)
+	stale.Size = 6
+	err = stale.AppendChunk(6, []byte("there"))
+	c.Assert(err, check.DeepEquals, &ArchiveUploadOffsetError{Offset: 6, Expected: 11})
+	c.Assert(stale.Size, check.Equals, int64(11))
+}
+
+func (s *S) TestArchiveUploadAppendLastChunkAgain(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello"))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello"))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("howdy"))
+	c.Assert(err, check.DeepEquals, &ArchiveUploadOffsetError{Offset: 0, Expected: 5})
+	c.Assert(upload.Size, check.Equals, int64(5))
+}
+
+func (s *S) TestGetArchiveUploadNotFound(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	_, err = GetArchiveUpload("otherapp", upload.ID.Hex())
+	c.Assert(err, check.Equals, ErrArchiveUploadNotFound)
+	_, err = GetArchiveUpload("myapp", "invalid")
+	c.Assert(err, check.Equals, ErrArchiveUploadNotFound)
+}
+
+func (s *S) TestArchiveUploadRemove(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello"))
+	c.Assert(err, check.IsNil)
+	err = upload.Remove()
+	c.Assert(err, check.IsNil)
+	_, err = GetArchiveUpload("myapp", upload.ID.Hex())
+	c.Assert(err, check.Equals, ErrArchiveUploadNotFound)
+	n, err := s.conn.DeployUploadChunks().Find(nil).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestArchiveUploadAppendChunkTooLarge(c *check.C) {
+	config.Set("server:deploy-uploads:max-size", 10)
+	defer config.Unset("server:deploy-uploads:max-size")
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello "))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(6, []byte("world"))
+	c.Assert(err, check.Equals, ErrArchiveUploadTooLarge)
+	c.Assert(upload.Size, check.Equals, int64(6))
+}
+
+func (s *S) TestArchiveUploadAppendChunkRefreshesChunksExpiration(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(0, []byte("hello "))
+	c.Assert(err, check.IsNil)
+	err = upload.AppendChunk(6, []byte("world"))
+	c.Assert(err, check.IsNil)
+	dbUpload, err := GetArchiveUpload("myapp", upload.ID.Hex())
+	c.Assert(err, check.IsNil)
+	var chunks []archiveUploadChunk
+	err = s.conn.DeployUploadChunks().Find(bson.M{"upload": upload.ID}).All(&chunks)
+	c.Assert(err, check.IsNil)
+	c.Assert(chunks, check.HasLen, 2)
+	for _, chunk := range chunks {
+		c.Assert(chunk.ExpiresAt.Equal(dbUpload.ExpiresAt), check.Equals, true)
+	}
+}
+
+func (s *S) TestArchiveUploadAppendChunkConcurrent(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	upload, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+	other := *upload
+	err = upload.AppendChunk(0, []byte("hello"))
+	c.Assert(err, check.IsNil)
+	err = other.AppendChunk(0, []byte("howdy"))
+	c.Assert(err, check.DeepEquals, &ArchiveUploadOffsetError{Offset: 0, Expected: 5})
+	data, err := ioutil.ReadAll(upload.Open())
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "hello")
+}
+
+func (s *S) TestNewArchiveUploadTooMany(c *check.C) {
+	config.Set("server:deploy-uploads:max-per-app", 2)
+	defer config.Unset("server:deploy-uploads:max-per-app")
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	for i := 0; i < 2; i++ {
+		_, err := NewArchiveUpload(&a, "admin@tsuru.io")
+		c.Assert(err, check.IsNil)
+	}
+	_, err := NewArchiveUpload(&a, "admin@tsuru.io")
+	c.Assert(err, check.Equals, ErrTooManyArchiveUploads)
+	n, err := s.conn.DeployUploads().Find(bson.M{"app": "myapp"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 2)
+	_, err = NewArchiveUpload(&App{Name: "otherapp"}, "admin@tsuru.io")
+	c.Assert(err, check.IsNil)
+}
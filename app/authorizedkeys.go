@@ -0,0 +1,140 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+)
+
+// writeAuthorizedKeysCmd replaces the authorized_keys file in a unit with the
+// content given as the first argument of the command.
+const writeAuthorizedKeysCmd = `mkdir -p ~/.ssh && chmod 700 ~/.ssh && printf '%s' "$0" > ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys`
+
+func propagateKeysEnabled() bool {
+	enabled, _ := config.GetBool("keys:propagate-to-units")
+	return enabled
+}
+
+// SyncUserAuthorizedKeys updates the authorized_keys file in the units of
+// every app the user is allowed to open a shell in. It does nothing unless
+// keys:propagate-to-units is enabled in the config.
+func SyncUserAuthorizedKeys(u *auth.User) error {
+	if !propagateKeysEnabled() {
+		return nil
+	}
+	perms, err := u.Permissions()
+	if err != nil {
+		return err
+	}
+	contexts := permission.ContextsFromListForPermission(perms, permission.PermAppRunShell)
+	if len(contexts) == 0 {
+		return nil
+	}
+	filter := &Filter{}
+contextsLoop:
+	for _, c := range contexts {
+		switch c.CtxType {
+		case permission.CtxGlobal:
+			filter.Extra = nil
+			break contextsLoop
+		case permission.CtxTeam:
+			filter.ExtraIn("teams", c.Value)
+		case permission.CtxApp:
+			filter.ExtraIn("name", c.Value)
+		case permission.CtxPool:
+			filter.ExtraIn("pool", c.Value)
+		}
+	}
+	apps, err := List(filter)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for i := range apps {
+		err = apps[i].SyncAuthorizedKeys()
+		if err != nil {
+			log.Errorf("[authorized-keys] unable to sync keys of app %q: %s", apps[i].Name, err)
+			failed = append(failed, apps[i].Name)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("unable to sync authorized keys in apps: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// SyncAuthorizedKeys writes the keys of all users allowed to open a shell in
+// the app to the authorized_keys file of each of its units.
+func (app *App) SyncAuthorizedKeys() error {
+	units, err := app.Units()
+	if err != nil {
+		return err
+	}
+	if len(units) == 0 {
+		return nil
+	}
+	content, err := app.authorizedKeys()
+	if err != nil {
+		return err
+	}
+	prov, err := app.getProvisioner()
+	if err != nil {
+		return err
+	}
+	execProv, ok := prov.(provision.ExecutableProvisioner)
+	if !ok {
+		return provision.ProvisionerNotSupported{Prov: prov, Action: "syncing authorized keys"}
+	}
+	var buf bytes.Buffer
+	err = execProv.ExecuteCommand(&buf, &buf, app, writeAuthorizedKeysCmd, content)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write authorized keys: %s", buf.String())
+	}
+	return nil
+}
+
+// authorizedKeys returns the content of the authorized_keys file for the
+// app, with one line for each key of the users allowed to open a shell in it.
+func (app *App) authorizedKeys() (string, error) {
+	contexts := append(permission.Contexts(permission.CtxTeam, app.Teams),
+		permission.Context(permission.CtxApp, app.Name),
+		permission.Context(permission.CtxPool, app.Pool),
+	)
+	wantedPerms := make([]permission.Permission, len(contexts))
+	for i, ctx := range contexts {
+		wantedPerms[i] = permission.Permission{Scheme: permission.PermAppRunShell, Context: ctx}
+	}
+	users, err := auth.ListUsersWithPermissions(wantedPerms...)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, u := range users {
+		keys, err := u.ListKeys()
+		if err != nil {
+			return "", err
+		}
+		for _, body := range keys {
+			body = strings.TrimSpace(body)
+			if body != "" {
+				lines = append(lines, body)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n", nil
+}
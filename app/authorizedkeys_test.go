@@ -0,0 +1,104 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/repository"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAppAuthorizedKeys(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}, Pool: s.Pool}
+	allowed := customUserWithPermission(c, "shelluser", permission.Permission{
+		Scheme:  permission.PermAppRunShell,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	other := customUserWithPermission(c, "otheruser", permission.Permission{
+		Scheme:  permission.PermAppRunShell,
+		Context: permission.Context(permission.CtxTeam, "otherteam"),
+	})
+	err := allowed.AddKey(repository.Key{Name: "k1", Body: "ssh-rsa bbbb shelluser"}, false)
+	c.Assert(err, check.IsNil)
+	err = allowed.AddKey(repository.Key{Name: "k2", Body: "ssh-rsa aaaa shelluser\n"}, false)
+	c.Assert(err, check.IsNil)
+	err = other.AddKey(repository.Key{Name: "k1", Body: "ssh-rsa cccc otheruser"}, false)
+	c.Assert(err, check.IsNil)
+	content, err := a.authorizedKeys()
+	c.Assert(err, check.IsNil)
+	c.Assert(content, check.Equals, "ssh-rsa aaaa shelluser\nssh-rsa bbbb shelluser\n")
+}
+
+func (s *S) TestAppSyncAuthorizedKeys(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}, Pool: s.Pool}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	s.provisioner.Provision(&a)
+	defer s.provisioner.Destroy(&a)
+	s.provisioner.AddUnits(&a, 2, "web", nil)
+	user := customUserWithPermission(c, "shelluser", permission.Permission{
+		Scheme:  permission.PermAppRunShell,
+		Context: permission.Context(permission.CtxApp, a.Name),
+	})
+	err = user.AddKey(repository.Key{Name: "k1", Body: "ssh-rsa aaaa shelluser"}, false)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareOutput(nil)
+	err = a.SyncAuthorizedKeys()
+	c.Assert(err, check.IsNil)
+	cmds := s.provisioner.GetCmds(writeAuthorizedKeysCmd, &a)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Args, check.DeepEquals, []string{"ssh-rsa aaaa shelluser\n"})
+}
+
+func (s *S) TestAppSyncAuthorizedKeysNoUnits(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}, Pool: s.Pool}
+	s.provisioner.Provision(&a)
+	defer s.provisioner.Destroy(&a)
+	err := a.SyncAuthorizedKeys()
+	c.Assert(err, check.IsNil)
+	c.Assert(s.provisioner.GetCmds(writeAuthorizedKeysCmd, &a), check.HasLen, 0)
+}
+
+func (s *S) TestSyncUserAuthorizedKeys(c *check.C) {
+	config.Set("keys:propagate-to-units", true)
+	defer config.Unset("keys:propagate-to-units")
+	a1 := App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}, Pool: s.Pool}
+	a2 := App{Name: "otherapp", TeamOwner: "otherteam", Teams: []string{"otherteam"}, Pool: s.Pool}
+	for _, a := range []*App{&a1, &a2} {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+		s.provisioner.Provision(a)
+		defer s.provisioner.Destroy(a)
+		s.provisioner.AddUnits(a, 1, "web", nil)
+	}
+	user := customUserWithPermission(c, "shelluser", permission.Permission{
+		Scheme:  permission.PermAppRunShell,
+		Context: permission.Context(permission.CtxTeam, s.team.Name),
+	})
+	err := user.AddKey(repository.Key{Name: "k1", Body: "ssh-rsa aaaa shelluser"}, false)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareOutput(nil)
+	err = SyncUserAuthorizedKeys(user)
+	c.Assert(err, check.IsNil)
+	c.Assert(s.provisioner.GetCmds(writeAuthorizedKeysCmd, &a1), check.HasLen, 1)
+	c.Assert(s.provisioner.GetCmds(writeAuthorizedKeysCmd, &a2), check.HasLen, 0)
+}
+
+func (s *S) TestSyncUserAuthorizedKeysDisabled(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name, Teams: []string{s.team.Name}, Pool: s.Pool}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	s.provisioner.Provision(&a)
+	defer s.provisioner.Destroy(&a)
+	s.provisioner.AddUnits(&a, 1, "web", nil)
+	user := customUserWithPermission(c, "shelluser", permission.Permission{
+		Scheme:  permission.PermAppRunShell,
+		Context: permission.Context(permission.CtxGlobal, ""),
+	})
+	err = SyncUserAuthorizedKeys(user)
+	c.Assert(err, check.IsNil)
+	c.Assert(s.provisioner.GetCmds(writeAuthorizedKeysCmd, &a), check.HasLen, 0)
+}
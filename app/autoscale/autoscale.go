@@ -0,0 +1,206 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package autoscale implements horizontal autoscaling of app units, based on
+// rules defined per app process and evaluated periodically against metrics
+// collected by tsuru or read from a Prometheus server.
+package autoscale
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+)
+
+const (
+	eventKind = "autoscale"
+
+	// tolerance is the relative distance between the metric value and the
+	// target under which no scaling happens, preventing the number of
+	// units from flapping.
+	tolerance = 0.1
+)
+
+// EventCustomData is stored in the events of scaling operations.
+type EventCustomData struct {
+	Rule   Rule
+	Value  float64
+	Error  string `bson:",omitempty"`
+	Before uint
+	After  uint
+}
+
+// Controller periodically evaluates the autoscaling rules, adding or
+// removing units from app processes.
+type Controller struct {
+	source   MetricsSource
+	interval time.Duration
+	quit     chan bool
+	wg       sync.WaitGroup
+}
+
+// Initialize starts the autoscale controller if autoscale:enabled is set. It
+// returns nil when autoscaling is disabled.
+func Initialize() (*Controller, error) {
+	enabled, _ := config.GetBool("autoscale:enabled")
+	if !enabled {
+		return nil, nil
+	}
+	var source MetricsSource
+	serverURL, _ := config.GetString("autoscale:prometheus:url")
+	if serverURL != "" {
+		var err error
+		source, err = newPrometheusSource(serverURL)
+		if err != nil {
+			return nil, err
+		}
+	} else if metricsEnabled, _ := config.GetBool("metrics:enabled"); metricsEnabled {
+		source = &internalSource{window: internalSourceWindow}
+	} else {
+		return nil, errors.New(`autoscale is enabled but neither "autoscale:prometheus:url" nor "metrics:enabled" are defined`)
+	}
+	interval, _ := config.GetInt("autoscale:run-interval")
+	if interval <= 0 {
+		interval = 60
+	}
+	c := newController(source, time.Duration(interval)*time.Second)
+	c.start()
+	shutdown.Register(c)
+	return c, nil
+}
+
+func newController(source MetricsSource, interval time.Duration) *Controller {
+	return &Controller{
+		source:   source,
+		interval: interval,
+		quit:     make(chan bool),
+	}
+}
+
+func (c *Controller) start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			c.runOnce()
+			select {
+			case <-c.quit:
+				return
+			case <-time.After(c.interval):
+			}
+		}
+	}()
+}
+
+func (c *Controller) Shutdown() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+func (c *Controller) String() string {
+	return "app autoscale"
+}
+
+func (c *Controller) runOnce() {
+	rules, err := ListRules("")
+	if err != nil {
+		log.Errorf("[app autoscale] unable to list rules: %s", err)
+		return
+	}
+	for i := range rules {
+		if !rules[i].Enabled {
+			continue
+		}
+		err = c.scale(&rules[i])
+		if err != nil {
+			log.Errorf("[app autoscale] unable to scale %s (process %q): %s", rules[i].App, rules[i].Process, err)
+		}
+	}
+}
+
+func (c *Controller) scale(r *Rule) error {
+	if time.Since(r.LastScale) < r.Cooldown {
+		return nil
+	}
+	a, err := app.GetByName(r.App)
+	if err != nil {
+		return err
+	}
+	if err = a.CheckFreeze(); err != nil {
+		if _, ok := err.(*app.AppFrozenError); ok {
+			log.Debugf("[app autoscale] skipping %s: %s", a.Name, err)
+			return nil
+		}
+		return err
+	}
+	current, err := a.CountUnits(r.Process)
+	if err != nil {
+		return err
+	}
+	data := EventCustomData{Rule: *r, Before: current}
+	value, err := c.source.UnitAverage(r.App, r.Process, r.Metric)
+	if err == nil {
+		data.Value = value
+		data.After = desiredUnits(r, current, value)
+	} else {
+		if err != ErrNoMetrics {
+			log.Errorf("[app autoscale] unable to get %s metric of %s: %s", r.Metric, r.App, err)
+		}
+		data.Error = err.Error()
+		data.After = clamp(r, current)
+	}
+	if data.After == current {
+		return nil
+	}
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		InternalKind: eventKind,
+		CustomData:   data,
+		Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxApp, a.Name)),
+	})
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[app autoscale] skipping %s, app is locked by another event", a.Name)
+			return nil
+		}
+		return err
+	}
+	claimed, err := claimScale(r, time.Now().UTC())
+	if err != nil || !claimed {
+		evt.Abort()
+		return err
+	}
+	evt.Logf("scaling %s (process %q) from %d to %d units, %s: %.2f (target %.2f)", a.Name, r.Process, current, data.After, r.Metric, value, r.Target)
+	err = a.SetUnits(data.After, r.Process, evt)
+	evt.Done(err)
+	return err
+}
+
+// desiredUnits calculates the number of units needed for the average value
+// of the metric per unit to get close to the target.
+func desiredUnits(r *Rule, current uint, value float64) uint {
+	ratio := value / r.Target
+	if current == 0 || math.Abs(ratio-1) <= tolerance {
+		return clamp(r, current)
+	}
+	return clamp(r, uint(math.Ceil(float64(current)*ratio)))
+}
+
+func clamp(r *Rule, n uint) uint {
+	if n < r.MinUnits {
+		return r.MinUnits
+	}
+	if n > r.MaxUnits {
+		return r.MaxUnits
+	}
+	return n
+}
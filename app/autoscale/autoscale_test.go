@@ -0,0 +1,133 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"gopkg.in/check.v1"
+)
+
+type fakeSource struct {
+	value float64
+	err   error
+}
+
+func (f *fakeSource) UnitAverage(app, process, metric string) (float64, error) {
+	return f.value, f.err
+}
+
+func (s *S) TestDesiredUnits(c *check.C) {
+	r := &Rule{Target: 50, MinUnits: 2, MaxUnits: 10}
+	tests := []struct {
+		current uint
+		value   float64
+		desired uint
+	}{
+		{4, 50, 4},
+		{4, 54, 4},
+		{4, 100, 8},
+		{4, 200, 10},
+		{4, 25, 2},
+		{4, 0, 2},
+		{0, 100, 2},
+		{12, 50, 10},
+	}
+	for _, tt := range tests {
+		c.Check(desiredUnits(r, tt.current, tt.value), check.Equals, tt.desired, check.Commentf("current %d, value %f", tt.current, tt.value))
+	}
+}
+
+func (s *S) TestScaleUp(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 1, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	ctrl := newController(&fakeSource{value: 100}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 4)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:   "autoscale",
+	}, eventtest.HasEvent)
+	dbRule, err := GetRule(a.Name, "web")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbRule.LastScale.IsZero(), check.Equals, false)
+}
+
+func (s *S) TestScaleDown(c *check.C) {
+	a := s.newApp(c, "myapp", 4)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricRequests, Target: 100, MinUnits: 1, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	ctrl := newController(&fakeSource{value: 50}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+}
+
+func (s *S) TestScaleCooldown(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 1, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	claimed, err := claimScale(&r, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	ctrl := newController(&fakeSource{value: 100}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+}
+
+func (s *S) TestScaleClaimedByAnotherInstance(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 1, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	stale, err := GetRule(a.Name, "web")
+	c.Assert(err, check.IsNil)
+	other := *stale
+	claimed, err := claimScale(&other, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	ctrl := newController(&fakeSource{value: 100}, time.Minute)
+	err = ctrl.scale(stale)
+	c.Assert(err, check.IsNil)
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+}
+
+func (s *S) TestScaleFrozenApp(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 1, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: a.Name, Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	ctrl := newController(&fakeSource{value: 100}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+}
+
+func (s *S) TestScaleDisabledRule(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 1, MaxUnits: 10}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	ctrl := newController(&fakeSource{value: 100}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+}
+
+func (s *S) TestScaleWithoutMetricsEnforcesBounds(c *check.C) {
+	a := s.newApp(c, "myapp", 1)
+	r := Rule{App: a.Name, Process: "web", Metric: MetricCPU, Target: 50, MinUnits: 3, MaxUnits: 10, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	ctrl := newController(&fakeSource{err: ErrNoMetrics}, time.Minute)
+	ctrl.runOnce()
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 3)
+}
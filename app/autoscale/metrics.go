@@ -0,0 +1,175 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/metrics"
+	tsuruNet "github.com/tsuru/tsuru/net"
+)
+
+// ErrNoMetrics is returned by metrics sources when there are no values for
+// the units of the app process.
+var ErrNoMetrics = errors.New("no metrics available")
+
+// MetricsSource provides the average value of a metric per unit of an app
+// process.
+type MetricsSource interface {
+	UnitAverage(app, process, metric string) (float64, error)
+}
+
+var defaultQueries = map[string]string{
+	MetricCPU:      `avg(rate(container_cpu_usage_seconds_total{tsuru_app_name="{{.App}}",tsuru_process_name="{{.Process}}"}[1m])) * 100`,
+	MetricRequests: `sum(rate(tsuru_router_requests_total{app="{{.App}}"}[1m])) / count(container_last_seen{tsuru_app_name="{{.App}}",tsuru_process_name="{{.Process}}"})`,
+	MetricNetwork:  `(sum(rate(container_network_receive_bytes_total{tsuru_app_name="{{.App}}",tsuru_process_name="{{.Process}}"}[1m])) + sum(rate(container_network_transmit_bytes_total{tsuru_app_name="{{.App}}",tsuru_process_name="{{.Process}}"}[1m]))) / count(container_last_seen{tsuru_app_name="{{.App}}",tsuru_process_name="{{.Process}}"})`,
+}
+
+// prometheusSource queries a Prometheus server for metrics, using the
+// queries configured in autoscale:prometheus:queries:<metric>. Queries are
+// templates receiving the App and Process names.
+type prometheusSource struct {
+	url     string
+	queries map[string]*template.Template
+	client  *http.Client
+}
+
+func newPrometheusSource(serverURL string) (*prometheusSource, error) {
+	s := &prometheusSource{
+		url:     strings.TrimRight(serverURL, "/"),
+		queries: make(map[string]*template.Template),
+		client:  tsuruNet.Dial5Full60ClientNoKeepAlive,
+	}
+	timeout, _ := config.GetInt("autoscale:prometheus:timeout")
+	if timeout > 0 {
+		s.client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	}
+	for metric, query := range defaultQueries {
+		if configured, _ := config.GetString("autoscale:prometheus:queries:" + metric); configured != "" {
+			query = configured
+		}
+		tpl, err := template.New(metric).Parse(query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid query for metric %q", metric)
+		}
+		s.queries[metric] = tpl
+	}
+	return s, nil
+}
+
+type prometheusResponse struct {
+	Status string
+	Error  string
+	Data   struct {
+		Result []struct {
+			Value []interface{}
+		}
+	}
+}
+
+func (s *prometheusSource) UnitAverage(app, process, metric string) (float64, error) {
+	tpl, ok := s.queries[metric]
+	if !ok {
+		return 0, errors.Errorf("unknown metric %q", metric)
+	}
+	var query bytes.Buffer
+	err := tpl.Execute(&query, map[string]string{"App": app, "Process": process})
+	if err != nil {
+		return 0, err
+	}
+	rsp, err := s.client.Get(fmt.Sprintf("%s/api/v1/query?query=%s", s.url, url.QueryEscape(query.String())))
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	var data prometheusResponse
+	err = json.NewDecoder(rsp.Body).Decode(&data)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse prometheus response (status %d)", rsp.StatusCode)
+	}
+	if data.Status != "success" {
+		return 0, errors.Errorf("prometheus query failed: %s", data.Error)
+	}
+	if len(data.Data.Result) == 0 || len(data.Data.Result[0].Value) != 2 {
+		return 0, ErrNoMetrics
+	}
+	str, _ := data.Data.Result[0].Value[1].(string)
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid value in prometheus response")
+	}
+	return value, nil
+}
+
+// internalSourceWindow is how far back the internal source looks for the
+// latest sample of each unit.
+const internalSourceWindow = 5 * time.Minute
+
+// internalSource reads the CPU and network usage of units from the samples
+// stored by the tsuru metrics collector. The requests metric is not
+// available.
+type internalSource struct {
+	window time.Duration
+}
+
+func (s *internalSource) UnitAverage(app, process, metric string) (float64, error) {
+	if metric != MetricCPU && metric != MetricNetwork {
+		return 0, errors.Errorf("metric %q requires a prometheus server, set autoscale:prometheus:url", metric)
+	}
+	samples, err := metrics.List(app, time.Now().Add(-s.window), time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	previous := make(map[string]metrics.Sample)
+	latest := make(map[string]metrics.Sample)
+	for _, sample := range samples {
+		if sample.Process == process {
+			if last, ok := latest[sample.Unit]; ok {
+				previous[sample.Unit] = last
+			}
+			latest[sample.Unit] = sample
+		}
+	}
+	var total float64
+	var count int
+	for unit, sample := range latest {
+		if metric == MetricCPU {
+			total += sample.CPU
+			count++
+			continue
+		}
+		if prev, ok := previous[unit]; ok {
+			if rate, ok := networkRate(&prev, &sample); ok {
+				total += rate
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, ErrNoMetrics
+	}
+	return total / float64(count), nil
+}
+
+// networkRate returns the bytes received and sent per second by the unit
+// between two samples. Counters that went backwards, as happens when the
+// container is restarted, are ignored.
+func networkRate(prev, cur *metrics.Sample) (float64, bool) {
+	elapsed := cur.Date.Sub(prev.Date).Seconds()
+	if elapsed <= 0 || cur.NetRx < prev.NetRx || cur.NetTx < prev.NetTx {
+		return 0, false
+	}
+	return float64(cur.NetRx-prev.NetRx+cur.NetTx-prev.NetTx) / elapsed, true
+}
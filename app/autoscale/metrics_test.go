@@ -0,0 +1,102 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/metrics"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPrometheusSourceUnitAverage(c *check.C) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, check.Equals, "/api/v1/query")
+		query = r.URL.Query().Get("query")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1480000000.1,"73.5"]}]}}`))
+	}))
+	defer srv.Close()
+	config.Set("autoscale:prometheus:queries:cpu", `cpu{app="{{.App}}",process="{{.Process}}"}`)
+	defer config.Unset("autoscale:prometheus:queries")
+	source, err := newPrometheusSource(srv.URL + "/")
+	c.Assert(err, check.IsNil)
+	value, err := source.UnitAverage("myapp", "web", MetricCPU)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 73.5)
+	c.Assert(query, check.Equals, `cpu{app="myapp",process="web"}`)
+}
+
+func (s *S) TestPrometheusSourceNoMetrics(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer srv.Close()
+	source, err := newPrometheusSource(srv.URL)
+	c.Assert(err, check.IsNil)
+	_, err = source.UnitAverage("myapp", "web", MetricRequests)
+	c.Assert(err, check.Equals, ErrNoMetrics)
+}
+
+func (s *S) TestPrometheusSourceError(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+	}))
+	defer srv.Close()
+	source, err := newPrometheusSource(srv.URL)
+	c.Assert(err, check.IsNil)
+	_, err = source.UnitAverage("myapp", "web", MetricCPU)
+	c.Assert(err, check.ErrorMatches, "prometheus query failed: parse error")
+}
+
+func (s *S) TestInternalSourceUnitAverage(c *check.C) {
+	now := time.Now().UTC()
+	samples := []metrics.Sample{
+		{App: "myapp", Unit: "unit1", Process: "web", Date: now.Add(-2 * time.Minute), CPU: 90},
+		{App: "myapp", Unit: "unit1", Process: "web", Date: now.Add(-time.Minute), CPU: 30},
+		{App: "myapp", Unit: "unit2", Process: "web", Date: now.Add(-time.Minute), CPU: 50},
+		{App: "myapp", Unit: "unit3", Process: "worker", Date: now.Add(-time.Minute), CPU: 100},
+		{App: "myapp", Unit: "unit4", Process: "web", Date: now.Add(-time.Hour), CPU: 100},
+	}
+	for _, sample := range samples {
+		err := s.conn.AppMetrics().Insert(sample)
+		c.Assert(err, check.IsNil)
+	}
+	source := &internalSource{window: internalSourceWindow}
+	value, err := source.UnitAverage("myapp", "web", MetricCPU)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 40.0)
+	_, err = source.UnitAverage("otherapp", "web", MetricCPU)
+	c.Assert(err, check.Equals, ErrNoMetrics)
+	_, err = source.UnitAverage("myapp", "web", MetricRequests)
+	c.Assert(err, check.ErrorMatches, `metric "requests" requires a prometheus server.*`)
+}
+
+func (s *S) TestInternalSourceUnitAverageNetwork(c *check.C) {
+	now := time.Now().UTC()
+	samples := []metrics.Sample{
+		{App: "myapp", Unit: "unit1", Process: "web", Date: now.Add(-2 * time.Minute), NetRx: 1000, NetTx: 2000},
+		{App: "myapp", Unit: "unit1", Process: "web", Date: now.Add(-time.Minute), NetRx: 7000, NetTx: 8000},
+		{App: "myapp", Unit: "unit2", Process: "web", Date: now.Add(-2 * time.Minute), NetRx: 0, NetTx: 0},
+		{App: "myapp", Unit: "unit2", Process: "web", Date: now.Add(-time.Minute), NetRx: 3000, NetTx: 3000},
+		{App: "myapp", Unit: "unit3", Process: "web", Date: now.Add(-2 * time.Minute), NetRx: 5000, NetTx: 5000},
+		{App: "myapp", Unit: "unit3", Process: "web", Date: now.Add(-time.Minute), NetRx: 10, NetTx: 10},
+		{App: "myapp", Unit: "unit4", Process: "web", Date: now.Add(-time.Minute), NetRx: 9000, NetTx: 9000},
+	}
+	for _, sample := range samples {
+		err := s.conn.AppMetrics().Insert(sample)
+		c.Assert(err, check.IsNil)
+	}
+	source := &internalSource{window: internalSourceWindow}
+	value, err := source.UnitAverage("myapp", "web", MetricNetwork)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 150.0)
+	_, err = source.UnitAverage("myapp", "worker", MetricNetwork)
+	c.Assert(err, check.Equals, ErrNoMetrics)
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	MetricCPU      = "cpu"
+	MetricRequests = "requests"
+	MetricNetwork  = "network"
+
+	defaultCooldown = 5 * time.Minute
+)
+
+var ErrRuleNotFound = errors.New("autoscale rule not found")
+
+// processNameRegexp matches the process names accepted in rules and
+// schedules. They're used in the Prometheus queries, so quotes and other
+// special characters are refused.
+var processNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]*$`)
+
+func validateProcessName(process string) error {
+	if !processNameRegexp.MatchString(process) {
+		return &tsuruErrors.ValidationError{Message: "invalid process name: " + process}
+	}
+	return nil
+}
+
+type ruleID struct {
+	App     string
+	Process string
+}
+
+// Rule defines how the units of an app process are scaled. The number of
+// units is kept between MinUnits and MaxUnits, so that the average value of
+// the metric per unit is close to Target, e.g. 70 for 70% of CPU usage, 100
+// for 100 requests per second per unit or 1048576 for 1MB per second of
+// network traffic, received and sent, per unit.
+type Rule struct {
+	ID        ruleID        `bson:"_id" json:"-"`
+	App       string        `bson:"-" json:"app"`
+	Process   string        `bson:"-" json:"process"`
+	Metric    string        `json:"metric"`
+	Target    float64       `json:"target"`
+	MinUnits  uint          `json:"minUnits"`
+	MaxUnits  uint          `json:"maxUnits"`
+	Cooldown  time.Duration `json:"cooldown"`
+	Enabled   bool          `json:"enabled"`
+	LastScale time.Time     `json:"lastScale"`
+}
+
+func (r *Rule) validate() error {
+	if r.App == "" {
+		return &tsuruErrors.ValidationError{Message: "app is required"}
+	}
+	if err := validateProcessName(r.Process); err != nil {
+		return err
+	}
+	if r.Metric != MetricCPU && r.Metric != MetricRequests && r.Metric != MetricNetwork {
+		return &tsuruErrors.ValidationError{Message: "metric must be cpu, requests or network"}
+	}
+	if r.Target <= 0 {
+		return &tsuruErrors.ValidationError{Message: "target must be greater than zero"}
+	}
+	if r.MinUnits == 0 {
+		return &tsuruErrors.ValidationError{Message: "the minimum number of units must be greater than zero"}
+	}
+	if r.MaxUnits < r.MinUnits {
+		return &tsuruErrors.ValidationError{Message: "the maximum number of units must be greater than or equal to the minimum"}
+	}
+	if r.Cooldown < 0 {
+		return &tsuruErrors.ValidationError{Message: "cooldown must not be negative"}
+	}
+	if r.Cooldown == 0 {
+		r.Cooldown = defaultCooldown
+	}
+	return nil
+}
+
+func (r *Rule) fillID() {
+	r.App = r.ID.App
+	r.Process = r.ID.Process
+}
+
+// SetRule creates or replaces the autoscaling rule of an app process.
+func SetRule(r *Rule) error {
+	err := r.validate()
+	if err != nil {
+		return err
+	}
+	r.ID = ruleID{App: r.App, Process: r.Process}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.AppAutoScaleRules().UpsertId(r.ID, r)
+	return err
+}
+
+// GetRule returns the autoscaling rule of an app process.
+func GetRule(app, process string) (*Rule, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var r Rule
+	err = conn.AppAutoScaleRules().FindId(ruleID{App: app, Process: process}).One(&r)
+	if err == mgo.ErrNotFound {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.fillID()
+	return &r, nil
+}
+
+// ListRules returns the autoscaling rules of the given app, or the rules of
+// every app if app is empty.
+func ListRules(app string) ([]Rule, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var query bson.M
+	if app != "" {
+		query = bson.M{"_id.app": app}
+	}
+	var rules []Rule
+	err = conn.AppAutoScaleRules().Find(query).Sort("_id.app", "_id.process").All(&rules)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		rules[i].fillID()
+	}
+	return rules, nil
+}
+
+// RemoveRule removes the autoscaling rule of an app process.
+func RemoveRule(app, process string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.AppAutoScaleRules().RemoveId(ruleID{App: app, Process: process})
+	if err == mgo.ErrNotFound {
+		return ErrRuleNotFound
+	}
+	return err
+}
+
+// claimScale records t as the last scale of the rule, returning false when
+// another tsuru API instance has already scaled the app process since the
+// rule was read.
+func claimScale(r *Rule, t time.Time) (bool, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	err = conn.AppAutoScaleRules().Update(
+		bson.M{"_id": r.ID, "lastscale": r.LastScale},
+		bson.M{"$set": bson.M{"lastscale": t}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.LastScale = t
+	return true, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSetRule(c *check.C) {
+	r := Rule{App: "myapp", Process: "web", Metric: MetricCPU, Target: 70, MinUnits: 1, MaxUnits: 5, Enabled: true}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	dbRule, err := GetRule("myapp", "web")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbRule.App, check.Equals, "myapp")
+	c.Assert(dbRule.Process, check.Equals, "web")
+	c.Assert(dbRule.Cooldown, check.Equals, defaultCooldown)
+	r.MaxUnits = 10
+	err = SetRule(&r)
+	c.Assert(err, check.IsNil)
+	rules, err := ListRules("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 1)
+	c.Assert(rules[0].MaxUnits, check.Equals, uint(10))
+}
+
+func (s *S) TestSetRuleValidation(c *check.C) {
+	tests := []struct {
+		rule Rule
+		msg  string
+	}{
+		{Rule{Metric: MetricCPU, Target: 1, MinUnits: 1, MaxUnits: 1}, "app is required"},
+		{Rule{App: "a", Process: `web"}`, Metric: MetricCPU, Target: 1, MinUnits: 1, MaxUnits: 1}, `invalid process name: web"}`},
+		{Rule{App: "a", Metric: "memory", Target: 1, MinUnits: 1, MaxUnits: 1}, "metric must be cpu, requests or network"},
+		{Rule{App: "a", Metric: MetricCPU, MinUnits: 1, MaxUnits: 1}, "target must be greater than zero"},
+		{Rule{App: "a", Metric: MetricCPU, Target: 1, MaxUnits: 1}, "the minimum number of units must be greater than zero"},
+		{Rule{App: "a", Metric: MetricCPU, Target: 1, MinUnits: 2, MaxUnits: 1}, "the maximum number of units must be greater than or equal to the minimum"},
+		{Rule{App: "a", Metric: MetricCPU, Target: 1, MinUnits: 1, MaxUnits: 1, Cooldown: -time.Second}, "cooldown must not be negative"},
+	}
+	for _, tt := range tests {
+		err := SetRule(&tt.rule)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+}
+
+func (s *S) TestListRules(c *check.C) {
+	for _, r := range []Rule{
+		{App: "b", Process: "web", Metric: MetricCPU, Target: 1, MinUnits: 1, MaxUnits: 2},
+		{App: "a", Process: "worker", Metric: MetricCPU, Target: 1, MinUnits: 1, MaxUnits: 2},
+		{App: "a", Process: "web", Metric: MetricRequests, Target: 1, MinUnits: 1, MaxUnits: 2},
+	} {
+		err := SetRule(&r)
+		c.Assert(err, check.IsNil)
+	}
+	rules, err := ListRules("")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 3)
+	c.Assert([]string{rules[0].App + "/" + rules[0].Process, rules[1].App + "/" + rules[1].Process, rules[2].App + "/" + rules[2].Process},
+		check.DeepEquals, []string{"a/web", "a/worker", "b/web"})
+	rules, err = ListRules("b")
+	c.Assert(err, check.IsNil)
+	c.Assert(rules, check.HasLen, 1)
+}
+
+func (s *S) TestRemoveRule(c *check.C) {
+	r := Rule{App: "myapp", Metric: MetricCPU, Target: 70, MinUnits: 1, MaxUnits: 5}
+	err := SetRule(&r)
+	c.Assert(err, check.IsNil)
+	err = RemoveRule("myapp", "")
+	c.Assert(err, check.IsNil)
+	_, err = GetRule("myapp", "")
+	c.Assert(err, check.Equals, ErrRuleNotFound)
+	err = RemoveRule("myapp", "")
+	c.Assert(err, check.Equals, ErrRuleNotFound)
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/cron"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const scheduleEventKind = "scheduled-scale"
+
+var ErrScheduleNotFound = errors.New("scaling schedule not found")
+
+// Schedule sets the number of units of an app process at the times defined
+// by a cron expression, e.g. "0 8 * * mon-fri", evaluated in the given
+// timezone.
+type Schedule struct {
+	ID       bson.ObjectId `bson:"_id" json:"id"`
+	App      string        `json:"app"`
+	Process  string        `json:"process"`
+	Cron     string        `json:"cron"`
+	Timezone string        `json:"timezone"`
+	Units    uint          `json:"units"`
+	LastRun  time.Time     `json:"lastRun"`
+	NextRun  time.Time     `json:"nextRun"`
+}
+
+func (s *Schedule) next(after time.Time) (time.Time, error) {
+	return cron.Next(s.Cron, s.Timezone, after)
+}
+
+// AddSchedule validates and stores a new scaling schedule.
+func AddSchedule(s *Schedule) error {
+	if s.App == "" {
+		return &tsuruErrors.ValidationError{Message: "app is required"}
+	}
+	if err := validateProcessName(s.Process); err != nil {
+		return err
+	}
+	if s.Units == 0 {
+		return &tsuruErrors.ValidationError{Message: "the number of units must be greater than zero"}
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	var err error
+	s.NextRun, err = s.next(time.Now())
+	if err != nil {
+		return err
+	}
+	s.ID = bson.NewObjectId()
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.AppScheduledScales().Insert(s)
+}
+
+// ListSchedules returns the scaling schedules of the given app, or the
+// schedules of every app if app is empty.
+func ListSchedules(app string) ([]Schedule, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var query bson.M
+	if app != "" {
+		query = bson.M{"app": app}
+	}
+	var schedules []Schedule
+	err = conn.AppScheduledScales().Find(query).Sort("app", "nextrun").All(&schedules)
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// RemoveSchedule removes a scaling schedule of the app.
+func RemoveSchedule(app, id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return ErrScheduleNotFound
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.AppScheduledScales().Remove(bson.M{"_id": bson.ObjectIdHex(id), "app": app})
+	if err == mgo.ErrNotFound {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+// StartScheduler starts running scaling schedules in background.
+func StartScheduler() *cron.Runner {
+	r := cron.NewRunner("app scheduled scaling", 30*time.Second)
+	r.Start(runSchedules)
+	shutdown.Register(r)
+	return r
+}
+
+func runSchedules(now time.Time) {
+	conn, err := db.Conn()
+	if err != nil {
+		log.Errorf("[scheduled scale] unable to connect to the database: %s", err)
+		return
+	}
+	var schedules []Schedule
+	err = conn.AppScheduledScales().Find(bson.M{"nextrun": bson.M{"$lte": now}}).All(&schedules)
+	conn.Close()
+	if err != nil {
+		log.Errorf("[scheduled scale] unable to list schedules: %s", err)
+		return
+	}
+	for i := range schedules {
+		err = runSchedule(&schedules[i], now)
+		if err != nil {
+			log.Errorf("[scheduled scale] unable to scale %s (process %q) to %d units: %s", schedules[i].App, schedules[i].Process, schedules[i].Units, err)
+		}
+	}
+}
+
+func runSchedule(s *Schedule, now time.Time) error {
+	a, err := app.GetByName(s.App)
+	if err != nil {
+		return err
+	}
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		InternalKind: scheduleEventKind,
+		CustomData:   s,
+		Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxApp, a.Name)),
+	})
+	if err != nil {
+		if _, ok := err.(event.ErrEventLocked); ok {
+			log.Debugf("[scheduled scale] app %s is locked by another event, trying again later", a.Name)
+			return nil
+		}
+		return err
+	}
+	claimed, err := claimSchedule(s, now)
+	if err != nil || !claimed {
+		evt.Abort()
+		return err
+	}
+	err = a.CheckFreeze()
+	if err == nil {
+		err = a.SetUnits(s.Units, s.Process, evt)
+	}
+	evt.Done(err)
+	return err
+}
+
+// claimSchedule moves the next run of the schedule forward, returning false
+// when another tsuru API instance has already claimed this run.
+func claimSchedule(s *Schedule, now time.Time) (bool, error) {
+	next, err := s.next(now)
+	if err != nil {
+		return false, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	claimed, err := cron.Claim(conn.AppScheduledScales(), s.ID, s.NextRun, now, next)
+	if claimed {
+		s.LastRun, s.NextRun = now, next
+	}
+	return claimed, err
+}
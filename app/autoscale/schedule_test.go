@@ -0,0 +1,141 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAddSchedule(c *check.C) {
+	sched := Schedule{App: "myapp", Process: "web", Cron: "0 8 * * mon-fri", Timezone: "America/Sao_Paulo", Units: 10}
+	err := AddSchedule(&sched)
+	c.Assert(err, check.IsNil)
+	c.Assert(sched.ID.Valid(), check.Equals, true)
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	c.Assert(err, check.IsNil)
+	local := sched.NextRun.In(loc)
+	c.Assert(local.Hour(), check.Equals, 8)
+	c.Assert(local.Minute(), check.Equals, 0)
+	c.Assert(local.Weekday() != time.Saturday && local.Weekday() != time.Sunday, check.Equals, true)
+	schedules, err := ListSchedules("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 1)
+	c.Assert(schedules[0].ID, check.Equals, sched.ID)
+	c.Assert(schedules[0].Units, check.Equals, uint(10))
+}
+
+func (s *S) TestAddScheduleDefaultTimezone(c *check.C) {
+	sched := Schedule{App: "myapp", Cron: "@daily", Units: 2}
+	err := AddSchedule(&sched)
+	c.Assert(err, check.IsNil)
+	c.Assert(sched.Timezone, check.Equals, "UTC")
+	c.Assert(sched.NextRun.Hour(), check.Equals, 0)
+}
+
+func (s *S) TestAddScheduleValidation(c *check.C) {
+	tests := []struct {
+		sched Schedule
+		msg   string
+	}{
+		{Schedule{Cron: "@daily", Units: 1}, "app is required"},
+		{Schedule{App: "a", Process: "web worker", Cron: "@daily", Units: 1}, "invalid process name: web worker"},
+		{Schedule{App: "a", Cron: "@daily"}, "the number of units must be greater than zero"},
+		{Schedule{App: "a", Cron: "0 8 * *", Units: 1}, `invalid cron expression .*`},
+		{Schedule{App: "a", Cron: "@daily", Timezone: "Mars/Olympus", Units: 1}, "invalid timezone: Mars/Olympus"},
+		{Schedule{App: "a", Cron: "0 0 30 2 *", Units: 1}, "the schedule 0 0 30 2 \\* never runs"},
+	}
+	for _, tt := range tests {
+		err := AddSchedule(&tt.sched)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+}
+
+func (s *S) TestRemoveSchedule(c *check.C) {
+	sched := Schedule{App: "myapp", Cron: "@daily", Units: 2}
+	err := AddSchedule(&sched)
+	c.Assert(err, check.IsNil)
+	err = RemoveSchedule("otherapp", sched.ID.Hex())
+	c.Assert(err, check.Equals, ErrScheduleNotFound)
+	err = RemoveSchedule("myapp", "invalid")
+	c.Assert(err, check.Equals, ErrScheduleNotFound)
+	err = RemoveSchedule("myapp", sched.ID.Hex())
+	c.Assert(err, check.IsNil)
+	schedules, err := ListSchedules("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 0)
+}
+
+func (s *S) TestRunSchedules(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	due := Schedule{App: a.Name, Process: "web", Cron: "0 8 * * *", Units: 5}
+	err := AddSchedule(&due)
+	c.Assert(err, check.IsNil)
+	notDue := Schedule{App: a.Name, Process: "web", Cron: "0 22 * * *", Units: 1}
+	err = AddSchedule(&notDue)
+	c.Assert(err, check.IsNil)
+	now := due.NextRun.Add(time.Minute)
+	err = s.conn.AppScheduledScales().UpdateId(notDue.ID, map[string]interface{}{"$set": map[string]interface{}{"nextrun": now.Add(time.Hour)}})
+	c.Assert(err, check.IsNil)
+	runSchedules(now)
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 5)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:   "scheduled-scale",
+	}, eventtest.HasEvent)
+	schedules, err := ListSchedules(a.Name)
+	c.Assert(err, check.IsNil)
+	for _, sched := range schedules {
+		if sched.ID == due.ID {
+			c.Assert(sched.LastRun.Equal(now), check.Equals, true)
+			c.Assert(sched.NextRun.After(now), check.Equals, true)
+		} else {
+			c.Assert(sched.LastRun.IsZero(), check.Equals, true)
+		}
+	}
+	runSchedules(now)
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 5)
+}
+
+func (s *S) TestRunSchedulesFrozenApp(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	sched := Schedule{App: a.Name, Process: "web", Cron: "0 8 * * *", Units: 5}
+	err := AddSchedule(&sched)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: a.Name, Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	now := sched.NextRun.Add(time.Minute)
+	runSchedules(now)
+	c.Assert(s.provisioner.GetUnits(a), check.HasLen, 2)
+	c.Assert(eventtest.EventDesc{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:         "scheduled-scale",
+		ErrorMatches: `app "myapp" is frozen .*`,
+	}, eventtest.HasEvent)
+	schedules, err := ListSchedules(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(schedules, check.HasLen, 1)
+	c.Assert(schedules[0].NextRun.After(now), check.Equals, true)
+}
+
+func (s *S) TestClaimScheduleAlreadyClaimed(c *check.C) {
+	sched := Schedule{App: "myapp", Cron: "@hourly", Units: 2}
+	err := AddSchedule(&sched)
+	c.Assert(err, check.IsNil)
+	stale := sched
+	now := sched.NextRun
+	claimed, err := claimSchedule(&sched, now)
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	claimed, err = claimSchedule(&stale, now)
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, false)
+}
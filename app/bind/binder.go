@@ -14,6 +14,9 @@ type EnvVar struct {
 	Value        string `json:"value"`
 	Public       bool   `json:"public"`
 	InstanceName string `json:"-"`
+	// Secret variables are private and stored encrypted, Value holds the
+	// encrypted value.
+	Secret bool `json:"secret,omitempty" bson:",omitempty"`
 }
 
 // Unit represents an application unit to be used in binds.
@@ -57,12 +60,16 @@ type SetEnvApp struct {
 	Envs          []EnvVar
 	PublicOnly    bool
 	ShouldRestart bool
+	// Owner is who requested the change, recorded in the env history.
+	Owner string
 }
 
 type UnsetEnvApp struct {
 	VariableNames []string
 	PublicOnly    bool
 	ShouldRestart bool
+	// Owner is who requested the change, recorded in the env history.
+	Owner string
 }
 
 type InstanceApp struct {
@@ -0,0 +1,137 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/service"
+)
+
+// internalEnvs are set by tsuru for each app and are never copied between
+// apps.
+var internalEnvs = map[string]bool{
+	"TSURU_APPNAME":     true,
+	"TSURU_APPDIR":      true,
+	"TSURU_APP_TOKEN":   true,
+	TsuruServicesEnvVar: true,
+}
+
+// CloneOptions are the options used to clone an app. ServiceInstances are the
+// instances bound to the new app, usually the ones bound to the source app;
+// callers must check the user is allowed to bind each of them.
+type CloneOptions struct {
+	Name             string
+	ExcludeSecrets   bool
+	User             *auth.User
+	Writer           io.Writer
+	ServiceInstances []service.ServiceInstance
+}
+
+// Clone creates a new app with the platform, plan, pool, teams, router
+// options and environment variables of the app, binding it to the service
+// instances in the options. The new app is returned even if copying its
+// settings fails after it's created, so callers can report what's left to do.
+func (app *App) Clone(opts CloneOptions) (*App, error) {
+	newApp := &App{
+		Name:        opts.Name,
+		Platform:    app.Platform,
+		Plan:        Plan{Name: app.Plan.Name},
+		Pool:        app.Pool,
+		TeamOwner:   app.TeamOwner,
+		Description: app.Description,
+		RouterOpts:  app.RouterOpts,
+		Tags:        app.Tags,
+	}
+	err := CreateApp(newApp, opts.User)
+	if err != nil {
+		return nil, err
+	}
+	w := opts.Writer
+	if w == nil {
+		w = ioutil.Discard
+	}
+	for _, teamName := range app.Teams {
+		if teamName == newApp.TeamOwner {
+			continue
+		}
+		team, err := auth.GetTeam(teamName)
+		if err != nil {
+			return newApp, errors.Wrapf(err, "unable to grant access to team %s", teamName)
+		}
+		err = newApp.Grant(team)
+		if err != nil && err != ErrAlreadyHaveAccess {
+			return newApp, errors.Wrapf(err, "unable to grant access to team %s", teamName)
+		}
+	}
+	appEnvs := app.Envs()
+	var names []string
+	for name, env := range appEnvs {
+		if internalEnvs[name] || env.InstanceName != "" {
+			continue
+		}
+		if env.Secret && opts.ExcludeSecrets {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	envs := make([]bind.EnvVar, len(names))
+	for i, name := range names {
+		envs[i] = appEnvs[name]
+	}
+	if len(envs) > 0 {
+		fmt.Fprintf(w, "---- Copying %d environment variables ----\n", len(envs))
+		err = newApp.SetEnvs(bind.SetEnvApp{
+			Envs:          envs,
+			PublicOnly:    false,
+			ShouldRestart: false,
+			Owner:         opts.User.Email,
+		}, nil)
+		if err != nil {
+			return newApp, errors.Wrap(err, "unable to copy environment variables")
+		}
+	}
+	instances := opts.ServiceInstances
+	for i := range instances {
+		fmt.Fprintf(w, "---- Binding service instance %q ----\n", instances[i].Name)
+		err = instances[i].BindApp(newApp, false, w)
+		if err != nil {
+			return newApp, errors.Wrapf(err, "unable to bind service instance %s", instances[i].Name)
+		}
+	}
+	return newApp, nil
+}
+
+// CopyUnitsFrom sets the number of units of each process of the app to the
+// number of units of the same process in src.
+func (app *App) CopyUnitsFrom(src *App, w io.Writer) error {
+	units, err := src.Units()
+	if err != nil {
+		return err
+	}
+	counts := map[string]uint{}
+	var processes []string
+	for _, u := range units {
+		if _, ok := counts[u.ProcessName]; !ok {
+			processes = append(processes, u.ProcessName)
+		}
+		counts[u.ProcessName]++
+	}
+	sort.Strings(processes)
+	for _, process := range processes {
+		err = app.SetUnits(counts[process], process, w)
+		if err != nil {
+			return errors.Wrapf(err, "unable to set units of process %s", process)
+		}
+	}
+	return nil
+}
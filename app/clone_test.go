@@ -0,0 +1,96 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/quota"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestClone(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	src := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name, Description: "my app"}
+	err := CreateApp(&src, s.user)
+	c.Assert(err, check.IsNil)
+	err = src.SetEnvs(bind.SetEnvApp{
+		Envs: []bind.EnvVar{
+			{Name: "DATABASE_HOST", Value: "localhost", Public: true},
+			{Name: "DATABASE_PASSWORD", Value: "secret", Public: true, Secret: true},
+		},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	clone, err := src.Clone(CloneOptions{Name: "myapp-clone", User: s.user})
+	c.Assert(err, check.IsNil)
+	c.Assert(clone.Name, check.Equals, "myapp-clone")
+	stored, err := GetByName("myapp-clone")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Platform, check.Equals, "python")
+	c.Assert(stored.TeamOwner, check.Equals, s.team.Name)
+	c.Assert(stored.Description, check.Equals, "my app")
+	envs := stored.Envs()
+	c.Assert(envs["DATABASE_HOST"].Value, check.Equals, "localhost")
+	c.Assert(envs["DATABASE_PASSWORD"].Value, check.Equals, "secret")
+	c.Assert(envs["DATABASE_PASSWORD"].Secret, check.Equals, true)
+	c.Assert(envs["TSURU_APPNAME"].Value, check.Equals, "myapp-clone")
+}
+
+func (s *S) TestCloneExcludeSecrets(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	src := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&src, s.user)
+	c.Assert(err, check.IsNil)
+	err = src.SetEnvs(bind.SetEnvApp{
+		Envs: []bind.EnvVar{
+			{Name: "DATABASE_HOST", Value: "localhost", Public: true},
+			{Name: "DATABASE_PASSWORD", Value: "secret", Public: true, Secret: true},
+		},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	_, err = src.Clone(CloneOptions{Name: "myapp-clone", ExcludeSecrets: true, User: s.user})
+	c.Assert(err, check.IsNil)
+	stored, err := GetByName("myapp-clone")
+	c.Assert(err, check.IsNil)
+	envs := stored.Envs()
+	c.Assert(envs["DATABASE_HOST"].Value, check.Equals, "localhost")
+	_, ok := envs["DATABASE_PASSWORD"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestCloneAppAlreadyExists(c *check.C) {
+	src := App{Name: "myapp", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&src, s.user)
+	c.Assert(err, check.IsNil)
+	clone, err := src.Clone(CloneOptions{Name: "myapp", User: s.user})
+	c.Assert(clone, check.IsNil)
+	e, ok := err.(*AppCreationError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Err, check.Equals, ErrAppAlreadyExists)
+}
+
+func (s *S) TestCopyUnitsFrom(c *check.C) {
+	src := App{Name: "myapp", Platform: "python", Quota: quota.Unlimited, TeamOwner: s.team.Name}
+	err := CreateApp(&src, s.user)
+	c.Assert(err, check.IsNil)
+	err = src.AddUnits(3, "web", nil)
+	c.Assert(err, check.IsNil)
+	err = src.AddUnits(1, "worker", nil)
+	c.Assert(err, check.IsNil)
+	dst := App{Name: "myapp-clone", Platform: "python", Quota: quota.Unlimited, TeamOwner: s.team.Name}
+	err = CreateApp(&dst, s.user)
+	c.Assert(err, check.IsNil)
+	err = dst.CopyUnitsFrom(&src, nil)
+	c.Assert(err, check.IsNil)
+	units, err := dst.Units()
+	c.Assert(err, check.IsNil)
+	counts := map[string]int{}
+	for _, u := range units {
+		counts[u.ProcessName]++
+	}
+	c.Assert(counts, check.DeepEquals, map[string]int{"web": 3, "worker": 1})
+}
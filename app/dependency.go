@@ -0,0 +1,206 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrDependencyNotFound = errors.New("dependency not found in app")
+
+// DependencyCycleError is returned when adding a dependency would make an app
+// depend on itself, directly or through other apps.
+type DependencyCycleError struct {
+	Apps []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(e.Apps, " -> "))
+}
+
+// AddDependency makes the app depend on the app with the given name. Apps are
+// restarted after their dependencies in bulk operations.
+func (app *App) AddDependency(name string) error {
+	if name == app.Name {
+		return &tsuruErrors.ValidationError{Message: "an app cannot depend on itself"}
+	}
+	for _, dep := range app.Dependencies {
+		if dep == name {
+			return nil
+		}
+	}
+	if _, err := GetByName(name); err != nil {
+		return err
+	}
+	path, err := dependencyPath(name, app.Name)
+	if err != nil {
+		return err
+	}
+	if path != nil {
+		return &DependencyCycleError{Apps: append([]string{app.Name}, path...)}
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$addToSet": bson.M{"dependencies": name}})
+	if err == mgo.ErrNotFound {
+		return ErrAppNotFound
+	}
+	if err != nil {
+		return err
+	}
+	app.Dependencies = append(app.Dependencies, name)
+	return nil
+}
+
+// RemoveDependency removes the app with the given name from the app
+// dependencies.
+func (app *App) RemoveDependency(name string) error {
+	var deps []string
+	for _, dep := range app.Dependencies {
+		if dep != name {
+			deps = append(deps, dep)
+		}
+	}
+	if len(deps) == len(app.Dependencies) {
+		return ErrDependencyNotFound
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$pull": bson.M{"dependencies": name}})
+	if err == mgo.ErrNotFound {
+		return ErrAppNotFound
+	}
+	if err != nil {
+		return err
+	}
+	app.Dependencies = deps
+	return nil
+}
+
+// dependencyPath returns the chain of apps leading from the app named from to
+// the app named to following the stored dependencies, or nil when there's
+// none.
+func dependencyPath(from, to string) ([]string, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	parent := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			var path []string
+			for name := current; name != ""; name = parent[name] {
+				path = append([]string{name}, path...)
+			}
+			return path, nil
+		}
+		var a App
+		err = conn.Apps().Find(bson.M{"name": current}).Select(bson.M{"dependencies": 1}).One(&a)
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range a.Dependencies {
+			if _, seen := parent[dep]; !seen {
+				parent[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// SortByDependencies returns the apps ordered so that every app comes after
+// the apps it depends on. Dependencies outside of the given list are ignored
+// and apps without dependencies between them keep their relative order.
+func SortByDependencies(apps []App) ([]App, error) {
+	index := make(map[string]int, len(apps))
+	for i, a := range apps {
+		index[a.Name] = i
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(apps))
+	sorted := make([]App, 0, len(apps))
+	var stack []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for j, name := range stack {
+				if name == apps[i].Name {
+					start = j
+					break
+				}
+			}
+			cycle := append(append([]string{}, stack[start:]...), apps[i].Name)
+			return &DependencyCycleError{Apps: cycle}
+		}
+		state[i] = visiting
+		stack = append(stack, apps[i].Name)
+		for _, dep := range apps[i].Dependencies {
+			if j, ok := index[dep]; ok {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		sorted = append(sorted, apps[i])
+		return nil
+	}
+	for i := range apps {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// CheckServicesHealth returns an error when any of the service instances bound
+// to the app reports it's not ready, so the app isn't restarted against a
+// service that's still coming back.
+func (app *App) CheckServicesHealth(requestID string) error {
+	instances, err := app.serviceInstances()
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		status, err := instance.Status(requestID)
+		if err != nil {
+			return errors.Wrapf(err, "unable to check status of service instance %q", instance.Name)
+		}
+		if status == "down" || status == "pending" {
+			return errors.Errorf("service instance %q is %s", instance.Name, status)
+		}
+	}
+	return nil
+}
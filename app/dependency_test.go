@@ -0,0 +1,102 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAddDependency(c *check.C) {
+	a := App{Name: "web", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	dep := App{Name: "db", Platform: "python", TeamOwner: s.team.Name}
+	err = CreateApp(&dep, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddDependency(dep.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Dependencies, check.DeepEquals, []string{"db"})
+	err = a.AddDependency(dep.Name)
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Dependencies, check.DeepEquals, []string{"db"})
+}
+
+func (s *S) TestAddDependencyNotFound(c *check.C) {
+	a := App{Name: "web", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddDependency("db")
+	c.Assert(err, check.Equals, ErrAppNotFound)
+	err = a.AddDependency("web")
+	c.Assert(err, check.ErrorMatches, "an app cannot depend on itself")
+}
+
+func (s *S) TestAddDependencyCycle(c *check.C) {
+	names := []string{"web", "worker", "db"}
+	apps := make([]App, len(names))
+	for i, name := range names {
+		apps[i] = App{Name: name, Platform: "python", TeamOwner: s.team.Name}
+		err := CreateApp(&apps[i], s.user)
+		c.Assert(err, check.IsNil)
+	}
+	err := apps[0].AddDependency("worker")
+	c.Assert(err, check.IsNil)
+	err = apps[1].AddDependency("db")
+	c.Assert(err, check.IsNil)
+	err = apps[2].AddDependency("web")
+	c.Assert(err, check.FitsTypeOf, &DependencyCycleError{})
+	c.Assert(err, check.ErrorMatches, "dependency cycle detected: db -> web -> worker -> db")
+	dbApp, err := GetByName("db")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Dependencies, check.HasLen, 0)
+}
+
+func (s *S) TestRemoveDependency(c *check.C) {
+	a := App{Name: "web", Platform: "python", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	dep := App{Name: "db", Platform: "python", TeamOwner: s.team.Name}
+	err = CreateApp(&dep, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddDependency(dep.Name)
+	c.Assert(err, check.IsNil)
+	err = a.RemoveDependency(dep.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Dependencies, check.HasLen, 0)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Dependencies, check.HasLen, 0)
+	err = a.RemoveDependency(dep.Name)
+	c.Assert(err, check.Equals, ErrDependencyNotFound)
+}
+
+func (s *S) TestSortByDependencies(c *check.C) {
+	apps := []App{
+		{Name: "web", Dependencies: []string{"api", "cache"}},
+		{Name: "api", Dependencies: []string{"db", "external"}},
+		{Name: "db"},
+		{Name: "other"},
+	}
+	sorted, err := SortByDependencies(apps)
+	c.Assert(err, check.IsNil)
+	var names []string
+	for _, a := range sorted {
+		names = append(names, a.Name)
+	}
+	c.Assert(names, check.DeepEquals, []string{"db", "api", "web", "other"})
+}
+
+func (s *S) TestSortByDependenciesCycle(c *check.C) {
+	apps := []App{
+		{Name: "web", Dependencies: []string{"api"}},
+		{Name: "api", Dependencies: []string{"db"}},
+		{Name: "db", Dependencies: []string{"api"}},
+	}
+	_, err := SortByDependencies(apps)
+	c.Assert(err, check.FitsTypeOf, &DependencyCycleError{})
+	c.Assert(err, check.ErrorMatches, "dependency cycle detected: api -> db -> api")
+}
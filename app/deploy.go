@@ -18,6 +18,7 @@ import (
 	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/prom"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/router/rebuild"
 	"github.com/tsuru/tsuru/set"
@@ -38,6 +39,13 @@ const (
 
 var reImageVersion = regexp.MustCompile("v[0-9]+$")
 
+var deployDuration = prom.NewHistogramVec(
+	"tsuru_deploy_duration_seconds",
+	"Duration of app deploys in the provisioner, by kind and result.",
+	[]float64{10, 30, 60, 120, 300, 600, 1200, 1800},
+	"kind", "result",
+)
+
 type DeployData struct {
 	ID          bson.ObjectId `bson:"_id,omitempty"`
 	App         string
@@ -224,11 +232,20 @@ func Deploy(opts DeployOptions) (string, error) {
 			}
 		}
 	}
-	logWriter := LogWriter{App: opts.App}
+	logWriter := LogWriter{App: opts.App, CorrelationID: opts.Event.CorrelationID}
 	logWriter.Async()
 	defer logWriter.Close()
-	opts.Event.SetLogWriter(io.MultiWriter(&tsuruIo.NoErrorWriter{Writer: opts.OutputStream}, &logWriter))
+	opts.Event.SetLogWriter(&deployLogWriter{
+		Writer: io.MultiWriter(&tsuruIo.NoErrorWriter{Writer: opts.OutputStream}, &logWriter),
+		out:    opts.OutputStream,
+	})
+	start := time.Now()
 	imageId, err := deployToProvisioner(&opts, opts.Event)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	deployDuration.Observe(time.Since(start).Seconds(), string(opts.GetKind()), result)
 	rebuild.RoutesRebuildOrEnqueue(opts.App.Name)
 	if err != nil {
 		return "", err
@@ -243,6 +260,20 @@ func Deploy(opts DeployOptions) (string, error) {
 	return imageId, nil
 }
 
+// deployLogWriter sends the output of the deploy to the client and to the
+// log of the app. Phase markers are only sent to the client.
+type deployLogWriter struct {
+	io.Writer
+	out io.Writer
+}
+
+func (w *deployLogWriter) WritePhase(phase tsuruIo.Phase) error {
+	if pw, ok := w.out.(tsuruIo.PhaseWriter); ok {
+		return pw.WritePhase(phase)
+	}
+	return nil
+}
+
 func deployToProvisioner(opts *DeployOptions, evt *event.Event) (string, error) {
 	prov, err := opts.App.getProvisioner()
 	if err != nil {
@@ -0,0 +1,204 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	DeployStatsByApp      = "app"
+	DeployStatsByPlatform = "platform"
+)
+
+// DeployStatsOptions defines which deploys are aggregated by GetDeployStats and
+// how. Deploys are grouped by app or by the current platform of their apps,
+// and split in periods of Interval, starting at Since.
+type DeployStatsOptions struct {
+	Filter   *Filter
+	GroupBy  string
+	Since    time.Time
+	Until    time.Time
+	Interval time.Duration
+}
+
+// DeployStatsSummary holds the statistics of a set of finished deploys. The
+// failure rate goes from 0 to 1.
+type DeployStatsSummary struct {
+	Count       int           `json:"count"`
+	Failures    int           `json:"failures"`
+	FailureRate float64       `json:"failureRate"`
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+}
+
+// DeployStatsPeriod is the summary of the deploys started in a period.
+type DeployStatsPeriod struct {
+	Start time.Time `json:"start"`
+	DeployStatsSummary
+}
+
+// DeployStats is the summary of the deploys of an app or platform, along
+// with the summary of each period with deploys.
+type DeployStats struct {
+	Name string `json:"name"`
+	DeployStatsSummary
+	Periods []DeployStatsPeriod `json:"periods"`
+}
+
+type deployStatsEntry struct {
+	start    time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// GetDeployStats aggregates the finished deploys of the apps matching the
+// filter, started in the given time range.
+func GetDeployStats(opts DeployStatsOptions) ([]DeployStats, error) {
+	if opts.GroupBy == "" {
+		opts.GroupBy = DeployStatsByApp
+	}
+	if opts.GroupBy != DeployStatsByApp && opts.GroupBy != DeployStatsByPlatform {
+		return nil, errors.Errorf("invalid group %q, must be either app or platform", opts.GroupBy)
+	}
+	if opts.Interval <= 0 {
+		return nil, errors.New("interval must be greater than zero")
+	}
+	apps, err := List(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(apps) == 0 {
+		return nil, nil
+	}
+	groups := make(map[string]string, len(apps))
+	names := make([]string, len(apps))
+	for i, a := range apps {
+		names[i] = a.Name
+		groups[a.Name] = a.Name
+		if opts.GroupBy == DeployStatsByPlatform {
+			groups[a.Name] = a.Platform
+		}
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	startQuery := bson.M{"$gte": opts.Since}
+	if !opts.Until.IsZero() {
+		startQuery["$lt"] = opts.Until
+	}
+	query := bson.M{
+		"target.type":  event.TargetTypeApp,
+		"target.value": bson.M{"$in": names},
+		"kind.type":    event.KindTypePermission,
+		"kind.name":    permission.PermAppDeploy.FullName(),
+		"running":      false,
+		"starttime":    startQuery,
+		"removedate":   bson.M{"$exists": false},
+	}
+	var evts []struct {
+		Target    event.Target
+		StartTime time.Time
+		EndTime   time.Time
+		Error     string
+	}
+	err = conn.Events().Find(query).Select(bson.M{"target": 1, "starttime": 1, "endtime": 1, "error": 1}).All(&evts)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]deployStatsEntry)
+	for _, evt := range evts {
+		group := groups[evt.Target.Value]
+		entries[group] = append(entries[group], deployStatsEntry{
+			start:    evt.StartTime,
+			duration: evt.EndTime.Sub(evt.StartTime),
+			failed:   evt.Error != "",
+		})
+	}
+	groupNames := make([]string, 0, len(entries))
+	for name := range entries {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	stats := make([]DeployStats, len(groupNames))
+	for i, name := range groupNames {
+		stats[i] = DeployStats{
+			Name:               name,
+			DeployStatsSummary: summarizeDeploys(entries[name]),
+			Periods:            deployStatsPeriods(entries[name], opts.Since, opts.Interval),
+		}
+	}
+	return stats, nil
+}
+
+func deployStatsPeriods(entries []deployStatsEntry, since time.Time, interval time.Duration) []DeployStatsPeriod {
+	byPeriod := make(map[int64][]deployStatsEntry)
+	var keys []int64
+	for _, e := range entries {
+		key := int64(e.start.Sub(since) / interval)
+		if _, ok := byPeriod[key]; !ok {
+			keys = append(keys, key)
+		}
+		byPeriod[key] = append(byPeriod[key], e)
+	}
+	sort.Sort(int64Slice(keys))
+	periods := make([]DeployStatsPeriod, len(keys))
+	for i, key := range keys {
+		periods[i] = DeployStatsPeriod{
+			Start:              since.Add(time.Duration(key) * interval),
+			DeployStatsSummary: summarizeDeploys(byPeriod[key]),
+		}
+	}
+	return periods
+}
+
+func summarizeDeploys(entries []deployStatsEntry) DeployStatsSummary {
+	summary := DeployStatsSummary{Count: len(entries)}
+	if len(entries) == 0 {
+		return summary
+	}
+	durations := make([]time.Duration, len(entries))
+	for i, e := range entries {
+		durations[i] = e.duration
+		if e.failed {
+			summary.Failures++
+		}
+	}
+	sort.Sort(durationSlice(durations))
+	summary.FailureRate = float64(summary.Failures) / float64(summary.Count)
+	summary.P50 = percentile(durations, 50)
+	summary.P95 = percentile(durations, 95)
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+type durationSlice []time.Duration
+
+func (s durationSlice) Len() int           { return len(s) }
+func (s durationSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s durationSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
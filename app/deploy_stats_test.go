@@ -0,0 +1,110 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) insertDeployStatsData(c *check.C, since time.Time) {
+	err := s.conn.Platforms().Insert(Platform{Name: "ruby"})
+	c.Assert(err, check.IsNil)
+	for _, a := range []App{
+		{Name: "app1", Platform: "python", TeamOwner: s.team.Name},
+		{Name: "app2", Platform: "python", TeamOwner: s.team.Name},
+		{Name: "app3", Platform: "ruby", TeamOwner: s.team.Name},
+	} {
+		err := CreateApp(&a, s.user)
+		c.Assert(err, check.IsNil)
+	}
+	for _, d := range []DeployData{
+		{App: "app1", Timestamp: since.Add(time.Hour), Duration: time.Minute},
+		{App: "app1", Timestamp: since.Add(2 * time.Hour), Duration: 3 * time.Minute, Error: "failed"},
+		{App: "app1", Timestamp: since.Add(25 * time.Hour), Duration: 2 * time.Minute},
+		{App: "app2", Timestamp: since.Add(time.Hour), Duration: 4 * time.Minute},
+		{App: "app3", Timestamp: since.Add(time.Hour), Duration: 10 * time.Minute, Error: "failed"},
+		{App: "app1", Timestamp: since.Add(-time.Hour), Duration: time.Hour},
+	} {
+		err := deployDataToEvent(&d)
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *S) TestGetDeployStatsByApp(c *check.C) {
+	since := time.Date(2016, time.October, 1, 0, 0, 0, 0, time.UTC)
+	s.insertDeployStatsData(c, since)
+	stats, err := GetDeployStats(DeployStatsOptions{Since: since, Interval: 24 * time.Hour})
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.HasLen, 3)
+	c.Assert(stats[0].Name, check.Equals, "app1")
+	c.Assert(stats[0].DeployStatsSummary, check.DeepEquals, DeployStatsSummary{
+		Count:       3,
+		Failures:    1,
+		FailureRate: 1.0 / 3,
+		P50:         2 * time.Minute,
+		P95:         3 * time.Minute,
+	})
+	c.Assert(stats[0].Periods, check.HasLen, 2)
+	c.Assert(stats[0].Periods[0].Start.Equal(since), check.Equals, true)
+	c.Assert(stats[0].Periods[0].Count, check.Equals, 2)
+	c.Assert(stats[0].Periods[0].FailureRate, check.Equals, 0.5)
+	c.Assert(stats[0].Periods[1].Start.Equal(since.Add(24*time.Hour)), check.Equals, true)
+	c.Assert(stats[0].Periods[1].Count, check.Equals, 1)
+	c.Assert(stats[0].Periods[1].P95, check.Equals, 2*time.Minute)
+	c.Assert(stats[1].Name, check.Equals, "app2")
+	c.Assert(stats[1].Count, check.Equals, 1)
+	c.Assert(stats[2].Name, check.Equals, "app3")
+	c.Assert(stats[2].FailureRate, check.Equals, 1.0)
+}
+
+func (s *S) TestGetDeployStatsByPlatform(c *check.C) {
+	since := time.Date(2016, time.October, 1, 0, 0, 0, 0, time.UTC)
+	s.insertDeployStatsData(c, since)
+	stats, err := GetDeployStats(DeployStatsOptions{
+		GroupBy:  DeployStatsByPlatform,
+		Since:    since,
+		Until:    since.Add(24 * time.Hour),
+		Interval: 24 * time.Hour,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.HasLen, 2)
+	c.Assert(stats[0].Name, check.Equals, "python")
+	c.Assert(stats[0].Count, check.Equals, 3)
+	c.Assert(stats[0].Failures, check.Equals, 1)
+	c.Assert(stats[0].P50, check.Equals, 3*time.Minute)
+	c.Assert(stats[0].P95, check.Equals, 4*time.Minute)
+	c.Assert(stats[0].Periods, check.HasLen, 1)
+	c.Assert(stats[1].Name, check.Equals, "ruby")
+	c.Assert(stats[1].Count, check.Equals, 1)
+}
+
+func (s *S) TestGetDeployStatsFilter(c *check.C) {
+	since := time.Date(2016, time.October, 1, 0, 0, 0, 0, time.UTC)
+	s.insertDeployStatsData(c, since)
+	stats, err := GetDeployStats(DeployStatsOptions{
+		Filter:   &Filter{Name: "app2"},
+		Since:    since,
+		Interval: time.Hour,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.HasLen, 1)
+	c.Assert(stats[0].Name, check.Equals, "app2")
+}
+
+func (s *S) TestGetDeployStatsInvalidOptions(c *check.C) {
+	_, err := GetDeployStats(DeployStatsOptions{GroupBy: "pool", Interval: time.Hour})
+	c.Assert(err, check.ErrorMatches, `invalid group "pool", must be either app or platform`)
+	_, err = GetDeployStats(DeployStatsOptions{})
+	c.Assert(err, check.ErrorMatches, "interval must be greater than zero")
+}
+
+func (s *S) TestPercentile(c *check.C) {
+	durations := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	c.Assert(percentile(durations, 50), check.Equals, time.Duration(5))
+	c.Assert(percentile(durations, 95), check.Equals, time.Duration(10))
+	c.Assert(percentile(durations[:1], 50), check.Equals, time.Duration(1))
+}
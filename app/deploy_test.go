@@ -264,6 +264,7 @@ func (s *S) TestDeployApp(c *check.C) {
 		Allowed:  event.Allowed(permission.PermApp),
 	})
 	c.Assert(err, check.IsNil)
+	deploys := deployDuration.Count("image", "success")
 	_, err = Deploy(DeployOptions{
 		App:          &a,
 		Image:        "myimage",
@@ -274,6 +275,7 @@ func (s *S) TestDeployApp(c *check.C) {
 	c.Assert(err, check.IsNil)
 	logs := writer.String()
 	c.Assert(logs, check.Equals, "Image deploy called")
+	c.Assert(deployDuration.Count("image", "success"), check.Equals, deploys+1)
 }
 
 func (s *S) TestDeployAppWithUpdatePlatform(c *check.C) {
@@ -0,0 +1,239 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tsuru/tsuru/app/bind"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+)
+
+const (
+	EnvFormatDotenv = "dotenv"
+	EnvFormatJSON   = "json"
+)
+
+var envNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func validateEnvFormat(format string) error {
+	if format != EnvFormatDotenv && format != EnvFormatJSON {
+		return &tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("invalid env format %q, must be %q or %q", format, EnvFormatDotenv, EnvFormatJSON),
+		}
+	}
+	return nil
+}
+
+// ExportEnvs writes the environment variables of the app to w in the given
+// format. Variables set by service instances are left out, as they're managed
+// by binds. Secret values are always masked and, when redact is true, so are
+// the values of every private variable.
+func (app *App) ExportEnvs(w io.Writer, format string, redact bool) error {
+	if err := validateEnvFormat(format); err != nil {
+		return err
+	}
+	envs := make(map[string]string)
+	var names []string
+	for name, env := range MaskSecretEnvs(app.Env) {
+		if env.InstanceName != "" {
+			continue
+		}
+		if redact && !env.Public {
+			env.Value = SecretEnvMask
+		}
+		envs[name] = env.Value
+		names = append(names, name)
+	}
+	if format == EnvFormatJSON {
+		return json.NewEncoder(w).Encode(envs)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", name, dotenvValue(envs[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dotenvValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"'#\\$") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// ParseEnvs parses a file with environment variables, either in dotenv format
+// (NAME=value lines) or as a JSON object mapping names to values. Variables are
+// returned sorted by name and marked as public.
+func ParseEnvs(data []byte, format string) ([]bind.EnvVar, error) {
+	if err := validateEnvFormat(format); err != nil {
+		return nil, err
+	}
+	var (
+		envs map[string]string
+		err  error
+	)
+	if format == EnvFormatJSON {
+		err = json.Unmarshal(data, &envs)
+		if err != nil {
+			return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("invalid JSON env file: %s", err)}
+		}
+	} else {
+		envs, err = parseDotenv(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]bind.EnvVar, 0, len(envs))
+	for _, name := range names {
+		if !envNameRegexp.MatchString(name) {
+			return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("invalid environment variable name %q", name)}
+		}
+		result = append(result, bind.EnvVar{Name: name, Value: envs[name], Public: true})
+	}
+	return result, nil
+}
+
+// parseDotenv parses NAME=value lines. Quoted values may span multiple lines
+// and be followed by a comment. Double quoted values support Go escape
+// sequences, single quoted values are taken literally.
+func parseDotenv(data []byte) (map[string]string, error) {
+	envs := make(map[string]string)
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("line %d: expected NAME=value", lineNumber)}
+		}
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			quote := value[0]
+			end := closingQuote(value, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				value += "\n" + lines[i]
+				end = closingQuote(value, quote)
+			}
+			if end < 0 {
+				return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("line %d: invalid quoted value", lineNumber)}
+			}
+			rest := strings.TrimSpace(value[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("line %d: invalid quoted value", lineNumber)}
+			}
+			value = value[1:end]
+			if quote == '"' {
+				unquoted, err := strconv.Unquote(`"` + strings.Replace(value, "\n", `\n`, -1) + `"`)
+				if err != nil {
+					return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("line %d: invalid quoted value", lineNumber)}
+				}
+				value = unquoted
+			}
+		}
+		if _, ok := envs[name]; ok {
+			return nil, &tsuruErrors.ValidationError{Message: fmt.Sprintf("line %d: duplicated variable %q", lineNumber, name)}
+		}
+		envs[name] = value
+	}
+	return envs, nil
+}
+
+// closingQuote returns the index of the quote closing the value, which starts
+// with it, or -1 if it's not closed. Quotes escaped with a backslash don't
+// close double quoted values.
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		if quote == '"' && value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// ImportEnvs sets all the given variables in a single change. The whole import
+// is rejected, and no variable is changed, when any of them has a masked value
+// or is managed by a service instance.
+func (app *App) ImportEnvs(setEnvs bind.SetEnvApp, w io.Writer) error {
+	if len(setEnvs.Envs) == 0 {
+		return &tsuruErrors.ValidationError{Message: "no environment variables to import"}
+	}
+	for _, env := range setEnvs.Envs {
+		if env.Value == SecretEnvMask {
+			return &tsuruErrors.ValidationError{Message: fmt.Sprintf("variable %q has a masked value", env.Name)}
+		}
+		if current, err := app.getEnv(env.Name); err == nil && current.InstanceName != "" {
+			return &tsuruErrors.ValidationError{
+				Message: fmt.Sprintf("variable %q is managed by the service instance %q", env.Name, current.InstanceName),
+			}
+		}
+		if env.Secret {
+			if err := CheckSecretEnvs(); err != nil {
+				return err
+			}
+		}
+	}
+	if w != nil {
+		app.writeEnvImportSummary(setEnvs.Envs, w)
+	}
+	return app.SetEnvs(setEnvs, w)
+}
+
+// writeEnvImportSummary writes which of the variables being imported will be
+// added, which will have their values or visibility changed and how many will
+// be kept as they are. Values are never written.
+func (app *App) writeEnvImportSummary(envs []bind.EnvVar, w io.Writer) {
+	var added, changed []string
+	for _, env := range envs {
+		if env.Secret {
+			env.Public = false
+		}
+		old, existed := app.Env[env.Name]
+		if existed {
+			if plain, err := plainEnv(old); err == nil {
+				old = plain
+			}
+		}
+		if _, ok := envSetChange(old, existed, env, ""); !ok {
+			continue
+		}
+		if existed {
+			changed = append(changed, env.Name)
+		} else {
+			added = append(added, env.Name)
+		}
+	}
+	fmt.Fprintf(w, "---- Importing %d environment variables: %d added, %d changed, %d unchanged ----\n",
+		len(envs), len(added), len(changed), len(envs)-len(added)-len(changed))
+	for _, name := range added {
+		fmt.Fprintf(w, "  + %s\n", name)
+	}
+	for _, name := range changed {
+		fmt.Fprintf(w, "  ~ %s\n", name)
+	}
+}
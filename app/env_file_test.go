@@ -0,0 +1,189 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/bind"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestExportEnvsDotenv(c *check.C) {
+	a := App{Name: "myapp", Env: map[string]bind.EnvVar{
+		"DATABASE_HOST": {Name: "DATABASE_HOST", Value: "localhost", Public: true},
+		"GREETING":      {Name: "GREETING", Value: "hello world", Public: true},
+		"TOKEN":         {Name: "TOKEN", Value: "abc"},
+		"SERVICE_URL":   {Name: "SERVICE_URL", Value: "http://svc", InstanceName: "mysql"},
+		"PASSWORD":      {Name: "PASSWORD", Value: "encrypted", Secret: true},
+	}}
+	var buf bytes.Buffer
+	err := a.ExportEnvs(&buf, EnvFormatDotenv, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `DATABASE_HOST=localhost
+GREETING="hello world"
+PASSWORD=*****
+TOKEN=abc
+`)
+	buf.Reset()
+	err = a.ExportEnvs(&buf, EnvFormatDotenv, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `DATABASE_HOST=localhost
+GREETING="hello world"
+PASSWORD=*****
+TOKEN=*****
+`)
+}
+
+func (s *S) TestExportEnvsJSON(c *check.C) {
+	a := App{Name: "myapp", Env: map[string]bind.EnvVar{
+		"DATABASE_HOST": {Name: "DATABASE_HOST", Value: "localhost", Public: true},
+		"TOKEN":         {Name: "TOKEN", Value: "abc"},
+	}}
+	var buf bytes.Buffer
+	err := a.ExportEnvs(&buf, EnvFormatJSON, false)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `{"DATABASE_HOST":"localhost","TOKEN":"abc"}`+"\n")
+}
+
+func (s *S) TestExportEnvsInvalidFormat(c *check.C) {
+	a := App{Name: "myapp"}
+	err := a.ExportEnvs(new(bytes.Buffer), "yaml", false)
+	c.Assert(err, check.FitsTypeOf, &tsuruErrors.ValidationError{})
+}
+
+func (s *S) TestParseEnvsDotenv(c *check.C) {
+	data := []byte(`# database
+DATABASE_HOST=localhost
+export GREETING="hello\nworld"
+  RAW = 'a "raw" value'
+
+EMPTY=
+`)
+	envs, err := ParseEnvs(data, EnvFormatDotenv)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, []bind.EnvVar{
+		{Name: "DATABASE_HOST", Value: "localhost", Public: true},
+		{Name: "EMPTY", Value: "", Public: true},
+		{Name: "GREETING", Value: "hello\nworld", Public: true},
+		{Name: "RAW", Value: `a "raw" value`, Public: true},
+	})
+}
+
+func (s *S) TestParseEnvsDotenvMultiline(c *check.C) {
+	data := []byte("KEY=\"-----BEGIN KEY-----\nabc\\\"def\n-----END KEY-----\" # the key\r\n" +
+		"RAW='first\n  second'\n" +
+		"AFTER=1\n")
+	envs, err := ParseEnvs(data, EnvFormatDotenv)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, []bind.EnvVar{
+		{Name: "AFTER", Value: "1", Public: true},
+		{Name: "KEY", Value: "-----BEGIN KEY-----\nabc\"def\n-----END KEY-----", Public: true},
+		{Name: "RAW", Value: "first\n  second", Public: true},
+	})
+}
+
+func (s *S) TestParseEnvsDotenvInvalid(c *check.C) {
+	tests := []struct {
+		data string
+		msg  string
+	}{
+		{"A=1\nINVALID", "line 2: expected NAME=value"},
+		{`A="unterminated`, "line 1: invalid quoted value"},
+		{"A=1\nB='unterminated\nC=2\n", "line 2: invalid quoted value"},
+		{`A="quoted" trailing`, "line 1: invalid quoted value"},
+		{"A=1\nA=2", `line 2: duplicated variable "A"`},
+		{"1A=1", `invalid environment variable name "1A"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseEnvs([]byte(tt.data), EnvFormatDotenv)
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+}
+
+func (s *S) TestParseEnvsJSON(c *check.C) {
+	envs, err := ParseEnvs([]byte(`{"B": "2", "A": "1"}`), EnvFormatJSON)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, []bind.EnvVar{
+		{Name: "A", Value: "1", Public: true},
+		{Name: "B", Value: "2", Public: true},
+	})
+	_, err = ParseEnvs([]byte(`["A"]`), EnvFormatJSON)
+	c.Assert(err, check.FitsTypeOf, &tsuruErrors.ValidationError{})
+}
+
+func (s *S) TestImportEnvs(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	envs, err := ParseEnvs([]byte("A=1\nB=2\n"), EnvFormatDotenv)
+	c.Assert(err, check.IsNil)
+	err = a.ImportEnvs(bind.SetEnvApp{Envs: envs, PublicOnly: true}, nil)
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Env["A"], check.DeepEquals, bind.EnvVar{Name: "A", Value: "1", Public: true})
+	c.Assert(dbApp.Env["B"], check.DeepEquals, bind.EnvVar{Name: "B", Value: "2", Public: true})
+}
+
+func (s *S) TestImportEnvsSummary(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{Envs: []bind.EnvVar{
+		{Name: "A", Value: "1", Public: true},
+		{Name: "B", Value: "2", Public: true},
+	}}, nil)
+	c.Assert(err, check.IsNil)
+	var buf bytes.Buffer
+	err = a.ImportEnvs(bind.SetEnvApp{Envs: []bind.EnvVar{
+		{Name: "A", Value: "1", Public: true},
+		{Name: "B", Value: "3", Public: true},
+		{Name: "C", Value: "4", Public: true},
+	}, PublicOnly: true}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `---- Importing 3 environment variables: 1 added, 1 changed, 1 unchanged ----
+  + C
+  ~ B
+---- Setting 3 new environment variables ----
+`)
+}
+
+func (s *S) TestImportEnvsRejectsWholeFile(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs: []bind.EnvVar{{Name: "DATABASE_URL", Value: "mysql://", InstanceName: "mydb"}},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	err = a.ImportEnvs(bind.SetEnvApp{Envs: []bind.EnvVar{
+		{Name: "A", Value: "1", Public: true},
+		{Name: "DATABASE_URL", Value: "other", Public: true},
+	}, PublicOnly: true}, nil)
+	c.Assert(err, check.ErrorMatches, `variable "DATABASE_URL" is managed by the service instance "mydb"`)
+	err = a.ImportEnvs(bind.SetEnvApp{Envs: []bind.EnvVar{
+		{Name: "A", Value: "1", Public: true},
+		{Name: "PASSWORD", Value: SecretEnvMask, Public: true},
+	}, PublicOnly: true}, nil)
+	c.Assert(err, check.ErrorMatches, `variable "PASSWORD" has a masked value`)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	_, ok := dbApp.Env["A"]
+	c.Assert(ok, check.Equals, false)
+}
+
+func (s *S) TestImportEnvsSecretWithoutKey(c *check.C) {
+	config.Unset("envs:secret-key")
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.ImportEnvs(bind.SetEnvApp{Envs: []bind.EnvVar{
+		{Name: "PASSWORD", Value: "secret", Secret: true},
+	}}, nil)
+	c.Assert(err, check.Equals, ErrSecretEnvsDisabled)
+}
@@ -0,0 +1,223 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	EnvChangeSet   = "set"
+	EnvChangeUnset = "unset"
+
+	EnvDiffAdded   = "added"
+	EnvDiffRemoved = "removed"
+	EnvDiffChanged = "changed"
+)
+
+// EnvChange is an entry in the history of environment variables of an app.
+// Values are never stored, only their hashes, see hashEnvValue.
+type EnvChange struct {
+	App          string    `json:"app"`
+	Name         string    `json:"name"`
+	Action       string    `json:"action"`
+	Public       bool      `json:"public"`
+	InstanceName string    `json:"instanceName,omitempty" bson:",omitempty"`
+	OldHash      string    `json:"oldHash,omitempty" bson:",omitempty"`
+	NewHash      string    `json:"newHash,omitempty" bson:",omitempty"`
+	Owner        string    `json:"owner,omitempty" bson:",omitempty"`
+	Date         time.Time `json:"date"`
+}
+
+// EnvDiff describes the difference of an environment variable between two
+// environments.
+type EnvDiff struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// envHistoryKeyLabel separates the key used in the hashes of secret values
+// from the master key of secret envs.
+const envHistoryKeyLabel = "tsuru env history"
+
+// hashEnvValue returns the hash of the value of the env recorded in the env
+// history and in env diffs. Values of secret envs are hashed with
+// HMAC-SHA256, keyed by a key derived from the master key of secret envs, so
+// whoever reads the history can't brute force them. The hash is
+// SecretEnvMask when the master key is not available.
+func hashEnvValue(env bind.EnvVar) string {
+	if !env.Secret {
+		sum := sha256.Sum256([]byte(env.Value))
+		return hex.EncodeToString(sum[:])
+	}
+	keyProviderMu.RLock()
+	p := keyProvider
+	keyProviderMu.RUnlock()
+	masterKey, err := p.SecretKey()
+	if err != nil {
+		return SecretEnvMask
+	}
+	keyMac := hmac.New(sha256.New, masterKey)
+	keyMac.Write([]byte(envHistoryKeyLabel))
+	mac := hmac.New(sha256.New, keyMac.Sum(nil))
+	mac.Write([]byte(env.Value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordEnvChanges stores changes in the env history of the app. Failing to
+// record the history doesn't fail the env change itself.
+func (app *App) recordEnvChanges(changes []EnvChange) {
+	if len(changes) == 0 {
+		return
+	}
+	docs := make([]interface{}, len(changes))
+	now := time.Now().UTC()
+	for i := range changes {
+		changes[i].App = app.Name
+		changes[i].Date = now
+		docs[i] = changes[i]
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		log.Errorf("[env history] unable to record env changes of app %s: %s", app.Name, err)
+		return
+	}
+	defer conn.Close()
+	err = conn.AppEnvHistory().Insert(docs...)
+	if err != nil {
+		log.Errorf("[env history] unable to record env changes of app %s: %s", app.Name, err)
+	}
+}
+
+func envSetChange(old bind.EnvVar, existed bool, env bind.EnvVar, owner string) (EnvChange, bool) {
+	if existed && old.Value == env.Value && old.Public == env.Public && old.Secret == env.Secret {
+		return EnvChange{}, false
+	}
+	change := EnvChange{
+		Name:         env.Name,
+		Action:       EnvChangeSet,
+		Public:       env.Public,
+		InstanceName: env.InstanceName,
+		NewHash:      hashEnvValue(env),
+		Owner:        owner,
+	}
+	if existed {
+		change.OldHash = hashEnvValue(old)
+	}
+	return change, true
+}
+
+// EnvHistory returns the changes in the environment variables of the app,
+// newest first. A limit of zero returns the whole history.
+func (app *App) EnvHistory(limit int) ([]EnvChange, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := conn.AppEnvHistory().Find(bson.M{"app": app.Name}).Sort("-date")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var changes []EnvChange
+	err = query.All(&changes)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// EnvDiffBetween returns the environment variables of the app changed
+// between from and to, comparing the value before the first change and
+// after the last change in the period.
+func (app *App) EnvDiffBetween(from, to time.Time) ([]EnvDiff, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := bson.M{"app": app.Name, "date": bson.M{"$gt": from, "$lte": to}}
+	var changes []EnvChange
+	err = conn.AppEnvHistory().Find(query).Sort("date").All(&changes)
+	if err != nil {
+		return nil, err
+	}
+	diffs := map[string]*EnvDiff{}
+	var names []string
+	for _, change := range changes {
+		d, ok := diffs[change.Name]
+		if !ok {
+			d = &EnvDiff{Name: change.Name, Before: change.OldHash}
+			diffs[change.Name] = d
+			names = append(names, change.Name)
+		}
+		d.After = change.NewHash
+	}
+	sort.Strings(names)
+	result := make([]EnvDiff, 0, len(names))
+	for _, name := range names {
+		d := diffs[name]
+		if d.Before == d.After {
+			continue
+		}
+		d.Status = diffStatus(d.Before, d.After)
+		result = append(result, *d)
+	}
+	return result, nil
+}
+
+// EnvDiffTo compares the current environment variables of the app with the
+// ones of other app.
+func (app *App) EnvDiffTo(other *App) []EnvDiff {
+	var names []string
+	for name := range app.Env {
+		names = append(names, name)
+	}
+	for name := range other.Env {
+		if _, ok := app.Env[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	envs, otherEnvs := app.decryptedEnvs(), other.decryptedEnvs()
+	var result []EnvDiff
+	for _, name := range names {
+		var d EnvDiff
+		if env, ok := envs[name]; ok {
+			d.Before = hashEnvValue(env)
+		}
+		if env, ok := otherEnvs[name]; ok {
+			d.After = hashEnvValue(env)
+		}
+		if d.Before == d.After {
+			continue
+		}
+		d.Name = name
+		d.Status = diffStatus(d.Before, d.After)
+		result = append(result, d)
+	}
+	return result
+}
+
+func diffStatus(before, after string) string {
+	switch {
+	case before == "":
+		return EnvDiffAdded
+	case after == "":
+		return EnvDiffRemoved
+	}
+	return EnvDiffChanged
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app/bind"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSetEnvsRecordsHistory(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs:  []bind.EnvVar{{Name: "DATABASE_HOST", Value: "localhost", Public: true}},
+		Owner: "me@tsuru.io",
+	}, nil)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs:  []bind.EnvVar{{Name: "DATABASE_HOST", Value: "localhost", Public: true}},
+		Owner: "me@tsuru.io",
+	}, nil)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs:  []bind.EnvVar{{Name: "DATABASE_HOST", Value: "db.tsuru.io", Public: true}},
+		Owner: "other@tsuru.io",
+	}, nil)
+	c.Assert(err, check.IsNil)
+	err = a.UnsetEnvs(bind.UnsetEnvApp{
+		VariableNames: []string{"DATABASE_HOST", "UNKNOWN"},
+		Owner:         "me@tsuru.io",
+	}, nil)
+	c.Assert(err, check.IsNil)
+	changes, err := a.EnvHistory(0)
+	c.Assert(err, check.IsNil)
+	c.Assert(changes, check.HasLen, 3)
+	for i := range changes {
+		c.Assert(changes[i].App, check.Equals, "myapp")
+		c.Assert(changes[i].Date.IsZero(), check.Equals, false)
+		changes[i].Date = time.Time{}
+	}
+	c.Assert(changes, check.DeepEquals, []EnvChange{
+		{App: "myapp", Name: "DATABASE_HOST", Action: EnvChangeUnset, Public: true, OldHash: hashEnvValue(bind.EnvVar{Value: "db.tsuru.io"}), Owner: "me@tsuru.io"},
+		{App: "myapp", Name: "DATABASE_HOST", Action: EnvChangeSet, Public: true, OldHash: hashEnvValue(bind.EnvVar{Value: "localhost"}), NewHash: hashEnvValue(bind.EnvVar{Value: "db.tsuru.io"}), Owner: "other@tsuru.io"},
+		{App: "myapp", Name: "DATABASE_HOST", Action: EnvChangeSet, Public: true, NewHash: hashEnvValue(bind.EnvVar{Value: "localhost"}), Owner: "me@tsuru.io"},
+	})
+	changes, err = a.EnvHistory(1)
+	c.Assert(err, check.IsNil)
+	c.Assert(changes, check.HasLen, 1)
+}
+
+func (s *S) TestEnvDiffBetween(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	base := time.Now().UTC().Add(-time.Hour)
+	err = s.conn.AppEnvHistory().Insert(
+		EnvChange{App: "myapp", Name: "A", Action: EnvChangeSet, NewHash: "a1", Date: base},
+		EnvChange{App: "myapp", Name: "A", Action: EnvChangeSet, OldHash: "a1", NewHash: "a2", Date: base.Add(10 * time.Minute)},
+		EnvChange{App: "myapp", Name: "A", Action: EnvChangeSet, OldHash: "a2", NewHash: "a3", Date: base.Add(20 * time.Minute)},
+		EnvChange{App: "myapp", Name: "B", Action: EnvChangeSet, NewHash: "b1", Date: base.Add(10 * time.Minute)},
+		EnvChange{App: "myapp", Name: "C", Action: EnvChangeSet, NewHash: "c1", Date: base},
+		EnvChange{App: "myapp", Name: "C", Action: EnvChangeUnset, OldHash: "c1", Date: base.Add(15 * time.Minute)},
+		EnvChange{App: "myapp", Name: "D", Action: EnvChangeSet, NewHash: "d1", Date: base.Add(10 * time.Minute)},
+		EnvChange{App: "myapp", Name: "D", Action: EnvChangeUnset, OldHash: "d1", Date: base.Add(15 * time.Minute)},
+		EnvChange{App: "otherapp", Name: "A", Action: EnvChangeSet, NewHash: "x", Date: base.Add(10 * time.Minute)},
+	)
+	c.Assert(err, check.IsNil)
+	diffs, err := a.EnvDiffBetween(base.Add(time.Minute), base.Add(30*time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(diffs, check.DeepEquals, []EnvDiff{
+		{Name: "A", Status: EnvDiffChanged, Before: "a1", After: "a3"},
+		{Name: "B", Status: EnvDiffAdded, After: "b1"},
+		{Name: "C", Status: EnvDiffRemoved, Before: "c1"},
+	})
+}
+
+func (s *S) TestEnvDiffTo(c *check.C) {
+	a := App{Name: "myapp", Env: map[string]bind.EnvVar{
+		"A": {Name: "A", Value: "1"},
+		"B": {Name: "B", Value: "2"},
+		"C": {Name: "C", Value: "3"},
+	}}
+	other := App{Name: "otherapp", Env: map[string]bind.EnvVar{
+		"A": {Name: "A", Value: "1"},
+		"B": {Name: "B", Value: "20"},
+		"D": {Name: "D", Value: "4"},
+	}}
+	c.Assert(a.EnvDiffTo(&other), check.DeepEquals, []EnvDiff{
+		{Name: "B", Status: EnvDiffChanged, Before: hashEnvValue(bind.EnvVar{Value: "2"}), After: hashEnvValue(bind.EnvVar{Value: "20"})},
+		{Name: "C", Status: EnvDiffRemoved, Before: hashEnvValue(bind.EnvVar{Value: "3"})},
+		{Name: "D", Status: EnvDiffAdded, After: hashEnvValue(bind.EnvVar{Value: "4"})},
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/bind"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+)
+
+// SecretEnvMask replaces the value of secret environment variables in API
+// responses and events.
+const SecretEnvMask = "*****"
+
+var ErrSecretEnvsDisabled = &tsuruErrors.ValidationError{Message: `secret environment variables are disabled, "envs:secret-key" is not configured`}
+
+// SecretKeyProvider provides the master key used to encrypt secret
+// environment variables. It may be replaced to fetch the key from a key
+// management service.
+type SecretKeyProvider interface {
+	SecretKey() ([]byte, error)
+}
+
+type configKeyProvider struct{}
+
+// SecretKey returns the base64 encoded key in envs:secret-key. The decoded
+// key must have 16, 24 or 32 bytes, selecting AES-128, AES-192 or AES-256.
+func (configKeyProvider) SecretKey() ([]byte, error) {
+	encoded, _ := config.GetString("envs:secret-key")
+	if encoded == "" {
+		return nil, ErrSecretEnvsDisabled
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, `invalid "envs:secret-key", it must be base64 encoded`)
+	}
+	return key, nil
+}
+
+var (
+	keyProviderMu sync.RWMutex
+	keyProvider   SecretKeyProvider = configKeyProvider{}
+)
+
+// SetSecretKeyProvider replaces the provider of the master key of secret
+// environment variables.
+func SetSecretKeyProvider(p SecretKeyProvider) {
+	keyProviderMu.Lock()
+	defer keyProviderMu.Unlock()
+	keyProvider = p
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	keyProviderMu.RLock()
+	p := keyProvider
+	keyProviderMu.RUnlock()
+	key, err := p.SecretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid secret key")
+	}
+	return cipher.NewGCM(block)
+}
+
+// CheckSecretEnvs returns an error if secret environment variables can't be
+// used, e.g. when no master key is configured.
+func CheckSecretEnvs() error {
+	_, err := secretCipher()
+	return err
+}
+
+// encryptEnvValue encrypts the value using AES-GCM, returning the nonce and
+// the sealed value, base64 encoded.
+func encryptEnvValue(value string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptEnvValue(encrypted string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid encrypted value")
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("invalid encrypted value")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	value, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to decrypt value")
+	}
+	return string(value), nil
+}
+
+// plainEnv returns the environment variable with its value decrypted, when
+// it's a secret.
+func plainEnv(env bind.EnvVar) (bind.EnvVar, error) {
+	if !env.Secret {
+		return env, nil
+	}
+	value, err := decryptEnvValue(env.Value)
+	if err != nil {
+		return env, errors.Wrapf(err, "unable to decrypt env %s", env.Name)
+	}
+	env.Value = value
+	return env, nil
+}
+
+// MaskSecretEnvs returns a copy of envs with the values of secret variables
+// replaced by SecretEnvMask.
+func MaskSecretEnvs(envs map[string]bind.EnvVar) map[string]bind.EnvVar {
+	masked := make(map[string]bind.EnvVar, len(envs))
+	for k, env := range envs {
+		if env.Secret {
+			env.Value = SecretEnvMask
+		}
+		masked[k] = env
+	}
+	return masked
+}
+
+// decryptedEnvs returns the environment variables of the app with secret
+// values decrypted. Variables that can't be decrypted are left out.
+func (app *App) decryptedEnvs() map[string]bind.EnvVar {
+	envs := make(map[string]bind.EnvVar, len(app.Env))
+	for k, env := range app.Env {
+		plain, err := plainEnv(env)
+		if err != nil {
+			log.Errorf("[secret envs] app %s: %s", app.Name, err)
+			continue
+		}
+		envs[k] = plain
+	}
+	return envs
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/base64"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app/bind"
+	"gopkg.in/check.v1"
+)
+
+var testSecretKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func (s *S) TestEncryptEnvValue(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	encrypted, err := encryptEnvValue("my-password")
+	c.Assert(err, check.IsNil)
+	c.Assert(encrypted, check.Not(check.Equals), "my-password")
+	other, err := encryptEnvValue("my-password")
+	c.Assert(err, check.IsNil)
+	c.Assert(other, check.Not(check.Equals), encrypted)
+	value, err := decryptEnvValue(encrypted)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "my-password")
+}
+
+func (s *S) TestEncryptEnvValueWithoutKey(c *check.C) {
+	_, err := encryptEnvValue("my-password")
+	c.Assert(err, check.Equals, ErrSecretEnvsDisabled)
+	c.Assert(CheckSecretEnvs(), check.Equals, ErrSecretEnvsDisabled)
+}
+
+func (s *S) TestDecryptEnvValueWrongKey(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	encrypted, err := encryptEnvValue("my-password")
+	c.Assert(err, check.IsNil)
+	config.Set("envs:secret-key", base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	_, err = decryptEnvValue(encrypted)
+	c.Assert(err, check.ErrorMatches, "unable to decrypt value: .*")
+}
+
+func (s *S) TestSetEnvsSecret(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetEnvs(bind.SetEnvApp{
+		Envs: []bind.EnvVar{{Name: "DATABASE_PASSWORD", Value: "secret", Public: true, Secret: true}},
+	}, nil)
+	c.Assert(err, check.IsNil)
+	stored, err := GetByName("myapp")
+	c.Assert(err, check.IsNil)
+	env := stored.Env["DATABASE_PASSWORD"]
+	c.Assert(env.Secret, check.Equals, true)
+	c.Assert(env.Public, check.Equals, false)
+	c.Assert(env.Value, check.Not(check.Equals), "secret")
+	c.Assert(stored.Envs()["DATABASE_PASSWORD"], check.DeepEquals, bind.EnvVar{
+		Name:   "DATABASE_PASSWORD",
+		Value:  "secret",
+		Secret: true,
+	})
+	c.Assert(MaskSecretEnvs(stored.Env)["DATABASE_PASSWORD"].Value, check.Equals, SecretEnvMask)
+	changes, err := stored.EnvHistory(0)
+	c.Assert(err, check.IsNil)
+	c.Assert(changes, check.HasLen, 1)
+	c.Assert(changes[0].NewHash, check.Equals, hashEnvValue(bind.EnvVar{Value: "secret", Secret: true}))
+	c.Assert(changes[0].NewHash, check.Not(check.Equals), hashEnvValue(bind.EnvVar{Value: "secret"}))
+}
+
+func (s *S) TestHashSecretEnvValueUsesSecretKey(c *check.C) {
+	env := bind.EnvVar{Value: "secret", Secret: true}
+	c.Assert(hashEnvValue(env), check.Equals, SecretEnvMask)
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	hash := hashEnvValue(env)
+	c.Assert(hash, check.HasLen, 64)
+	c.Assert(hashEnvValue(env), check.Equals, hash)
+	c.Assert(hashEnvValue(bind.EnvVar{Value: "other", Secret: true}), check.Not(check.Equals), hash)
+}
+
+func (s *S) TestEnvsSkipsUndecryptableSecrets(c *check.C) {
+	config.Set("envs:secret-key", testSecretKey)
+	defer config.Unset("envs:secret-key")
+	a := App{Name: "myapp", Env: map[string]bind.EnvVar{
+		"PLAIN":  {Name: "PLAIN", Value: "value", Public: true},
+		"SECRET": {Name: "SECRET", Value: "not-encrypted", Secret: true},
+	}}
+	c.Assert(a.Envs(), check.DeepEquals, map[string]bind.EnvVar{
+		"PLAIN": {Name: "PLAIN", Value: "value", Public: true},
+	})
+}
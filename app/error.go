@@ -30,3 +30,14 @@ type ManyTeamsError struct{}
 func (err ManyTeamsError) Error() string {
 	return "You belong to more than one team, choose one to be owner for this app."
 }
+
+// PoolAccessError is the error returned when the team owner of an app is not
+// allowed to use the pool chosen for the app.
+type PoolAccessError struct {
+	Team string
+	Pool string
+}
+
+func (err *PoolAccessError) Error() string {
+	return fmt.Sprintf("App team owner %q has no access to pool %q", err.Team, err.Pool)
+}
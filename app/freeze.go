@@ -0,0 +1,119 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrFreezeNotFound = errors.New("freeze not found")
+
+// Freeze blocks changes to an app, or to every app when App is empty, during
+// change freeze windows.
+type Freeze struct {
+	App    string    `json:"app,omitempty"`
+	Reason string    `json:"reason"`
+	Owner  string    `json:"owner"`
+	Date   time.Time `json:"date"`
+}
+
+// IsGlobal returns whether the freeze applies to all apps.
+func (f *Freeze) IsGlobal() bool {
+	return f.App == ""
+}
+
+// AppFrozenError is returned when trying to change a frozen app.
+type AppFrozenError struct {
+	App    string
+	Freeze Freeze
+}
+
+func (e *AppFrozenError) Error() string {
+	scope := fmt.Sprintf("app %q is frozen", e.App)
+	if e.Freeze.IsGlobal() {
+		scope = "all apps are frozen"
+	}
+	return fmt.Sprintf("%s by %s since %s: %s", scope, e.Freeze.Owner, e.Freeze.Date.Format(time.RFC3339), e.Freeze.Reason)
+}
+
+// AddFreeze freezes the app named in the freeze, or all apps when it has no
+// app, replacing any previous freeze with the same scope.
+func AddFreeze(f Freeze) error {
+	if f.Reason == "" {
+		return &tsuruErrors.ValidationError{Message: "the reason of the freeze is required"}
+	}
+	if !f.IsGlobal() {
+		if _, err := GetByName(f.App); err != nil {
+			return err
+		}
+	}
+	if f.Date.IsZero() {
+		f.Date = time.Now().UTC()
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Freezes().Upsert(bson.M{"app": f.App}, f)
+	return err
+}
+
+// RemoveFreeze removes the freeze of the app with the given name, or the
+// global freeze when the name is empty.
+func RemoveFreeze(appName string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Freezes().Remove(bson.M{"app": appName})
+	if err == mgo.ErrNotFound {
+		return ErrFreezeNotFound
+	}
+	return err
+}
+
+// ListFreezes returns the global freeze, if any, followed by the freezes of
+// apps.
+func ListFreezes() ([]Freeze, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var freezes []Freeze
+	err = conn.Freezes().Find(nil).Sort("app").All(&freezes)
+	if err != nil {
+		return nil, err
+	}
+	return freezes, nil
+}
+
+// CheckFreeze returns an *AppFrozenError when the app, or all apps, are
+// frozen. The global freeze takes precedence over the freeze of the app.
+func (app *App) CheckFreeze() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var freezes []Freeze
+	err = conn.Freezes().Find(bson.M{"app": bson.M{"$in": []string{"", app.Name}}}).Sort("app").All(&freezes)
+	if err != nil {
+		return err
+	}
+	if len(freezes) == 0 {
+		return nil
+	}
+	return &AppFrozenError{App: app.Name, Freeze: freezes[0]}
+}
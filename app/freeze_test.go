@@ -0,0 +1,97 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAddFreezeAndCheckFreeze(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	c.Assert(a.CheckFreeze(), check.IsNil)
+	date := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	err = AddFreeze(Freeze{App: "myapp", Reason: "black friday", Owner: "admin@tsuru.io", Date: date})
+	c.Assert(err, check.IsNil)
+	err = a.CheckFreeze()
+	c.Assert(err, check.FitsTypeOf, &AppFrozenError{})
+	c.Assert(err, check.ErrorMatches, `app "myapp" is frozen by admin@tsuru.io since 2016-12-20T10:00:00Z: black friday`)
+	other := App{Name: "otherapp", TeamOwner: s.team.Name}
+	err = CreateApp(&other, s.user)
+	c.Assert(err, check.IsNil)
+	c.Assert(other.CheckFreeze(), check.IsNil)
+}
+
+func (s *S) TestAddFreezeGlobal(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{App: "myapp", Reason: "migration", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = a.CheckFreeze()
+	c.Assert(err, check.NotNil)
+	frozenErr, ok := err.(*AppFrozenError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(frozenErr.Freeze.IsGlobal(), check.Equals, true)
+	c.Assert(frozenErr.Freeze.Reason, check.Equals, "holidays")
+	freezes, err := ListFreezes()
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 2)
+	c.Assert(freezes[0].App, check.Equals, "")
+	c.Assert(freezes[1].App, check.Equals, "myapp")
+}
+
+func (s *S) TestAddFreezeReplacesPreviousFreeze(c *check.C) {
+	err := AddFreeze(Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{Reason: "still holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	freezes, err := ListFreezes()
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 1)
+	c.Assert(freezes[0].Reason, check.Equals, "still holidays")
+}
+
+func (s *S) TestAddFreezeValidation(c *check.C) {
+	err := AddFreeze(Freeze{Owner: "admin@tsuru.io"})
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	err = AddFreeze(Freeze{App: "unknown", Reason: "migration"})
+	c.Assert(err, check.Equals, ErrAppNotFound)
+}
+
+func (s *S) TestRemoveFreeze(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{App: "myapp", Reason: "migration"})
+	c.Assert(err, check.IsNil)
+	err = RemoveFreeze("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(a.CheckFreeze(), check.IsNil)
+	err = RemoveFreeze("myapp")
+	c.Assert(err, check.Equals, ErrFreezeNotFound)
+}
+
+func (s *S) TestDeleteRemovesFreeze(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{App: "myapp", Reason: "migration", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = AddFreeze(Freeze{Reason: "holidays", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = Delete(&a, nil)
+	c.Assert(err, check.IsNil)
+	freezes, err := ListFreezes()
+	c.Assert(err, check.IsNil)
+	c.Assert(freezes, check.HasLen, 1)
+	c.Assert(freezes[0].IsGlobal(), check.Equals, true)
+}
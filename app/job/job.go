@@ -0,0 +1,370 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package job implements scheduled jobs, commands that run in the image of an
+// app on a cron schedule, each run in a new and ephemeral container.
+package job
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/cron"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+
+	// maxOutputSize is the number of bytes kept from the end of the output
+	// of each run.
+	maxOutputSize = 64 * 1024
+	// maxRuns is the number of runs kept in the history of each job.
+	maxRuns = 100
+
+	runEventKind = "app-job-run"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobAlreadyExists = errors.New("job already exists")
+
+	jobNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+)
+
+// Job is a command that runs in the image of an app at the times defined by
+// a cron expression, evaluated in the given timezone.
+type Job struct {
+	ID       bson.ObjectId `bson:"_id" json:"id"`
+	App      string        `json:"app"`
+	Name     string        `json:"name"`
+	Command  string        `json:"command"`
+	Cron     string        `json:"cron"`
+	Timezone string        `json:"timezone"`
+	Creator  string        `json:"creator"`
+	LastRun  time.Time     `json:"lastRun"`
+	NextRun  time.Time     `json:"nextRun"`
+}
+
+// Run is an execution of a job.
+type Run struct {
+	ID       bson.ObjectId `bson:"_id" json:"id"`
+	App      string        `json:"app"`
+	Job      string        `json:"job"`
+	Command  string        `json:"command"`
+	Status   string        `json:"status"`
+	ExitCode int           `json:"exitCode"`
+	Output   string        `json:"output"`
+	Error    string        `json:"error,omitempty" bson:",omitempty"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+}
+
+func (j *Job) next(after time.Time) (time.Time, error) {
+	return cron.Next(j.Cron, j.Timezone, after)
+}
+
+// AddJob validates and stores a new job.
+func AddJob(j *Job) error {
+	if j.App == "" {
+		return &tsuruErrors.ValidationError{Message: "app is required"}
+	}
+	if !jobNameRegexp.MatchString(j.Name) {
+		return &tsuruErrors.ValidationError{Message: "invalid job name, it must start with a letter and contain only lowercase letters, numbers and dashes, with at most 40 characters"}
+	}
+	if j.Command == "" {
+		return &tsuruErrors.ValidationError{Message: "command is required"}
+	}
+	if j.Creator == "" {
+		return &tsuruErrors.ValidationError{Message: "creator is required"}
+	}
+	if j.Timezone == "" {
+		j.Timezone = "UTC"
+	}
+	var err error
+	j.NextRun, err = j.next(time.Now())
+	if err != nil {
+		return err
+	}
+	j.ID = bson.NewObjectId()
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.AppJobs().Insert(j)
+	if mgo.IsDup(err) {
+		return ErrJobAlreadyExists
+	}
+	return err
+}
+
+// ListJobs returns the jobs of the given app.
+func ListJobs(appName string) ([]Job, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var jobs []Job
+	err = conn.AppJobs().Find(bson.M{"app": appName}).Sort("name").All(&jobs)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GetJob returns the job of the app with the given name.
+func GetJob(appName, name string) (*Job, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var j Job
+	err = conn.AppJobs().Find(bson.M{"app": appName, "name": name}).One(&j)
+	if err == mgo.ErrNotFound {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// RemoveJob removes a job of the app along with its run history.
+func RemoveJob(appName, name string) error {
+	j, err := GetJob(appName, name)
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.AppJobs().RemoveId(j.ID)
+	if err == mgo.ErrNotFound {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = conn.AppJobRuns().RemoveAll(bson.M{"app": appName, "job": name})
+	return err
+}
+
+// ListRuns returns the runs of a job, newest first. A limit of zero returns
+// the whole history.
+func ListRuns(appName, name string, limit int) ([]Run, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := conn.AppJobRuns().Find(bson.M{"app": appName, "job": name}).Sort("-start")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var runs []Run
+	err = query.All(&runs)
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// StartScheduler starts running jobs in background.
+func StartScheduler() *cron.Runner {
+	r := cron.NewRunner("app jobs", 30*time.Second)
+	r.Start(func(now time.Time) { runJobs(r, now) })
+	shutdown.Register(r)
+	return r
+}
+
+func runJobs(r *cron.Runner, now time.Time) {
+	conn, err := db.Conn()
+	if err != nil {
+		log.Errorf("[jobs] unable to connect to the database: %s", err)
+		return
+	}
+	var jobs []Job
+	err = conn.AppJobs().Find(bson.M{"nextrun": bson.M{"$lte": now}}).All(&jobs)
+	conn.Close()
+	if err != nil {
+		log.Errorf("[jobs] unable to list jobs: %s", err)
+		return
+	}
+	for i := range jobs {
+		j := &jobs[i]
+		claimed, err := claimJob(j, now)
+		if err != nil {
+			log.Errorf("[jobs] unable to claim job %s of app %s: %s", j.Name, j.App, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		r.Go(func() {
+			if _, err := runJob(j, now); err != nil {
+				log.Errorf("[jobs] unable to run job %s of app %s: %s", j.Name, j.App, err)
+			}
+		})
+	}
+}
+
+// claimJob moves the next run of the job forward, returning false when
+// another tsuru API instance has already claimed this run.
+func claimJob(j *Job, now time.Time) (bool, error) {
+	next, err := j.next(now)
+	if err != nil {
+		return false, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	claimed, err := cron.Claim(conn.AppJobs(), j.ID, j.NextRun, now, next)
+	if claimed {
+		j.LastRun, j.NextRun = now, next
+	}
+	return claimed, err
+}
+
+// runJob runs the command of the job in a new container, storing the run in
+// the history of the job.
+func runJob(j *Job, now time.Time) (*Run, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	run := Run{
+		ID:      bson.NewObjectId(),
+		App:     j.App,
+		Job:     j.Name,
+		Command: j.Command,
+		Status:  RunStatusRunning,
+		Start:   now,
+	}
+	err = conn.AppJobRuns().Insert(run)
+	if err != nil {
+		return nil, err
+	}
+	var output tailBuffer
+	var evt *event.Event
+	a, err := app.GetByName(j.App)
+	if err == nil {
+		err = checkCreator(j, a)
+	}
+	if err == nil {
+		err = a.CheckFreeze()
+	}
+	if err == nil {
+		evt, err = event.NewInternal(&event.Opts{
+			Target:       event.Target{Type: event.TargetTypeApp, Value: a.Name},
+			InternalKind: runEventKind,
+			RawOwner:     event.Owner{Type: event.OwnerTypeUser, Name: j.Creator},
+			CustomData:   j,
+			DisableLock:  true,
+			Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxApp, a.Name)),
+		})
+	}
+	if err == nil {
+		err = a.Run(j.Command, &output, provision.RunArgs{Isolated: true})
+		evt.Done(err)
+	}
+	run.End = time.Now().UTC()
+	run.Output = output.String()
+	run.Status = RunStatusSucceeded
+	if err != nil {
+		run.Status = RunStatusFailed
+		run.ExitCode = -1
+		if exitErr, ok := errors.Cause(err).(*provision.ExitError); ok {
+			run.ExitCode = exitErr.Code
+		}
+		run.Error = err.Error()
+	}
+	err = conn.AppJobRuns().UpdateId(run.ID, run)
+	if err != nil {
+		return nil, err
+	}
+	pruneRuns(conn, j)
+	return &run, nil
+}
+
+// checkCreator ensures the user who added the job is still allowed to run
+// commands in the app, so jobs don't outlive the permissions of their
+// creators.
+func checkCreator(j *Job, a *app.App) error {
+	u, err := auth.GetUserByEmail(j.Creator)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find the creator of the job %q", j.Creator)
+	}
+	perms, err := u.Permissions()
+	if err != nil {
+		return err
+	}
+	contexts := append(permission.Contexts(permission.CtxTeam, a.Teams),
+		permission.Context(permission.CtxApp, a.Name),
+		permission.Context(permission.CtxPool, a.Pool),
+	)
+	if !permission.CheckFromPermList(perms, permission.PermAppRun, contexts...) {
+		return errors.Errorf("the creator of the job, %s, is no longer allowed to run commands in the app", j.Creator)
+	}
+	return nil
+}
+
+// pruneRuns removes the oldest runs of the job, keeping the last maxRuns.
+func pruneRuns(conn *db.Storage, j *Job) {
+	var oldest Run
+	query := bson.M{"app": j.App, "job": j.Name}
+	err := conn.AppJobRuns().Find(query).Sort("-start").Skip(maxRuns - 1).One(&oldest)
+	if err != nil {
+		return
+	}
+	query["start"] = bson.M{"$lt": oldest.Start}
+	_, err = conn.AppJobRuns().RemoveAll(query)
+	if err != nil {
+		log.Errorf("[jobs] unable to prune runs of job %s of app %s: %s", j.Name, j.App, err)
+	}
+}
+
+// tailBuffer is a writer that keeps the last maxOutputSize bytes written to
+// it.
+type tailBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > maxOutputSize {
+		b.data = b.data[len(b.data)-maxOutputSize:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
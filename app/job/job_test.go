@@ -0,0 +1,198 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package job
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/cron"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestAddJob(c *check.C) {
+	j := Job{App: "myapp", Name: "cleanup", Command: "./cleanup.sh", Creator: jobCreator, Cron: "30 3 * * *", Timezone: "America/Sao_Paulo"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	c.Assert(j.ID.Valid(), check.Equals, true)
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	c.Assert(err, check.IsNil)
+	local := j.NextRun.In(loc)
+	c.Assert(local.Hour(), check.Equals, 3)
+	c.Assert(local.Minute(), check.Equals, 30)
+	stored, err := GetJob("myapp", "cleanup")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Command, check.Equals, "./cleanup.sh")
+	err = AddJob(&Job{App: "myapp", Name: "cleanup", Command: "true", Creator: jobCreator, Cron: "@daily"})
+	c.Assert(err, check.Equals, ErrJobAlreadyExists)
+	err = AddJob(&Job{App: "otherapp", Name: "cleanup", Command: "true", Creator: jobCreator, Cron: "@daily"})
+	c.Assert(err, check.IsNil)
+	jobs, err := ListJobs("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(jobs, check.HasLen, 1)
+}
+
+func (s *S) TestAddJobValidation(c *check.C) {
+	tests := []struct {
+		job Job
+		msg string
+	}{
+		{Job{Name: "j", Command: "true", Cron: "@daily"}, "app is required"},
+		{Job{App: "a", Name: "My_Job", Command: "true", Creator: jobCreator, Cron: "@daily"}, "invalid job name.*"},
+		{Job{App: "a", Name: "j", Cron: "@daily"}, "command is required"},
+		{Job{App: "a", Name: "j", Command: "true", Cron: "@daily"}, "creator is required"},
+		{Job{App: "a", Name: "j", Command: "true", Creator: jobCreator, Cron: "* *"}, "invalid cron expression .*"},
+		{Job{App: "a", Name: "j", Command: "true", Creator: jobCreator, Cron: "@daily", Timezone: "Mars/Olympus"}, "invalid timezone: Mars/Olympus"},
+	}
+	for _, tt := range tests {
+		err := AddJob(&tt.job)
+		c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+		c.Assert(err, check.ErrorMatches, tt.msg)
+	}
+}
+
+func (s *S) TestRemoveJob(c *check.C) {
+	j := Job{App: "myapp", Name: "cleanup", Command: "true", Creator: jobCreator, Cron: "@daily"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	err = s.conn.AppJobRuns().Insert(Run{ID: bson.NewObjectId(), App: "myapp", Job: "cleanup"})
+	c.Assert(err, check.IsNil)
+	err = RemoveJob("otherapp", "cleanup")
+	c.Assert(err, check.Equals, ErrJobNotFound)
+	err = RemoveJob("myapp", "cleanup")
+	c.Assert(err, check.IsNil)
+	_, err = GetJob("myapp", "cleanup")
+	c.Assert(err, check.Equals, ErrJobNotFound)
+	runs, err := ListRuns("myapp", "cleanup", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 0)
+}
+
+func (s *S) TestRunJobs(c *check.C) {
+	a := s.newApp(c, "myapp")
+	due := Job{App: a.Name, Name: "due", Command: "./report.sh", Creator: jobCreator, Cron: "0 8 * * *"}
+	err := AddJob(&due)
+	c.Assert(err, check.IsNil)
+	notDue := Job{App: a.Name, Name: "not-due", Command: "./other.sh", Creator: jobCreator, Cron: "0 22 * * *"}
+	err = AddJob(&notDue)
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC()
+	err = s.conn.AppJobs().UpdateId(due.ID, map[string]interface{}{"$set": map[string]interface{}{"nextrun": now.Add(-time.Minute)}})
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareOutput([]byte("report generated"))
+	r := cron.NewRunner("test jobs", time.Minute)
+	runJobs(r, now)
+	r.Wait()
+	cmds := s.provisioner.GetCmds("", a)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(strings.HasSuffix(cmds[0].Cmd, "./report.sh"), check.Equals, true)
+	runs, err := ListRuns(a.Name, "due", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 1)
+	c.Assert(runs[0].Status, check.Equals, RunStatusSucceeded)
+	c.Assert(runs[0].ExitCode, check.Equals, 0)
+	c.Assert(runs[0].Output, check.Equals, "report generated")
+	c.Assert(runs[0].End.IsZero(), check.Equals, false)
+	stored, err := GetJob(a.Name, "due")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.NextRun.After(now), check.Equals, true)
+	c.Assert(stored.LastRun.IsZero(), check.Equals, false)
+	runs, err = ListRuns(a.Name, "not-due", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 0)
+	runJobs(r, now)
+	r.Wait()
+	c.Assert(s.provisioner.GetCmds("", a), check.HasLen, 1)
+}
+
+func (s *S) TestRunJobFailure(c *check.C) {
+	a := s.newApp(c, "myapp")
+	j := Job{App: a.Name, Name: "migrate", Command: "./migrate.sh", Creator: jobCreator, Cron: "@daily"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	s.provisioner.PrepareFailure("ExecuteCommandIsolated", &provision.ExitError{Code: 3})
+	run, err := runJob(&j, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	c.Assert(run.Status, check.Equals, RunStatusFailed)
+	c.Assert(run.ExitCode, check.Equals, 3)
+	c.Assert(run.Error, check.Equals, "command exited with status 3")
+	runs, err := ListRuns(a.Name, "migrate", 1)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, 1)
+	c.Assert(runs[0].ID, check.Equals, run.ID)
+	c.Assert(runs[0].Status, check.Equals, RunStatusFailed)
+}
+
+func (s *S) TestRunJobFrozenApp(c *check.C) {
+	a := s.newApp(c, "myapp")
+	j := Job{App: a.Name, Name: "migrate", Command: "./migrate.sh", Creator: jobCreator, Cron: "@daily"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	err = app.AddFreeze(app.Freeze{App: a.Name, Reason: "black friday", Owner: "admin@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	run, err := runJob(&j, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	c.Assert(run.Status, check.Equals, RunStatusFailed)
+	c.Assert(run.Error, check.Matches, `app "myapp" is frozen .*`)
+	c.Assert(s.provisioner.GetCmds("", a), check.HasLen, 0)
+}
+
+func (s *S) TestRunJobCreatesEvent(c *check.C) {
+	a := s.newApp(c, "myapp")
+	j := Job{App: a.Name, Name: "report", Command: "./report.sh", Creator: jobCreator, Cron: "@daily"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	_, err = runJob(&j, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	evts, err := event.List(&event.Filter{KindName: runEventKind})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	c.Assert(evts[0].Target, check.DeepEquals, event.Target{Type: event.TargetTypeApp, Value: a.Name})
+	c.Assert(evts[0].Owner, check.DeepEquals, event.Owner{Type: event.OwnerTypeUser, Name: jobCreator})
+	c.Assert(evts[0].Running, check.Equals, false)
+	c.Assert(evts[0].Error, check.Equals, "")
+}
+
+func (s *S) TestRunJobCreatorWithoutPermission(c *check.C) {
+	a := s.newApp(c, "myapp")
+	j := Job{App: a.Name, Name: "report", Command: "./report.sh", Creator: jobCreator, Cron: "@daily"}
+	err := AddJob(&j)
+	c.Assert(err, check.IsNil)
+	err = s.user.RemoveRole("job-runner", "")
+	c.Assert(err, check.IsNil)
+	run, err := runJob(&j, time.Now().UTC())
+	c.Assert(err, check.IsNil)
+	c.Assert(run.Status, check.Equals, RunStatusFailed)
+	c.Assert(run.Error, check.Matches, ".*no longer allowed to run commands.*")
+	c.Assert(s.provisioner.GetCmds("", a), check.HasLen, 0)
+}
+
+func (s *S) TestRunJobPrunesHistory(c *check.C) {
+	j := Job{App: "myapp", Name: "cleanup", Command: "true", Creator: jobCreator, Cron: "@daily"}
+	start := time.Now().UTC().Add(-time.Hour)
+	for i := 0; i < maxRuns+5; i++ {
+		err := s.conn.AppJobRuns().Insert(Run{ID: bson.NewObjectId(), App: "myapp", Job: "cleanup", Start: start.Add(time.Duration(i) * time.Second)})
+		c.Assert(err, check.IsNil)
+	}
+	pruneRuns(s.conn, &j)
+	runs, err := ListRuns("myapp", "cleanup", 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(runs, check.HasLen, maxRuns)
+	c.Assert(runs[maxRuns-1].Start.Equal(start.Add(5*time.Second)), check.Equals, true)
+}
+
+func (s *S) TestTailBuffer(c *check.C) {
+	var b tailBuffer
+	b.Write([]byte(strings.Repeat("a", maxOutputSize)))
+	b.Write([]byte("end"))
+	out := b.String()
+	c.Assert(out, check.HasLen, maxOutputSize)
+	c.Assert(strings.HasSuffix(out, "aend"), check.Equals, true)
+}
@@ -6,6 +6,8 @@ package app
 
 import (
 	"encoding/json"
+	"regexp"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,8 +20,11 @@ var LogPubSubQueuePrefix = "pubsub:"
 var bulkMaxWaitTime = time.Second
 
 type LogListener struct {
-	c <-chan Applog
-	q queue.PubSubQ
+	c      <-chan Applog
+	q      queue.PubSubQ
+	stream *logStream
+	sub    *logSubscriber
+	closed int32
 }
 
 func logQueueName(appName string) string {
@@ -27,35 +32,18 @@ func logQueueName(appName string) string {
 }
 
 func NewLogListener(a *App, filterLog Applog) (*LogListener, error) {
-	factory, err := queue.Factory()
-	if err != nil {
-		return nil, err
-	}
-	pubSubQ, err := factory.PubSub(logQueueName(a.Name))
-	if err != nil {
-		return nil, err
-	}
-	subChan, err := pubSubQ.Sub()
+	return NewLogSearchListener(a, filterLog, nil)
+}
+
+// NewLogSearchListener works like NewLogListener, also skipping lines whose
+// message doesn't match the given pattern. Listeners of the same app share a
+// single pubsub subscription, see logStream.
+func NewLogSearchListener(a *App, filterLog Applog, message *regexp.Regexp) (*LogListener, error) {
+	stream, sub, err := logStreams.subscribe(a.Name, filterLog, message)
 	if err != nil {
 		return nil, err
 	}
-	c := make(chan Applog, 10)
-	go func() {
-		defer close(c)
-		for msg := range subChan {
-			applog := Applog{}
-			err := json.Unmarshal(msg, &applog)
-			if err != nil {
-				log.Errorf("Unparsable log message, ignoring: %s", string(msg))
-				continue
-			}
-			if (filterLog.Source == "" || filterLog.Source == applog.Source) &&
-				(filterLog.Unit == "" || filterLog.Unit == applog.Unit) {
-				c <- applog
-			}
-		}
-	}()
-	l := LogListener{c: c, q: pubSubQ}
+	l := LogListener{c: sub.c, q: stream.q, stream: stream, sub: sub}
 	return &l, nil
 }
 
@@ -63,14 +51,11 @@ func (l *LogListener) ListenChan() <-chan Applog {
 	return l.c
 }
 
-func (l *LogListener) Close() (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = errors.Errorf("Recovered panic closing listener (possible double close): %v", r)
-		}
-	}()
-	err = l.q.UnSub()
-	return
+func (l *LogListener) Close() error {
+	if !atomic.CompareAndSwapInt32(&l.closed, 0, 1) {
+		return errors.New("listener already closed")
+	}
+	return logStreams.unsubscribe(l.stream, l.sub)
 }
 
 func notify(appName string, messages []interface{}) {
@@ -100,6 +85,8 @@ func notify(appName string, messages []interface{}) {
 type logDispatcher struct {
 	dispatchers map[string]*appLogDispatcher
 	msgCh       chan *msgLog
+	limit       logRateLimit
+	limiter     *logRateLimiter
 }
 
 type msgLog struct {
@@ -111,6 +98,8 @@ func NewlogDispatcher(chanSize, numberGoroutines int) *logDispatcher {
 	d := &logDispatcher{
 		dispatchers: make(map[string]*appLogDispatcher),
 		msgCh:       make(chan *msgLog, chanSize),
+		limit:       logRateLimitFromConfig(),
+		limiter:     appLogRateLimiter,
 	}
 	for i := 0; i < numberGoroutines; i++ {
 		go d.runWriter()
@@ -131,13 +120,29 @@ func (d *logDispatcher) runWriter() {
 	}
 }
 
+// Send queues the message to be written to the log of its app. When the
+// server:app-log-rate-limit is enabled, messages from apps over the limit are
+// dropped and a single message with the number of dropped lines is written
+// before the next accepted one.
 func (d *logDispatcher) Send(msg *Applog) {
 	appName := msg.AppName
+	var dropped int
+	if d.limit.enabled() {
+		var allowed bool
+		allowed, dropped = d.limiter.take(appName, d.limit, time.Now())
+		if !allowed {
+			return
+		}
+	}
 	appD, ok := d.dispatchers[appName]
 	if !ok {
 		appD = newAppLogDispatcher(appName)
 		d.dispatchers[appName] = appD
 	}
+	parseStructuredLog(msg)
+	if dropped > 0 {
+		d.msgCh <- &msgLog{dispatcher: appD, msg: droppedLogsMessage(appName, dropped, d.limit)}
+	}
 	msgWithDispatcher := &msgLog{dispatcher: appD, msg: msg}
 	d.msgCh <- msgWithDispatcher
 }
@@ -154,6 +159,7 @@ type appLogDispatcher struct {
 	appName string
 	done    chan bool
 	toFlush chan *Applog
+	drains  *appLogDrains
 }
 
 func newAppLogDispatcher(appName string) *appLogDispatcher {
@@ -161,6 +167,7 @@ func newAppLogDispatcher(appName string) *appLogDispatcher {
 		appName: appName,
 		done:    make(chan bool),
 		toFlush: make(chan *Applog),
+		drains:  newAppLogDrains(appName),
 	}
 	go d.runFlusher()
 	return d
@@ -175,6 +182,7 @@ func (d *appLogDispatcher) runFlusher() {
 		var flush bool
 		select {
 		case <-d.done:
+			d.drains.close()
 			return
 		case msg := <-d.toFlush:
 			if pos == sz {
@@ -201,6 +209,7 @@ func (d *appLogDispatcher) runFlusher() {
 				log.Errorf("[log flusher] unable to insert logs: %s", err)
 				continue
 			}
+			d.drains.forward(bulkBuffer[:pos])
 			pos = 0
 		}
 	}
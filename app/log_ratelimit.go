@@ -0,0 +1,86 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+)
+
+// logRateLimit describes a token bucket for log lines: an app may send up to
+// Burst lines at once, and the bucket is refilled at Rate lines per second.
+type logRateLimit struct {
+	Rate  float64
+	Burst float64
+}
+
+func (l logRateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+func logRateLimitFromConfig() logRateLimit {
+	rate, _ := config.GetFloat("server:app-log-rate-limit:rate")
+	burst, _ := config.GetFloat("server:app-log-rate-limit:burst")
+	if burst < 1 {
+		burst = math.Max(1, rate)
+	}
+	return logRateLimit{Rate: rate, Burst: burst}
+}
+
+type logRateBucket struct {
+	tokens     float64
+	lastUpdate time.Time
+	dropped    int
+}
+
+// logRateLimiter holds one bucket per app. Buckets live in memory, so each
+// API instance enforces the limit on the logs it receives.
+type logRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*logRateBucket
+}
+
+var appLogRateLimiter = newLogRateLimiter()
+
+func newLogRateLimiter() *logRateLimiter {
+	return &logRateLimiter{buckets: make(map[string]*logRateBucket)}
+}
+
+// take removes one token from the bucket of the app. When the line is
+// accepted, it also returns the number of lines dropped since the last
+// accepted one, so the drop can be reported in the app log.
+func (l *logRateLimiter) take(appName string, limit logRateLimit, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bucket, ok := l.buckets[appName]
+	if !ok {
+		bucket = &logRateBucket{tokens: limit.Burst, lastUpdate: now}
+		l.buckets[appName] = bucket
+	}
+	elapsed := math.Max(0, now.Sub(bucket.lastUpdate).Seconds())
+	bucket.tokens = math.Min(limit.Burst, bucket.tokens+elapsed*limit.Rate)
+	bucket.lastUpdate = now
+	if bucket.tokens < 1 {
+		bucket.dropped++
+		return false, 0
+	}
+	bucket.tokens--
+	dropped := bucket.dropped
+	bucket.dropped = 0
+	return true, dropped
+}
+
+func droppedLogsMessage(appName string, dropped int, limit logRateLimit) *Applog {
+	return &Applog{
+		Date:    time.Now().In(time.UTC),
+		Message: fmt.Sprintf("%d log messages dropped, app exceeded the limit of %g lines per second", dropped, limit.Rate),
+		Source:  "tsuru",
+		AppName: appName,
+	}
+}
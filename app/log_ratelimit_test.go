@@ -0,0 +1,80 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/tsuru/config"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLogRateLimitFromConfig(c *check.C) {
+	c.Assert(logRateLimitFromConfig().enabled(), check.Equals, false)
+	config.Set("server:app-log-rate-limit:rate", 100)
+	defer config.Unset("server:app-log-rate-limit")
+	c.Assert(logRateLimitFromConfig(), check.Equals, logRateLimit{Rate: 100, Burst: 100})
+	config.Set("server:app-log-rate-limit:burst", 500)
+	c.Assert(logRateLimitFromConfig(), check.Equals, logRateLimit{Rate: 100, Burst: 500})
+}
+
+func (s *S) TestLogRateLimiterTake(c *check.C) {
+	limiter := newLogRateLimiter()
+	limit := logRateLimit{Rate: 2, Burst: 3}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		allowed, dropped := limiter.take("myapp", limit, now)
+		c.Assert(allowed, check.Equals, true)
+		c.Assert(dropped, check.Equals, 0)
+	}
+	for i := 0; i < 4; i++ {
+		allowed, _ := limiter.take("myapp", limit, now)
+		c.Assert(allowed, check.Equals, false)
+	}
+	allowed, _ := limiter.take("otherapp", limit, now)
+	c.Assert(allowed, check.Equals, true)
+	allowed, dropped := limiter.take("myapp", limit, now.Add(500*time.Millisecond))
+	c.Assert(allowed, check.Equals, true)
+	c.Assert(dropped, check.Equals, 4)
+	allowed, dropped = limiter.take("myapp", limit, now.Add(time.Second))
+	c.Assert(allowed, check.Equals, true)
+	c.Assert(dropped, check.Equals, 0)
+}
+
+func (s *S) TestLogDispatcherSendRateLimited(c *check.C) {
+	config.Set("server:app-log-rate-limit:rate", 1)
+	config.Set("server:app-log-rate-limit:burst", 2)
+	defer config.Unset("server:app-log-rate-limit")
+	app := App{Name: "myapp1", Platform: "zend", TeamOwner: s.team.Name}
+	err := CreateApp(&app, s.user)
+	c.Assert(err, check.IsNil)
+	dispatcher := NewlogDispatcher(2000000, runtime.NumCPU())
+	dispatcher.limiter = newLogRateLimiter()
+	for i := 0; i < 5; i++ {
+		dispatcher.Send(&Applog{Date: time.Now(), Message: "spam", Source: "web", AppName: "myapp1", Unit: "unit1"})
+	}
+	dispatcher.limiter.buckets["myapp1"].lastUpdate = time.Now().Add(-time.Second)
+	dispatcher.Send(&Applog{Date: time.Now(), Message: "last", Source: "web", AppName: "myapp1", Unit: "unit1"})
+	timeout := time.After(5 * time.Second)
+	var logs []Applog
+	for len(logs) < 4 {
+		select {
+		case <-timeout:
+			c.Fatalf("timeout waiting for logs, last count: %d", len(logs))
+		case <-time.After(100 * time.Millisecond):
+		}
+		logs, err = app.LastLogs(10, Applog{})
+		c.Assert(err, check.IsNil)
+	}
+	dispatcher.Stop()
+	var messages []string
+	for _, l := range logs {
+		messages = append(messages, l.Message)
+	}
+	sort.Strings(messages)
+	c.Assert(messages, check.DeepEquals, []string{"3 log messages dropped, app exceeded the limit of 1 lines per second", "last", "spam", "spam"})
+}
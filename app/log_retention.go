@@ -0,0 +1,142 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// LogRetention defines which logs of an app are kept. Lines is the maximum
+// number of lines stored, older lines are discarded as new ones are written.
+// Lines older than MaxAge are not returned anymore. Zero values are inherited
+// from the plan of the app and then from the server configuration.
+type LogRetention struct {
+	Lines  int           `json:"lines,omitempty"`
+	MaxAge time.Duration `json:"maxAge,omitempty"`
+}
+
+func (r *LogRetention) validate() error {
+	if r.Lines < 0 {
+		return &tsuruErrors.ValidationError{Message: "log lines must not be negative"}
+	}
+	if r.MaxAge < 0 {
+		return &tsuruErrors.ValidationError{Message: "log max age must not be negative"}
+	}
+	return nil
+}
+
+// GetLogRetention returns the log retention in effect for the app, merging
+// the retention set in the app, in its plan and in the server configuration.
+func (app *App) GetLogRetention() LogRetention {
+	var r LogRetention
+	r.Lines, _ = config.GetInt("server:app-log-retention:lines")
+	if r.Lines <= 0 {
+		r.Lines = db.DefaultLogLines
+	}
+	if maxAge, _ := config.GetInt("server:app-log-retention:max-age"); maxAge > 0 {
+		r.MaxAge = time.Duration(maxAge) * time.Second
+	}
+	for _, override := range []*LogRetention{app.Plan.LogRetention, app.LogRetention} {
+		if override == nil {
+			continue
+		}
+		if override.Lines > 0 {
+			r.Lines = override.Lines
+		}
+		if override.MaxAge > 0 {
+			r.MaxAge = override.MaxAge
+		}
+	}
+	return r
+}
+
+// SetLogRetention changes the log retention of the app, overriding the one
+// defined by its plan. Zero values go back to the plan or server defaults.
+// When the number of lines changes, the logs collection is resized keeping
+// the most recent lines.
+func (app *App) SetLogRetention(r LogRetention) error {
+	err := r.validate()
+	if err != nil {
+		return err
+	}
+	oldLines := app.GetLogRetention().Lines
+	app.LogRetention = &r
+	if r == (LogRetention{}) {
+		app.LogRetention = nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$set": bson.M{"logretention": app.LogRetention}})
+	if err != nil {
+		return err
+	}
+	if lines := app.GetLogRetention().Lines; lines != oldLines {
+		return app.resizeLogs(lines)
+	}
+	return nil
+}
+
+// PurgeLogs removes all logs of the app.
+func (app *App) PurgeLogs() error {
+	conn, err := db.LogConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Logs(app.Name).DropCollection()
+	if err != nil {
+		return err
+	}
+	return conn.CreateLogs(app.Name, app.GetLogRetention().Lines)
+}
+
+// createLogs creates the logs collection of the app, sized according to its
+// log retention.
+func (app *App) createLogs() error {
+	conn, err := db.LogConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.CreateLogs(app.Name, app.GetLogRetention().Lines)
+}
+
+// resizeLogs recreates the capped collection holding the logs of the app with
+// room for the given number of lines, copying the most recent lines to the
+// new collection. Lines written while the collection is recreated are lost.
+func (app *App) resizeLogs(lines int) error {
+	conn, err := db.LogConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	coll := conn.Logs(app.Name)
+	var logs []Applog
+	err = coll.Find(nil).Sort("-$natural").Limit(lines).All(&logs)
+	if err != nil {
+		return err
+	}
+	err = coll.DropCollection()
+	if err != nil {
+		return err
+	}
+	err = conn.CreateLogs(app.Name, lines)
+	if err != nil || len(logs) == 0 {
+		return err
+	}
+	docs := make([]interface{}, len(logs))
+	for i := range logs {
+		docs[len(logs)-1-i] = logs[i]
+	}
+	return conn.Logs(app.Name).Insert(docs...)
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetLogRetention(c *check.C) {
+	a := App{Name: "myapp"}
+	c.Assert(a.GetLogRetention(), check.Equals, LogRetention{Lines: 5000})
+	config.Set("server:app-log-retention:lines", 1000)
+	defer config.Unset("server:app-log-retention:lines")
+	config.Set("server:app-log-retention:max-age", 3600)
+	defer config.Unset("server:app-log-retention:max-age")
+	c.Assert(a.GetLogRetention(), check.Equals, LogRetention{Lines: 1000, MaxAge: time.Hour})
+	a.Plan.LogRetention = &LogRetention{Lines: 200}
+	c.Assert(a.GetLogRetention(), check.Equals, LogRetention{Lines: 200, MaxAge: time.Hour})
+	a.LogRetention = &LogRetention{MaxAge: time.Minute}
+	c.Assert(a.GetLogRetention(), check.Equals, LogRetention{Lines: 200, MaxAge: time.Minute})
+}
+
+func (s *S) TestSetLogRetention(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 10; i++ {
+		err = a.Log(strconv.Itoa(i), "tsuru", "unit1")
+		c.Assert(err, check.IsNil)
+	}
+	err = a.SetLogRetention(LogRetention{Lines: 5})
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.LogRetention, check.DeepEquals, &LogRetention{Lines: 5})
+	logs, err := a.LastLogs(10, Applog{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 5)
+	c.Assert(logs[0].Message, check.Equals, "5")
+	c.Assert(logs[4].Message, check.Equals, "9")
+	for i := 10; i < 13; i++ {
+		err = a.Log(strconv.Itoa(i), "tsuru", "unit1")
+		c.Assert(err, check.IsNil)
+	}
+	logs, err = a.LastLogs(10, Applog{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 5)
+	c.Assert(logs[0].Message, check.Equals, "8")
+	err = a.SetLogRetention(LogRetention{})
+	c.Assert(err, check.IsNil)
+	dbApp, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.LogRetention, check.IsNil)
+}
+
+func (s *S) TestSetLogRetentionInvalid(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetLogRetention(LogRetention{Lines: -1})
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	err = a.SetLogRetention(LogRetention{MaxAge: -time.Second})
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+}
+
+func (s *S) TestLastLogsMaxAge(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name, LogRetention: &LogRetention{MaxAge: time.Hour}}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	now := time.Now().UTC()
+	for i, date := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute)} {
+		err = s.logConn.Logs(a.Name).Insert(Applog{Date: date, Message: strconv.Itoa(i), Source: "tsuru", AppName: a.Name})
+		c.Assert(err, check.IsNil)
+	}
+	logs, err := a.LastLogs(10, Applog{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "1")
+}
+
+func (s *S) TestPurgeLogs(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.Log("some log", "tsuru", "unit1")
+	c.Assert(err, check.IsNil)
+	err = a.PurgeLogs()
+	c.Assert(err, check.IsNil)
+	logs, err := a.LastLogs(10, Applog{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 0)
+}
@@ -0,0 +1,207 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/queue"
+)
+
+const (
+	defaultLogStreamBuffer = 1000
+
+	logStreamDropOldest = "oldest"
+	logStreamDropNewest = "newest"
+)
+
+var logStreams = logStreamRegistry{streams: make(map[string]*logStream)}
+
+// logStreamRegistry keeps a single pubsub subscription per app, shared by
+// all clients following its log in this API instance.
+type logStreamRegistry struct {
+	sync.Mutex
+	streams map[string]*logStream
+}
+
+// logStream fans out the messages of the pubsub subscription of an app to
+// its subscribers. Each subscriber has its own buffer, so a slow client
+// never blocks the others, dropping messages according to the drop policy
+// once its buffer is full.
+type logStream struct {
+	appName     string
+	q           queue.PubSubQ
+	mu          sync.Mutex
+	subscribers map[*logSubscriber]struct{}
+}
+
+type logSubscriber struct {
+	c       chan Applog
+	filter  Applog
+	message *regexp.Regexp
+	policy  string
+	dropped int
+}
+
+// logStreamBufferSize returns the number of messages buffered for each
+// client, set in server:log-stream:buffer.
+func logStreamBufferSize() int {
+	size, _ := config.GetInt("server:log-stream:buffer")
+	if size <= 0 {
+		return defaultLogStreamBuffer
+	}
+	return size
+}
+
+// logStreamDropPolicy returns which messages are dropped when the buffer of
+// a client is full, set in server:log-stream:drop-policy: the oldest buffered
+// message (default) or the new one.
+func logStreamDropPolicy() string {
+	policy, _ := config.GetString("server:log-stream:drop-policy")
+	if policy != logStreamDropNewest {
+		return logStreamDropOldest
+	}
+	return policy
+}
+
+// subscribe adds a subscriber to the stream of the app, subscribing to its
+// pubsub queue if this is the first subscriber.
+func (r *logStreamRegistry) subscribe(appName string, filter Applog, message *regexp.Regexp) (*logStream, *logSubscriber, error) {
+	r.Lock()
+	defer r.Unlock()
+	stream, ok := r.streams[appName]
+	if !ok {
+		factory, err := queue.Factory()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubSubQ, err := factory.PubSub(logQueueName(appName))
+		if err != nil {
+			return nil, nil, err
+		}
+		subChan, err := pubSubQ.Sub()
+		if err != nil {
+			return nil, nil, err
+		}
+		stream = &logStream{
+			appName:     appName,
+			q:           pubSubQ,
+			subscribers: make(map[*logSubscriber]struct{}),
+		}
+		r.streams[appName] = stream
+		go stream.run(subChan)
+	}
+	filter.Level = normalizeLogLevel(filter.Level)
+	sub := &logSubscriber{
+		c:       make(chan Applog, logStreamBufferSize()),
+		filter:  filter,
+		message: message,
+		policy:  logStreamDropPolicy(),
+	}
+	stream.mu.Lock()
+	stream.subscribers[sub] = struct{}{}
+	stream.mu.Unlock()
+	return stream, sub, nil
+}
+
+// unsubscribe removes the subscriber from the stream, closing its channel,
+// and unsubscribes from the pubsub queue when no subscribers are left.
+func (r *logStreamRegistry) unsubscribe(stream *logStream, sub *logSubscriber) error {
+	r.Lock()
+	defer r.Unlock()
+	stream.mu.Lock()
+	if _, ok := stream.subscribers[sub]; ok {
+		delete(stream.subscribers, sub)
+		close(sub.c)
+	}
+	remaining := len(stream.subscribers)
+	stream.mu.Unlock()
+	if remaining > 0 || r.streams[stream.appName] != stream {
+		return nil
+	}
+	delete(r.streams, stream.appName)
+	return stream.q.UnSub()
+}
+
+func (s *logStream) run(subChan <-chan []byte) {
+	for msg := range subChan {
+		var applog Applog
+		err := json.Unmarshal(msg, &applog)
+		if err != nil {
+			log.Errorf("Unparsable log message, ignoring: %s", string(msg))
+			continue
+		}
+		s.mu.Lock()
+		for sub := range s.subscribers {
+			if sub.matches(&applog) {
+				sub.send(s.appName, applog)
+			}
+		}
+		s.mu.Unlock()
+	}
+	logStreams.Lock()
+	if logStreams.streams[s.appName] == s {
+		delete(logStreams.streams, s.appName)
+	}
+	logStreams.Unlock()
+	s.mu.Lock()
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.c)
+	}
+	s.mu.Unlock()
+}
+
+func (sub *logSubscriber) matches(applog *Applog) bool {
+	return (sub.filter.Source == "" || sub.filter.Source == applog.Source) &&
+		(sub.filter.Unit == "" || sub.filter.Unit == applog.Unit) &&
+		(sub.filter.Level == "" || sub.filter.Level == applog.Level) &&
+		(sub.filter.CorrelationID == "" || sub.filter.CorrelationID == applog.CorrelationID) &&
+		(sub.message == nil || sub.message.MatchString(applog.Message))
+}
+
+// send buffers the message without blocking. Once the buffer has room again
+// after messages were dropped, a message with the number of dropped lines is
+// buffered before the next one.
+func (sub *logSubscriber) send(appName string, applog Applog) {
+	if sub.dropped > 0 && len(sub.c) < cap(sub.c)-1 {
+		sub.c <- droppedStreamMessage(appName, sub.dropped)
+		sub.dropped = 0
+	}
+	select {
+	case sub.c <- applog:
+		return
+	default:
+	}
+	if sub.policy == logStreamDropNewest {
+		sub.dropped++
+		return
+	}
+	select {
+	case <-sub.c:
+		sub.dropped++
+	default:
+	}
+	select {
+	case sub.c <- applog:
+	default:
+		sub.dropped++
+	}
+}
+
+func droppedStreamMessage(appName string, dropped int) Applog {
+	return Applog{
+		Date:    time.Now().In(time.UTC),
+		Message: fmt.Sprintf("%d log messages dropped, client is not keeping up with the log stream", dropped),
+		Source:  "tsuru",
+		AppName: appName,
+	}
+}
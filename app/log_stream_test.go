@@ -0,0 +1,65 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/config"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLogListenersShareSubscription(c *check.C) {
+	app := App{Name: "myapp"}
+	l1, err := NewLogListener(&app, Applog{})
+	c.Assert(err, check.IsNil)
+	l2, err := NewLogListener(&app, Applog{Source: "web"})
+	c.Assert(err, check.IsNil)
+	c.Assert(l1.q, check.Equals, l2.q)
+	notify("myapp", []interface{}{Applog{Message: "1", Source: "worker"}, Applog{Message: "2", Source: "web"}})
+	c.Assert((<-l1.c).Message, check.Equals, "1")
+	c.Assert((<-l1.c).Message, check.Equals, "2")
+	c.Assert((<-l2.c).Message, check.Equals, "2")
+	err = l1.Close()
+	c.Assert(err, check.IsNil)
+	logStreams.Lock()
+	c.Assert(logStreams.streams["myapp"], check.NotNil)
+	logStreams.Unlock()
+	err = l2.Close()
+	c.Assert(err, check.IsNil)
+	logStreams.Lock()
+	c.Assert(logStreams.streams["myapp"], check.IsNil)
+	logStreams.Unlock()
+}
+
+func (s *S) TestLogSubscriberDropOldest(c *check.C) {
+	sub := &logSubscriber{c: make(chan Applog, 3), policy: logStreamDropOldest}
+	for _, msg := range []string{"1", "2", "3", "4", "5"} {
+		sub.send("myapp", Applog{Message: msg})
+	}
+	c.Assert(sub.dropped, check.Equals, 2)
+	c.Assert((<-sub.c).Message, check.Equals, "3")
+	c.Assert((<-sub.c).Message, check.Equals, "4")
+	sub.send("myapp", Applog{Message: "6"})
+	c.Assert((<-sub.c).Message, check.Equals, "5")
+	c.Assert((<-sub.c).Message, check.Equals, "2 log messages dropped, client is not keeping up with the log stream")
+	c.Assert((<-sub.c).Message, check.Equals, "6")
+	c.Assert(sub.dropped, check.Equals, 0)
+}
+
+func (s *S) TestLogSubscriberDropNewest(c *check.C) {
+	sub := &logSubscriber{c: make(chan Applog, 2), policy: logStreamDropNewest}
+	for _, msg := range []string{"1", "2", "3"} {
+		sub.send("myapp", Applog{Message: msg})
+	}
+	c.Assert(sub.dropped, check.Equals, 1)
+	c.Assert((<-sub.c).Message, check.Equals, "1")
+	c.Assert((<-sub.c).Message, check.Equals, "2")
+}
+
+func (s *S) TestLogStreamDropPolicy(c *check.C) {
+	c.Assert(logStreamDropPolicy(), check.Equals, logStreamDropOldest)
+	config.Set("server:log-stream:drop-policy", "newest")
+	defer config.Unset("server:log-stream:drop-policy")
+	c.Assert(logStreamDropPolicy(), check.Equals, logStreamDropNewest)
+}
@@ -0,0 +1,433 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrLogDrainNotFound      = errors.New("log drain not found")
+	ErrLogDrainAlreadyExists = errors.New("log drain already exists")
+
+	// logDrainsRefreshInterval is how often the log dispatcher reloads the
+	// drains of each app.
+	logDrainsRefreshInterval = time.Minute
+	logDrainTimeout          = 5 * time.Second
+	// logDrainQueueSize is the number of batches of messages of each app
+	// waiting to be forwarded. Batches are dropped when the drains of the
+	// app don't keep up.
+	logDrainQueueSize = 100
+
+	// privateNetworks are the address ranges reserved for private networks
+	// (RFC 1918 and RFC 4193), which log drains can't reach by default.
+	privateNetworks = parseNetworks("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+func isPrivateIP(ip net.IP) bool {
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogDrain is an external destination to which the logs of an app are
+// forwarded, in addition to being stored by tsuru. The URL scheme selects the
+// protocol: syslog (UDP) or syslog+tcp for remote syslog, udp for JSON
+// messages as accepted by logstash, and http or https for JSON messages sent
+// in POST requests.
+type LogDrain struct {
+	App string `json:"app"`
+	URL string `json:"url"`
+}
+
+func (d *LogDrain) validate() error {
+	u, err := url.Parse(d.URL)
+	if err != nil || u.Host == "" {
+		return &tsuruErrors.ValidationError{Message: fmt.Sprintf("invalid log drain URL %q", d.URL)}
+	}
+	switch u.Scheme {
+	case "syslog", "syslog+tcp", "udp", "http", "https":
+	default:
+		return &tsuruErrors.ValidationError{
+			Message: fmt.Sprintf("unsupported log drain scheme %q, must be syslog, syslog+tcp, udp, http or https", u.Scheme),
+		}
+	}
+	host := u.Host
+	if h, _, splitErr := net.SplitHostPort(u.Host); splitErr == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if err = checkLogDrainIP(ip); err != nil {
+			return &tsuruErrors.ValidationError{Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// checkLogDrainIP returns an error when logs can't be sent to the IP. Unless
+// server:log-drains:allow-private-networks is set, drains only reach public
+// addresses, so app owners can't make tsuru send data to internal services.
+func checkLogDrainIP(ip net.IP) error {
+	if allowPrivate, _ := config.GetBool("server:log-drains:allow-private-networks"); allowPrivate {
+		return nil
+	}
+	if ip.IsLoopback() || isPrivateIP(ip) || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return errors.Errorf("log drain destination %s is not a public address", ip)
+	}
+	return nil
+}
+
+// dialLogDrain connects to the address of a log drain, checking every address
+// the host resolves to, so DNS can't be used to reach internal addresses.
+func dialLogDrain(network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Errorf("no addresses found for %s", host)
+	}
+	for _, addr := range addrs {
+		if err = checkLogDrainIP(addr); err != nil {
+			return nil, err
+		}
+	}
+	dialer := net.Dialer{Timeout: logDrainTimeout}
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = dialer.Dial(network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// AddLogDrain starts forwarding the logs of the app to the given URL.
+func (app *App) AddLogDrain(rawURL string) error {
+	drain := LogDrain{App: app.Name, URL: rawURL}
+	if err := drain.validate(); err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.LogDrains().Insert(drain)
+	if mgo.IsDup(err) {
+		return ErrLogDrainAlreadyExists
+	}
+	return err
+}
+
+// RemoveLogDrain stops forwarding the logs of the app to the given URL.
+func (app *App) RemoveLogDrain(rawURL string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.LogDrains().Remove(bson.M{"app": app.Name, "url": rawURL})
+	if err == mgo.ErrNotFound {
+		return ErrLogDrainNotFound
+	}
+	return err
+}
+
+// LogDrains returns the log drains of the app.
+func (app *App) LogDrains() ([]LogDrain, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var drains []LogDrain
+	err = conn.LogDrains().Find(bson.M{"app": app.Name}).Sort("url").All(&drains)
+	if err != nil {
+		return nil, err
+	}
+	return drains, nil
+}
+
+type logForwarder interface {
+	Forward(msgs []*Applog) error
+	Close() error
+}
+
+func newLogForwarder(rawURL string) (logForwarder, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "syslog", "syslog+tcp":
+		network := "udp"
+		if u.Scheme == "syslog+tcp" {
+			network = "tcp"
+		}
+		conn, err := dialLogDrain(network, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &syslogForwarder{conn: conn}, nil
+	case "udp":
+		conn, err := dialLogDrain("udp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonForwarder{conn: conn}, nil
+	case "http", "https":
+		client := &http.Client{
+			Timeout:   logDrainTimeout,
+			Transport: &http.Transport{Dial: dialLogDrain},
+		}
+		return &httpForwarder{url: rawURL, client: client}, nil
+	}
+	return nil, errors.Errorf("unsupported log drain scheme %q", u.Scheme)
+}
+
+type drainMessage struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Message   string    `json:"message"`
+	App       string    `json:"app"`
+	Unit      string    `json:"unit"`
+	Source    string    `json:"source"`
+}
+
+func newDrainMessage(msg *Applog) drainMessage {
+	return drainMessage{
+		Timestamp: msg.Date,
+		Message:   msg.Message,
+		App:       msg.AppName,
+		Unit:      msg.Unit,
+		Source:    msg.Source,
+	}
+}
+
+// syslogForwarder writes messages in the RFC 5424 format, using the unit as
+// hostname, the app as app name and the source as process id.
+type syslogForwarder struct {
+	conn net.Conn
+}
+
+func (f *syslogForwarder) Forward(msgs []*Applog) error {
+	const priority = 14 // user.info
+	f.conn.SetWriteDeadline(time.Now().Add(logDrainTimeout))
+	for _, msg := range msgs {
+		_, err := fmt.Fprintf(f.conn, "<%d>1 %s %s %s %s - - %s\n", priority, msg.Date.UTC().Format(time.RFC3339Nano),
+			syslogField(msg.Unit), syslogField(msg.AppName), syslogField(msg.Source), msg.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *syslogForwarder) Close() error {
+	return f.conn.Close()
+}
+
+func syslogField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+type jsonForwarder struct {
+	conn net.Conn
+}
+
+func (f *jsonForwarder) Forward(msgs []*Applog) error {
+	f.conn.SetWriteDeadline(time.Now().Add(logDrainTimeout))
+	for _, msg := range msgs {
+		data, err := json.Marshal(newDrainMessage(msg))
+		if err != nil {
+			return err
+		}
+		_, err = f.conn.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *jsonForwarder) Close() error {
+	return f.conn.Close()
+}
+
+// httpForwarder sends each batch of messages as a JSON array.
+type httpForwarder struct {
+	url    string
+	client *http.Client
+}
+
+func (f *httpForwarder) Forward(msgs []*Applog) error {
+	batch := make([]drainMessage, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = newDrainMessage(msg)
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Post(f.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (f *httpForwarder) Close() error {
+	return nil
+}
+
+// appLogDrains forwards the logs of an app to its log drains, reloading them
+// periodically. Messages are forwarded in background, so a slow or failing
+// drain never blocks storing them.
+type appLogDrains struct {
+	appName    string
+	forwarders map[string]logForwarder
+	loadedAt   time.Time
+	queue      chan []*Applog
+	done       chan struct{}
+}
+
+func newAppLogDrains(appName string) *appLogDrains {
+	d := &appLogDrains{
+		appName:    appName,
+		forwarders: make(map[string]logForwarder),
+		queue:      make(chan []*Applog, logDrainQueueSize),
+		done:       make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+func (d *appLogDrains) run() {
+	defer close(d.done)
+	for msgs := range d.queue {
+		d.send(msgs)
+	}
+	for rawURL, f := range d.forwarders {
+		if f != nil {
+			f.Close()
+		}
+		delete(d.forwarders, rawURL)
+	}
+}
+
+func (d *appLogDrains) reload() {
+	if time.Since(d.loadedAt) < logDrainsRefreshInterval {
+		return
+	}
+	d.loadedAt = time.Now()
+	a := App{Name: d.appName}
+	drains, err := a.LogDrains()
+	if err != nil {
+		log.Errorf("[log drains] unable to load log drains of app %s: %s", d.appName, err)
+		return
+	}
+	current := make(map[string]bool, len(drains))
+	for _, drain := range drains {
+		current[drain.URL] = true
+		if _, ok := d.forwarders[drain.URL]; !ok {
+			d.forwarders[drain.URL] = nil
+		}
+	}
+	for rawURL, f := range d.forwarders {
+		if !current[rawURL] {
+			if f != nil {
+				f.Close()
+			}
+			delete(d.forwarders, rawURL)
+		}
+	}
+}
+
+// forward queues the messages to be sent to the log drains, dropping them
+// when the queue is full.
+func (d *appLogDrains) forward(bulk []interface{}) {
+	msgs := make([]*Applog, len(bulk))
+	for i := range bulk {
+		msgs[i] = bulk[i].(*Applog)
+	}
+	select {
+	case d.queue <- msgs:
+	default:
+		log.Errorf("[log drains] queue of app %s is full, dropping %d messages", d.appName, len(msgs))
+	}
+}
+
+func (d *appLogDrains) send(msgs []*Applog) {
+	d.reload()
+	for rawURL, f := range d.forwarders {
+		if f == nil {
+			var err error
+			f, err = newLogForwarder(rawURL)
+			if err != nil {
+				log.Errorf("[log drains] unable to connect to log drain %s of app %s: %s", rawURL, d.appName, err)
+				continue
+			}
+			d.forwarders[rawURL] = f
+		}
+		if err := f.Forward(msgs); err != nil {
+			log.Errorf("[log drains] unable to forward logs to %s of app %s: %s", rawURL, d.appName, err)
+			f.Close()
+			d.forwarders[rawURL] = nil
+		}
+	}
+}
+
+// close stops forwarding messages, waiting for the queued ones to be sent.
+func (d *appLogDrains) close() {
+	close(d.queue)
+	<-d.done
+}
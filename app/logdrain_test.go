@@ -0,0 +1,197 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAddLogDrain(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("syslog://logs.example.com:514")
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("https://logs.example.com/ingest")
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("syslog://logs.example.com:514")
+	c.Assert(err, check.Equals, ErrLogDrainAlreadyExists)
+	drains, err := a.LogDrains()
+	c.Assert(err, check.IsNil)
+	c.Assert(drains, check.DeepEquals, []LogDrain{
+		{App: "myapp", URL: "https://logs.example.com/ingest"},
+		{App: "myapp", URL: "syslog://logs.example.com:514"},
+	})
+}
+
+func (s *S) TestAddLogDrainInvalidURL(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("ftp://logs.example.com")
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	c.Assert(err, check.ErrorMatches, `unsupported log drain scheme "ftp".*`)
+	err = a.AddLogDrain("logs.example.com")
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	drains, err := a.LogDrains()
+	c.Assert(err, check.IsNil)
+	c.Assert(drains, check.HasLen, 0)
+}
+
+func (s *S) TestRemoveLogDrain(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("udp://logstash.example.com:5000")
+	c.Assert(err, check.IsNil)
+	err = a.RemoveLogDrain("udp://logstash.example.com:5000")
+	c.Assert(err, check.IsNil)
+	drains, err := a.LogDrains()
+	c.Assert(err, check.IsNil)
+	c.Assert(drains, check.HasLen, 0)
+	err = a.RemoveLogDrain("udp://logstash.example.com:5000")
+	c.Assert(err, check.Equals, ErrLogDrainNotFound)
+}
+
+func (s *S) TestAppLogDrainsForwardHTTP(c *check.C) {
+	config.Set("server:log-drains:allow-private-networks", true)
+	defer config.Unset("server:log-drains:allow-private-networks")
+	var received []drainMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewDecoder(r.Body).Decode(&received)
+		c.Assert(err, check.IsNil)
+	}))
+	defer server.Close()
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain(server.URL)
+	c.Assert(err, check.IsNil)
+	date := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	drains := newAppLogDrains("myapp")
+	drains.forward([]interface{}{
+		&Applog{Date: date, Message: "msg1", Source: "web", AppName: "myapp", Unit: "unit1"},
+	})
+	drains.close()
+	c.Assert(received, check.HasLen, 1)
+	c.Assert(received[0].Message, check.Equals, "msg1")
+	c.Assert(received[0].App, check.Equals, "myapp")
+	c.Assert(received[0].Unit, check.Equals, "unit1")
+	c.Assert(received[0].Source, check.Equals, "web")
+	c.Assert(received[0].Timestamp.Equal(date), check.Equals, true)
+}
+
+func (s *S) TestAppLogDrainsForwardSyslog(c *check.C) {
+	config.Set("server:log-drains:allow-private-networks", true)
+	defer config.Unset("server:log-drains:allow-private-networks")
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err = CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("syslog://" + listener.LocalAddr().String())
+	c.Assert(err, check.IsNil)
+	date := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	drains := newAppLogDrains("myapp")
+	defer drains.close()
+	drains.forward([]interface{}{
+		&Applog{Date: date, Message: "msg1", Source: "web", AppName: "myapp", Unit: "unit1"},
+	})
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := listener.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(buf[:n]), check.Equals, "<14>1 2016-12-20T10:00:00Z unit1 myapp web - - msg1\n")
+}
+
+func (s *S) TestAddLogDrainPrivateAddress(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	for _, rawURL := range []string{
+		"syslog://127.0.0.1:514",
+		"syslog+tcp://localhost:514",
+		"http://169.254.169.254/latest/meta-data",
+		"udp://10.0.0.5:5000",
+		"https://[::1]/ingest",
+		"http://192.168.0.10/ingest",
+		"syslog://172.20.1.1:514",
+		"https://[fd00::1]:8443/ingest",
+	} {
+		err = a.AddLogDrain(rawURL)
+		c.Check(err, check.FitsTypeOf, &errors.ValidationError{}, check.Commentf(rawURL))
+		c.Check(err, check.ErrorMatches, "log drain destination .* is not a public address", check.Commentf(rawURL))
+	}
+	config.Set("server:log-drains:allow-private-networks", true)
+	defer config.Unset("server:log-drains:allow-private-networks")
+	err = a.AddLogDrain("syslog://10.0.0.5:514")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestDialLogDrainPrivateAddress(c *check.C) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	_, err = dialLogDrain("tcp", net.JoinHostPort("localhost", port))
+	c.Assert(err, check.ErrorMatches, "log drain destination .* is not a public address")
+}
+
+type blockingForwarder struct {
+	release chan bool
+}
+
+func (f *blockingForwarder) Forward(msgs []*Applog) error {
+	<-f.release
+	return nil
+}
+
+func (f *blockingForwarder) Close() error {
+	return nil
+}
+
+func (s *S) TestAppLogDrainsForwardDoesNotBlock(c *check.C) {
+	forwarder := &blockingForwarder{release: make(chan bool)}
+	drains := newAppLogDrains("myapp")
+	drains.loadedAt = time.Now()
+	drains.forwarders["syslog://logs.example.com:514"] = forwarder
+	msgs := []interface{}{&Applog{Message: "msg1", AppName: "myapp"}}
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < logDrainQueueSize+10; i++ {
+			drains.forward(msgs)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("forwarding logs blocked on a slow drain")
+	}
+	close(forwarder.release)
+	drains.close()
+}
+
+func (s *S) TestDeleteRemovesLogDrains(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.AddLogDrain("syslog://logs.example.com:514")
+	c.Assert(err, check.IsNil)
+	err = Delete(&a, nil)
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.LogDrains().Find(nil).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
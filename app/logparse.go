@@ -0,0 +1,83 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	logLevelKeys     = []string{"level", "lvl", "severity", "loglevel"}
+	logTimestampKeys = []string{"timestamp", "time", "ts", "@timestamp"}
+	logLevelAliases  = map[string]string{
+		"warn":  "warning",
+		"err":   "error",
+		"crit":  "critical",
+		"fatal": "critical",
+		"panic": "critical",
+		"trace": "debug",
+	}
+)
+
+// parseStructuredLog detects log lines written as JSON objects and fills the
+// level and timestamp of the log with the values found in the line. The
+// message is kept as sent by the app, lines that are not JSON objects are
+// left untouched.
+func parseStructuredLog(l *Applog) {
+	msg := strings.TrimSpace(l.Message)
+	if !strings.HasPrefix(msg, "{") {
+		return
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(msg), &fields); err != nil {
+		return
+	}
+	for _, key := range logLevelKeys {
+		if level, ok := fields[key].(string); ok && level != "" {
+			l.Level = normalizeLogLevel(level)
+			break
+		}
+	}
+	for _, key := range logTimestampKeys {
+		if ts, ok := parseLogTimestamp(fields[key]); ok {
+			l.Timestamp = ts
+			break
+		}
+	}
+}
+
+func normalizeLogLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if alias, ok := logLevelAliases[level]; ok {
+		return alias
+	}
+	return level
+}
+
+// parseLogTimestamp accepts RFC 3339 strings and numeric Unix timestamps, in
+// seconds or milliseconds.
+func parseLogTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return ts.UTC(), true
+	case float64:
+		if v <= 0 {
+			return time.Time{}, false
+		}
+		if v > 1e12 {
+			v /= 1000
+		}
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
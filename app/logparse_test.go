@@ -0,0 +1,53 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestParseStructuredLog(c *check.C) {
+	tests := []struct {
+		msg       string
+		level     string
+		timestamp time.Time
+	}{
+		{"plain text message", "", time.Time{}},
+		{"{not json", "", time.Time{}},
+		{`{"level":"info","msg":"started"}`, "info", time.Time{}},
+		{`{"severity":"WARN","time":"2016-10-01T10:00:00Z"}`, "warning", time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC)},
+		{` {"lvl":"fatal","ts":1475316000.5}`, "critical", time.Date(2016, 10, 1, 10, 0, 0, 5e8, time.UTC)},
+		{`{"level":"error","timestamp":1475316000000}`, "error", time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC)},
+		{`{"level":3,"time":"yesterday"}`, "", time.Time{}},
+	}
+	for _, tt := range tests {
+		l := Applog{Message: tt.msg}
+		parseStructuredLog(&l)
+		c.Check(l.Message, check.Equals, tt.msg)
+		c.Check(l.Level, check.Equals, tt.level, check.Commentf("message: %s", tt.msg))
+		c.Check(l.Timestamp.Equal(tt.timestamp), check.Equals, true, check.Commentf("message: %s, got %s", tt.msg, l.Timestamp))
+	}
+}
+
+func (s *S) TestLastLogsFilterByLevel(c *check.C) {
+	a := App{Name: "myapp", Platform: "zend", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.Log(`{"level":"info","msg":"started"}`+"\n"+`{"level":"warn","msg":"slow"}`, "app", "unit1")
+	c.Assert(err, check.IsNil)
+	err = a.Log("plain text", "app", "unit1")
+	c.Assert(err, check.IsNil)
+	logs, err := a.LastLogs(10, Applog{Level: "WARNING"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, `{"level":"warn","msg":"slow"}`)
+	c.Assert(logs[0].Level, check.Equals, "warning")
+	logs, err = a.LastLogs(10, Applog{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 3)
+	c.Assert(logs[2].Level, check.Equals, "")
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"time"
+
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+)
+
+const (
+	// maxLogPatternLength limits the size of expressions used to search logs,
+	// as they're evaluated by the database against every stored line.
+	maxLogPatternLength = 256
+
+	// maxLogPatternRepeat limits counted repetitions, like a{1,1000}, in
+	// expressions used to search logs.
+	maxLogPatternRepeat = 100
+
+	// logSearchTimeout is how long the database may spend searching the logs
+	// of an app.
+	logSearchTimeout = 10 * time.Second
+
+	// mongoExceededTimeLimit is the error code returned by MongoDB when a
+	// query takes longer than its max time.
+	mongoExceededTimeLimit = 50
+)
+
+// LogMessagePattern builds the pattern used to search log messages, matching
+// messages that contain text or, when expr is given, messages matching the
+// regular expression expr. Expressions are evaluated both by the database and
+// by tsuru, so they must use the syntax shared by RE2 and PCRE. The database
+// evaluates them by backtracking, so quantifiers can't be applied to groups
+// with other quantifiers or alternatives, like (a+)+ or (a|ab)*. A nil pattern
+// is returned when both text and expr are empty.
+func LogMessagePattern(text, expr string, ignoreCase bool) (*regexp.Regexp, error) {
+	if text != "" && expr != "" {
+		return nil, &tsuruErrors.ValidationError{Message: "either a text or a regular expression must be provided, not both"}
+	}
+	if text == "" && expr == "" {
+		return nil, nil
+	}
+	if len(text) > maxLogPatternLength || len(expr) > maxLogPatternLength {
+		return nil, &tsuruErrors.ValidationError{Message: "log search pattern is too long"}
+	}
+	if text != "" {
+		expr = regexp.QuoteMeta(text)
+	} else if err := checkLogPatternSyntax(expr); err != nil {
+		return nil, err
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, &tsuruErrors.ValidationError{Message: "invalid regular expression: " + err.Error()}
+	}
+	return pattern, nil
+}
+
+func checkLogPatternSyntax(expr string) error {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return &tsuruErrors.ValidationError{Message: "invalid regular expression: " + err.Error()}
+	}
+	return checkLogPatternNode(re, false)
+}
+
+func checkLogPatternNode(re *syntax.Regexp, repeated bool) error {
+	switch re.Op {
+	case syntax.OpRepeat:
+		if re.Max > maxLogPatternRepeat || re.Min > maxLogPatternRepeat {
+			return &tsuruErrors.ValidationError{Message: "repetitions in log search patterns are limited to 100"}
+		}
+		fallthrough
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		if repeated {
+			return &tsuruErrors.ValidationError{Message: "nested quantifiers are not allowed in log search patterns"}
+		}
+		repeated = true
+	case syntax.OpAlternate:
+		if repeated {
+			return &tsuruErrors.ValidationError{Message: "quantified alternatives are not allowed in log search patterns"}
+		}
+	}
+	for _, sub := range re.Sub {
+		if err := checkLogPatternNode(sub, repeated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLogMessagePattern(c *check.C) {
+	pattern, err := LogMessagePattern("", "", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(pattern, check.IsNil)
+	pattern, err = LogMessagePattern("a.b", "", false)
+	c.Assert(err, check.IsNil)
+	c.Assert(pattern.String(), check.Equals, `a\.b`)
+	pattern, err = LogMessagePattern("", "^a.b$", true)
+	c.Assert(err, check.IsNil)
+	c.Assert(pattern.String(), check.Equals, "(?i)^a.b$")
+	c.Assert(pattern.MatchString("AxB"), check.Equals, true)
+	_, err = LogMessagePattern("a", "b", false)
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	_, err = LogMessagePattern("", "(a", false)
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	_, err = LogMessagePattern(strings.Repeat("a", 300), "", false)
+	c.Assert(err, check.ErrorMatches, "log search pattern is too long")
+}
+
+func (s *S) TestSearchLogs(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	base := time.Date(2016, 12, 20, 10, 0, 0, 0, time.UTC)
+	for i, msg := range []string{"connection refused", "request done", "Connection reset", "connection refused"} {
+		err = s.logConn.Logs(a.Name).Insert(Applog{
+			Date:    base.Add(time.Duration(i) * time.Minute),
+			Message: msg,
+			Source:  "app",
+			AppName: a.Name,
+		})
+		c.Assert(err, check.IsNil)
+	}
+	pattern, err := LogMessagePattern("", "^connection", true)
+	c.Assert(err, check.IsNil)
+	logs, err := a.SearchLogs(10, Applog{}, pattern, time.Time{}, base.Add(2*time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+	c.Assert(logs[0].Message, check.Equals, "connection refused")
+	c.Assert(logs[1].Message, check.Equals, "Connection reset")
+	pattern, err = LogMessagePattern("refused", "", false)
+	c.Assert(err, check.IsNil)
+	logs, err = a.SearchLogs(10, Applog{}, pattern, time.Time{}, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 2)
+}
+
+func (s *S) TestLogMessagePatternRestrictedSyntax(c *check.C) {
+	for _, expr := range []string{"^(a+)+$", "(a*)*b", "(foo|foobar)*", "(a{2})+", "a{1,1000}", "a{101}"} {
+		_, err := LogMessagePattern("", expr, false)
+		c.Check(err, check.FitsTypeOf, &errors.ValidationError{}, check.Commentf("expr: %s", expr))
+	}
+	for _, expr := range []string{"^connection (refused|reset)", "[a-z]+ done$", "a{1,100}", "(ab)+c"} {
+		_, err := LogMessagePattern("", expr, false)
+		c.Check(err, check.IsNil, check.Commentf("expr: %s", expr))
+	}
+	_, err := LogMessagePattern("(a+)+", "", false)
+	c.Assert(err, check.IsNil)
+}
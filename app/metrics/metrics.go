@@ -0,0 +1,253 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics periodically samples the resources used by the units of
+// apps and by nodes, storing them as time series in the database or pushing
+// them to external sinks, such as statsd and graphite.
+package metrics
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	defaultInterval  = time.Minute
+	defaultRetention = 7 * 24 * time.Hour
+)
+
+// Sample is the resource usage of an app unit at a given time. CPU is a
+// percentage of one CPU, Memory is in bytes and NetRx and NetTx are the total
+// bytes received and sent by the unit. DiskUsed and DiskTotal are only set
+// when the provisioner samples the filesystems of units.
+type Sample struct {
+	App       string    `json:"app"`
+	Unit      string    `json:"unit"`
+	Process   string    `json:"process"`
+	Date      time.Time `json:"date"`
+	CPU       float64   `json:"cpu"`
+	Memory    uint64    `json:"memory"`
+	NetRx     uint64    `json:"netrx"`
+	NetTx     uint64    `json:"nettx"`
+	DiskUsed  uint64    `json:"diskused,omitempty"`
+	DiskTotal uint64    `json:"disktotal,omitempty"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Collector periodically samples the units of all apps. Nodes are sampled
+// by up to concurrency goroutines, each node waiting for a random delay of up
+// to jitter before being sampled, so that nodes aren't inspected in lockstep.
+type Collector struct {
+	interval    time.Duration
+	retention   time.Duration
+	jitter      time.Duration
+	nodeTimeout time.Duration
+	concurrency int
+	quit        chan bool
+	wg          sync.WaitGroup
+}
+
+// Initialize starts the metrics collector if metrics:enabled is set. It
+// returns nil when the collection is disabled.
+func Initialize() (*Collector, error) {
+	enabled, _ := config.GetBool("metrics:enabled")
+	if !enabled {
+		return nil, nil
+	}
+	c := newCollector()
+	c.start()
+	shutdown.Register(c)
+	return c, nil
+}
+
+func newCollector() *Collector {
+	c := &Collector{
+		interval:  collectInterval(),
+		retention: retention(),
+		quit:      make(chan bool),
+	}
+	if jitter, _ := config.GetInt("metrics:collect-jitter"); jitter > 0 {
+		c.jitter = time.Duration(jitter) * time.Second
+	}
+	if timeout, _ := config.GetInt("metrics:node-timeout"); timeout > 0 {
+		c.nodeTimeout = time.Duration(timeout) * time.Second
+	}
+	c.concurrency, _ = config.GetInt("metrics:node-concurrency")
+	return c
+}
+
+func collectInterval() time.Duration {
+	if interval, _ := config.GetInt("metrics:collect-interval"); interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return defaultInterval
+}
+
+func (c *Collector) start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			c.runOnce()
+			select {
+			case <-c.quit:
+				return
+			case <-time.After(c.interval + c.randomJitter()):
+			}
+		}
+	}()
+}
+
+// randomJitter returns a random duration between zero and the jitter of the
+// collector.
+func (c *Collector) randomJitter() time.Duration {
+	if c.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(c.jitter)))
+}
+
+func (c *Collector) Shutdown() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+func (c *Collector) String() string {
+	return "app metrics collector"
+}
+
+func (c *Collector) runOnce() {
+	apps, err := app.List(nil)
+	if err != nil {
+		log.Errorf("[metrics] unable to list apps: %s", err)
+		return
+	}
+	for i := range apps {
+		err = c.collect(&apps[i])
+		if err != nil {
+			log.Errorf("[metrics] unable to collect metrics of app %s: %s", apps[i].Name, err)
+		}
+		err = c.collectStatuses(&apps[i])
+		if err != nil {
+			log.Errorf("[metrics] unable to collect unit statuses of app %s: %s", apps[i].Name, err)
+		}
+		err = apps[i].CheckSLO()
+		if err != nil {
+			log.Errorf("[metrics] unable to check SLO of app %s: %s", apps[i].Name, err)
+		}
+	}
+	c.collectNodes()
+}
+
+// collect samples the units of the app, unless another API instance sampled
+// them during the current interval, storing the samples in the database when
+// the internal sink is enabled and reporting them to the external sinks.
+func (c *Collector) collect(a *app.App) error {
+	now := time.Now().UTC()
+	internal := storesInternal()
+	var conn *db.Storage
+	if internal {
+		var err error
+		conn, err = db.Conn()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		var last Sample
+		err = conn.AppMetrics().Find(bson.M{"app": a.Name}).Sort("-date").One(&last)
+		if err == nil && now.Sub(last.Date) < c.interval/2 {
+			return nil
+		}
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+	unitsMetrics, err := a.UnitsMetrics()
+	if err != nil {
+		if _, ok := err.(provision.ProvisionerNotSupported); ok {
+			return nil
+		}
+		return err
+	}
+	if len(unitsMetrics) == 0 {
+		return nil
+	}
+	samples := make([]interface{}, len(unitsMetrics))
+	for i, m := range unitsMetrics {
+		sample := Sample{
+			App:       a.Name,
+			Unit:      m.ID,
+			Process:   m.Process,
+			Date:      now,
+			CPU:       m.CPU,
+			Memory:    m.Memory,
+			NetRx:     m.NetRx,
+			NetTx:     m.NetTx,
+			DiskUsed:  m.DiskUsed,
+			DiskTotal: m.DiskTotal,
+			ExpiresAt: now.Add(c.retention),
+		}
+		samples[i] = sample
+		Report(sample.points()...)
+	}
+	if !internal {
+		return nil
+	}
+	return conn.AppMetrics().Insert(samples...)
+}
+
+// points returns the sample as gauges named
+// apps.<app>.<process>.<unit>.<metric>. Disk gauges are only included when
+// the filesystems of the unit were sampled.
+func (s *Sample) points() []Point {
+	unit := s.Unit
+	if len(unit) > 12 {
+		unit = unit[:12]
+	}
+	prefix := fmt.Sprintf("apps.%s.%s.%s.", sanitizeName(s.App), sanitizeName(s.Process), sanitizeName(unit))
+	points := []Point{
+		{Name: prefix + "cpu", Value: s.CPU, Type: Gauge, Date: s.Date},
+		{Name: prefix + "memory", Value: float64(s.Memory), Type: Gauge, Date: s.Date},
+		{Name: prefix + "netrx", Value: float64(s.NetRx), Type: Gauge, Date: s.Date},
+		{Name: prefix + "nettx", Value: float64(s.NetTx), Type: Gauge, Date: s.Date},
+	}
+	if s.DiskTotal > 0 {
+		points = append(points,
+			Point{Name: prefix + "disk_used", Value: float64(s.DiskUsed), Type: Gauge, Date: s.Date},
+			Point{Name: prefix + "disk_total", Value: float64(s.DiskTotal), Type: Gauge, Date: s.Date},
+		)
+	}
+	return points
+}
+
+// List returns the samples of the units of the app taken between since and
+// until, sorted by date. A zero until means no upper bound.
+func List(appName string, since, until time.Time) ([]Sample, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	dateQuery := bson.M{"$gte": since}
+	if !until.IsZero() {
+		dateQuery["$lte"] = until
+	}
+	var samples []Sample
+	err = conn.AppMetrics().Find(bson.M{"app": appName, "date": dateQuery}).Sort("date", "unit").All(&samples)
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestCollectorCollect(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	units, err := s.provisioner.Units(a)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.SetUnitsMetrics(a, []provision.UnitMetrics{
+		{ID: units[0].ID, Process: "web", CPU: 12.5, Memory: 1024, NetRx: 10, NetTx: 20},
+		{ID: units[1].ID, Process: "web", CPU: 50, Memory: 2048, NetRx: 30, NetTx: 40, DiskUsed: 100, DiskTotal: 1000},
+	})
+	c.Assert(err, check.IsNil)
+	collector := &Collector{interval: time.Minute, retention: time.Hour}
+	before := time.Now().Add(-time.Second)
+	collector.runOnce()
+	samples, err := List("myapp", before, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 2)
+	c.Assert(samples[0].Unit, check.Equals, units[0].ID)
+	c.Assert(samples[0].Process, check.Equals, "web")
+	c.Assert(samples[0].CPU, check.Equals, 12.5)
+	c.Assert(samples[0].Memory, check.Equals, uint64(1024))
+	c.Assert(samples[0].NetRx, check.Equals, uint64(10))
+	c.Assert(samples[0].NetTx, check.Equals, uint64(20))
+	c.Assert(samples[0].ExpiresAt.Sub(samples[0].Date), check.Equals, time.Hour)
+	c.Assert(samples[1].Unit, check.Equals, units[1].ID)
+	c.Assert(samples[1].CPU, check.Equals, 50.0)
+	c.Assert(samples[1].DiskUsed, check.Equals, uint64(100))
+	c.Assert(samples[1].DiskTotal, check.Equals, uint64(1000))
+}
+
+func (s *S) TestSamplePointsWithDisk(c *check.C) {
+	sample := Sample{App: "myapp", Unit: "unit1", Process: "web"}
+	c.Assert(sample.points(), check.HasLen, 4)
+	sample.DiskUsed = 100
+	sample.DiskTotal = 1000
+	points := sample.points()
+	c.Assert(points, check.HasLen, 6)
+	c.Assert(points[4].Name, check.Equals, "apps.myapp.web.unit1.disk_used")
+	c.Assert(points[4].Value, check.Equals, 100.0)
+	c.Assert(points[5].Name, check.Equals, "apps.myapp.web.unit1.disk_total")
+}
+
+func (s *S) TestCollectorCollectSkipsRecentlySampledApps(c *check.C) {
+	s.newApp(c, "myapp", 1)
+	collector := &Collector{interval: time.Minute, retention: time.Hour}
+	collector.runOnce()
+	collector.runOnce()
+	count, err := s.conn.AppMetrics().Find(bson.M{"app": "myapp"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 1)
+}
+
+func (s *S) TestList(c *check.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		err := s.conn.AppMetrics().Insert(Sample{
+			App:  "myapp",
+			Unit: "unit1",
+			Date: now.Add(time.Duration(i) * time.Minute),
+			CPU:  float64(i),
+		})
+		c.Assert(err, check.IsNil)
+	}
+	err := s.conn.AppMetrics().Insert(Sample{App: "otherapp", Unit: "unit2", Date: now})
+	c.Assert(err, check.IsNil)
+	samples, err := List("myapp", now.Add(time.Minute), time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 2)
+	c.Assert(samples[0].CPU, check.Equals, 1.0)
+	c.Assert(samples[1].CPU, check.Equals, 2.0)
+	samples, err = List("myapp", now, now.Add(time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 2)
+	c.Assert(samples[0].CPU, check.Equals, 0.0)
+	c.Assert(samples[1].CPU, check.Equals, 1.0)
+}
@@ -0,0 +1,186 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// NodeSample is the resource usage of a node at a given time, as described
+// in provision.NodeMetrics.
+type NodeSample struct {
+	Node              string    `json:"node"`
+	Pool              string    `json:"pool"`
+	Date              time.Time `json:"date"`
+	CPUs              int       `json:"cpus"`
+	CPU               float64   `json:"cpu"`
+	MemoryTotal       uint64    `json:"memoryTotal"`
+	MemoryUsed        uint64    `json:"memoryUsed"`
+	DiskTotal         uint64    `json:"diskTotal"`
+	DiskUsed          uint64    `json:"diskUsed"`
+	Containers        int       `json:"containers"`
+	ContainersRunning int       `json:"containersRunning"`
+	ExpiresAt         time.Time `json:"-"`
+}
+
+func (c *Collector) collectNodes() {
+	provisioners, err := provision.Registry()
+	if err != nil {
+		log.Errorf("[metrics] unable to list provisioners: %s", err)
+		return
+	}
+	for _, p := range provisioners {
+		nodeProv, ok := p.(provision.NodeProvisioner)
+		if !ok {
+			continue
+		}
+		metricsProv, ok := p.(provision.NodeMetricsProvisioner)
+		if !ok {
+			continue
+		}
+		nodes, err := nodeProv.ListNodes(nil)
+		if err != nil {
+			log.Errorf("[metrics] unable to list nodes: %s", err)
+			continue
+		}
+		concurrency := c.concurrency
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, n := range nodes {
+			wg.Add(1)
+			go func(n provision.Node) {
+				defer wg.Done()
+				time.Sleep(c.randomJitter())
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				err := c.collectNodeWithTimeout(metricsProv, n)
+				if err != nil {
+					log.Errorf("[metrics] unable to collect metrics of node %s: %s", n.Address(), err)
+				}
+			}(n)
+		}
+		wg.Wait()
+	}
+}
+
+// collectNodeWithTimeout samples the node, giving up after the node timeout
+// of the collector, when set. The sample is discarded when it finishes after
+// the timeout.
+func (c *Collector) collectNodeWithTimeout(p provision.NodeMetricsProvisioner, n provision.Node) error {
+	if c.nodeTimeout <= 0 {
+		return c.collectNode(p, n, nil)
+	}
+	timedOut := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.collectNode(p, n, timedOut)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(c.nodeTimeout):
+		close(timedOut)
+		return errors.Errorf("timed out after %s", c.nodeTimeout)
+	}
+}
+
+// collectNode samples the node, unless another API instance sampled it
+// during the current interval, following the same rules used for apps. The
+// sample is discarded if timedOut is closed before it's stored.
+func (c *Collector) collectNode(p provision.NodeMetricsProvisioner, n provision.Node, timedOut <-chan struct{}) error {
+	now := time.Now().UTC()
+	internal := storesInternal()
+	var conn *db.Storage
+	if internal {
+		var err error
+		conn, err = db.Conn()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		var last NodeSample
+		err = conn.NodeMetrics().Find(bson.M{"node": n.Address()}).Sort("-date").One(&last)
+		if err == nil && now.Sub(last.Date) < c.interval/2 {
+			return nil
+		}
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+	m, err := p.NodeMetrics(n)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-timedOut:
+		return nil
+	default:
+	}
+	sample := NodeSample{
+		Node:              n.Address(),
+		Pool:              n.Pool(),
+		Date:              now,
+		CPUs:              m.CPUs,
+		CPU:               m.CPU,
+		MemoryTotal:       m.MemoryTotal,
+		MemoryUsed:        m.MemoryUsed,
+		DiskTotal:         m.DiskTotal,
+		DiskUsed:          m.DiskUsed,
+		Containers:        m.Containers,
+		ContainersRunning: m.ContainersRunning,
+		ExpiresAt:         now.Add(c.retention),
+	}
+	Report(sample.points()...)
+	if !internal {
+		return nil
+	}
+	return conn.NodeMetrics().Insert(sample)
+}
+
+// points returns the sample as gauges named nodes.<host>.<metric>.
+func (s *NodeSample) points() []Point {
+	prefix := fmt.Sprintf("nodes.%s.", sanitizeName(net.URLToHost(s.Node)))
+	return []Point{
+		{Name: prefix + "cpu", Value: s.CPU, Type: Gauge, Date: s.Date},
+		{Name: prefix + "memory_used", Value: float64(s.MemoryUsed), Type: Gauge, Date: s.Date},
+		{Name: prefix + "memory_total", Value: float64(s.MemoryTotal), Type: Gauge, Date: s.Date},
+		{Name: prefix + "disk_used", Value: float64(s.DiskUsed), Type: Gauge, Date: s.Date},
+		{Name: prefix + "disk_total", Value: float64(s.DiskTotal), Type: Gauge, Date: s.Date},
+		{Name: prefix + "containers", Value: float64(s.ContainersRunning), Type: Gauge, Date: s.Date},
+	}
+}
+
+// ListNodeSamples returns the samples of the node taken between since and
+// until, sorted by date. A zero until means no upper bound.
+func ListNodeSamples(address string, since, until time.Time) ([]NodeSample, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	dateQuery := bson.M{"$gte": since}
+	if !until.IsZero() {
+		dateQuery["$lte"] = until
+	}
+	var samples []NodeSample
+	err = conn.NodeMetrics().Find(bson.M{"node": address, "date": dateQuery}).Sort("date").All(&samples)
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCollectorCollectNodes(c *check.C) {
+	err := s.provisioner.AddNode(provision.AddNodeOptions{
+		Address:  "http://node1:2375",
+		Metadata: map[string]string{"pool": "pool1"},
+	})
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.SetNodeMetrics("http://node1:2375", provision.NodeMetrics{
+		CPUs:              4,
+		CPU:               150,
+		MemoryTotal:       8192,
+		MemoryUsed:        1024,
+		DiskTotal:         100,
+		DiskUsed:          10,
+		Containers:        3,
+		ContainersRunning: 2,
+	})
+	c.Assert(err, check.IsNil)
+	collector := &Collector{interval: time.Minute, retention: time.Hour}
+	before := time.Now().Add(-time.Second)
+	collector.runOnce()
+	collector.runOnce()
+	samples, err := ListNodeSamples("http://node1:2375", before, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 1)
+	c.Assert(samples[0].Pool, check.Equals, "pool1")
+	c.Assert(samples[0].CPUs, check.Equals, 4)
+	c.Assert(samples[0].CPU, check.Equals, 150.0)
+	c.Assert(samples[0].MemoryUsed, check.Equals, uint64(1024))
+	c.Assert(samples[0].DiskTotal, check.Equals, uint64(100))
+	c.Assert(samples[0].ContainersRunning, check.Equals, 2)
+	c.Assert(samples[0].ExpiresAt.Sub(samples[0].Date), check.Equals, time.Hour)
+}
+
+func (s *S) TestListNodeSamples(c *check.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		err := s.conn.NodeMetrics().Insert(NodeSample{
+			Node: "http://node1:2375",
+			Date: now.Add(time.Duration(i) * time.Minute),
+			CPU:  float64(i),
+		})
+		c.Assert(err, check.IsNil)
+	}
+	err := s.conn.NodeMetrics().Insert(NodeSample{Node: "http://node2:2375", Date: now})
+	c.Assert(err, check.IsNil)
+	samples, err := ListNodeSamples("http://node1:2375", now.Add(time.Minute), time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 2)
+	c.Assert(samples[0].CPU, check.Equals, 1.0)
+	c.Assert(samples[1].CPU, check.Equals, 2.0)
+}
+
+func (s *S) TestCollectorCollectNodesConcurrently(c *check.C) {
+	for _, addr := range []string{"http://node1:2375", "http://node2:2375", "http://node3:2375"} {
+		err := s.provisioner.AddNode(provision.AddNodeOptions{Address: addr})
+		c.Assert(err, check.IsNil)
+		err = s.provisioner.SetNodeMetrics(addr, provision.NodeMetrics{CPUs: 2})
+		c.Assert(err, check.IsNil)
+	}
+	collector := &Collector{
+		interval:    time.Minute,
+		retention:   time.Hour,
+		jitter:      10 * time.Millisecond,
+		nodeTimeout: time.Minute,
+		concurrency: 2,
+	}
+	collector.collectNodes()
+	count, err := s.conn.NodeMetrics().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 3)
+}
+
+func (s *S) TestCollectorRandomJitter(c *check.C) {
+	collector := &Collector{}
+	c.Assert(collector.randomJitter(), check.Equals, time.Duration(0))
+	collector.jitter = time.Second
+	for i := 0; i < 10; i++ {
+		jitter := collector.randomJitter()
+		c.Assert(jitter >= 0 && jitter < time.Second, check.Equals, true)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+// AppUsage is the usage of an app in a month. Unit hours and memory hours
+// are estimated from the resource usage samples, each sample accounting for
+// one collect interval, and memory hours are in gigabyte-hours.
+type AppUsage struct {
+	App         string  `json:"app"`
+	UnitHours   float64 `json:"unitHours"`
+	MemoryHours float64 `json:"memoryHours"`
+	Deploys     int     `json:"deploys"`
+}
+
+// TeamUsage is the usage of the apps owned by a team in a month.
+type TeamUsage struct {
+	Team        string     `json:"team"`
+	UnitHours   float64    `json:"unitHours"`
+	MemoryHours float64    `json:"memoryHours"`
+	Deploys     int        `json:"deploys"`
+	Apps        []AppUsage `json:"apps"`
+}
+
+type sampleAggregate struct {
+	App     string `bson:"_id"`
+	Samples int
+	Memory  float64
+}
+
+type deployAggregate struct {
+	App   string `bson:"_id"`
+	Count int
+}
+
+// UsageReport returns the usage of the apps of each team in the month
+// starting at the given time, sorted by team name. Samples are only
+// available for the period set in metrics:retention, which must be longer
+// than a month for complete reports. Apps removed since are reported with an
+// empty team.
+func UsageReport(month time.Time) ([]TeamUsage, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var samples []sampleAggregate
+	err = conn.AppMetrics().Pipe([]bson.M{
+		{"$match": bson.M{"date": bson.M{"$gte": start, "$lt": end}}},
+		{"$group": bson.M{"_id": "$app", "samples": bson.M{"$sum": 1}, "memory": bson.M{"$sum": "$memory"}}},
+	}).All(&samples)
+	if err != nil {
+		return nil, err
+	}
+	var deploys []deployAggregate
+	err = conn.Events().Pipe([]bson.M{
+		{"$match": bson.M{
+			"kind.name":   permission.PermAppDeploy.FullName(),
+			"target.type": "app",
+			"starttime":   bson.M{"$gte": start, "$lt": end},
+		}},
+		{"$group": bson.M{"_id": "$target.value", "count": bson.M{"$sum": 1}}},
+	}).All(&deploys)
+	if err != nil {
+		return nil, err
+	}
+	usages := make(map[string]*AppUsage)
+	appUsage := func(name string) *AppUsage {
+		if usages[name] == nil {
+			usages[name] = &AppUsage{App: name}
+		}
+		return usages[name]
+	}
+	hours := collectInterval().Hours()
+	for _, s := range samples {
+		u := appUsage(s.App)
+		u.UnitHours = float64(s.Samples) * hours
+		u.MemoryHours = s.Memory / gigabyte * hours
+	}
+	for _, d := range deploys {
+		appUsage(d.App).Deploys = d.Count
+	}
+	apps, err := app.List(nil)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string, len(apps))
+	for _, a := range apps {
+		owners[a.Name] = a.TeamOwner
+	}
+	teams := make(map[string]*TeamUsage)
+	for name, u := range usages {
+		team := owners[name]
+		t := teams[team]
+		if t == nil {
+			t = &TeamUsage{Team: team}
+			teams[team] = t
+		}
+		t.UnitHours += u.UnitHours
+		t.MemoryHours += u.MemoryHours
+		t.Deploys += u.Deploys
+		t.Apps = append(t.Apps, *u)
+	}
+	report := make([]TeamUsage, 0, len(teams))
+	for _, t := range teams {
+		sort.Sort(appUsageByName(t.Apps))
+		report = append(report, *t)
+	}
+	sort.Sort(teamUsageByName(report))
+	return report, nil
+}
+
+type appUsageByName []AppUsage
+
+func (l appUsageByName) Len() int           { return len(l) }
+func (l appUsageByName) Less(i, j int) bool { return l[i].App < l[j].App }
+func (l appUsageByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type teamUsageByName []TeamUsage
+
+func (l teamUsageByName) Len() int           { return len(l) }
+func (l teamUsageByName) Less(i, j int) bool { return l[i].Team < l[j].Team }
+func (l teamUsageByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -0,0 +1,60 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestUsageReport(c *check.C) {
+	config.Set("metrics:collect-interval", 1800)
+	defer config.Unset("metrics:collect-interval")
+	err := s.conn.Apps().Insert(
+		app.App{Name: "app1", TeamOwner: "team1"},
+		app.App{Name: "app2", TeamOwner: "team1"},
+		app.App{Name: "app3", TeamOwner: "team2"},
+	)
+	c.Assert(err, check.IsNil)
+	month := time.Date(2016, time.November, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{App: "app1", Unit: "u1", Date: month.Add(time.Hour), Memory: gigabyte},
+		{App: "app1", Unit: "u2", Date: month.Add(time.Hour), Memory: gigabyte},
+		{App: "app2", Unit: "u3", Date: month.Add(48 * time.Hour), Memory: 2 * gigabyte},
+		{App: "app3", Unit: "u4", Date: month.AddDate(0, 1, 0), Memory: gigabyte},
+		{App: "removed", Unit: "u5", Date: month.Add(time.Hour), Memory: gigabyte},
+	}
+	for _, sample := range samples {
+		err = s.conn.AppMetrics().Insert(sample)
+		c.Assert(err, check.IsNil)
+	}
+	for _, date := range []time.Time{month.Add(time.Hour), month.Add(2 * time.Hour), month.AddDate(0, -1, 0)} {
+		err = s.conn.Events().Insert(bson.M{
+			"_id":       bson.NewObjectId(),
+			"kind":      bson.M{"type": "permission", "name": "app.deploy"},
+			"target":    bson.M{"type": "app", "value": "app3"},
+			"starttime": date,
+		})
+		c.Assert(err, check.IsNil)
+	}
+	report, err := UsageReport(month.Add(10 * 24 * time.Hour))
+	c.Assert(err, check.IsNil)
+	c.Assert(report, check.DeepEquals, []TeamUsage{
+		{Team: "", UnitHours: 0.5, MemoryHours: 0.5, Apps: []AppUsage{
+			{App: "removed", UnitHours: 0.5, MemoryHours: 0.5},
+		}},
+		{Team: "team1", UnitHours: 1.5, MemoryHours: 2, Apps: []AppUsage{
+			{App: "app1", UnitHours: 1, MemoryHours: 1},
+			{App: "app2", UnitHours: 0.5, MemoryHours: 1},
+		}},
+		{Team: "team2", Deploys: 2, Apps: []AppUsage{
+			{App: "app3", Deploys: 2},
+		}},
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// RequestSample is a request to an app sampled by a router. Backend is the
+// address the router sent the request to, in the form host:port, and is used
+// to find the unit that handled the request. Latency is the time the backend
+// took to answer the request.
+type RequestSample struct {
+	App        string        `json:"app"`
+	Unit       string        `json:"unit"`
+	Backend    string        `json:"backend"`
+	Date       time.Time     `json:"date"`
+	Latency    time.Duration `json:"latency"`
+	StatusCode int           `json:"status"`
+	ExpiresAt  time.Time     `json:"-"`
+}
+
+// RequestStats summarizes the sampled requests handled by a unit of an app.
+// Errors is the number of requests answered with a 5xx status code.
+type RequestStats struct {
+	Unit    string        `json:"unit"`
+	Backend string        `json:"backend"`
+	Count   int           `json:"count"`
+	Errors  int           `json:"errors"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+}
+
+// RecordRequests stores requests to the app sampled by a router. Requests
+// whose backend doesn't match any unit of the app are stored without a unit,
+// as the unit may have been removed after the request was sampled. Samples
+// are kept for the same period as the resource usage of units.
+func RecordRequests(a *app.App, samples []RequestSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	units, err := a.Units()
+	if err != nil {
+		return err
+	}
+	unitsByBackend := make(map[string]string, len(units))
+	for _, u := range units {
+		if u.Address != nil {
+			unitsByBackend[u.Address.Host] = u.ID
+		}
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(retention())
+	docs := make([]interface{}, len(samples))
+	for i, s := range samples {
+		if s.Backend == "" {
+			return &tsuruErrors.ValidationError{Message: "backend is required"}
+		}
+		if s.Latency < 0 {
+			return &tsuruErrors.ValidationError{Message: "latency must not be negative"}
+		}
+		s.App = a.Name
+		s.Backend = backendHost(s.Backend)
+		s.Unit = unitsByBackend[s.Backend]
+		if s.Date.IsZero() {
+			s.Date = now
+		}
+		s.ExpiresAt = expiresAt
+		docs[i] = s
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.AppRequests().Insert(docs...)
+}
+
+// backendHost strips the scheme and path from backends reported as URLs.
+func backendHost(backend string) string {
+	if !strings.Contains(backend, "://") {
+		return backend
+	}
+	u, err := url.Parse(backend)
+	if err != nil || u.Host == "" {
+		return backend
+	}
+	return u.Host
+}
+
+// RequestsStats returns the latency percentiles of the requests to each unit
+// of the app sampled between since and until, sorted by p95, slowest first.
+// A zero until means no upper bound.
+func RequestsStats(appName string, since, until time.Time) ([]RequestStats, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	dateQuery := bson.M{"$gte": since}
+	if !until.IsZero() {
+		dateQuery["$lte"] = until
+	}
+	var samples []RequestSample
+	err = conn.AppRequests().Find(bson.M{"app": appName, "date": dateQuery}).All(&samples)
+	if err != nil {
+		return nil, err
+	}
+	byBackend := make(map[string][]RequestSample)
+	for _, s := range samples {
+		byBackend[s.Backend] = append(byBackend[s.Backend], s)
+	}
+	stats := make([]RequestStats, 0, len(byBackend))
+	for backend, samples := range byBackend {
+		stats = append(stats, summarizeRequests(backend, samples))
+	}
+	sort.Sort(requestStatsBySlowest(stats))
+	return stats, nil
+}
+
+func summarizeRequests(backend string, samples []RequestSample) RequestStats {
+	stats := RequestStats{Backend: backend, Count: len(samples)}
+	latencies := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		latencies[i] = s.Latency
+		if s.StatusCode >= 500 {
+			stats.Errors++
+		}
+		if s.Unit != "" {
+			stats.Unit = s.Unit
+		}
+	}
+	sort.Sort(durationSlice(latencies))
+	stats.P50 = percentile(latencies, 50)
+	stats.P95 = percentile(latencies, 95)
+	stats.P99 = percentile(latencies, 99)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func retention() time.Duration {
+	if retention, _ := config.GetInt("metrics:retention"); retention > 0 {
+		return time.Duration(retention) * time.Second
+	}
+	return defaultRetention
+}
+
+type durationSlice []time.Duration
+
+func (s durationSlice) Len() int           { return len(s) }
+func (s durationSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s durationSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type requestStatsBySlowest []RequestStats
+
+func (s requestStatsBySlowest) Len() int      { return len(s) }
+func (s requestStatsBySlowest) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s requestStatsBySlowest) Less(i, j int) bool {
+	if s[i].P95 != s[j].P95 {
+		return s[i].P95 > s[j].P95
+	}
+	return s[i].Backend < s[j].Backend
+}
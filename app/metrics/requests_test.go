@@ -0,0 +1,54 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRecordRequests(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	units, err := a.Units()
+	c.Assert(err, check.IsNil)
+	before := time.Now().Add(-time.Second)
+	var samples []RequestSample
+	for i := 1; i <= 20; i++ {
+		samples = append(samples,
+			RequestSample{Backend: units[0].Address.Host, Latency: time.Duration(i) * time.Millisecond, StatusCode: 200},
+			RequestSample{Backend: units[1].Address.String(), Latency: time.Duration(i) * time.Second, StatusCode: 200},
+		)
+	}
+	samples = append(samples,
+		RequestSample{Backend: units[1].Address.Host, Latency: time.Second, StatusCode: 502},
+		RequestSample{Backend: "10.0.0.1:8888", Latency: time.Millisecond, StatusCode: 200},
+	)
+	err = RecordRequests(a, samples)
+	c.Assert(err, check.IsNil)
+	stats, err := RequestsStats(a.Name, before, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.DeepEquals, []RequestStats{
+		{Unit: units[1].ID, Backend: units[1].Address.Host, Count: 21, Errors: 1, P50: 10 * time.Second, P95: 19 * time.Second, P99: 20 * time.Second},
+		{Unit: units[0].ID, Backend: units[0].Address.Host, Count: 20, P50: 10 * time.Millisecond, P95: 19 * time.Millisecond, P99: 20 * time.Millisecond},
+		{Backend: "10.0.0.1:8888", Count: 1, P50: time.Millisecond, P95: time.Millisecond, P99: time.Millisecond},
+	})
+	stats, err = RequestsStats("otherapp", before, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Assert(stats, check.HasLen, 0)
+}
+
+func (s *S) TestRecordRequestsInvalid(c *check.C) {
+	a := s.newApp(c, "myapp", 1)
+	err := RecordRequests(a, []RequestSample{{Latency: time.Second}})
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	c.Assert(err, check.ErrorMatches, "backend is required")
+	err = RecordRequests(a, []RequestSample{{Backend: "10.0.0.1:8888", Latency: -time.Second}})
+	c.Assert(err, check.ErrorMatches, "latency must not be negative")
+	count, err := s.conn.AppRequests().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
@@ -0,0 +1,335 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/log"
+)
+
+const (
+	sinkInternal = "internal"
+	sinkStatsd   = "statsd"
+	sinkGraphite = "graphite"
+
+	defaultPrefix        = "tsuru."
+	defaultFlushInterval = 10 * time.Second
+	maxPendingPoints     = 10000
+	sinkTimeout          = 5 * time.Second
+
+	// statsdPacketSize keeps statsd packets below the usual MTU.
+	statsdPacketSize = 1400
+)
+
+// PointType defines how a point is aggregated by sinks.
+type PointType int
+
+const (
+	Gauge PointType = iota
+	Counter
+	Timing
+)
+
+// Point is a single value of a metric. The value of Timing points is in
+// milliseconds.
+type Point struct {
+	Name  string
+	Value float64
+	Type  PointType
+	Date  time.Time
+}
+
+// Sink pushes points to an external metrics backend.
+type Sink interface {
+	Send(points []Point) error
+}
+
+var (
+	reporterMu     sync.Mutex
+	activeReporter *reporter
+	storeInternal  = true
+)
+
+// StartReporter configures the sinks listed in metrics:sinks and starts
+// pushing points to them. The internal sink, which is the default, stores
+// unit samples in the database, and is the only one read by the API.
+func StartReporter() error {
+	names, err := config.GetList("metrics:sinks")
+	if err != nil {
+		names = []string{sinkInternal}
+	}
+	var sinks []Sink
+	internal := false
+	for _, name := range names {
+		switch name {
+		case sinkInternal:
+			internal = true
+		case sinkStatsd, sinkGraphite:
+			address, _ := config.GetString("metrics:" + name + ":address")
+			if address == "" {
+				return errors.Errorf(`metrics sink %q requires "metrics:%s:address"`, name, name)
+			}
+			prefix, err := config.GetString("metrics:" + name + ":prefix")
+			if err != nil {
+				prefix = defaultPrefix
+			}
+			if name == sinkStatsd {
+				sinks = append(sinks, &statsdSink{address: address, prefix: prefix})
+			} else {
+				sinks = append(sinks, &graphiteSink{address: address, prefix: prefix})
+			}
+		default:
+			return errors.Errorf("unknown metrics sink %q", name)
+		}
+	}
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	storeInternal = internal
+	if len(sinks) == 0 {
+		return nil
+	}
+	interval := defaultFlushInterval
+	if seconds, _ := config.GetInt("metrics:flush-interval"); seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	if activeReporter != nil {
+		activeReporter.stop()
+	}
+	activeReporter = newReporter(sinks, interval)
+	activeReporter.start()
+	shutdown.Register(reporterShutdown{})
+	return nil
+}
+
+// StopReporter stops pushing points to the external sinks, after sending the
+// pending ones.
+func StopReporter() {
+	reporterMu.Lock()
+	r := activeReporter
+	activeReporter = nil
+	storeInternal = true
+	reporterMu.Unlock()
+	if r != nil {
+		r.stop()
+	}
+}
+
+type reporterShutdown struct{}
+
+func (reporterShutdown) Shutdown() {
+	StopReporter()
+}
+
+func (reporterShutdown) String() string {
+	return "metrics reporter"
+}
+
+// Report queues points to be pushed to the external sinks. It does nothing
+// when there are no external sinks configured.
+func Report(points ...Point) {
+	reporterMu.Lock()
+	r := activeReporter
+	reporterMu.Unlock()
+	if r == nil {
+		return
+	}
+	r.add(points)
+}
+
+// Reporting returns whether there are external sinks configured.
+func Reporting() bool {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	return activeReporter != nil
+}
+
+func storesInternal() bool {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	return storeInternal
+}
+
+// reporter accumulates points and pushes them to the sinks periodically, so
+// reporting a point never blocks the caller on the network.
+type reporter struct {
+	sinks    []Sink
+	interval time.Duration
+	mu       sync.Mutex
+	pending  []Point
+	quit     chan bool
+	wg       sync.WaitGroup
+}
+
+func newReporter(sinks []Sink, interval time.Duration) *reporter {
+	return &reporter{sinks: sinks, interval: interval, quit: make(chan bool)}
+}
+
+func (r *reporter) add(points []Point) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range points {
+		if len(r.pending) >= maxPendingPoints {
+			return
+		}
+		if p.Date.IsZero() {
+			p.Date = now
+		}
+		r.pending = append(r.pending, p)
+	}
+}
+
+func (r *reporter) start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-r.quit:
+				r.flush()
+				return
+			case <-time.After(r.interval):
+				r.flush()
+			}
+		}
+	}()
+}
+
+func (r *reporter) flush() {
+	r.mu.Lock()
+	points := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if len(points) == 0 {
+		return
+	}
+	for _, s := range r.sinks {
+		if err := s.Send(points); err != nil {
+			log.Errorf("[metrics] unable to send metrics to %s: %s", s, err)
+		}
+	}
+}
+
+func (r *reporter) stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// sanitizeName replaces characters with special meaning in metric paths.
+func sanitizeName(name string) string {
+	return strings.NewReplacer(".", "_", " ", "_", ":", "_", "|", "_", "/", "_").Replace(name)
+}
+
+// statsdSink sends points to statsd over UDP, leaving the aggregation of
+// counters and timings to statsd.
+type statsdSink struct {
+	address string
+	prefix  string
+}
+
+func (s *statsdSink) Send(points []Point) error {
+	conn, err := net.DialTimeout("udp", s.address, sinkTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var buf bytes.Buffer
+	for _, p := range points {
+		var suffix string
+		switch p.Type {
+		case Counter:
+			suffix = "c"
+		case Timing:
+			suffix = "ms"
+		default:
+			suffix = "g"
+		}
+		line := fmt.Sprintf("%s%s:%g|%s\n", s.prefix, p.Name, p.Value, suffix)
+		if buf.Len() > 0 && buf.Len()+len(line) > statsdPacketSize {
+			if _, err = conn.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		buf.WriteString(line)
+	}
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
+
+func (s *statsdSink) String() string {
+	return "statsd " + s.address
+}
+
+// graphiteSink sends points to graphite using the plaintext protocol.
+// Graphite stores a single value per metric and timestamp, so counters are
+// summed and timings are sent as their mean and count during each flush.
+type graphiteSink struct {
+	address string
+	prefix  string
+}
+
+func (s *graphiteSink) Send(points []Point) error {
+	type aggregateKey struct {
+		name string
+		typ  PointType
+	}
+	type aggregate struct {
+		sum   float64
+		count int
+		date  time.Time
+	}
+	var buf bytes.Buffer
+	aggregates := make(map[aggregateKey]*aggregate)
+	var keys []aggregateKey
+	for _, p := range points {
+		if p.Type == Gauge {
+			fmt.Fprintf(&buf, "%s%s %g %d\n", s.prefix, p.Name, p.Value, p.Date.Unix())
+			continue
+		}
+		key := aggregateKey{name: p.Name, typ: p.Type}
+		agg, ok := aggregates[key]
+		if !ok {
+			agg = &aggregate{}
+			aggregates[key] = agg
+			keys = append(keys, key)
+		}
+		agg.sum += p.Value
+		agg.count++
+		if p.Date.After(agg.date) {
+			agg.date = p.Date
+		}
+	}
+	for _, key := range keys {
+		agg := aggregates[key]
+		if key.typ == Counter {
+			fmt.Fprintf(&buf, "%s%s %g %d\n", s.prefix, key.name, agg.sum, agg.date.Unix())
+			continue
+		}
+		fmt.Fprintf(&buf, "%s%s.mean %g %d\n", s.prefix, key.name, agg.sum/float64(agg.count), agg.date.Unix())
+		fmt.Fprintf(&buf, "%s%s.count %d %d\n", s.prefix, key.name, agg.count, agg.date.Unix())
+	}
+	conn, err := net.DialTimeout("tcp", s.address, sinkTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
+
+func (s *graphiteSink) String() string {
+	return "graphite " + s.address
+}
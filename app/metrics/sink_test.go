@@ -0,0 +1,139 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+)
+
+type fakeSink struct {
+	points chan []Point
+}
+
+func (s *fakeSink) Send(points []Point) error {
+	s.points <- points
+	return nil
+}
+
+func (s *S) TestStatsdSinkSend(c *check.C) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	sink := &statsdSink{address: listener.LocalAddr().String(), prefix: "tsuru."}
+	err = sink.Send([]Point{
+		{Name: "apps.myapp.web.unit1.cpu", Value: 12.5, Type: Gauge},
+		{Name: "api.requests.200", Value: 1, Type: Counter},
+		{Name: "api.response-time.get", Value: 3.5, Type: Timing},
+	})
+	c.Assert(err, check.IsNil)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, _, err := listener.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(buf[:n]), check.Equals, "tsuru.apps.myapp.web.unit1.cpu:12.5|g\n"+
+		"tsuru.api.requests.200:1|c\n"+
+		"tsuru.api.response-time.get:3.5|ms\n")
+}
+
+func (s *S) TestGraphiteSinkSend(c *check.C) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, acceptErr := listener.Accept()
+		if acceptErr != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := ioutil.ReadAll(conn)
+		received <- string(data)
+	}()
+	date := time.Unix(1480000000, 0)
+	sink := &graphiteSink{address: listener.Addr().String(), prefix: "prod."}
+	err = sink.Send([]Point{
+		{Name: "apps.myapp.web.unit1.cpu", Value: 12.5, Type: Gauge, Date: date},
+		{Name: "api.requests.200", Value: 1, Type: Counter, Date: date},
+		{Name: "api.requests.200", Value: 1, Type: Counter, Date: date},
+		{Name: "api.response-time.get", Value: 2, Type: Timing, Date: date},
+		{Name: "api.response-time.get", Value: 4, Type: Timing, Date: date},
+	})
+	c.Assert(err, check.IsNil)
+	select {
+	case data := <-received:
+		c.Assert(data, check.Equals, "prod.apps.myapp.web.unit1.cpu 12.5 1480000000\n"+
+			"prod.api.requests.200 2 1480000000\n"+
+			"prod.api.response-time.get.mean 3 1480000000\n"+
+			"prod.api.response-time.get.count 2 1480000000\n")
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for graphite data")
+	}
+}
+
+func (s *S) TestReporterFlush(c *check.C) {
+	sink := &fakeSink{points: make(chan []Point, 1)}
+	r := newReporter([]Sink{sink}, time.Hour)
+	r.start()
+	r.add([]Point{{Name: "api.requests.200", Value: 1, Type: Counter}})
+	r.stop()
+	points := <-sink.points
+	c.Assert(points, check.HasLen, 1)
+	c.Assert(points[0].Name, check.Equals, "api.requests.200")
+	c.Assert(points[0].Date.IsZero(), check.Equals, false)
+}
+
+func (s *S) TestStartReporterInvalidConfig(c *check.C) {
+	defer StopReporter()
+	config.Set("metrics:sinks", []string{"statsd"})
+	defer config.Unset("metrics:sinks")
+	err := StartReporter()
+	c.Assert(err, check.ErrorMatches, `metrics sink "statsd" requires "metrics:statsd:address"`)
+	config.Set("metrics:sinks", []string{"influx"})
+	err = StartReporter()
+	c.Assert(err, check.ErrorMatches, `unknown metrics sink "influx"`)
+	c.Assert(Reporting(), check.Equals, false)
+}
+
+func (s *S) TestCollectorWithoutInternalSink(c *check.C) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer listener.Close()
+	config.Set("metrics:sinks", []string{"statsd"})
+	config.Set("metrics:statsd:address", listener.LocalAddr().String())
+	config.Set("metrics:statsd:prefix", "")
+	defer config.Unset("metrics")
+	err = StartReporter()
+	c.Assert(err, check.IsNil)
+	defer StopReporter()
+	c.Assert(Reporting(), check.Equals, true)
+	a := s.newApp(c, "myapp", 1)
+	units, err := s.provisioner.Units(a)
+	c.Assert(err, check.IsNil)
+	err = s.provisioner.SetUnitsMetrics(a, []provision.UnitMetrics{
+		{ID: units[0].ID, Process: "web", CPU: 10, Memory: 20, NetRx: 30, NetTx: 40},
+	})
+	c.Assert(err, check.IsNil)
+	collector := &Collector{interval: time.Minute, retention: time.Hour}
+	collector.runOnce()
+	count, err := s.conn.AppMetrics().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+	StopReporter()
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, _, err := listener.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	prefix := "apps.myapp.web." + units[0].ID
+	c.Assert(string(buf[:n]), check.Equals, prefix+".cpu:10|g\n"+
+		prefix+".memory:20|g\n"+
+		prefix+".netrx:30|g\n"+
+		prefix+".nettx:40|g\n")
+}
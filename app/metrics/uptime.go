@@ -0,0 +1,110 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// StatusSample is the status of an app unit at a given time.
+type StatusSample struct {
+	App       string    `json:"app"`
+	Unit      string    `json:"unit"`
+	Process   string    `json:"process"`
+	Date      time.Time `json:"date"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Uptime is the percentage of the status samples of the units of an app in
+// which the unit was started, in the last day and in the last week. Periods
+// without samples are nil.
+type Uptime struct {
+	App     string   `json:"app"`
+	Last24h *float64 `json:"last24h"`
+	Last7d  *float64 `json:"last7d"`
+}
+
+// collectStatuses samples the status of the units of the app, unless another
+// API instance sampled them during the current interval. Statuses are only
+// stored when the internal sink is enabled.
+func (c *Collector) collectStatuses(a *app.App) error {
+	if !storesInternal() {
+		return nil
+	}
+	now := time.Now().UTC()
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var last StatusSample
+	err = conn.AppUnitStatuses().Find(bson.M{"app": a.Name}).Sort("-date").One(&last)
+	if err == nil && now.Sub(last.Date) < c.interval/2 {
+		return nil
+	}
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	units, err := a.Units()
+	if err != nil {
+		return err
+	}
+	if len(units) == 0 {
+		return nil
+	}
+	samples := make([]interface{}, len(units))
+	for i, u := range units {
+		samples[i] = StatusSample{
+			App:       a.Name,
+			Unit:      u.ID,
+			Process:   u.ProcessName,
+			Date:      now,
+			Status:    u.Status.String(),
+			ExpiresAt: now.Add(c.retention),
+		}
+	}
+	return conn.AppUnitStatuses().Insert(samples...)
+}
+
+// AppUptime returns the uptime of the app until the given time, derived from
+// the collected status samples. Samples are kept for the period set in
+// metrics:retention, so the weekly uptime only covers that period when it's
+// shorter than a week.
+func AppUptime(appName string, until time.Time) (Uptime, error) {
+	uptime := Uptime{App: appName}
+	conn, err := db.Conn()
+	if err != nil {
+		return uptime, err
+	}
+	defer conn.Close()
+	uptime.Last24h, err = startedRatio(conn, appName, until.Add(-24*time.Hour), until)
+	if err != nil {
+		return uptime, err
+	}
+	uptime.Last7d, err = startedRatio(conn, appName, until.Add(-7*24*time.Hour), until)
+	return uptime, err
+}
+
+func startedRatio(conn *db.Storage, appName string, since, until time.Time) (*float64, error) {
+	query := bson.M{"app": appName, "date": bson.M{"$gte": since, "$lte": until}}
+	total, err := conn.AppUnitStatuses().Find(query).Count()
+	if err != nil || total == 0 {
+		return nil, err
+	}
+	query["status"] = provision.StatusStarted.String()
+	started, err := conn.AppUnitStatuses().Find(query).Count()
+	if err != nil {
+		return nil, err
+	}
+	ratio := float64(started) * 100 / float64(total)
+	return &ratio, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestCollectorCollectStatuses(c *check.C) {
+	a := s.newApp(c, "myapp", 2)
+	units, err := s.provisioner.Units(a)
+	c.Assert(err, check.IsNil)
+	collector := &Collector{interval: time.Minute, retention: time.Hour}
+	collector.runOnce()
+	collector.runOnce()
+	var samples []StatusSample
+	err = s.conn.AppUnitStatuses().Find(bson.M{"app": "myapp"}).Sort("unit").All(&samples)
+	c.Assert(err, check.IsNil)
+	c.Assert(samples, check.HasLen, 2)
+	c.Assert(samples[0].Unit, check.Equals, units[0].ID)
+	c.Assert(samples[0].Process, check.Equals, "web")
+	c.Assert(samples[0].Status, check.Equals, units[0].Status.String())
+	c.Assert(samples[0].ExpiresAt.Sub(samples[0].Date), check.Equals, time.Hour)
+}
+
+func (s *S) TestAppUptime(c *check.C) {
+	now := time.Now().UTC()
+	statuses := []struct {
+		date   time.Time
+		status provision.Status
+	}{
+		{now.Add(-2 * 24 * time.Hour), provision.StatusError},
+		{now.Add(-2 * 24 * time.Hour), provision.StatusError},
+		{now.Add(-2 * time.Hour), provision.StatusStarted},
+		{now.Add(-time.Hour), provision.StatusStarted},
+		{now.Add(-time.Hour), provision.StatusStarted},
+		{now.Add(-time.Minute), provision.StatusStopped},
+	}
+	for _, st := range statuses {
+		err := s.conn.AppUnitStatuses().Insert(StatusSample{App: "myapp", Unit: "unit1", Date: st.date, Status: st.status.String()})
+		c.Assert(err, check.IsNil)
+	}
+	uptime, err := AppUptime("myapp", now)
+	c.Assert(err, check.IsNil)
+	c.Assert(uptime.App, check.Equals, "myapp")
+	c.Assert(*uptime.Last24h, check.Equals, 75.0)
+	c.Assert(*uptime.Last7d, check.Equals, 50.0)
+	uptime, err = AppUptime("otherapp", now)
+	c.Assert(err, check.IsNil)
+	c.Assert(uptime.Last24h, check.IsNil)
+	c.Assert(uptime.Last7d, check.IsNil)
+}
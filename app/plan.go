@@ -17,12 +17,13 @@ import (
 )
 
 type Plan struct {
-	Name     string `bson:"_id" json:"name"`
-	Memory   int64  `json:"memory"`
-	Swap     int64  `json:"swap"`
-	CpuShare int    `json:"cpushare"`
-	Default  bool   `json:"default,omitempty"`
-	Router   string `json:"router,omitempty"`
+	Name         string        `bson:"_id" json:"name"`
+	Memory       int64         `json:"memory"`
+	Swap         int64         `json:"swap"`
+	CpuShare     int           `json:"cpushare"`
+	Default      bool          `json:"default,omitempty"`
+	Router       string        `json:"router,omitempty"`
+	LogRetention *LogRetention `json:"logRetention,omitempty" bson:",omitempty"`
 }
 
 type PlanValidationError struct{ field string }
@@ -39,7 +40,7 @@ var (
 	ErrLimitOfMemory        = errors.New("The minimum allowed memory is 4MB")
 )
 
-func (plan *Plan) Save() error {
+func (plan *Plan) validate() error {
 	if plan.Name == "" {
 		return PlanValidationError{"name"}
 	}
@@ -49,12 +50,28 @@ func (plan *Plan) Save() error {
 	if plan.Memory > 0 && plan.Memory < 4194304 {
 		return ErrLimitOfMemory
 	}
+	if plan.LogRetention != nil {
+		if plan.LogRetention.Lines < 0 {
+			return PlanValidationError{"log lines"}
+		}
+		if plan.LogRetention.MaxAge < 0 {
+			return PlanValidationError{"log max age"}
+		}
+	}
 	if plan.Router != "" {
 		_, err := router.Get(plan.Router)
 		if err != nil {
 			return PlanValidationError{fmt.Sprintf("router error: %v", err)}
 		}
 	}
+	return nil
+}
+
+func (plan *Plan) Save() error {
+	err := plan.validate()
+	if err != nil {
+		return err
+	}
 	conn, err := db.Conn()
 	if err != nil {
 		return err
@@ -91,7 +108,7 @@ func PlansList() ([]Plan, error) {
 	return plans, err
 }
 
-func findPlanByName(name string) (*Plan, error) {
+func GetPlanByName(name string) (*Plan, error) {
 	conn, err := db.Conn()
 	if err != nil {
 		return nil, err
@@ -149,3 +166,35 @@ func PlanRemove(planName string) error {
 	}
 	return err
 }
+
+// PlanUpdate replaces the values of an existing plan. Apps using the plan
+// get the new values, which are applied to their units in the next restart.
+func PlanUpdate(plan Plan) error {
+	err := plan.validate()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Plans().UpdateId(plan.Name, plan)
+	if err == mgo.ErrNotFound {
+		return ErrPlanNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if plan.Default {
+		_, err = conn.Plans().UpdateAll(
+			bson.M{"_id": bson.M{"$ne": plan.Name}, "default": true},
+			bson.M{"$unset": bson.M{"default": false}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = conn.Apps().UpdateAll(bson.M{"plan._id": plan.Name}, bson.M{"$set": bson.M{"plan": plan}})
+	return err
+}
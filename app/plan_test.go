@@ -175,6 +175,47 @@ func (s *S) TestPlanRemoveInvalid(c *check.C) {
 	c.Assert(err, check.Equals, ErrPlanNotFound)
 }
 
+func (s *S) TestPlanUpdate(c *check.C) {
+	plan := Plan{Name: "plan1", Memory: 4194304, Swap: 2, CpuShare: 3}
+	err := s.conn.Plans().Insert(plan)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Plans().RemoveId(plan.Name)
+	a := App{Name: "myapp", Plan: plan}
+	err = s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	plan.Memory = 8388608
+	plan.CpuShare = 10
+	err = PlanUpdate(plan)
+	c.Assert(err, check.IsNil)
+	dbPlan, err := GetPlanByName(plan.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(*dbPlan, check.DeepEquals, plan)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.Plan, check.DeepEquals, plan)
+}
+
+func (s *S) TestPlanUpdateAsDefault(c *check.C) {
+	plan := Plan{Name: "plan1", Memory: 4194304, Swap: 2, CpuShare: 3}
+	err := s.conn.Plans().Insert(plan)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Plans().RemoveId(plan.Name)
+	defer s.conn.Plans().UpdateId(s.defaultPlan.Name, s.defaultPlan)
+	plan.Default = true
+	err = PlanUpdate(plan)
+	c.Assert(err, check.IsNil)
+	p, err := DefaultPlan()
+	c.Assert(err, check.IsNil)
+	c.Assert(*p, check.DeepEquals, plan)
+}
+
+func (s *S) TestPlanUpdateInvalid(c *check.C) {
+	err := PlanUpdate(Plan{Name: "xxxx", CpuShare: 2})
+	c.Assert(err, check.Equals, ErrPlanNotFound)
+	err = PlanUpdate(Plan{Name: "xxxx", CpuShare: 1})
+	c.Assert(err, check.Equals, ErrLimitOfCpuShare)
+}
+
 func (s *S) TestDefaultPlan(c *check.C) {
 	p, err := DefaultPlan()
 	c.Assert(err, check.IsNil)
@@ -209,7 +250,7 @@ func (s *S) TestFindPlanByName(c *check.C) {
 	err := p.Save()
 	c.Assert(err, check.IsNil)
 	defer s.conn.Plans().RemoveId(p.Name)
-	dbPlan, err := findPlanByName(p.Name)
+	dbPlan, err := GetPlanByName(p.Name)
 	c.Assert(err, check.IsNil)
 	c.Assert(*dbPlan, check.DeepEquals, p)
 }
@@ -0,0 +1,134 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	sloAtRiskEventKind    = "slo-at-risk"
+	sloRecoveredEventKind = "slo-recovered"
+
+	// sloRiskBudget is the percentage of the error budget below which the
+	// SLO of an app is considered at risk.
+	sloRiskBudget = 25.0
+)
+
+// SLO is the availability objective of an app, as the percentage of health
+// checks of its units that should succeed, e.g. 99.9. AtRisk is the last
+// state reported by CheckSLO.
+type SLO struct {
+	Availability float64 `json:"availability"`
+	AtRisk       bool    `json:"atRisk"`
+}
+
+// SLOStatus is the compliance of an app with its SLO, computed from the
+// health check history of its units. Availability is the percentage of
+// successful checks and ErrorBudget is the percentage of the allowed failures
+// still available. Both are nil when there are no checks in the history.
+type SLOStatus struct {
+	Target       float64  `json:"target"`
+	Checks       int      `json:"checks"`
+	Availability *float64 `json:"availability"`
+	ErrorBudget  *float64 `json:"errorBudget"`
+	AtRisk       bool     `json:"atRisk"`
+}
+
+// SetSLO changes the availability objective of the app. Zero removes it.
+func (app *App) SetSLO(availability float64) error {
+	if availability < 0 || availability >= 100 {
+		return &tsuruErrors.ValidationError{Message: "availability must be between 0 and 100"}
+	}
+	app.SLO = nil
+	if availability > 0 {
+		app.SLO = &SLO{Availability: availability}
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$set": bson.M{"slo": app.SLO}})
+}
+
+// GetSLOStatus returns the compliance of the app with its SLO, or nil when
+// the app has no SLO.
+func (app *App) GetSLOStatus() (*SLOStatus, error) {
+	if app.SLO == nil {
+		return nil, nil
+	}
+	units, err := app.Units()
+	if err != nil {
+		return nil, err
+	}
+	return sloStatus(app.SLO, units), nil
+}
+
+func sloStatus(slo *SLO, units []provision.Unit) *SLOStatus {
+	status := SLOStatus{Target: slo.Availability}
+	var failures int
+	for _, u := range units {
+		for _, hc := range u.Healthchecks {
+			status.Checks++
+			if hc.Error != "" {
+				failures++
+			}
+		}
+	}
+	if status.Checks == 0 {
+		return &status
+	}
+	failureRatio := float64(failures) / float64(status.Checks)
+	availability := (1 - failureRatio) * 100
+	budget := (1 - failureRatio*100/(100-slo.Availability)) * 100
+	if budget < 0 {
+		budget = 0
+	}
+	status.Availability = &availability
+	status.ErrorBudget = &budget
+	status.AtRisk = budget < sloRiskBudget
+	return &status
+}
+
+// CheckSLO computes the compliance of the app with its SLO and, when the SLO
+// becomes at risk or recovers, stores the new state and creates an event of
+// kind slo-at-risk or slo-recovered.
+func (app *App) CheckSLO() error {
+	status, err := app.GetSLOStatus()
+	if err != nil || status == nil || status.AtRisk == app.SLO.AtRisk {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Apps().Update(bson.M{"name": app.Name}, bson.M{"$set": bson.M{"slo.atrisk": status.AtRisk}})
+	if err != nil {
+		return err
+	}
+	app.SLO.AtRisk = status.AtRisk
+	kind := sloRecoveredEventKind
+	if status.AtRisk {
+		kind = sloAtRiskEventKind
+	}
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeApp, Value: app.Name},
+		InternalKind: kind,
+		CustomData:   status,
+		DisableLock:  true,
+		Allowed:      event.Allowed(permission.PermAppReadEvents, permission.Context(permission.CtxApp, app.Name)),
+	})
+	if err != nil {
+		return err
+	}
+	return evt.Done(nil)
+}
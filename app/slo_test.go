@@ -0,0 +1,88 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+)
+
+func healthchecks(total, failures int) []provision.UnitHealthcheck {
+	checks := make([]provision.UnitHealthcheck, total)
+	for i := range checks {
+		checks[i].Date = time.Now()
+		if i < failures {
+			checks[i].Error = "healthcheck fail"
+		}
+	}
+	return checks
+}
+
+func (s *S) TestSLOStatus(c *check.C) {
+	slo := &SLO{Availability: 90}
+	status := sloStatus(slo, []provision.Unit{
+		{ID: "u1", Healthchecks: healthchecks(10, 1)},
+		{ID: "u2", Healthchecks: healthchecks(10, 0)},
+	})
+	c.Assert(status.Target, check.Equals, 90.0)
+	c.Assert(status.Checks, check.Equals, 20)
+	c.Assert(*status.Availability, check.Equals, 95.0)
+	c.Assert(*status.ErrorBudget, check.Equals, 50.0)
+	c.Assert(status.AtRisk, check.Equals, false)
+	status = sloStatus(slo, []provision.Unit{{ID: "u1", Healthchecks: healthchecks(10, 3)}})
+	c.Assert(*status.ErrorBudget, check.Equals, 0.0)
+	c.Assert(status.AtRisk, check.Equals, true)
+	status = sloStatus(slo, []provision.Unit{{ID: "u1"}})
+	c.Assert(status.Checks, check.Equals, 0)
+	c.Assert(status.Availability, check.IsNil)
+	c.Assert(status.AtRisk, check.Equals, false)
+}
+
+func (s *S) TestSetSLO(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	err = a.SetSLO(99.9)
+	c.Assert(err, check.IsNil)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.SLO, check.DeepEquals, &SLO{Availability: 99.9})
+	err = a.SetSLO(0)
+	c.Assert(err, check.IsNil)
+	dbApp, err = GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.SLO, check.IsNil)
+	err = a.SetSLO(100)
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+}
+
+func (s *S) TestCheckSLO(c *check.C) {
+	a := App{Name: "myapp", TeamOwner: s.team.Name}
+	err := CreateApp(&a, s.user)
+	c.Assert(err, check.IsNil)
+	s.provisioner.AddUnit(&a, provision.Unit{ID: "u1", AppName: a.Name, Healthchecks: healthchecks(10, 2)})
+	err = a.SetSLO(90)
+	c.Assert(err, check.IsNil)
+	err = a.CheckSLO()
+	c.Assert(err, check.IsNil)
+	c.Assert(a.SLO.AtRisk, check.Equals, true)
+	dbApp, err := GetByName(a.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbApp.SLO.AtRisk, check.Equals, true)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeApp, Value: a.Name},
+		Kind:   "slo-at-risk",
+	}, eventtest.HasEvent)
+	err = dbApp.CheckSLO()
+	c.Assert(err, check.IsNil)
+	evts, err := event.List(&event.Filter{KindType: event.KindTypeInternal})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/quota"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TeamUsage holds the resources used by the apps owned by a team and its
+// sub-teams, counted against the team quota.
+type TeamUsage struct {
+	Apps   int   `json:"apps"`
+	Units  int   `json:"units"`
+	Memory int64 `json:"memory"`
+}
+
+// GetTeamUsage returns the resources used by the apps owned by the given
+// team and by its sub-teams.
+func GetTeamUsage(teamName string) (TeamUsage, error) {
+	var usage TeamUsage
+	subTeams, err := auth.SubTeams(teamName)
+	if err != nil {
+		return usage, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return usage, err
+	}
+	defer conn.Close()
+	var apps []App
+	query := bson.M{"teamowner": bson.M{"$in": append([]string{teamName}, subTeams...)}}
+	err = conn.Apps().Find(query).Select(bson.M{"quota": 1, "plan.memory": 1}).All(&apps)
+	if err != nil {
+		return usage, err
+	}
+	usage.Apps = len(apps)
+	for _, a := range apps {
+		usage.Units += a.Quota.InUse
+		usage.Memory += int64(a.Quota.InUse) * a.Plan.Memory
+	}
+	return usage, nil
+}
+
+// quotaTeams returns the team and its parent teams, as the quota of each one
+// of them limits the resources the team can use.
+func quotaTeams(teamName string) ([]*auth.Team, error) {
+	team, err := auth.GetTeam(teamName)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := auth.TeamAncestors(teamName)
+	if err != nil {
+		return nil, err
+	}
+	teams := []*auth.Team{team}
+	for _, name := range ancestors {
+		parent, err := auth.GetTeam(name)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, parent)
+	}
+	return teams, nil
+}
+
+// checkTeamAppQuota returns an error if the team owns more apps than
+// allowed. It must be called after the new app is inserted, so apps created
+// concurrently count against each other and can't exceed the limit together.
+func checkTeamAppQuota(teamName string) error {
+	teams, err := quotaTeams(teamName)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		if team.Quota.Apps <= 0 {
+			continue
+		}
+		usage, err := GetTeamUsage(team.Name)
+		if err != nil {
+			return err
+		}
+		if usage.Apps > team.Quota.Apps {
+			available := team.Quota.Apps - (usage.Apps - 1)
+			if available < 0 {
+				available = 0
+			}
+			return &quota.QuotaExceededError{Requested: 1, Available: uint(available)}
+		}
+	}
+	return nil
+}
+
+// checkTeamUnitsQuota returns an error if the n units being added to the app
+// exceed the units or memory limits of the team owning it, or of any of its
+// parent teams. The units must already be reserved in the app quota, with
+// the conditional increment done by reserveUnits, so concurrent reservations
+// see each other and can't exceed the limits together.
+func checkTeamUnitsQuota(app *App, n int) error {
+	teams, err := quotaTeams(app.TeamOwner)
+	if err != nil {
+		return err
+	}
+	available := n
+	for _, team := range teams {
+		if team.Quota.Units <= 0 && team.Quota.Memory <= 0 {
+			continue
+		}
+		usage, err := GetTeamUsage(team.Name)
+		if err != nil {
+			return err
+		}
+		if team.Quota.Units > 0 {
+			if byUnits := team.Quota.Units - (usage.Units - n); byUnits < available {
+				available = byUnits
+			}
+		}
+		if team.Quota.Memory > 0 && app.Plan.Memory > 0 {
+			used := usage.Memory - int64(n)*app.Plan.Memory
+			byMemory := int((team.Quota.Memory - used) / app.Plan.Memory)
+			if byMemory < available {
+				available = byMemory
+			}
+		}
+	}
+	if available < 0 {
+		available = 0
+	}
+	if n > available {
+		return &quota.QuotaExceededError{Requested: uint(n), Available: uint(available)}
+	}
+	return nil
+}
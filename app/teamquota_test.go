@@ -0,0 +1,128 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/quota"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestGetTeamUsage(c *check.C) {
+	apps := []App{
+		{Name: "app1", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 2}, Plan: Plan{Memory: 100}},
+		{Name: "app2", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 3}, Plan: Plan{Memory: 10}},
+		{Name: "app3", TeamOwner: "otherteam", Quota: quota.Quota{InUse: 5}, Plan: Plan{Memory: 10}},
+	}
+	for _, a := range apps {
+		err := s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	usage, err := GetTeamUsage(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.DeepEquals, TeamUsage{Apps: 2, Units: 5, Memory: 230})
+}
+
+func (s *S) TestCheckTeamAppQuota(c *check.C) {
+	err := checkTeamAppQuota(s.team.Name)
+	c.Assert(err, check.IsNil)
+	err = auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{Apps: 1})
+	c.Assert(err, check.IsNil)
+	defer auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{})
+	err = s.conn.Apps().Insert(App{Name: "app1", TeamOwner: s.team.Name})
+	c.Assert(err, check.IsNil)
+	err = checkTeamAppQuota(s.team.Name)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Insert(App{Name: "app2", TeamOwner: s.team.Name})
+	c.Assert(err, check.IsNil)
+	err = checkTeamAppQuota(s.team.Name)
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 1, Available: 0})
+}
+
+func (s *S) TestCheckTeamUnitsQuota(c *check.C) {
+	err := auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{Units: 5})
+	c.Assert(err, check.IsNil)
+	defer auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{})
+	a := App{Name: "app1", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 5}}
+	err = s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 2)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Update(bson.M{"name": a.Name}, bson.M{"$inc": bson.M{"quota.inuse": 1}})
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 3)
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 2})
+}
+
+func (s *S) TestCheckTeamUnitsQuotaMemory(c *check.C) {
+	err := auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{Memory: 1000})
+	c.Assert(err, check.IsNil)
+	defer auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{})
+	a := App{Name: "app1", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 3}, Plan: Plan{Memory: 300}}
+	err = s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 1)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Update(bson.M{"name": a.Name}, bson.M{"$inc": bson.M{"quota.inuse": 1}})
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 2)
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 2, Available: 1})
+}
+
+func (s *S) TestCreateAppTeamQuotaExceeded(c *check.C) {
+	err := auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{Apps: 1})
+	c.Assert(err, check.IsNil)
+	defer auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{})
+	err = s.conn.Apps().Insert(App{Name: "app1", TeamOwner: s.team.Name})
+	c.Assert(err, check.IsNil)
+	a := App{Name: "app2", Platform: "python", TeamOwner: s.team.Name}
+	err = CreateApp(&a, s.user)
+	c.Assert(err, check.NotNil)
+	e, ok := err.(*AppCreationError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.Err, check.DeepEquals, &quota.QuotaExceededError{Requested: 1, Available: 0})
+	count, err := s.conn.Apps().Find(bson.M{"name": "app2"}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
+
+func (s *S) TestGetTeamUsageIncludesSubTeams(c *check.C) {
+	err := s.conn.Teams().Insert(auth.Team{Name: "subteam", Parent: s.team.Name})
+	c.Assert(err, check.IsNil)
+	apps := []App{
+		{Name: "app1", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 2}, Plan: Plan{Memory: 100}},
+		{Name: "app2", TeamOwner: "subteam", Quota: quota.Quota{InUse: 3}, Plan: Plan{Memory: 10}},
+	}
+	for _, a := range apps {
+		err = s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	usage, err := GetTeamUsage(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.DeepEquals, TeamUsage{Apps: 2, Units: 5, Memory: 230})
+	usage, err = GetTeamUsage("subteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.DeepEquals, TeamUsage{Apps: 1, Units: 3, Memory: 30})
+}
+
+func (s *S) TestCheckTeamUnitsQuotaParentTeam(c *check.C) {
+	err := s.conn.Teams().Insert(auth.Team{Name: "subteam", Parent: s.team.Name})
+	c.Assert(err, check.IsNil)
+	err = auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{Units: 5})
+	c.Assert(err, check.IsNil)
+	defer auth.ChangeTeamQuota(s.team.Name, auth.TeamQuota{})
+	err = s.conn.Apps().Insert(App{Name: "app1", TeamOwner: s.team.Name, Quota: quota.Quota{InUse: 3}})
+	c.Assert(err, check.IsNil)
+	a := App{Name: "app2", TeamOwner: "subteam", Quota: quota.Quota{InUse: 2}}
+	err = s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 2)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Update(bson.M{"name": a.Name}, bson.M{"$inc": bson.M{"quota.inuse": 1}})
+	c.Assert(err, check.IsNil)
+	err = checkTeamUnitsQuota(&a, 3)
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 2})
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/db"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrTransferTargetRequired = errors.New("the user owns apps, either the new owner or the new team must be provided")
+
+// TransferUserApps transfers the apps owned by the user with the given email
+// to newOwner and, when newTeam is not empty, to newTeam, which is also
+// granted access to them. All apps are updated in a single operation and the
+// app quota of both users is adjusted. It returns the names of the
+// transferred apps.
+func TransferUserApps(email string, newOwner *auth.User, newTeam string) ([]string, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var names []string
+	err = conn.Apps().Find(bson.M{"owner": email}).Distinct("name", &names)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if newOwner == nil && newTeam == "" {
+		return nil, ErrTransferTargetRequired
+	}
+	if newTeam != "" {
+		if _, err = auth.GetTeam(newTeam); err != nil {
+			return nil, err
+		}
+	}
+	set := bson.M{}
+	update := bson.M{"$set": set}
+	if newOwner != nil {
+		set["owner"] = newOwner.Email
+	}
+	if newTeam != "" {
+		set["teamowner"] = newTeam
+		update["$addToSet"] = bson.M{"teams": newTeam}
+	}
+	_, err = conn.Apps().UpdateAll(bson.M{"name": bson.M{"$in": names}}, update)
+	if err != nil {
+		return nil, err
+	}
+	if newOwner != nil && newOwner.Email != email {
+		n := len(names)
+		err = conn.Users().Update(bson.M{"email": email, "quota.inuse": bson.M{"$gte": n}}, bson.M{"$inc": bson.M{"quota.inuse": -n}})
+		if err != nil && err != mgo.ErrNotFound {
+			return names, errors.Wrapf(err, "unable to release app quota of user %q", email)
+		}
+		err = conn.Users().Update(bson.M{"email": newOwner.Email}, bson.M{"$inc": bson.M{"quota.inuse": n}})
+		if err != nil {
+			return names, errors.Wrapf(err, "unable to reserve app quota of user %q", newOwner.Email)
+		}
+	}
+	return names, nil
+}
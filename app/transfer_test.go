@@ -0,0 +1,83 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"sort"
+
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/quota"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestTransferUserApps(c *check.C) {
+	err := s.conn.Teams().Insert(auth.Team{Name: "newteam"})
+	c.Assert(err, check.IsNil)
+	newOwner := &auth.User{Email: "new@owner.com", Quota: quota.Quota{Limit: -1, InUse: 1}}
+	err = newOwner.Create()
+	c.Assert(err, check.IsNil)
+	err = s.conn.Users().Update(bson.M{"email": s.user.Email}, bson.M{"$set": bson.M{"quota.inuse": 2}})
+	c.Assert(err, check.IsNil)
+	apps := []App{
+		{Name: "app1", Owner: s.user.Email, TeamOwner: s.team.Name, Teams: []string{s.team.Name}},
+		{Name: "app2", Owner: s.user.Email, TeamOwner: s.team.Name, Teams: []string{s.team.Name}},
+		{Name: "app3", Owner: "other@user.com", TeamOwner: s.team.Name, Teams: []string{s.team.Name}},
+	}
+	for _, a := range apps {
+		err = s.conn.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	names, err := TransferUserApps(s.user.Email, newOwner, "newteam")
+	c.Assert(err, check.IsNil)
+	sort.Strings(names)
+	c.Assert(names, check.DeepEquals, []string{"app1", "app2"})
+	for _, name := range names {
+		a, err := GetByName(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(a.Owner, check.Equals, newOwner.Email)
+		c.Assert(a.TeamOwner, check.Equals, "newteam")
+		c.Assert(a.Teams, check.DeepEquals, []string{s.team.Name, "newteam"})
+	}
+	a, err := GetByName("app3")
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Owner, check.Equals, "other@user.com")
+	c.Assert(a.TeamOwner, check.Equals, s.team.Name)
+	oldOwner, err := auth.GetUserByEmail(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(oldOwner.Quota.InUse, check.Equals, 0)
+	newOwner, err = auth.GetUserByEmail(newOwner.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(newOwner.Quota.InUse, check.Equals, 3)
+}
+
+func (s *S) TestTransferUserAppsOnlyTeam(c *check.C) {
+	err := s.conn.Teams().Insert(auth.Team{Name: "newteam"})
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Insert(App{Name: "app1", Owner: s.user.Email, TeamOwner: s.team.Name, Teams: []string{s.team.Name}})
+	c.Assert(err, check.IsNil)
+	names, err := TransferUserApps(s.user.Email, nil, "newteam")
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"app1"})
+	a, err := GetByName("app1")
+	c.Assert(err, check.IsNil)
+	c.Assert(a.Owner, check.Equals, s.user.Email)
+	c.Assert(a.TeamOwner, check.Equals, "newteam")
+}
+
+func (s *S) TestTransferUserAppsWithoutApps(c *check.C) {
+	names, err := TransferUserApps(s.user.Email, nil, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, 0)
+}
+
+func (s *S) TestTransferUserAppsRequiresTarget(c *check.C) {
+	err := s.conn.Apps().Insert(App{Name: "app1", Owner: s.user.Email, TeamOwner: s.team.Name})
+	c.Assert(err, check.IsNil)
+	_, err = TransferUserApps(s.user.Email, nil, "")
+	c.Assert(err, check.Equals, ErrTransferTargetRequired)
+	_, err = TransferUserApps(s.user.Email, nil, "unknown")
+	c.Assert(err, check.Equals, auth.ErrTeamNotFound)
+}
@@ -16,13 +16,22 @@ type Logger interface {
 	Log(string, string, string) error
 }
 
+// correlatedLogger is implemented by loggers able to stamp log lines with
+// the correlation ID of an event.
+type correlatedLogger interface {
+	LogWithCorrelationID(string, string, string, string) error
+}
+
 type LogWriter struct {
 	App    Logger
 	Source string
-	msgCh  chan []byte
-	doneCh chan bool
-	closed bool
-	finLk  sync.RWMutex
+	// CorrelationID is stamped in the written lines when App implements
+	// LogWithCorrelationID.
+	CorrelationID string
+	msgCh         chan []byte
+	doneCh        chan bool
+	closed        bool
+	finLk         sync.RWMutex
 }
 
 func (w *LogWriter) Async() {
@@ -81,5 +90,8 @@ func (w *LogWriter) write(data []byte) error {
 	if source == "" {
 		source = "tsuru"
 	}
+	if l, ok := w.App.(correlatedLogger); ok && w.CorrelationID != "" {
+		return l.LogWithCorrelationID(string(data), source, "api", w.CorrelationID)
+	}
 	return w.App.Log(string(data), source, "api")
 }
@@ -71,6 +71,23 @@ func (s *WriterSuite) TestLogWriterCustomSource(c *check.C) {
 	c.Assert(logs[0].Source, check.Equals, "cool-test")
 }
 
+func (s *WriterSuite) TestLogWriterCorrelationID(c *check.C) {
+	a := App{Name: "down"}
+	err := s.conn.Apps().Insert(a)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Apps().Remove(bson.M{"name": a.Name})
+	writer := LogWriter{App: &a, CorrelationID: "deploy-1"}
+	_, err = writer.Write([]byte("ble"))
+	c.Assert(err, check.IsNil)
+	err = a.Log("unrelated", "app", "unit1")
+	c.Assert(err, check.IsNil)
+	logs, err := a.LastLogs(10, Applog{CorrelationID: "deploy-1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(logs, check.HasLen, 1)
+	c.Assert(logs[0].Message, check.Equals, "ble")
+	c.Assert(logs[0].CorrelationID, check.Equals, "deploy-1")
+}
+
 func (s *WriterSuite) TestLogWriterShouldReturnTheDataSize(c *check.C) {
 	a := App{Name: "down"}
 	err := s.conn.Apps().Insert(a)
@@ -0,0 +1,199 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const defaultImpersonationExpiration = 30 * time.Minute
+
+var (
+	ErrImpersonateSelf       = errors.New("users cannot impersonate themselves")
+	ErrImpersonationChaining = errors.New("impersonation tokens cannot be used to impersonate other users")
+	ErrImpersonationEscalate = errors.New("users cannot impersonate users with permissions they don't have")
+)
+
+// ImpersonationToken is a short-lived token that allows a privileged user,
+// the impersonator, to act as another user, e.g. to reproduce a problem
+// reported to the support team. Requests made with it have the permissions
+// of the impersonated user, and events started with it record the
+// impersonator.
+type ImpersonationToken struct {
+	Token        string    `json:"token"`
+	UserEmail    string    `json:"user"`
+	Impersonator string    `json:"impersonator"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+func impersonationExpiration() time.Duration {
+	minutes, err := config.GetInt("auth:impersonation-expire-minutes")
+	if err != nil || minutes <= 0 {
+		return defaultImpersonationExpiration
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Impersonate creates a token that allows impersonator to act as user until
+// it expires, after auth:impersonation-expire-minutes.
+func Impersonate(impersonator Token, user *User) (*ImpersonationToken, error) {
+	if _, ok := impersonator.(*ImpersonationToken); ok {
+		return nil, ErrImpersonationChaining
+	}
+	if impersonator.GetUserName() == user.Email {
+		return nil, ErrImpersonateSelf
+	}
+	err := checkImpersonationPermissions(impersonator, user)
+	if err != nil {
+		return nil, err
+	}
+	randomBytes := make([]byte, 32)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+	h := crypto.SHA256.New()
+	h.Write([]byte(user.Email))
+	h.Write(randomBytes)
+	h.Write([]byte(time.Now().Format(time.RFC3339Nano)))
+	now := time.Now().UTC()
+	t := ImpersonationToken{
+		Token:        fmt.Sprintf("%x", h.Sum(nil)),
+		UserEmail:    user.Email,
+		Impersonator: impersonator.GetUserName(),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(impersonationExpiration()),
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = conn.ImpersonationTokens().Insert(t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// checkImpersonationPermissions ensures the impersonator holds every
+// permission of the impersonated user, except for the permissions in the
+// context of the user itself, so impersonation can't be used to escalate
+// privileges.
+func checkImpersonationPermissions(impersonator Token, user *User) error {
+	impersonatorPerms, err := impersonator.Permissions()
+	if err != nil {
+		return err
+	}
+	userPerms, err := user.Permissions()
+	if err != nil {
+		return err
+	}
+	for _, perm := range userPerms {
+		if perm.Context.CtxType == permission.CtxUser && perm.Context.Value == user.Email {
+			continue
+		}
+		if !permission.CheckFromPermList(impersonatorPerms, perm.Scheme, perm.Context) {
+			return ErrImpersonationEscalate
+		}
+	}
+	return nil
+}
+
+// ImpersonationAuth returns the impersonation token matching the given
+// authorization header, if it has not expired.
+func ImpersonationAuth(header string) (*ImpersonationToken, error) {
+	value, err := ParseToken(header)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var t ImpersonationToken
+	err = conn.ImpersonationTokens().Find(bson.M{"token": value}).One(&t)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return &t, nil
+}
+
+// RemoveImpersonationToken ends the impersonation started with the given
+// token.
+func RemoveImpersonationToken(value string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.ImpersonationTokens().Remove(bson.M{"token": value})
+	if err == mgo.ErrNotFound {
+		return ErrInvalidToken
+	}
+	return err
+}
+
+func (t *ImpersonationToken) GetValue() string {
+	return t.Token
+}
+
+func (t *ImpersonationToken) User() (*User, error) {
+	return GetUserByEmail(t.UserEmail)
+}
+
+func (t *ImpersonationToken) IsAppToken() bool {
+	return false
+}
+
+func (t *ImpersonationToken) GetUserName() string {
+	return t.UserEmail
+}
+
+func (t *ImpersonationToken) GetAppName() string {
+	return ""
+}
+
+// GetExpiration returns when the token expires.
+func (t *ImpersonationToken) GetExpiration() time.Time {
+	return t.ExpiresAt
+}
+
+// GetImpersonator returns the email of the user acting as the owner of the
+// token.
+func (t *ImpersonationToken) GetImpersonator() string {
+	return t.Impersonator
+}
+
+// Permissions returns the permissions of the impersonated user. The token
+// stops working if the impersonated user is deactivated.
+func (t *ImpersonationToken) Permissions() ([]permission.Permission, error) {
+	u, err := t.User()
+	if err != nil {
+		return nil, err
+	}
+	if u.Deactivated {
+		return nil, ErrUserDeactivated
+	}
+	return u.Permissions()
+}
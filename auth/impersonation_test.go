@@ -0,0 +1,107 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) supportToken(c *check.C) *APIToken {
+	u := User{Email: "support@tsuru.io", Password: "123456"}
+	err := u.Create()
+	c.Assert(err, check.IsNil)
+	return &APIToken{Token: "support-key", UserEmail: u.Email}
+}
+
+func (s *S) TestImpersonate(c *check.C) {
+	support := s.supportToken(c)
+	t, err := Impersonate(support, s.user)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Token, check.Not(check.Equals), "")
+	c.Assert(t.UserEmail, check.Equals, s.user.Email)
+	c.Assert(t.Impersonator, check.Equals, "support@tsuru.io")
+	c.Assert(t.ExpiresAt.Sub(t.CreatedAt), check.Equals, 30*time.Minute)
+	found, err := ImpersonationAuth("bearer " + t.Token)
+	c.Assert(err, check.IsNil)
+	c.Assert(found.GetUserName(), check.Equals, s.user.Email)
+	c.Assert(found.GetImpersonator(), check.Equals, "support@tsuru.io")
+	c.Assert(found.IsAppToken(), check.Equals, false)
+	perms, err := found.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms[0], check.DeepEquals, permission.Permission{
+		Scheme:  permission.PermUser,
+		Context: permission.Context(permission.CtxUser, s.user.Email),
+	})
+}
+
+func (s *S) TestImpersonateExpiration(c *check.C) {
+	config.Set("auth:impersonation-expire-minutes", 5)
+	defer config.Unset("auth:impersonation-expire-minutes")
+	support := s.supportToken(c)
+	t, err := Impersonate(support, s.user)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.ExpiresAt.Sub(t.CreatedAt), check.Equals, 5*time.Minute)
+	err = s.conn.ImpersonationTokens().Update(bson.M{"token": t.Token}, bson.M{"$set": bson.M{"expiresat": time.Now().Add(-time.Minute)}})
+	c.Assert(err, check.IsNil)
+	_, err = ImpersonationAuth("bearer " + t.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestImpersonateUserWithMorePermissions(c *check.C) {
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	err = s.user.AddRole(role.Name, s.team.Name)
+	c.Assert(err, check.IsNil)
+	support := s.supportToken(c)
+	_, err = Impersonate(support, s.user)
+	c.Assert(err, check.Equals, ErrImpersonationEscalate)
+	supportUser, err := support.User()
+	c.Assert(err, check.IsNil)
+	err = supportUser.AddRole(role.Name, s.team.Name)
+	c.Assert(err, check.IsNil)
+	_, err = Impersonate(support, s.user)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestImpersonateSelf(c *check.C) {
+	own := &APIToken{Token: "own-key", UserEmail: s.user.Email}
+	_, err := Impersonate(own, s.user)
+	c.Assert(err, check.Equals, ErrImpersonateSelf)
+}
+
+func (s *S) TestImpersonateChaining(c *check.C) {
+	impersonated := &ImpersonationToken{Token: "abc", UserEmail: "other@tsuru.io", Impersonator: "support@tsuru.io"}
+	_, err := Impersonate(impersonated, s.user)
+	c.Assert(err, check.Equals, ErrImpersonationChaining)
+}
+
+func (s *S) TestRemoveImpersonationToken(c *check.C) {
+	support := s.supportToken(c)
+	t, err := Impersonate(support, s.user)
+	c.Assert(err, check.IsNil)
+	err = RemoveImpersonationToken(t.Token)
+	c.Assert(err, check.IsNil)
+	_, err = ImpersonationAuth("bearer " + t.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	err = RemoveImpersonationToken(t.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestImpersonationPermissionsDeactivatedUser(c *check.C) {
+	support := s.supportToken(c)
+	t, err := Impersonate(support, s.user)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Users().Update(bson.M{"email": s.user.Email}, bson.M{"$set": bson.M{"deactivated": true}})
+	c.Assert(err, check.IsNil)
+	_, err = t.Permissions()
+	c.Assert(err, check.Equals, ErrUserDeactivated)
+}
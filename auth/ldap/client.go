@@ -0,0 +1,85 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	goldap "gopkg.in/ldap.v2"
+)
+
+func equalityFilter(attr, value string) string {
+	return fmt.Sprintf("(%s=%s)", goldap.EscapeFilter(attr), goldap.EscapeFilter(value))
+}
+
+func andFilter(filters ...string) string {
+	return "(&" + strings.Join(filters, "") + ")"
+}
+
+// attributeValue returns the first value of the attribute of the entry,
+// matching its name case-insensitively, as LDAP attribute names are.
+func attributeValue(e *goldap.Entry, name string) string {
+	for _, attr := range e.Attributes {
+		if strings.EqualFold(attr.Name, name) && len(attr.Values) > 0 {
+			return attr.Values[0]
+		}
+	}
+	return ""
+}
+
+// dial connects to the LDAP server in the given ldap:// or ldaps:// URL,
+// returning the connection and the name used to verify the certificate of the
+// server.
+func dial(rawurl string, timeout time.Duration) (*goldap.Conn, string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "invalid ldap url %q", rawurl)
+	}
+	var defaultPort string
+	switch u.Scheme {
+	case "ldap":
+		defaultPort = "389"
+	case "ldaps":
+		defaultPort = "636"
+	default:
+		return nil, "", errors.Errorf("invalid ldap url %q: scheme must be ldap or ldaps", rawurl)
+	}
+	host := u.Host
+	serverName, _, err := net.SplitHostPort(host)
+	if err != nil {
+		serverName = strings.Trim(host, "[]")
+		host = net.JoinHostPort(serverName, defaultPort)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if u.Scheme == "ldaps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: serverName})
+	} else {
+		conn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "unable to connect to ldap server %q", rawurl)
+	}
+	c := goldap.NewConn(conn, u.Scheme == "ldaps")
+	c.Start()
+	c.SetTimeout(timeout)
+	return c, serverName, nil
+}
+
+func search(c *goldap.Conn, base, filter string, attributes []string, timeout time.Duration) ([]*goldap.Entry, error) {
+	req := goldap.NewSearchRequest(base, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		0, int(timeout.Seconds()), false, filter, attributes, nil)
+	result, err := c.Search(req)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/auth/native"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/validation"
+	goldap "gopkg.in/ldap.v2"
+)
+
+var (
+	ErrMissingCredentials = &tsuruErrors.ValidationError{Message: "You must provide the user and password to login"}
+	ErrMissingEmail       = &tsuruErrors.NotAuthorizedError{Message: "The LDAP user doesn't have an email address."}
+)
+
+// LDAPScheme authenticates users against an LDAP server, such as OpenLDAP
+// or Active Directory. Users are looked up with a service account, using the
+// name given in the login, and authenticated by binding with their own DN and
+// password. Users are created in tsuru on their first login, and may be
+// granted a role in teams mapped from their LDAP groups.
+type LDAPScheme struct {
+	BaseConfig BaseConfig
+}
+
+type BaseConfig struct {
+	URL                  string
+	BindDN               string
+	BindPassword         string
+	SearchBase           string
+	UserAttribute        string
+	UserObjectClass      string
+	MailAttribute        string
+	GroupSearchBase      string
+	GroupObjectClass     string
+	GroupMemberAttribute string
+	GroupNameAttribute   string
+	GroupTeams           map[string]string
+	TeamRole             string
+	StartTLS             bool
+	Timeout              time.Duration
+}
+
+func init() {
+	auth.RegisterScheme("ldap", &LDAPScheme{})
+}
+
+func configWithDefault(key, defaultValue string) string {
+	value, err := config.GetString(key)
+	if err != nil || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// This method loads basic config and returns a copy of the
+// config object.
+func (s *LDAPScheme) loadConfig() (BaseConfig, error) {
+	if s.BaseConfig.URL != "" {
+		return s.BaseConfig, nil
+	}
+	var emptyConfig BaseConfig
+	url, err := config.GetString("auth:ldap:url")
+	if err != nil {
+		return emptyConfig, err
+	}
+	searchBase, err := config.GetString("auth:ldap:search-base")
+	if err != nil {
+		return emptyConfig, err
+	}
+	conf := BaseConfig{
+		URL:                  url,
+		SearchBase:           searchBase,
+		BindDN:               configWithDefault("auth:ldap:bind-dn", ""),
+		BindPassword:         configWithDefault("auth:ldap:bind-password", ""),
+		UserAttribute:        configWithDefault("auth:ldap:user-attribute", "uid"),
+		UserObjectClass:      configWithDefault("auth:ldap:user-object-class", ""),
+		MailAttribute:        configWithDefault("auth:ldap:mail-attribute", "mail"),
+		GroupSearchBase:      configWithDefault("auth:ldap:group-search-base", ""),
+		GroupObjectClass:     configWithDefault("auth:ldap:group-object-class", ""),
+		GroupMemberAttribute: configWithDefault("auth:ldap:group-member-attribute", "member"),
+		GroupNameAttribute:   configWithDefault("auth:ldap:group-name-attribute", "cn"),
+		TeamRole:             configWithDefault("auth:ldap:team-role", ""),
+		GroupTeams:           make(map[string]string),
+		Timeout:              10 * time.Second,
+	}
+	conf.StartTLS, _ = config.GetBool("auth:ldap:start-tls")
+	if timeout, err := config.GetInt("auth:ldap:timeout"); err == nil && timeout > 0 {
+		conf.Timeout = time.Duration(timeout) * time.Second
+	}
+	groupTeams, _ := config.Get("auth:ldap:group-teams")
+	if groupTeams != nil {
+		teamsMap, ok := groupTeams.(map[interface{}]interface{})
+		if !ok {
+			return emptyConfig, errors.New("auth:ldap:group-teams must be a map of group names to team names")
+		}
+		for group, team := range teamsMap {
+			conf.GroupTeams[fmt.Sprint(group)] = fmt.Sprint(team)
+		}
+	}
+	s.BaseConfig = conf
+	return conf, nil
+}
+
+func (s *LDAPScheme) userFilter(conf BaseConfig, username string) string {
+	filter := equalityFilter(conf.UserAttribute, username)
+	if conf.UserObjectClass != "" {
+		filter = andFilter(equalityFilter("objectClass", conf.UserObjectClass), filter)
+	}
+	return filter
+}
+
+func (s *LDAPScheme) groupFilter(conf BaseConfig, dn string) string {
+	filter := equalityFilter(conf.GroupMemberAttribute, dn)
+	if conf.GroupObjectClass != "" {
+		filter = andFilter(equalityFilter("objectClass", conf.GroupObjectClass), filter)
+	}
+	return filter
+}
+
+func (s *LDAPScheme) Login(params map[string]string) (auth.Token, error) {
+	conf, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	username, password := params["email"], params["password"]
+	if username == "" || password == "" {
+		return nil, ErrMissingCredentials
+	}
+	c, serverName, err := dial(conf.URL, conf.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if conf.StartTLS {
+		err = c.StartTLS(&tls.Config{ServerName: serverName})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to start tls with ldap server")
+		}
+	}
+	if conf.BindDN != "" {
+		err = c.Bind(conf.BindDN, conf.BindPassword)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to bind to ldap server")
+		}
+	}
+	entries, err := search(c, conf.SearchBase, s.userFilter(conf, username), []string{conf.MailAttribute}, conf.Timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to search ldap user")
+	}
+	if len(entries) != 1 {
+		return nil, auth.AuthenticationFailure{}
+	}
+	userEntry := entries[0]
+	err = c.Bind(userEntry.DN, password)
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, auth.AuthenticationFailure{}
+		}
+		return nil, err
+	}
+	email := attributeValue(userEntry, conf.MailAttribute)
+	if email == "" && validation.ValidateEmail(username) {
+		email = username
+	}
+	if email == "" {
+		return nil, ErrMissingEmail
+	}
+	user, err := auth.GetUserByEmail(email)
+	if err != nil {
+		if err != auth.ErrUserNotFound {
+			return nil, err
+		}
+		registrationEnabled, _ := config.GetBool("auth:user-registration")
+		if !registrationEnabled {
+			return nil, err
+		}
+		user = &auth.User{Email: email}
+		err = user.Create()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if user.Deactivated {
+		return nil, auth.ErrUserDeactivated
+	}
+	err = s.syncTeams(c, conf, user, userEntry.DN)
+	if err != nil {
+		log.Errorf("unable to sync ldap groups of user %q: %s", email, err)
+	}
+	return native.CreateUserToken(user)
+}
+
+// syncTeams grants the configured team role to the user in every team mapped
+// from the LDAP groups the user is a member of, and removes it from the
+// mapped teams of the groups the user is no longer a member of.
+func (s *LDAPScheme) syncTeams(c *goldap.Conn, conf BaseConfig, user *auth.User, dn string) error {
+	if conf.GroupSearchBase == "" || conf.TeamRole == "" || len(conf.GroupTeams) == 0 {
+		return nil
+	}
+	if conf.BindDN != "" {
+		err := c.Bind(conf.BindDN, conf.BindPassword)
+		if err != nil {
+			return err
+		}
+	}
+	groups, err := search(c, conf.GroupSearchBase, s.groupFilter(conf, dn), []string{conf.GroupNameAttribute}, conf.Timeout)
+	if err != nil {
+		return err
+	}
+	memberTeams := make(map[string]bool)
+	for _, group := range groups {
+		if team, ok := conf.GroupTeams[attributeValue(group, conf.GroupNameAttribute)]; ok {
+			memberTeams[team] = true
+		}
+	}
+	granted := make(map[string]bool)
+	for _, r := range user.Roles {
+		if r.Name == conf.TeamRole {
+			granted[r.ContextValue] = true
+		}
+	}
+	for _, team := range conf.GroupTeams {
+		switch {
+		case memberTeams[team] && !granted[team]:
+			err = user.AddRole(conf.TeamRole, team)
+		case !memberTeams[team] && granted[team]:
+			err = user.RemoveRole(conf.TeamRole, team)
+		}
+		if err != nil {
+			return err
+		}
+		granted[team] = memberTeams[team]
+	}
+	return nil
+}
+
+func (s *LDAPScheme) AppLogin(appName string) (auth.Token, error) {
+	nativeScheme := native.NativeScheme{}
+	return nativeScheme.AppLogin(appName)
+}
+
+func (s *LDAPScheme) AppLogout(token string) error {
+	return s.Logout(token)
+}
+
+func (s *LDAPScheme) Logout(token string) error {
+	return native.NativeScheme{}.Logout(token)
+}
+
+func (s *LDAPScheme) Auth(token string) (auth.Token, error) {
+	return native.NativeScheme{}.Auth(token)
+}
+
+func (s *LDAPScheme) Name() string {
+	return "ldap"
+}
+
+func (s *LDAPScheme) Info() (auth.SchemeInfo, error) {
+	return nil, nil
+}
+
+func (s *LDAPScheme) Create(user *auth.User) (*auth.User, error) {
+	user.Password = ""
+	err := user.Create()
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RevokeUserTokens removes all session tokens of the user.
+func (s *LDAPScheme) RevokeUserTokens(email string) error {
+	return native.NativeScheme{}.RevokeUserTokens(email)
+}
+
+func (s *LDAPScheme) Remove(u *auth.User) error {
+	return native.NativeScheme{}.Remove(u)
+}
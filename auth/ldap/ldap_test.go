@@ -0,0 +1,207 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/auth/native"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) addUser(dn, uid, mail, password string) {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	attrs := map[string][]string{"uid": {uid}, "objectClass": {"inetOrgPerson"}}
+	if mail != "" {
+		attrs["mail"] = []string{mail}
+	}
+	s.server.entries = append(s.server.entries, fakeEntry{dn: dn, attributes: attrs})
+	s.server.passwords[dn] = password
+}
+
+func (s *S) TestLDAPLogin(c *check.C) {
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "x@x.com", "123456")
+	scheme := LDAPScheme{}
+	token, err := scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.IsNil)
+	c.Assert(token.GetUserName(), check.Equals, "x@x.com")
+	c.Assert(s.server.binds, check.DeepEquals, []string{
+		"cn=admin,dc=example,dc=com",
+		"uid=x,ou=people,dc=example,dc=com",
+	})
+	user, err := auth.GetUserByEmail("x@x.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(user.Password, check.Equals, "")
+	t, err := scheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(t.GetUserName(), check.Equals, "x@x.com")
+}
+
+func (s *S) TestLDAPLoginWrongPassword(c *check.C) {
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "x@x.com", "123456")
+	scheme := LDAPScheme{}
+	_, err := scheme.Login(map[string]string{"email": "x", "password": "wrong"})
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+	_, err = auth.GetUserByEmail("x@x.com")
+	c.Assert(err, check.Equals, auth.ErrUserNotFound)
+}
+
+func (s *S) TestLDAPLoginUserNotFound(c *check.C) {
+	scheme := LDAPScheme{}
+	_, err := scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+}
+
+func (s *S) TestLDAPLoginMissingCredentials(c *check.C) {
+	scheme := LDAPScheme{}
+	_, err := scheme.Login(map[string]string{"email": "x"})
+	c.Assert(err, check.Equals, ErrMissingCredentials)
+}
+
+func (s *S) TestLDAPLoginWithoutMailUsesUsername(c *check.C) {
+	s.addUser("uid=x@x.com,ou=people,dc=example,dc=com", "x@x.com", "", "123456")
+	scheme := LDAPScheme{}
+	token, err := scheme.Login(map[string]string{"email": "x@x.com", "password": "123456"})
+	c.Assert(err, check.IsNil)
+	c.Assert(token.GetUserName(), check.Equals, "x@x.com")
+}
+
+func (s *S) TestLDAPLoginWithoutMail(c *check.C) {
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "", "123456")
+	scheme := LDAPScheme{}
+	_, err := scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.Equals, ErrMissingEmail)
+}
+
+func (s *S) TestLDAPLoginSyncTeams(c *check.C) {
+	role, err := permission.NewRole("team-member", string(permission.CtxTeam), "")
+	c.Assert(err, check.IsNil)
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "x@x.com", "123456")
+	s.server.entries = append(s.server.entries,
+		fakeEntry{dn: "cn=devs,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"cn":     {"devs"},
+			"member": {"uid=x,ou=people,dc=example,dc=com"},
+		}},
+		fakeEntry{dn: "cn=ops,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"cn":     {"ops"},
+			"member": {"uid=y,ou=people,dc=example,dc=com"},
+		}},
+	)
+	scheme := LDAPScheme{BaseConfig: BaseConfig{
+		URL:                  "ldap://" + s.server.listener.Addr().String(),
+		SearchBase:           "ou=people,dc=example,dc=com",
+		BindDN:               "cn=admin,dc=example,dc=com",
+		BindPassword:         "adminpass",
+		UserAttribute:        "uid",
+		MailAttribute:        "mail",
+		GroupSearchBase:      "ou=groups,dc=example,dc=com",
+		GroupMemberAttribute: "member",
+		GroupNameAttribute:   "cn",
+		GroupTeams:           map[string]string{"devs": "developers", "ops": "operations"},
+		TeamRole:             role.Name,
+		Timeout:              time.Second,
+	}}
+	_, err = scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.IsNil)
+	user, err := auth.GetUserByEmail("x@x.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(user.Roles, check.DeepEquals, []auth.RoleInstance{{Name: "team-member", ContextValue: "developers"}})
+}
+
+func (s *S) TestLDAPLoginSyncTeamsRemovesLeftGroups(c *check.C) {
+	role, err := permission.NewRole("team-member", string(permission.CtxTeam), "")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("team-admin", string(permission.CtxTeam), "")
+	c.Assert(err, check.IsNil)
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "x@x.com", "123456")
+	s.server.entries = append(s.server.entries,
+		fakeEntry{dn: "cn=devs,ou=groups,dc=example,dc=com", attributes: map[string][]string{
+			"cn":     {"devs"},
+			"member": {"uid=x,ou=people,dc=example,dc=com"},
+		}},
+	)
+	user := &auth.User{Email: "x@x.com"}
+	err = user.Create()
+	c.Assert(err, check.IsNil)
+	err = user.AddRole(role.Name, "operations")
+	c.Assert(err, check.IsNil)
+	err = user.AddRole("team-admin", "operations")
+	c.Assert(err, check.IsNil)
+	scheme := LDAPScheme{BaseConfig: BaseConfig{
+		URL:                  "ldap://" + s.server.listener.Addr().String(),
+		SearchBase:           "ou=people,dc=example,dc=com",
+		BindDN:               "cn=admin,dc=example,dc=com",
+		BindPassword:         "adminpass",
+		UserAttribute:        "uid",
+		MailAttribute:        "mail",
+		GroupSearchBase:      "ou=groups,dc=example,dc=com",
+		GroupMemberAttribute: "member",
+		GroupNameAttribute:   "cn",
+		GroupTeams:           map[string]string{"devs": "developers", "ops": "operations"},
+		TeamRole:             role.Name,
+		Timeout:              time.Second,
+	}}
+	_, err = scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.IsNil)
+	user, err = auth.GetUserByEmail("x@x.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(user.Roles, check.DeepEquals, []auth.RoleInstance{
+		{Name: "team-admin", ContextValue: "operations"},
+		{Name: "team-member", ContextValue: "developers"},
+	})
+}
+
+func (s *S) TestLDAPLoginStartTLSRefused(c *check.C) {
+	s.addUser("uid=x,ou=people,dc=example,dc=com", "x", "x@x.com", "123456")
+	scheme := LDAPScheme{BaseConfig: BaseConfig{
+		URL:           "ldap://" + s.server.listener.Addr().String(),
+		SearchBase:    "ou=people,dc=example,dc=com",
+		BindDN:        "cn=admin,dc=example,dc=com",
+		BindPassword:  "adminpass",
+		UserAttribute: "uid",
+		MailAttribute: "mail",
+		StartTLS:      true,
+		Timeout:       time.Second,
+	}}
+	_, err := scheme.Login(map[string]string{"email": "x", "password": "123456"})
+	c.Assert(err, check.ErrorMatches, "unable to start tls with ldap server: LDAP Result Code 2 .*")
+	c.Assert(s.server.binds, check.IsNil)
+}
+
+func (s *S) TestLDAPLoadConfig(c *check.C) {
+	config.Set("auth:ldap:group-teams", map[interface{}]interface{}{"devs": "developers"})
+	defer config.Unset("auth:ldap:group-teams")
+	scheme := LDAPScheme{}
+	conf, err := scheme.loadConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.UserAttribute, check.Equals, "uid")
+	c.Assert(conf.MailAttribute, check.Equals, "mail")
+	c.Assert(conf.GroupTeams, check.DeepEquals, map[string]string{"devs": "developers"})
+	c.Assert(conf.StartTLS, check.Equals, false)
+}
+
+func (s *S) TestLDAPName(c *check.C) {
+	scheme := LDAPScheme{}
+	c.Assert(scheme.Name(), check.Equals, "ldap")
+}
+
+func (s *S) TestLDAPRemove(c *check.C) {
+	scheme := LDAPScheme{}
+	user, err := scheme.Create(&auth.User{Email: "x@x.com", Password: "123456"})
+	c.Assert(err, check.IsNil)
+	c.Assert(user.Password, check.Equals, "")
+	token, err := native.CreateUserToken(user)
+	c.Assert(err, check.IsNil)
+	err = scheme.Remove(user)
+	c.Assert(err, check.IsNil)
+	_, err = auth.GetUserByEmail("x@x.com")
+	c.Assert(err, check.Equals, auth.ErrUserNotFound)
+	_, err = scheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+}
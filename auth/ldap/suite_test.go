@@ -0,0 +1,212 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/dbtest"
+	"github.com/tsuru/tsuru/repository/repositorytest"
+	"gopkg.in/asn1-ber.v1"
+	"gopkg.in/check.v1"
+	goldap "gopkg.in/ldap.v2"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct {
+	conn   *db.Storage
+	server *fakeServer
+}
+
+var _ = check.Suite(&S{})
+
+func (s *S) SetUpSuite(c *check.C) {
+	var err error
+	s.server, err = newFakeServer()
+	c.Assert(err, check.IsNil)
+	config.Set("database:url", "127.0.0.1:27017")
+	config.Set("database:name", "tsuru_auth_ldap_test")
+	config.Set("auth:ldap:url", "ldap://"+s.server.listener.Addr().String())
+	config.Set("auth:ldap:bind-dn", "cn=admin,dc=example,dc=com")
+	config.Set("auth:ldap:bind-password", "adminpass")
+	config.Set("auth:ldap:search-base", "ou=people,dc=example,dc=com")
+	config.Set("auth:user-registration", true)
+	config.Set("repo-manager", "fake")
+}
+
+func (s *S) SetUpTest(c *check.C) {
+	s.conn, _ = db.Conn()
+	s.server.reset()
+	s.server.passwords["cn=admin,dc=example,dc=com"] = "adminpass"
+	repositorytest.Reset()
+}
+
+func (s *S) TearDownTest(c *check.C) {
+	err := dbtest.ClearAllCollections(s.conn.Users().Database)
+	c.Assert(err, check.IsNil)
+	s.conn.Close()
+}
+
+func (s *S) TearDownSuite(c *check.C) {
+	s.server.listener.Close()
+	conn, err := db.Conn()
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	conn.Users().Database.DropDatabase()
+}
+
+// fakeEntry is an entry stored in the fake LDAP server.
+type fakeEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+// fakeServer is an LDAP server handling simple binds and searches with
+// equality and "and" filters over a fixed set of entries. StartTLS requests
+// are refused.
+type fakeServer struct {
+	listener  net.Listener
+	mu        sync.Mutex
+	passwords map[string]string
+	entries   []fakeEntry
+	binds     []string
+}
+
+func newFakeServer() (*fakeServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	srv := &fakeServer{listener: l}
+	srv.reset()
+	go srv.serve()
+	return srv, nil
+}
+
+func (srv *fakeServer) reset() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.passwords = make(map[string]string)
+	srv.entries = nil
+	srv.binds = nil
+}
+
+func (srv *fakeServer) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handle(conn)
+	}
+}
+
+func (srv *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		msg, err := ber.ReadPacket(conn)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		id, _ := msg.Children[0].Value.(int64)
+		op := msg.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			code := srv.bind(op.Children[1].Data.String(), op.Children[2].Data.String())
+			conn.Write(response(id, result(goldap.ApplicationBindResponse, code)))
+		case goldap.ApplicationSearchRequest:
+			for _, e := range srv.search(op.Children[0].Data.String(), op.Children[6]) {
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, ""))
+				attrs := ber.NewSequence("")
+				for name, values := range e.attributes {
+					attr := ber.NewSequence("")
+					attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+					vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+					for _, v := range values {
+						vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+					}
+					attr.AppendChild(vals)
+					attrs.AppendChild(attr)
+				}
+				entry.AppendChild(attrs)
+				conn.Write(response(id, entry))
+			}
+			conn.Write(response(id, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess)))
+		case goldap.ApplicationExtendedRequest:
+			conn.Write(response(id, result(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError)))
+		default:
+			return
+		}
+	}
+}
+
+func response(id int64, op *ber.Packet) []byte {
+	msg := ber.NewSequence("")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	msg.AppendChild(op)
+	return msg.Bytes()
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+func (srv *fakeServer) bind(dn, password string) int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.binds = append(srv.binds, dn)
+	if expected, ok := srv.passwords[dn]; !ok || expected != password {
+		return goldap.LDAPResultInvalidCredentials
+	}
+	return goldap.LDAPResultSuccess
+}
+
+func (srv *fakeServer) search(base string, filter *ber.Packet) []fakeEntry {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var entries []fakeEntry
+	for _, e := range srv.entries {
+		if strings.HasSuffix(e.dn, base) && matches(e, filter) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func matches(e fakeEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, f := range filter.Children {
+			if !matches(e, f) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterEqualityMatch:
+		name, value := filter.Children[0].Data.String(), filter.Children[1].Data.String()
+		for attr, values := range e.attributes {
+			if !strings.EqualFold(attr, name) {
+				continue
+			}
+			for _, v := range values {
+				if v == value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -16,6 +16,17 @@ need to use the following token to finish this process:
 
 If you think this is email is wrong, just ignore it.`))
 
+var emailVerificationData = template.Must(template.New("verify").Parse(`Subject: [tsuru] Confirm your email address
+To: {{.UserEmail}}
+
+Welcome to tsuru! Before logging in, you need to confirm your email address by
+following the link below:
+
+{{.Link}}
+
+The link is valid for {{.Expiration}}. If you didn't create an account on
+tsuru, just ignore this email.`))
+
 var passwordResetConfirm = template.Must(template.New("reset").Parse(`Subject: [tsuru] Password successfully reset
 To: {{.email}}
 
@@ -27,4 +38,12 @@ This message is the confirmation that your password has been reset. The new pass
 
 Use it to authenticate with tsuru server, and change it later.`))
 
+var accountLockedData = template.Must(template.New("locked").Parse(`Subject: [tsuru] Account temporarily locked
+To: {{.email}}
+
+Your tsuru account has been temporarily locked after too many failed login
+attempts. You will be able to login again after {{.until}}.
+
+If these attempts weren't made by you, consider changing your password.`))
+
 var passwordChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz1234567890_@#$%^&*()~[]{}?=-+,.<>:;`"
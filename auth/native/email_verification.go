@@ -0,0 +1,176 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	defaultEmailVerificationExpire = 48 * time.Hour
+	// emailVerificationResendInterval is the minimum interval between two
+	// confirmation links sent to the same user.
+	emailVerificationResendInterval = 5 * time.Minute
+)
+
+var (
+	ErrEmailNotVerified     = &errors.NotAuthorizedError{Message: "email address not confirmed, follow the link sent to your email to confirm it"}
+	ErrEmailAlreadyVerified = &errors.ValidationError{Message: "email address already confirmed"}
+)
+
+type emailVerificationToken struct {
+	Token     string `bson:"_id"`
+	UserEmail string
+	Creation  time.Time
+}
+
+func emailVerificationEnabled() bool {
+	enabled, _ := config.GetBool("auth:email-verification:enabled")
+	return enabled
+}
+
+func emailVerificationExpire() time.Duration {
+	hours, _ := config.GetInt("auth:email-verification:expire-hours")
+	if hours <= 0 {
+		return defaultEmailVerificationExpire
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func createEmailVerificationToken(u *auth.User) (*emailVerificationToken, error) {
+	t := emailVerificationToken{
+		Token:     token(u.Email, crypto.SHA256),
+		UserEmail: u.Email,
+		Creation:  time.Now(),
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = conn.EmailVerificationTokens().Insert(t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func getEmailVerificationToken(token string) (*emailVerificationToken, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var t emailVerificationToken
+	err = conn.EmailVerificationTokens().FindId(token).One(&t)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	if time.Since(t.Creation) > emailVerificationExpire() {
+		return nil, auth.ErrInvalidToken
+	}
+	return &t, nil
+}
+
+func (t *emailVerificationToken) link() string {
+	host, _ := config.GetString("host")
+	u := url.URL{
+		Path:     fmt.Sprintf("/users/%s/verify", t.UserEmail),
+		RawQuery: url.Values{"token": {t.Token}}.Encode(),
+	}
+	return strings.TrimRight(host, "/") + u.String()
+}
+
+func sendEmailVerification(u *auth.User, t *emailVerificationToken) {
+	data := map[string]string{
+		"UserEmail":  u.Email,
+		"Link":       t.link(),
+		"Expiration": emailVerificationExpire().String(),
+	}
+	var body bytes.Buffer
+	err := emailVerificationData.Execute(&body, data)
+	if err != nil {
+		log.Errorf("Failed to send email verification to user %q: %s", u.Email, err)
+		return
+	}
+	err = sendEmail(u.Email, body.Bytes())
+	if err != nil {
+		log.Errorf("Failed to send email verification to user %q: %s", u.Email, err)
+	}
+}
+
+func startEmailVerification(u *auth.User) error {
+	t, err := createEmailVerificationToken(u)
+	if err != nil {
+		return err
+	}
+	go sendEmailVerification(u, t)
+	return nil
+}
+
+// VerifyEmail confirms the email address of the user, using the token sent
+// to the user by email, allowing the user to login.
+func (s NativeScheme) VerifyEmail(user *auth.User, token string) error {
+	if token == "" {
+		return auth.ErrInvalidToken
+	}
+	t, err := getEmailVerificationToken(token)
+	if err != nil {
+		return err
+	}
+	if t.UserEmail != user.Email {
+		return auth.ErrInvalidToken
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Users().Update(bson.M{"email": user.Email}, bson.M{"$unset": bson.M{"verificationpending": ""}})
+	if err != nil {
+		return err
+	}
+	user.VerificationPending = false
+	_, err = conn.EmailVerificationTokens().RemoveAll(bson.M{"useremail": user.Email})
+	return err
+}
+
+// ResendEmailVerification sends a new confirmation link to users that
+// haven't confirmed their email addresses yet. Requests made less than
+// emailVerificationResendInterval after the last link was sent are ignored,
+// so the endpoint can't be used to flood the mailbox of users.
+func (s NativeScheme) ResendEmailVerification(user *auth.User) error {
+	if !user.VerificationPending {
+		return ErrEmailAlreadyVerified
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	recent, err := conn.EmailVerificationTokens().Find(bson.M{
+		"useremail": user.Email,
+		"creation":  bson.M{"$gt": time.Now().Add(-emailVerificationResendInterval)},
+	}).Count()
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+	return startEmailVerification(user)
+}
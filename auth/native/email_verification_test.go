@@ -0,0 +1,107 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/auth/authtest"
+	"github.com/tsuru/tsuru/tsurutest"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestCreateWithEmailVerification(c *check.C) {
+	config.Set("auth:email-verification:enabled", true)
+	config.Set("host", "http://tsuru.example.com")
+	defer config.Unset("auth:email-verification")
+	defer config.Unset("host")
+	defer s.server.Reset()
+	u := &auth.User{Email: "new@tsuru.io", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	dbUser, err := auth.GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.VerificationPending, check.Equals, true)
+	var t emailVerificationToken
+	err = s.conn.EmailVerificationTokens().Find(bson.M{"useremail": u.Email}).One(&t)
+	c.Assert(err, check.IsNil)
+	var m authtest.Mail
+	err = tsurutest.WaitCondition(time.Second, func() bool {
+		s.server.Lock()
+		defer s.server.Unlock()
+		if len(s.server.MailBox) != 1 {
+			return false
+		}
+		m = s.server.MailBox[0]
+		return true
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(m.To, check.DeepEquals, []string{u.Email})
+	c.Assert(strings.Contains(string(m.Data), "http://tsuru.example.com/users/new@tsuru.io/verify?token="+t.Token), check.Equals, true)
+	_, err = nativeScheme.Login(map[string]string{"email": u.Email, "password": "wrong-password"})
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+	_, err = nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.Equals, ErrEmailNotVerified)
+	err = nativeScheme.VerifyEmail(dbUser, t.Token)
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.Login(map[string]string{"email": u.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.EmailVerificationTokens().Find(bson.M{"useremail": u.Email}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestCreateWithoutEmailVerification(c *check.C) {
+	u := &auth.User{Email: "new@tsuru.io", Password: "123456"}
+	_, err := nativeScheme.Create(u)
+	c.Assert(err, check.IsNil)
+	c.Assert(u.VerificationPending, check.Equals, false)
+	n, err := s.conn.EmailVerificationTokens().Find(nil).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestVerifyEmailInvalidToken(c *check.C) {
+	u := &auth.User{Email: "new@tsuru.io", VerificationPending: true}
+	err := nativeScheme.VerifyEmail(u, "")
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+	err = nativeScheme.VerifyEmail(u, "unknown")
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+	t, err := createEmailVerificationToken(&auth.User{Email: "other@tsuru.io"})
+	c.Assert(err, check.IsNil)
+	err = nativeScheme.VerifyEmail(u, t.Token)
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+}
+
+func (s *S) TestVerifyEmailExpiredToken(c *check.C) {
+	u := &auth.User{Email: "new@tsuru.io", VerificationPending: true}
+	t, err := createEmailVerificationToken(u)
+	c.Assert(err, check.IsNil)
+	err = s.conn.EmailVerificationTokens().UpdateId(t.Token, bson.M{"$set": bson.M{"creation": time.Now().Add(-49 * time.Hour)}})
+	c.Assert(err, check.IsNil)
+	err = nativeScheme.VerifyEmail(u, t.Token)
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+}
+
+func (s *S) TestResendEmailVerification(c *check.C) {
+	defer s.server.Reset()
+	u := &auth.User{Email: "new@tsuru.io", VerificationPending: true}
+	err := nativeScheme.ResendEmailVerification(u)
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.EmailVerificationTokens().Find(bson.M{"useremail": u.Email}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 1)
+	err = nativeScheme.ResendEmailVerification(u)
+	c.Assert(err, check.IsNil)
+	n, err = s.conn.EmailVerificationTokens().Find(bson.M{"useremail": u.Email}).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 1)
+	err = nativeScheme.ResendEmailVerification(s.user)
+	c.Assert(err, check.Equals, ErrEmailAlreadyVerified)
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const defaultLockoutDuration = 15 * time.Minute
+
+// loginAttempts tracks the failed logins of a user. Once the number of
+// failures reaches auth:login-throttle:max-attempts, the account is locked
+// for auth:login-throttle:lockout seconds.
+type loginAttempts struct {
+	Email       string `bson:"_id"`
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+func loginThrottleConfig() (int, time.Duration) {
+	maxAttempts, _ := config.GetInt("auth:login-throttle:max-attempts")
+	lockout := defaultLockoutDuration
+	if seconds, err := config.GetInt("auth:login-throttle:lockout"); err == nil && seconds > 0 {
+		lockout = time.Duration(seconds) * time.Second
+	}
+	return maxAttempts, lockout
+}
+
+// checkLocked returns an authentication failure if the account of the given
+// user is temporarily locked.
+func checkLocked(email string) error {
+	maxAttempts, _ := loginThrottleConfig()
+	if maxAttempts <= 0 {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var attempts loginAttempts
+	err = conn.LoginAttempts().FindId(email).One(&attempts)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().Before(attempts.LockedUntil) {
+		return auth.AuthenticationFailure{
+			Message: fmt.Sprintf("Too many failed login attempts, account locked until %s.", attempts.LockedUntil.Format(time.RFC3339)),
+		}
+	}
+	return nil
+}
+
+// registerFailedLogin counts a failed login of the user, locking the account
+// and notifying the user by email when the limit is reached. Failures older
+// than the lockout duration are forgotten. The counter is updated atomically,
+// so concurrent failures are neither lost nor notified twice.
+func registerFailedLogin(u *auth.User) error {
+	maxAttempts, lockout := loginThrottleConfig()
+	if maxAttempts <= 0 {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	now := time.Now().UTC()
+	coll := conn.LoginAttempts()
+	err = coll.Update(
+		bson.M{"_id": u.Email, "lastfailure": bson.M{"$lt": now.Add(-lockout)}},
+		bson.M{"$set": bson.M{"failures": 0}},
+	)
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	var attempts loginAttempts
+	_, err = coll.FindId(u.Email).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"lastfailure": now}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &attempts)
+	if err != nil {
+		return err
+	}
+	if attempts.Failures < maxAttempts {
+		return nil
+	}
+	lockedUntil := now.Add(lockout)
+	err = coll.Update(
+		bson.M{"_id": u.Email, "failures": bson.M{"$gte": maxAttempts}},
+		bson.M{"$set": bson.M{"failures": 0, "lockeduntil": lockedUntil}},
+	)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	go sendAccountLocked(u, lockedUntil)
+	return nil
+}
+
+// resetLoginAttempts forgets the failed logins of the user, after a
+// successful login.
+func resetLoginAttempts(email string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.LoginAttempts().RemoveId(email)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func sendAccountLocked(u *auth.User, until time.Time) {
+	m := map[string]string{
+		"email": u.Email,
+		"until": until.Format(time.RFC3339),
+	}
+	var body bytes.Buffer
+	err := accountLockedData.Execute(&body, m)
+	if err != nil {
+		log.Errorf("Failed to send account locked notification to user %q: %s", u.Email, err)
+		return
+	}
+	err = sendEmail(u.Email, body.Bytes())
+	if err != nil {
+		log.Errorf("Failed to send account locked notification to user %q: %s", u.Email, err)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/tsurutest"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestNativeLoginLocksAccount(c *check.C) {
+	config.Set("auth:login-throttle:max-attempts", 3)
+	config.Set("auth:login-throttle:lockout", 60)
+	defer config.Unset("auth:login-throttle")
+	defer s.server.Reset()
+	wrong := map[string]string{"email": s.user.Email, "password": "wrongpass"}
+	for i := 0; i < 3; i++ {
+		_, err := nativeScheme.Login(wrong)
+		c.Assert(err, check.ErrorMatches, "Authentication failed, wrong password.")
+	}
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+	c.Assert(err, check.ErrorMatches, "Too many failed login attempts, account locked until .*")
+	err = tsurutest.WaitCondition(time.Second, func() bool {
+		s.server.RLock()
+		defer s.server.RUnlock()
+		return len(s.server.MailBox) == 1
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(s.server.MailBox[0].To, check.DeepEquals, []string{s.user.Email})
+	c.Assert(strings.Contains(string(s.server.MailBox[0].Data), "Account temporarily locked"), check.Equals, true)
+}
+
+func (s *S) TestNativeLoginLockExpires(c *check.C) {
+	config.Set("auth:login-throttle:max-attempts", 1)
+	config.Set("auth:login-throttle:lockout", 60)
+	defer config.Unset("auth:login-throttle")
+	defer s.server.Reset()
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "wrongpass"})
+	c.Assert(err, check.ErrorMatches, "Authentication failed, wrong password.")
+	err = s.conn.LoginAttempts().UpdateId(s.user.Email, map[string]interface{}{
+		"$set": map[string]interface{}{"lockeduntil": time.Now().Add(-time.Second)},
+	})
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	count, err := s.conn.LoginAttempts().FindId(s.user.Email).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(count, check.Equals, 0)
+}
+
+func (s *S) TestRegisterFailedLoginConcurrent(c *check.C) {
+	config.Set("auth:login-throttle:max-attempts", 5)
+	config.Set("auth:login-throttle:lockout", 60)
+	defer config.Unset("auth:login-throttle")
+	defer s.server.Reset()
+	var wg sync.WaitGroup
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := registerFailedLogin(s.user)
+			c.Check(err, check.IsNil)
+		}()
+	}
+	wg.Wait()
+	var attempts loginAttempts
+	err := s.conn.LoginAttempts().FindId(s.user.Email).One(&attempts)
+	c.Assert(err, check.IsNil)
+	c.Assert(attempts.Failures < 5, check.Equals, true)
+	c.Assert(attempts.LockedUntil.After(time.Now()), check.Equals, true)
+	err = tsurutest.WaitCondition(time.Second, func() bool {
+		s.server.RLock()
+		defer s.server.RUnlock()
+		return len(s.server.MailBox) == 1
+	})
+	c.Assert(err, check.IsNil)
+	time.Sleep(100 * time.Millisecond)
+	s.server.RLock()
+	defer s.server.RUnlock()
+	c.Assert(s.server.MailBox, check.HasLen, 1)
+}
+
+func (s *S) TestRegisterFailedLoginForgetsOldFailures(c *check.C) {
+	config.Set("auth:login-throttle:max-attempts", 2)
+	config.Set("auth:login-throttle:lockout", 60)
+	defer config.Unset("auth:login-throttle")
+	err := s.conn.LoginAttempts().Insert(loginAttempts{
+		Email:       s.user.Email,
+		Failures:    1,
+		LastFailure: time.Now().UTC().Add(-time.Hour),
+	})
+	c.Assert(err, check.IsNil)
+	err = registerFailedLogin(s.user)
+	c.Assert(err, check.IsNil)
+	var attempts loginAttempts
+	err = s.conn.LoginAttempts().FindId(s.user.Email).One(&attempts)
+	c.Assert(err, check.IsNil)
+	c.Assert(attempts.Failures, check.Equals, 1)
+	c.Assert(attempts.LockedUntil.IsZero(), check.Equals, true)
+}
+
+func (s *S) TestNativeLoginThrottleDisabled(c *check.C) {
+	wrong := map[string]string{"email": s.user.Email, "password": "wrongpass"}
+	for i := 0; i < 10; i++ {
+		nativeScheme.Login(wrong)
+	}
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestNativeLoginUpdatesPasswordCost(c *check.C) {
+	config.Set("auth:hash-cost", bcrypt.MinCost+1)
+	defer config.Set("auth:hash-cost", bcrypt.MinCost)
+	cost = 0
+	tokenExpire = 0
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	u, err := auth.GetUserByEmail(s.user.Email)
+	c.Assert(err, check.IsNil)
+	hashCost, err := bcrypt.Cost([]byte(u.Password))
+	c.Assert(err, check.IsNil)
+	c.Assert(hashCost, check.Equals, bcrypt.MinCost+1)
+}
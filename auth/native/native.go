@@ -8,6 +8,7 @@ import (
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/validation"
 )
 
@@ -18,6 +19,7 @@ var (
 	ErrInvalidPassword      = &errors.ValidationError{Message: "password length should be least 6 characters and at most 50 characters"}
 	ErrEmailRegistered      = &errors.ConflictError{Message: "this email is already registered"}
 	ErrPasswordMismatch     = &errors.NotAuthorizedError{Message: "the given password didn't match the user's current password"}
+	ErrTokenNotRefreshable  = &errors.ValidationError{Message: "only user session tokens can be refreshed"}
 )
 
 type NativeScheme struct{}
@@ -35,17 +37,63 @@ func (s NativeScheme) Login(params map[string]string) (auth.Token, error) {
 	if !ok {
 		return nil, ErrMissingPasswordError
 	}
-	user, err := auth.GetUserByEmail(email)
+	user, err := checkCredentials(email, password)
+	if err != nil {
+		return nil, err
+	}
+	err = checkTwoFactor(user, params["otp"])
 	if err != nil {
+		if err == ErrTwoFactorInvalidCode {
+			if regErr := registerFailedLogin(user); regErr != nil {
+				log.Errorf("Failed to register failed login of user %q: %s", user.Email, regErr)
+			}
+		}
 		return nil, err
 	}
-	token, err := createToken(user, password)
+	token, err := insertUserToken(user)
 	if err != nil {
 		return nil, err
 	}
+	if err = resetLoginAttempts(user.Email); err != nil {
+		log.Errorf("Failed to reset login attempts of user %q: %s", user.Email, err)
+	}
+	if err = updatePasswordCost(user, password); err != nil {
+		log.Errorf("Failed to update password hash of user %q: %s", user.Email, err)
+	}
 	return token, nil
 }
 
+// checkCredentials returns the user identified by the given email and
+// password, counting failed attempts towards the account lockout. The state
+// of the account is only reported after the password is checked, so it can't
+// be probed without the credentials.
+func checkCredentials(email, password string) (*auth.User, error) {
+	user, err := auth.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	err = checkLocked(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPassword(user.Password, password)
+	if err != nil {
+		if _, ok := err.(auth.AuthenticationFailure); ok {
+			if regErr := registerFailedLogin(user); regErr != nil {
+				log.Errorf("Failed to register failed login of user %q: %s", user.Email, regErr)
+			}
+		}
+		return nil, err
+	}
+	if user.Deactivated {
+		return nil, auth.ErrUserDeactivated
+	}
+	if user.VerificationPending {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
+
 func (s NativeScheme) Auth(token string) (auth.Token, error) {
 	return getToken(token)
 }
@@ -54,6 +102,28 @@ func (s NativeScheme) Logout(token string) error {
 	return deleteToken(token)
 }
 
+// RefreshToken exchanges a user token for a new one, with a renewed
+// expiration, removing the given token.
+func (s NativeScheme) RefreshToken(t auth.Token) (auth.Token, error) {
+	nativeToken, ok := t.(*Token)
+	if !ok || nativeToken.IsAppToken() {
+		return nil, ErrTokenNotRefreshable
+	}
+	user, err := nativeToken.User()
+	if err != nil {
+		return nil, err
+	}
+	newToken, err := insertUserToken(user)
+	if err != nil {
+		return nil, err
+	}
+	err = deleteToken(nativeToken.Token)
+	if err != nil {
+		return nil, err
+	}
+	return newToken, nil
+}
+
 func (s NativeScheme) AppLogin(appName string) (auth.Token, error) {
 	return createApplicationToken(appName)
 }
@@ -69,15 +139,27 @@ func (s NativeScheme) Create(user *auth.User) (*auth.User, error) {
 	if !validation.ValidateLength(user.Password, passwordMinLen, passwordMaxLen) {
 		return nil, ErrInvalidPassword
 	}
+	if err := loadPasswordPolicy().validate(user.Password); err != nil {
+		return nil, err
+	}
 	if _, err := auth.GetUserByEmail(user.Email); err == nil {
 		return nil, ErrEmailRegistered
 	}
 	if err := hashPassword(user); err != nil {
 		return nil, err
 	}
+	verify := emailVerificationEnabled()
+	if verify {
+		user.VerificationPending = true
+	}
 	if err := user.Create(); err != nil {
 		return nil, err
 	}
+	if verify {
+		if err := startEmailVerification(user); err != nil {
+			log.Errorf("Failed to start email verification of user %q: %s", user.Email, err)
+		}
+	}
 	return user, nil
 }
 
@@ -92,6 +174,9 @@ func (s NativeScheme) ChangePassword(token auth.Token, oldPassword string, newPa
 	if !validation.ValidateLength(newPassword, passwordMinLen, passwordMaxLen) {
 		return ErrInvalidPassword
 	}
+	if err = loadPasswordPolicy().validate(newPassword); err != nil {
+		return err
+	}
 	user.Password = newPassword
 	hashPassword(user)
 	return user.Update()
@@ -125,7 +210,7 @@ func (s NativeScheme) ResetPassword(user *auth.User, resetToken string) error {
 	if passToken.UserEmail != user.Email {
 		return auth.ErrInvalidToken
 	}
-	password := generatePassword(12)
+	password := loadPasswordPolicy().generate()
 	user.Password = password
 	hashPassword(user)
 	go sendNewPassword(user, password)
@@ -134,11 +219,22 @@ func (s NativeScheme) ResetPassword(user *auth.User, resetToken string) error {
 	return user.Update()
 }
 
+// RevokeUserTokens removes all session tokens of the user.
+func (s NativeScheme) RevokeUserTokens(email string) error {
+	return deleteAllTokens(email)
+}
+
 func (s NativeScheme) Remove(u *auth.User) error {
 	err := deleteAllTokens(u.Email)
 	if err != nil {
 		return err
 	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.TwoFactor().RemoveId(u.Email)
 	return u.Delete()
 }
 
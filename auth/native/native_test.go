@@ -47,6 +47,38 @@ func (s *S) TestNativeLogin(c *check.C) {
 	c.Assert(u.Email, check.Equals, "timeredbull@globo.com")
 }
 
+func (s *S) TestNativeLoginDeactivatedUser(c *check.C) {
+	err := s.user.Deactivate()
+	c.Assert(err, check.IsNil)
+	scheme := NativeScheme{}
+	params := map[string]string{"email": s.user.Email, "password": "123456"}
+	_, err = scheme.Login(params)
+	c.Assert(err, check.Equals, auth.ErrUserDeactivated)
+	err = s.user.Activate()
+	c.Assert(err, check.IsNil)
+	_, err = scheme.Login(params)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestNativeLoginDeactivatedUserWrongPassword(c *check.C) {
+	err := s.user.Deactivate()
+	c.Assert(err, check.IsNil)
+	scheme := NativeScheme{}
+	_, err = scheme.Login(map[string]string{"email": s.user.Email, "password": "wrong-password"})
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+}
+
+func (s *S) TestNativeRevokeUserTokens(c *check.C) {
+	scheme := NativeScheme{}
+	params := map[string]string{"email": s.user.Email, "password": "123456"}
+	token, err := scheme.Login(params)
+	c.Assert(err, check.IsNil)
+	err = scheme.RevokeUserTokens(s.user.Email)
+	c.Assert(err, check.IsNil)
+	_, err = scheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+}
+
 func (s *S) TestNativeLoginWrongPassword(c *check.C) {
 	scheme := NativeScheme{}
 	params := make(map[string]string)
@@ -255,3 +287,22 @@ func (s *S) TestNativeRemove(c *check.C) {
 	_, err = auth.GetUserByEmail("timeredbull@globo.com")
 	c.Assert(err, check.Equals, auth.ErrUserNotFound)
 }
+
+func (s *S) TestNativeRefreshToken(c *check.C) {
+	newToken, err := nativeScheme.RefreshToken(s.token)
+	c.Assert(err, check.IsNil)
+	c.Assert(newToken.GetValue(), check.Not(check.Equals), s.token.GetValue())
+	c.Assert(newToken.GetUserName(), check.Equals, s.user.Email)
+	_, err = nativeScheme.Auth("bearer " + s.token.GetValue())
+	c.Assert(err, check.Equals, auth.ErrInvalidToken)
+	t, err := nativeScheme.Auth("bearer " + newToken.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(t.GetUserName(), check.Equals, s.user.Email)
+}
+
+func (s *S) TestNativeRefreshAppToken(c *check.C) {
+	appToken, err := nativeScheme.AppLogin("myapp")
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.RefreshToken(appToken)
+	c.Assert(err, check.Equals, ErrTokenNotRefreshable)
+}
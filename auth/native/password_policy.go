@@ -0,0 +1,93 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/validation"
+)
+
+// passwordPolicy holds the complexity requirements of user passwords, loaded
+// from the auth:password section of the config file.
+type passwordPolicy struct {
+	minLength        int
+	requireUppercase bool
+	requireLowercase bool
+	requireDigit     bool
+	requireSymbol    bool
+}
+
+func loadPasswordPolicy() passwordPolicy {
+	p := passwordPolicy{minLength: passwordMinLen}
+	if minLength, err := config.GetInt("auth:password:min-length"); err == nil && minLength > p.minLength {
+		p.minLength = minLength
+	}
+	if p.minLength > passwordMaxLen {
+		p.minLength = passwordMaxLen
+	}
+	p.requireUppercase, _ = config.GetBool("auth:password:require-uppercase")
+	p.requireLowercase, _ = config.GetBool("auth:password:require-lowercase")
+	p.requireDigit, _ = config.GetBool("auth:password:require-digit")
+	p.requireSymbol, _ = config.GetBool("auth:password:require-symbol")
+	return p
+}
+
+// validate returns a validation error describing every requirement the
+// password fails to meet.
+func (p passwordPolicy) validate(password string) error {
+	var failures []string
+	if !validation.ValidateLength(password, p.minLength, passwordMaxLen) {
+		failures = append(failures, fmt.Sprintf("have between %d and %d characters", p.minLength, passwordMaxLen))
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.requireUppercase && !upper {
+		failures = append(failures, "contain an uppercase letter")
+	}
+	if p.requireLowercase && !lower {
+		failures = append(failures, "contain a lowercase letter")
+	}
+	if p.requireDigit && !digit {
+		failures = append(failures, "contain a digit")
+	}
+	if p.requireSymbol && !symbol {
+		failures = append(failures, "contain a symbol")
+	}
+	if len(failures) > 0 {
+		return &errors.ValidationError{Message: "password must " + strings.Join(failures, ", ")}
+	}
+	return nil
+}
+
+// generate generates a random password satisfying the policy,
+// used when resetting passwords.
+func (p passwordPolicy) generate() string {
+	length := 12
+	if p.minLength > length {
+		length = p.minLength
+	}
+	for {
+		password := generatePassword(length)
+		if p.validate(password) == nil {
+			return password
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPasswordPolicyDefault(c *check.C) {
+	p := loadPasswordPolicy()
+	c.Assert(p.validate("123456"), check.IsNil)
+	c.Assert(p.validate("12345"), check.NotNil)
+}
+
+func (s *S) TestPasswordPolicyValidate(c *check.C) {
+	config.Set("auth:password:min-length", 8)
+	config.Set("auth:password:require-uppercase", true)
+	config.Set("auth:password:require-lowercase", true)
+	config.Set("auth:password:require-digit", true)
+	config.Set("auth:password:require-symbol", true)
+	defer config.Unset("auth:password")
+	p := loadPasswordPolicy()
+	c.Assert(p.validate("Secret-123"), check.IsNil)
+	err := p.validate("secret")
+	c.Assert(err, check.FitsTypeOf, &errors.ValidationError{})
+	c.Assert(err, check.ErrorMatches, "password must have between 8 and 50 characters, contain an uppercase letter, contain a digit, contain a symbol")
+	c.Assert(p.validate(p.generate()), check.IsNil)
+}
+
+func (s *S) TestNativeCreatePasswordPolicy(c *check.C) {
+	config.Set("auth:password:require-digit", true)
+	defer config.Unset("auth:password")
+	_, err := nativeScheme.Create(&auth.User{Email: "x@x.com", Password: "abcdefg"})
+	c.Assert(err, check.ErrorMatches, "password must contain a digit")
+	_, err = nativeScheme.Create(&auth.User{Email: "x@x.com", Password: "abcdefg1"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestNativeChangePasswordPolicy(c *check.C) {
+	config.Set("auth:password:require-uppercase", true)
+	defer config.Unset("auth:password")
+	err := nativeScheme.ChangePassword(s.token, "123456", "abcdefg")
+	c.Assert(err, check.ErrorMatches, "password must contain an uppercase letter")
+	err = nativeScheme.ChangePassword(s.token, "123456", "Abcdefg")
+	c.Assert(err, check.IsNil)
+}
@@ -63,6 +63,15 @@ func (t *Token) GetAppName() string {
 	return t.AppName
 }
 
+// GetExpiration returns when the token expires, or the zero time if it never
+// expires.
+func (t *Token) GetExpiration() time.Time {
+	if t.Expires <= 0 {
+		return time.Time{}
+	}
+	return t.Creation.Add(t.Expires)
+}
+
 func (t *Token) Permissions() ([]permission.Permission, error) {
 	return auth.BaseTokenPermission(t)
 }
@@ -96,6 +105,23 @@ func hashPassword(u *auth.User) error {
 	return nil
 }
 
+// updatePasswordCost rehashes the password of the user if it was hashed with
+// a cost other than auth:hash-cost, so changes in the setting apply to
+// existing users as they login.
+func updatePasswordCost(u *auth.User, password string) error {
+	loadConfig()
+	hashCost, err := bcrypt.Cost([]byte(u.Password))
+	if err != nil || hashCost == cost {
+		return err
+	}
+	u.Password = password
+	err = hashPassword(u)
+	if err != nil {
+		return err
+	}
+	return u.Update()
+}
+
 func token(data string, hash crypto.Hash) string {
 	var tokenKey [keySize]byte
 	n, err := rand.Read(tokenKey[:])
@@ -176,6 +202,12 @@ func createToken(u *auth.User, password string) (*Token, error) {
 	if err := checkPassword(u.Password, password); err != nil {
 		return nil, err
 	}
+	return insertUserToken(u)
+}
+
+// insertUserToken creates a session token for a user whose credentials were
+// already checked.
+func insertUserToken(u *auth.User) (*Token, error) {
 	conn, err := db.Conn()
 	if err != nil {
 		return nil, err
@@ -190,6 +222,20 @@ func createToken(u *auth.User, password string) (*Token, error) {
 	return token, err
 }
 
+// CreateUserToken creates a session token for a user authenticated by other
+// means, such as an LDAP bind. Schemes that only differ from the native
+// scheme in the way users are authenticated use it to share its tokens.
+func CreateUserToken(u *auth.User) (auth.Token, error) {
+	if u.Email == "" {
+		return nil, errors.New("User does not have an email")
+	}
+	token, err := insertUserToken(u)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
 func getToken(header string) (*Token, error) {
 	conn, err := db.Conn()
 	if err != nil {
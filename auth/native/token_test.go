@@ -210,6 +210,23 @@ func (s *S) TestCreateTokenShouldSaveTheTokenInTheDatabase(c *check.C) {
 	c.Assert(result.Token, check.NotNil)
 }
 
+func (s *S) TestCreateUserToken(c *check.C) {
+	u := auth.User{Email: "wolverine@xmen.com"}
+	err := u.Create()
+	c.Assert(err, check.IsNil)
+	defer u.Delete()
+	token, err := CreateUserToken(&u)
+	c.Assert(err, check.IsNil)
+	t, err := nativeScheme.Auth("bearer " + token.GetValue())
+	c.Assert(err, check.IsNil)
+	c.Assert(t.GetUserName(), check.Equals, "wolverine@xmen.com")
+}
+
+func (s *S) TestCreateUserTokenWithoutEmail(c *check.C) {
+	_, err := CreateUserToken(&auth.User{})
+	c.Assert(err, check.ErrorMatches, "User does not have an email")
+}
+
 func (s *S) TestCreateTokenRemoveOldTokens(c *check.C) {
 	config.Set("auth:max-simultaneous-sessions", 2)
 	u := auth.User{Email: "para@xmen.com", Password: "123456"}
@@ -454,3 +471,11 @@ func (s *S) TestDeleteAllTokens(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(tokensDB, check.HasLen, 1)
 }
+
+func (s *S) TestTokenGetExpiration(c *check.C) {
+	creation := time.Now().UTC()
+	t := Token{Creation: creation, Expires: time.Hour}
+	c.Assert(t.GetExpiration(), check.DeepEquals, creation.Add(time.Hour))
+	t = Token{Creation: creation}
+	c.Assert(t.GetExpiration().IsZero(), check.Equals, true)
+}
@@ -0,0 +1,35 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestTOTPCode(c *check.C) {
+	// Test vectors from RFC 6238, truncated to 6 digits.
+	secret := []byte("12345678901234567890")
+	c.Assert(totpCode(secret, totpCounter(time.Unix(59, 0))), check.Equals, "287082")
+	c.Assert(totpCode(secret, totpCounter(time.Unix(1111111109, 0))), check.Equals, "081804")
+	c.Assert(totpCode(secret, totpCounter(time.Unix(1234567890, 0))), check.Equals, "005924")
+}
+
+func (s *S) TestValidateTOTP(c *check.C) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	counter, ok := validateTOTP(secret, "081804", now, 0)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(counter, check.Equals, totpCounter(now))
+	_, ok = validateTOTP(secret, "081804", now.Add(totpPeriod*time.Second), 0)
+	c.Assert(ok, check.Equals, true)
+	_, ok = validateTOTP(secret, "081804", now.Add(2*totpPeriod*time.Second), 0)
+	c.Assert(ok, check.Equals, false)
+	_, ok = validateTOTP(secret, "081804", now, counter)
+	c.Assert(ok, check.Equals, false)
+	_, ok = validateTOTP(secret, "000000", now, 0)
+	c.Assert(ok, check.Equals, false)
+}
@@ -0,0 +1,272 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const recoveryCodesCount = 10
+
+var (
+	ErrTwoFactorCodeRequired   = auth.AuthenticationFailure{Message: "Two-factor authentication code required."}
+	ErrTwoFactorInvalidCode    = auth.AuthenticationFailure{Message: "Invalid two-factor authentication code."}
+	ErrTwoFactorEnrollRequired = &errors.NotAuthorizedError{Message: "two-factor authentication is required, enroll before logging in"}
+	ErrTwoFactorNotEnrolled    = &errors.ValidationError{Message: "two-factor authentication is not enrolled"}
+	ErrTwoFactorAlreadyEnabled = &errors.ConflictError{Message: "two-factor authentication is already enabled"}
+)
+
+// twoFactor holds the TOTP secret and the recovery codes of a user. Recovery
+// codes are stored hashed, and each one may be used only once.
+type twoFactor struct {
+	Email         string `bson:"_id"`
+	Secret        string
+	Enabled       bool
+	RecoveryCodes []string
+	LastCounter   int64
+	CreatedAt     time.Time
+}
+
+func hashRecoveryCode(code string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(code)))
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = fmt.Sprintf("%x", b)
+	}
+	return codes, nil
+}
+
+func getTwoFactor(email string) (*twoFactor, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var tf twoFactor
+	err = conn.TwoFactor().FindId(email).One(&tf)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// twoFactorRequired returns whether the user must use two-factor
+// authentication, either because it's globally required or because the user
+// is a member of a team listed in auth:two-factor:required-teams.
+func twoFactorRequired(u *auth.User) (bool, error) {
+	if required, _ := config.GetBool("auth:two-factor:required"); required {
+		return true, nil
+	}
+	teams, _ := config.GetList("auth:two-factor:required-teams")
+	if len(teams) == 0 {
+		return false, nil
+	}
+	requiredTeams := make(map[string]bool, len(teams))
+	for _, t := range teams {
+		requiredTeams[t] = true
+	}
+	for _, roleData := range u.Roles {
+		if !requiredTeams[roleData.ContextValue] {
+			continue
+		}
+		role, err := permission.FindRole(roleData.Name)
+		if err == permission.ErrRoleNotFound {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if role.ContextType == permission.CtxTeam {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkTwoFactor validates the second factor of a login, which may be either
+// a TOTP code or one of the recovery codes of the user.
+func checkTwoFactor(u *auth.User, code string) error {
+	tf, err := getTwoFactor(u.Email)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		required, err := twoFactorRequired(u)
+		if err != nil {
+			return err
+		}
+		if required {
+			return ErrTwoFactorEnrollRequired
+		}
+		return nil
+	}
+	if code == "" {
+		return ErrTwoFactorCodeRequired
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if counter, ok := validateTOTP(tf.Secret, code, time.Now(), tf.LastCounter); ok {
+		err = conn.TwoFactor().Update(
+			bson.M{"_id": u.Email, "lastcounter": bson.M{"$lt": counter}},
+			bson.M{"$set": bson.M{"lastcounter": counter}},
+		)
+		if err == mgo.ErrNotFound {
+			return ErrTwoFactorInvalidCode
+		}
+		return err
+	}
+	err = conn.TwoFactor().Update(
+		bson.M{"_id": u.Email, "recoverycodes": hashRecoveryCode(code)},
+		bson.M{"$pull": bson.M{"recoverycodes": hashRecoveryCode(code)}},
+	)
+	if err == mgo.ErrNotFound {
+		return ErrTwoFactorInvalidCode
+	}
+	return err
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user, replacing any
+// pending enrollment. Two-factor authentication is only enabled once the
+// enrollment is confirmed with a valid code.
+func (s NativeScheme) EnrollTwoFactor(email, password string) (*auth.TwoFactorEnrollment, error) {
+	user, err := checkCredentials(email, password)
+	if err != nil {
+		return nil, err
+	}
+	tf, err := getTwoFactor(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	secret := make([]byte, 20)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	tf = &twoFactor{
+		Email:     user.Email,
+		Secret:    totpEncoding.EncodeToString(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_, err = conn.TwoFactor().UpsertId(tf.Email, tf)
+	if err != nil {
+		return nil, err
+	}
+	issuer, _ := config.GetString("auth:two-factor:issuer")
+	if issuer == "" {
+		issuer = "tsuru"
+	}
+	uri := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + user.Email,
+		RawQuery: url.Values{
+			"secret": []string{tf.Secret},
+			"issuer": []string{issuer},
+		}.Encode(),
+	}
+	return &auth.TwoFactorEnrollment{Secret: tf.Secret, URI: uri.String()}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication for the user, given a
+// valid code generated from the enrolled secret, returning the recovery
+// codes. Recovery codes are only returned once.
+func (s NativeScheme) ConfirmTwoFactor(email, password, code string) ([]string, error) {
+	user, err := checkCredentials(email, password)
+	if err != nil {
+		return nil, err
+	}
+	tf, err := getTwoFactor(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	counter, ok := validateTOTP(tf.Secret, code, time.Now(), tf.LastCounter)
+	if !ok {
+		return nil, ErrTwoFactorInvalidCode
+	}
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashed := make([]string, len(codes))
+	for i, c := range codes {
+		hashed[i] = hashRecoveryCode(c)
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = conn.TwoFactor().UpdateId(user.Email, bson.M{"$set": bson.M{
+		"enabled":       true,
+		"recoverycodes": hashed,
+		"lastcounter":   counter,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor disables two-factor authentication for the user, given a
+// valid TOTP or recovery code.
+func (s NativeScheme) DisableTwoFactor(user *auth.User, code string) error {
+	tf, err := getTwoFactor(user.Email)
+	if err != nil {
+		return err
+	}
+	if tf == nil || !tf.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+	err = checkTwoFactor(user, code)
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.TwoFactor().RemoveId(user.Email)
+}
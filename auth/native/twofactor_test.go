@@ -0,0 +1,154 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package native
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func currentCode(c *check.C, secret string) string {
+	key, err := totpEncoding.DecodeString(secret)
+	c.Assert(err, check.IsNil)
+	return totpCode(key, totpCounter(time.Now()))
+}
+
+func (s *S) enableTwoFactor(c *check.C) (string, []string) {
+	enrollment, err := nativeScheme.EnrollTwoFactor(s.user.Email, "123456")
+	c.Assert(err, check.IsNil)
+	codes, err := nativeScheme.ConfirmTwoFactor(s.user.Email, "123456", currentCode(c, enrollment.Secret))
+	c.Assert(err, check.IsNil)
+	return enrollment.Secret, codes
+}
+
+func (s *S) TestEnrollTwoFactor(c *check.C) {
+	enrollment, err := nativeScheme.EnrollTwoFactor(s.user.Email, "123456")
+	c.Assert(err, check.IsNil)
+	c.Assert(enrollment.Secret, check.Not(check.Equals), "")
+	uri, err := url.Parse(enrollment.URI)
+	c.Assert(err, check.IsNil)
+	c.Assert(uri.Scheme, check.Equals, "otpauth")
+	c.Assert(uri.Host, check.Equals, "totp")
+	c.Assert(uri.Path, check.Equals, "/tsuru:"+s.user.Email)
+	c.Assert(uri.Query().Get("secret"), check.Equals, enrollment.Secret)
+	c.Assert(uri.Query().Get("issuer"), check.Equals, "tsuru")
+	tf, err := getTwoFactor(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(tf.Enabled, check.Equals, false)
+	_, err = nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestEnrollTwoFactorWrongPassword(c *check.C) {
+	_, err := nativeScheme.EnrollTwoFactor(s.user.Email, "wrongpass")
+	c.Assert(err, check.FitsTypeOf, auth.AuthenticationFailure{})
+}
+
+func (s *S) TestConfirmTwoFactor(c *check.C) {
+	enrollment, err := nativeScheme.EnrollTwoFactor(s.user.Email, "123456")
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.ConfirmTwoFactor(s.user.Email, "123456", "000000")
+	c.Assert(err, check.Equals, ErrTwoFactorInvalidCode)
+	codes, err := nativeScheme.ConfirmTwoFactor(s.user.Email, "123456", currentCode(c, enrollment.Secret))
+	c.Assert(err, check.IsNil)
+	c.Assert(codes, check.HasLen, recoveryCodesCount)
+	tf, err := getTwoFactor(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(tf.Enabled, check.Equals, true)
+	c.Assert(tf.RecoveryCodes, check.HasLen, recoveryCodesCount)
+	c.Assert(tf.RecoveryCodes[0], check.Equals, hashRecoveryCode(codes[0]))
+	_, err = nativeScheme.EnrollTwoFactor(s.user.Email, "123456")
+	c.Assert(err, check.Equals, ErrTwoFactorAlreadyEnabled)
+}
+
+func (s *S) TestConfirmTwoFactorNotEnrolled(c *check.C) {
+	_, err := nativeScheme.ConfirmTwoFactor(s.user.Email, "123456", "000000")
+	c.Assert(err, check.Equals, ErrTwoFactorNotEnrolled)
+}
+
+func (s *S) TestNativeLoginTwoFactor(c *check.C) {
+	secret, _ := s.enableTwoFactor(c)
+	params := map[string]string{"email": s.user.Email, "password": "123456"}
+	_, err := nativeScheme.Login(params)
+	c.Assert(err, check.Equals, ErrTwoFactorCodeRequired)
+	params["otp"] = "000000"
+	_, err = nativeScheme.Login(params)
+	c.Assert(err, check.Equals, ErrTwoFactorInvalidCode)
+	// The code used in the confirmation can't be used again.
+	params["otp"] = currentCode(c, secret)
+	_, err = nativeScheme.Login(params)
+	c.Assert(err, check.Equals, ErrTwoFactorInvalidCode)
+	err = s.conn.TwoFactor().UpdateId(s.user.Email, map[string]interface{}{
+		"$set": map[string]interface{}{"lastcounter": 0},
+	})
+	c.Assert(err, check.IsNil)
+	token, err := nativeScheme.Login(params)
+	c.Assert(err, check.IsNil)
+	c.Assert(token.GetUserName(), check.Equals, s.user.Email)
+}
+
+func (s *S) TestNativeLoginTwoFactorRecoveryCode(c *check.C) {
+	_, codes := s.enableTwoFactor(c)
+	params := map[string]string{"email": s.user.Email, "password": "123456", "otp": codes[3]}
+	_, err := nativeScheme.Login(params)
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.Login(params)
+	c.Assert(err, check.Equals, ErrTwoFactorInvalidCode)
+	tf, err := getTwoFactor(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(tf.RecoveryCodes, check.HasLen, recoveryCodesCount-1)
+}
+
+func (s *S) TestNativeLoginTwoFactorRequired(c *check.C) {
+	config.Set("auth:two-factor:required", true)
+	defer config.Unset("auth:two-factor")
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.Equals, ErrTwoFactorEnrollRequired)
+	s.enableTwoFactor(c)
+	_, err = nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.Equals, ErrTwoFactorCodeRequired)
+}
+
+func (s *S) TestNativeLoginTwoFactorRequiredTeams(c *check.C) {
+	config.Set("auth:two-factor:required-teams", []interface{}{s.team.Name})
+	defer config.Unset("auth:two-factor")
+	_, err := nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+	role, err := permission.NewRole("team-member", "team", "")
+	c.Assert(err, check.IsNil)
+	err = s.user.AddRole(role.Name, s.team.Name)
+	c.Assert(err, check.IsNil)
+	_, err = nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.Equals, ErrTwoFactorEnrollRequired)
+}
+
+func (s *S) TestDisableTwoFactor(c *check.C) {
+	_, codes := s.enableTwoFactor(c)
+	err := nativeScheme.DisableTwoFactor(s.user, "000000")
+	c.Assert(err, check.Equals, ErrTwoFactorInvalidCode)
+	err = nativeScheme.DisableTwoFactor(s.user, codes[0])
+	c.Assert(err, check.IsNil)
+	tf, err := getTwoFactor(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(tf, check.IsNil)
+	err = nativeScheme.DisableTwoFactor(s.user, codes[1])
+	c.Assert(err, check.Equals, ErrTwoFactorNotEnrolled)
+	_, err = nativeScheme.Login(map[string]string{"email": s.user.Email, "password": "123456"})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestGenerateRecoveryCodes(c *check.C) {
+	codes, err := generateRecoveryCodes()
+	c.Assert(err, check.IsNil)
+	c.Assert(codes, check.HasLen, recoveryCodesCount)
+	c.Assert(codes[0], check.Not(check.Equals), codes[1])
+	c.Assert(strings.Trim(codes[0], "0123456789abcdef"), check.Equals, "")
+}
@@ -48,10 +48,21 @@ func (s *OAuthScheme) loadConfig() (oauth2.Config, error) {
 	if s.BaseConfig.ClientID != "" {
 		return s.BaseConfig, nil
 	}
+	var emptyConfig oauth2.Config
+	var preset provider
+	if name, _ := config.GetString("auth:oauth:provider"); name != "" {
+		var ok bool
+		preset, ok = providers[name]
+		if !ok {
+			return emptyConfig, errors.Errorf("unknown oauth provider %q", name)
+		}
+	}
+	if s.Parser == nil {
+		s.Parser = preset.parser
+	}
 	if s.Parser == nil {
 		s.Parser = s
 	}
-	var emptyConfig oauth2.Config
 	clientId, err := config.GetString("auth:oauth:client-id")
 	if err != nil {
 		return emptyConfig, err
@@ -60,19 +71,19 @@ func (s *OAuthScheme) loadConfig() (oauth2.Config, error) {
 	if err != nil {
 		return emptyConfig, err
 	}
-	scope, err := config.GetString("auth:oauth:scope")
+	scope, err := configWithDefault("auth:oauth:scope", preset.scope)
 	if err != nil {
 		return emptyConfig, err
 	}
-	authURL, err := config.GetString("auth:oauth:auth-url")
+	authURL, err := configWithDefault("auth:oauth:auth-url", preset.authURL)
 	if err != nil {
 		return emptyConfig, err
 	}
-	tokenURL, err := config.GetString("auth:oauth:token-url")
+	tokenURL, err := configWithDefault("auth:oauth:token-url", preset.tokenURL)
 	if err != nil {
 		return emptyConfig, err
 	}
-	infoURL, err := config.GetString("auth:oauth:info-url")
+	infoURL, err := configWithDefault("auth:oauth:info-url", preset.infoURL)
 	if err != nil {
 		return emptyConfig, err
 	}
@@ -94,6 +105,14 @@ func (s *OAuthScheme) loadConfig() (oauth2.Config, error) {
 	return s.BaseConfig, nil
 }
 
+func configWithDefault(key, defaultValue string) (string, error) {
+	value, err := config.GetString(key)
+	if err != nil && defaultValue != "" {
+		return defaultValue, nil
+	}
+	return value, err
+}
+
 func (s *OAuthScheme) Login(params map[string]string) (auth.Token, error) {
 	conf, err := s.loadConfig()
 	if err != nil {
@@ -151,6 +170,9 @@ func (s *OAuthScheme) handleToken(t *oauth2.Token) (*Token, error) {
 			return nil, err
 		}
 	}
+	if user.Deactivated {
+		return nil, auth.ErrUserDeactivated
+	}
 	token := Token{*t, email}
 	err = token.save()
 	if err != nil {
@@ -236,6 +258,11 @@ func (s *OAuthScheme) Create(user *auth.User) (*auth.User, error) {
 	return user, nil
 }
 
+// RevokeUserTokens removes all tokens of the user.
+func (s *OAuthScheme) RevokeUserTokens(email string) error {
+	return deleteAllTokens(email)
+}
+
 func (s *OAuthScheme) Remove(u *auth.User) error {
 	err := deleteAllTokens(u.Email)
 	if err != nil {
@@ -0,0 +1,70 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// provider holds the default settings of a well known OAuth server, used when
+// auth:oauth:provider is set. Settings explicitly configured in auth:oauth
+// take precedence.
+type provider struct {
+	scope    string
+	authURL  string
+	tokenURL string
+	infoURL  string
+	parser   OAuthParser
+}
+
+var providers = map[string]provider{
+	"github": {
+		scope:    "user:email",
+		authURL:  "https://github.com/login/oauth/authorize",
+		tokenURL: "https://github.com/login/oauth/access_token",
+		infoURL:  "https://api.github.com/user/emails",
+		parser:   githubParser{},
+	},
+	"google": {
+		scope:    "email",
+		authURL:  "https://accounts.google.com/o/oauth2/auth",
+		tokenURL: "https://accounts.google.com/o/oauth2/token",
+		infoURL:  "https://www.googleapis.com/oauth2/v3/userinfo",
+	},
+}
+
+// githubParser extracts the primary verified email of the user from the
+// response of GitHub's /user/emails endpoint, as the email returned by /user
+// is empty for users who keep their email private.
+type githubParser struct{}
+
+func (githubParser) Parse(infoResponse *http.Response) (string, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	data, err := ioutil.ReadAll(infoResponse.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read user data response")
+	}
+	if infoResponse.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected user data response %d: %s", infoResponse.StatusCode, data)
+	}
+	err = json.Unmarshal(data, &emails)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to parse user data: %s", data)
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, nil
+		}
+	}
+	return "", nil
+}
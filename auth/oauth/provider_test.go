@@ -0,0 +1,65 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/tsuru/config"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestOAuthProviderDefaults(c *check.C) {
+	config.Set("auth:oauth:provider", "github")
+	defer config.Unset("auth:oauth:provider")
+	config.Unset("auth:oauth:auth-url")
+	config.Unset("auth:oauth:scope")
+	defer config.Set("auth:oauth:auth-url", s.server.URL+"/auth")
+	defer config.Set("auth:oauth:scope", "myscope")
+	scheme := OAuthScheme{}
+	conf, err := scheme.loadConfig()
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.Endpoint.AuthURL, check.Equals, "https://github.com/login/oauth/authorize")
+	c.Assert(conf.Endpoint.TokenURL, check.Equals, s.server.URL+"/token")
+	c.Assert(conf.Scopes, check.DeepEquals, []string{"user:email"})
+	c.Assert(scheme.InfoUrl, check.Equals, s.server.URL+"/user")
+	c.Assert(scheme.Parser, check.FitsTypeOf, githubParser{})
+}
+
+func (s *S) TestOAuthProviderUnknown(c *check.C) {
+	config.Set("auth:oauth:provider", "myprovider")
+	defer config.Unset("auth:oauth:provider")
+	scheme := OAuthScheme{}
+	_, err := scheme.loadConfig()
+	c.Assert(err, check.ErrorMatches, `unknown oauth provider "myprovider"`)
+}
+
+func (s *S) TestGithubParserParse(c *check.C) {
+	b := ioutil.NopCloser(bytes.NewBufferString(`[
+		{"email":"x@users.noreply.github.com","primary":false,"verified":true},
+		{"email":"x@x.com","primary":true,"verified":true}
+	]`))
+	rsp := &http.Response{Body: b, StatusCode: http.StatusOK}
+	email, err := githubParser{}.Parse(rsp)
+	c.Assert(err, check.IsNil)
+	c.Assert(email, check.Equals, "x@x.com")
+}
+
+func (s *S) TestGithubParserParseUnverifiedEmail(c *check.C) {
+	b := ioutil.NopCloser(bytes.NewBufferString(`[{"email":"x@x.com","primary":true,"verified":false}]`))
+	rsp := &http.Response{Body: b, StatusCode: http.StatusOK}
+	email, err := githubParser{}.Parse(rsp)
+	c.Assert(err, check.IsNil)
+	c.Assert(email, check.Equals, "")
+}
+
+func (s *S) TestGithubParserParseInvalidStatus(c *check.C) {
+	b := ioutil.NopCloser(bytes.NewBufferString(`bad credentials`))
+	rsp := &http.Response{Body: b, StatusCode: http.StatusUnauthorized}
+	_, err := githubParser{}.Parse(rsp)
+	c.Assert(err, check.ErrorMatches, `unexpected user data response 401: bad credentials`)
+}
@@ -5,6 +5,8 @@
 package oauth
 
 import (
+	"time"
+
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
@@ -41,6 +43,12 @@ func (t *Token) GetAppName() string {
 	return ""
 }
 
+// GetExpiration returns when the access token expires, or the zero time if it
+// never expires.
+func (t *Token) GetExpiration() time.Time {
+	return t.Expiry
+}
+
 func (t *Token) Permissions() ([]permission.Permission, error) {
 	return auth.BaseTokenPermission(t)
 }
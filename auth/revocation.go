@@ -0,0 +1,109 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/db"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// revocationCacheInterval is how long the revocation list is cached in
+// memory. Tokens revoked in other API instances are rejected after at most
+// this interval.
+const revocationCacheInterval = 10 * time.Second
+
+// revokedToken is an entry in the revocation list. Only the hash of the token
+// is stored, so the list doesn't leak valid credentials. Entries of tokens
+// that expire are removed once the token expires.
+type revokedToken struct {
+	Hash      string `bson:"_id"`
+	User      string
+	RevokedAt time.Time
+	ExpiresAt time.Time `bson:",omitempty"`
+}
+
+var revocationCache = struct {
+	sync.Mutex
+	hashes   map[string]struct{}
+	loadedAt time.Time
+}{}
+
+func hashToken(value string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(value)))
+}
+
+// RevokeToken adds the token to the revocation list. Revoked tokens are
+// rejected by the API regardless of the scheme that issued them, including
+// API keys and tokens of external authentication providers.
+func RevokeToken(t Token) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	entry := revokedToken{
+		Hash:      hashToken(t.GetValue()),
+		User:      t.GetUserName(),
+		RevokedAt: time.Now().UTC(),
+	}
+	if expirable, ok := t.(ExpirableToken); ok {
+		entry.ExpiresAt = expirable.GetExpiration()
+	}
+	_, err = conn.RevokedTokens().UpsertId(entry.Hash, entry)
+	if err != nil {
+		return err
+	}
+	revocationCache.Lock()
+	revocationCache.hashes = nil
+	revocationCache.Unlock()
+	return nil
+}
+
+// IsTokenRevoked returns whether the token in the given authorization header
+// was revoked. The revocation list is cached in memory, so checking it
+// doesn't hit the database on every request.
+func IsTokenRevoked(header string) (bool, error) {
+	value, err := ParseToken(header)
+	if err != nil {
+		return false, err
+	}
+	hashes, err := revokedTokenHashes()
+	if err != nil {
+		return false, err
+	}
+	_, revoked := hashes[hashToken(value)]
+	return revoked, nil
+}
+
+func revokedTokenHashes() (map[string]struct{}, error) {
+	revocationCache.Lock()
+	defer revocationCache.Unlock()
+	if revocationCache.hashes != nil && time.Since(revocationCache.loadedAt) < revocationCacheInterval {
+		return revocationCache.hashes, nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	hashes := make(map[string]struct{})
+	var entry revokedToken
+	iter := conn.RevokedTokens().Find(nil).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&entry) {
+		hashes[entry.Hash] = struct{}{}
+	}
+	err = iter.Close()
+	if err != nil {
+		return nil, err
+	}
+	revocationCache.hashes = hashes
+	revocationCache.loadedAt = time.Now()
+	return hashes, nil
+}
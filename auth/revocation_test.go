@@ -0,0 +1,67 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRevokeToken(c *check.C) {
+	t := &APIToken{Token: "abc123", UserEmail: s.user.Email}
+	revoked, err := IsTokenRevoked("bearer abc123")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, false)
+	err = RevokeToken(t)
+	c.Assert(err, check.IsNil)
+	revoked, err = IsTokenRevoked("bearer abc123")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, true)
+	revoked, err = IsTokenRevoked("bearer abc1234")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, false)
+	var entry revokedToken
+	err = s.conn.RevokedTokens().FindId(hashToken("abc123")).One(&entry)
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.User, check.Equals, s.user.Email)
+	err = RevokeToken(t)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestIsTokenRevokedInvalidHeader(c *check.C) {
+	_, err := IsTokenRevoked("")
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestRevokeTokenExpiration(c *check.C) {
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	err := RevokeToken(&ScopedToken{Token: "abc123", Creator: s.user.Email, ExpiresAt: expiresAt})
+	c.Assert(err, check.IsNil)
+	err = RevokeToken(&APIToken{Token: "def456", UserEmail: s.user.Email})
+	c.Assert(err, check.IsNil)
+	var entry revokedToken
+	err = s.conn.RevokedTokens().FindId(hashToken("abc123")).One(&entry)
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.ExpiresAt.Equal(expiresAt), check.Equals, true)
+	err = s.conn.RevokedTokens().FindId(hashToken("def456")).One(&entry)
+	c.Assert(err, check.IsNil)
+	c.Assert(entry.ExpiresAt.IsZero(), check.Equals, true)
+}
+
+func (s *S) TestIsTokenRevokedCached(c *check.C) {
+	revoked, err := IsTokenRevoked("bearer abc123")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, false)
+	err = s.conn.RevokedTokens().Insert(revokedToken{Hash: hashToken("abc123")})
+	c.Assert(err, check.IsNil)
+	revoked, err = IsTokenRevoked("bearer abc123")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, false)
+	revocationCache.loadedAt = time.Now().Add(-revocationCacheInterval)
+	revoked, err = IsTokenRevoked("bearer abc123")
+	c.Assert(err, check.IsNil)
+	c.Assert(revoked, check.Equals, true)
+}
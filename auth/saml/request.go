@@ -23,6 +23,7 @@ type request struct {
 	Expires  time.Time `json:"expires"`
 	Email    string    `json:"email"`
 	Authed   bool      `json:"authed"`
+	Groups   []string  `json:"groups"`
 }
 
 func (r *request) expireTime() time.Duration {
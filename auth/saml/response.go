@@ -5,6 +5,8 @@
 package saml
 
 import (
+	"strings"
+
 	"github.com/diego-araujo/go-saml"
 	"github.com/pkg/errors"
 	"github.com/tsuru/config"
@@ -36,11 +38,32 @@ func getUserIdentity(r *saml.Response) (string, error) {
 	}
 	userIdentifier := r.GetAttribute(attrFriendlyNameIdentifier)
 	if userIdentifier == "" {
-		return "", errors.Errorf("unable to parse identity provider data - not found  <Attribute FriendlyName=" + attrFriendlyNameIdentifier + "> ")
+		return "", errors.Errorf("unable to parse identity provider data - not found  <Attribute FriendlyName=%s> ", attrFriendlyNameIdentifier)
 	}
 	return userIdentifier, nil
 }
 
+// getUserGroups returns the values of every attribute with the given name or
+// friendly name. Values may also be sent as a comma separated list.
+func getUserGroups(r *saml.Response, name string) []string {
+	attrStatement := r.Assertion.AttributeStatement
+	if r.IsEncrypted() {
+		attrStatement = r.EncryptedAssertion.Assertion.AttributeStatement
+	}
+	var groups []string
+	for _, attr := range attrStatement.Attributes {
+		if attr.Name != name && attr.FriendlyName != name {
+			continue
+		}
+		for _, group := range strings.Split(attr.AttributeValue.Value, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
 func validateResponse(r *saml.Response, sp *saml.ServiceProviderSettings) error {
 	if err := r.Validate(sp); err != nil {
 		return err
@@ -47,6 +47,9 @@ type BaseConfig struct {
 	SignRequest           bool
 	SignedResponse        bool
 	DeflatEncodedResponse bool
+	GroupsAttribute       string
+	GroupTeams            map[string]string
+	TeamRole              string
 }
 
 func init() {
@@ -110,6 +113,18 @@ func (s *SAMLAuthScheme) loadConfig() (BaseConfig, error) {
 		deflatEncodedResponse = false
 		log.Debugf("auth:saml:idp-deflate-encoding not found using default [false]: %s", err)
 	}
+	groupsAttribute, _ := config.GetString("auth:saml:idp-attribute-groups")
+	teamRole, _ := config.GetString("auth:saml:team-role")
+	groupTeams := make(map[string]string)
+	if rawGroupTeams, _ := config.Get("auth:saml:group-teams"); rawGroupTeams != nil {
+		teamsMap, ok := rawGroupTeams.(map[interface{}]interface{})
+		if !ok {
+			return emptyConfig, errors.New("auth:saml:group-teams must be a map of group names to team names")
+		}
+		for group, team := range teamsMap {
+			groupTeams[fmt.Sprint(group)] = fmt.Sprint(team)
+		}
+	}
 	s.BaseConfig = BaseConfig{
 		EntityID:              entityId,
 		DisplayName:           displayName,
@@ -121,6 +136,9 @@ func (s *SAMLAuthScheme) loadConfig() (BaseConfig, error) {
 		SignRequest:           signRequest,
 		SignedResponse:        signedResponse,
 		DeflatEncodedResponse: deflatEncodedResponse,
+		GroupsAttribute:       groupsAttribute,
+		GroupTeams:            groupTeams,
+		TeamRole:              teamRole,
 	}
 	return s.BaseConfig, nil
 }
@@ -173,6 +191,13 @@ func (s *SAMLAuthScheme) Login(params map[string]string) (auth.Token, error) {
 			return nil, err
 		}
 	}
+	if user.Deactivated {
+		return nil, auth.ErrUserDeactivated
+	}
+	err = s.syncTeams(user, req.Groups)
+	if err != nil {
+		log.Errorf("unable to sync saml groups of user %q: %s", user.Email, err)
+	}
 	token, err := createToken(user)
 	if err != nil {
 		return nil, err
@@ -239,10 +264,49 @@ func (s *SAMLAuthScheme) callback(params map[string]string) error {
 	}
 	req.Authed = true
 	req.Email = email
+	if s.BaseConfig.GroupsAttribute != "" {
+		req.Groups = getUserGroups(response, s.BaseConfig.GroupsAttribute)
+	}
 	req.Update()
 	return nil
 }
 
+// syncTeams grants the configured team role to the user in every team mapped
+// from the groups sent by the identity provider, and removes it from the
+// mapped teams of the groups the user is no longer a member of.
+func (s *SAMLAuthScheme) syncTeams(user *auth.User, groups []string) error {
+	conf := s.BaseConfig
+	if conf.TeamRole == "" || len(conf.GroupTeams) == 0 {
+		return nil
+	}
+	memberTeams := make(map[string]bool)
+	for _, group := range groups {
+		if team, ok := conf.GroupTeams[group]; ok {
+			memberTeams[team] = true
+		}
+	}
+	granted := make(map[string]bool)
+	for _, r := range user.Roles {
+		if r.Name == conf.TeamRole {
+			granted[r.ContextValue] = true
+		}
+	}
+	var err error
+	for _, team := range conf.GroupTeams {
+		switch {
+		case memberTeams[team] && !granted[team]:
+			err = user.AddRole(conf.TeamRole, team)
+		case !memberTeams[team] && granted[team]:
+			err = user.RemoveRole(conf.TeamRole, team)
+		}
+		if err != nil {
+			return err
+		}
+		granted[team] = memberTeams[team]
+	}
+	return nil
+}
+
 func (s *SAMLAuthScheme) AppLogin(appName string) (auth.Token, error) {
 	nativeScheme := native.NativeScheme{}
 	return nativeScheme.AppLogin(appName)
@@ -364,6 +428,11 @@ func (s *SAMLAuthScheme) Create(user *auth.User) (*auth.User, error) {
 	return user, nil
 }
 
+// RevokeUserTokens removes all session tokens of the user.
+func (s *SAMLAuthScheme) RevokeUserTokens(email string) error {
+	return deleteAllTokens(email)
+}
+
 func (s *SAMLAuthScheme) Remove(u *auth.User) error {
 	if err := deleteAllTokens(u.Email); err != nil {
 		return err
@@ -11,7 +11,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/diego-araujo/go-saml"
 	"github.com/tsuru/tsuru/auth"
+	"github.com/tsuru/tsuru/permission"
 	"gopkg.in/check.v1"
 )
 
@@ -486,3 +488,75 @@ func (s *S) TestSamlCreate(c *check.C) {
 	c.Assert(dbUser.Email, check.Equals, user.Email)
 	c.Assert(dbUser.Password, check.Equals, "")
 }
+
+func (s *S) TestSamlGetUserGroups(c *check.C) {
+	response := &saml.Response{}
+	response.Assertion.AttributeStatement.Attributes = []saml.Attribute{
+		{Name: "urn:oid:1.3.6.1.4.1.5923.1.5.1.1", FriendlyName: "memberOf", AttributeValue: saml.AttributeValue{Value: "developers, sre"}},
+		{Name: "memberOf", AttributeValue: saml.AttributeValue{Value: "admins"}},
+		{Name: "mail", AttributeValue: saml.AttributeValue{Value: "me@tsuru.io"}},
+	}
+	groups := getUserGroups(response, "memberOf")
+	c.Assert(groups, check.DeepEquals, []string{"developers", "sre", "admins"})
+	c.Assert(getUserGroups(response, "unknown"), check.HasLen, 0)
+}
+
+func (s *S) TestSamlLoginSyncTeams(c *check.C) {
+	role, err := permission.NewRole("team-member", "team", "")
+	c.Assert(err, check.IsNil)
+	r := request{
+		ID:       "_b533e78c-9c8f-49c6-4dc0-377dd47ed423",
+		Creation: time.Now(),
+		Expires:  time.Now().Add(time.Minute),
+		Email:    "me@tsuru.io",
+		Authed:   true,
+		Groups:   []string{"developers", "unmapped"},
+	}
+	err = s.conn.SAMLRequests().Insert(r)
+	c.Assert(err, check.IsNil)
+	scheme := SAMLAuthScheme{BaseConfig: BaseConfig{
+		EntityID:   "tsuru.myservice.com",
+		GroupTeams: map[string]string{"developers": "dev-team"},
+		TeamRole:   role.Name,
+	}}
+	token, err := scheme.Login(map[string]string{"request_id": r.ID})
+	c.Assert(err, check.IsNil)
+	u, err := token.User()
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Roles, check.DeepEquals, []auth.RoleInstance{{Name: role.Name, ContextValue: "dev-team"}})
+}
+
+func (s *S) TestSamlLoginSyncTeamsRemovesLeftGroups(c *check.C) {
+	role, err := permission.NewRole("team-member", "team", "")
+	c.Assert(err, check.IsNil)
+	user := auth.User{Email: "me@tsuru.io"}
+	err = user.Create()
+	c.Assert(err, check.IsNil)
+	err = user.AddRole(role.Name, "ops")
+	c.Assert(err, check.IsNil)
+	err = user.AddRole(role.Name, "unmapped-team")
+	c.Assert(err, check.IsNil)
+	r := request{
+		ID:       "_b533e78c-9c8f-49c6-4dc0-377dd47ed423",
+		Creation: time.Now(),
+		Expires:  time.Now().Add(time.Minute),
+		Email:    "me@tsuru.io",
+		Authed:   true,
+		Groups:   []string{"developers"},
+	}
+	err = s.conn.SAMLRequests().Insert(r)
+	c.Assert(err, check.IsNil)
+	scheme := SAMLAuthScheme{BaseConfig: BaseConfig{
+		EntityID:   "tsuru.myservice.com",
+		GroupTeams: map[string]string{"developers": "dev-team", "sre": "ops"},
+		TeamRole:   role.Name,
+	}}
+	token, err := scheme.Login(map[string]string{"request_id": r.ID})
+	c.Assert(err, check.IsNil)
+	u, err := token.User()
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Roles, check.DeepEquals, []auth.RoleInstance{
+		{Name: role.Name, ContextValue: "unmapped-team"},
+		{Name: role.Name, ContextValue: "dev-team"},
+	})
+}
@@ -54,6 +54,15 @@ func (t *Token) GetAppName() string {
 	return t.AppName
 }
 
+// GetExpiration returns when the token expires, or the zero time if it never
+// expires.
+func (t *Token) GetExpiration() time.Time {
+	if t.Expires <= 0 {
+		return time.Time{}
+	}
+	return t.Creation.Add(t.Expires)
+}
+
 func (t *Token) Permissions() ([]permission.Permission, error) {
 	return auth.BaseTokenPermission(t)
 }
@@ -27,6 +27,44 @@ type ManagedScheme interface {
 	ChangePassword(token Token, oldPassword string, newPassword string) error
 }
 
+// TokenRefresher is implemented by schemes able to exchange a valid token
+// for a new one, with a renewed expiration. The exchanged token is no longer
+// valid after the refresh.
+type TokenRefresher interface {
+	RefreshToken(t Token) (Token, error)
+}
+
+// UserTokenRevoker is implemented by schemes able to invalidate every token
+// issued to a user, used when the user is deactivated.
+type UserTokenRevoker interface {
+	RevokeUserTokens(email string) error
+}
+
+// EmailVerifier is implemented by schemes that require users to confirm
+// their email addresses, using a token sent by email, before logging in.
+type EmailVerifier interface {
+	VerifyEmail(user *User, token string) error
+	ResendEmailVerification(user *User) error
+}
+
+// TwoFactorScheme is implemented by schemes supporting two-factor
+// authentication with time-based one-time passwords. Enrollment requires the
+// user credentials instead of a token, so users required to use two-factor
+// authentication are able to enroll before logging in.
+type TwoFactorScheme interface {
+	Scheme
+	EnrollTwoFactor(email, password string) (*TwoFactorEnrollment, error)
+	ConfirmTwoFactor(email, password, code string) ([]string, error)
+	DisableTwoFactor(user *User, code string) error
+}
+
+// TwoFactorEnrollment holds the secret generated for a user enrolling in
+// two-factor authentication, along with its otpauth provisioning URI.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
 type AuthenticationFailure struct {
 	Message string
 }
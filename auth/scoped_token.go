@@ -0,0 +1,254 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrScopedTokenNotFound  = errors.New("scoped token not found")
+	ErrScopedTokenNoScopes  = errors.New("at least one permission is required")
+	ErrScopedTokenBadScheme = errors.New("only app permissions are allowed in scoped tokens")
+	ErrScopedTokenTokenPerm = errors.New("app token permissions are not allowed in scoped tokens")
+)
+
+// scopedTokenForbiddenScheme is the permission tree that can't be granted to
+// scoped tokens, otherwise a token could be used to mint other tokens that
+// outlive it. Its ancestors are forbidden as well, as they include it.
+const scopedTokenForbiddenScheme = "app.token"
+
+// ScopedToken is a token bound to a single app and restricted to a list of
+// permissions in it, e.g. only deploying, optionally expiring at a given time.
+// It's meant to be used by CI systems, which shouldn't hold the full-power
+// credentials of a user. Requests made with a scoped token are recorded as
+// made by the user who created it.
+type ScopedToken struct {
+	ID        bson.ObjectId `json:"id" bson:"_id"`
+	Token     string        `json:"token,omitempty"`
+	AppName   string        `json:"app"`
+	Creator   string        `json:"creator"`
+	Scopes    []string      `json:"permissions"`
+	CreatedAt time.Time     `json:"createdAt"`
+	ExpiresAt time.Time     `json:"expiresAt" bson:",omitempty"`
+}
+
+// CreateScopedToken creates a token for the given app, allowing only the
+// given app permissions. A zero expiration creates a token that never
+// expires.
+func CreateScopedToken(creator *User, appName string, schemes []*permission.PermissionScheme, expiration time.Duration) (*ScopedToken, error) {
+	if len(schemes) == 0 {
+		return nil, ErrScopedTokenNoScopes
+	}
+	names := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		name := scheme.FullName()
+		if name != "app" && !strings.HasPrefix(name, "app.") {
+			return nil, ErrScopedTokenBadScheme
+		}
+		if isScopedTokenForbidden(name) {
+			return nil, ErrScopedTokenTokenPerm
+		}
+		names[i] = name
+	}
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+	h := crypto.SHA256.New()
+	h.Write([]byte(appName))
+	h.Write(randomBytes)
+	h.Write([]byte(time.Now().Format(time.RFC3339Nano)))
+	t := ScopedToken{
+		ID:        bson.NewObjectId(),
+		Token:     fmt.Sprintf("%x", h.Sum(nil)),
+		AppName:   appName,
+		Creator:   creator.Email,
+		Scopes:    names,
+		CreatedAt: time.Now().UTC(),
+	}
+	if expiration > 0 {
+		t.ExpiresAt = t.CreatedAt.Add(expiration)
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = conn.ScopedTokens().Insert(t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListScopedTokens returns the scoped tokens of the given app, without their
+// values.
+func ListScopedTokens(appName string) ([]ScopedToken, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var tokens []ScopedToken
+	err = conn.ScopedTokens().Find(bson.M{"appname": appName}).Select(bson.M{"token": 0}).Sort("createdat").All(&tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RemoveScopedToken revokes the scoped token with the given id from the given
+// app.
+func RemoveScopedToken(appName string, id bson.ObjectId) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.ScopedTokens().Remove(bson.M{"_id": id, "appname": appName})
+	if err == mgo.ErrNotFound {
+		return ErrScopedTokenNotFound
+	}
+	return err
+}
+
+// ScopedAuth returns the scoped token matching the given authorization
+// header, if it has not expired.
+func ScopedAuth(header string) (*ScopedToken, error) {
+	value, err := ParseToken(header)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var t ScopedToken
+	err = conn.ScopedTokens().Find(bson.M{"token": value}).One(&t)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if t.Expired() {
+		return nil, ErrInvalidToken
+	}
+	return &t, nil
+}
+
+// Expired reports whether the token has an expiration time in the past.
+func (t *ScopedToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && !time.Now().Before(t.ExpiresAt)
+}
+
+func (t *ScopedToken) GetValue() string {
+	return t.Token
+}
+
+func (t *ScopedToken) User() (*User, error) {
+	return GetUserByEmail(t.Creator)
+}
+
+func (t *ScopedToken) IsAppToken() bool {
+	return false
+}
+
+func (t *ScopedToken) GetUserName() string {
+	return t.Creator
+}
+
+func (t *ScopedToken) GetAppName() string {
+	return t.AppName
+}
+
+// GetExpiration returns when the token expires, or the zero time if it never
+// expires.
+func (t *ScopedToken) GetExpiration() time.Time {
+	return t.ExpiresAt
+}
+
+// Permissions returns the permissions listed in the token, in the context of
+// its app, that its creator still holds in the app. The token stops working
+// if its creator is deactivated or removed, or if its app is removed.
+func (t *ScopedToken) Permissions() ([]permission.Permission, error) {
+	u, err := t.User()
+	if err != nil {
+		return nil, err
+	}
+	if u.Deactivated {
+		return nil, ErrUserDeactivated
+	}
+	userPerms, err := u.Permissions()
+	if err != nil {
+		return nil, err
+	}
+	contexts, err := scopedTokenContexts(t.AppName)
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]permission.Permission, 0, len(t.Scopes))
+	for _, name := range t.Scopes {
+		if isScopedTokenForbidden(name) {
+			continue
+		}
+		scheme, err := permission.SafeGet(name)
+		if err != nil {
+			continue
+		}
+		if !permission.CheckFromPermList(userPerms, scheme, contexts...) {
+			continue
+		}
+		perms = append(perms, permission.Permission{
+			Scheme:  scheme,
+			Context: permission.Context(permission.CtxApp, t.AppName),
+		})
+	}
+	return perms, nil
+}
+
+func isScopedTokenForbidden(name string) bool {
+	return name == scopedTokenForbiddenScheme ||
+		strings.HasPrefix(name, scopedTokenForbiddenScheme+".") ||
+		strings.HasPrefix(scopedTokenForbiddenScheme, name+".")
+}
+
+// scopedTokenContexts returns the contexts in which the permissions of the
+// token creator are checked: the app itself, its teams and its pool.
+func scopedTokenContexts(appName string) ([]permission.PermissionContext, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var a struct {
+		Teams []string
+		Pool  string
+	}
+	err = conn.Apps().Find(bson.M{"name": appName}).Select(bson.M{"teams": 1, "pool": 1}).One(&a)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return append(permission.Contexts(permission.CtxTeam, a.Teams),
+		permission.Context(permission.CtxApp, appName),
+		permission.Context(permission.CtxPool, a.Pool),
+	), nil
+}
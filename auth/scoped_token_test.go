@@ -0,0 +1,123 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) addAppDeployer(c *check.C, appName string) {
+	err := s.conn.Apps().Insert(bson.M{"name": appName, "teams": []string{s.team.Name}})
+	c.Assert(err, check.IsNil)
+	role, err := permission.NewRole("scoped-deployer", "app", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	err = s.user.AddRole(role.Name, appName)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestCreateScopedToken(c *check.C) {
+	s.addAppDeployer(c, "myapp")
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy}
+	t, err := CreateScopedToken(s.user, "myapp", schemes, time.Hour)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Token, check.Not(check.Equals), "")
+	c.Assert(t.AppName, check.Equals, "myapp")
+	c.Assert(t.Creator, check.Equals, s.user.Email)
+	c.Assert(t.Scopes, check.DeepEquals, []string{"app.deploy"})
+	c.Assert(t.ExpiresAt.Sub(t.CreatedAt), check.Equals, time.Hour)
+	dbToken, err := ScopedAuth("bearer " + t.Token)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbToken.ID, check.Equals, t.ID)
+	c.Assert(dbToken.GetUserName(), check.Equals, s.user.Email)
+	c.Assert(dbToken.GetAppName(), check.Equals, "myapp")
+	c.Assert(dbToken.IsAppToken(), check.Equals, false)
+	perms, err := dbToken.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms, check.DeepEquals, []permission.Permission{
+		{Scheme: permission.PermAppDeploy, Context: permission.Context(permission.CtxApp, "myapp")},
+	})
+}
+
+func (s *S) TestCreateScopedTokenInvalidPermissions(c *check.C) {
+	_, err := CreateScopedToken(s.user, "myapp", nil, 0)
+	c.Assert(err, check.Equals, ErrScopedTokenNoScopes)
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy, permission.PermTeamCreate}
+	_, err = CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.Equals, ErrScopedTokenBadScheme)
+	schemes = []*permission.PermissionScheme{permission.PermAppTokenCreate}
+	_, err = CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.Equals, ErrScopedTokenTokenPerm)
+	schemes = []*permission.PermissionScheme{permission.PermApp}
+	_, err = CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.Equals, ErrScopedTokenTokenPerm)
+}
+
+func (s *S) TestScopedTokenPermissionsIntersectWithCreator(c *check.C) {
+	s.addAppDeployer(c, "myapp")
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy, permission.PermAppUpdateEnvSet}
+	t, err := CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.IsNil)
+	perms, err := t.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms, check.DeepEquals, []permission.Permission{
+		{Scheme: permission.PermAppDeploy, Context: permission.Context(permission.CtxApp, "myapp")},
+	})
+	err = s.user.RemoveRole("scoped-deployer", "myapp")
+	c.Assert(err, check.IsNil)
+	perms, err = t.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms, check.HasLen, 0)
+}
+
+func (s *S) TestScopedTokenPermissionsAppRemoved(c *check.C) {
+	s.addAppDeployer(c, "myapp")
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy}
+	t, err := CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.IsNil)
+	err = s.conn.Apps().Remove(bson.M{"name": "myapp"})
+	c.Assert(err, check.IsNil)
+	_, err = t.Permissions()
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestScopedAuthExpired(c *check.C) {
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy}
+	t, err := CreateScopedToken(s.user, "myapp", schemes, time.Nanosecond)
+	c.Assert(err, check.IsNil)
+	time.Sleep(time.Millisecond)
+	_, err = ScopedAuth("bearer " + t.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestScopedAuthNotFound(c *check.C) {
+	_, err := ScopedAuth("bearer invalid")
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
+
+func (s *S) TestListAndRemoveScopedTokens(c *check.C) {
+	schemes := []*permission.PermissionScheme{permission.PermAppDeploy}
+	t1, err := CreateScopedToken(s.user, "myapp", schemes, 0)
+	c.Assert(err, check.IsNil)
+	_, err = CreateScopedToken(s.user, "otherapp", schemes, 0)
+	c.Assert(err, check.IsNil)
+	tokens, err := ListScopedTokens("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(tokens, check.HasLen, 1)
+	c.Assert(tokens[0].ID, check.Equals, t1.ID)
+	c.Assert(tokens[0].Token, check.Equals, "")
+	c.Assert(tokens[0].ExpiresAt.IsZero(), check.Equals, true)
+	err = RemoveScopedToken("otherapp", t1.ID)
+	c.Assert(err, check.Equals, ErrScopedTokenNotFound)
+	err = RemoveScopedToken("myapp", t1.ID)
+	c.Assert(err, check.IsNil)
+	_, err = ScopedAuth("bearer " + t1.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
@@ -0,0 +1,223 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrServiceAccountNotFound      = errors.New("service account not found")
+	ErrServiceAccountAlreadyExists = errors.New("service account already exists")
+	ErrInvalidServiceAccountName   = errors.New("invalid service account name")
+	ErrServiceAccountInvalidRole   = errors.New("service accounts may only be granted roles with team context")
+	ErrServiceAccountNotUser       = errors.New("service accounts are not allowed to perform actions on behalf of a user")
+
+	serviceAccountNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+)
+
+// ServiceAccount is a non-human principal owned by a team, meant to be used
+// by CI systems and scripts. It has its own token, independent of any user
+// credentials, and the permissions of a single role in the context of its
+// team.
+type ServiceAccount struct {
+	ID          bson.ObjectId `json:"id" bson:"_id"`
+	Name        string        `json:"name"`
+	Team        string        `json:"team"`
+	Description string        `json:"description"`
+	Role        string        `json:"role"`
+	Token       string        `json:"token,omitempty"`
+	Creator     string        `json:"creator"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	RotatedAt   time.Time     `json:"rotatedAt"`
+}
+
+func newServiceAccountToken(team, name string) (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	h := crypto.SHA256.New()
+	h.Write([]byte(team + "/" + name))
+	h.Write(randomBytes)
+	h.Write([]byte(time.Now().Format(time.RFC3339Nano)))
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// CreateServiceAccount creates a service account in the given team, granted
+// the given role in the team context. The returned account holds its token,
+// which isn't returned by ListServiceAccounts.
+func CreateServiceAccount(creator *User, team, name, description, roleName string) (*ServiceAccount, error) {
+	if !serviceAccountNameRegexp.MatchString(name) {
+		return nil, ErrInvalidServiceAccountName
+	}
+	role, err := permission.FindRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role.ContextType != permission.CtxTeam {
+		return nil, ErrServiceAccountInvalidRole
+	}
+	_, err = GetTeam(team)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newServiceAccountToken(team, name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	account := ServiceAccount{
+		ID:          bson.NewObjectId(),
+		Name:        name,
+		Team:        team,
+		Description: description,
+		Role:        role.Name,
+		Token:       token,
+		Creator:     creator.Email,
+		CreatedAt:   now,
+		RotatedAt:   now,
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = conn.ServiceAccounts().Insert(account)
+	if mgo.IsDup(err) {
+		return nil, ErrServiceAccountAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListServiceAccounts returns the service accounts of the given team, without
+// their tokens.
+func ListServiceAccounts(team string) ([]ServiceAccount, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var accounts []ServiceAccount
+	err = conn.ServiceAccounts().Find(bson.M{"team": team}).Select(bson.M{"token": 0}).Sort("name").All(&accounts)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// RotateServiceAccountToken replaces the token of the service account,
+// immediately revoking the previous one.
+func RotateServiceAccountToken(team, name string) (*ServiceAccount, error) {
+	token, err := newServiceAccountToken(team, name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var account ServiceAccount
+	_, err = conn.ServiceAccounts().Find(bson.M{"team": team, "name": name}).Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"token": token, "rotatedat": time.Now().UTC()}},
+		ReturnNew: true,
+	}, &account)
+	if err == mgo.ErrNotFound {
+		return nil, ErrServiceAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// RemoveServiceAccount removes the service account, revoking its token.
+func RemoveServiceAccount(team, name string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.ServiceAccounts().Remove(bson.M{"team": team, "name": name})
+	if err == mgo.ErrNotFound {
+		return ErrServiceAccountNotFound
+	}
+	return err
+}
+
+// ServiceAccountAuth returns the service account matching the given
+// authorization header.
+func ServiceAccountAuth(header string) (*ServiceAccount, error) {
+	value, err := ParseToken(header)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var account ServiceAccount
+	err = conn.ServiceAccounts().Find(bson.M{"token": value}).One(&account)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (s *ServiceAccount) GetValue() string {
+	return s.Token
+}
+
+// User always returns ErrServiceAccountNotUser, as service accounts are not
+// backed by a user.
+func (s *ServiceAccount) User() (*User, error) {
+	return nil, ErrServiceAccountNotUser
+}
+
+func (s *ServiceAccount) IsAppToken() bool {
+	return false
+}
+
+// GetUserName returns the name identifying the service account in events and
+// logs.
+func (s *ServiceAccount) GetUserName() string {
+	return fmt.Sprintf("serviceaccount:%s/%s", s.Team, s.Name)
+}
+
+func (s *ServiceAccount) GetAppName() string {
+	return ""
+}
+
+// Permissions returns the permissions of the account role in the context of
+// its team and of its sub-teams.
+func (s *ServiceAccount) Permissions() ([]permission.Permission, error) {
+	role, err := permission.FindRole(s.Role)
+	if err != nil {
+		if err == permission.ErrRoleNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cascadeTeamPermissions(role.PermissionsFor(s.Team))
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCreateServiceAccount(c *check.C) {
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	account, err := CreateServiceAccount(s.user, s.team.Name, "ci", "continuous integration", "deployer")
+	c.Assert(err, check.IsNil)
+	c.Assert(account.Token, check.Not(check.Equals), "")
+	c.Assert(account.Team, check.Equals, s.team.Name)
+	c.Assert(account.Creator, check.Equals, s.user.Email)
+	dbAccount, err := ServiceAccountAuth("bearer " + account.Token)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbAccount.ID, check.Equals, account.ID)
+	c.Assert(dbAccount.GetUserName(), check.Equals, "serviceaccount:cobrateam/ci")
+	c.Assert(dbAccount.IsAppToken(), check.Equals, false)
+	_, err = dbAccount.User()
+	c.Assert(err, check.Equals, ErrServiceAccountNotUser)
+	perms, err := dbAccount.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms, check.DeepEquals, []permission.Permission{
+		{Scheme: permission.PermAppDeploy, Context: permission.Context(permission.CtxTeam, s.team.Name)},
+	})
+}
+
+func (s *S) TestCreateServiceAccountInvalid(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("appdeployer", "app", "")
+	c.Assert(err, check.IsNil)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "Invalid Name", "", "deployer")
+	c.Assert(err, check.Equals, ErrInvalidServiceAccountName)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "ci", "", "unknown")
+	c.Assert(err, check.Equals, permission.ErrRoleNotFound)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "ci", "", "appdeployer")
+	c.Assert(err, check.Equals, ErrServiceAccountInvalidRole)
+	_, err = CreateServiceAccount(s.user, "unknownteam", "ci", "", "deployer")
+	c.Assert(err, check.Equals, ErrTeamNotFound)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.Equals, ErrServiceAccountAlreadyExists)
+}
+
+func (s *S) TestRotateServiceAccountToken(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	account, err := CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	rotated, err := RotateServiceAccountToken(s.team.Name, "ci")
+	c.Assert(err, check.IsNil)
+	c.Assert(rotated.Token, check.Not(check.Equals), account.Token)
+	_, err = ServiceAccountAuth("bearer " + account.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	dbAccount, err := ServiceAccountAuth("bearer " + rotated.Token)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbAccount.ID, check.Equals, account.ID)
+	_, err = RotateServiceAccountToken(s.team.Name, "unknown")
+	c.Assert(err, check.Equals, ErrServiceAccountNotFound)
+}
+
+func (s *S) TestListAndRemoveServiceAccounts(c *check.C) {
+	_, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	account, err := CreateServiceAccount(s.user, s.team.Name, "ci", "", "deployer")
+	c.Assert(err, check.IsNil)
+	_, err = CreateServiceAccount(s.user, s.team.Name, "backup", "", "deployer")
+	c.Assert(err, check.IsNil)
+	accounts, err := ListServiceAccounts(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(accounts, check.HasLen, 2)
+	c.Assert(accounts[0].Name, check.Equals, "backup")
+	c.Assert(accounts[1].Name, check.Equals, "ci")
+	c.Assert(accounts[1].Token, check.Equals, "")
+	err = RemoveServiceAccount(s.team.Name, "ci")
+	c.Assert(err, check.IsNil)
+	_, err = ServiceAccountAuth("bearer " + account.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	err = RemoveServiceAccount(s.team.Name, "ci")
+	c.Assert(err, check.Equals, ErrServiceAccountNotFound)
+}
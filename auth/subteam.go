@@ -0,0 +1,177 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrTeamParentNotFound = errors.New("parent team not found")
+	ErrTeamParentCycle    = errors.New("a team cannot be a sub-team of itself or of its own sub-teams")
+)
+
+// teamTree maps the name of each sub-team to the name of its parent. Root
+// teams are not part of the tree.
+type teamTree map[string]string
+
+func loadTeamTree() (teamTree, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var teams []Team
+	query := bson.M{"parent": bson.M{"$nin": []interface{}{nil, ""}}}
+	err = conn.Teams().Find(query).Select(bson.M{"_id": 1, "parent": 1}).All(&teams)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(teamTree, len(teams))
+	for _, t := range teams {
+		tree[t.Name] = t.Parent
+	}
+	return tree, nil
+}
+
+// ancestors returns the parent of the team, the parent of its parent and so
+// on, up to the root team.
+func (tree teamTree) ancestors(name string) []string {
+	var result []string
+	seen := map[string]bool{name: true}
+	for parent := tree[name]; parent != "" && !seen[parent]; parent = tree[parent] {
+		seen[parent] = true
+		result = append(result, parent)
+	}
+	return result
+}
+
+// descendants returns the sub-teams of the team, at any depth.
+func (tree teamTree) descendants(name string) []string {
+	children := make(map[string][]string)
+	for team, parent := range tree {
+		if parent != "" {
+			children[parent] = append(children[parent], team)
+		}
+	}
+	var result []string
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range children[current] {
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			result = append(result, child)
+			queue = append(queue, child)
+		}
+	}
+	return result
+}
+
+// SetTeamParent makes the team a sub-team of parent, so that permissions and
+// quotas of the parent team also apply to it. An empty parent turns the team
+// into a root team.
+func SetTeamParent(name, parent string) error {
+	_, err := GetTeam(name)
+	if err != nil {
+		return err
+	}
+	if parent != "" {
+		_, err = GetTeam(parent)
+		if err == ErrTeamNotFound {
+			return ErrTeamParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if parent == name {
+			return ErrTeamParentCycle
+		}
+		tree, err := loadTeamTree()
+		if err != nil {
+			return err
+		}
+		for _, ancestor := range tree.ancestors(parent) {
+			if ancestor == name {
+				return ErrTeamParentCycle
+			}
+		}
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Teams().UpdateId(name, bson.M{"$set": bson.M{"parent": parent}})
+	if err == mgo.ErrNotFound {
+		return ErrTeamNotFound
+	}
+	return err
+}
+
+// SubTeams returns the names of the sub-teams of the team, at any depth.
+func SubTeams(name string) ([]string, error) {
+	tree, err := loadTeamTree()
+	if err != nil {
+		return nil, err
+	}
+	return tree.descendants(name), nil
+}
+
+// TeamAncestors returns the names of the parent teams of the team, starting
+// with its direct parent.
+func TeamAncestors(name string) ([]string, error) {
+	tree, err := loadTeamTree()
+	if err != nil {
+		return nil, err
+	}
+	return tree.ancestors(name), nil
+}
+
+// cascadeTeamPermissions copies the permissions granted in the context of a
+// team to the contexts of all its sub-teams.
+func cascadeTeamPermissions(perms []permission.Permission) ([]permission.Permission, error) {
+	hasTeamCtx := false
+	for _, p := range perms {
+		if p.Context.CtxType == permission.CtxTeam {
+			hasTeamCtx = true
+			break
+		}
+	}
+	if !hasTeamCtx {
+		return perms, nil
+	}
+	tree, err := loadTeamTree()
+	if err != nil {
+		return nil, err
+	}
+	result := perms
+	subTeams := make(map[string][]string)
+	for _, p := range perms {
+		if p.Context.CtxType != permission.CtxTeam {
+			continue
+		}
+		teams, ok := subTeams[p.Context.Value]
+		if !ok {
+			teams = tree.descendants(p.Context.Value)
+			subTeams[p.Context.Value] = teams
+		}
+		for _, subTeam := range teams {
+			result = append(result, permission.Permission{
+				Scheme:  p.Scheme,
+				Context: permission.Context(permission.CtxTeam, subTeam),
+			})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"sort"
+
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) insertTeams(c *check.C, teams ...Team) {
+	for _, t := range teams {
+		err := s.conn.Teams().Insert(t)
+		c.Assert(err, check.IsNil)
+	}
+}
+
+func (s *S) TestSetTeamParent(c *check.C) {
+	s.insertTeams(c, Team{Name: "engineering"})
+	err := SetTeamParent(s.team.Name, "engineering")
+	c.Assert(err, check.IsNil)
+	team, err := GetTeam(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, "engineering")
+	err = SetTeamParent(s.team.Name, "")
+	c.Assert(err, check.IsNil)
+	team, err = GetTeam(s.team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, "")
+}
+
+func (s *S) TestSetTeamParentNotFound(c *check.C) {
+	err := SetTeamParent("unknown", s.team.Name)
+	c.Assert(err, check.Equals, ErrTeamNotFound)
+	err = SetTeamParent(s.team.Name, "unknown")
+	c.Assert(err, check.Equals, ErrTeamParentNotFound)
+}
+
+func (s *S) TestSetTeamParentCycle(c *check.C) {
+	s.insertTeams(c,
+		Team{Name: "engineering"},
+		Team{Name: "platform", Parent: "engineering"},
+		Team{Name: "runtime", Parent: "platform"},
+	)
+	err := SetTeamParent("engineering", "engineering")
+	c.Assert(err, check.Equals, ErrTeamParentCycle)
+	err = SetTeamParent("engineering", "runtime")
+	c.Assert(err, check.Equals, ErrTeamParentCycle)
+	team, err := GetTeam("engineering")
+	c.Assert(err, check.IsNil)
+	c.Assert(team.Parent, check.Equals, "")
+}
+
+func (s *S) TestSubTeamsAndAncestors(c *check.C) {
+	s.insertTeams(c,
+		Team{Name: "engineering"},
+		Team{Name: "platform", Parent: "engineering"},
+		Team{Name: "runtime", Parent: "platform"},
+		Team{Name: "frontend", Parent: "engineering"},
+	)
+	subTeams, err := SubTeams("engineering")
+	c.Assert(err, check.IsNil)
+	sort.Strings(subTeams)
+	c.Assert(subTeams, check.DeepEquals, []string{"frontend", "platform", "runtime"})
+	subTeams, err = SubTeams("runtime")
+	c.Assert(err, check.IsNil)
+	c.Assert(subTeams, check.HasLen, 0)
+	ancestors, err := TeamAncestors("runtime")
+	c.Assert(err, check.IsNil)
+	c.Assert(ancestors, check.DeepEquals, []string{"platform", "engineering"})
+	ancestors, err = TeamAncestors("engineering")
+	c.Assert(err, check.IsNil)
+	c.Assert(ancestors, check.HasLen, 0)
+}
+
+func (s *S) TestUserPermissionsCascadeToSubTeams(c *check.C) {
+	s.insertTeams(c,
+		Team{Name: "engineering"},
+		Team{Name: "platform", Parent: "engineering"},
+	)
+	role, err := permission.NewRole("deployer", "team", "")
+	c.Assert(err, check.IsNil)
+	err = role.AddPermissions("app.deploy")
+	c.Assert(err, check.IsNil)
+	u := User{Email: "me@tsuru.com", Password: "123"}
+	err = u.Create()
+	c.Assert(err, check.IsNil)
+	err = u.AddRole(role.Name, "engineering")
+	c.Assert(err, check.IsNil)
+	perms, err := u.Permissions()
+	c.Assert(err, check.IsNil)
+	c.Assert(perms, check.DeepEquals, []permission.Permission{
+		{Scheme: permission.PermUser, Context: permission.Context(permission.CtxUser, u.Email)},
+		{Scheme: permission.PermAppDeploy, Context: permission.Context(permission.CtxTeam, "engineering")},
+		{Scheme: permission.PermAppDeploy, Context: permission.Context(permission.CtxTeam, "platform")},
+	})
+}
+
+func (s *S) TestRemoveTeamWithSubTeams(c *check.C) {
+	s.insertTeams(c, Team{Name: "platform", Parent: s.team.Name})
+	err := RemoveTeam(s.team.Name)
+	c.Assert(err, check.ErrorMatches, "Sub-teams: platform")
+}
@@ -51,6 +51,8 @@ func (s *S) TearDownSuite(c *check.C) {
 }
 
 func (s *S) SetUpTest(c *check.C) {
+	pendingTokenUsage.usage = make(map[string]TokenUsage)
+	revocationCache.hashes = nil
 	err := dbtest.ClearAllCollections(s.conn.Apps().Database)
 	c.Assert(err, check.IsNil)
 	s.user = &User{Email: "timeredbull@globo.com", Password: "123456"}
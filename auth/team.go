@@ -28,19 +28,36 @@ var (
 type ErrTeamStillUsed struct {
 	Apps             []string
 	ServiceInstances []string
+	SubTeams         []string
 }
 
 func (e *ErrTeamStillUsed) Error() string {
 	if len(e.Apps) > 0 {
 		return fmt.Sprintf("Apps: %s", strings.Join(e.Apps, ", "))
 	}
+	if len(e.SubTeams) > 0 {
+		return fmt.Sprintf("Sub-teams: %s", strings.Join(e.SubTeams, ", "))
+	}
 	return fmt.Sprintf("Service instances: %s", strings.Join(e.ServiceInstances, ", "))
 }
 
 // Team represents a real world team, a team has one creating user and a name.
+// Teams may be nested under a parent team, inheriting the permissions and
+// quotas defined for it.
 type Team struct {
 	Name         string `bson:"_id" json:"name"`
 	CreatingUser string
+	Quota        TeamQuota `json:"quota"`
+	Parent       string    `json:"parent,omitempty"`
+}
+
+// TeamQuota holds the limits of resources used by the apps owned by a team,
+// checked in addition to the user and app quotas. Memory is in bytes. Zero or
+// negative limits mean there's no limit.
+type TeamQuota struct {
+	Apps   int   `json:"apps"`
+	Units  int   `json:"units"`
+	Memory int64 `json:"memory"`
 }
 
 // AllowedApps returns the apps that the team has access.
@@ -116,6 +133,20 @@ func GetTeam(name string) (*Team, error) {
 	return &t, nil
 }
 
+// ChangeTeamQuota redefines the resource limits of the team.
+func ChangeTeamQuota(name string, q TeamQuota) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Teams().UpdateId(name, bson.M{"$set": bson.M{"quota": q}})
+	if err == mgo.ErrNotFound {
+		return ErrTeamNotFound
+	}
+	return err
+}
+
 // GetTeamsNames find teams by a list of team names.
 func GetTeamsNames(teams []Team) []string {
 	tn := make([]string, len(teams))
@@ -147,6 +178,14 @@ func RemoveTeam(teamName string) error {
 	if len(serviceInstances) > 0 {
 		return &ErrTeamStillUsed{ServiceInstances: serviceInstances}
 	}
+	var subTeams []string
+	err = conn.Teams().Find(bson.M{"parent": teamName}).Distinct("_id", &subTeams)
+	if err != nil {
+		return err
+	}
+	if len(subTeams) > 0 {
+		return &ErrTeamStillUsed{SubTeams: subTeams}
+	}
 	err = conn.Teams().RemoveId(teamName)
 	if err == mgo.ErrNotFound {
 		return ErrTeamNotFound
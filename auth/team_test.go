@@ -105,6 +105,23 @@ func (s *S) TestGetTeam(c *check.C) {
 	c.Assert(t, check.IsNil)
 }
 
+func (s *S) TestChangeTeamQuota(c *check.C) {
+	team := Team{Name: "symfonia"}
+	err := s.conn.Teams().Insert(team)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Teams().RemoveId(team.Name)
+	err = ChangeTeamQuota(team.Name, TeamQuota{Apps: 2, Units: 10, Memory: 1024})
+	c.Assert(err, check.IsNil)
+	t, err := GetTeam(team.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Quota, check.DeepEquals, TeamQuota{Apps: 2, Units: 10, Memory: 1024})
+}
+
+func (s *S) TestChangeTeamQuotaTeamNotFound(c *check.C) {
+	err := ChangeTeamQuota("wat", TeamQuota{Apps: 2})
+	c.Assert(err, check.Equals, ErrTeamNotFound)
+}
+
 func (s *S) TestRemoveTeam(c *check.C) {
 	team := Team{Name: "atreides"}
 	err := s.conn.Teams().Insert(team)
@@ -6,6 +6,7 @@ package auth
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/permission"
@@ -20,6 +21,12 @@ type Token interface {
 	Permissions() ([]permission.Permission, error)
 }
 
+// ExpirableToken is implemented by tokens that expire. A zero expiration means
+// that the token never expires.
+type ExpirableToken interface {
+	GetExpiration() time.Time
+}
+
 var ErrInvalidToken = errors.New("Invalid token")
 
 // ParseToken extracts token from a header:
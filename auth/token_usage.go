@@ -0,0 +1,340 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/api/shutdown"
+	"github.com/tsuru/tsuru/cron"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	tokenUsageFlushInterval = 10 * time.Second
+	maxPendingTokenUsage    = 10000
+)
+
+// TokenUsage holds information about the last time a token was used to
+// authenticate a request. Tokens are identified by their hash, as in the
+// revocation list. The ID may be used to revoke the token.
+type TokenUsage struct {
+	ID            string    `json:"id" bson:"_id"`
+	Kind          string    `json:"kind"`
+	User          string    `json:"user"`
+	App           string    `json:"app,omitempty" bson:",omitempty"`
+	LastUsedAt    time.Time `json:"lastUsedAt"`
+	LastIP        string    `json:"lastIP"`
+	LastUserAgent string    `json:"lastUserAgent"`
+	Revoked       bool      `json:"revoked" bson:"-"`
+}
+
+// pendingTokenUsage holds the usage recorded since the last flush, by token
+// hash, so the API doesn't write to the database on every request.
+var pendingTokenUsage = struct {
+	sync.Mutex
+	usage map[string]TokenUsage
+}{usage: make(map[string]TokenUsage)}
+
+func tokenKind(t Token) string {
+	switch t.(type) {
+	case *APIToken:
+		return "api-key"
+	case *ScopedToken:
+		return "scoped"
+	case *ServiceAccount:
+		return "service-account"
+	case *ImpersonationToken:
+		return "impersonation"
+	}
+	if t.IsAppToken() {
+		return "app"
+	}
+	return "session"
+}
+
+// RecordTokenUsage records the time, the source IP and the user agent of the
+// last request authenticated with the given token. The usage is kept in
+// memory and saved by FlushTokenUsage, so it's safe to call it on every
+// request.
+func RecordTokenUsage(t Token, ip, userAgent string) {
+	hash := hashToken(t.GetValue())
+	pendingTokenUsage.Lock()
+	defer pendingTokenUsage.Unlock()
+	if _, ok := pendingTokenUsage.usage[hash]; !ok && len(pendingTokenUsage.usage) >= maxPendingTokenUsage {
+		return
+	}
+	pendingTokenUsage.usage[hash] = TokenUsage{
+		ID:            hash,
+		Kind:          tokenKind(t),
+		User:          t.GetUserName(),
+		App:           t.GetAppName(),
+		LastUsedAt:    time.Now().UTC(),
+		LastIP:        ip,
+		LastUserAgent: userAgent,
+	}
+}
+
+// FlushTokenUsage saves the usage recorded since the last flush in a single
+// bulk write.
+func FlushTokenUsage() error {
+	pendingTokenUsage.Lock()
+	usage := pendingTokenUsage.usage
+	pendingTokenUsage.usage = make(map[string]TokenUsage)
+	pendingTokenUsage.Unlock()
+	if len(usage) == 0 {
+		return nil
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bulk := conn.TokenUsage().Bulk()
+	bulk.Unordered()
+	for hash, u := range usage {
+		bulk.Upsert(bson.M{"_id": hash}, u)
+	}
+	_, err = bulk.Run()
+	return err
+}
+
+type tokenUsageRecorder struct {
+	runner *cron.Runner
+}
+
+// StartTokenUsageRecorder starts saving the recorded token usage in
+// background.
+func StartTokenUsageRecorder() {
+	r := cron.NewRunner("token usage recorder", tokenUsageFlushInterval)
+	r.Start(func(time.Time) {
+		if err := FlushTokenUsage(); err != nil {
+			log.Errorf("unable to save token usage: %s", err)
+		}
+	})
+	shutdown.Register(&tokenUsageRecorder{runner: r})
+}
+
+func (r *tokenUsageRecorder) Shutdown() {
+	r.runner.Shutdown()
+	if err := FlushTokenUsage(); err != nil {
+		log.Errorf("unable to save token usage: %s", err)
+	}
+}
+
+func (r *tokenUsageRecorder) String() string {
+	return r.runner.String()
+}
+
+// ListTokenUsage returns the usage information of the tokens of the given
+// user, most recently used first.
+func ListTokenUsage(email string) ([]TokenUsage, error) {
+	err := FlushTokenUsage()
+	if err != nil {
+		return nil, err
+	}
+	return findTokenUsage(bson.M{"user": email})
+}
+
+// UnusedTokens returns the valid tokens that were not used since the given
+// time, including the ones that were never used, least recently used first.
+// Revoked and expired tokens are not included.
+func UnusedTokens(since time.Time) ([]TokenUsage, error) {
+	err := FlushTokenUsage()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := activeTokens()
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	hashes := make([]string, len(tokens))
+	for i, t := range tokens {
+		hashes[i] = t.usage.ID
+	}
+	usage, err := findTokenUsage(bson.M{"_id": bson.M{"$in": hashes}})
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := revokedHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	usageByHash := make(map[string]TokenUsage, len(usage))
+	for _, u := range usage {
+		usageByHash[u.ID] = u
+	}
+	result := make([]TokenUsage, 0, len(tokens))
+	for _, t := range tokens {
+		u, ok := usageByHash[t.usage.ID]
+		if !ok {
+			u = t.usage
+		}
+		if revoked[u.ID] || !u.LastUsedAt.Before(since) {
+			continue
+		}
+		result = append(result, u)
+	}
+	sort.Stable(tokenUsageByLastUse(result))
+	return result, nil
+}
+
+type tokenUsageByLastUse []TokenUsage
+
+func (l tokenUsageByLastUse) Len() int           { return len(l) }
+func (l tokenUsageByLastUse) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l tokenUsageByLastUse) Less(i, j int) bool { return l[i].LastUsedAt.Before(l[j].LastUsedAt) }
+
+// TokenValueByID returns the token with the given ID, as reported in the
+// token usage, or ErrInvalidToken if there's no valid token with this ID.
+func TokenValueByID(id string) (string, error) {
+	tokens, err := activeTokens()
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tokens {
+		if t.usage.ID == id {
+			return t.value, nil
+		}
+	}
+	return "", ErrInvalidToken
+}
+
+type activeToken struct {
+	value string
+	usage TokenUsage
+}
+
+// activeTokens lists the tokens that are still valid, from all the
+// collections holding tokens, with their usage information filled only with
+// their kind and owner.
+func activeTokens() ([]activeToken, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	now := time.Now().UTC()
+	var result []activeToken
+	add := func(value, kind, user, app string) {
+		if value == "" {
+			return
+		}
+		result = append(result, activeToken{
+			value: value,
+			usage: TokenUsage{ID: hashToken(value), Kind: kind, User: user, App: app},
+		})
+	}
+	var sessions []struct {
+		Token     string
+		Creation  time.Time
+		Expires   time.Duration
+		UserEmail string
+		AppName   string
+	}
+	err = conn.Tokens().Find(nil).All(&sessions)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range sessions {
+		if t.Expires > 0 && !t.Creation.Add(t.Expires).After(now) {
+			continue
+		}
+		kind := "session"
+		if t.AppName != "" {
+			kind = "app"
+		}
+		add(t.Token, kind, t.UserEmail, t.AppName)
+	}
+	var users []User
+	err = conn.Users().Find(bson.M{"apikey": bson.M{"$nin": []interface{}{"", nil}}}).All(&users)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		add(u.APIKey, "api-key", u.Email, "")
+	}
+	var scoped []ScopedToken
+	err = conn.ScopedTokens().Find(nil).All(&scoped)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range scoped {
+		if !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now) {
+			continue
+		}
+		add(t.Token, "scoped", t.Creator, t.AppName)
+	}
+	var accounts []ServiceAccount
+	err = conn.ServiceAccounts().Find(nil).All(&accounts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		add(accounts[i].Token, "service-account", accounts[i].GetUserName(), "")
+	}
+	var impersonations []ImpersonationToken
+	err = conn.ImpersonationTokens().Find(bson.M{"expiresat": bson.M{"$gt": now}}).All(&impersonations)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range impersonations {
+		add(t.Token, "impersonation", t.UserEmail, "")
+	}
+	return result, nil
+}
+
+func findTokenUsage(query bson.M) ([]TokenUsage, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var usage []TokenUsage
+	err = conn.TokenUsage().Find(query).Sort("-lastusedat").All(&usage)
+	if err != nil {
+		return nil, err
+	}
+	if len(usage) == 0 {
+		return usage, nil
+	}
+	hashes := make([]string, len(usage))
+	for i, u := range usage {
+		hashes[i] = u.ID
+	}
+	revoked, err := revokedHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range usage {
+		usage[i].Revoked = revoked[usage[i].ID]
+	}
+	return usage, nil
+}
+
+func revokedHashes(hashes []string) (map[string]bool, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var revoked []revokedToken
+	err = conn.RevokedTokens().Find(bson.M{"_id": bson.M{"$in": hashes}}).All(&revoked)
+	if err != nil {
+		return nil, err
+	}
+	revokedSet := make(map[string]bool, len(revoked))
+	for _, r := range revoked {
+		revokedSet[r.Hash] = true
+	}
+	return revokedSet, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *S) TestRecordTokenUsage(c *check.C) {
+	t := &APIToken{Token: "abc123", UserEmail: s.user.Email}
+	RecordTokenUsage(t, "10.0.0.1", "tsuru-client/1.1")
+	RecordTokenUsage(t, "10.0.0.2", "curl/7.50")
+	n, err := s.conn.TokenUsage().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+	usage, err := ListTokenUsage(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.HasLen, 1)
+	c.Assert(usage[0].ID, check.Equals, hashToken("abc123"))
+	c.Assert(usage[0].Kind, check.Equals, "api-key")
+	c.Assert(usage[0].User, check.Equals, s.user.Email)
+	c.Assert(usage[0].LastIP, check.Equals, "10.0.0.2")
+	c.Assert(usage[0].LastUserAgent, check.Equals, "curl/7.50")
+	c.Assert(usage[0].Revoked, check.Equals, false)
+	c.Assert(time.Since(usage[0].LastUsedAt) < time.Minute, check.Equals, true)
+}
+
+func (s *S) TestFlushTokenUsage(c *check.C) {
+	RecordTokenUsage(&APIToken{Token: "abc123", UserEmail: s.user.Email}, "10.0.0.1", "tsuru-client/1.1")
+	RecordTokenUsage(&APIToken{Token: "def456", UserEmail: s.user.Email}, "10.0.0.1", "tsuru-client/1.1")
+	err := FlushTokenUsage()
+	c.Assert(err, check.IsNil)
+	n, err := s.conn.TokenUsage().Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 2)
+	err = FlushTokenUsage()
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestListTokenUsageRevoked(c *check.C) {
+	t1 := &APIToken{Token: "abc123", UserEmail: s.user.Email}
+	t2 := &ScopedToken{Token: "def456", Creator: s.user.Email, AppName: "myapp"}
+	other := &APIToken{Token: "ghi789", UserEmail: "other@tsuru.io"}
+	for _, t := range []Token{t1, t2, other} {
+		RecordTokenUsage(t, "10.0.0.1", "tsuru-client/1.1")
+	}
+	err := RevokeToken(t1)
+	c.Assert(err, check.IsNil)
+	usage, err := ListTokenUsage(s.user.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.HasLen, 2)
+	byKind := map[string]TokenUsage{}
+	for _, u := range usage {
+		byKind[u.Kind] = u
+	}
+	c.Assert(byKind["api-key"].Revoked, check.Equals, true)
+	c.Assert(byKind["scoped"].Revoked, check.Equals, false)
+	c.Assert(byKind["scoped"].App, check.Equals, "myapp")
+}
+
+func (s *S) TestUnusedTokens(c *check.C) {
+	now := time.Now().UTC()
+	users := []User{
+		{Email: "recent@tsuru.io", APIKey: "recent"},
+		{Email: "old@tsuru.io", APIKey: "old"},
+		{Email: "older@tsuru.io", APIKey: "older"},
+		{Email: "revoked@tsuru.io", APIKey: "revoked"},
+	}
+	for i := range users {
+		err := s.conn.Users().Insert(users[i])
+		c.Assert(err, check.IsNil)
+		RecordTokenUsage(&APIToken{Token: users[i].APIKey, UserEmail: users[i].Email}, "10.0.0.1", "tsuru-client/1.1")
+	}
+	err := s.conn.Tokens().Insert(
+		bson.M{"token": "never-used", "creation": now, "expires": time.Hour, "useremail": s.user.Email},
+		bson.M{"token": "expired", "creation": now.Add(-2 * time.Hour), "expires": time.Hour, "useremail": s.user.Email},
+	)
+	c.Assert(err, check.IsNil)
+	RecordTokenUsage(&APIToken{Token: "logged-out", UserEmail: s.user.Email}, "10.0.0.1", "tsuru-client/1.1")
+	err = FlushTokenUsage()
+	c.Assert(err, check.IsNil)
+	lastUsed := map[string]time.Time{
+		"old":        now.AddDate(0, 0, -40),
+		"older":      now.AddDate(0, 0, -60),
+		"revoked":    now.AddDate(0, 0, -90),
+		"logged-out": now.AddDate(0, 0, -90),
+	}
+	for value, date := range lastUsed {
+		err = s.conn.TokenUsage().UpdateId(hashToken(value), bson.M{"$set": bson.M{"lastusedat": date}})
+		c.Assert(err, check.IsNil)
+	}
+	err = RevokeToken(&APIToken{Token: "revoked"})
+	c.Assert(err, check.IsNil)
+	usage, err := UnusedTokens(now.AddDate(0, 0, -30))
+	c.Assert(err, check.IsNil)
+	c.Assert(usage, check.HasLen, 3)
+	c.Assert(usage[0].ID, check.Equals, hashToken("never-used"))
+	c.Assert(usage[0].Kind, check.Equals, "session")
+	c.Assert(usage[0].User, check.Equals, s.user.Email)
+	c.Assert(usage[0].LastUsedAt.IsZero(), check.Equals, true)
+	c.Assert(usage[1].ID, check.Equals, hashToken("older"))
+	c.Assert(usage[2].ID, check.Equals, hashToken("old"))
+}
+
+func (s *S) TestTokenValueByID(c *check.C) {
+	err := s.conn.Tokens().Insert(bson.M{"token": "abc123", "creation": time.Now().UTC(), "useremail": s.user.Email})
+	c.Assert(err, check.IsNil)
+	value, err := TokenValueByID(hashToken("abc123"))
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "abc123")
+	_, err = TokenValueByID(hashToken("unknown"))
+	c.Assert(err, check.Equals, ErrInvalidToken)
+}
@@ -9,6 +9,7 @@ import (
 	"crypto/rand"
 	_ "crypto/sha256"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,13 +21,15 @@ import (
 	"github.com/tsuru/tsuru/quota"
 	"github.com/tsuru/tsuru/repository"
 	"github.com/tsuru/tsuru/validation"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrInvalidKey   = errors.New("invalid key")
-	ErrKeyDisabled  = errors.New("key management is disabled")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrKeyDisabled     = errors.New("key management is disabled")
+	ErrUserDeactivated = &tsuruErrors.NotAuthorizedError{Message: "user is deactivated"}
 )
 
 type RoleInstance struct {
@@ -36,10 +39,14 @@ type RoleInstance struct {
 
 type User struct {
 	quota.Quota
-	Email    string
-	Password string
-	APIKey   string
-	Roles    []RoleInstance `bson:",omitempty"`
+	Email       string
+	Password    string
+	APIKey      string
+	Roles       []RoleInstance `bson:",omitempty"`
+	Deactivated bool           `bson:",omitempty"`
+	// VerificationPending indicates the user has not confirmed the email
+	// address yet, and is not able to login.
+	VerificationPending bool `bson:",omitempty"`
 }
 
 func listUsers(filter bson.M) ([]User, error) {
@@ -161,6 +168,51 @@ func (u *User) Update() error {
 	return conn.Users().Update(bson.M{"email": u.Email}, u)
 }
 
+// Deactivate prevents the user from logging in again and invalidates the
+// user API key, the scoped tokens created by the user and the impersonation
+// tokens acting as or held by the user. Session tokens must be revoked by the
+// auth scheme, see UserTokenRevoker.
+func (u *User) Deactivate() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Users().Update(bson.M{"email": u.Email}, bson.M{
+		"$set":   bson.M{"deactivated": true},
+		"$unset": bson.M{"apikey": ""},
+	})
+	if err != nil {
+		return err
+	}
+	u.Deactivated = true
+	u.APIKey = ""
+	_, err = conn.ScopedTokens().RemoveAll(bson.M{"creator": u.Email})
+	if err != nil {
+		return err
+	}
+	_, err = conn.ImpersonationTokens().RemoveAll(bson.M{"$or": []bson.M{
+		{"useremail": u.Email},
+		{"impersonator": u.Email},
+	}})
+	return err
+}
+
+// Activate allows a deactivated user to log in again.
+func (u *User) Activate() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Users().Update(bson.M{"email": u.Email}, bson.M{"$unset": bson.M{"deactivated": ""}})
+	if err != nil {
+		return err
+	}
+	u.Deactivated = false
+	return nil
+}
+
 func (u *User) AddKey(key repository.Key, force bool) error {
 	if mngr, ok := repository.Manager().(repository.KeyRepositoryManager); ok {
 		if key.Name == "" {
@@ -248,7 +300,7 @@ func (u *User) Permissions() ([]permission.Permission, error) {
 		}
 		permissions = append(permissions, role.PermissionsFor(roleData.ContextValue)...)
 	}
-	return permissions, nil
+	return cascadeTeamPermissions(permissions)
 }
 
 func (u *User) AddRole(roleName string, contextValue string) error {
@@ -291,6 +343,98 @@ func RemoveRoleFromAllUsers(roleName string) error {
 	return err
 }
 
+// roleConfigKeys are the config entries holding role names, which can't be
+// updated when a role is renamed.
+var roleConfigKeys = []string{"auth:ldap:team-role", "auth:saml:team-role"}
+
+// ErrRoleReferencedInConfig is returned when renaming a role whose name is
+// used in the tsuru config.
+type ErrRoleReferencedInConfig struct {
+	Role string
+	Key  string
+}
+
+func (e *ErrRoleReferencedInConfig) Error() string {
+	return fmt.Sprintf("role %q is referenced in config entry %q, change it before renaming the role", e.Role, e.Key)
+}
+
+// RenameRole renames the role and changes its description, updating every
+// user and service account holding it. The role with the new name is created
+// before its references are updated, and the old one is only removed when no
+// reference is left, so the role is never missing. If any reference can't be
+// updated, the changes are reverted and an error is returned.
+func RenameRole(name, newName, description string) (permission.Role, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || newName == name {
+		return permission.UpdateRole(name, "", description)
+	}
+	for _, key := range roleConfigKeys {
+		if value, _ := config.GetString(key); value == name {
+			return permission.Role{}, &ErrRoleReferencedInConfig{Role: name, Key: key}
+		}
+	}
+	role, err := permission.UpdateRole(name, newName, description)
+	if err != nil {
+		return role, err
+	}
+	err = renameRoleReferences(name, role.Name)
+	if err != nil {
+		if revertErr := renameRoleReferences(role.Name, name); revertErr != nil {
+			log.Errorf("unable to revert the rename of role %q to %q: %s", name, role.Name, revertErr)
+		} else if destroyErr := permission.DestroyRole(role.Name); destroyErr != nil {
+			log.Errorf("unable to remove role %q after failing to rename %q: %s", role.Name, name, destroyErr)
+		}
+		return role, err
+	}
+	return role, permission.DestroyRole(name)
+}
+
+// renameRoleReferences updates the role name in every user and service
+// account holding it. Each user is updated with a single write, replacing all
+// the instances of the role, and the write is skipped if the user roles were
+// concurrently changed. It fails if any reference to the old name is left.
+func renameRoleReferences(roleName, newRoleName string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var users []User
+	err = conn.Users().Find(bson.M{"roles.name": roleName}).All(&users)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		roles := make([]RoleInstance, len(u.Roles))
+		for i, r := range u.Roles {
+			if r.Name == roleName {
+				r.Name = newRoleName
+			}
+			roles[i] = r
+		}
+		err = conn.Users().Update(bson.M{"email": u.Email, "roles": u.Roles}, bson.M{"$set": bson.M{"roles": roles}})
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+	_, err = conn.ServiceAccounts().UpdateAll(bson.M{"role": roleName}, bson.M{"$set": bson.M{"role": newRoleName}})
+	if err != nil {
+		return err
+	}
+	leftUsers, err := conn.Users().Find(bson.M{"roles.name": roleName}).Count()
+	if err != nil {
+		return err
+	}
+	leftAccounts, err := conn.ServiceAccounts().Find(bson.M{"role": roleName}).Count()
+	if err != nil {
+		return err
+	}
+	if leftUsers > 0 || leftAccounts > 0 {
+		return errors.Errorf("unable to rename role %q to %q: %d users and %d service accounts still hold it", roleName, newRoleName, leftUsers, leftAccounts)
+	}
+	return nil
+}
+
 func (u *User) RemoveRole(roleName string, contextValue string) error {
 	conn, err := db.Conn()
 	if err != nil {
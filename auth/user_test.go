@@ -377,6 +377,94 @@ func (s *S) TestRemoveRoleFromAllUsers(c *check.C) {
 	c.Assert(uDB.Roles, check.DeepEquals, expected)
 }
 
+func (s *S) TestRenameRole(c *check.C) {
+	r1, err := permission.NewRole("r1", "team", "")
+	c.Assert(err, check.IsNil)
+	err = r1.AddPermissions("app")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("r2", "team", "")
+	c.Assert(err, check.IsNil)
+	u := User{
+		Email:    "me@tsuru.com",
+		Password: "123",
+		Roles: []RoleInstance{
+			{Name: "r1", ContextValue: "c1"},
+			{Name: "r1", ContextValue: "c2"},
+			{Name: "r2", ContextValue: "x"},
+		},
+	}
+	err = u.Create()
+	c.Assert(err, check.IsNil)
+	account := ServiceAccount{ID: bson.NewObjectId(), Name: "ci", Team: "c1", Role: "r1"}
+	err = s.conn.ServiceAccounts().Insert(account)
+	c.Assert(err, check.IsNil)
+	role, err := RenameRole("r1", "r3", "new role")
+	c.Assert(err, check.IsNil)
+	c.Assert(role.Name, check.Equals, "r3")
+	c.Assert(role.Description, check.Equals, "new role")
+	c.Assert(role.SchemeNames, check.DeepEquals, []string{"app"})
+	_, err = permission.FindRole("r1")
+	c.Assert(err, check.Equals, permission.ErrRoleNotFound)
+	expected := []RoleInstance{
+		{Name: "r2", ContextValue: "x"},
+		{Name: "r3", ContextValue: "c1"},
+		{Name: "r3", ContextValue: "c2"},
+	}
+	sort.Sort(roleInstanceList(expected))
+	uDB, err := GetUserByEmail("me@tsuru.com")
+	c.Assert(err, check.IsNil)
+	sort.Sort(roleInstanceList(uDB.Roles))
+	c.Assert(uDB.Roles, check.DeepEquals, expected)
+	var dbAccount ServiceAccount
+	err = s.conn.ServiceAccounts().FindId(account.ID).One(&dbAccount)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbAccount.Role, check.Equals, "r3")
+}
+
+func (s *S) TestRenameRoleOnlyDescription(c *check.C) {
+	_, err := permission.NewRole("r1", "team", "")
+	c.Assert(err, check.IsNil)
+	u := User{Email: "me@tsuru.com", Password: "123", Roles: []RoleInstance{{Name: "r1", ContextValue: "c1"}}}
+	err = u.Create()
+	c.Assert(err, check.IsNil)
+	role, err := RenameRole("r1", " r1 ", "new role")
+	c.Assert(err, check.IsNil)
+	c.Assert(role.Description, check.Equals, "new role")
+	_, err = permission.FindRole("r1")
+	c.Assert(err, check.IsNil)
+	uDB, err := GetUserByEmail("me@tsuru.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(uDB.Roles, check.DeepEquals, []RoleInstance{{Name: "r1", ContextValue: "c1"}})
+}
+
+func (s *S) TestRenameRoleReferencedInConfig(c *check.C) {
+	config.Set("auth:ldap:team-role", "r1")
+	defer config.Unset("auth:ldap:team-role")
+	_, err := permission.NewRole("r1", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = RenameRole("r1", "r3", "")
+	c.Assert(err, check.DeepEquals, &ErrRoleReferencedInConfig{Role: "r1", Key: "auth:ldap:team-role"})
+	_, err = permission.FindRole("r1")
+	c.Assert(err, check.IsNil)
+	_, err = permission.FindRole("r3")
+	c.Assert(err, check.Equals, permission.ErrRoleNotFound)
+}
+
+func (s *S) TestRenameRoleAlreadyExists(c *check.C) {
+	_, err := permission.NewRole("r1", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = permission.NewRole("r3", "team", "")
+	c.Assert(err, check.IsNil)
+	u := User{Email: "me@tsuru.com", Password: "123", Roles: []RoleInstance{{Name: "r1", ContextValue: "c1"}}}
+	err = u.Create()
+	c.Assert(err, check.IsNil)
+	_, err = RenameRole("r1", "r3", "")
+	c.Assert(err, check.Equals, permission.ErrRoleAlreadyExists)
+	uDB, err := GetUserByEmail("me@tsuru.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(uDB.Roles, check.DeepEquals, []RoleInstance{{Name: "r1", ContextValue: "c1"}})
+}
+
 func (s *S) TestUserPermissions(c *check.C) {
 	u := User{Email: "me@tsuru.com", Password: "123"}
 	err := u.Create()
@@ -487,3 +575,36 @@ func (s *S) TestAddRolesForEvent(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(u.Roles, check.DeepEquals, []RoleInstance{{Name: "r1", ContextValue: "team1"}})
 }
+
+func (s *S) TestUserDeactivateAndActivate(c *check.C) {
+	u := User{Email: "me@tsuru.com", Password: "123"}
+	err := u.Create()
+	c.Assert(err, check.IsNil)
+	key, err := u.RegenerateAPIKey()
+	c.Assert(err, check.IsNil)
+	scoped, err := CreateScopedToken(&u, "myapp", []*permission.PermissionScheme{permission.PermAppDeploy}, 0)
+	c.Assert(err, check.IsNil)
+	admin := User{Email: "admin@tsuru.com", Password: "123"}
+	err = admin.Create()
+	c.Assert(err, check.IsNil)
+	impersonated, err := Impersonate(&APIToken{Token: "admin-key", UserEmail: admin.Email}, &u)
+	c.Assert(err, check.IsNil)
+	err = u.Deactivate()
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Deactivated, check.Equals, true)
+	dbUser, err := GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.Deactivated, check.Equals, true)
+	c.Assert(dbUser.APIKey, check.Equals, "")
+	_, err = APIAuth("bearer " + key)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	_, err = ScopedAuth("bearer " + scoped.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	_, err = ImpersonationAuth("bearer " + impersonated.Token)
+	c.Assert(err, check.Equals, ErrInvalidToken)
+	err = u.Activate()
+	c.Assert(err, check.IsNil)
+	dbUser, err = GetUserByEmail(u.Email)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbUser.Deactivated, check.Equals, false)
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestIgnoreMatcher(c *check.C) {
+	m, err := NewIgnoreMatcher([]string{
+		"# comment",
+		"",
+		"*.log",
+		"!important.log",
+		"/build",
+		"tmp/",
+		"docs/*.md",
+		"**/cache/**",
+		"file[0-9].txt",
+	})
+	c.Assert(err, check.IsNil)
+	var tests = []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"important.log", false, false},
+		{"logs/important.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"tmp", true, true},
+		{"src/tmp", true, true},
+		{"tmp", false, false},
+		{"docs/index.md", false, true},
+		{"docs/api/index.md", false, false},
+		{"a/cache/b/c", false, true},
+		{"cache/b", false, true},
+		{"file1.txt", false, true},
+		{"filea.txt", false, false},
+		{"main.go", false, false},
+		{"# comment", false, false},
+	}
+	for _, t := range tests {
+		c.Check(m.Match(t.path, t.isDir), check.Equals, t.want, check.Commentf("path %q", t.path))
+	}
+}
+
+func (s *S) TestIgnoreMatcherEscaped(c *check.C) {
+	m, err := NewIgnoreMatcher([]string{`\#notes`, `\!bang`, `what\?`})
+	c.Assert(err, check.IsNil)
+	c.Assert(m.Match("#notes", false), check.Equals, true)
+	c.Assert(m.Match("!bang", false), check.Equals, true)
+	c.Assert(m.Match("what?", false), check.Equals, true)
+	c.Assert(m.Match("whats", false), check.Equals, false)
+}
+
+func (s *S) TestReadIgnoreFileNotFound(c *check.C) {
+	dir, err := ioutil.TempDir("", "tsuru-archive")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	m, err := ReadIgnoreFile(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(m.Match("anything", false), check.Equals, false)
+}
+
+func (s *S) TestArchive(c *check.C) {
+	dir, err := ioutil.TempDir("", "tsuru-archive")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		".tsuruignore":        "*.log\nnode_modules/\n!keep.log\n",
+		"app.py":              "print('hi')",
+		"debug.log":           "debug",
+		"keep.log":            "keep",
+		"static/app.js":       "js",
+		"node_modules/x/a.js": "dep",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), check.IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), check.IsNil)
+	}
+	var buf bytes.Buffer
+	err = Archive(&buf, dir)
+	c.Assert(err, check.IsNil)
+	gzipReader, err := gzip.NewReader(&buf)
+	c.Assert(err, check.IsNil)
+	tarReader := tar.NewReader(gzipReader)
+	var names []string
+	contents := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		names = append(names, header.Name)
+		if header.Typeflag == tar.TypeReg {
+			data, err := ioutil.ReadAll(tarReader)
+			c.Assert(err, check.IsNil)
+			contents[header.Name] = string(data)
+		}
+	}
+	sort.Strings(names)
+	c.Assert(names, check.DeepEquals, []string{".tsuruignore", "app.py", "keep.log", "static/", "static/app.js"})
+	c.Assert(contents["static/app.js"], check.Equals, "js")
+	c.Assert(contents["app.py"], check.Equals, "print('hi')")
+}
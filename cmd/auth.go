@@ -14,8 +14,10 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tsuru/gnuflag"
 	tsuruNet "github.com/tsuru/tsuru/net"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -30,6 +32,9 @@ type login struct {
 }
 
 func nativeLogin(context *Context, client *Client) error {
+	if noInput {
+		return errInputRequired("the password")
+	}
 	var email string
 	if len(context.Args) > 0 {
 		email = context.Args[0]
@@ -109,7 +114,9 @@ After that, the token generated by the tsuru server will be stored in
 [[${HOME}/.tsuru/token]].
 
 All tsuru actions require the user to be authenticated (except [[tsuru login]]
-and [[tsuru version]]).`,
+and [[tsuru version]]).
+
+The native login always prompts for the password, so it fails with --no-input.`,
 		MinArgs: 0,
 	}
 }
@@ -129,6 +136,9 @@ func (c *logout) Run(context *Context, client *Client) error {
 		request, _ := http.NewRequest("DELETE", url, nil)
 		client.Do(request)
 	}
+	if tokenPath, err := targetTokenPath(currentTargetLabel()); err == nil {
+		filesystem().Remove(tokenPath)
+	}
 	err := filesystem().Remove(JoinWithUserDir(".tsuru", "token"))
 	if err != nil && os.IsNotExist(err) {
 		return errors.New("You're not logged in!")
@@ -223,6 +233,141 @@ func (userInfo) Run(context *Context, client *Client) error {
 	return nil
 }
 
+type apiTokenUsage struct {
+	ID            string
+	Kind          string
+	User          string
+	App           string
+	LastUsedAt    time.Time
+	LastIP        string
+	LastUserAgent string
+	Revoked       bool
+}
+
+type tokenList struct {
+	OutputCommand
+	fs     *gnuflag.FlagSet
+	unused int
+}
+
+func (c *tokenList) Info() *Info {
+	return &Info{
+		Name:  "token-list",
+		Usage: "token-list [--unused days] [--json] [--format template]",
+		Desc: `Lists the tokens of the current user, along with the last time, source IP
+and user agent each one was used.
+
+With the --unused flag, lists the tokens of all users that were not used in
+the given number of days. This report is restricted to admin users.`,
+	}
+}
+
+func (c *tokenList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("token-list", gnuflag.ExitOnError)
+		c.fs.IntVar(&c.unused, "unused", 0, "List tokens of all users unused in the given number of days")
+		c.fs.IntVar(&c.unused, "u", 0, "List tokens of all users unused in the given number of days")
+		c.fs = MergeFlagSet(c.fs, c.OutputCommand.Flags())
+	}
+	return c.fs
+}
+
+func (c *tokenList) Run(context *Context, client *Client) error {
+	path := "/users/tokens"
+	if c.unused > 0 {
+		path += fmt.Sprintf("/unused?days=%d", c.unused)
+	}
+	url, err := GetURL(path)
+	if err != nil {
+		return err
+	}
+	request, _ := http.NewRequest("GET", url, nil)
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var tokens []apiTokenUsage
+	if resp.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(resp.Body).Decode(&tokens)
+		if err != nil {
+			return err
+		}
+	}
+	if rendered, err := c.Render(context.Stdout, tokens); rendered {
+		return err
+	}
+	table := NewTable()
+	table.Headers = Row{"ID", "Kind", "User", "App", "Last used", "Last IP", "Last user agent"}
+	for _, t := range tokens {
+		id := t.ID
+		if t.Revoked {
+			id += " (revoked)"
+		}
+		lastUsed := "never"
+		if !t.LastUsedAt.IsZero() {
+			lastUsed = t.LastUsedAt.Local().Format(time.RFC822)
+		}
+		table.AddRow(Row{id, t.Kind, t.User, t.App, lastUsed, t.LastIP, t.LastUserAgent})
+	}
+	fmt.Fprint(context.Stdout, table.String())
+	return nil
+}
+
+type tokenRevoke struct {
+	fs *gnuflag.FlagSet
+	id string
+}
+
+func (c *tokenRevoke) Info() *Info {
+	return &Info{
+		Name:  "token-revoke",
+		Usage: "token-revoke [token] [--id id]",
+		Desc: `Revokes a token, making the tsuru API reject it from now on. The token may be
+given as argument or identified by its id, as displayed by the token-list
+command.
+
+Revoking tokens of other users is restricted to admin users.`,
+		MaxArgs: 1,
+	}
+}
+
+func (c *tokenRevoke) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("token-revoke", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.id, "id", "", "The id of the token to revoke")
+	}
+	return c.fs
+}
+
+func (c *tokenRevoke) Run(context *Context, client *Client) error {
+	v := url.Values{}
+	switch {
+	case len(context.Args) > 0 && c.id == "":
+		v.Set("token", context.Args[0])
+	case len(context.Args) == 0 && c.id != "":
+		v.Set("id", c.id)
+	default:
+		return errors.New("You must provide either the token or its id.")
+	}
+	u, err := GetURL("/users/tokens/revoke")
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	fmt.Fprintln(context.Stdout, "Token successfully revoked.")
+	return nil
+}
+
 func PasswordFromReader(reader io.Reader) (string, error) {
 	var (
 		password []byte
@@ -54,6 +54,22 @@ func (s *S) TestNativeLogin(c *check.C) {
 	c.Assert(token, check.Equals, "sometoken")
 }
 
+func (s *S) TestNativeLoginNoInput(c *check.C) {
+	noInput = true
+	defer func() {
+		noInput = false
+		inputRequired = false
+	}()
+	nativeScheme()
+	reader := strings.NewReader("chico\n")
+	context := Context{[]string{"foo@foo.com"}, globalManager.stdout, globalManager.stderr, reader}
+	command := login{}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "the password must be typed in a prompt, refusing to prompt with --no-input")
+	c.Assert(inputRequired, check.Equals, true)
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "")
+}
+
 func (s *S) TestNativeLoginWithoutEmailFromArg(c *check.C) {
 	os.Unsetenv("TSURU_TOKEN")
 	nativeScheme()
@@ -325,6 +341,117 @@ Permissions:
 	c.Assert(called, check.Equals, true)
 }
 
+func (s *S) TestTokenListRun(c *check.C) {
+	var called bool
+	context := Context{[]string{}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenList{}
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{
+			Message: `[
+	{"id":"0123456789abcdef","kind":"session","user":"myuser@company.com","lastUsedAt":"2016-11-09T16:11:20Z","lastIP":"10.0.0.1","lastUserAgent":"tsuru-client"},
+	{"id":"fedcba9876543210","kind":"api-key","user":"myuser@company.com","lastUsedAt":"2016-11-09T16:11:20Z","lastIP":"10.0.0.2","lastUserAgent":"curl","revoked":true},
+	{"id":"00112233445566778899","kind":"app","user":"myuser@company.com","app":"myapp","lastUsedAt":"0001-01-01T00:00:00Z"}
+]`,
+			Status: http.StatusOK,
+		},
+		CondFunc: func(req *http.Request) bool {
+			called = true
+			return req.Method == "GET" && req.URL.Path == "/1.0/users/tokens"
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	out := globalManager.stdout.(*bytes.Buffer).String()
+	c.Assert(out, check.Matches, `(?s).*\| 0123456789abcdef\s+\| session \| myuser@company.com \|.*\| 10.0.0.1 \| tsuru-client\s+\|.*`)
+	c.Assert(out, check.Matches, `(?s).*\| fedcba9876543210 \(revoked\) \| api-key \|.*\| 10.0.0.2 \| curl\s+\|.*`)
+	c.Assert(out, check.Matches, `(?s).*\| 00112233445566778899\s+\| app\s+\| myuser@company.com \| myapp \| never\s+\|.*`)
+}
+
+func (s *S) TestTokenListRunFormat(c *check.C) {
+	context := Context{[]string{}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenList{}
+	command.Flags().Parse(true, []string{"--format", "{{.ID}} {{.Kind}}"})
+	transport := cmdtest.Transport{
+		Message: `[{"id":"0123456789abcdef","kind":"session"},{"id":"fedcba9876543210","kind":"api-key"}]`,
+		Status:  http.StatusOK,
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "0123456789abcdef session\nfedcba9876543210 api-key\n")
+}
+
+func (s *S) TestTokenListRunUnused(c *check.C) {
+	var called bool
+	context := Context{[]string{}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenList{}
+	command.Flags().Parse(true, []string{"--unused", "90"})
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusNoContent},
+		CondFunc: func(req *http.Request) bool {
+			called = true
+			return req.Method == "GET" && req.URL.Path == "/1.0/users/tokens/unused" &&
+				req.URL.Query().Get("days") == "90"
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+}
+
+func (s *S) TestTokenRevokeRun(c *check.C) {
+	var called bool
+	context := Context{[]string{"abc123"}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenRevoke{}
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			called = true
+			return req.Method == "POST" && req.URL.Path == "/1.0/users/tokens/revoke" &&
+				req.FormValue("token") == "abc123" && req.FormValue("id") == ""
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "Token successfully revoked.\n")
+}
+
+func (s *S) TestTokenRevokeRunByID(c *check.C) {
+	var called bool
+	context := Context{[]string{}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenRevoke{}
+	command.Flags().Parse(true, []string{"--id", "0123456789abcdef"})
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			called = true
+			return req.Method == "POST" && req.URL.Path == "/1.0/users/tokens/revoke" &&
+				req.FormValue("id") == "0123456789abcdef" && req.FormValue("token") == ""
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(called, check.Equals, true)
+}
+
+func (s *S) TestTokenRevokeRunRequiresTokenOrID(c *check.C) {
+	context := Context{[]string{"abc123"}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	command := tokenRevoke{}
+	command.Flags().Parse(true, []string{"--id", "0123456789abcdef"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide either the token or its id.")
+	command = tokenRevoke{}
+	context.Args = nil
+	err = command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "You must provide either the token or its id.")
+}
+
 func (s *S) TestPasswordFromReaderUsingFile(c *check.C) {
 	tmpdir, err := filepath.EvalSymlinks(os.TempDir())
 	filename := path.Join(tmpdir, "password-reader.txt")
@@ -6,6 +6,7 @@ package cmd
 
 import (
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -118,7 +119,17 @@ and download the last version.
 		if len(body) > 0 {
 			err.Message = string(body)
 		}
-
+		if response.Header.Get("Content-Type") == "application/x-json-stream" {
+			var msg tsuruio.SimpleJsonMessage
+			if json.Unmarshal(body, &msg) == nil {
+				if msg.Quota != nil {
+					return response, msg.Quota
+				}
+				if msg.Error != "" {
+					err.Message = msg.Error
+				}
+			}
+		}
 		return response, err
 	}
 	return response, nil
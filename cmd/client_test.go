@@ -14,6 +14,7 @@ import (
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/fs/fstest"
+	"github.com/tsuru/tsuru/quota"
 	"gopkg.in/check.v1"
 )
 
@@ -74,6 +75,36 @@ func (s *S) TestShouldReturnBodyMessageOnError(c *check.C) {
 			`You can't do this.*`)
 }
 
+func (s *S) TestShouldReturnQuotaExceededErrorFromStream(c *check.C) {
+	request, err := http.NewRequest("PUT", "/", nil)
+	c.Assert(err, check.IsNil)
+	transport := cmdtest.Transport{
+		Message: `{"Message":"","Error":"Quota exceeded. Available: 1. Requested: 3.","Quota":{"Requested":3,"Available":1}}` + "\n",
+		Status:  http.StatusForbidden,
+		Headers: map[string][]string{"Content-Type": {"application/x-json-stream"}},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	_, err = client.Do(request)
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 1})
+}
+
+func (s *S) TestShouldReturnErrorMessageFromStream(c *check.C) {
+	request, err := http.NewRequest("PUT", "/", nil)
+	c.Assert(err, check.IsNil)
+	transport := cmdtest.Transport{
+		Message: `{"Message":"","Error":"something went wrong"}` + "\n",
+		Status:  http.StatusInternalServerError,
+		Headers: map[string][]string{"Content-Type": {"application/x-json-stream"}},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	_, err = client.Do(request)
+	c.Assert(err, check.NotNil)
+	httpErr, ok := err.(*errors.HTTP)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(httpErr.Code, check.Equals, http.StatusInternalServerError)
+	c.Assert(httpErr.Message, check.Equals, "something went wrong")
+}
+
 func (s *S) TestShouldReturnStatusMessageOnErrorWhenBodyIsEmpty(c *check.C) {
 	request, err := http.NewRequest("GET", "/", nil)
 	c.Assert(err, check.IsNil)
@@ -70,7 +70,13 @@ func NewManager(name, ver, verHeader string, stdout, stderr io.Writer, stdin io.
 	return manager
 }
 
+// BuildBaseManager returns a manager with the commands shared by the tsuru
+// clients. When lookup is nil, unknown commands are run as plugins, see
+// PluginLookup.
 func BuildBaseManager(name, version, versionHeader string, lookup Lookup) *Manager {
+	if lookup == nil {
+		lookup = PluginLookup
+	}
 	m := NewManager(name, version, versionHeader, os.Stdout, os.Stderr, os.Stdin, lookup)
 	m.Register(&login{})
 	m.Register(&logout{})
@@ -79,6 +85,14 @@ func BuildBaseManager(name, version, versionHeader string, lookup Lookup) *Manag
 	m.Register(&targetRemove{})
 	m.Register(&targetSet{})
 	m.Register(userInfo{})
+	m.Register(&tokenList{})
+	m.Register(&tokenRevoke{})
+	m.Register(&pluginInstall{})
+	m.Register(&pluginRemove{})
+	m.Register(&pluginList{})
+	m.Register(&completion{manager: m})
+	m.Register(&completionList{})
+	m.Register(&appValidate{})
 	m.RegisterTopic("target", fmt.Sprintf(targetTopic, name))
 	return m
 }
@@ -165,12 +179,18 @@ func (m *Manager) Run(args []string) {
 	flagset.BoolVar(&displayHelp, "help", false, "Display help and exit")
 	flagset.BoolVar(&displayHelp, "h", false, "Display help and exit")
 	flagset.BoolVar(&displayVersion, "version", false, "Print version and exit")
+	flagset.BoolVar(&jsonOutput, "json", false, "Display the output of commands as JSON, when supported")
+	flagset.BoolVar(&noInput, "no-input", false, "Never prompt for input, failing commands that need confirmation unless -y is given. Also disables colors and the pager")
 	parseErr := flagset.Parse(false, args)
 	if parseErr != nil {
 		fmt.Fprint(m.stderr, parseErr)
-		m.finisher().Exit(2)
+		m.finisher().Exit(ExitUsage)
 		return
 	}
+	if os.Getenv(noInputEnv) != "" {
+		noInput = true
+	}
+	inputRequired = false
 	args = flagset.Args()
 	if displayHelp {
 		args = append([]string{"help"}, args...)
@@ -182,7 +202,7 @@ func (m *Manager) Run(args []string) {
 		err := m.lookup(context)
 		if err != nil && err != ErrLookup {
 			fmt.Fprint(m.stderr, err)
-			m.finisher().Exit(1)
+			m.finisher().Exit(ExitError)
 			return
 		} else if err == nil {
 			return
@@ -207,7 +227,7 @@ func (m *Manager) Run(args []string) {
 			}
 		}
 		fmt.Fprint(m.stderr, msg)
-		m.finisher().Exit(1)
+		m.finisher().Exit(ExitError)
 		return
 	}
 	args = args[1:]
@@ -215,13 +235,13 @@ func (m *Manager) Run(args []string) {
 	command, args, err := m.handleFlags(command, name, args)
 	if err != nil {
 		fmt.Fprint(m.stderr, err)
-		m.finisher().Exit(1)
+		m.finisher().Exit(ExitError)
 		return
 	}
 	if info.fail {
 		command = m.Commands["help"]
 		args = []string{name}
-		status = 1
+		status = ExitError
 	}
 	if length := len(args); (length < info.MinArgs || (info.MaxArgs > 0 && length > info.MaxArgs)) &&
 		name != "help" {
@@ -229,13 +249,13 @@ func (m *Manager) Run(args []string) {
 		m.original = info.Name
 		command = m.Commands["help"]
 		args = []string{name}
-		status = 1
+		status = ExitError
 	}
 	context := m.newContext(args, m.stdout, m.stderr, m.stdin)
 	client := NewClient(net.Dial5FullUnlimitedClient, context, m)
 	client.Verbosity = verbosity
 	err = command.Run(context, client)
-	if err == errUnauthorized && name != loginCmdName {
+	if err == errUnauthorized && name != loginCmdName && !noInput {
 		if cmd, ok := m.Commands[loginCmdName]; ok {
 			fmt.Fprintln(m.stderr, "Error: you're not authenticated or your session has expired.")
 			fmt.Fprintf(m.stderr, "Calling the %q command...\n", loginCmdName)
@@ -258,13 +278,20 @@ func (m *Manager) Run(args []string) {
 		if err != ErrAbortCommand {
 			io.WriteString(m.stderr, "Error: "+errorMsg)
 		}
-		status = 1
+		status = ExitError
+		if inputRequired {
+			status = ExitInputRequired
+		}
+	} else if inputRequired {
+		status = ExitInputRequired
 	}
 	m.finisher().Exit(status)
 }
 
 func (m *Manager) newContext(args []string, stdout io.Writer, stderr io.Writer, stdin io.Reader) *Context {
-	stdout = newPagerWriter(stdout)
+	if !noInput {
+		stdout = newPagerWriter(stdout)
+	}
 	stdin = newSyncReader(stdin, stdout)
 	ctx := &Context{args, stdout, stderr, stdin}
 	m.contexts = append(m.contexts, ctx)
@@ -219,6 +219,65 @@ func (s *S) TestManagerRunWithInvalidValueForFlag(c *check.C) {
 	c.Assert(exiter.value(), check.Equals, 1)
 }
 
+func (s *S) TestManagerRunNoInputRequiresConfirmationFlag(c *check.C) {
+	defer func() {
+		noInput = false
+	}()
+	cmd := &ConfirmCommand{}
+	globalManager.Register(cmd)
+	globalManager.Run([]string{"--no-input", "confirm"})
+	c.Assert(cmd.confirmed, check.Equals, false)
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "")
+	c.Assert(globalManager.stderr.(*bytes.Buffer).String(), check.Equals, "Error: Are you sure? Refusing to prompt for confirmation with --no-input, use -y to confirm.\n")
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitInputRequired)
+	globalManager.Run([]string{"--no-input", "confirm", "-y"})
+	c.Assert(cmd.confirmed, check.Equals, true)
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitOK)
+}
+
+func (s *S) TestManagerRunNoInputFromEnvironment(c *check.C) {
+	os.Setenv("TSURU_NO_INPUT", "1")
+	defer func() {
+		os.Unsetenv("TSURU_NO_INPUT")
+		noInput = false
+	}()
+	cmd := &ConfirmCommand{}
+	globalManager.Register(cmd)
+	globalManager.Run([]string{"confirm"})
+	c.Assert(cmd.confirmed, check.Equals, false)
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitInputRequired)
+}
+
+func (s *S) TestManagerRunNoInputDoesNotCallLogin(c *check.C) {
+	defer func() {
+		noInput = false
+	}()
+	globalManager.Register(&SuccessLoginCommand{})
+	cmd := &FailAndWorkCommand{}
+	globalManager.Register(cmd)
+	globalManager.Run([]string{"--no-input", "fail-and-work"})
+	c.Assert(cmd.calls, check.Equals, 1)
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "")
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitError)
+}
+
+func (s *S) TestManagerRunNoInputLogin(c *check.C) {
+	defer func() {
+		noInput = false
+	}()
+	globalManager.Register(&login{scheme: &loginScheme{Name: "native"}})
+	globalManager.stdin = strings.NewReader("chico\n")
+	globalManager.Run([]string{"--no-input", "login", "foo@foo.com"})
+	c.Assert(globalManager.stdout.(*bytes.Buffer).String(), check.Equals, "")
+	c.Assert(globalManager.stderr.(*bytes.Buffer).String(), check.Equals, "Error: the password must be typed in a prompt, refusing to prompt with --no-input\n")
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitInputRequired)
+}
+
+func (s *S) TestManagerRunWithInvalidGlobalFlag(c *check.C) {
+	globalManager.Run([]string{"--invalid-flag", "foo"})
+	c.Assert(globalManager.e.(*recordingExiter).value(), check.Equals, ExitUsage)
+}
+
 func (s *S) TestRun(c *check.C) {
 	globalManager.Register(&TestCommand{})
 	globalManager.Run([]string{"foo"})
@@ -652,6 +711,20 @@ func (s *S) TestUserInfoIsRegisteredByBaseManager(c *check.C) {
 	c.Assert(info, check.FitsTypeOf, userInfo{})
 }
 
+func (s *S) TestTokenListIsRegisteredByBaseManager(c *check.C) {
+	mngr := BuildBaseManager("tsuru", "1.0", "", nil)
+	cmd, ok := mngr.Commands["token-list"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cmd, check.FitsTypeOf, &tokenList{})
+}
+
+func (s *S) TestTokenRevokeIsRegisteredByBaseManager(c *check.C) {
+	mngr := BuildBaseManager("tsuru", "1.0", "", nil)
+	cmd, ok := mngr.Commands["token-revoke"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cmd, check.FitsTypeOf, &tokenRevoke{})
+}
+
 func (s *S) TestInvalidCommandFuzzyMatch01(c *check.C) {
 	mngr := BuildBaseManager("tsuru", "1.0", "", nil)
 	var stdout, stderr bytes.Buffer
@@ -704,7 +777,10 @@ func (s *S) TestInvalidCommandFuzzyMatch03(c *check.C) {
 	expectedOutput := `.*: "list" is not a tsuru command. See "tsuru help".
 
 Did you mean?
+	completion-list
+	plugin-list
 	target-list
+	token-list
 `
 	expectedOutput = strings.Replace(expectedOutput, "\n", "\\W", -1)
 	expectedOutput = strings.Replace(expectedOutput, "\t", "\\W+", -1)
@@ -856,6 +932,20 @@ func (c *ErrorCommand) Run(context *Context, client *Client) error {
 	return fmt.Errorf(c.msg)
 }
 
+type ConfirmCommand struct {
+	ConfirmationCommand
+	confirmed bool
+}
+
+func (c *ConfirmCommand) Info() *Info {
+	return &Info{Name: "confirm"}
+}
+
+func (c *ConfirmCommand) Run(context *Context, client *Client) error {
+	c.confirmed = c.Confirm(context, "Are you sure?")
+	return nil
+}
+
 type FailAndWorkCommand struct {
 	calls int
 }
@@ -0,0 +1,220 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// completionCacheTTL is for how long the names returned by completion-list
+// are reused, so completing doesn't hit the API on every key press.
+var completionCacheTTL = time.Minute
+
+const bashCompletion = `_{{name}}_complete() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=( $(compgen -W "{{commands}}" -- "$cur") )
+        return
+    fi
+    case "$prev" in
+        -a|--app)
+            COMPREPLY=( $(compgen -W "$({{name}} completion-list apps 2>/dev/null)" -- "$cur") )
+            return
+            ;;
+    esac
+    case "${COMP_WORDS[1]}" in
+        service-*)
+            COMPREPLY=( $(compgen -W "$({{name}} completion-list services 2>/dev/null)" -- "$cur") )
+            ;;
+        help)
+            COMPREPLY=( $(compgen -W "{{commands}}" -- "$cur") )
+            ;;
+    esac
+}
+complete -o default -F _{{name}}_complete {{name}}
+`
+
+const zshCompletion = `autoload -U +X bashcompinit && bashcompinit
+`
+
+type completion struct {
+	manager *Manager
+}
+
+func (c *completion) Info() *Info {
+	return &Info{
+		Name:  "completion",
+		Usage: "completion <bash|zsh>",
+		Desc: `Prints a script that adds completion of commands, app names and service
+names to the given shell. To enable it, add the following line to the
+initialization file of the shell (~/.bashrc or ~/.zshrc):
+
+  source <(tsuru completion bash)
+
+App and service names are fetched from the current target and cached for a
+minute.`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *completion) Run(context *Context, client *Client) error {
+	var script string
+	switch context.Args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion + bashCompletion
+	default:
+		return errors.Errorf("unsupported shell %q, must be bash or zsh", context.Args[0])
+	}
+	var commands []string
+	for name, cmd := range c.manager.Commands {
+		if _, ok := cmd.(*DeprecatedCommand); !ok {
+			commands = append(commands, name)
+		}
+	}
+	sort.Strings(commands)
+	name := strings.Replace(c.manager.name, "-", "_", -1)
+	script = strings.Replace(script, "_{{name}}_complete", "_"+name+"_complete", -1)
+	script = strings.Replace(script, "{{name}}", c.manager.name, -1)
+	script = strings.Replace(script, "{{commands}}", strings.Join(commands, " "), -1)
+	fmt.Fprint(context.Stdout, script)
+	return nil
+}
+
+type completionList struct{}
+
+func (c *completionList) Info() *Info {
+	return &Info{
+		Name:    "completion-list",
+		Usage:   "completion-list <apps|services>",
+		Desc:    "Lists the names of apps or services in the current target, used by shell completion.",
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *completionList) Run(context *Context, client *Client) error {
+	kind := context.Args[0]
+	if kind != "apps" && kind != "services" {
+		return errors.Errorf("unsupported kind %q, must be apps or services", kind)
+	}
+	target, err := GetTarget()
+	if err != nil {
+		return err
+	}
+	if names, ok := readCompletionCache(kind, target); ok {
+		fmt.Fprint(context.Stdout, names)
+		return nil
+	}
+	names, err := completionNames(client, kind)
+	if err != nil {
+		return err
+	}
+	content := strings.Join(names, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	writeCompletionCache(kind, target, content)
+	fmt.Fprint(context.Stdout, content)
+	return nil
+}
+
+func completionNames(client *Client, kind string) ([]string, error) {
+	path := "/apps"
+	if kind == "services" {
+		path = "/services/instances"
+	}
+	url, err := GetURL(path)
+	if err != nil {
+		return nil, err
+	}
+	request, _ := http.NewRequest("GET", url, nil)
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	var names []string
+	if kind == "services" {
+		var services []ServiceModel
+		err = json.NewDecoder(resp.Body).Decode(&services)
+		for _, s := range services {
+			names = append(names, s.Service)
+			names = append(names, s.Instances...)
+		}
+	} else {
+		var apps []struct {
+			Name string `json:"name"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&apps)
+		for _, a := range apps {
+			names = append(names, a.Name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func completionCachePath(kind string) string {
+	return JoinWithUserDir(".tsuru", "completion", kind)
+}
+
+// readCompletionCache returns the cached names, as long as they were cached
+// for the same target less than completionCacheTTL ago. The first line of
+// the cache file is the target.
+func readCompletionCache(kind, target string) (string, bool) {
+	path := completionCachePath(kind)
+	info, err := filesystem().Stat(path)
+	if err != nil || time.Since(info.ModTime()) > completionCacheTTL {
+		return "", false
+	}
+	file, err := filesystem().Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", false
+	}
+	parts := bytes.SplitN(data, []byte("\n"), 2)
+	if len(parts) != 2 || string(parts[0]) != target {
+		return "", false
+	}
+	return string(parts[1]), true
+}
+
+func writeCompletionCache(kind, target, names string) {
+	err := filesystem().MkdirAll(JoinWithUserDir(".tsuru", "completion"), 0700)
+	if err != nil {
+		return
+	}
+	file, err := filesystem().OpenFile(completionCachePath(kind), syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(target + "\n" + names)
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCompletionBash(c *check.C) {
+	mngr := BuildBaseManager("tsuru-admin", "1.0", "", nil)
+	var stdout bytes.Buffer
+	context := Context{Args: []string{"bash"}, Stdout: &stdout}
+	command := completion{manager: mngr}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.IsNil)
+	script := stdout.String()
+	c.Assert(strings.Contains(script, "_tsuru_admin_complete()"), check.Equals, true)
+	c.Assert(strings.Contains(script, "complete -o default -F _tsuru_admin_complete tsuru-admin\n"), check.Equals, true)
+	c.Assert(strings.Contains(script, `$(tsuru-admin completion-list apps 2>/dev/null)`), check.Equals, true)
+	c.Assert(strings.Contains(script, " login logout "), check.Equals, true)
+	c.Assert(strings.Contains(script, "bashcompinit"), check.Equals, false)
+}
+
+func (s *S) TestCompletionZsh(c *check.C) {
+	mngr := BuildBaseManager("tsuru", "1.0", "", nil)
+	var stdout bytes.Buffer
+	context := Context{Args: []string{"zsh"}, Stdout: &stdout}
+	command := completion{manager: mngr}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.HasPrefix(stdout.String(), "autoload -U +X bashcompinit && bashcompinit\n"), check.Equals, true)
+}
+
+func (s *S) TestCompletionUnknownShell(c *check.C) {
+	context := Context{Args: []string{"fish"}}
+	command := completion{manager: globalManager}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `unsupported shell "fish", must be bash or zsh`)
+}
+
+func (s *S) TestCompletionListCached(c *check.C) {
+	home, err := ioutil.TempDir("", "tsuru-home")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(home)
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+	var calls int
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{
+			Message: `[{"service":"mysql","instances":["db1","db2"]}]`,
+			Status:  http.StatusOK,
+		},
+		CondFunc: func(req *http.Request) bool {
+			calls++
+			return req.Method == "GET" && req.URL.Path == "/1.0/services/instances"
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	for i := 0; i < 2; i++ {
+		var stdout bytes.Buffer
+		context := Context{Args: []string{"services"}, Stdout: &stdout}
+		err = (&completionList{}).Run(&context, client)
+		c.Assert(err, check.IsNil)
+		c.Assert(stdout.String(), check.Equals, "db1\ndb2\nmysql\n")
+	}
+	c.Assert(calls, check.Equals, 1)
+	os.Setenv("TSURU_TARGET", "http://otherhost")
+	var stdout bytes.Buffer
+	context := Context{Args: []string{"services"}, Stdout: &stdout}
+	err = (&completionList{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.Equals, 2)
+}
@@ -28,6 +28,11 @@ func (cmd *ConfirmationCommand) Confirm(context *Context, question string) bool
 	if cmd.yes {
 		return true
 	}
+	if noInput {
+		fmt.Fprintf(context.Stderr, "Error: %s Refusing to prompt for confirmation with --no-input, use -y to confirm.\n", question)
+		inputRequired = true
+		return false
+	}
 	fmt.Fprintf(context.Stdout, `%s (y/n) `, question)
 	var answer string
 	fmt.Fscanf(context.Stdin, "%s", &answer)
@@ -50,3 +50,21 @@ func (s *S) TestConfirmationConfirmWithFlagLong(c *check.C) {
 	c.Assert(result, check.Equals, true)
 	c.Assert(stdout.String(), check.Equals, "")
 }
+
+func (s *S) TestConfirmationConfirmNoInput(c *check.C) {
+	noInput = true
+	defer func() {
+		noInput = false
+		inputRequired = false
+	}()
+	var stdout, stderr bytes.Buffer
+	context := Context{Stdout: &stdout, Stderr: &stderr, Stdin: strings.NewReader("y\n")}
+	cmd := ConfirmationCommand{}
+	result := cmd.Confirm(&context, "Are you sure you wanna do it?")
+	c.Assert(result, check.Equals, false)
+	c.Assert(stdout.String(), check.Equals, "")
+	c.Assert(stderr.String(), check.Equals, "Error: Are you sure you wanna do it? Refusing to prompt for confirmation with --no-input, use -y to confirm.\n")
+	c.Assert(inputRequired, check.Equals, true)
+	cmd.Flags().Parse(true, []string{"-y"})
+	c.Assert(cmd.Confirm(&context, "Are you sure you wanna do it?"), check.Equals, true)
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	tsuruIo "github.com/tsuru/tsuru/io"
+)
+
+type phaseTiming struct {
+	name  string
+	start time.Time
+}
+
+// DeployProgressWriter writes the output of a deploy, replacing the phase
+// markers sent by the API with a progress indicator for each phase. Deploy
+// requests must include the tsuruIo.PhaseMarkersHeader header for the API to
+// send the markers. Summary writes how long each phase took.
+type DeployProgressWriter struct {
+	w           io.Writer
+	pending     []byte
+	atLineStart bool
+	start       time.Time
+	phases      []phaseTiming
+	now         func() time.Time
+}
+
+func NewDeployProgressWriter(w io.Writer) *DeployProgressWriter {
+	return &DeployProgressWriter{w: w, atLineStart: true, now: time.Now}
+}
+
+func (w *DeployProgressWriter) Write(p []byte) (int, error) {
+	if w.start.IsZero() {
+		w.start = w.now()
+	}
+	data := append(w.pending, p...)
+	w.pending = nil
+	prefix := []byte(tsuruIo.PhaseMarkerPrefix)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if w.atLineStart {
+			if end == 0 && (bytes.HasPrefix(data, prefix) || bytes.HasPrefix(prefix, data)) {
+				w.pending = data
+				break
+			}
+			if end > 0 {
+				if phase, ok := tsuruIo.ParsePhaseMarker(data[:end]); ok {
+					w.startPhase(phase.Name)
+					data = data[end:]
+					continue
+				}
+			}
+		}
+		if end == 0 {
+			end = len(data)
+		}
+		_, err := w.w.Write(data[:end])
+		if err != nil {
+			return len(p), err
+		}
+		w.atLineStart = data[end-1] == '\n'
+		data = data[end:]
+	}
+	return len(p), nil
+}
+
+func (w *DeployProgressWriter) startPhase(name string) {
+	w.phases = append(w.phases, phaseTiming{name: name, start: w.now()})
+	for i, phase := range tsuruIo.DeployPhases {
+		if phase == name {
+			fmt.Fprintf(w.w, "==> [%d/%d] %s\n", i+1, len(tsuruIo.DeployPhases), name)
+			return
+		}
+	}
+	fmt.Fprintf(w.w, "==> %s\n", name)
+}
+
+// Summary writes any output still buffered and how long each phase took,
+// the last phase ending now.
+func (w *DeployProgressWriter) Summary() error {
+	if len(w.pending) > 0 {
+		_, err := w.w.Write(w.pending)
+		if err != nil {
+			return err
+		}
+		w.pending = nil
+	}
+	if len(w.phases) == 0 {
+		return nil
+	}
+	end := w.now()
+	fmt.Fprintln(w.w, "\nDeploy phases:")
+	for i, phase := range w.phases {
+		phaseEnd := end
+		if i < len(w.phases)-1 {
+			phaseEnd = w.phases[i+1].start
+		}
+		fmt.Fprintf(w.w, "  %-12s %6.1fs\n", phase.name, phaseEnd.Sub(phase.start).Seconds())
+	}
+	_, err := fmt.Fprintf(w.w, "  %-12s %6.1fs\n", "total", end.Sub(w.start).Seconds())
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestDeployProgressWriter(c *check.C) {
+	var buf bytes.Buffer
+	w := NewDeployProgressWriter(&buf)
+	now := time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC)
+	w.now = func() time.Time {
+		now = now.Add(2 * time.Second)
+		return now
+	}
+	chunks := []string{
+		"#tsuru-pha",
+		`se {"name":"build"}` + "\nrunning build",
+		" commands\n#tsuru-phase {\"name\":\"distribute\"}\n",
+		" ---> Sending image\n#tsuru-phase {\"name\":\"custom\"}\n#tsuru",
+		"-phase is not a marker\n",
+		"\nOK\n",
+	}
+	for _, chunk := range chunks {
+		n, err := w.Write([]byte(chunk))
+		c.Assert(err, check.IsNil)
+		c.Assert(n, check.Equals, len(chunk))
+	}
+	err := w.Summary()
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `==> [2/5] build
+running build commands
+==> [3/5] distribute
+ ---> Sending image
+==> custom
+#tsuru-phase is not a marker
+
+OK
+
+Deploy phases:
+  build           2.0s
+  distribute      2.0s
+  custom          2.0s
+  total           8.0s
+`)
+}
+
+func (s *S) TestDeployProgressWriterNoPhases(c *check.C) {
+	var buf bytes.Buffer
+	w := NewDeployProgressWriter(&buf)
+	w.Write([]byte("deploying\n#tsuru"))
+	err := w.Summary()
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "deploying\n#tsuru")
+}
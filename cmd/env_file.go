@@ -0,0 +1,79 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnvImportOptions describes the import of a dotenv file into an app, as done
+// by env-set -f.
+type EnvImportOptions struct {
+	App string
+	// Path is the dotenv file, or "-" to read it from the standard input.
+	Path      string
+	Private   bool
+	Secret    bool
+	NoRestart bool
+}
+
+// ReadEnvFile returns the content of the dotenv file at path, reading from
+// the standard input of the context when path is "-".
+func ReadEnvFile(context *Context, path string) ([]byte, error) {
+	var r io.Reader
+	if path == "-" {
+		r = context.Stdin
+	} else {
+		file, err := filesystem().Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read env file %s", path)
+		}
+		defer file.Close()
+		r = file
+	}
+	return ioutil.ReadAll(r)
+}
+
+// ImportEnvFile sets all the variables in the dotenv file in a single request,
+// so either all of them are set or none is. The file is parsed by the API,
+// which streams a summary of the added and changed variables before applying
+// them.
+func ImportEnvFile(context *Context, client *Client, opts EnvImportOptions) error {
+	data, err := ReadEnvFile(context, opts.Path)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return errors.New("the env file is empty")
+	}
+	v := url.Values{}
+	v.Set("envs", string(data))
+	v.Set("format", "dotenv")
+	v.Set("private", strconv.FormatBool(opts.Private))
+	v.Set("secret", strconv.FormatBool(opts.Secret))
+	v.Set("noRestart", strconv.FormatBool(opts.NoRestart))
+	u, err := GetURL(fmt.Sprintf("/apps/%s/env/import", opts.App))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	return StreamJSONResponse(context.Stdout, response)
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestImportEnvFile(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: "A=1\nB=\"multi\nline\"\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout bytes.Buffer
+	context := Context{Stdout: &stdout, Stderr: &stdout}
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{
+			Message: `{"Message":"---- Importing 2 environment variables: 2 added, 0 changed, 0 unchanged ----\n"}` + "\n",
+			Status:  http.StatusOK,
+		},
+		CondFunc: func(r *http.Request) bool {
+			return r.Method == "POST" && r.URL.Path == "/1.0/apps/myapp/env/import" &&
+				r.FormValue("envs") == "A=1\nB=\"multi\nline\"\n" &&
+				r.FormValue("private") == "true" && r.FormValue("noRestart") == "false"
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := ImportEnvFile(&context, client, EnvImportOptions{App: "myapp", Path: ".env", Private: true})
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "---- Importing 2 environment variables: 2 added, 0 changed, 0 unchanged ----\n")
+}
+
+func (s *S) TestImportEnvFileFromStdin(c *check.C) {
+	var stdout bytes.Buffer
+	context := Context{Stdout: &stdout, Stderr: &stdout, Stdin: strings.NewReader("A=1\n")}
+	transport := cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: `{"Message":"ok\n"}` + "\n", Status: http.StatusOK},
+		CondFunc: func(r *http.Request) bool {
+			return r.FormValue("envs") == "A=1\n"
+		},
+	}
+	client := NewClient(&http.Client{Transport: &transport}, nil, globalManager)
+	err := ImportEnvFile(&context, client, EnvImportOptions{App: "myapp", Path: "-"})
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "ok\n")
+}
+
+func (s *S) TestImportEnvFileEmpty(c *check.C) {
+	context := Context{Stdin: strings.NewReader("\n  \n")}
+	err := ImportEnvFile(&context, nil, EnvImportOptions{App: "myapp", Path: "-"})
+	c.Assert(err, check.ErrorMatches, "the env file is empty")
+}
+
+func (s *S) TestImportEnvFileNotFound(c *check.C) {
+	fsystem = &fstest.FileNotFoundFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	err := ImportEnvFile(&Context{}, nil, EnvImportOptions{App: "myapp", Path: ".env"})
+	c.Assert(err, check.ErrorMatches, "unable to read env file .env: .*")
+}
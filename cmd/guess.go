@@ -5,8 +5,11 @@
 package cmd
 
 import (
+	"bufio"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/gnuflag"
@@ -14,6 +17,15 @@ import (
 	"github.com/tsuru/tsuru/git"
 )
 
+// AppFileName is the file that sets the name of the app deployed from a
+// directory and its subdirectories.
+const AppFileName = ".tsuru-app"
+
+var (
+	gitRemoteRegexp = regexp.MustCompile(`^.*@.*:(.*)\.git$`)
+	sshRemoteRegexp = regexp.MustCompile(`^ssh://[^@/]+@[^/]+/(.*)\.git$`)
+)
+
 // AppGuesser is used to guess the name of an app based in a file path.
 type AppGuesser interface {
 	GuessName(path string) (string, error)
@@ -22,8 +34,8 @@ type AppGuesser interface {
 // GitGuesser uses git to guess the name of the app.
 //
 // It reads the "tsuru" remote from git config file. If the remote does not
-// exist, or does not match the tsuru pattern (<user>@<somehost>:<app-name>.git),
-// GuessName will return an error.
+// exist, or does not match the tsuru pattern (<user>@<somehost>:<app-name>.git
+// or ssh://<user>@<somehost>/<app-name>.git), GuessName will return an error.
 type GitGuesser struct{}
 
 func (g GitGuesser) GuessName(path string) (string, error) {
@@ -39,14 +51,70 @@ func (g GitGuesser) GuessName(path string) (string, error) {
 	if err != nil {
 		return "", errors.New("tsuru remote not declared.")
 	}
-	re := regexp.MustCompile(`^.*@.*:(.*)\.git$`)
-	matches := re.FindStringSubmatch(remoteURL)
+	matches := sshRemoteRegexp.FindStringSubmatch(remoteURL)
+	if matches == nil {
+		matches = gitRemoteRegexp.FindStringSubmatch(remoteURL)
+	}
 	if len(matches) < 2 {
 		return "", errors.Errorf(`"tsuru" remote did not match the pattern. Want something like <user>@<host>:<app-name>.git, got %s`, remoteURL)
 	}
 	return matches[1], nil
 }
 
+// AppFileGuesser reads the name of the app from the .tsuru-app file in the
+// path or in the closest of its parent directories. The name is the first line
+// of the file that is neither blank nor a comment starting with #. The search
+// stops at the root of the git repository holding the path, so a file out of
+// the repository doesn't take precedence over its tsuru remote.
+type AppFileGuesser struct{}
+
+func (g AppFileGuesser) GuessName(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		name, err := readAppFile(filepath.Join(dir, AppFileName))
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			return "", err
+		}
+		if _, err = filesystem().Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", errors.Errorf("%s file not found in the git repository", AppFileName)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.Errorf("%s file not found", AppFileName)
+		}
+		dir = parent
+	}
+}
+
+func readAppFile(path string) (string, error) {
+	file, err := filesystem().Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.ContainsAny(line, " \t") {
+			return "", errors.Errorf("invalid app name %q in %s", line, path)
+		}
+		return line, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("no app name in %s", path)
+}
+
 // MultiGuesser can use multiple guessers
 type MultiGuesser struct {
 	Guessers []AppGuesser
@@ -73,7 +141,7 @@ type GuessingCommand struct {
 
 func (cmd *GuessingCommand) guesser() AppGuesser {
 	if cmd.G == nil {
-		cmd.G = MultiGuesser{Guessers: []AppGuesser{GitGuesser{}}}
+		cmd.G = MultiGuesser{Guessers: []AppGuesser{AppFileGuesser{}, GitGuesser{}}}
 	}
 	return cmd.G
 }
@@ -6,6 +6,7 @@ package cmd
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
@@ -80,6 +81,14 @@ func (s *S) TestGitGuesserNonGitUser(c *check.C) {
 	c.Assert(name, check.Equals, "gopher")
 }
 
+func (s *S) TestGitGuesserSSHURL(c *check.C) {
+	p := writeConfig("testdata/gitconfig-ok-ssh", c)
+	defer os.RemoveAll(p)
+	name, err := GitGuesser{}.GuessName(p)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "gopher")
+}
+
 // This test may fail if you have a git repository in /tmp. By the way, if you
 // do have a repository in the temporary file hierarchy, please kill yourself.
 func (s *S) TestGitGuesserWhenTheDirectoryIsNotAGitRepository(c *check.C) {
@@ -111,6 +120,81 @@ func (s *S) TestGitGuesserWithTsuruRemoteNotMatchingTsuruPattern(c *check.C) {
 	c.Assert(err.Error(), check.Equals, `"tsuru" remote did not match the pattern. Want something like <user>@<host>:<app-name>.git, got git://myhost.com/gopher.git`)
 }
 
+func (s *S) TestAppFileGuesser(c *check.C) {
+	p := path.Join(os.TempDir(), "guesser-tests")
+	dirPath := path.Join(p, "somepath", "subdir")
+	err := os.MkdirAll(dirPath, 0700)
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(p)
+	err = ioutil.WriteFile(path.Join(p, AppFileName), []byte("# deployed by CI\n\n  gopher  \n"), 0644)
+	c.Assert(err, check.IsNil)
+	g := AppFileGuesser{}
+	name, err := g.GuessName(p)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "gopher")
+	name, err = g.GuessName(dirPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "gopher")
+	err = ioutil.WriteFile(path.Join(p, "somepath", AppFileName), []byte("otherapp\n"), 0644)
+	c.Assert(err, check.IsNil)
+	name, err = g.GuessName(dirPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "otherapp")
+}
+
+func (s *S) TestAppFileGuesserEmptyFile(c *check.C) {
+	p := path.Join(os.TempDir(), "guesser-tests")
+	err := os.MkdirAll(p, 0700)
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(p)
+	err = ioutil.WriteFile(path.Join(p, AppFileName), []byte("# nothing here\n"), 0644)
+	c.Assert(err, check.IsNil)
+	name, err := AppFileGuesser{}.GuessName(p)
+	c.Assert(name, check.Equals, "")
+	c.Assert(err, check.ErrorMatches, "no app name in .*/.tsuru-app")
+}
+
+func (s *S) TestAppFileGuesserInvalidName(c *check.C) {
+	p := path.Join(os.TempDir(), "guesser-tests")
+	err := os.MkdirAll(p, 0700)
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(p)
+	err = ioutil.WriteFile(path.Join(p, AppFileName), []byte("my app\n"), 0644)
+	c.Assert(err, check.IsNil)
+	_, err = AppFileGuesser{}.GuessName(p)
+	c.Assert(err, check.ErrorMatches, `invalid app name "my app" in .*`)
+}
+
+func (s *S) TestAppFileGuesserStopsAtRepositoryRoot(c *check.C) {
+	p := path.Join(os.TempDir(), "guesser-tests")
+	dirPath := path.Join(p, "repo", "subdir")
+	err := os.MkdirAll(dirPath, 0700)
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(p)
+	err = os.Mkdir(path.Join(p, "repo", ".git"), 0700)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(path.Join(p, AppFileName), []byte("otherapp\n"), 0644)
+	c.Assert(err, check.IsNil)
+	_, err = AppFileGuesser{}.GuessName(dirPath)
+	c.Assert(err, check.ErrorMatches, "[.]tsuru-app file not found in the git repository")
+	err = ioutil.WriteFile(path.Join(p, "repo", AppFileName), []byte("gopher\n"), 0644)
+	c.Assert(err, check.IsNil)
+	name, err := AppFileGuesser{}.GuessName(dirPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "gopher")
+}
+
+func (s *S) TestAppFileGuesserTakesPrecedenceOverGit(c *check.C) {
+	p := writeConfig("testdata/gitconfig-ok", c)
+	defer os.RemoveAll(p)
+	err := ioutil.WriteFile(path.Join(p, AppFileName), []byte("otherapp\n"), 0644)
+	c.Assert(err, check.IsNil)
+	g := GuessingCommand{}
+	name, err := g.guesser().GuessName(p)
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "otherapp")
+}
+
 func (s *S) TestGuessingCommandGuesserNil(c *check.C) {
 	g := GuessingCommand{G: nil}
 	c.Assert(g.guesser(), check.FitsTypeOf, MultiGuesser{})
@@ -0,0 +1,47 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import "github.com/pkg/errors"
+
+// Exit codes of the tsuru clients. Scripts can rely on them, so they must not
+// change between releases.
+const (
+	// ExitOK means the command succeeded, or was aborted by the user in an
+	// interactive confirmation prompt.
+	ExitOK = 0
+	// ExitError means the command failed, was not found or was called with
+	// invalid arguments or flags.
+	ExitError = 1
+	// ExitUsage means the global flags, given before the command name, are
+	// invalid.
+	ExitUsage = 2
+	// ExitInputRequired means the command needs a confirmation but the client
+	// is running with --no-input and -y was not given, or the command needs
+	// other input, such as the password in login, that can't be given
+	// without a prompt.
+	ExitInputRequired = 3
+)
+
+// noInputEnv is the environment variable that enables the non-interactive
+// mode, the same as the global --no-input flag.
+const noInputEnv = "TSURU_NO_INPUT"
+
+// noInput is set by the global --no-input flag, making the client suitable
+// for scripts and CI: confirmation prompts fail instead of reading from the
+// standard input, and both the pager and colors are disabled.
+var noInput bool
+
+// inputRequired is set when a command refuses to prompt for confirmation in
+// the non-interactive mode, so the manager exits with ExitInputRequired.
+var inputRequired bool
+
+// errInputRequired returns the error of a command that can't run without
+// prompting for the given input in the non-interactive mode, making the
+// manager exit with ExitInputRequired.
+func errInputRequired(input string) error {
+	inputRequired = true
+	return errors.Errorf("%s must be typed in a prompt, refusing to prompt with --no-input", input)
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/gnuflag"
+)
+
+// jsonOutput is set by the global --json flag, making every OutputCommand
+// write JSON.
+var jsonOutput bool
+
+// OutputCommand adds the --json and --format flags to commands that display
+// data, so scripts don't have to parse tables. Commands embedding it call
+// Render before writing their default output.
+type OutputCommand struct {
+	json   bool
+	format string
+	fs     *gnuflag.FlagSet
+}
+
+func (cmd *OutputCommand) Flags() *gnuflag.FlagSet {
+	if cmd.fs == nil {
+		cmd.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		cmd.fs.BoolVar(&cmd.json, "json", false, "Display the output as JSON.")
+		cmd.fs.StringVar(&cmd.format, "format", "", "Format the output using the given Go template. For lists, the template is applied to each item.")
+	}
+	return cmd.fs
+}
+
+// Render writes data as JSON or formatted with the template given in
+// --format. It returns false, writing nothing, when none of the flags were
+// given, meaning the command should write its default output.
+func (cmd *OutputCommand) Render(w io.Writer, data interface{}) (bool, error) {
+	if cmd.format != "" {
+		tmpl, err := template.New("format").Parse(cmd.format)
+		if err != nil {
+			return true, errors.Wrap(err, "invalid format")
+		}
+		value := reflect.ValueOf(data)
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			return true, executeFormat(w, tmpl, data)
+		}
+		for i := 0; i < value.Len(); i++ {
+			err = executeFormat(w, tmpl, value.Index(i).Interface())
+			if err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+	if cmd.json || jsonOutput {
+		out, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return true, err
+		}
+		_, err = w.Write(append(out, '\n'))
+		return true, err
+	}
+	return false, nil
+}
+
+func executeFormat(w io.Writer, tmpl *template.Template, data interface{}) error {
+	err := tmpl.Execute(w, data)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
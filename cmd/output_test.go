@@ -0,0 +1,79 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+
+	"gopkg.in/check.v1"
+)
+
+type outputItem struct {
+	Name  string `json:"name"`
+	Units int    `json:"units"`
+}
+
+func (s *S) TestOutputCommandRenderDefault(c *check.C) {
+	var buf bytes.Buffer
+	command := OutputCommand{}
+	command.Flags().Parse(true, []string{})
+	rendered, err := command.Render(&buf, []outputItem{{Name: "app1", Units: 1}})
+	c.Assert(err, check.IsNil)
+	c.Assert(rendered, check.Equals, false)
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestOutputCommandRenderJSON(c *check.C) {
+	var buf bytes.Buffer
+	command := OutputCommand{}
+	command.Flags().Parse(true, []string{"--json"})
+	rendered, err := command.Render(&buf, []outputItem{{Name: "app1", Units: 1}})
+	c.Assert(err, check.IsNil)
+	c.Assert(rendered, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, `[
+  {
+    "name": "app1",
+    "units": 1
+  }
+]
+`)
+}
+
+func (s *S) TestOutputCommandRenderGlobalJSON(c *check.C) {
+	jsonOutput = true
+	defer func() {
+		jsonOutput = false
+	}()
+	var buf bytes.Buffer
+	command := OutputCommand{}
+	rendered, err := command.Render(&buf, outputItem{Name: "app1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rendered, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, "{\n  \"name\": \"app1\",\n  \"units\": 0\n}\n")
+}
+
+func (s *S) TestOutputCommandRenderFormat(c *check.C) {
+	var buf bytes.Buffer
+	command := OutputCommand{}
+	command.Flags().Parse(true, []string{"--format", "{{.Name}}: {{.Units}}"})
+	rendered, err := command.Render(&buf, []outputItem{{Name: "app1", Units: 1}, {Name: "app2", Units: 3}})
+	c.Assert(err, check.IsNil)
+	c.Assert(rendered, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, "app1: 1\napp2: 3\n")
+	buf.Reset()
+	rendered, err = command.Render(&buf, outputItem{Name: "app3", Units: 2})
+	c.Assert(err, check.IsNil)
+	c.Assert(rendered, check.Equals, true)
+	c.Assert(buf.String(), check.Equals, "app3: 2\n")
+}
+
+func (s *S) TestOutputCommandRenderInvalidFormat(c *check.C) {
+	var buf bytes.Buffer
+	command := OutputCommand{}
+	command.Flags().Parse(true, []string{"--format", "{{.Name"})
+	rendered, err := command.Render(&buf, outputItem{Name: "app1"})
+	c.Assert(rendered, check.Equals, true)
+	c.Assert(err, check.ErrorMatches, "invalid format: .*")
+}
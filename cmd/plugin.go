@@ -0,0 +1,180 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/exec"
+)
+
+const pluginPrefix = "tsuru-"
+
+func pluginsDir() string {
+	return JoinWithUserDir(".tsuru", "plugins")
+}
+
+// validatePluginName ensures the name of the plugin can't be used to reach
+// files outside of the plugins directory.
+func validatePluginName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return errors.Errorf("Invalid plugin name %q.", name)
+	}
+	return nil
+}
+
+// findPlugin returns the path to the executable of the plugin, looking
+// first in the plugins directory, where plugin-install puts them, and then
+// for an executable named tsuru-<name> in the PATH.
+func findPlugin(name string) (string, error) {
+	if err := validatePluginName(name); err != nil {
+		return "", err
+	}
+	pluginPath := JoinWithUserDir(".tsuru", "plugins", name)
+	if _, err := filesystem().Stat(pluginPath); err == nil {
+		return pluginPath, nil
+	}
+	return osexec.LookPath(pluginPrefix + name)
+}
+
+// PluginLookup is a Lookup that runs plugins, executables invoked as
+// commands of the client. Plugins receive the remaining arguments, with the
+// current target and token in the TSURU_TARGET and TSURU_TOKEN environment
+// variables.
+func PluginLookup(context *Context) error {
+	if len(context.Args) == 0 {
+		return ErrLookup
+	}
+	pluginName := context.Args[0]
+	pluginPath, err := findPlugin(pluginName)
+	if err != nil {
+		return ErrLookup
+	}
+	target, err := GetTarget()
+	if err != nil {
+		return err
+	}
+	token, err := ReadToken()
+	if err != nil {
+		return err
+	}
+	envs := append(os.Environ(),
+		"TSURU_TARGET="+target,
+		"TSURU_TOKEN="+token,
+		"TSURU_PLUGIN_NAME="+pluginName,
+	)
+	opts := exec.ExecuteOptions{
+		Cmd:    pluginPath,
+		Args:   context.Args[1:],
+		Envs:   envs,
+		Stdin:  context.Stdin,
+		Stdout: context.Stdout,
+		Stderr: context.Stderr,
+	}
+	return executor().Execute(opts)
+}
+
+type pluginInstall struct{}
+
+func (c *pluginInstall) Info() *Info {
+	return &Info{
+		Name:    "plugin-install",
+		Usage:   "plugin-install <plugin-name> <plugin-url>",
+		Desc:    "Downloads the plugin file. It will be placed in ~/.tsuru/plugins and run as a command of the client.",
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *pluginInstall) Run(context *Context, client *Client) error {
+	pluginName := context.Args[0]
+	pluginURL := context.Args[1]
+	err := validatePluginName(pluginName)
+	if err != nil {
+		return err
+	}
+	err = filesystem().MkdirAll(pluginsDir(), 0755)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Get(pluginURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Invalid status code reading plugin: %d - %q", resp.StatusCode, resp.Status)
+	}
+	pluginPath := JoinWithUserDir(".tsuru", "plugins", pluginName)
+	file, err := filesystem().OpenFile(pluginPath, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	n, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("Failed to install plugin.")
+	}
+	fmt.Fprintf(context.Stdout, "Plugin %q successfully installed!\n", pluginName)
+	return nil
+}
+
+type pluginRemove struct{}
+
+func (c *pluginRemove) Info() *Info {
+	return &Info{
+		Name:    "plugin-remove",
+		Usage:   "plugin-remove <plugin-name>",
+		Desc:    "Removes a previously installed plugin.",
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *pluginRemove) Run(context *Context, client *Client) error {
+	pluginName := context.Args[0]
+	err := validatePluginName(pluginName)
+	if err != nil {
+		return err
+	}
+	err = filesystem().Remove(JoinWithUserDir(".tsuru", "plugins", pluginName))
+	if err != nil {
+		return errors.Errorf("Failed to remove plugin %q: %s", pluginName, err)
+	}
+	fmt.Fprintf(context.Stdout, "Plugin %q successfully removed!\n", pluginName)
+	return nil
+}
+
+type pluginList struct{}
+
+func (c *pluginList) Info() *Info {
+	return &Info{
+		Name:  "plugin-list",
+		Usage: "plugin-list",
+		Desc:  "List installed plugins.",
+	}
+}
+
+func (c *pluginList) Run(context *Context, client *Client) error {
+	files, err := ioutil.ReadDir(pluginsDir())
+	if err != nil {
+		return nil
+	}
+	for _, f := range files {
+		fmt.Fprintln(context.Stdout, f.Name())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/tsuru/tsuru/exec/exectest"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPluginLookup(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	f, err := rfs.Create(JoinWithUserDir(".tsuru", "plugins", "myplugin"))
+	c.Assert(err, check.IsNil)
+	f.Close()
+	fexec := exectest.FakeExecutor{}
+	execut = &fexec
+	defer func() {
+		execut = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := Context{Args: []string{"myplugin", "arg1", "arg2"}, Stdout: &stdout, Stderr: &stderr}
+	err = PluginLookup(&context)
+	c.Assert(err, check.IsNil)
+	pluginPath := JoinWithUserDir(".tsuru", "plugins", "myplugin")
+	c.Assert(fexec.ExecutedCmd(pluginPath, []string{"arg1", "arg2"}), check.Equals, true)
+	commands := fexec.GetCommands(pluginPath)
+	c.Assert(commands, check.HasLen, 1)
+	c.Assert(commands[0].GetEnvs(), check.DeepEquals, append(os.Environ(),
+		"TSURU_TARGET=http://localhost",
+		"TSURU_TOKEN=abc123",
+		"TSURU_PLUGIN_NAME=myplugin",
+	))
+}
+
+func (s *S) TestPluginLookupNotFound(c *check.C) {
+	fsystem = &fstest.RecordingFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	fexec := exectest.FakeExecutor{}
+	execut = &fexec
+	defer func() {
+		execut = nil
+	}()
+	context := Context{Args: []string{"unknown-plugin-xyz"}}
+	err := PluginLookup(&context)
+	c.Assert(err, check.Equals, ErrLookup)
+	c.Assert(fexec.GetCommands(JoinWithUserDir(".tsuru", "plugins", "unknown-plugin-xyz")), check.HasLen, 0)
+}
+
+func (s *S) TestPluginLookupInvalidName(c *check.C) {
+	fsystem = &fstest.RecordingFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	fexec := exectest.FakeExecutor{}
+	execut = &fexec
+	defer func() {
+		execut = nil
+	}()
+	context := Context{Args: []string{"../../bin/sh"}}
+	err := PluginLookup(&context)
+	c.Assert(err, check.Equals, ErrLookup)
+}
+
+func (s *S) TestPluginInstallInvalidName(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	for _, name := range []string{"../evil", "sub/plugin", `sub\plugin`, ".."} {
+		context := Context{Args: []string{name, "http://localhost/plugin"}}
+		command := pluginInstall{}
+		err := command.Run(&context, nil)
+		c.Assert(err, check.ErrorMatches, "Invalid plugin name .*")
+	}
+	c.Assert(rfs.HasAction(fmt.Sprintf("mkdirall %s with mode 0755", pluginsDir())), check.Equals, false)
+}
+
+func (s *S) TestPluginRemoveInvalidName(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	context := Context{Args: []string{"../../.bashrc"}}
+	command := pluginRemove{}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "Invalid plugin name .*")
+	c.Assert(rfs.HasAction("remove "+JoinWithUserDir(".tsuru", "plugins", "../../.bashrc")), check.Equals, false)
+}
+
+func (s *S) TestPluginInstall(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "fakeplugin")
+	}))
+	defer ts.Close()
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout bytes.Buffer
+	context := Context{Args: []string{"myplugin", ts.URL}, Stdout: &stdout}
+	command := pluginInstall{}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.IsNil)
+	pluginsPath := JoinWithUserDir(".tsuru", "plugins")
+	c.Assert(rfs.HasAction(fmt.Sprintf("mkdirall %s with mode 0755", pluginsPath)), check.Equals, true)
+	pluginPath := JoinWithUserDir(".tsuru", "plugins", "myplugin")
+	c.Assert(rfs.HasAction(fmt.Sprintf("openfile %s with mode 0755", pluginPath)), check.Equals, true)
+	f, err := rfs.Open(pluginPath)
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "fakeplugin\n")
+	c.Assert(stdout.String(), check.Equals, `Plugin "myplugin" successfully installed!`+"\n")
+}
+
+func (s *S) TestPluginRemove(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	pluginPath := JoinWithUserDir(".tsuru", "plugins", "myplugin")
+	f, err := rfs.Create(pluginPath)
+	c.Assert(err, check.IsNil)
+	f.Close()
+	var stdout bytes.Buffer
+	context := Context{Args: []string{"myplugin"}, Stdout: &stdout}
+	command := pluginRemove{}
+	err = command.Run(&context, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(rfs.HasAction(fmt.Sprintf("remove %s", pluginPath)), check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, `Plugin "myplugin" successfully removed!`+"\n")
+}
+
+func (s *S) TestPluginCommandsAreRegistered(c *check.C) {
+	mngr := BuildBaseManager("tsuru", "1.0", "", nil)
+	c.Assert(mngr.Commands["plugin-install"], check.FitsTypeOf, &pluginInstall{})
+	c.Assert(mngr.Commands["plugin-remove"], check.FitsTypeOf, &pluginRemove{})
+	c.Assert(mngr.Commands["plugin-list"], check.FitsTypeOf, &pluginList{})
+}
@@ -260,6 +260,15 @@ func (s *S) TestColorBoldYellowGreenBG(c *check.C) {
 	c.Assert(output, check.Equals, "\033[1;33;42mmust return a bold yellow with green background\033[0m")
 }
 
+func (s *S) TestColorNoInput(c *check.C) {
+	noInput = true
+	defer func() {
+		noInput = false
+	}()
+	output := Colorfy("must not return a color pattern", "red", "", "bold")
+	c.Assert(output, check.Equals, "must not return a color pattern")
+}
+
 func (s *S) TestResizeLastColumn(c *check.C) {
 	t := NewTable()
 	t.AddRow(Row{"1", "abcdefghijk"})
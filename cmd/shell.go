@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"syscall"
 
+	"github.com/tsuru/gnuflag"
 	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/net/websocket"
 )
@@ -23,19 +24,32 @@ var httpRegexp = regexp.MustCompile(`^http`)
 
 type ShellToContainerCmd struct {
 	GuessingCommand
+	fs   *gnuflag.FlagSet
+	unit string
 }
 
 func (c *ShellToContainerCmd) Info() *Info {
 	return &Info{
 		Name:  "app-shell",
-		Usage: "app-shell [unit-id] -a/--app <appname>",
+		Usage: "app-shell [unit-id] -a/--app <appname> [-u/--unit <unit-id>]",
 		Desc: `Opens a remote shell inside unit, using the API server as a proxy. You
 can access an app unit just giving app name, or specifying the id of the unit.
-You can get the ID of the unit using the app-info command.`,
+You can get the ID of the unit using the app-info command.
+
+Changes in the size of the local terminal are forwarded to the remote shell.`,
 		MinArgs: 0,
 	}
 }
 
+func (c *ShellToContainerCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.StringVar(&c.unit, "unit", "", "The ID of the unit.")
+		c.fs.StringVar(&c.unit, "u", "", "The ID of the unit.")
+	}
+	return c.fs
+}
+
 func (c *ShellToContainerCmd) Run(context *Context, client *Client) error {
 	appName, err := c.Guess()
 	if err != nil {
@@ -52,31 +66,34 @@ func (c *ShellToContainerCmd) Run(context *Context, client *Client) error {
 	}
 	context.RawOutput()
 	var width, height int
-	if desc, ok := context.Stdin.(descriptable); ok {
-		fd := int(desc.Fd())
-		if terminal.IsTerminal(fd) {
-			width, height, _ = terminal.GetSize(fd)
-			oldState, terminalErr := terminal.MakeRaw(fd)
-			if terminalErr != nil {
-				return err
-			}
-			defer terminal.Restore(fd, oldState)
-			sigChan := make(chan os.Signal, 2)
-			go func(c <-chan os.Signal) {
-				if _, ok := <-c; ok {
-					terminal.Restore(fd, oldState)
-					os.Exit(1)
-				}
-			}(sigChan)
-			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGQUIT)
+	fd := -1
+	if desc, ok := context.Stdin.(descriptable); ok && terminal.IsTerminal(int(desc.Fd())) {
+		fd = int(desc.Fd())
+		width, height, _ = terminal.GetSize(fd)
+		oldState, terminalErr := terminal.MakeRaw(fd)
+		if terminalErr != nil {
+			return err
 		}
+		defer terminal.Restore(fd, oldState)
+		sigChan := make(chan os.Signal, 2)
+		go func(c <-chan os.Signal) {
+			if _, ok := <-c; ok {
+				terminal.Restore(fd, oldState)
+				os.Exit(1)
+			}
+		}(sigChan)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGQUIT)
 	}
 	queryString := make(url.Values)
 	queryString.Set("width", strconv.Itoa(width))
 	queryString.Set("height", strconv.Itoa(height))
-	if len(context.Args) > 0 {
-		queryString.Set("unit", context.Args[0])
-		queryString.Set("container_id", context.Args[0])
+	unit := c.unit
+	if unit == "" && len(context.Args) > 0 {
+		unit = context.Args[0]
+	}
+	if unit != "" {
+		queryString.Set("unit", unit)
+		queryString.Set("container_id", unit)
 	}
 	if term := os.Getenv("TERM"); term != "" {
 		queryString.Set("term", term)
@@ -99,6 +116,10 @@ func (c *ShellToContainerCmd) Run(context *Context, client *Client) error {
 		return err
 	}
 	defer conn.Close()
+	if fd >= 0 {
+		stop := forwardTerminalResize(fd, conn)
+		defer stop()
+	}
 	errs := make(chan error, 2)
 	quit := make(chan bool)
 	go io.Copy(conn, context.Stdin)
@@ -0,0 +1,43 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// forwardTerminalResize writes the resize sequence understood by the remote
+// shell handler to conn whenever the terminal is resized. The returned
+// function stops watching the terminal.
+func forwardTerminalResize(fd int, conn io.Writer) func() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigChan:
+				width, height, err := terminal.GetSize(fd)
+				if err == nil {
+					fmt.Fprintf(conn, "\x1b[8;%d;%dt", height, width)
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigChan)
+		close(done)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import "io"
+
+// forwardTerminalResize is a no-op on Windows, where there's no signal
+// notifying changes in the size of the terminal.
+func forwardTerminalResize(fd int, conn io.Writer) func() {
+	return func() {}
+}
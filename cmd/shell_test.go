@@ -103,6 +103,36 @@ func (s *S) TestShellToContainerWithUnit(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, "hello my friend\nglad to see you here\n")
 }
 
+func (s *S) TestShellToContainerWithUnitFlag(c *check.C) {
+	transport := cmdtest.Transport{Message: "", Status: http.StatusOK}
+	guesser := cmdtest.FakeGuesser{Name: "myapp"}
+	units := make(chan string, 1)
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		units <- conn.Request().URL.Query().Get("unit")
+		conn.Write([]byte("hello"))
+		conn.Close()
+	}))
+	defer server.Close()
+	os.Setenv("TSURU_TARGET", "http://"+server.Listener.Addr().String())
+	defer os.Unsetenv("TSURU_TARGET")
+	var stdout, stderr, stdin bytes.Buffer
+	context := Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  &stdin,
+	}
+	var command ShellToContainerCmd
+	command.GuessingCommand = GuessingCommand{G: &guesser}
+	err := command.Flags().Parse(true, []string{"-a", "myapp", "-u", "containerid"})
+	c.Assert(err, check.IsNil)
+	mngr := NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := NewClient(&http.Client{Transport: &transport}, &context, mngr)
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(<-units, check.Equals, "containerid")
+	c.Assert(stdout.String(), check.Equals, "hello")
+}
+
 func (s *S) TestShellToContainerCmdConnectionRefused(c *check.C) {
 	var buf bytes.Buffer
 	transport := cmdtest.ConditionalTransport{
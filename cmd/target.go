@@ -22,6 +22,8 @@ var errUndefinedTarget = errors.New(`No target defined. Please use target-add/ta
 
 For more details, please run "tsuru help target".`)
 
+var errInvalidTargetLabel = errors.New(`Invalid target label, it must not be "." or ".." nor contain path separators.`)
+
 type tsuruTarget struct {
 	label, url string
 }
@@ -121,6 +123,78 @@ func deleteTargetFile() {
 	filesystem().Remove(JoinWithUserDir(".tsuru", "target"))
 }
 
+// targetTokenPath returns the path of the file keeping the token of the
+// target with the given label, so users can switch between targets without
+// logging in again. Labels that can't be used as a file name in the token.d
+// directory are rejected.
+func targetTokenPath(label string) (string, error) {
+	if !validTargetLabel(label) {
+		return "", errInvalidTargetLabel
+	}
+	return JoinWithUserDir(".tsuru", "token.d", label), nil
+}
+
+func validTargetLabel(label string) bool {
+	return label != "" && label != "." && label != ".." && !strings.ContainsAny(label, `/\`)
+}
+
+// currentTargetLabel returns the label of the current target in the target
+// list, or an empty string if the current target isn't in the list.
+func currentTargetLabel() string {
+	current, err := ReadTarget()
+	if err != nil {
+		return ""
+	}
+	targets, err := getTargets()
+	if err != nil {
+		return ""
+	}
+	for label, url := range targets {
+		if url == current {
+			return label
+		}
+	}
+	return ""
+}
+
+func writeTargetToken(label, token string) error {
+	tokenPath, err := targetTokenPath(label)
+	if err != nil {
+		return err
+	}
+	err = filesystem().MkdirAll(JoinWithUserDir(".tsuru", "token.d"), 0700)
+	if err != nil {
+		return err
+	}
+	file, err := filesystem().OpenFile(tokenPath, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	n, err := file.WriteString(token)
+	if n != len(token) || err != nil {
+		return errors.New("Failed to write the token file")
+	}
+	return nil
+}
+
+func readTargetToken(label string) (string, error) {
+	tokenPath, err := targetTokenPath(label)
+	if err != nil {
+		return "", err
+	}
+	file, err := filesystem().Open(tokenPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	token, err := ioutil.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
 func GetTarget() (string, error) {
 	var prefix string
 	target, err := ReadTarget()
@@ -182,6 +256,9 @@ func (t *targetAdd) Run(ctx *Context, client *Client) error {
 	}
 	label = ctx.Args[0]
 	target = ctx.Args[1]
+	if !validTargetLabel(label) {
+		return errInvalidTargetLabel
+	}
 	err := WriteOnTargetList(label, target)
 	if err != nil {
 		return err
@@ -366,6 +443,9 @@ func (t *targetRemove) Run(ctx *Context, client *Client) error {
 		if current, err = ReadTarget(); err == nil && current == turl {
 			deleteTargetFile()
 		}
+		if tokenPath, err := targetTokenPath(targetLabelToRemove); err == nil {
+			filesystem().Remove(tokenPath)
+		}
 	}
 	err = resetTargetList()
 	if err != nil {
@@ -380,7 +460,8 @@ func (t *targetRemove) Run(ctx *Context, client *Client) error {
 type targetSet struct{}
 
 func (t *targetSet) Info() *Info {
-	desc := `Change current target (tsuru server)
+	desc := `Change current target (tsuru server). Each target keeps its own
+token, so switching back to a target doesn't require logging in again.
 `
 	return &Info{
 		Name:    "target-set",
@@ -403,14 +484,43 @@ func (t *targetSet) Run(ctx *Context, client *Client) error {
 	if err != nil {
 		return err
 	}
+	if currentLabel := currentTargetLabel(); currentLabel != "" {
+		if _, err = readTargetToken(currentLabel); err != nil {
+			if token, err := readTokenFile(); err == nil && token != "" {
+				writeTargetToken(currentLabel, token)
+			}
+		}
+	}
 	for label, target := range targets {
 		if label == targetLabelToSet {
 			err = WriteTarget(target)
 			if err != nil {
 				return err
 			}
+			err = switchTargetToken(label)
+			if err != nil {
+				return err
+			}
 			fmt.Fprintf(ctx.Stdout, "New target is %s -> %s\n", label, target)
 		}
 	}
 	return nil
 }
+
+// switchTargetToken makes the token saved for the target the current token,
+// removing the current token when there's no token saved for the target.
+func switchTargetToken(label string) error {
+	tokenPath := JoinWithUserDir(".tsuru", "token")
+	token, err := readTargetToken(label)
+	if err != nil {
+		filesystem().Remove(tokenPath)
+		return nil
+	}
+	file, err := filesystem().OpenFile(tokenPath, syscall.O_WRONLY|syscall.O_CREAT|syscall.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(token)
+	return err
+}
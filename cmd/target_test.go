@@ -249,6 +249,19 @@ func (s *S) TestTargetAddRunOnlyOneArg(c *check.C) {
 	c.Assert(err.Error(), check.Equals, "Invalid arguments")
 }
 
+func (s *S) TestTargetAddRunInvalidLabel(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	context := &Context{[]string{"../evil", "http://tsuru.google.com"}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	targetAdd := &targetAdd{}
+	err := targetAdd.Run(context, nil)
+	c.Assert(err, check.Equals, errInvalidTargetLabel)
+	c.Assert(rfs.HasAction("openfile "+JoinWithUserDir(".tsuru", "targets")+" with mode 0600"), check.Equals, false)
+}
+
 func (s *S) TestTargetAddWithSet(c *check.C) {
 	os.Unsetenv("TSURU_TARGET")
 	rfs := &fstest.RecordingFs{FileContent: "old\thttp://tsuru.io"}
@@ -482,7 +495,8 @@ func (s *S) TestTargetRemoveCurrentTarget(c *check.C) {
 }
 
 func (s *S) TestTargetSetInfo(c *check.C) {
-	desc := `Change current target (tsuru server)
+	desc := `Change current target (tsuru server). Each target keeps its own
+token, so switching back to a target doesn't require logging in again.
 `
 	expected := &Info{
 		Name:    "target-set",
@@ -508,6 +522,81 @@ func (s *S) TestTargetSetRun(c *check.C) {
 	c.Assert(strings.Contains(got, "New target is default -> http://tsuru.google.com\n"), check.Equals, true)
 }
 
+func (s *S) TestTargetSetRunKeepsTokenPerTarget(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	os.Unsetenv("TSURU_TARGET")
+	os.Unsetenv("TSURU_TOKEN")
+	err := WriteOnTargetList("dev", "http://dev.tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = WriteOnTargetList("prod", "http://prod.tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = WriteTarget("http://dev.tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = writeToken("dev-token")
+	c.Assert(err, check.IsNil)
+	context := &Context{[]string{"prod"}, globalManager.stdout, globalManager.stderr, globalManager.stdin}
+	err = (&targetSet{}).Run(context, nil)
+	c.Assert(err, check.IsNil)
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "")
+	err = writeToken("prod-token")
+	c.Assert(err, check.IsNil)
+	context.Args = []string{"dev"}
+	err = (&targetSet{}).Run(context, nil)
+	c.Assert(err, check.IsNil)
+	token, err = ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "dev-token")
+	context.Args = []string{"prod"}
+	err = (&targetSet{}).Run(context, nil)
+	c.Assert(err, check.IsNil)
+	token, err = ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "prod-token")
+	c.Assert(rfs.HasAction("openfile "+JoinWithUserDir(".tsuru", "token.d", "dev")+" with mode 0600"), check.Equals, true)
+	c.Assert(rfs.HasAction("openfile "+JoinWithUserDir(".tsuru", "token.d", "prod")+" with mode 0600"), check.Equals, true)
+}
+
+func (s *S) TestTargetTokenPathInvalidLabel(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	for _, label := range []string{"", ".", "..", "../token", "a/b", `a\b`} {
+		_, err := targetTokenPath(label)
+		c.Check(err, check.Equals, errInvalidTargetLabel, check.Commentf("label %q", label))
+		err = writeTargetToken(label, "mytoken")
+		c.Check(err, check.Equals, errInvalidTargetLabel, check.Commentf("label %q", label))
+	}
+	c.Assert(rfs.HasAction("mkdirall "+JoinWithUserDir(".tsuru", "token.d")+" with mode 0700"), check.Equals, false)
+}
+
+func (s *S) TestWriteTokenWithInvalidTargetLabel(c *check.C) {
+	rfs := &fstest.RecordingFs{}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	os.Unsetenv("TSURU_TARGET")
+	os.Unsetenv("TSURU_TOKEN")
+	err := WriteOnTargetList("..", "http://dev.tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = WriteTarget("http://dev.tsuru.io")
+	c.Assert(err, check.IsNil)
+	err = writeToken("mytoken")
+	c.Assert(err, check.IsNil)
+	token, err := ReadToken()
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "mytoken")
+	c.Assert(rfs.HasAction("mkdirall "+JoinWithUserDir(".tsuru", "token.d")+" with mode 0700"), check.Equals, false)
+}
+
 func (s *S) TestTargetSetRunUnknowTarget(c *check.C) {
 	rfs := &fstest.RecordingFs{FileContent: "first\thttp://tsuru.io/\ndefault\thttp://tsuru.google.com"}
 	fsystem = rfs
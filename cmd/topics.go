@@ -9,6 +9,8 @@ const targetTopic = `In tsuru, a target is the address of the remote tsuru serve
 Each target is identified by a label and a HTTP/HTTPS address. The client
 requires at least one target to connect to, there's no default target. A user
 may have multiple targets, but he/she will be able to use only per session.
+The client keeps the token of each target, so users don't have to log in again
+when switching between targets.
 
 The following commands are used to manage targets in the client:
 
@@ -25,6 +25,7 @@ func (c *apiCmd) Run(context *cmd.Context, client *cmd.Client) error {
 		checkGandalf,
 		checkPubSub,
 		checkQueue,
+		checkCORS,
 	}, context.Stderr)
 	if err != nil {
 		return err
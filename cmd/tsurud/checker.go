@@ -58,6 +58,20 @@ func checkPubSub() error {
 	return nil
 }
 
+func checkCORS() error {
+	credentials, _ := config.GetBool("server:cors:allow-credentials")
+	if !credentials {
+		return nil
+	}
+	origins, _ := config.GetList("server:cors:allowed-origins")
+	for _, o := range origins {
+		if o == "*" {
+			return errors.New(`config error: "server:cors:allowed-origins" must list explicit origins when "server:cors:allow-credentials" is enabled`)
+		}
+	}
+	return nil
+}
+
 func checkQueue() error {
 	queueConfig, _ := config.GetString("queue:mongo-url")
 	if queueConfig == "" {
@@ -112,6 +112,19 @@ func (s *CheckerSuite) TestCheckGandalfSuccessRepoManagerDefined(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *CheckerSuite) TestCheckCORS(c *check.C) {
+	config.Set("server:cors:allowed-origins", []interface{}{"*"})
+	defer config.Unset("server:cors")
+	err := checkCORS()
+	c.Assert(err, check.IsNil)
+	config.Set("server:cors:allow-credentials", true)
+	err = checkCORS()
+	c.Assert(err, check.NotNil)
+	config.Set("server:cors:allowed-origins", []interface{}{"http://dashboard.tsuru.io"})
+	err = checkCORS()
+	c.Assert(err, check.IsNil)
+}
+
 func (s *CheckerSuite) TestCheckSchedulerConfig(c *check.C) {
 	err := checkScheduler()
 	c.Assert(err, check.IsNil)
@@ -0,0 +1,191 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	tsuruerr "github.com/tsuru/tsuru/errors"
+)
+
+const (
+	defaultUploadChunkSize  = 4 << 20
+	defaultUploadMaxRetries = 5
+	defaultUploadRetryDelay = 2 * time.Second
+)
+
+// UploadStatus is the state of an archive upload in the API.
+type UploadStatus struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// ArchiveUploader sends archives to the API in chunks, so a failure only
+// requires sending the chunk again. Failed chunks are retried, waiting longer
+// after each attempt, and the upload continues from the offset reported by
+// the API, which allows resuming uploads started by previous runs as well.
+// Once uploaded, the archive is deployed by sending its ID in the upload
+// parameter of the deploy request.
+type ArchiveUploader struct {
+	Client  *Client
+	AppName string
+	// ChunkSize is the size of each chunk, 4MB by default.
+	ChunkSize int64
+	// MaxRetries is how many times each chunk is retried, 5 by default.
+	MaxRetries int
+	// RetryDelay is how long to wait before the first retry of a chunk,
+	// doubled after each failure. It defaults to two seconds.
+	RetryDelay time.Duration
+	// Progress, when set, receives the progress of the upload.
+	Progress io.Writer
+}
+
+// Upload starts a new upload with size bytes from r, returning its ID.
+func (u *ArchiveUploader) Upload(r io.ReaderAt, size int64) (string, error) {
+	status, err := u.create()
+	if err != nil {
+		return "", err
+	}
+	return status.ID, u.send(status, r, size)
+}
+
+// Resume continues the upload with the given ID. r must have the same content
+// sent in the previous attempts.
+func (u *ArchiveUploader) Resume(id string, r io.ReaderAt, size int64) error {
+	status, err := u.status(id)
+	if err != nil {
+		return err
+	}
+	if status.Size > size {
+		return errors.Errorf("upload %s has %d bytes, more than the %d bytes of the archive", id, status.Size, size)
+	}
+	return u.send(status, r, size)
+}
+
+func (u *ArchiveUploader) send(status *UploadStatus, r io.ReaderAt, size int64) error {
+	chunkSize := u.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultUploadChunkSize
+	}
+	maxRetries := u.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultUploadMaxRetries
+	}
+	baseDelay := u.RetryDelay
+	if baseDelay == 0 {
+		baseDelay = defaultUploadRetryDelay
+	}
+	delay := baseDelay
+	buf := make([]byte, chunkSize)
+	offset := status.Size
+	retries := 0
+	for offset < size {
+		n, err := r.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			return errors.Errorf("unable to read the archive at offset %d", offset)
+		}
+		var current *UploadStatus
+		current, err = u.sendChunk(status.ID, offset, buf[:n])
+		if err != nil {
+			if !isRetryableUploadError(err) || retries >= maxRetries {
+				return errors.Wrapf(err, "unable to upload chunk at offset %d", offset)
+			}
+			retries++
+			u.progress("Failed to upload chunk at offset %d (%s), retrying in %s...\n", offset, err, delay)
+			time.Sleep(delay)
+			delay *= 2
+			current, err = u.status(status.ID)
+			if err != nil {
+				continue
+			}
+		} else {
+			retries = 0
+			delay = baseDelay
+		}
+		if current.Size > size {
+			return errors.Errorf("upload %s has %d bytes, more than the %d bytes of the archive", status.ID, current.Size, size)
+		}
+		offset = current.Size
+		u.progress("Uploaded %d of %d bytes (%d%%)\n", offset, size, offset*100/size)
+	}
+	return nil
+}
+
+func (u *ArchiveUploader) progress(format string, args ...interface{}) {
+	if u.Progress != nil {
+		fmt.Fprintf(u.Progress, format, args...)
+	}
+}
+
+// isRetryableUploadError returns whether the error may go away by sending the
+// chunk again: connection errors, server errors and conflicting offsets, which
+// are solved by asking the API where the upload stopped.
+func isRetryableUploadError(err error) bool {
+	httpErr, ok := err.(*tsuruerr.HTTP)
+	if !ok {
+		return err != errUnauthorized
+	}
+	return httpErr.Code >= http.StatusInternalServerError || httpErr.Code == http.StatusConflict
+}
+
+func (u *ArchiveUploader) create() (*UploadStatus, error) {
+	url, err := GetURL(fmt.Sprintf("/apps/%s/deploy/uploads", u.AppName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return u.do(request)
+}
+
+func (u *ArchiveUploader) status(id string) (*UploadStatus, error) {
+	url, err := GetURL(fmt.Sprintf("/apps/%s/deploy/uploads/%s", u.AppName, id))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return u.do(request)
+}
+
+func (u *ArchiveUploader) sendChunk(id string, offset int64, data []byte) (*UploadStatus, error) {
+	url, err := GetURL(fmt.Sprintf("/apps/%s/deploy/uploads/%s?offset=%d", u.AppName, id, offset))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("PUT", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	return u.do(request)
+}
+
+func (u *ArchiveUploader) do(request *http.Request) (*UploadStatus, error) {
+	response, err := u.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var status UploadStatus
+	err = json.NewDecoder(response.Body).Decode(&status)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse upload status")
+	}
+	return &status, nil
+}
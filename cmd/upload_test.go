@@ -0,0 +1,110 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+// uploadTransport simulates the upload endpoints of the API, failing the
+// chunk requests listed in failures.
+type uploadTransport struct {
+	data     []byte
+	requests int
+	failures map[int]int
+	chunks   []int64
+}
+
+func (t *uploadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	switch {
+	case req.Method == "POST" && req.URL.Path == "/1.0/apps/myapp/deploy/uploads":
+		status = http.StatusCreated
+	case req.Method == "GET" && req.URL.Path == "/1.0/apps/myapp/deploy/uploads/up1":
+	case req.Method == "PUT" && req.URL.Path == "/1.0/apps/myapp/deploy/uploads/up1":
+		t.requests++
+		if code, ok := t.failures[t.requests]; ok {
+			if code == 0 {
+				return nil, fmt.Errorf("connection reset")
+			}
+			return &http.Response{StatusCode: code, Body: ioutil.NopCloser(strings.NewReader("failed")), Header: http.Header{}}, nil
+		}
+		offset, _ := strconv.ParseInt(req.URL.Query().Get("offset"), 10, 64)
+		if offset != int64(len(t.data)) {
+			return &http.Response{StatusCode: http.StatusConflict, Body: ioutil.NopCloser(strings.NewReader("invalid offset")), Header: http.Header{}}, nil
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		t.data = append(t.data, body...)
+		t.chunks = append(t.chunks, offset)
+	default:
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+	}
+	body := fmt.Sprintf(`{"id":"up1","size":%d}`, len(t.data))
+	return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+}
+
+func (s *S) TestArchiveUploaderUpload(c *check.C) {
+	transport := &uploadTransport{}
+	client := NewClient(&http.Client{Transport: transport}, nil, globalManager)
+	archive := []byte("0123456789abcdefghij")
+	var progress bytes.Buffer
+	uploader := ArchiveUploader{Client: client, AppName: "myapp", ChunkSize: 8, Progress: &progress}
+	id, err := uploader.Upload(bytes.NewReader(archive), int64(len(archive)))
+	c.Assert(err, check.IsNil)
+	c.Assert(id, check.Equals, "up1")
+	c.Assert(string(transport.data), check.Equals, string(archive))
+	c.Assert(transport.chunks, check.DeepEquals, []int64{0, 8, 16})
+	c.Assert(progress.String(), check.Equals, "Uploaded 8 of 20 bytes (40%)\nUploaded 16 of 20 bytes (80%)\nUploaded 20 of 20 bytes (100%)\n")
+}
+
+func (s *S) TestArchiveUploaderRetriesFailedChunks(c *check.C) {
+	transport := &uploadTransport{failures: map[int]int{2: 0, 3: http.StatusBadGateway}}
+	client := NewClient(&http.Client{Transport: transport}, nil, globalManager)
+	archive := []byte("0123456789abcdefghij")
+	uploader := ArchiveUploader{Client: client, AppName: "myapp", ChunkSize: 8, RetryDelay: time.Millisecond}
+	_, err := uploader.Upload(bytes.NewReader(archive), int64(len(archive)))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(transport.data), check.Equals, string(archive))
+	c.Assert(transport.requests, check.Equals, 5)
+}
+
+func (s *S) TestArchiveUploaderGivesUpAfterMaxRetries(c *check.C) {
+	transport := &uploadTransport{failures: map[int]int{1: 0, 2: 0, 3: 0}}
+	client := NewClient(&http.Client{Transport: transport}, nil, globalManager)
+	archive := []byte("0123456789")
+	uploader := ArchiveUploader{Client: client, AppName: "myapp", ChunkSize: 8, MaxRetries: 2, RetryDelay: time.Millisecond}
+	_, err := uploader.Upload(bytes.NewReader(archive), int64(len(archive)))
+	c.Assert(err, check.ErrorMatches, "unable to upload chunk at offset 0: Failed to connect to tsuru server .*")
+	c.Assert(transport.requests, check.Equals, 3)
+}
+
+func (s *S) TestArchiveUploaderDoesNotRetryClientErrors(c *check.C) {
+	transport := &uploadTransport{failures: map[int]int{1: http.StatusForbidden}}
+	client := NewClient(&http.Client{Transport: transport}, nil, globalManager)
+	archive := []byte("0123456789")
+	uploader := ArchiveUploader{Client: client, AppName: "myapp", ChunkSize: 8, RetryDelay: time.Millisecond}
+	_, err := uploader.Upload(bytes.NewReader(archive), int64(len(archive)))
+	c.Assert(err, check.ErrorMatches, "unable to upload chunk at offset 0: failed")
+	c.Assert(transport.requests, check.Equals, 1)
+}
+
+func (s *S) TestArchiveUploaderResume(c *check.C) {
+	archive := []byte("0123456789abcdefghij")
+	transport := &uploadTransport{data: append([]byte(nil), archive[:8]...)}
+	client := NewClient(&http.Client{Transport: transport}, nil, globalManager)
+	uploader := ArchiveUploader{Client: client, AppName: "myapp", ChunkSize: 8}
+	err := uploader.Resume("up1", bytes.NewReader(archive), int64(len(archive)))
+	c.Assert(err, check.IsNil)
+	c.Assert(string(transport.data), check.Equals, string(archive))
+	c.Assert(transport.chunks, check.DeepEquals, []int64{8, 16})
+}
@@ -43,6 +43,9 @@ func writeToken(token string) error {
 	if n != len(token) {
 		return errors.New("Failed to write token file.")
 	}
+	if label := currentTargetLabel(); validTargetLabel(label) {
+		return writeTargetToken(label, token)
+	}
 	return nil
 }
 
@@ -50,6 +53,10 @@ func ReadToken() (string, error) {
 	if token := os.Getenv("TSURU_TOKEN"); token != "" {
 		return token, nil
 	}
+	return readTokenFile()
+}
+
+func readTokenFile() (string, error) {
 	tokenPath := JoinWithUserDir(".tsuru", "token")
 	file, err := filesystem().Open(tokenPath)
 	if os.IsNotExist(err) {
@@ -0,0 +1,272 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+var procfileLineRegex = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+var validHealthcheckMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+type appValidate struct{}
+
+func (c *appValidate) Info() *Info {
+	return &Info{
+		Name:  "app-validate",
+		Usage: "app-validate [path]",
+		Desc: `Validates the tsuru.yaml (or tsuru.yml) and the Procfile of the app in the
+given directory, which defaults to the current directory. It reports unknown
+keys and hooks, invalid health check definitions and malformed Procfile
+lines, which would otherwise only be found during the deploy.`,
+		MinArgs: 0,
+		MaxArgs: 1,
+	}
+}
+
+func (c *appValidate) Run(context *Context, client *Client) error {
+	dir := "."
+	if len(context.Args) > 0 {
+		dir = context.Args[0]
+	}
+	var problems []string
+	var found bool
+	for _, name := range []string{"tsuru.yaml", "tsuru.yml"} {
+		data, err := readValidateFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		found = true
+		for _, p := range validateTsuruYaml(data) {
+			problems = append(problems, name+": "+p)
+		}
+		break
+	}
+	data, err := readValidateFile(filepath.Join(dir, "Procfile"))
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		found = true
+		for _, p := range validateProcfile(data) {
+			problems = append(problems, "Procfile: "+p)
+		}
+	}
+	if !found {
+		return errors.Errorf("no tsuru.yaml, tsuru.yml or Procfile found in %s", dir)
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(context.Stderr, p)
+		}
+		return errors.Errorf("%d problem(s) found", len(problems))
+	}
+	fmt.Fprintln(context.Stdout, "No problems found.")
+	return nil
+}
+
+// readValidateFile returns the content of the file, or nil if it doesn't
+// exist.
+func readValidateFile(path string) ([]byte, error) {
+	file, err := filesystem().Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// validateTsuruYaml returns the problems found in the content of a
+// tsuru.yaml file, following the fields read by the provisioners.
+func validateTsuruYaml(data []byte) []string {
+	var content map[string]interface{}
+	err := yaml.Unmarshal(data, &content)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	for _, key := range sortedYamlKeys(content) {
+		switch key {
+		case "hooks":
+			problems = append(problems, validateHooks(content[key])...)
+		case "healthcheck":
+			problems = append(problems, validateHealthcheck(content[key])...)
+		default:
+			problems = append(problems, fmt.Sprintf("unknown key %q", key))
+		}
+	}
+	return problems
+}
+
+func validateHooks(value interface{}) []string {
+	hooks, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return []string{"hooks must be a map"}
+	}
+	var problems []string
+	for _, key := range sortedYamlKeys(hooks) {
+		switch key {
+		case "build":
+			if !isStringList(hooks[key]) {
+				problems = append(problems, "hooks:build must be a list of commands")
+			}
+		case "restart":
+			restart, ok := hooks[key].(map[interface{}]interface{})
+			if !ok {
+				problems = append(problems, "hooks:restart must be a map")
+				continue
+			}
+			for _, when := range sortedYamlKeys(restart) {
+				if when != "before" && when != "after" {
+					problems = append(problems, fmt.Sprintf("unknown hook %q", "restart:"+when))
+				} else if !isStringList(restart[when]) {
+					problems = append(problems, fmt.Sprintf("hooks:restart:%s must be a list of commands", when))
+				}
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown hook %q", key))
+		}
+	}
+	return problems
+}
+
+func validateHealthcheck(value interface{}) []string {
+	hc, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return []string{"healthcheck must be a map"}
+	}
+	var problems []string
+	for _, key := range sortedYamlKeys(hc) {
+		v := hc[key]
+		switch key {
+		case "path", "router_body":
+			if _, ok := v.(string); !ok {
+				problems = append(problems, fmt.Sprintf("healthcheck:%s must be a string", key))
+			}
+		case "method":
+			method, ok := v.(string)
+			if !ok || !isValidHealthcheckMethod(method) {
+				problems = append(problems, fmt.Sprintf("healthcheck:method must be one of %s, got %v", strings.Join(validHealthcheckMethods, ", "), v))
+			}
+		case "status":
+			status, ok := v.(int)
+			if !ok || status < 100 || status > 599 {
+				problems = append(problems, fmt.Sprintf("healthcheck:status must be a valid HTTP status code, got %v", v))
+			}
+		case "match":
+			match, ok := v.(string)
+			if !ok {
+				problems = append(problems, "healthcheck:match must be a string")
+			} else if _, err := regexp.Compile(match); err != nil {
+				problems = append(problems, fmt.Sprintf("healthcheck:match is not a valid regular expression: %s", err))
+			}
+		case "allowed_failures":
+			failures, ok := v.(int)
+			if !ok || failures < 0 {
+				problems = append(problems, fmt.Sprintf("healthcheck:allowed_failures must be a non-negative number, got %v", v))
+			}
+		case "use_in_router":
+			if _, ok := v.(bool); !ok {
+				problems = append(problems, fmt.Sprintf("healthcheck:use_in_router must be true or false, got %v", v))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown healthcheck key %q", key))
+		}
+	}
+	if path, _ := hc["path"].(string); strings.TrimSpace(path) == "" && len(hc) > 0 {
+		problems = append(problems, "healthcheck:path is not set, the health check will be ignored")
+	}
+	return problems
+}
+
+// validateProcfile returns the problems found in the content of a Procfile:
+// lines not in the "<name>: <command>" format and duplicated process names.
+func validateProcfile(data []byte) []string {
+	var problems []string
+	names := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		matches := procfileLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			problems = append(problems, fmt.Sprintf("line %d: invalid process definition, must be <name>: <command>", lineNumber))
+			continue
+		}
+		if names[matches[1]] {
+			problems = append(problems, fmt.Sprintf("line %d: duplicated process %q", lineNumber, matches[1]))
+		}
+		names[matches[1]] = true
+	}
+	if len(names) == 0 && len(problems) == 0 {
+		problems = append(problems, "no processes defined")
+	}
+	return problems
+}
+
+func sortedYamlKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[interface{}]interface{}:
+		for k := range m {
+			keys = append(keys, fmt.Sprint(k))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isStringList(value interface{}) bool {
+	list, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidHealthcheckMethod(method string) bool {
+	for _, m := range validHealthcheckMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAppValidateInfo(c *check.C) {
+	c.Assert((&appValidate{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppValidateRun(c *check.C) {
+	dir, err := ioutil.TempDir("", "app-validate")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	yamlData := `hooks:
+  restart:
+    before:
+      - python manage.py migrate
+  build:
+    - python manage.py collectstatic
+healthcheck:
+  path: /healthcheck
+  method: GET
+  status: 200
+  match: .*OKAY.*
+  allowed_failures: 1
+  use_in_router: true
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "tsuru.yaml"), []byte(yamlData), 0644)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "Procfile"), []byte("web: gunicorn -w 3 wsgi\n# comment\nworker: celery worker\n"), 0644)
+	c.Assert(err, check.IsNil)
+	var stdout, stderr bytes.Buffer
+	context := Context{Args: []string{dir}, Stdout: &stdout, Stderr: &stderr}
+	err = (&appValidate{}).Run(&context, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "No problems found.\n")
+	c.Assert(stderr.String(), check.Equals, "")
+}
+
+func (s *S) TestAppValidateRunProblems(c *check.C) {
+	dir, err := ioutil.TempDir("", "app-validate")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	yamlData := `hooks:
+  restart:
+    before-each:
+      - ./migrate
+  deploy:
+    - make
+healthcheck:
+  method: FETCH
+  status: 700
+  match: "(["
+  allowed_failures: -1
+  timeout: 10
+other: value
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "tsuru.yml"), []byte(yamlData), 0644)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(filepath.Join(dir, "Procfile"), []byte("web: ./run\nweb: ./other\nworker ./worker\n"), 0644)
+	c.Assert(err, check.IsNil)
+	var stdout, stderr bytes.Buffer
+	context := Context{Args: []string{dir}, Stdout: &stdout, Stderr: &stderr}
+	err = (&appValidate{}).Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `11 problem\(s\) found`)
+	c.Assert(stdout.String(), check.Equals, "")
+	expected := `tsuru.yml: healthcheck:allowed_failures must be a non-negative number, got -1
+tsuru.yml: healthcheck:match is not a valid regular expression: error parsing regexp: missing closing ]: ` + "`[`" + `
+tsuru.yml: healthcheck:method must be one of GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS, got FETCH
+tsuru.yml: healthcheck:status must be a valid HTTP status code, got 700
+tsuru.yml: unknown healthcheck key "timeout"
+tsuru.yml: healthcheck:path is not set, the health check will be ignored
+tsuru.yml: unknown hook "deploy"
+tsuru.yml: unknown hook "restart:before-each"
+tsuru.yml: unknown key "other"
+Procfile: line 2: duplicated process "web"
+Procfile: line 3: invalid process definition, must be <name>: <command>
+`
+	c.Assert(stderr.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppValidateRunNoFiles(c *check.C) {
+	dir, err := ioutil.TempDir("", "app-validate")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+	context := Context{Args: []string{dir}, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	err = (&appValidate{}).Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "no tsuru.yaml, tsuru.yml or Procfile found in .*")
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (s *S) TestNext(c *check.C) {
+	tests := []struct {
+		expr string
+		from string
+		next string
+	}{
+		{"* * * * *", "2016-11-10 10:00", "2016-11-10 10:01"},
+		{"*/15 * * * *", "2016-11-10 10:01", "2016-11-10 10:15"},
+		{"0 8 * * 1-5", "2016-11-10 10:00", "2016-11-11 08:00"},
+		{"0 8 * * mon-fri", "2016-11-11 10:00", "2016-11-14 08:00"},
+		{"0 22 * * *", "2016-11-10 22:00", "2016-11-11 22:00"},
+		{"30 6,18 * * *", "2016-11-10 07:00", "2016-11-10 18:30"},
+		{"0 0 1 * *", "2016-11-10 07:00", "2016-12-01 00:00"},
+		{"0 0 1 jan *", "2016-11-10 07:00", "2017-01-01 00:00"},
+		{"0 0 * * 7", "2016-11-10 07:00", "2016-11-13 00:00"},
+		{"0 0 13 * 5", "2016-11-10 07:00", "2016-11-11 00:00"},
+		{"0 0 29 2 *", "2017-01-01 00:00", "2020-02-29 00:00"},
+		{"@hourly", "2016-11-10 10:30", "2016-11-10 11:00"},
+		{"@weekly", "2016-11-10 10:30", "2016-11-13 00:00"},
+	}
+	for _, tt := range tests {
+		sched, err := Parse(tt.expr)
+		c.Assert(err, check.IsNil, check.Commentf(tt.expr))
+		c.Check(sched.Next(date(tt.from)), check.DeepEquals, date(tt.next), check.Commentf(tt.expr))
+	}
+}
+
+func (s *S) TestNextNever(c *check.C) {
+	sched, err := Parse("0 0 30 2 *")
+	c.Assert(err, check.IsNil)
+	c.Assert(sched.Next(date("2016-11-10 10:00")).IsZero(), check.Equals, true)
+}
+
+func (s *S) TestNextIgnoresSeconds(c *check.C) {
+	sched, err := Parse("* * * * *")
+	c.Assert(err, check.IsNil)
+	from := time.Date(2016, 11, 10, 10, 0, 30, 500, time.UTC)
+	c.Assert(sched.Next(from), check.DeepEquals, date("2016-11-10 10:01"))
+}
+
+func (s *S) TestParseInvalid(c *check.C) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+	}
+	for _, expr := range tests {
+		_, err := Parse(expr)
+		c.Check(err, check.NotNil, check.Commentf(expr))
+	}
+}
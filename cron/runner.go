@@ -0,0 +1,129 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cron
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/db/storage"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// defaultShutdownTimeout is how long a runner waits for the work it started
+// when tsuru is shutting down.
+const defaultShutdownTimeout = time.Minute
+
+// Next returns the first activation of the cron expression after the given
+// time, evaluated in the given timezone. The returned time is in UTC.
+func Next(expr, timezone string, after time.Time) (time.Time, error) {
+	sched, err := Parse(expr)
+	if err != nil {
+		return time.Time{}, &tsuruErrors.ValidationError{Message: err.Error()}
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, &tsuruErrors.ValidationError{Message: "invalid timezone: " + timezone}
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return next, &tsuruErrors.ValidationError{Message: "the schedule " + expr + " never runs"}
+	}
+	return next.UTC(), nil
+}
+
+// Claim moves the next run of the scheduled document with the given id from
+// nextRun to next, recording now as its last run. The documents must store
+// both times in the "lastrun" and "nextrun" fields. It returns false when
+// another tsuru API instance has already claimed this run.
+func Claim(coll *storage.Collection, id bson.ObjectId, nextRun, now, next time.Time) (bool, error) {
+	err := coll.Update(
+		bson.M{"_id": id, "nextrun": nextRun},
+		bson.M{"$set": bson.M{"lastrun": now, "nextrun": next}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Runner calls a function periodically in background, keeping track of the
+// work started by it so tsuru can wait for it on shutdown.
+type Runner struct {
+	name            string
+	interval        time.Duration
+	shutdownTimeout time.Duration
+	quit            chan struct{}
+	quitOnce        sync.Once
+	wg              sync.WaitGroup
+}
+
+// NewRunner returns a runner with the given name, used in logs, that calls
+// its function every interval once started.
+func NewRunner(name string, interval time.Duration) *Runner {
+	return &Runner{
+		name:            name,
+		interval:        interval,
+		shutdownTimeout: defaultShutdownTimeout,
+		quit:            make(chan struct{}),
+	}
+}
+
+// Start calls fn with the current time in UTC right away and then every
+// interval, until the runner is shut down.
+func (r *Runner) Start(fn func(now time.Time)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			fn(time.Now().UTC())
+			select {
+			case <-r.quit:
+				return
+			case <-time.After(r.interval):
+			}
+		}
+	}()
+}
+
+// Go runs fn in a new goroutine tracked by the runner.
+func (r *Runner) Go(fn func()) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until every goroutine started by the runner finishes.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Shutdown stops the runner and waits for the work it started, giving up
+// after a timeout so a stuck run can't prevent tsuru from stopping.
+func (r *Runner) Shutdown() {
+	r.quitOnce.Do(func() { close(r.quit) })
+	done := make(chan struct{})
+	go func() {
+		r.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(r.shutdownTimeout):
+		log.Errorf("[%s] gave up waiting for running work after %s", r.name, r.shutdownTimeout)
+	}
+}
+
+func (r *Runner) String() string {
+	return r.name
+}
@@ -0,0 +1,62 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cron
+
+import (
+	"sync/atomic"
+	"time"
+
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestNextInTimezone(c *check.C) {
+	next, err := Next("30 3 * * *", "America/Sao_Paulo", date("2016-06-01 12:00"))
+	c.Assert(err, check.IsNil)
+	c.Assert(next, check.DeepEquals, date("2016-06-02 06:30"))
+	c.Assert(next.Location(), check.Equals, time.UTC)
+}
+
+func (s *S) TestNextInvalid(c *check.C) {
+	_, err := Next("* *", "UTC", time.Now())
+	c.Assert(err, check.FitsTypeOf, &tsuruErrors.ValidationError{})
+	_, err = Next("@daily", "Mars/Olympus", time.Now())
+	c.Assert(err, check.ErrorMatches, "invalid timezone: Mars/Olympus")
+	_, err = Next("0 0 30 2 *", "UTC", time.Now())
+	c.Assert(err, check.ErrorMatches, "the schedule 0 0 30 2 \\* never runs")
+}
+
+func (s *S) TestRunner(c *check.C) {
+	var calls, work int32
+	r := NewRunner("test", time.Millisecond)
+	r.Start(func(now time.Time) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			r.Go(func() { atomic.AddInt32(&work, 1) })
+		}
+	})
+	time.Sleep(10 * time.Millisecond)
+	r.Shutdown()
+	c.Assert(atomic.LoadInt32(&calls) > 1, check.Equals, true)
+	c.Assert(atomic.LoadInt32(&work), check.Equals, int32(1))
+	c.Assert(r.String(), check.Equals, "test")
+}
+
+func (s *S) TestRunnerShutdownTimeout(c *check.C) {
+	r := NewRunner("test", time.Minute)
+	r.shutdownTimeout = 10 * time.Millisecond
+	block := make(chan struct{})
+	defer close(block)
+	r.Go(func() { <-block })
+	done := make(chan struct{})
+	go func() {
+		r.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the runner to shut down")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tsuru/tsuru/prom"
+	"gopkg.in/mgo.v2"
+)
+
+// mgo has no hook for timing each query, so the latency of MongoDB is
+// exposed as the duration of a ping, along with the socket and operation
+// stats kept by the driver. The metrics endpoint doesn't require
+// authentication, so the ping is made at most once every pingInterval,
+// instead of on every scrape.
+func init() {
+	mgo.SetStats(true)
+	prom.NewGaugeFunc("tsuru_mongodb_sockets_alive", "Number of sockets open to MongoDB.", func() float64 {
+		return float64(mgo.GetStats().SocketsAlive)
+	})
+	prom.NewGaugeFunc("tsuru_mongodb_sockets_in_use", "Number of sockets to MongoDB in use.", func() float64 {
+		return float64(mgo.GetStats().SocketsInUse)
+	})
+	prom.NewGaugeFunc("tsuru_mongodb_sent_ops", "Number of operations sent to MongoDB.", func() float64 {
+		return float64(mgo.GetStats().SentOps)
+	})
+	prom.NewGaugeFunc("tsuru_mongodb_received_ops", "Number of replies received from MongoDB.", func() float64 {
+		return float64(mgo.GetStats().ReceivedOps)
+	})
+	prom.NewGaugeFunc("tsuru_mongodb_ping_duration_seconds", "Time taken by MongoDB to reply a ping.", pingDuration)
+}
+
+const pingInterval = 15 * time.Second
+
+var lastPing = struct {
+	sync.Mutex
+	duration float64
+	at       time.Time
+}{duration: math.NaN()}
+
+func pingDuration() float64 {
+	lastPing.Lock()
+	defer lastPing.Unlock()
+	if time.Since(lastPing.at) < pingInterval {
+		return lastPing.duration
+	}
+	lastPing.at = time.Now()
+	lastPing.duration = ping()
+	return lastPing.duration
+}
+
+func ping() float64 {
+	conn, err := Conn()
+	if err != nil {
+		return math.NaN()
+	}
+	defer conn.Close()
+	start := time.Now()
+	err = conn.Apps().Database.Session.Ping()
+	if err != nil {
+		return math.NaN()
+	}
+	return time.Since(start).Seconds()
+}
@@ -13,6 +13,8 @@ package db
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/db/storage"
@@ -91,8 +93,10 @@ func LogConn() (*LogStorage, error) {
 // Apps returns the apps collection from MongoDB.
 func (s *Storage) Apps() *storage.Collection {
 	nameIndex := mgo.Index{Key: []string{"name"}, Unique: true}
+	tagsIndex := mgo.Index{Key: []string{"tags"}}
 	c := s.Collection("apps")
 	c.EnsureIndex(nameIndex)
+	c.EnsureIndex(tagsIndex)
 	return c
 }
 
@@ -106,6 +110,16 @@ func (s *Storage) Services() *storage.Collection {
 	return s.Collection("services")
 }
 
+// ServiceBrokers returns the service_brokers collection from MongoDB.
+func (s *Storage) ServiceBrokers() *storage.Collection {
+	return s.Collection("service_brokers")
+}
+
+// Volumes returns the volumes collection from MongoDB.
+func (s *Storage) Volumes() *storage.Collection {
+	return s.Collection("volumes")
+}
+
 // ServiceInstances returns the services_instances collection from MongoDB.
 func (s *Storage) ServiceInstances() *storage.Collection {
 	return s.Collection("service_instances")
@@ -135,10 +149,206 @@ func (s *Storage) Tokens() *storage.Collection {
 	return coll
 }
 
+// ImpersonationTokens returns the collection of tokens used by users to act
+// as other users. Expired tokens are removed automatically.
+func (s *Storage) ImpersonationTokens() *storage.Collection {
+	tokenIndex := mgo.Index{Key: []string{"token"}, Unique: true}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("impersonation_tokens")
+	c.EnsureIndex(tokenIndex)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// ScopedTokens returns the collection of app scoped tokens from MongoDB.
+// Expired tokens are removed automatically.
+func (s *Storage) ScopedTokens() *storage.Collection {
+	tokenIndex := mgo.Index{Key: []string{"token"}, Unique: true}
+	appIndex := mgo.Index{Key: []string{"appname"}}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("scoped_tokens")
+	c.EnsureIndex(tokenIndex)
+	c.EnsureIndex(appIndex)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// ServiceAccounts returns the collection of team service accounts from
+// MongoDB.
+func (s *Storage) ServiceAccounts() *storage.Collection {
+	nameIndex := mgo.Index{Key: []string{"team", "name"}, Unique: true}
+	tokenIndex := mgo.Index{Key: []string{"token"}, Unique: true}
+	c := s.Collection("service_accounts")
+	c.EnsureIndex(nameIndex)
+	c.EnsureIndex(tokenIndex)
+	return c
+}
+
 func (s *Storage) PasswordTokens() *storage.Collection {
 	return s.Collection("password_tokens")
 }
 
+// AppAutoScaleRules returns the collection holding autoscaling rules of
+// apps.
+func (s *Storage) AppAutoScaleRules() *storage.Collection {
+	return s.Collection("app_autoscale_rules")
+}
+
+// AppScheduledScales returns the collection holding time based scaling
+// schedules of apps.
+func (s *Storage) AppScheduledScales() *storage.Collection {
+	return s.Collection("app_scheduled_scales")
+}
+
+// AppJobs returns the collection holding scheduled jobs of apps.
+func (s *Storage) AppJobs() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "name"}, Unique: true}
+	c := s.Collection("app_jobs")
+	c.EnsureIndex(index)
+	return c
+}
+
+// AppJobRuns returns the collection holding the run history of app jobs.
+func (s *Storage) AppJobRuns() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "job", "-start"}}
+	c := s.Collection("app_job_runs")
+	c.EnsureIndex(index)
+	return c
+}
+
+// AppEnvHistory returns the collection holding the history of changes in
+// environment variables of apps.
+func (s *Storage) AppEnvHistory() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "-date"}}
+	c := s.Collection("app_env_history")
+	c.EnsureIndex(index)
+	return c
+}
+
+// DeployUploads returns the collection holding the archives being uploaded in
+// chunks for deploys. Uploads are removed by MongoDB once they expire.
+func (s *Storage) DeployUploads() *storage.Collection {
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("deploy_uploads")
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// DeployUploadChunks returns the collection holding the chunks of the archives
+// in DeployUploads.
+func (s *Storage) DeployUploadChunks() *storage.Collection {
+	index := mgo.Index{Key: []string{"upload", "offset"}, Unique: true}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("deploy_upload_chunks")
+	c.EnsureIndex(index)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// Freezes returns the collection holding the change freezes of apps.
+func (s *Storage) Freezes() *storage.Collection {
+	index := mgo.Index{Key: []string{"app"}, Unique: true}
+	c := s.Collection("freezes")
+	c.EnsureIndex(index)
+	return c
+}
+
+// LogDrains returns the collection holding the external destinations to
+// which app logs are forwarded.
+func (s *Storage) LogDrains() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "url"}, Unique: true}
+	c := s.Collection("log_drains")
+	c.EnsureIndex(index)
+	return c
+}
+
+// AppMetrics returns the collection holding samples of the resources used by
+// app units. Expired samples are removed automatically.
+func (s *Storage) AppMetrics() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "date"}}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("app_metrics")
+	c.EnsureIndex(index)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// NodeMetrics returns the collection holding samples of the resources used
+// in nodes. Expired samples are removed automatically.
+func (s *Storage) NodeMetrics() *storage.Collection {
+	index := mgo.Index{Key: []string{"node", "date"}}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("node_metrics")
+	c.EnsureIndex(index)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// AppUnitStatuses returns the collection holding samples of the status of
+// app units. Expired samples are removed automatically.
+func (s *Storage) AppUnitStatuses() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "date"}}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("app_unit_statuses")
+	c.EnsureIndex(index)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// AppRequests returns the collection holding the requests to app units
+// sampled by routers. Expired samples are removed automatically.
+func (s *Storage) AppRequests() *storage.Collection {
+	index := mgo.Index{Key: []string{"app", "date"}}
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("app_requests")
+	c.EnsureIndex(index)
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// AlertRules returns the collection holding the alerting rules of teams.
+func (s *Storage) AlertRules() *storage.Collection {
+	return s.Collection("alert_rules")
+}
+
+// Alerts returns the collection holding the alerts currently firing, one per
+// rule and app.
+func (s *Storage) Alerts() *storage.Collection {
+	return s.Collection("alerts")
+}
+
+// EmailVerificationTokens returns the collection holding tokens sent to
+// users to confirm their email addresses.
+func (s *Storage) EmailVerificationTokens() *storage.Collection {
+	return s.Collection("email_verification_tokens")
+}
+
+// RevokedTokens returns the collection holding the hashes of revoked
+// tokens. Entries are removed automatically once the revoked token expires.
+func (s *Storage) RevokedTokens() *storage.Collection {
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("revoked_tokens")
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
+// TokenUsage returns the collection holding the last usage of each token.
+func (s *Storage) TokenUsage() *storage.Collection {
+	return s.Collection("token_usage")
+}
+
+// TwoFactor returns the collection holding the two-factor authentication
+// secrets and recovery codes of users.
+func (s *Storage) TwoFactor() *storage.Collection {
+	return s.Collection("two_factor")
+}
+
+// LoginAttempts returns the collection tracking failed logins of users, used
+// to temporarily lock accounts.
+func (s *Storage) LoginAttempts() *storage.Collection {
+	return s.Collection("login_attempts")
+}
+
 func (s *Storage) UserActions() *storage.Collection {
 	return s.Collection("user_actions")
 }
@@ -164,23 +374,54 @@ func (s *Storage) SAMLRequests() *storage.Collection {
 	return coll
 }
 
-var logCappedInfo = mgo.CollectionInfo{
-	Capped:       true,
-	MaxBytes:     200 * 5000,
-	MaxDocs:      5000,
-	ForceIdIndex: true,
-}
+// DefaultLogLines is the number of log lines kept for each app when neither
+// the app, its plan nor server:app-log-retention:lines define a limit.
+const DefaultLogLines = 5000
 
-// Logs returns the logs collection for one app from MongoDB.
+// logLineSize is the average size of a log line, in bytes, used to size the
+// capped collections holding logs.
+const logLineSize = 200
+
+// Logs returns the logs collection for one app from MongoDB. The collection
+// is created with the default size if it doesn't exist.
 func (s *LogStorage) Logs(appName string) *storage.Collection {
 	if appName == "" {
 		return nil
 	}
 	c := s.Collection("logs_" + appName)
-	c.Create(&logCappedInfo)
+	s.createLogs(c, 0)
+	c.EnsureIndex(mgo.Index{Key: []string{"level"}})
+	c.EnsureIndex(mgo.Index{Key: []string{"correlationid"}, Sparse: true})
 	return c
 }
 
+// CreateLogs creates the capped collection holding the logs of an app, so it
+// keeps at most maxLines lines. A zero maxLines uses the value of
+// server:app-log-retention:lines. Creating a collection that already exists
+// is a no-op, the existing collection must be dropped to be resized.
+func (s *LogStorage) CreateLogs(appName string, maxLines int) error {
+	return s.createLogs(s.Collection("logs_"+appName), maxLines)
+}
+
+func (s *LogStorage) createLogs(c *storage.Collection, maxLines int) error {
+	if maxLines <= 0 {
+		maxLines, _ = config.GetInt("server:app-log-retention:lines")
+		if maxLines <= 0 {
+			maxLines = DefaultLogLines
+		}
+	}
+	err := c.Create(&mgo.CollectionInfo{
+		Capped:       true,
+		MaxBytes:     logLineSize * maxLines,
+		MaxDocs:      maxLines,
+		ForceIdIndex: true,
+	})
+	if qErr, ok := err.(*mgo.QueryError); ok && (qErr.Code == 48 || strings.Contains(qErr.Message, "already exists")) {
+		return nil
+	}
+	return err
+}
+
 // LogsCollections returns logs collections for all apps from MongoDB.
 func (s *LogStorage) LogsCollections() ([]*storage.Collection, error) {
 	var names []struct {
@@ -210,14 +451,35 @@ func (s *Storage) Limiter() *storage.Collection {
 	return s.Collection("limiter")
 }
 
+// RateLimit returns the collection holding API rate limit buckets. Buckets
+// untouched for one hour are removed by MongoDB.
+func (s *Storage) RateLimit() *storage.Collection {
+	lastUpdateIndex := mgo.Index{Key: []string{"lastupdate"}, ExpireAfter: time.Hour}
+	c := s.Collection("rate_limit")
+	c.EnsureIndex(lastUpdateIndex)
+	return c
+}
+
+// IdempotencyKeys returns the collection holding the results of requests sent
+// with an Idempotency-Key header. Results are removed by MongoDB once they
+// expire.
+func (s *Storage) IdempotencyKeys() *storage.Collection {
+	expiresIndex := mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second}
+	c := s.Collection("idempotency_keys")
+	c.EnsureIndex(expiresIndex)
+	return c
+}
+
 func (s *Storage) Events() *storage.Collection {
 	ownerIndex := mgo.Index{Key: []string{"owner"}}
 	kindIndex := mgo.Index{Key: []string{"kind"}}
 	startTimeIndex := mgo.Index{Key: []string{"-starttime"}}
+	correlationIndex := mgo.Index{Key: []string{"correlationid"}}
 	c := s.Collection("events")
 	c.EnsureIndex(ownerIndex)
 	c.EnsureIndex(kindIndex)
 	c.EnsureIndex(startTimeIndex)
+	c.EnsureIndex(correlationIndex)
 	return c
 }
 
@@ -7,6 +7,7 @@ package db
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/db/storage"
@@ -194,6 +195,25 @@ func (s *S) TestLogs(c *check.C) {
 	c.Assert(logs, check.DeepEquals, logsc)
 }
 
+func (s *S) TestCreateLogs(c *check.C) {
+	strg, err := LogConn()
+	c.Assert(err, check.IsNil)
+	defer strg.Close()
+	err = strg.CreateLogs("cappedapp", 10)
+	c.Assert(err, check.IsNil)
+	defer strg.Collection("logs_cappedapp").DropCollection()
+	err = strg.CreateLogs("cappedapp", 10)
+	c.Assert(err, check.IsNil)
+	logs := strg.Logs("cappedapp")
+	for i := 0; i < 20; i++ {
+		err = logs.Insert(map[string]int{"n": i})
+		c.Assert(err, check.IsNil)
+	}
+	n, err := logs.Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 10)
+}
+
 func (s *S) TestRoles(c *check.C) {
 	strg, err := Conn()
 	c.Assert(err, check.IsNil)
@@ -211,3 +231,25 @@ func (s *S) TestInstallHosts(c *check.C) {
 	hostsc := strg.Collection("install_hosts")
 	c.Assert(hosts, check.DeepEquals, hostsc)
 }
+
+func (s *S) TestRateLimit(c *check.C) {
+	strg, err := Conn()
+	c.Assert(err, check.IsNil)
+	defer strg.Close()
+	rateLimit := strg.RateLimit()
+	rateLimitc := strg.Collection("rate_limit")
+	c.Assert(rateLimit, check.DeepEquals, rateLimitc)
+}
+
+func (s *S) TestPingDurationIsCached(c *check.C) {
+	lastPing.Lock()
+	lastPing.at = time.Now()
+	lastPing.duration = 1.5
+	lastPing.Unlock()
+	defer func() {
+		lastPing.Lock()
+		lastPing.at = time.Time{}
+		lastPing.Unlock()
+	}()
+	c.Assert(pingDuration(), check.Equals, 1.5)
+}
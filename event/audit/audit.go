@@ -0,0 +1,82 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package audit provides sinks that mirror tsuru events to external systems,
+// like syslog servers and SIEM HTTP collectors.
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type sinkFactory func(configPrefix string) (event.Sink, error)
+
+var sinkFactories = map[string]sinkFactory{}
+
+func register(name string, factory sinkFactory) {
+	sinkFactories[name] = factory
+}
+
+// Initialize adds the sinks listed in the event:audit:sinks config entry to
+// the event package, each one configured under event:audit:<name>.
+func Initialize() ([]string, error) {
+	names, _ := config.GetList("event:audit:sinks")
+	for _, name := range names {
+		factory, ok := sinkFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+		sink, err := factory("event:audit:" + name)
+		if err != nil {
+			return nil, err
+		}
+		event.AddSink(sink)
+	}
+	return names, nil
+}
+
+// Record is the representation of an event sent to audit sinks.
+type Record struct {
+	ID              string       `json:"id"`
+	Kind            string       `json:"kind"`
+	Target          event.Target `json:"target"`
+	Owner           string       `json:"owner"`
+	StartTime       time.Time    `json:"startTime"`
+	EndTime         time.Time    `json:"endTime"`
+	Success         bool         `json:"success"`
+	Error           string       `json:"error,omitempty"`
+	StartCustomData interface{}  `json:"startCustomData,omitempty"`
+	EndCustomData   interface{}  `json:"endCustomData,omitempty"`
+}
+
+func newRecord(evt *event.Event) Record {
+	return Record{
+		ID:              evt.UniqueID.Hex(),
+		Kind:            evt.Kind.Name,
+		Target:          evt.Target,
+		Owner:           evt.Owner.String(),
+		StartTime:       evt.StartTime,
+		EndTime:         evt.EndTime,
+		Success:         evt.Error == "",
+		Error:           evt.Error,
+		StartCustomData: decodeCustomData(evt.StartCustomData),
+		EndCustomData:   decodeCustomData(evt.EndCustomData),
+	}
+}
+
+func decodeCustomData(raw bson.Raw) interface{} {
+	if raw.Kind == 0 {
+		return nil
+	}
+	var data interface{}
+	if err := raw.Unmarshal(&data); err != nil {
+		return nil
+	}
+	return data
+}
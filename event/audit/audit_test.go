@@ -0,0 +1,148 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	tsuruNet "github.com/tsuru/tsuru/net"
+	"gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TearDownTest(c *check.C) {
+	config.Unset("event:audit")
+}
+
+func newTestEvent(c *check.C) *event.Event {
+	evt := &event.Event{}
+	evt.UniqueID = bson.NewObjectId()
+	evt.Kind = event.Kind{Type: event.KindTypePermission, Name: "app.update.env.set"}
+	evt.Target = event.Target{Type: event.TargetTypeApp, Value: "myapp"}
+	evt.Owner = event.Owner{Type: event.OwnerTypeUser, Name: "me@me.com"}
+	evt.StartTime = time.Date(2016, time.November, 10, 10, 0, 0, 0, time.UTC)
+	evt.EndTime = evt.StartTime.Add(time.Minute)
+	evt.Error = "myerr"
+	data, err := bson.Marshal(bson.M{"envs": "A=1"})
+	c.Assert(err, check.IsNil)
+	evt.StartCustomData = bson.Raw{Kind: 3, Data: data}
+	return evt
+}
+
+func (s *S) TestNewRecord(c *check.C) {
+	evt := newTestEvent(c)
+	record := newRecord(evt)
+	c.Assert(record, check.DeepEquals, Record{
+		ID:              evt.UniqueID.Hex(),
+		Kind:            "app.update.env.set",
+		Target:          evt.Target,
+		Owner:           "user me@me.com",
+		StartTime:       evt.StartTime,
+		EndTime:         evt.EndTime,
+		Success:         false,
+		Error:           "myerr",
+		StartCustomData: bson.M{"envs": "A=1"},
+	})
+}
+
+func (s *S) TestHTTPSinkSend(c *check.C) {
+	var received Record
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+	config.Set("event:audit:http:url", srv.URL)
+	config.Set("event:audit:http:token", "secret")
+	sink, err := newHTTPSink("event:audit:http")
+	c.Assert(err, check.IsNil)
+	evt := newTestEvent(c)
+	err = sink.Send(evt)
+	c.Assert(err, check.IsNil)
+	c.Assert(auth, check.Equals, "bearer secret")
+	c.Assert(received.ID, check.Equals, evt.UniqueID.Hex())
+	c.Assert(received.Kind, check.Equals, "app.update.env.set")
+}
+
+func (s *S) TestHTTPSinkSendSigned(c *check.C) {
+	var verifyErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		verifyErr = tsuruNet.VerifyRequest(r, body, "s3cr3t", time.Minute)
+	}))
+	defer srv.Close()
+	config.Set("event:audit:http:url", srv.URL)
+	config.Set("event:audit:http:secret", "s3cr3t")
+	sink, err := newHTTPSink("event:audit:http")
+	c.Assert(err, check.IsNil)
+	err = sink.Send(newTestEvent(c))
+	c.Assert(err, check.IsNil)
+	c.Assert(verifyErr, check.IsNil)
+}
+
+func (s *S) TestHTTPSinkSendError(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	config.Set("event:audit:http:url", srv.URL)
+	sink, err := newHTTPSink("event:audit:http")
+	c.Assert(err, check.IsNil)
+	err = sink.Send(newTestEvent(c))
+	c.Assert(err, check.ErrorMatches, "unexpected status code 500 from .*")
+}
+
+func (s *S) TestHTTPSinkRequiresURL(c *check.C) {
+	_, err := newHTTPSink("event:audit:http")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestSyslogSinkSend(c *check.C) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	config.Set("event:audit:syslog:network", "udp")
+	config.Set("event:audit:syslog:address", conn.LocalAddr().String())
+	sink, err := newSyslogSink("event:audit:syslog")
+	c.Assert(err, check.IsNil)
+	evt := newTestEvent(c)
+	err = sink.Send(evt)
+	c.Assert(err, check.IsNil)
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	c.Assert(err, check.IsNil)
+	msg := string(buf[:n])
+	c.Assert(strings.Contains(msg, "tsuru-audit"), check.Equals, true)
+	c.Assert(strings.Contains(msg, `"id":"`+evt.UniqueID.Hex()+`"`), check.Equals, true)
+}
+
+func (s *S) TestInitializeNoSinks(c *check.C) {
+	names, err := Initialize()
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.HasLen, 0)
+}
+
+func (s *S) TestInitializeUnknownSink(c *check.C) {
+	config.Set("event:audit:sinks", []interface{}{"kafka"})
+	_, err := Initialize()
+	c.Assert(err, check.ErrorMatches, `unknown audit sink "kafka"`)
+}
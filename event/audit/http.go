@@ -0,0 +1,79 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	tsuruNet "github.com/tsuru/tsuru/net"
+)
+
+func init() {
+	register("http", newHTTPSink)
+}
+
+// httpSink posts each event as JSON to an HTTP endpoint.
+type httpSink struct {
+	url    string
+	token  string
+	secret string
+	client *http.Client
+}
+
+func newHTTPSink(prefix string) (event.Sink, error) {
+	url, err := config.GetString(prefix + ":url")
+	if err != nil {
+		return nil, err
+	}
+	token, _ := config.GetString(prefix + ":token")
+	secret, _ := config.GetString(prefix + ":secret")
+	timeout, _ := config.GetInt(prefix + ":timeout")
+	if timeout == 0 {
+		timeout = 10
+	}
+	return &httpSink{
+		url:    url,
+		token:  token,
+		secret: secret,
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+func (s *httpSink) Name() string {
+	return "http"
+}
+
+func (s *httpSink) Send(evt *event.Event) error {
+	data, err := json.Marshal(newRecord(evt))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "bearer "+s.token)
+	}
+	if s.secret != "" {
+		tsuruNet.SignRequest(req, data, s.secret)
+	}
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d from %s", rsp.StatusCode, s.url)
+	}
+	return nil
+}
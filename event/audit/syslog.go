@@ -0,0 +1,49 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+)
+
+func init() {
+	register("syslog", newSyslogSink)
+}
+
+// syslogSink writes each event as a JSON line to a syslog server.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(prefix string) (event.Sink, error) {
+	network, _ := config.GetString(prefix + ":network")
+	address, _ := config.GetString(prefix + ":address")
+	tag, _ := config.GetString(prefix + ":tag")
+	if tag == "" {
+		tag = "tsuru-audit"
+	}
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) Name() string {
+	return "syslog"
+}
+
+func (s *syslogSink) Send(evt *event.Event) error {
+	data, err := json.Marshal(newRecord(evt))
+	if err != nil {
+		return err
+	}
+	_, err = s.writer.Write(data)
+	return err
+}
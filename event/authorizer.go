@@ -0,0 +1,72 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"net/http"
+	"sync"
+
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+)
+
+// AuthorizationRequest describes an operation about to be started, sent to
+// the authorizer before the event is created.
+type AuthorizationRequest struct {
+	Kind   Kind
+	Target Target
+	Owner  Owner
+}
+
+// Authorizer is consulted before any event started on behalf of a user or an
+// app, giving external systems the opportunity to deny operations based on
+// their own policies, e.g. change freeze windows. Returning an
+// AuthorizationDeniedError prevents the operation from happening, any other
+// error is handled as a failure in the authorizer itself.
+type Authorizer interface {
+	Name() string
+	Authorize(req AuthorizationRequest) error
+}
+
+// AuthorizationDeniedError is returned by authorizers to deny operations.
+type AuthorizationDeniedError struct {
+	Reason string
+}
+
+func (e *AuthorizationDeniedError) Error() string {
+	if e.Reason == "" {
+		return "operation denied by the authorization hook"
+	}
+	return "operation denied by the authorization hook: " + e.Reason
+}
+
+var authorizer struct {
+	sync.RWMutex
+	a Authorizer
+}
+
+// SetAuthorizer defines the authorizer consulted before starting events. A nil
+// authorizer allows every operation.
+func SetAuthorizer(a Authorizer) {
+	authorizer.Lock()
+	defer authorizer.Unlock()
+	authorizer.a = a
+}
+
+func authorize(req AuthorizationRequest) error {
+	authorizer.RLock()
+	a := authorizer.a
+	authorizer.RUnlock()
+	if a == nil {
+		return nil
+	}
+	err := a.Authorize(req)
+	if err == nil {
+		return nil
+	}
+	if denied, ok := err.(*AuthorizationDeniedError); ok {
+		return &tsuruErrors.HTTP{Code: http.StatusForbidden, Message: denied.Error()}
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package authz provides an event authorizer that consults an external HTTP
+// service before operations are started, allowing companies to enforce their
+// own policies, like change freeze windows or ticket requirements.
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	tsuruNet "github.com/tsuru/tsuru/net"
+)
+
+const configPrefix = "event:authorization-hook"
+
+// Initialize sets the HTTP authorizer in the event package, if an URL is
+// configured in event:authorization-hook:url. It returns the URL of the
+// authorization service, or an empty string if none is configured.
+func Initialize() (string, error) {
+	url, _ := config.GetString(configPrefix + ":url")
+	if url == "" {
+		event.SetAuthorizer(nil)
+		return "", nil
+	}
+	event.SetAuthorizer(newHTTPAuthorizer(url))
+	return url, nil
+}
+
+// Request is the body of the request sent to the authorization service.
+type Request struct {
+	User         string       `json:"user"`
+	OwnerType    string       `json:"ownerType"`
+	Impersonator string       `json:"impersonator,omitempty"`
+	Action       string       `json:"action"`
+	Target       event.Target `json:"target"`
+}
+
+// httpAuthorizer posts each operation as JSON to an HTTP endpoint. Responses
+// with a 2xx status allow the operation, 403 responses deny it, using the
+// body as the reason. Other responses and connection failures deny the
+// operation, unless fail-open is enabled.
+type httpAuthorizer struct {
+	url      string
+	token    string
+	secret   string
+	failOpen bool
+	client   *http.Client
+}
+
+func newHTTPAuthorizer(url string) *httpAuthorizer {
+	token, _ := config.GetString(configPrefix + ":token")
+	secret, _ := config.GetString(configPrefix + ":secret")
+	failOpen, _ := config.GetBool(configPrefix + ":fail-open")
+	timeout, _ := config.GetInt(configPrefix + ":timeout")
+	if timeout == 0 {
+		timeout = 5
+	}
+	return &httpAuthorizer{
+		url:      url,
+		token:    token,
+		secret:   secret,
+		failOpen: failOpen,
+		client:   &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+}
+
+func (a *httpAuthorizer) Name() string {
+	return "http"
+}
+
+func (a *httpAuthorizer) Authorize(req event.AuthorizationRequest) error {
+	err := a.authorize(req)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*event.AuthorizationDeniedError); ok {
+		return err
+	}
+	if a.failOpen {
+		log.Errorf("[authorization-hook] allowing %s on %s %s after failure: %s", req.Kind, req.Target.Type, req.Target.Value, err)
+		return nil
+	}
+	return &event.AuthorizationDeniedError{Reason: fmt.Sprintf("unable to reach authorization service: %s", err)}
+}
+
+func (a *httpAuthorizer) authorize(req event.AuthorizationRequest) error {
+	data, err := json.Marshal(Request{
+		User:         req.Owner.Name,
+		OwnerType:    string(req.Owner.Type),
+		Impersonator: req.Owner.Impersonator,
+		Action:       req.Kind.Name,
+		Target:       req.Target,
+	})
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest("POST", a.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		httpReq.Header.Set("Authorization", "bearer "+a.token)
+	}
+	if a.secret != "" {
+		tsuruNet.SignRequest(httpReq, data, a.secret)
+	}
+	rsp, err := a.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(rsp.Body)
+	if rsp.StatusCode == http.StatusForbidden {
+		return &event.AuthorizationDeniedError{Reason: strings.TrimSpace(string(body))}
+	}
+	return fmt.Errorf("unexpected status code %d from %s", rsp.StatusCode, a.url)
+}
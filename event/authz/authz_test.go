@@ -0,0 +1,115 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package authz
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/event"
+	tsuruNet "github.com/tsuru/tsuru/net"
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TearDownTest(c *check.C) {
+	config.Unset(configPrefix)
+	event.SetAuthorizer(nil)
+}
+
+func newTestRequest() event.AuthorizationRequest {
+	return event.AuthorizationRequest{
+		Kind:   event.Kind{Type: event.KindTypePermission, Name: "app.deploy"},
+		Target: event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+		Owner:  event.Owner{Type: event.OwnerTypeUser, Name: "me@me.com", Impersonator: "support@me.com"},
+	}
+}
+
+func (s *S) TestInitializeNotConfigured(c *check.C) {
+	url, err := Initialize()
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "")
+}
+
+func (s *S) TestInitialize(c *check.C) {
+	config.Set(configPrefix+":url", "http://authz.example.com")
+	url, err := Initialize()
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "http://authz.example.com")
+}
+
+func (s *S) TestHTTPAuthorizerAllow(c *check.C) {
+	var received Request
+	var header http.Header
+	var body []byte
+	var verifyErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		verifyErr = tsuruNet.VerifyRequest(r, body, "s3cr3t", time.Minute)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	config.Set(configPrefix+":url", srv.URL)
+	config.Set(configPrefix+":token", "mytoken")
+	config.Set(configPrefix+":secret", "s3cr3t")
+	a := newHTTPAuthorizer(srv.URL)
+	err := a.Authorize(newTestRequest())
+	c.Assert(err, check.IsNil)
+	c.Assert(received, check.DeepEquals, Request{
+		User:         "me@me.com",
+		OwnerType:    "user",
+		Impersonator: "support@me.com",
+		Action:       "app.deploy",
+		Target:       event.Target{Type: event.TargetTypeApp, Value: "myapp"},
+	})
+	c.Assert(header.Get("Content-Type"), check.Equals, "application/json")
+	c.Assert(header.Get("Authorization"), check.Equals, "bearer mytoken")
+	c.Assert(verifyErr, check.IsNil)
+}
+
+func (s *S) TestHTTPAuthorizerDeny(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("change freeze until monday\n"))
+	}))
+	defer srv.Close()
+	a := newHTTPAuthorizer(srv.URL)
+	err := a.Authorize(newTestRequest())
+	c.Assert(err, check.DeepEquals, &event.AuthorizationDeniedError{Reason: "change freeze until monday"})
+}
+
+func (s *S) TestHTTPAuthorizerFailure(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	a := newHTTPAuthorizer(srv.URL)
+	err := a.Authorize(newTestRequest())
+	c.Assert(err, check.FitsTypeOf, &event.AuthorizationDeniedError{})
+	c.Assert(err, check.ErrorMatches, `operation denied by the authorization hook: unable to reach authorization service: unexpected status code 500 from .*`)
+}
+
+func (s *S) TestHTTPAuthorizerFailOpen(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	config.Set(configPrefix+":fail-open", true)
+	a := newHTTPAuthorizer(srv.URL)
+	err := a.Authorize(newTestRequest())
+	c.Assert(err, check.IsNil)
+}
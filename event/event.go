@@ -17,6 +17,7 @@ import (
 	"github.com/tsuru/tsuru/auth"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/storage"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/safe"
@@ -69,6 +70,9 @@ var (
 	TargetTypePlan            = TargetType("plan")
 	TargetTypeNodeContainer   = TargetType("node-container")
 	TargetTypeInstallHost     = TargetType("install-host")
+	TargetTypeServiceBroker   = TargetType("service-broker")
+	TargetTypeVolume          = TargetType("volume")
+	TargetTypeGlobal          = TargetType("global")
 )
 
 const (
@@ -156,6 +160,18 @@ type eventData struct {
 	Running         bool
 	Allowed         AllowedPermission
 	AllowedCancel   AllowedPermission
+	CorrelationID   string `bson:",omitempty"`
+	RequestID       string `bson:",omitempty"`
+	Steps           []Step `bson:",omitempty"`
+}
+
+// Step is an operation done on behalf of an event, such as an action run by
+// a pipeline or a call to a router.
+type Step struct {
+	Kind  string
+	Name  string
+	Time  time.Time
+	Error string `bson:",omitempty"`
 }
 
 type cancelInfo struct {
@@ -196,8 +212,9 @@ func GetTargetType(t string) (TargetType, error) {
 }
 
 type Owner struct {
-	Type ownerType
-	Name string
+	Type         ownerType
+	Name         string
+	Impersonator string `json:",omitempty" bson:",omitempty"`
 }
 
 type Kind struct {
@@ -256,6 +273,13 @@ type Opts struct {
 	Cancelable    bool
 	Allowed       AllowedPermission
 	AllowedCancel AllowedPermission
+	// CorrelationID links the event to other events and to log lines
+	// produced by the same operation, such as a deploy. Events created
+	// without one use their own unique ID.
+	CorrelationID string
+	// RequestID is the ID of the API request that triggered the event,
+	// allowing provisioner operations to be traced back to it.
+	RequestID string
 }
 
 func Allowed(scheme *permission.PermissionScheme, contexts ...permission.PermissionContext) AllowedPermission {
@@ -310,6 +334,8 @@ type Filter struct {
 	Running        *bool
 	IncludeRemoved bool
 	ErrorOnly      bool
+	CorrelationID  string
+	RequestID      string
 	Raw            bson.M
 	AllowedTargets []TargetFilter
 	Permissions    []permission.Permission
@@ -384,6 +410,12 @@ func (f *Filter) toQuery() (bson.M, error) {
 	if f.KindName != "" {
 		query["kind.name"] = f.KindName
 	}
+	if f.CorrelationID != "" {
+		query["correlationid"] = f.CorrelationID
+	}
+	if f.RequestID != "" {
+		query["requestid"] = f.RequestID
+	}
 	if f.OwnerType != "" {
 		query["owner.type"] = f.OwnerType
 	}
@@ -643,6 +675,15 @@ func newEvt(opts *Opts) (*Event, error) {
 	} else {
 		o.Type = OwnerTypeUser
 		o.Name = opts.Owner.GetUserName()
+		if t, ok := opts.Owner.(*auth.ImpersonationToken); ok {
+			o.Impersonator = t.GetImpersonator()
+		}
+	}
+	if o.Type != OwnerTypeInternal {
+		err := authorize(AuthorizationRequest{Kind: k, Target: opts.Target, Owner: o})
+		if err != nil {
+			return nil, err
+		}
 	}
 	conn, err := db.Conn()
 	if err != nil {
@@ -681,6 +722,10 @@ func newEvt(opts *Opts) (*Event, error) {
 	} else {
 		id.Target = opts.Target
 	}
+	correlationID := opts.CorrelationID
+	if correlationID == "" {
+		correlationID = uniqID.Hex()
+	}
 	evt := Event{eventData: eventData{
 		ID:              id,
 		UniqueID:        uniqID,
@@ -694,6 +739,8 @@ func newEvt(opts *Opts) (*Event, error) {
 		Cancelable:      opts.Cancelable,
 		Allowed:         opts.Allowed,
 		AllowedCancel:   opts.AllowedCancel,
+		CorrelationID:   correlationID,
+		RequestID:       opts.RequestID,
 	}}
 	maxRetries := 1
 	for i := 0; i < maxRetries+1; i++ {
@@ -774,6 +821,35 @@ func (e *Event) SetOtherCustomData(data interface{}) error {
 	})
 }
 
+// AddStep records an operation done on behalf of the event.
+func (e *Event) AddStep(kind, name string, stepErr error) error {
+	step := Step{Kind: kind, Name: name, Time: time.Now().UTC()}
+	if stepErr != nil {
+		step.Error = stepErr.Error()
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Events().UpdateId(e.ID, bson.M{
+		"$push": bson.M{"steps": step},
+	})
+}
+
+// RecordAction records an action run by a pipeline as a step of the event,
+// implementing action.Recorder.
+func (e *Event) RecordAction(name string, rollback bool, actionErr error) {
+	kind := "action"
+	if rollback {
+		kind = "rollback"
+	}
+	err := e.AddStep(kind, name, actionErr)
+	if err != nil {
+		log.Errorf("[events] unable to record action %s in event %s: %s", name, e.UniqueID.Hex(), err)
+	}
+}
+
 func (e *Event) Logf(format string, params ...interface{}) {
 	log.Debugf(fmt.Sprintf("%s(%s)[%s] %s", e.Target.Type, e.Target.Value, e.Kind, format), params...)
 	format += "\n"
@@ -783,6 +859,15 @@ func (e *Event) Logf(format string, params ...interface{}) {
 	fmt.Fprintf(&e.logBuffer, format, params...)
 }
 
+// WritePhase sends the phase marker to the log writer, if it supports
+// markers. Markers are not stored in the event log.
+func (e *Event) WritePhase(phase tsuruIo.Phase) error {
+	if pw, ok := e.logWriter.(tsuruIo.PhaseWriter); ok {
+		return pw.WritePhase(phase)
+	}
+	return nil
+}
+
 func (e *Event) Write(data []byte) (int, error) {
 	if e.logWriter != nil {
 		e.logWriter.Write(data)
@@ -790,6 +875,19 @@ func (e *Event) Write(data []byte) (int, error) {
 	return e.logBuffer.Write(data)
 }
 
+// UpdateLog persists the log written to a running event so far, allowing
+// clients to follow its progress before it's done.
+func (e *Event) UpdateLog() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Events().Update(bson.M{"uniqueid": e.UniqueID}, bson.M{
+		"$set": bson.M{"log": e.logBuffer.String()},
+	})
+}
+
 func (e *Event) TryCancel(reason, owner string) error {
 	if !e.Cancelable || !e.Running {
 		return ErrNotCancelable
@@ -897,13 +995,19 @@ func (e *Event) done(evtErr error, customData interface{}, abort bool) (err erro
 	err = coll.FindId(e.ID).One(&dbEvt.eventData)
 	if err == nil {
 		e.OtherCustomData = dbEvt.OtherCustomData
+		e.Steps = dbEvt.Steps
 	}
 	if len(e.ID.ObjId) != 0 {
-		return coll.UpdateId(e.ID, e.eventData)
+		err = coll.UpdateId(e.ID, e.eventData)
+	} else {
+		defer coll.RemoveId(e.ID)
+		e.ID = eventID{ObjId: e.UniqueID}
+		err = coll.Insert(e.eventData)
 	}
-	defer coll.RemoveId(e.ID)
-	e.ID = eventID{ObjId: e.UniqueID}
-	return coll.Insert(e.eventData)
+	if err == nil {
+		sinks.notify(e)
+	}
+	return err
 }
 
 type lockUpdater struct {
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"net/http"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -19,6 +20,8 @@ import (
 	"github.com/tsuru/tsuru/auth/native"
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/dbtest"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/permission"
 	"github.com/tsuru/tsuru/safe"
@@ -66,6 +69,7 @@ func (s *S) TestNewDone(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:             eventID{Target: Target{Type: "app", Value: "myapp"}},
 		UniqueID:       evt.UniqueID,
+		CorrelationID:  evt.UniqueID.Hex(),
 		Target:         Target{Type: "app", Value: "myapp"},
 		Kind:           Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:          Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -118,6 +122,7 @@ func (s *S) TestNewCustomDataDone(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:              eventID{Target: Target{Type: "app", Value: "myapp"}},
 		UniqueID:        evt.UniqueID,
+		CorrelationID:   evt.UniqueID.Hex(),
 		Target:          Target{Type: "app", Value: "myapp"},
 		Kind:            Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:           Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -183,6 +188,7 @@ func (s *S) TestNewDoneDisableLock(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:             eventID{ObjId: evt.UniqueID},
 		UniqueID:       evt.UniqueID,
+		CorrelationID:  evt.UniqueID.Hex(),
 		Target:         Target{Type: "app", Value: "myapp"},
 		Kind:           Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:          Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -323,6 +329,7 @@ func (s *S) TestEventDoneError(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:             eventID{ObjId: evts[0].ID.ObjId},
 		UniqueID:       evts[0].UniqueID,
+		CorrelationID:  evts[0].UniqueID.Hex(),
 		Target:         Target{Type: "app", Value: "myapp"},
 		Kind:           Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:          Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -555,6 +562,25 @@ func (s *S) TestEventDoneLogError(c *check.C) {
 	c.Assert(logBuf.String(), check.Matches, `(?s).*\[events\] error marking event as done - .*: no reachable servers.*`)
 }
 
+func (s *S) TestEventUpdateLog(c *check.C) {
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	evt.Logf("hey %s", "joe")
+	err = evt.UpdateLog()
+	c.Assert(err, check.IsNil)
+	dbEvt, err := GetByID(evt.UniqueID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbEvt.Running, check.Equals, true)
+	c.Assert(dbEvt.Log, check.Equals, "hey joe\n")
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+}
+
 func (s *S) TestNewThrottledAllKinds(c *check.C) {
 	SetThrottling(ThrottlingSpec{
 		TargetType: TargetTypeApp,
@@ -810,6 +836,7 @@ func (s *S) TestNewWithPermission(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:             eventID{Target: Target{Type: "app", Value: "myapp"}},
 		UniqueID:       evt.UniqueID,
+		CorrelationID:  evt.UniqueID.Hex(),
 		Target:         Target{Type: "app", Value: "myapp"},
 		Kind:           Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:          Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -860,6 +887,67 @@ func (s *S) TestNewLockRetryRace(c *check.C) {
 	c.Assert(evts, check.HasLen, int(countOK))
 }
 
+func (s *S) TestNewImpersonatedOwner(c *check.C) {
+	token := &auth.ImpersonationToken{Token: "abc", UserEmail: "user@tsuru.io", Impersonator: "support@tsuru.io"}
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Owner:   token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Owner, check.DeepEquals, Owner{Type: OwnerTypeUser, Name: "user@tsuru.io", Impersonator: "support@tsuru.io"})
+	evts, err := All()
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	c.Assert(evts[0].Owner.Impersonator, check.Equals, "support@tsuru.io")
+}
+
+type denyAuthorizer struct {
+	reqs []AuthorizationRequest
+}
+
+func (a *denyAuthorizer) Name() string { return "deny" }
+
+func (a *denyAuthorizer) Authorize(req AuthorizationRequest) error {
+	a.reqs = append(a.reqs, req)
+	return &AuthorizationDeniedError{Reason: "change freeze"}
+}
+
+func (s *S) TestNewDeniedByAuthorizer(c *check.C) {
+	a := &denyAuthorizer{}
+	SetAuthorizer(a)
+	defer SetAuthorizer(nil)
+	_, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.DeepEquals, &tsuruErrors.HTTP{Code: http.StatusForbidden, Message: "operation denied by the authorization hook: change freeze"})
+	c.Assert(a.reqs, check.DeepEquals, []AuthorizationRequest{{
+		Kind:   Kind{Type: KindTypePermission, Name: "app.update.env.set"},
+		Target: Target{Type: "app", Value: "myapp"},
+		Owner:  Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
+	}})
+	evts, err := All()
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 0)
+}
+
+func (s *S) TestNewInternalNotAuthorized(c *check.C) {
+	a := &denyAuthorizer{}
+	SetAuthorizer(a)
+	defer SetAuthorizer(nil)
+	_, err := NewInternal(&Opts{
+		Target:       Target{Type: "node", Value: "http://10.0.0.1"},
+		InternalKind: "healer",
+		Allowed:      Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(a.reqs, check.HasLen, 0)
+}
+
 func (s *S) TestNewCustomDataPtr(c *check.C) {
 	customData := struct{ A string }{A: "value"}
 	evt, err := New(&Opts{
@@ -879,6 +967,7 @@ func (s *S) TestNewCustomDataPtr(c *check.C) {
 	expected := &Event{eventData: eventData{
 		ID:              eventID{Target: Target{Type: "app", Value: "myapp"}},
 		UniqueID:        evt.UniqueID,
+		CorrelationID:   evt.UniqueID.Hex(),
 		Target:          Target{Type: "app", Value: "myapp"},
 		Kind:            Kind{Type: KindTypePermission, Name: "app.update.env.set"},
 		Owner:           Owner{Type: OwnerTypeUser, Name: s.token.GetUserName()},
@@ -890,3 +979,97 @@ func (s *S) TestNewCustomDataPtr(c *check.C) {
 	}}
 	c.Assert(evt, check.DeepEquals, expected)
 }
+
+func (s *S) TestNewWithCorrelationID(c *check.C) {
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.CorrelationID, check.Equals, evt.UniqueID.Hex())
+	child, err := NewInternal(&Opts{
+		Target:        Target{Type: "container", Value: "c1"},
+		InternalKind:  "healer",
+		Allowed:       Allowed(permission.PermAppReadEvents),
+		CorrelationID: evt.CorrelationID,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(child.CorrelationID, check.Equals, evt.CorrelationID)
+	_, err = New(&Opts{
+		Target:  Target{Type: "app", Value: "otherapp"},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	evts, err := List(&Filter{CorrelationID: evt.CorrelationID})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 2)
+}
+
+func (s *S) TestNewWithRequestID(c *check.C) {
+	evt, err := New(&Opts{
+		Target:    Target{Type: "app", Value: "myapp"},
+		Kind:      permission.PermAppDeploy,
+		Owner:     s.token,
+		Allowed:   Allowed(permission.PermAppReadEvents),
+		RequestID: "req-1",
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.RequestID, check.Equals, "req-1")
+	_, err = New(&Opts{
+		Target:  Target{Type: "app", Value: "otherapp"},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	evts, err := List(&Filter{RequestID: "req-1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	c.Assert(evts[0].UniqueID, check.Equals, evt.UniqueID)
+}
+
+func (s *S) TestEventAddStep(c *check.C) {
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppDeploy,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	evt.RecordAction("create-container", false, nil)
+	evt.RecordAction("create-container", true, nil)
+	err = evt.AddStep("router", "add-routes", errors.New("router down"))
+	c.Assert(err, check.IsNil)
+	err = evt.Done(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(evt.Steps, check.HasLen, 3)
+	evts, err := All()
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	steps := evts[0].Steps
+	c.Assert(steps, check.HasLen, 3)
+	c.Assert(steps[0].Kind, check.Equals, "action")
+	c.Assert(steps[0].Name, check.Equals, "create-container")
+	c.Assert(steps[0].Error, check.Equals, "")
+	c.Assert(steps[1].Kind, check.Equals, "rollback")
+	c.Assert(steps[2].Kind, check.Equals, "router")
+	c.Assert(steps[2].Name, check.Equals, "add-routes")
+	c.Assert(steps[2].Error, check.Equals, "router down")
+}
+
+func (s *S) TestEventWritePhase(c *check.C) {
+	var buf bytes.Buffer
+	evt := &Event{}
+	evt.SetLogWriter(tsuruIo.NewPhaseMarkerWriter(&buf))
+	err := evt.WritePhase(tsuruIo.Phase{Name: tsuruIo.PhaseBuild})
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `#tsuru-phase {"name":"build"}`+"\n")
+	c.Assert(evt.logBuffer.String(), check.Equals, "")
+	evt.SetLogWriter(&bytes.Buffer{})
+	err = evt.WritePhase(tsuruIo.Phase{Name: tsuruIo.PhaseBuild})
+	c.Assert(err, check.IsNil)
+}
@@ -0,0 +1,70 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"sync"
+
+	"github.com/tsuru/tsuru/log"
+)
+
+const sinkQueueSize = 1000
+
+// Sink receives a copy of every finished event, mirroring it to an external
+// system.
+type Sink interface {
+	Name() string
+	Send(evt *Event) error
+}
+
+type sinkDispatcher struct {
+	sync.Mutex
+	sinks []Sink
+	queue chan *Event
+}
+
+var sinks sinkDispatcher
+
+// AddSink registers a sink to receive every event marked as done from now on.
+// Events are delivered asynchronously, in the order they finish.
+func AddSink(s Sink) {
+	sinks.Lock()
+	defer sinks.Unlock()
+	if sinks.queue == nil {
+		sinks.queue = make(chan *Event, sinkQueueSize)
+		go sinks.run(sinks.queue)
+	}
+	sinks.sinks = append(sinks.sinks, s)
+}
+
+func (d *sinkDispatcher) list() []Sink {
+	d.Lock()
+	defer d.Unlock()
+	return d.sinks
+}
+
+func (d *sinkDispatcher) run(queue <-chan *Event) {
+	for evt := range queue {
+		for _, s := range d.list() {
+			if err := s.Send(evt); err != nil {
+				log.Errorf("[events] [sink %s] unable to send event %s: %s", s.Name(), evt.UniqueID.Hex(), err)
+			}
+		}
+	}
+}
+
+func (d *sinkDispatcher) notify(e *Event) {
+	d.Lock()
+	queue := d.queue
+	d.Unlock()
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- &Event{eventData: e.eventData}:
+	default:
+		log.Errorf("[events] sink queue full, dropping event %s", e.UniqueID.Hex())
+	}
+}
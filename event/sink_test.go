@@ -0,0 +1,70 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tsuru/tsuru/permission"
+	"gopkg.in/check.v1"
+)
+
+type fakeSink struct {
+	events chan *Event
+}
+
+func (s *fakeSink) Name() string {
+	return "fake"
+}
+
+func (s *fakeSink) Send(evt *Event) error {
+	s.events <- evt
+	return nil
+}
+
+func (s *S) TestEventDoneNotifiesSinks(c *check.C) {
+	defer func() { sinks = sinkDispatcher{} }()
+	sink := &fakeSink{events: make(chan *Event, 1)}
+	AddSink(sink)
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	err = evt.Done(errors.New("myerr"))
+	c.Assert(err, check.IsNil)
+	select {
+	case sent := <-sink.events:
+		c.Assert(sent.UniqueID, check.Equals, evt.UniqueID)
+		c.Assert(sent.Kind.Name, check.Equals, "app.update.env.set")
+		c.Assert(sent.Error, check.Equals, "myerr")
+		c.Assert(sent.Running, check.Equals, false)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for event in sink")
+	}
+}
+
+func (s *S) TestEventAbortDoesNotNotifySinks(c *check.C) {
+	defer func() { sinks = sinkDispatcher{} }()
+	sink := &fakeSink{events: make(chan *Event, 1)}
+	AddSink(sink)
+	evt, err := New(&Opts{
+		Target:  Target{Type: "app", Value: "myapp"},
+		Kind:    permission.PermAppUpdateEnvSet,
+		Owner:   s.token,
+		Allowed: Allowed(permission.PermAppReadEvents),
+	})
+	c.Assert(err, check.IsNil)
+	err = evt.Abort()
+	c.Assert(err, check.IsNil)
+	select {
+	case <-sink.events:
+		c.Fatal("aborted event should not be sent to sinks")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
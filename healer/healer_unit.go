@@ -0,0 +1,140 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package healer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/storage"
+	"github.com/tsuru/tsuru/scopedconfig"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	unitHealerConfigCollection = "unit-healer"
+	unitHealsCollection        = "unit_heals"
+
+	defaultUnitMaxStuckTime    = 5 * time.Minute
+	defaultUnitMaxHealsPerHour = 10
+)
+
+// UnitHealerConfig controls the healing of units stuck in the error,
+// created or starting status, for all pools or for a single pool. Units stuck
+// for longer than MaxStuckTime seconds are recreated, as long as fewer than
+// MaxHealsPerHour units of the pool were healed in the last hour.
+type UnitHealerConfig struct {
+	Enabled                  *bool
+	MaxStuckTime             *int
+	MaxHealsPerHour          *int
+	EnabledInherited         bool
+	MaxStuckTimeInherited    bool
+	MaxHealsPerHourInherited bool
+}
+
+// IsEnabled returns whether units should be healed.
+func (c *UnitHealerConfig) IsEnabled() bool {
+	return c.Enabled != nil && *c.Enabled
+}
+
+// StuckTime returns for how long a unit must be stuck before being healed.
+func (c *UnitHealerConfig) StuckTime() time.Duration {
+	if c.MaxStuckTime == nil || *c.MaxStuckTime <= 0 {
+		return defaultUnitMaxStuckTime
+	}
+	return time.Duration(*c.MaxStuckTime) * time.Second
+}
+
+// HealsPerHour returns the maximum number of units healed per hour in the
+// pool. There's always a limit, so a broken image can't make tsuru recreate
+// units endlessly.
+func (c *UnitHealerConfig) HealsPerHour() int {
+	if c.MaxHealsPerHour == nil || *c.MaxHealsPerHour <= 0 {
+		return defaultUnitMaxHealsPerHour
+	}
+	return *c.MaxHealsPerHour
+}
+
+// ClaimUnitHeal records a unit heal in the pool, unless max units of the pool
+// were already healed in the hour before now. The check and the record are a
+// single update in the database, so tsuru API instances healing units at the
+// same time can't go over the limit. It returns false when the limit was
+// reached.
+func ClaimUnitHeal(pool string, max int, now time.Time) (bool, error) {
+	coll, err := unitHealsColl()
+	if err != nil {
+		return false, err
+	}
+	defer coll.Close()
+	_, err = coll.UpsertId(pool, bson.M{
+		"$pull": bson.M{"heals": bson.M{"$lte": now.Add(-time.Hour)}},
+	})
+	if err != nil {
+		return false, err
+	}
+	err = coll.Update(
+		bson.M{"_id": pool, fmt.Sprintf("heals.%d", max-1): bson.M{"$exists": false}},
+		bson.M{"$push": bson.M{"heals": now}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func unitHealsColl() (*storage.Collection, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.Collection(unitHealsCollection), nil
+}
+
+func unitHealerConfig() *scopedconfig.ScopedConfig {
+	conf := scopedconfig.FindScopedConfig(unitHealerConfigCollection)
+	conf.AllowEmpty = true
+	return conf
+}
+
+// LoadUnitConfig returns the unit healer config of the pool, merged with the
+// config set for all pools.
+func LoadUnitConfig(pool string) (UnitHealerConfig, error) {
+	var config UnitHealerConfig
+	err := unitHealerConfig().Load(pool, &config)
+	return config, err
+}
+
+func UpdateUnitConfig(pool string, config UnitHealerConfig) error {
+	conf := unitHealerConfig()
+	err := conf.SaveMerge(pool, config)
+	if err != nil {
+		return errors.Wrap(err, "unable to save config")
+	}
+	return nil
+}
+
+func RemoveUnitConfig(pool, name string) error {
+	conf := unitHealerConfig()
+	if name == "" {
+		return conf.Remove(pool)
+	}
+	return conf.RemoveField(pool, name)
+}
+
+func GetUnitConfig() (map[string]UnitHealerConfig, error) {
+	conf := unitHealerConfig()
+	var ret map[string]UnitHealerConfig
+	err := conf.LoadAll(&ret)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal config")
+	}
+	return ret, nil
+}
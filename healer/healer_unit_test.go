@@ -0,0 +1,62 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package healer
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestLoadUnitConfig(c *check.C) {
+	conf, err := LoadUnitConfig("p1")
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.IsEnabled(), check.Equals, false)
+	c.Assert(conf.StuckTime(), check.Equals, 5*time.Minute)
+	c.Assert(conf.HealsPerHour(), check.Equals, 10)
+	err = UpdateUnitConfig("", UnitHealerConfig{
+		Enabled:      boolPtr(true),
+		MaxStuckTime: intPtr(60),
+	})
+	c.Assert(err, check.IsNil)
+	err = UpdateUnitConfig("p1", UnitHealerConfig{
+		MaxHealsPerHour: intPtr(3),
+	})
+	c.Assert(err, check.IsNil)
+	conf, err = LoadUnitConfig("p1")
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.IsEnabled(), check.Equals, true)
+	c.Assert(conf.StuckTime(), check.Equals, time.Minute)
+	c.Assert(conf.HealsPerHour(), check.Equals, 3)
+	conf, err = LoadUnitConfig("p2")
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.HealsPerHour(), check.Equals, 10)
+	err = RemoveUnitConfig("p1", "MaxHealsPerHour")
+	c.Assert(err, check.IsNil)
+	all, err := GetUnitConfig()
+	c.Assert(err, check.IsNil)
+	allPools, p1 := all[""], all["p1"]
+	c.Assert(allPools.IsEnabled(), check.Equals, true)
+	c.Assert(p1.HealsPerHour(), check.Equals, 10)
+}
+
+func (s *S) TestClaimUnitHeal(c *check.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	claimed, err := ClaimUnitHeal("p1", 2, now.Add(-2*time.Hour))
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	claimed, err = ClaimUnitHeal("p1", 2, now.Add(-time.Minute))
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	claimed, err = ClaimUnitHeal("p1", 2, now)
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+	claimed, err = ClaimUnitHeal("p1", 2, now)
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, false)
+	claimed, err = ClaimUnitHeal("p2", 2, now)
+	c.Assert(err, check.IsNil)
+	c.Assert(claimed, check.Equals, true)
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// PhaseMarkersHeader is the header sent by clients that want to receive phase
+// markers in the deploy stream.
+const PhaseMarkersHeader = "X-Tsuru-Phase-Markers"
+
+// PhaseMarkerPrefix starts the lines with phase markers in a text stream.
+const PhaseMarkerPrefix = "#tsuru-phase "
+
+// Phases of a deploy, in the order they happen.
+const (
+	PhaseUpload     = "upload"
+	PhaseBuild      = "build"
+	PhaseDistribute = "distribute"
+	PhaseStart      = "start"
+	PhaseRoute      = "route"
+)
+
+var DeployPhases = []string{PhaseUpload, PhaseBuild, PhaseDistribute, PhaseStart, PhaseRoute}
+
+// Phase marks the beginning of a step of a long running operation. A phase
+// ends when the next one begins or when the stream ends.
+type Phase struct {
+	Name string `json:"name"`
+}
+
+// PhaseWriter is implemented by writers able to send phase markers.
+type PhaseWriter interface {
+	WritePhase(Phase) error
+}
+
+// WritePhase marks the beginning of the phase if w is a PhaseWriter, doing
+// nothing otherwise.
+func WritePhase(w io.Writer, name string) error {
+	if pw, ok := w.(PhaseWriter); ok {
+		return pw.WritePhase(Phase{Name: name})
+	}
+	return nil
+}
+
+// ParsePhaseMarker returns the phase in the marker line, and false if the
+// line is not a phase marker.
+func ParsePhaseMarker(line []byte) (Phase, bool) {
+	var phase Phase
+	if !bytes.HasPrefix(line, []byte(PhaseMarkerPrefix)) {
+		return phase, false
+	}
+	err := json.Unmarshal(bytes.TrimSpace(line[len(PhaseMarkerPrefix):]), &phase)
+	if err != nil || phase.Name == "" {
+		return phase, false
+	}
+	return phase, true
+}
+
+type phaseMarkerWriter struct {
+	w        io.Writer
+	mu       sync.Mutex
+	lastByte byte
+}
+
+// NewPhaseMarkerWriter returns a writer that writes phases to w as marker
+// lines, which start with PhaseMarkerPrefix followed by the phase in JSON.
+func NewPhaseMarkerWriter(w io.Writer) *phaseMarkerWriter {
+	return &phaseMarkerWriter{w: w, lastByte: '\n'}
+}
+
+func (w *phaseMarkerWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.w.Write(b)
+	if n > 0 {
+		w.lastByte = b[n-1]
+	}
+	return n, err
+}
+
+func (w *phaseMarkerWriter) WritePhase(phase Phase) error {
+	data, err := json.Marshal(phase)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var marker []byte
+	if w.lastByte != '\n' {
+		marker = append(marker, '\n')
+	}
+	marker = append(marker, PhaseMarkerPrefix...)
+	marker = append(marker, data...)
+	marker = append(marker, '\n')
+	_, err = w.w.Write(marker)
+	w.lastByte = '\n'
+	return err
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package io
+
+import (
+	"bytes"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestPhaseMarkerWriter(c *check.C) {
+	var buf bytes.Buffer
+	w := NewPhaseMarkerWriter(&buf)
+	err := WritePhase(w, PhaseBuild)
+	c.Assert(err, check.IsNil)
+	w.Write([]byte("step 1\nstep 2"))
+	err = WritePhase(w, PhaseDistribute)
+	c.Assert(err, check.IsNil)
+	w.Write([]byte("sending\n"))
+	c.Assert(buf.String(), check.Equals, `#tsuru-phase {"name":"build"}
+step 1
+step 2
+#tsuru-phase {"name":"distribute"}
+sending
+`)
+}
+
+func (s *S) TestWritePhaseIgnoredByOtherWriters(c *check.C) {
+	var buf bytes.Buffer
+	err := WritePhase(&buf, PhaseBuild)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "")
+}
+
+func (s *S) TestParsePhaseMarker(c *check.C) {
+	phase, ok := ParsePhaseMarker([]byte(`#tsuru-phase {"name":"route"}` + "\n"))
+	c.Assert(ok, check.Equals, true)
+	c.Assert(phase, check.Equals, Phase{Name: "route"})
+	_, ok = ParsePhaseMarker([]byte("#tsuru-phase invalid\n"))
+	c.Assert(ok, check.Equals, false)
+	_, ok = ParsePhaseMarker([]byte(`{"name":"route"}`))
+	c.Assert(ok, check.Equals, false)
+}
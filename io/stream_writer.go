@@ -10,6 +10,7 @@ import (
 	"io"
 
 	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/quota"
 )
 
 type streamWriter struct {
@@ -62,7 +63,8 @@ func (w *streamWriter) Write(b []byte) (int, error) {
 
 type SimpleJsonMessage struct {
 	Message string
-	Error   string `json:",omitempty"`
+	Error   string                    `json:",omitempty"`
+	Quota   *quota.QuotaExceededError `json:",omitempty"`
 }
 
 type SimpleJsonMessageFormatter struct{}
@@ -76,6 +78,9 @@ func (SimpleJsonMessageFormatter) Format(out io.Writer, data []byte) error {
 	if err != nil {
 		return ErrInvalidStreamChunk
 	}
+	if msg.Quota != nil {
+		return msg.Quota
+	}
 	if msg.Error != "" {
 		return errors.New(msg.Error)
 	}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/tsuru/tsuru/quota"
 	"gopkg.in/check.v1"
 )
 
@@ -198,6 +199,14 @@ func (s *S) TestSimpleJsonMessageFormatter(c *check.C) {
 	c.Assert(buf.String(), check.Equals, "")
 }
 
+func (s *S) TestSimpleJsonMessageFormatterQuotaExceeded(c *check.C) {
+	formatter := SimpleJsonMessageFormatter{}
+	buf := bytes.Buffer{}
+	err := formatter.Format(&buf, []byte(`{"Message": "", "Error": "Quota exceeded. Available: 2. Requested: 3.", "Quota": {"Requested": 3, "Available": 2}}`))
+	c.Assert(err, check.DeepEquals, &quota.QuotaExceededError{Requested: 3, Available: 2})
+	c.Assert(buf.String(), check.Equals, "")
+}
+
 func (s *S) TestSimpleJsonMessageEncoderWriter(c *check.C) {
 	buf := bytes.Buffer{}
 	encoder := json.NewEncoder(&buf)
@@ -28,6 +28,7 @@ github.com/tsuru/tsuru/api.authScheme
 github.com/tsuru/tsuru/api.healthcheck
 github.com/tsuru/tsuru/api.index
 github.com/tsuru/tsuru/api.info
+github.com/tsuru/tsuru/api.schema
 github.com/tsuru/tsuru/api.resetPassword
 github.com/tsuru/tsuru/api.samlCallbackLogin
 github.com/tsuru/tsuru/api.samlMetadata
@@ -35,7 +36,11 @@ github.com/tsuru/tsuru/api.addKeyToUser
 github.com/tsuru/tsuru/api.listPlans
 github.com/tsuru/tsuru/api.login
 github.com/tsuru/tsuru/api.logout
+github.com/tsuru/tsuru/api.refreshToken
 github.com/tsuru/tsuru/api.changePassword
+github.com/tsuru/tsuru/api.enrollTwoFactor
+github.com/tsuru/tsuru/api.confirmTwoFactor
+github.com/tsuru/tsuru/api.disableTwoFactor
 github.com/tsuru/tsuru/api.userInfo
 github.com/tsuru/tsuru/api.serviceInfo
 github.com/tsuru/tsuru/api.serviceInstances
@@ -45,6 +50,7 @@ github.com/tsuru/tsuru/api.removeKeyFromUser
 github.com/tsuru/tsuru/api.setNodeStatus
 github.com/tsuru/tsuru/api.kindList
 github.com/tsuru/tsuru/api.eventList
+github.com/tsuru/tsuru/api.eventStream
 github.com/tsuru/tsuru/api.eventInfo
 github.com/tsuru/tsuru/api.eventCancel
 github.com/tsuru/tsuru/api.listNodesHandler
@@ -0,0 +1,79 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SignatureHeader = "X-Tsuru-Signature"
+	TimestampHeader = "X-Tsuru-Timestamp"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrMissingSignature = errors.New("request signature is missing")
+	ErrInvalidSignature = errors.New("request signature is invalid")
+	ErrExpiredSignature = errors.New("request signature has expired")
+)
+
+// Signature returns the HMAC-SHA256 signature of a request, computed with the
+// given secret over the timestamp, the method, the path with the query string
+// and the body of the request.
+func Signature(secret, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + method + "\n" + uri + "\n"))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the signature and timestamp headers in the request, so the
+// receiver can check it was sent by someone holding the secret and that it
+// wasn't tampered with. The body must be the same sent in the request.
+func SignRequest(req *http.Request, body []byte, secret string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Signature(secret, timestamp, req.Method, req.URL.RequestURI(), body))
+}
+
+// VerifyRequest checks the signature of a request signed by SignRequest,
+// rejecting signatures older than maxAge to prevent replays. The body must be
+// the body read from the request.
+func VerifyRequest(req *http.Request, body []byte, secret string, maxAge time.Duration) error {
+	signature := req.Header.Get(SignatureHeader)
+	timestamp := req.Header.Get(TimestampHeader)
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age < 0 {
+		age = -age
+	}
+	if maxAge > 0 && age > maxAge {
+		return ErrExpiredSignature
+	}
+	expected := Signature(secret, timestamp, req.Method, req.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
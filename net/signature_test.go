@@ -0,0 +1,55 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package net
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSignAndVerifyRequest(c *check.C) {
+	body := []byte("name=mysql-instance&team=myteam")
+	req, err := http.NewRequest("POST", "http://service.example.com/resources?x=1", strings.NewReader(string(body)))
+	c.Assert(err, check.IsNil)
+	SignRequest(req, body, "s3cr3t")
+	c.Assert(req.Header.Get(TimestampHeader), check.Not(check.Equals), "")
+	c.Assert(req.Header.Get(SignatureHeader), check.Matches, "sha256=[0-9a-f]{64}")
+	err = VerifyRequest(req, body, "s3cr3t", time.Minute)
+	c.Assert(err, check.IsNil)
+	err = VerifyRequest(req, body, "other", time.Minute)
+	c.Assert(err, check.Equals, ErrInvalidSignature)
+	err = VerifyRequest(req, []byte("name=other-instance&team=myteam"), "s3cr3t", time.Minute)
+	c.Assert(err, check.Equals, ErrInvalidSignature)
+	req.Method = "DELETE"
+	err = VerifyRequest(req, body, "s3cr3t", time.Minute)
+	c.Assert(err, check.Equals, ErrInvalidSignature)
+}
+
+func (s *S) TestVerifyRequestExpired(c *check.C) {
+	req, err := http.NewRequest("GET", "http://service.example.com/resources", nil)
+	c.Assert(err, check.IsNil)
+	timestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Signature("s3cr3t", timestamp, "GET", "/resources", nil))
+	err = VerifyRequest(req, nil, "s3cr3t", 5*time.Minute)
+	c.Assert(err, check.Equals, ErrExpiredSignature)
+	err = VerifyRequest(req, nil, "s3cr3t", 0)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestVerifyRequestMissingSignature(c *check.C) {
+	req, err := http.NewRequest("GET", "http://service.example.com/resources", nil)
+	c.Assert(err, check.IsNil)
+	err = VerifyRequest(req, nil, "s3cr3t", time.Minute)
+	c.Assert(err, check.Equals, ErrMissingSignature)
+	req.Header.Set(TimestampHeader, "abc")
+	req.Header.Set(SignatureHeader, "sha256=abc")
+	err = VerifyRequest(req, nil, "s3cr3t", time.Minute)
+	c.Assert(err, check.Equals, ErrInvalidSignature)
+}
@@ -53,9 +53,10 @@ var (
 	CtxIaaS            = contextType("iaas")
 	CtxService         = contextType("service")
 	CtxServiceInstance = contextType("service-instance")
+	CtxVolume          = contextType("volume")
 
 	ContextTypes = []contextType{
-		CtxGlobal, CtxApp, CtxTeam, CtxPool, CtxIaaS, CtxService, CtxServiceInstance,
+		CtxGlobal, CtxApp, CtxTeam, CtxPool, CtxIaaS, CtxService, CtxServiceInstance, CtxVolume,
 	}
 )
 
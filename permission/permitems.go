@@ -11,6 +11,7 @@ var (
 	PermAll                              = PermissionRegistry.get("")                                    // [global]
 	PermApp                              = PermissionRegistry.get("app")                                 // [global app team pool]
 	PermAppAdmin                         = PermissionRegistry.get("app.admin")                           // [global app team pool]
+	PermAppAdminFreeze                   = PermissionRegistry.get("app.admin.freeze")                    // [global app team pool]
 	PermAppAdminQuota                    = PermissionRegistry.get("app.admin.quota")                     // [global app team pool]
 	PermAppAdminRoutes                   = PermissionRegistry.get("app.admin.routes")                    // [global app team pool]
 	PermAppAdminUnlock                   = PermissionRegistry.get("app.admin.unlock")                    // [global app team pool]
@@ -24,33 +25,49 @@ var (
 	PermAppDeployRollback                = PermissionRegistry.get("app.deploy.rollback")                 // [global app team pool]
 	PermAppDeployUpload                  = PermissionRegistry.get("app.deploy.upload")                   // [global app team pool]
 	PermAppRead                          = PermissionRegistry.get("app.read")                            // [global app team pool]
+	PermAppReadAutoscale                 = PermissionRegistry.get("app.read.autoscale")                  // [global app team pool]
 	PermAppReadDeploy                    = PermissionRegistry.get("app.read.deploy")                     // [global app team pool]
 	PermAppReadEnv                       = PermissionRegistry.get("app.read.env")                        // [global app team pool]
 	PermAppReadEvents                    = PermissionRegistry.get("app.read.events")                     // [global app team pool]
+	PermAppReadJob                       = PermissionRegistry.get("app.read.job")                        // [global app team pool]
 	PermAppReadLog                       = PermissionRegistry.get("app.read.log")                        // [global app team pool]
 	PermAppReadMetric                    = PermissionRegistry.get("app.read.metric")                     // [global app team pool]
 	PermAppRun                           = PermissionRegistry.get("app.run")                             // [global app team pool]
 	PermAppRunShell                      = PermissionRegistry.get("app.run.shell")                       // [global app team pool]
+	PermAppToken                         = PermissionRegistry.get("app.token")                           // [global app team pool]
+	PermAppTokenCreate                   = PermissionRegistry.get("app.token.create")                    // [global app team pool]
+	PermAppTokenDelete                   = PermissionRegistry.get("app.token.delete")                    // [global app team pool]
+	PermAppTokenRead                     = PermissionRegistry.get("app.token.read")                      // [global app team pool]
 	PermAppUpdate                        = PermissionRegistry.get("app.update")                          // [global app team pool]
+	PermAppUpdateAutoscale               = PermissionRegistry.get("app.update.autoscale")                // [global app team pool]
 	PermAppUpdateBind                    = PermissionRegistry.get("app.update.bind")                     // [global app team pool]
 	PermAppUpdateCname                   = PermissionRegistry.get("app.update.cname")                    // [global app team pool]
 	PermAppUpdateCnameAdd                = PermissionRegistry.get("app.update.cname.add")                // [global app team pool]
 	PermAppUpdateCnameRemove             = PermissionRegistry.get("app.update.cname.remove")             // [global app team pool]
+	PermAppUpdateDependency              = PermissionRegistry.get("app.update.dependency")               // [global app team pool]
 	PermAppUpdateDescription             = PermissionRegistry.get("app.update.description")              // [global app team pool]
 	PermAppUpdateEnv                     = PermissionRegistry.get("app.update.env")                      // [global app team pool]
 	PermAppUpdateEnvSet                  = PermissionRegistry.get("app.update.env.set")                  // [global app team pool]
 	PermAppUpdateEnvUnset                = PermissionRegistry.get("app.update.env.unset")                // [global app team pool]
 	PermAppUpdateEvents                  = PermissionRegistry.get("app.update.events")                   // [global app team pool]
 	PermAppUpdateGrant                   = PermissionRegistry.get("app.update.grant")                    // [global app team pool]
+	PermAppUpdateJob                     = PermissionRegistry.get("app.update.job")                      // [global app team pool]
 	PermAppUpdateLog                     = PermissionRegistry.get("app.update.log")                      // [global app team pool]
+	PermAppUpdateLogDrain                = PermissionRegistry.get("app.update.log-drain")                // [global app team pool]
+	PermAppUpdateLogPurge                = PermissionRegistry.get("app.update.log-purge")                // [global app team pool]
+	PermAppUpdateLogRetention            = PermissionRegistry.get("app.update.log-retention")            // [global app team pool]
+	PermAppUpdateMetadata                = PermissionRegistry.get("app.update.metadata")                 // [global app team pool]
 	PermAppUpdatePlan                    = PermissionRegistry.get("app.update.plan")                     // [global app team pool]
 	PermAppUpdatePool                    = PermissionRegistry.get("app.update.pool")                     // [global app team pool]
+	PermAppUpdateRequests                = PermissionRegistry.get("app.update.requests")                 // [global app team pool]
 	PermAppUpdateRestart                 = PermissionRegistry.get("app.update.restart")                  // [global app team pool]
 	PermAppUpdateRevoke                  = PermissionRegistry.get("app.update.revoke")                   // [global app team pool]
 	PermAppUpdateSleep                   = PermissionRegistry.get("app.update.sleep")                    // [global app team pool]
+	PermAppUpdateSlo                     = PermissionRegistry.get("app.update.slo")                      // [global app team pool]
 	PermAppUpdateStart                   = PermissionRegistry.get("app.update.start")                    // [global app team pool]
 	PermAppUpdateStop                    = PermissionRegistry.get("app.update.stop")                     // [global app team pool]
 	PermAppUpdateSwap                    = PermissionRegistry.get("app.update.swap")                     // [global app team pool]
+	PermAppUpdateTags                    = PermissionRegistry.get("app.update.tags")                     // [global app team pool]
 	PermAppUpdateTeamowner               = PermissionRegistry.get("app.update.teamowner")                // [global app team pool]
 	PermAppUpdateUnbind                  = PermissionRegistry.get("app.update.unbind")                   // [global app team pool]
 	PermAppUpdateUnit                    = PermissionRegistry.get("app.update.unit")                     // [global app team pool]
@@ -58,6 +75,9 @@ var (
 	PermAppUpdateUnitRegister            = PermissionRegistry.get("app.update.unit.register")            // [global app team pool]
 	PermAppUpdateUnitRemove              = PermissionRegistry.get("app.update.unit.remove")              // [global app team pool]
 	PermAppUpdateUnitStatus              = PermissionRegistry.get("app.update.unit.status")              // [global app team pool]
+	PermAppUpdateVolume                  = PermissionRegistry.get("app.update.volume")                   // [global app team pool]
+	PermAppUpdateVolumeBind              = PermissionRegistry.get("app.update.volume.bind")              // [global app team pool]
+	PermAppUpdateVolumeUnbind            = PermissionRegistry.get("app.update.volume.unbind")            // [global app team pool]
 	PermDebug                            = PermissionRegistry.get("debug")                               // [global]
 	PermHealing                          = PermissionRegistry.get("healing")                             // [global pool]
 	PermHealingDelete                    = PermissionRegistry.get("healing.delete")                      // [global pool]
@@ -101,6 +121,7 @@ var (
 	PermPlanDelete                       = PermissionRegistry.get("plan.delete")                         // [global]
 	PermPlanRead                         = PermissionRegistry.get("plan.read")                           // [global]
 	PermPlanReadEvents                   = PermissionRegistry.get("plan.read.events")                    // [global]
+	PermPlanUpdate                       = PermissionRegistry.get("plan.update")                         // [global]
 	PermPlatform                         = PermissionRegistry.get("platform")                            // [global]
 	PermPlatformCreate                   = PermissionRegistry.get("platform.create")                     // [global]
 	PermPlatformDelete                   = PermissionRegistry.get("platform.delete")                     // [global]
@@ -132,6 +153,12 @@ var (
 	PermRoleUpdatePermissionAdd          = PermissionRegistry.get("role.update.permission.add")          // [global]
 	PermRoleUpdatePermissionRemove       = PermissionRegistry.get("role.update.permission.remove")       // [global]
 	PermService                          = PermissionRegistry.get("service")                             // [global service team]
+	PermServiceBroker                    = PermissionRegistry.get("service-broker")                      // [global]
+	PermServiceBrokerCreate              = PermissionRegistry.get("service-broker.create")               // [global]
+	PermServiceBrokerDelete              = PermissionRegistry.get("service-broker.delete")               // [global]
+	PermServiceBrokerRead                = PermissionRegistry.get("service-broker.read")                 // [global]
+	PermServiceBrokerReadEvents          = PermissionRegistry.get("service-broker.read.events")          // [global]
+	PermServiceBrokerUpdate              = PermissionRegistry.get("service-broker.update")               // [global]
 	PermServiceInstance                  = PermissionRegistry.get("service-instance")                    // [global service-instance team]
 	PermServiceInstanceCreate            = PermissionRegistry.get("service-instance.create")             // [global team]
 	PermServiceInstanceDelete            = PermissionRegistry.get("service-instance.delete")             // [global service-instance team]
@@ -160,13 +187,28 @@ var (
 	PermTeamCreate                       = PermissionRegistry.get("team.create")                         // [global]
 	PermTeamDelete                       = PermissionRegistry.get("team.delete")                         // [global team]
 	PermTeamRead                         = PermissionRegistry.get("team.read")                           // [global team]
+	PermTeamReadAlert                    = PermissionRegistry.get("team.read.alert")                     // [global team]
 	PermTeamReadEvents                   = PermissionRegistry.get("team.read.events")                    // [global team]
+	PermTeamReadQuota                    = PermissionRegistry.get("team.read.quota")                     // [global team]
+	PermTeamReadUsage                    = PermissionRegistry.get("team.read.usage")                     // [global team]
+	PermTeamServiceAccount               = PermissionRegistry.get("team.service-account")                // [global team]
+	PermTeamServiceAccountCreate         = PermissionRegistry.get("team.service-account.create")         // [global team]
+	PermTeamServiceAccountDelete         = PermissionRegistry.get("team.service-account.delete")         // [global team]
+	PermTeamServiceAccountRead           = PermissionRegistry.get("team.service-account.read")           // [global team]
+	PermTeamServiceAccountUpdate         = PermissionRegistry.get("team.service-account.update")         // [global team]
+	PermTeamUpdate                       = PermissionRegistry.get("team.update")                         // [global team]
+	PermTeamUpdateAlert                  = PermissionRegistry.get("team.update.alert")                   // [global team]
+	PermTeamUpdateParent                 = PermissionRegistry.get("team.update.parent")                  // [global team]
+	PermTeamUpdateQuota                  = PermissionRegistry.get("team.update.quota")                   // [global]
 	PermUser                             = PermissionRegistry.get("user")                                // [global user]
 	PermUserCreate                       = PermissionRegistry.get("user.create")                         // [global]
 	PermUserDelete                       = PermissionRegistry.get("user.delete")                         // [global user]
+	PermUserImpersonate                  = PermissionRegistry.get("user.impersonate")                    // [global user]
 	PermUserRead                         = PermissionRegistry.get("user.read")                           // [global user]
 	PermUserReadEvents                   = PermissionRegistry.get("user.read.events")                    // [global user]
+	PermUserReadTokens                   = PermissionRegistry.get("user.read.tokens")                    // [global user]
 	PermUserUpdate                       = PermissionRegistry.get("user.update")                         // [global user]
+	PermUserUpdateDeactivate             = PermissionRegistry.get("user.update.deactivate")              // [global user]
 	PermUserUpdateKey                    = PermissionRegistry.get("user.update.key")                     // [global user]
 	PermUserUpdateKeyAdd                 = PermissionRegistry.get("user.update.key.add")                 // [global user]
 	PermUserUpdateKeyRemove              = PermissionRegistry.get("user.update.key.remove")              // [global user]
@@ -174,4 +216,14 @@ var (
 	PermUserUpdateQuota                  = PermissionRegistry.get("user.update.quota")                   // [global user]
 	PermUserUpdateReset                  = PermissionRegistry.get("user.update.reset")                   // [global user]
 	PermUserUpdateToken                  = PermissionRegistry.get("user.update.token")                   // [global user]
+	PermUserUpdateTwoFactor              = PermissionRegistry.get("user.update.two-factor")              // [global user]
+	PermUserUpdateVerify                 = PermissionRegistry.get("user.update.verify")                  // [global user]
+	PermVolume                           = PermissionRegistry.get("volume")                              // [global volume team]
+	PermVolumeCreate                     = PermissionRegistry.get("volume.create")                       // [global team]
+	PermVolumeDelete                     = PermissionRegistry.get("volume.delete")                       // [global volume team]
+	PermVolumeRead                       = PermissionRegistry.get("volume.read")                         // [global volume team]
+	PermVolumeReadEvents                 = PermissionRegistry.get("volume.read.events")                  // [global volume team]
+	PermVolumeUpdate                     = PermissionRegistry.get("volume.update")                       // [global volume team]
+	PermVolumeUpdateBind                 = PermissionRegistry.get("volume.update.bind")                  // [global volume team]
+	PermVolumeUpdateUnbind               = PermissionRegistry.get("volume.update.unbind")                // [global volume team]
 )
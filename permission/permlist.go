@@ -12,12 +12,21 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.create", []contextType{CtxTeam},
 ).add(
 	"app.update.description",
+	"app.update.tags",
+	"app.update.metadata",
 	"app.update.log",
+	"app.update.log-drain",
+	"app.update.log-retention",
+	"app.update.log-purge",
+	"app.update.slo",
+	"app.update.requests",
 	"app.update.pool",
 	"app.update.unit.add",
 	"app.update.unit.remove",
 	"app.update.unit.register",
 	"app.update.unit.status",
+	"app.update.autoscale",
+	"app.update.job",
 	"app.update.env.set",
 	"app.update.env.unset",
 	"app.update.restart",
@@ -30,10 +39,13 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.update.teamowner",
 	"app.update.cname.add",
 	"app.update.cname.remove",
+	"app.update.dependency",
 	"app.update.plan",
 	"app.update.bind",
 	"app.update.events",
 	"app.update.unbind",
+	"app.update.volume.bind",
+	"app.update.volume.unbind",
 	"app.deploy",
 	"app.deploy.archive-url",
 	"app.deploy.build",
@@ -46,13 +58,19 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"app.read.env",
 	"app.read.events",
 	"app.read.metric",
+	"app.read.autoscale",
+	"app.read.job",
 	"app.read.log",
 	"app.delete",
 	"app.run",
 	"app.run.shell",
+	"app.token.create",
+	"app.token.read",
+	"app.token.delete",
 	"app.admin.unlock",
 	"app.admin.routes",
 	"app.admin.quota",
+	"app.admin.freeze",
 ).addWithCtx(
 	"node", []contextType{CtxPool},
 ).add(
@@ -86,18 +104,34 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"team.create", []contextType{},
 ).add(
 	"team.read.events",
+	"team.read.quota",
+	"team.read.usage",
+	"team.read.alert",
+	"team.update.alert",
+	"team.update.parent",
+	"team.service-account.create",
+	"team.service-account.read",
+	"team.service-account.update",
+	"team.service-account.delete",
 	"team.delete",
+).addWithCtx(
+	"team.update.quota", []contextType{},
 ).addWithCtx(
 	"user", []contextType{CtxUser},
 ).addWithCtx(
 	"user.create", []contextType{},
 ).add(
 	"user.delete",
+	"user.impersonate",
 	"user.read.events",
+	"user.read.tokens",
 	"user.update.token",
 	"user.update.quota",
 	"user.update.password",
+	"user.update.two-factor",
+	"user.update.deactivate",
 	"user.update.reset",
+	"user.update.verify",
 	"user.update.key.add",
 	"user.update.key.remove",
 ).addWithCtx(
@@ -127,6 +161,22 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"service-instance.update.grant",
 	"service-instance.update.revoke",
 	"service-instance.update.description",
+).add(
+	"service-broker.create",
+	"service-broker.read",
+	"service-broker.read.events",
+	"service-broker.update",
+	"service-broker.delete",
+).addWithCtx(
+	"volume", []contextType{CtxVolume, CtxTeam},
+).addWithCtx(
+	"volume.create", []contextType{CtxTeam},
+).add(
+	"volume.read",
+	"volume.read.events",
+	"volume.update.bind",
+	"volume.update.unbind",
+	"volume.delete",
 ).add(
 	"role.create",
 	"role.delete",
@@ -144,6 +194,7 @@ var PermissionRegistry = (&registry{}).addWithCtx(
 	"platform.read.events",
 ).add(
 	"plan.create",
+	"plan.update",
 	"plan.delete",
 	"plan.read.events",
 ).addWithCtx(
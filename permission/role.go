@@ -178,6 +178,36 @@ func FindRole(name string) (Role, error) {
 	return role, nil
 }
 
+// UpdateRole renames the role and changes its description. Empty values
+// are left unchanged. Renaming creates the role with the new name and keeps
+// the old one, which must be removed with DestroyRole once nothing
+// references it.
+func UpdateRole(name, newName, description string) (Role, error) {
+	role, err := FindRole(name)
+	if err != nil {
+		return role, err
+	}
+	if description != "" {
+		role.Description = description
+	}
+	newName = strings.TrimSpace(newName)
+	coll, err := rolesCollection()
+	if err != nil {
+		return role, err
+	}
+	defer coll.Close()
+	if newName == "" || newName == name {
+		err = coll.UpdateId(name, bson.M{"$set": bson.M{"description": role.Description}})
+		return role, err
+	}
+	role.Name = newName
+	err = coll.Insert(role)
+	if mgo.IsDup(err) {
+		return role, ErrRoleAlreadyExists
+	}
+	return role, err
+}
+
 func DestroyRole(name string) error {
 	coll, err := rolesCollection()
 	if err != nil {
@@ -124,6 +124,40 @@ func (s *S) TestDestroyRole(c *check.C) {
 	c.Assert(err, check.Equals, ErrRoleNotFound)
 }
 
+func (s *S) TestUpdateRole(c *check.C) {
+	r, err := NewRole("myrole", "team", "my role")
+	c.Assert(err, check.IsNil)
+	err = r.AddPermissions("app.update")
+	c.Assert(err, check.IsNil)
+	updated, err := UpdateRole("myrole", "", "new description")
+	c.Assert(err, check.IsNil)
+	c.Assert(updated.Name, check.Equals, "myrole")
+	c.Assert(updated.Description, check.Equals, "new description")
+	updated, err = UpdateRole("myrole", "newrole", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(updated.Name, check.Equals, "newrole")
+	_, err = FindRole("myrole")
+	c.Assert(err, check.IsNil)
+	dbRole, err := FindRole("newrole")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbRole.Description, check.Equals, "new description")
+	c.Assert(dbRole.ContextType, check.Equals, CtxTeam)
+	c.Assert(dbRole.SchemeNames, check.DeepEquals, []string{"app.update"})
+}
+
+func (s *S) TestUpdateRoleErrors(c *check.C) {
+	_, err := UpdateRole("myrole", "newrole", "")
+	c.Assert(err, check.Equals, ErrRoleNotFound)
+	_, err = NewRole("myrole", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = NewRole("otherrole", "team", "")
+	c.Assert(err, check.IsNil)
+	_, err = UpdateRole("myrole", "otherrole", "")
+	c.Assert(err, check.Equals, ErrRoleAlreadyExists)
+	_, err = FindRole("myrole")
+	c.Assert(err, check.IsNil)
+}
+
 func (s *S) TestPermissionsFor(c *check.C) {
 	r, err := NewRole("myrole", "team", "")
 	c.Assert(err, check.IsNil)
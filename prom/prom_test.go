@@ -0,0 +1,73 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prom
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestCounterVec(c *check.C) {
+	counter := NewCounterVec("test_requests_total", "Number of requests.", "method", "status")
+	counter.Inc("GET", "200")
+	counter.Inc("GET", "200")
+	counter.Add(3, "POST", "500")
+	c.Assert(counter.Value("GET", "200"), check.Equals, 2.0)
+	c.Assert(counter.Value("PUT", "200"), check.Equals, 0.0)
+	var buf bytes.Buffer
+	counter.write(&buf)
+	c.Assert(buf.String(), check.Equals, `# HELP test_requests_total Number of requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",status="200"} 2
+test_requests_total{method="POST",status="500"} 3
+`)
+}
+
+func (s *S) TestCounterVecInvalidLabels(c *check.C) {
+	counter := NewCounterVec("test_invalid_labels_total", "Invalid.", "method")
+	c.Assert(func() { counter.Inc() }, check.PanicMatches, `prom: metric "test_invalid_labels_total" expects 1 label values, got 0`)
+	c.Assert(func() { counter.Add(-1, "GET") }, check.PanicMatches, `prom: counter "test_invalid_labels_total" can't be decreased`)
+}
+
+func (s *S) TestHistogramVec(c *check.C) {
+	histogram := NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "kind")
+	histogram.Observe(0.0625, "a\"b")
+	histogram.Observe(0.5, "a\"b")
+	histogram.Observe(2, "a\"b")
+	c.Assert(histogram.Count("a\"b"), check.Equals, uint64(3))
+	var buf bytes.Buffer
+	histogram.write(&buf)
+	c.Assert(buf.String(), check.Equals, `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{kind="a\"b",le="0.1"} 1
+test_duration_seconds_bucket{kind="a\"b",le="1"} 2
+test_duration_seconds_bucket{kind="a\"b",le="+Inf"} 3
+test_duration_seconds_sum{kind="a\"b"} 2.5625
+test_duration_seconds_count{kind="a\"b"} 3
+`)
+}
+
+func (s *S) TestHandler(c *check.C) {
+	NewGaugeFunc("test_gauge", "A gauge.", func() float64 { return 42 })
+	c.Assert(func() { NewGaugeFunc("test_gauge", "Again.", nil) }, check.PanicMatches, `prom: metric "test_gauge" registered twice`)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/metrics", nil)
+	c.Assert(err, check.IsNil)
+	Handler().ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "text/plain; version=0.0.4")
+	c.Assert(strings.Contains(recorder.Body.String(), "# TYPE test_gauge gauge\ntest_gauge 42\n"), check.Equals, true)
+}
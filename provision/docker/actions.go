@@ -18,6 +18,7 @@ import (
 	"github.com/tsuru/tsuru/action"
 	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/event"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/docker/container"
@@ -389,7 +390,7 @@ var bindAndHealthcheck = action.Action{
 			}
 			toRollback <- c
 			if doHealthcheck && c.ProcessName == webProcessName {
-				err = runHealthcheck(c, writer)
+				err = args.provisioner.runHealthcheck(c, writer)
 				if err != nil {
 					return err
 				}
@@ -452,6 +453,7 @@ var addNewRoutes = action.Action{
 			writer = ioutil.Discard
 		}
 		if len(newContainers) > 0 {
+			tsuruIo.WritePhase(writer, tsuruIo.PhaseRoute)
 			fmt.Fprintf(writer, "\n---- Adding routes to new units ----\n")
 		}
 		var routesToAdd []*url.URL
@@ -468,7 +470,9 @@ var addNewRoutes = action.Action{
 			return newContainers, nil
 		}
 		err = r.AddRoutes(args.app.GetName(), routesToAdd)
+		recordRouterStep(args.event, "add-routes", err)
 		if err != nil {
+			observeRouterFailure(args.app, "add-routes")
 			r.RemoveRoutes(args.app.GetName(), routesToAdd)
 			return nil, err
 		}
@@ -549,6 +553,7 @@ var setRouterHealthcheck = action.Action{
 		}
 		fmt.Fprintf(writer, "\n---- Setting router healthcheck (%s) ----\n", msg)
 		err = hcRouter.SetHealthcheck(args.app.GetName(), hcData)
+		recordRouterStep(args.event, "set-healthcheck", err)
 		return newContainers, err
 	},
 	Backward: func(ctx action.BWContext) {
@@ -624,7 +629,9 @@ var removeOldRoutes = action.Action{
 			return
 		}
 		err = r.RemoveRoutes(args.app.GetName(), routesToRemove)
+		recordRouterStep(args.event, "remove-routes", err)
 		if err != nil {
+			observeRouterFailure(args.app, "remove-routes")
 			if !args.appDestroy {
 				r.AddRoutes(args.app.GetName(), routesToRemove)
 			}
@@ -828,3 +835,18 @@ var updateAppImage = action.Action{
 	},
 	OnError: rollbackNotice,
 }
+
+func observeRouterFailure(app provision.App, operation string) {
+	routerName, _ := app.GetRouter()
+	router.ObserveFailure(routerName, operation)
+}
+
+func recordRouterStep(evt *event.Event, operation string, err error) {
+	if evt == nil {
+		return
+	}
+	stepErr := evt.AddStep("router", operation, err)
+	if stepErr != nil {
+		log.Errorf("unable to record router operation %s in event: %s", operation, stepErr)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ajg/form"
+	"github.com/pkg/errors"
+	"github.com/tsuru/gnuflag"
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/provision"
+)
+
+// parseNodeParams parses arguments in the <name>=<value> format into a map.
+func parseNodeParams(args []string) (map[string]string, error) {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid parameter %q, must be in the format <name>=<value>", arg)
+		}
+		params[parts[0]] = parts[1]
+	}
+	return params, nil
+}
+
+type nodeAddCmd struct {
+	fs       *gnuflag.FlagSet
+	register bool
+}
+
+func (c *nodeAddCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "node-add",
+		Usage: "node-add [param_name=param_value]... [--register]",
+		Desc: `Creates or registers a new node in the cluster.
+By default, this command will call the configured IaaS to create a new
+machine. Every param will be sent to the IaaS implementation.
+
+Parameters with special meaning:
+  iaas=<iaas name>          Which iaas provider should be used, if not set
+                            tsuru will use the default iaas specified in
+                            tsuru.conf file.
+  template=<template name>  A machine template with predefined parameters,
+                            additional parameters will override template ones.
+  pool=<pool name>          The pool the node belongs to. It's required.
+
+The --register flag registers an existing node, instead of creating a new
+machine. The address=<url> parameter must be used with it.`,
+		MinArgs: 1,
+	}
+}
+
+func (c *nodeAddCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.register, "register", false, "Registers an existing node in the cluster")
+	}
+	return c.fs
+}
+
+func (c *nodeAddCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	metadata, err := parseNodeParams(context.Args)
+	if err != nil {
+		return err
+	}
+	opts := provision.AddNodeOptions{
+		Metadata: metadata,
+		Register: c.register,
+	}
+	v, err := form.EncodeToValues(&opts)
+	if err != nil {
+		return err
+	}
+	u, err := cmd.GetURL("/docker/node")
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", u, bytes.NewBufferString(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	err = cmd.StreamJSONResponse(context.Stdout, response)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout, "Node successfully registered.")
+	return nil
+}
+
+type nodeListCmd struct {
+	cmd.OutputCommand
+	fs     *gnuflag.FlagSet
+	filter cmd.MapFlag
+}
+
+func (c *nodeListCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "node-list",
+		Usage: "node-list [--filter/-f <metadata>=<value>]... [--json] [--format template]",
+		Desc: `Lists the nodes in the cluster, with their status, IaaS machine ID and
+metadata. The --filter flag only lists nodes whose metadata match the given
+values.`,
+	}
+}
+
+func (c *nodeListCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(gnuflag.NewFlagSet("", gnuflag.ExitOnError), c.OutputCommand.Flags())
+		desc := "Filter by node metadata"
+		c.fs.Var(&c.filter, "filter", desc)
+		c.fs.Var(&c.filter, "f", desc)
+	}
+	return c.fs
+}
+
+func (c *nodeListCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	u, err := cmd.GetURL("/docker/node")
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	var result struct {
+		Nodes []provision.NodeSpec `json:"nodes"`
+	}
+	if response.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			return err
+		}
+	}
+	nodes := make([]provision.NodeSpec, 0, len(result.Nodes))
+	for _, n := range result.Nodes {
+		if c.matches(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	if rendered, err := c.Render(context.Stdout, nodes); rendered {
+		return err
+	}
+	t := cmd.NewTable()
+	t.Headers = cmd.Row{"Address", "IaaS ID", "Status", "Metadata"}
+	t.LineSeparator = true
+	for _, n := range nodes {
+		var metadata []string
+		for k, v := range n.Metadata {
+			metadata = append(metadata, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(metadata)
+		t.AddRow(cmd.Row{n.Address, n.Metadata["iaas-id"], n.Status, strings.Join(metadata, "\n")})
+	}
+	t.Sort()
+	context.Stdout.Write(t.Bytes())
+	return nil
+}
+
+func (c *nodeListCmd) matches(n provision.NodeSpec) bool {
+	for k, v := range c.filter {
+		if n.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+type nodeUpdateCmd struct {
+	fs      *gnuflag.FlagSet
+	disable bool
+	enable  bool
+}
+
+func (c *nodeUpdateCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "node-update",
+		Usage: "node-update <address> [param_name=param_value]... [--disable] [--enable]",
+		Desc: `Modifies metadata associated to a node. If a parameter is set to an empty
+value, it will be removed from the node's metadata.
+
+If the --disable flag is used, the node will be marked as disabled and the
+scheduler won't consider it when selecting a node to receive containers.
+
+If the --enable flag is used, the node will be enabled again.`,
+		MinArgs: 1,
+	}
+}
+
+func (c *nodeUpdateCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		c.fs.BoolVar(&c.disable, "disable", false, "Disables the node in the cluster")
+		c.fs.BoolVar(&c.enable, "enable", false, "Enables a disabled node in the cluster")
+	}
+	return c.fs
+}
+
+func (c *nodeUpdateCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	if c.disable && c.enable {
+		return errors.New("A node can't be enabled and disabled simultaneously.")
+	}
+	metadata, err := parseNodeParams(context.Args[1:])
+	if err != nil {
+		return err
+	}
+	opts := provision.UpdateNodeOptions{
+		Address:  context.Args[0],
+		Metadata: metadata,
+		Disable:  c.disable,
+		Enable:   c.enable,
+	}
+	v, err := form.EncodeToValues(&opts)
+	if err != nil {
+		return err
+	}
+	u, err := cmd.GetURL("/docker/node")
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("PUT", u, bytes.NewBufferString(v.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = client.Do(request)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout, "Node successfully updated.")
+	return nil
+}
+
+type nodeRemoveCmd struct {
+	cmd.ConfirmationCommand
+	fs          *gnuflag.FlagSet
+	destroy     bool
+	noRebalance bool
+}
+
+func (c *nodeRemoveCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "node-remove",
+		Usage: "node-remove <address> [--destroy] [--no-rebalance] [-y/--assume-yes]",
+		Desc: `Removes a node from the cluster.
+
+By default, the units running in the node are moved to other nodes before it's
+removed. The --no-rebalance flag skips moving them.
+
+The --destroy flag also destroys the machine in the IaaS used to create it,
+if any.`,
+		MinArgs: 1,
+	}
+}
+
+func (c *nodeRemoveCmd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.ConfirmationCommand.Flags()
+		c.fs.BoolVar(&c.destroy, "destroy", false, "Destroy node's machine in the IaaS")
+		c.fs.BoolVar(&c.noRebalance, "no-rebalance", false, "Don't move the units of the node to other nodes")
+	}
+	return c.fs
+}
+
+func (c *nodeRemoveCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	msg := "Are you sure you want to remove \"%s\" from cluster"
+	if c.destroy {
+		msg += " and DESTROY the machine from IaaS"
+	}
+	if !c.Confirm(context, fmt.Sprintf(msg+"?", context.Args[0])) {
+		return nil
+	}
+	v := url.Values{}
+	if c.destroy {
+		v.Set("remove-iaas", "true")
+	}
+	if c.noRebalance {
+		v.Set("no-rebalance", "true")
+	}
+	u, err := cmd.GetURL(fmt.Sprintf("/docker/node/%s?%s", context.Args[0], v.Encode()))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, err = io.Copy(context.Stdout, response.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Stdout, "Node successfully removed.")
+	return nil
+}
+
+type nodeDrainCmd struct {
+	cmd.ConfirmationCommand
+}
+
+func (c *nodeDrainCmd) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "node-drain",
+		Usage: "node-drain <address> [-y/--assume-yes]",
+		Desc: `Disables the node, so it doesn't receive new units, and moves all its units
+to other nodes. The node is kept in the cluster and may be enabled again with
+node-update --enable, after maintenance for example.`,
+		MinArgs: 1,
+		MaxArgs: 1,
+	}
+}
+
+func (c *nodeDrainCmd) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	address := context.Args[0]
+	if !c.Confirm(context, fmt.Sprintf("Are you sure you want to drain node %q?", address)) {
+		return nil
+	}
+	u, err := cmd.GetURL(fmt.Sprintf("/docker/node/%s/drain", address))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", u, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	return cmd.StreamJSONResponse(context.Stdout, response)
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/cmd/cmdtest"
+	tsuruIo "github.com/tsuru/tsuru/io"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestNodeAddRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"pool=poolTest", "address=http://localhost:2375"},
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusCreated},
+		CondFunc: func(req *http.Request) bool {
+			req.ParseForm()
+			return req.URL.Path == "/1.0/docker/node" && req.Method == "POST" &&
+				req.FormValue("Metadata.pool") == "poolTest" &&
+				req.FormValue("Metadata.address") == "http://localhost:2375" &&
+				req.FormValue("Register") == "true"
+		},
+	}
+	manager := cmd.NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := nodeAddCmd{}
+	command.Flags().Parse(true, []string{"--register"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Node successfully registered.\n")
+}
+
+func (s *S) TestNodeAddRunInvalidParam(c *check.C) {
+	context := cmd.Context{Args: []string{"pool"}}
+	command := nodeAddCmd{}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `invalid parameter "pool", must be in the format <name>=<value>`)
+}
+
+func (s *S) TestNodeListRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	result := `{"nodes":[
+{"Address":"http://node2:2375","Metadata":{"pool":"pool1","iaas-id":"m-2"},"Status":"ready"},
+{"Address":"http://node1:2375","Metadata":{"pool":"pool2"},"Status":"disabled"}
+],"machines":null}`
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: result, Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Path == "/1.0/docker/node" && req.Method == "GET"
+		},
+	}
+	manager := cmd.NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	command := nodeListCmd{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `+-------------------+---------+----------+-------------+
+| Address           | IaaS ID | Status   | Metadata    |
++-------------------+---------+----------+-------------+
+| http://node1:2375 |         | disabled | pool=pool2  |
++-------------------+---------+----------+-------------+
+| http://node2:2375 | m-2     | ready    | iaas-id=m-2 |
+|                   |         |          | pool=pool1  |
++-------------------+---------+----------+-------------+
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+	stdout.Reset()
+	command = nodeListCmd{}
+	command.Flags().Parse(true, []string{"-f", "pool=pool1", "--format", "{{.Address}} {{.Status}}"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "http://node2:2375 ready\n")
+}
+
+func (s *S) TestNodeUpdateRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"http://localhost:2375", "pool=pool2", "zone="},
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			req.ParseForm()
+			_, hasZone := req.Form["Metadata.zone"]
+			return req.URL.Path == "/1.0/docker/node" && req.Method == "PUT" &&
+				req.FormValue("Address") == "http://localhost:2375" &&
+				req.FormValue("Metadata.pool") == "pool2" && hasZone &&
+				req.FormValue("Disable") == "true"
+		},
+	}
+	manager := cmd.NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := nodeUpdateCmd{}
+	command.Flags().Parse(true, []string{"--disable"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Node successfully updated.\n")
+}
+
+func (s *S) TestNodeUpdateRunEnableAndDisable(c *check.C) {
+	context := cmd.Context{Args: []string{"http://localhost:2375"}}
+	command := nodeUpdateCmd{}
+	command.Flags().Parse(true, []string{"--disable", "--enable"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "A node can't be enabled and disabled simultaneously.")
+}
+
+func (s *S) TestNodeRemoveRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"http://localhost:2375"},
+	}
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Path == "/1.0/docker/node/http://localhost:2375" && req.Method == "DELETE" &&
+				req.URL.Query().Get("remove-iaas") == "true" && req.URL.Query().Get("no-rebalance") == ""
+		},
+	}
+	manager := cmd.NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := nodeRemoveCmd{}
+	command.Flags().Parse(true, []string{"-y", "--destroy"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Node successfully removed.\n")
+}
+
+func (s *S) TestNodeDrainRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"http://localhost:2375"},
+	}
+	msg, _ := json.Marshal(tsuruIo.SimpleJsonMessage{Message: "Node drained"})
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(msg), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.URL.Path == "/1.0/docker/node/http://localhost:2375/drain" && req.Method == "POST"
+		},
+	}
+	manager := cmd.NewManager("admin", "0.1", "admin-ver", &stdout, &stderr, nil, nil)
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := nodeDrainCmd{}
+	command.Flags().Parse(true, []string{"-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "Node drained")
+}
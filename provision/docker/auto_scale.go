@@ -491,8 +491,8 @@ func canRemoveNode(chosenNode *cluster.Node, nodes []*cluster.Node) (bool, error
 func cleanMetadata(n *cluster.Node) map[string]string {
 	// iaas-id is ignored because it wasn't created in previous tsuru versions
 	// and having nodes with and without it would cause unbalanced metadata
-	// errors.
-	ignoredMetadata := []string{"iaas-id"}
+	// errors. disk-pressure is set only while the node is short on disk.
+	ignoredMetadata := []string{"iaas-id", diskPressureMetadata}
 	metadata := n.CleanMetadata()
 	for _, val := range ignoredMetadata {
 		delete(metadata, val)
@@ -0,0 +1,111 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+)
+
+var cadvisorClient = &http.Client{Timeout: statsTimeout}
+
+// cadvisorContainerInfo is the subset of the container information returned
+// by the cAdvisor v1.3 API used to sample units.
+type cadvisorContainerInfo struct {
+	Stats []cadvisorStats `json:"stats"`
+}
+
+type cadvisorStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	CPU       struct {
+		Usage struct {
+			Total uint64 `json:"total"`
+		} `json:"usage"`
+	} `json:"cpu"`
+	Memory struct {
+		WorkingSet uint64 `json:"working_set"`
+	} `json:"memory"`
+	Network struct {
+		Interfaces []struct {
+			RxBytes uint64 `json:"rx_bytes"`
+			TxBytes uint64 `json:"tx_bytes"`
+		} `json:"interfaces"`
+	} `json:"network"`
+	Filesystem []struct {
+		Capacity uint64 `json:"capacity"`
+		Usage    uint64 `json:"usage"`
+	} `json:"filesystem"`
+}
+
+// cadvisorPort returns the port of the cAdvisor agents running in the docker
+// nodes, set in docker:metrics:cadvisor-port, or zero when units should be
+// sampled from the docker daemon only.
+func cadvisorPort() int {
+	port, _ := config.GetInt("docker:metrics:cadvisor-port")
+	return port
+}
+
+// cadvisorUnitMetrics samples the container from the cAdvisor agent running
+// in its node, which, unlike the docker daemon, reports the usage of the
+// filesystems of the container. The CPU usage is calculated between the two
+// latest stats kept by cAdvisor.
+func cadvisorUnitMetrics(port int, c *container.Container) (*provision.UnitMetrics, error) {
+	url := fmt.Sprintf("http://%s:%d/api/v1.3/docker/%s", c.HostAddr, port, c.ID)
+	rsp, err := cadvisorClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code from cadvisor: %d", rsp.StatusCode)
+	}
+	var infos map[string]cadvisorContainerInfo
+	err = json.NewDecoder(rsp.Body).Decode(&infos)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse cadvisor response")
+	}
+	var stats []cadvisorStats
+	for _, info := range infos {
+		stats = info.Stats
+	}
+	if len(stats) == 0 {
+		return nil, errors.New("no stats returned")
+	}
+	last := stats[len(stats)-1]
+	m := provision.UnitMetrics{
+		ID:      c.ID,
+		Process: c.ProcessName,
+		Memory:  last.Memory.WorkingSet,
+	}
+	if len(stats) > 1 {
+		m.CPU = cadvisorCPUPercent(&stats[len(stats)-2], &last)
+	}
+	for _, iface := range last.Network.Interfaces {
+		m.NetRx += iface.RxBytes
+		m.NetTx += iface.TxBytes
+	}
+	for _, fs := range last.Filesystem {
+		m.DiskUsed += fs.Usage
+		m.DiskTotal += fs.Capacity
+	}
+	return &m, nil
+}
+
+// cadvisorCPUPercent calculates the usage of the container between two
+// samples, as a percentage of one CPU.
+func cadvisorCPUPercent(prev, cur *cadvisorStats) float64 {
+	elapsed := cur.Timestamp.Sub(prev.Timestamp)
+	if elapsed <= 0 || cur.CPU.Usage.Total <= prev.CPU.Usage.Total {
+		return 0
+	}
+	return float64(cur.CPU.Usage.Total-prev.CPU.Usage.Total) / float64(elapsed) * 100
+}
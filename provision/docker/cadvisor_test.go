@@ -0,0 +1,65 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestCadvisorUnitMetrics(c *check.C) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"/docker/abc123": {"stats": [
+			{"timestamp": "2016-12-01T10:00:00Z", "cpu": {"usage": {"total": 1000000000}}, "memory": {"working_set": 100}},
+			{"timestamp": "2016-12-01T10:00:02Z", "cpu": {"usage": {"total": 2000000000}}, "memory": {"working_set": 200},
+			 "network": {"interfaces": [{"rx_bytes": 10, "tx_bytes": 20}, {"rx_bytes": 1, "tx_bytes": 2}]},
+			 "filesystem": [{"capacity": 1000, "usage": 300}, {"capacity": 500, "usage": 100}]}
+		]}}`))
+	}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	c.Assert(err, check.IsNil)
+	host, portStr, err := net.SplitHostPort(srvURL.Host)
+	c.Assert(err, check.IsNil)
+	port, _ := strconv.Atoi(portStr)
+	cont := container.Container{ID: "abc123", ProcessName: "web", HostAddr: host}
+	m, err := cadvisorUnitMetrics(port, &cont)
+	c.Assert(err, check.IsNil)
+	c.Assert(path, check.Equals, "/api/v1.3/docker/abc123")
+	c.Assert(*m, check.DeepEquals, provision.UnitMetrics{
+		ID:        "abc123",
+		Process:   "web",
+		CPU:       50,
+		Memory:    200,
+		NetRx:     11,
+		NetTx:     22,
+		DiskUsed:  400,
+		DiskTotal: 1500,
+	})
+}
+
+func (s *S) TestCadvisorUnitMetricsNoStats(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	c.Assert(err, check.IsNil)
+	host, portStr, err := net.SplitHostPort(srvURL.Host)
+	c.Assert(err, check.IsNil)
+	port, _ := strconv.Atoi(portStr)
+	cont := container.Container{ID: "abc123", HostAddr: host}
+	_, err = cadvisorUnitMetrics(port, &cont)
+	c.Assert(err, check.ErrorMatches, "no stats returned")
+}
@@ -19,10 +19,13 @@ import (
 	"github.com/tsuru/config"
 	"github.com/tsuru/docker-cluster/cluster"
 	"github.com/tsuru/tsuru/db/storage"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/router"
+	"github.com/tsuru/tsuru/volume"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -72,9 +75,12 @@ type Container struct {
 	BuildingImage           string
 	LastStatusUpdate        time.Time
 	LastSuccessStatusUpdate time.Time
+	StatusSince             time.Time
 	LockedUntil             time.Time
 	Routable                bool `bson:"-"`
 	ExposedPort             string
+	LastTermination         *provision.UnitTermination  `bson:",omitempty"`
+	Healthchecks            []provision.UnitHealthcheck `bson:",omitempty"`
 }
 
 func (c *Container) ShortID() string {
@@ -254,6 +260,7 @@ func (c *Container) ExpectedStatus() provision.Status {
 }
 
 func (c *Container) SetStatus(p DockerProvisioner, status provision.Status, updateDB bool) error {
+	changed := c.Status != status.String()
 	c.Status = status.String()
 	c.LastStatusUpdate = time.Now().In(time.UTC)
 	if c.Status != provision.StatusError.String() {
@@ -264,6 +271,10 @@ func (c *Container) SetStatus(p DockerProvisioner, status provision.Status, upda
 		"statusbeforeerror": c.StatusBeforeError,
 		"laststatusupdate":  c.LastStatusUpdate,
 	}
+	if changed {
+		c.StatusSince = c.LastStatusUpdate
+		updateData["statussince"] = c.StatusSince
+	}
 	if c.Status == provision.StatusStarted.String() ||
 		c.Status == provision.StatusStarting.String() ||
 		c.Status == provision.StatusStopped.String() {
@@ -278,6 +289,50 @@ func (c *Container) SetStatus(p DockerProvisioner, status provision.Status, upda
 	return coll.Update(bson.M{"id": c.ID, "status": bson.M{"$ne": provision.StatusBuilding.String()}}, bson.M{"$set": updateData})
 }
 
+// SetLastTermination records an unexpected termination of the container,
+// setting its status to error. It returns false when the termination has
+// already been recorded by another tsuru API instance watching the same node.
+func (c *Container) SetLastTermination(p DockerProvisioner, termination provision.UnitTermination) (bool, error) {
+	changed := c.Status != provision.StatusError.String()
+	c.Status = provision.StatusError.String()
+	c.LastStatusUpdate = time.Now().In(time.UTC)
+	c.LastTermination = &termination
+	updateData := bson.M{
+		"status":           c.Status,
+		"laststatusupdate": c.LastStatusUpdate,
+		"lasttermination":  c.LastTermination,
+	}
+	if changed {
+		c.StatusSince = c.LastStatusUpdate
+		updateData["statussince"] = c.StatusSince
+	}
+	coll := p.Collection()
+	defer coll.Close()
+	err := coll.Update(bson.M{"id": c.ID, "lasttermination.date": bson.M{"$ne": termination.Date}}, bson.M{"$set": updateData})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddHealthcheck appends the result of a health check to the history of the
+// container, keeping only the last max results.
+func (c *Container) AddHealthcheck(p DockerProvisioner, result provision.UnitHealthcheck, max int) error {
+	c.Healthchecks = append(c.Healthchecks, result)
+	if len(c.Healthchecks) > max {
+		c.Healthchecks = c.Healthchecks[len(c.Healthchecks)-max:]
+	}
+	coll := p.Collection()
+	defer coll.Close()
+	return coll.Update(bson.M{"id": c.ID}, bson.M{"$push": bson.M{"healthchecks": bson.M{
+		"$each":  []provision.UnitHealthcheck{result},
+		"$slice": -max,
+	}}})
+}
+
 func (c *Container) SetImage(p DockerProvisioner, imageId string) error {
 	c.Image = imageId
 	coll := p.Collection()
@@ -310,6 +365,7 @@ type Pty struct {
 	Width  int
 	Height int
 	Term   string
+	Resize <-chan provision.TerminalSize
 }
 
 func (c *Container) Shell(p DockerProvisioner, stdin io.Reader, stdout, stderr io.Writer, pty Pty) error {
@@ -350,7 +406,18 @@ func (c *Container) Shell(p DockerProvisioner, stdin io.Reader, stdout, stderr i
 		return err
 	}
 	p.Cluster().ResizeExecTTY(exec.ID, c.ID, pty.Height, pty.Width)
-	return <-errs
+	for {
+		select {
+		case err = <-errs:
+			return err
+		case size, ok := <-pty.Resize:
+			if !ok {
+				pty.Resize = nil
+				continue
+			}
+			p.Cluster().ResizeExecTTY(exec.ID, c.ID, size.Height, size.Width)
+		}
+	}
 }
 
 type execErr struct {
@@ -420,6 +487,7 @@ func (c *Container) Commit(p DockerProvisioner, writer io.Writer) (string, error
 		}
 		imgSize = fmt.Sprintf("(%.02fMB)", float64(fullSize)/1024/1024)
 	}
+	tsuruIo.WritePhase(writer, tsuruIo.PhaseDistribute)
 	fmt.Fprintf(writer, " ---> Sending image to repository %s\n", imgSize)
 	log.Debugf("image %s generated from container %s", image.ID, c.ID)
 	maxTry, _ := config.GetInt("docker:registry-max-try")
@@ -500,6 +568,12 @@ func (c *Container) hostConfig(app provision.App, isDeploy bool) (*docker.HostCo
 			Type:   driver,
 			Config: opts,
 		}
+		binds, volumeDriver, err := volume.DockerBinds(app.GetName())
+		if err != nil {
+			return nil, err
+		}
+		hostConfig.Binds = append(hostConfig.Binds, binds...)
+		hostConfig.VolumeDriver = volumeDriver
 	} else {
 		hostConfig.OomScoreAdj = 1000
 	}
@@ -523,6 +597,21 @@ func (c *Container) hostConfig(app provision.App, isDeploy bool) (*docker.HostCo
 	return &hostConfig, nil
 }
 
+// CommandHostConfig returns the host config of containers running one-off
+// commands of the app, like isolated commands and jobs. They get the same
+// memory, CPU, log and volume settings as the units of the app, but are
+// never restarted and don't expose ports.
+func CommandHostConfig(app provision.App) (*docker.HostConfig, error) {
+	c := Container{AppName: app.GetName()}
+	hostConfig, err := c.hostConfig(app, false)
+	if err != nil {
+		return nil, err
+	}
+	hostConfig.RestartPolicy = docker.RestartPolicy{}
+	hostConfig.PortBindings = nil
+	return hostConfig, nil
+}
+
 func (c *Container) Start(args *StartArgs) error {
 	done := args.Provisioner.ActionLimiter().Start(c.HostAddr)
 	err := args.Provisioner.Cluster().StartContainer(c.ID, nil)
@@ -565,14 +654,16 @@ func (c *Container) AsUnit(a provision.App) provision.Unit {
 		cType = a.GetPlatform()
 	}
 	return provision.Unit{
-		ID:          c.ID,
-		Name:        c.Name,
-		AppName:     a.GetName(),
-		Type:        cType,
-		Ip:          c.HostAddr,
-		Status:      status,
-		ProcessName: c.ProcessName,
-		Address:     c.Address(),
+		ID:              c.ID,
+		Name:            c.Name,
+		AppName:         a.GetName(),
+		Type:            cType,
+		Ip:              c.HostAddr,
+		Status:          status,
+		ProcessName:     c.ProcessName,
+		Address:         c.Address(),
+		LastTermination: c.LastTermination,
+		Healthchecks:    c.Healthchecks,
 	}
 }
 
@@ -575,6 +575,36 @@ func (s *S) TestContainerSetStatusError(c *check.C) {
 	c.Assert(c2.StatusBeforeError, check.Equals, provision.StatusStarted.String())
 }
 
+func (s *S) TestContainerSetStatusSince(c *check.C) {
+	container := Container{ID: "telnet"}
+	coll := s.p.Collection()
+	defer coll.Close()
+	err := coll.Insert(container)
+	c.Assert(err, check.IsNil)
+	defer coll.Remove(bson.M{"id": container.ID})
+	err = container.SetStatus(s.p, provision.StatusError, true)
+	c.Assert(err, check.IsNil)
+	var c2 Container
+	err = coll.Find(bson.M{"id": container.ID}).One(&c2)
+	c.Assert(err, check.IsNil)
+	c.Assert(c2.StatusSince.IsZero(), check.Equals, false)
+	since := time.Date(2016, 12, 1, 10, 0, 0, 0, time.UTC)
+	err = coll.Update(bson.M{"id": container.ID}, bson.M{"$set": bson.M{"statussince": since}})
+	c.Assert(err, check.IsNil)
+	err = coll.Find(bson.M{"id": container.ID}).One(&c2)
+	c.Assert(err, check.IsNil)
+	err = c2.SetStatus(s.p, provision.StatusError, true)
+	c.Assert(err, check.IsNil)
+	err = coll.Find(bson.M{"id": container.ID}).One(&c2)
+	c.Assert(err, check.IsNil)
+	c.Assert(c2.StatusSince.Equal(since), check.Equals, true)
+	err = c2.SetStatus(s.p, provision.StatusStarted, true)
+	c.Assert(err, check.IsNil)
+	err = coll.Find(bson.M{"id": container.ID}).One(&c2)
+	c.Assert(err, check.IsNil)
+	c.Assert(c2.StatusSince.After(since), check.Equals, true)
+}
+
 func (s *S) TestContainerSetStatusBuilding(c *check.C) {
 	c1 := Container{
 		ID:     "something-300",
@@ -1039,3 +1069,17 @@ func (s *S) TestContainerValidAddr(c *check.C) {
 	c.Assert((&Container{HostAddr: "1.1.1.1", HostPort: "0"}).ValidAddr(), check.Equals, false)
 	c.Assert((&Container{HostAddr: "1.1.1.1", HostPort: "123"}).ValidAddr(), check.Equals, true)
 }
+
+func (s *S) TestCommandHostConfig(c *check.C) {
+	app := provisiontest.NewFakeApp("app-name", "brainfuck", 1)
+	app.Memory = 15
+	app.Swap = 15
+	app.CpuShare = 50
+	hostConfig, err := CommandHostConfig(app)
+	c.Assert(err, check.IsNil)
+	c.Assert(hostConfig.Memory, check.Equals, int64(15))
+	c.Assert(hostConfig.MemorySwap, check.Equals, int64(30))
+	c.Assert(hostConfig.CPUShares, check.Equals, int64(50))
+	c.Assert(hostConfig.RestartPolicy, check.DeepEquals, docker.RestartPolicy{})
+	c.Assert(hostConfig.PortBindings, check.IsNil)
+}
@@ -5,15 +5,16 @@
 package docker
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/pkg/errors"
+	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/action"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/image"
@@ -116,6 +117,9 @@ func (p *dockerProvisioner) runReplaceUnitsPipeline(w io.Writer, a provision.App
 			&provisionUnbindOldUnits,
 		)
 	}
+	if evt != nil {
+		pipeline.SetRecorder(evt)
+	}
 	err := pipeline.Execute(args)
 	if err != nil {
 		return nil, err
@@ -144,6 +148,9 @@ func (p *dockerProvisioner) runCreateUnitsPipeline(w io.Writer, a provision.App,
 		&setRouterHealthcheck,
 		&updateAppImage,
 	)
+	if evt != nil {
+		pipeline.SetRecorder(evt)
+	}
 	err := pipeline.Execute(args)
 	if err != nil {
 		return nil, err
@@ -303,12 +310,73 @@ func (p *dockerProvisioner) rebalanceContainersByFilter(writer io.Writer, appFil
 	return p, p.moveContainerList(containers, "", writer)
 }
 
+// rebalanceContainersByHost moves all containers in the host to other nodes.
+// Containers are moved in waves, so the units of an app are only moved after
+// the units of the apps it depends on are running in their new nodes.
 func (p *dockerProvisioner) rebalanceContainersByHost(address string, w io.Writer) error {
 	containers, err := p.listContainersByHost(address)
 	if err != nil {
 		return err
 	}
-	return p.moveContainerList(containers, "", w)
+	waves, err := containersByDependencies(containers)
+	if err != nil {
+		if _, ok := err.(*app.DependencyCycleError); !ok {
+			return err
+		}
+		fmt.Fprintf(w, "Ignoring app dependencies: %s\n", err)
+		waves = [][]container.Container{containers}
+	}
+	for _, wave := range waves {
+		err = p.moveContainerList(wave, "", w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containersByDependencies splits the containers in waves, with the units of
+// each app in the wave after the last wave holding units of its dependencies.
+// Apps without dependencies among the containers are in the first wave.
+func containersByDependencies(containers []container.Container) ([][]container.Container, error) {
+	byApp := make(map[string][]container.Container)
+	var apps []app.App
+	for _, c := range containers {
+		if _, ok := byApp[c.AppName]; !ok {
+			a, err := app.GetByName(c.AppName)
+			if err != nil && err != app.ErrAppNotFound {
+				return nil, err
+			}
+			if a == nil {
+				a = &app.App{Name: c.AppName}
+			}
+			apps = append(apps, *a)
+		}
+		byApp[c.AppName] = append(byApp[c.AppName], c)
+	}
+	sorted, err := app.SortByDependencies(apps)
+	if err != nil {
+		return nil, err
+	}
+	level := make(map[string]int, len(sorted))
+	var waves [][]container.Container
+	for _, a := range sorted {
+		var l int
+		for _, dep := range a.Dependencies {
+			if depLevel, ok := level[dep]; ok && depLevel+1 > l {
+				l = depLevel + 1
+			}
+		}
+		level[a.Name] = l
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+		waves[l] = append(waves[l], byApp[a.Name]...)
+	}
+	if len(waves) == 0 {
+		waves = [][]container.Container{containers}
+	}
+	return waves, nil
 }
 
 func (p *dockerProvisioner) rebalanceContainers(writer io.Writer, dryRun bool) error {
@@ -316,16 +384,43 @@ func (p *dockerProvisioner) rebalanceContainers(writer io.Writer, dryRun bool) e
 	return err
 }
 
-func (p *dockerProvisioner) runCommandInContainer(image string, command string, app provision.App) (bytes.Buffer, error) {
-	var output bytes.Buffer
+// defaultRunCommandTimeout is the maximum duration of commands run in new
+// containers, when docker:run-command-timeout is not set.
+const defaultRunCommandTimeout = time.Hour
+
+func runCommandTimeout() time.Duration {
+	timeout, _ := config.GetInt("docker:run-command-timeout")
+	if timeout <= 0 {
+		return defaultRunCommandTimeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// runCommandInContainer runs the command in a new container from the given
+// image, with the given environment variables, removing the container after
+// the command finishes. The container has the same resource limits and
+// volumes as the units of the app. The stderr of the command is discarded
+// when stderr is nil. Commands running for longer than
+// docker:run-command-timeout are killed.
+func (p *dockerProvisioner) runCommandInContainer(image string, app provision.App, env []string, stdout, stderr io.Writer, command string, args ...string) error {
+	hostConfig, err := container.CommandHostConfig(app)
+	if err != nil {
+		return err
+	}
 	createOptions := docker.CreateContainerOptions{
 		Config: &docker.Config{
 			AttachStdout: true,
 			AttachStderr: true,
 			Image:        image,
+			Env:          env,
 			Entrypoint:   []string{"/bin/bash", "-c"},
-			Cmd:          []string{command},
+			Cmd:          append([]string{command}, args...),
+			Memory:       hostConfig.Memory,
+			MemorySwap:   hostConfig.MemorySwap,
+			CPUShares:    hostConfig.CPUShares,
+			SecurityOpts: hostConfig.SecurityOpt,
 		},
+		HostConfig: hostConfig,
 	}
 	cluster := p.Cluster()
 	schedOpts := &container.SchedulerOpts{
@@ -338,7 +433,7 @@ func (p *dockerProvisioner) runCommandInContainer(image string, command string,
 		schedOpts.LimiterDone()
 	}
 	if err != nil {
-		return output, err
+		return err
 	}
 	defer func() {
 		done := p.ActionLimiter().Start(hostAddr)
@@ -347,14 +442,16 @@ func (p *dockerProvisioner) runCommandInContainer(image string, command string,
 	}()
 	attachOptions := docker.AttachToContainerOptions{
 		Container:    cont.ID,
-		OutputStream: &output,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
 		Stream:       true,
 		Stdout:       true,
+		Stderr:       stderr != nil,
 		Success:      make(chan struct{}),
 	}
 	waiter, err := cluster.AttachToContainerNonBlocking(attachOptions)
 	if err != nil {
-		return output, err
+		return err
 	}
 	<-attachOptions.Success
 	close(attachOptions.Success)
@@ -362,8 +459,25 @@ func (p *dockerProvisioner) runCommandInContainer(image string, command string,
 	err = cluster.StartContainer(cont.ID, nil)
 	done()
 	if err != nil {
-		return output, err
+		return err
+	}
+	waitDone := make(chan struct{})
+	go func() {
+		waiter.Wait()
+		close(waitDone)
+	}()
+	timeout := runCommandTimeout()
+	select {
+	case <-waitDone:
+	case <-time.After(timeout):
+		return errors.Errorf("command timed out after %s", timeout)
+	}
+	contData, err := cluster.InspectContainer(cont.ID)
+	if err != nil {
+		return err
 	}
-	waiter.Wait()
-	return output, nil
+	if !contData.State.Running && contData.State.ExitCode != 0 {
+		return &provision.ExitError{Code: contData.State.ExitCode}
+	}
+	return nil
 }
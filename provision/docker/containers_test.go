@@ -428,6 +428,41 @@ func (s *S) TestRebalanceContainersByHost(c *check.C) {
 	c.Assert(c2, check.HasLen, 5)
 }
 
+func (s *S) TestContainersByDependencies(c *check.C) {
+	for _, a := range []app.App{
+		{Name: "web", Dependencies: []string{"api"}},
+		{Name: "api", Dependencies: []string{"db"}},
+		{Name: "db"},
+		{Name: "other", Dependencies: []string{"notmoved"}},
+	} {
+		err := s.storage.Apps().Insert(a)
+		c.Assert(err, check.IsNil)
+	}
+	containers := []container.Container{
+		{ID: "web-1", AppName: "web"},
+		{ID: "api-1", AppName: "api"},
+		{ID: "db-1", AppName: "db"},
+		{ID: "other-1", AppName: "other"},
+		{ID: "web-2", AppName: "web"},
+		{ID: "removed-1", AppName: "removed"},
+	}
+	waves, err := containersByDependencies(containers)
+	c.Assert(err, check.IsNil)
+	var ids [][]string
+	for _, wave := range waves {
+		var waveIDs []string
+		for _, cont := range wave {
+			waveIDs = append(waveIDs, cont.ID)
+		}
+		ids = append(ids, waveIDs)
+	}
+	c.Assert(ids, check.DeepEquals, [][]string{
+		{"db-1", "other-1", "removed-1"},
+		{"api-1"},
+		{"web-1", "web-2"},
+	})
+}
+
 func (s *S) TestAppLocker(c *check.C) {
 	appName := "myapp"
 	appDB := &app.App{Name: appName}
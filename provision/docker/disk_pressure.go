@@ -0,0 +1,231 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+	"github.com/tsuru/docker-cluster/cluster"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/permission"
+)
+
+const (
+	// diskPressureMetadata is set in the metadata of nodes whose disk usage
+	// is above the threshold, holding the time when it was detected. No
+	// units are scheduled to these nodes.
+	diskPressureMetadata = "disk-pressure"
+
+	diskPressureEventKind         = "disk-pressure"
+	diskPressureResolvedEventKind = "disk-pressure-resolved"
+
+	defaultDiskPressureInterval = time.Minute
+)
+
+type diskPressureData struct {
+	Used           uint64
+	Total          uint64
+	Ratio          float64
+	Threshold      float64
+	RemovedImages  []string `json:",omitempty"`
+	ImageGCFailure string   `json:",omitempty"`
+}
+
+// diskPressureMonitor periodically checks the disk usage reported by the
+// storage driver of each node. Nodes crossing the threshold are marked with
+// the disk-pressure metadata, so the scheduler skips them, and an event is
+// created. When imageGC is set, images not used by any container in the node
+// are also removed.
+type diskPressureMonitor struct {
+	provisioner *dockerProvisioner
+	threshold   float64
+	interval    time.Duration
+	imageGC     bool
+	quit        chan bool
+	wg          sync.WaitGroup
+}
+
+func newDiskPressureMonitor(p *dockerProvisioner, threshold float64, interval time.Duration, imageGC bool) *diskPressureMonitor {
+	if interval <= 0 {
+		interval = defaultDiskPressureInterval
+	}
+	return &diskPressureMonitor{
+		provisioner: p,
+		threshold:   threshold,
+		interval:    interval,
+		imageGC:     imageGC,
+		quit:        make(chan bool),
+	}
+}
+
+func (m *diskPressureMonitor) start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-m.quit:
+				return
+			case <-time.After(m.interval):
+			}
+			err := m.runOnce()
+			if err != nil {
+				log.Errorf("[disk-pressure] unable to check nodes: %s", err)
+			}
+		}
+	}()
+}
+
+func (m *diskPressureMonitor) Shutdown() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *diskPressureMonitor) String() string {
+	return "node disk pressure monitor"
+}
+
+func (m *diskPressureMonitor) runOnce() error {
+	nodes, err := m.provisioner.Cluster().UnfilteredNodes()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		err = m.checkNode(node)
+		if err != nil {
+			log.Errorf("[disk-pressure] unable to check node %s: %s", node.Address, err)
+		}
+	}
+	return nil
+}
+
+func (m *diskPressureMonitor) checkNode(node cluster.Node) error {
+	client, err := node.Client()
+	if err != nil {
+		return err
+	}
+	info, err := client.Info()
+	if err != nil {
+		return err
+	}
+	used, total := driverDiskUsage(info.DriverStatus)
+	if total == 0 {
+		return nil
+	}
+	return m.updateNode(node, used, total)
+}
+
+// updateNode marks or unmarks the node as under disk pressure, creating an
+// event whenever its state changes.
+func (m *diskPressureMonitor) updateNode(node cluster.Node, used, total uint64) error {
+	data := diskPressureData{
+		Used:      used,
+		Total:     total,
+		Ratio:     float64(used) / float64(total),
+		Threshold: m.threshold,
+	}
+	underPressure := data.Ratio >= m.threshold
+	wasUnderPressure := node.Metadata[diskPressureMetadata] != ""
+	if underPressure == wasUnderPressure {
+		return nil
+	}
+	kind := diskPressureResolvedEventKind
+	metadata := map[string]string{diskPressureMetadata: ""}
+	if underPressure {
+		kind = diskPressureEventKind
+		metadata[diskPressureMetadata] = time.Now().UTC().Format(time.RFC3339)
+	}
+	_, err := m.provisioner.Cluster().UpdateNode(cluster.Node{Address: node.Address, Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeNode, Value: node.Address},
+		InternalKind: kind,
+		CustomData:   data,
+		Allowed:      event.Allowed(permission.PermPoolReadEvents, permission.Context(permission.CtxPool, node.Metadata["pool"])),
+	})
+	if err != nil {
+		return err
+	}
+	if underPressure {
+		log.Errorf("[disk-pressure] node %s is using %.2f%% of its disk, no units will be scheduled to it", node.Address, data.Ratio*100)
+		if m.imageGC {
+			data.RemovedImages, err = removeUnusedImages(node)
+			if err != nil {
+				data.ImageGCFailure = err.Error()
+			}
+		}
+	}
+	return evt.DoneCustomData(nil, data)
+}
+
+// removeUnusedImages removes the images not used by any container in the
+// node, returning the IDs of the removed images.
+func removeUnusedImages(node cluster.Node) ([]string, error) {
+	client, err := node.Client()
+	if err != nil {
+		return nil, err
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		used[c.Image] = true
+	}
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, img := range images {
+		if imageInUse(img, used) {
+			continue
+		}
+		err = client.RemoveImage(img.ID)
+		if err != nil {
+			log.Errorf("[disk-pressure] unable to remove image %s from node %s: %s", img.ID, node.Address, err)
+			continue
+		}
+		removed = append(removed, img.ID)
+	}
+	return removed, nil
+}
+
+func imageInUse(img docker.APIImages, used map[string]bool) bool {
+	if used[img.ID] {
+		return true
+	}
+	for _, tag := range img.RepoTags {
+		if used[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByDiskPressure removes the nodes under disk pressure from the list
+// of nodes available for new units.
+func filterByDiskPressure(nodes []cluster.Node) ([]cluster.Node, error) {
+	nodeList := make([]cluster.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Metadata[diskPressureMetadata] != "" {
+			log.Errorf("Node %q is under disk pressure since %s, skipping it.", net.URLToHost(node.Address), node.Metadata[diskPressureMetadata])
+			continue
+		}
+		nodeList = append(nodeList, node)
+	}
+	if len(nodeList) == 0 && len(nodes) > 0 {
+		return nil, errors.Errorf("all %d nodes are under disk pressure, refusing to schedule units", len(nodes))
+	}
+	return nodeList, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/tsuru/docker-cluster/cluster"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestFilterByDiskPressure(c *check.C) {
+	nodes := []cluster.Node{
+		{Address: "http://n1:2375", Metadata: map[string]string{"pool": "p1"}},
+		{Address: "http://n2:2375", Metadata: map[string]string{"pool": "p1", diskPressureMetadata: "2016-12-20T10:00:00Z"}},
+	}
+	filtered, err := filterByDiskPressure(nodes)
+	c.Assert(err, check.IsNil)
+	c.Assert(filtered, check.HasLen, 1)
+	c.Assert(filtered[0].Address, check.Equals, "http://n1:2375")
+	_, err = filterByDiskPressure(nodes[1:])
+	c.Assert(err, check.ErrorMatches, "all 1 nodes are under disk pressure, refusing to schedule units")
+	filtered, err = filterByDiskPressure(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(filtered, check.HasLen, 0)
+}
+
+func (s *S) TestDiskPressureMonitorUpdateNode(c *check.C) {
+	monitor := newDiskPressureMonitor(s.p, 0.9, time.Minute, false)
+	node, err := s.p.Cluster().GetNode(s.server.URL())
+	c.Assert(err, check.IsNil)
+	err = monitor.updateNode(node, 50, 100)
+	c.Assert(err, check.IsNil)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+	err = monitor.updateNode(node, 95, 100)
+	c.Assert(err, check.IsNil)
+	node, err = s.p.Cluster().GetNode(s.server.URL())
+	c.Assert(err, check.IsNil)
+	c.Assert(node.Metadata[diskPressureMetadata], check.Not(check.Equals), "")
+	c.Assert(node.Metadata["pool"], check.Equals, "test-default")
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeNode, Value: s.server.URL()},
+		Kind:   diskPressureEventKind,
+	}, eventtest.HasEvent)
+	err = monitor.updateNode(node, 96, 100)
+	c.Assert(err, check.IsNil)
+	evts, err := event.All()
+	c.Assert(err, check.IsNil)
+	c.Assert(evts, check.HasLen, 1)
+	err = monitor.updateNode(node, 10, 100)
+	c.Assert(err, check.IsNil)
+	node, err = s.p.Cluster().GetNode(s.server.URL())
+	c.Assert(err, check.IsNil)
+	_, ok := node.Metadata[diskPressureMetadata]
+	c.Assert(ok, check.Equals, false)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeNode, Value: s.server.URL()},
+		Kind:   diskPressureResolvedEventKind,
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestRemoveUnusedImages(c *check.C) {
+	cont, err := s.newContainer(nil, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	err = s.newFakeImage(s.p, "tsuru/unused", nil)
+	c.Assert(err, check.IsNil)
+	node, err := s.p.Cluster().GetNode(s.server.URL())
+	c.Assert(err, check.IsNil)
+	client, err := node.Client()
+	c.Assert(err, check.IsNil)
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	c.Assert(err, check.IsNil)
+	removed, err := removeUnusedImages(node)
+	c.Assert(err, check.IsNil)
+	c.Assert(removed, check.HasLen, len(images)-1)
+	images, err = client.ListImages(docker.ListImagesOptions{})
+	c.Assert(err, check.IsNil)
+	c.Assert(images, check.HasLen, 1)
+}
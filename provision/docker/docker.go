@@ -20,6 +20,7 @@ import (
 	"github.com/tsuru/tsuru/action"
 	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/event"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/provision"
@@ -85,6 +86,7 @@ func (p *dockerProvisioner) deployPipeline(app provision.App, imageId string, co
 	if evt == nil {
 		writer = ioutil.Discard
 	}
+	tsuruIo.WritePhase(writer, tsuruIo.PhaseBuild)
 	args := runContainerActionsArgs{
 		app:           app,
 		imageID:       imageId,
@@ -95,6 +97,9 @@ func (p *dockerProvisioner) deployPipeline(app provision.App, imageId string, co
 		provisioner:   p,
 		event:         evt,
 	}
+	if evt != nil {
+		pipeline.SetRecorder(evt)
+	}
 	err = pipeline.Execute(args)
 	if err != nil {
 		log.Errorf("error on execute deploy pipeline for app %s - %s", app.GetName(), err)
@@ -28,6 +28,7 @@ import (
 	_ "github.com/tsuru/tsuru/iaas/ec2"
 	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/docker/container"
 	"github.com/tsuru/tsuru/provision/docker/healer"
 	"gopkg.in/mgo.v2"
@@ -37,6 +38,7 @@ func init() {
 	api.RegisterHandler("/docker/container/{id}/move", "POST", api.AuthorizationRequiredHandler(moveContainerHandler))
 	api.RegisterHandler("/docker/containers/move", "POST", api.AuthorizationRequiredHandler(moveContainersHandler))
 	api.RegisterHandler("/docker/containers/rebalance", "POST", api.AuthorizationRequiredHandler(rebalanceContainersHandler))
+	api.RegisterHandler("/docker/node/{address:.*}/drain", "POST", api.AuthorizationRequiredHandler(nodeDrainHandler))
 	api.RegisterHandler("/docker/healing", "GET", api.AuthorizationRequiredHandler(healingHistoryHandler))
 	api.RegisterHandler("/docker/autoscale", "GET", api.AuthorizationRequiredHandler(autoScaleHistoryHandler))
 	api.RegisterHandler("/docker/autoscale/config", "GET", api.AuthorizationRequiredHandler(autoScaleGetConfig))
@@ -289,6 +291,51 @@ func moveContainersHandler(w http.ResponseWriter, r *http.Request, t auth.Token)
 	return nil
 }
 
+// title: drain node
+// path: /docker/node/{address}/drain
+// method: POST
+// produce: application/x-json-stream
+// responses:
+//   200: Ok
+//   401: Unauthorized
+//   404: Not found
+func nodeDrainHandler(w http.ResponseWriter, r *http.Request, t auth.Token) (err error) {
+	r.ParseForm()
+	address := r.URL.Query().Get(":address")
+	node, err := mainDockerProvisioner.GetNode(address)
+	if err != nil {
+		if err == provision.ErrNodeNotFound {
+			return &tsuruErrors.HTTP{Code: http.StatusNotFound, Message: err.Error()}
+		}
+		return err
+	}
+	poolContext := permission.Context(permission.CtxPool, node.Pool())
+	if !permission.Check(t, permission.PermNodeUpdate, poolContext) {
+		return permission.ErrUnauthorized
+	}
+	evt, err := event.New(&event.Opts{
+		Target:     event.Target{Type: event.TargetTypeNode, Value: node.Address()},
+		Kind:       permission.PermNodeUpdate,
+		Owner:      t,
+		CustomData: event.FormToCustomData(r.Form),
+		Allowed:    event.Allowed(permission.PermPoolReadEvents, poolContext),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { evt.Done(err) }()
+	w.Header().Set("Content-Type", "application/x-json-stream")
+	keepAliveWriter := tsuruIo.NewKeepAliveWriter(w, 15*time.Second, "")
+	defer keepAliveWriter.Stop()
+	writer := &tsuruIo.SimpleJsonMessageEncoderWriter{Encoder: json.NewEncoder(keepAliveWriter)}
+	err = mainDockerProvisioner.DrainNode(node.Address(), writer)
+	if err != nil {
+		return errors.Wrap(err, "Error trying to drain node")
+	}
+	fmt.Fprintf(writer, "Node %s drained successfully!\n", node.Address())
+	return nil
+}
+
 func moveContainersPermissionContexts(from, to string) ([]permission.PermissionContext, error) {
 	originHost, err := mainDockerProvisioner.GetNodeByHost(from)
 	if err != nil {
@@ -207,6 +207,43 @@ func (s *HandlersSuite) TestMoveContainersHandler(c *check.C) {
 	}, eventtest.HasEvent)
 }
 
+func (s *HandlersSuite) TestNodeDrainHandler(c *check.C) {
+	mainDockerProvisioner.Cluster().Register(cluster.Node{Address: "http://localhost:2375"})
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("POST", "/docker/node/http://localhost:2375/drain", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	server := api.RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusOK)
+	c.Assert(recorder.Header().Get("Content-Type"), check.Equals, "application/x-json-stream")
+	validJson := fmt.Sprintf("[%s]", strings.Replace(strings.Trim(recorder.Body.String(), "\n "), "\n", ",", -1))
+	var result []tsuruIo.SimpleJsonMessage
+	err = json.Unmarshal([]byte(validJson), &result)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.DeepEquals, []tsuruIo.SimpleJsonMessage{
+		{Message: "Node http://localhost:2375 drained successfully!\n"},
+	})
+	node, err := mainDockerProvisioner.Cluster().GetNode("http://localhost:2375")
+	c.Assert(err, check.IsNil)
+	c.Assert(node.CreationStatus, check.Equals, cluster.NodeCreationStatusDisabled)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeNode, Value: "http://localhost:2375"},
+		Owner:  s.token.GetUserName(),
+		Kind:   "node.update",
+	}, eventtest.HasEvent)
+}
+
+func (s *HandlersSuite) TestNodeDrainHandlerNotFound(c *check.C) {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("POST", "/docker/node/http://localhost:2375/drain", nil)
+	c.Assert(err, check.IsNil)
+	request.Header.Set("Authorization", "bearer "+s.token.GetValue())
+	server := api.RunServer(true)
+	server.ServeHTTP(recorder, request)
+	c.Assert(recorder.Code, check.Equals, http.StatusNotFound)
+}
+
 func (s *HandlersSuite) TestMoveContainerNotFound(c *check.C) {
 	recorder := httptest.NewRecorder()
 	mainDockerProvisioner.Cluster().Register(cluster.Node{Address: "http://127.0.0.1:2375"})
@@ -0,0 +1,173 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package healer
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	tsuruHealer "github.com/tsuru/tsuru/healer"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const unitHealerEventKind = "unit-healer"
+
+var stuckStatuses = []string{
+	provision.StatusError.String(),
+	provision.StatusCreated.String(),
+	provision.StatusStarting.String(),
+}
+
+// UnitHealer recreates units that have been stuck in the error, created or
+// starting status, according to the unit healer config of the pool of their
+// apps. Units are moved to a new container, which also swaps their routes.
+type UnitHealer struct {
+	provisioner DockerProvisioner
+	locker      AppLocker
+	done        chan bool
+}
+
+type UnitHealerArgs struct {
+	Provisioner DockerProvisioner
+	Done        chan bool
+	Locker      AppLocker
+}
+
+type unitHealerCustomData struct {
+	Pool      string
+	Container container.Container
+}
+
+func NewUnitHealer(args UnitHealerArgs) *UnitHealer {
+	return &UnitHealer{
+		provisioner: args.Provisioner,
+		done:        args.Done,
+		locker:      args.Locker,
+	}
+}
+
+func (h *UnitHealer) RunUnitHealer() {
+	for {
+		h.runUnitHealerOnce()
+		select {
+		case <-h.done:
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+func (h *UnitHealer) Shutdown() {
+	h.done <- true
+}
+
+func (h *UnitHealer) String() string {
+	return "unit healer"
+}
+
+func (h *UnitHealer) runUnitHealerOnce() {
+	configs, err := tsuruHealer.GetUnitConfig()
+	if err != nil {
+		log.Errorf("Units healing: couldn't load config: %s", err)
+		return
+	}
+	var enabled bool
+	for _, conf := range configs {
+		enabled = enabled || conf.IsEnabled()
+	}
+	if !enabled {
+		return
+	}
+	containers, err := listStuckContainers(h.provisioner)
+	if err != nil {
+		log.Errorf("Units healing: couldn't list stuck containers: %s", err)
+		return
+	}
+	for _, cont := range containers {
+		err = h.healUnitIfNeeded(cont)
+		if err != nil {
+			log.Errorf("Units healing: couldn't heal container: %s", err)
+		}
+	}
+}
+
+func (h *UnitHealer) healUnitIfNeeded(cont container.Container) error {
+	a, err := app.GetByName(cont.AppName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to heal %q couldn't get app %q", cont.ID, cont.AppName)
+	}
+	conf, err := tsuruHealer.LoadUnitConfig(a.Pool)
+	if err != nil {
+		return err
+	}
+	if !conf.IsEnabled() || time.Since(cont.StatusSince) < conf.StuckTime() {
+		return nil
+	}
+	locked := h.locker.Lock(cont.AppName)
+	if !locked {
+		return errors.Errorf("unable to heal %q couldn't lock app %s", cont.ID, cont.AppName)
+	}
+	defer h.locker.Unlock(cont.AppName)
+	current, err := h.provisioner.GetContainer(cont.ID)
+	if err != nil {
+		if _, isNotFound := err.(*provision.UnitNotFoundError); isNotFound {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to heal %q couldn't verify it still exists", cont.ID)
+	}
+	if current.Status != cont.Status || !current.StatusSince.Equal(cont.StatusSince) {
+		return nil
+	}
+	max := conf.HealsPerHour()
+	claimed, err := tsuruHealer.ClaimUnitHeal(a.Pool, max, time.Now().UTC())
+	if err != nil {
+		return errors.Wrapf(err, "unable to heal %q couldn't check the heals of pool %q", cont.ID, a.Pool)
+	}
+	if !claimed {
+		log.Debugf("Units healing: skipping %q, %d units of pool %q healed in the last hour", cont.ID, max, a.Pool)
+		return nil
+	}
+	log.Errorf("Initiating healing process for container %q, %s since %s.", cont.ID, cont.Status, cont.StatusSince)
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeContainer, Value: cont.ID},
+		InternalKind: unitHealerEventKind,
+		CustomData:   unitHealerCustomData{Pool: a.Pool, Container: cont},
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permission.CtxTeam, a.Teams),
+			permission.Context(permission.CtxApp, a.Name),
+			permission.Context(permission.CtxPool, a.Pool),
+		)...),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error trying to insert unit healing event, healing aborted")
+	}
+	containerHealer := ContainerHealer{provisioner: h.provisioner, locker: h.locker}
+	newCont, healErr := containerHealer.healContainer(cont)
+	if healErr != nil {
+		healErr = errors.Errorf("Error healing container %q: %s", cont.ID, healErr.Error())
+	}
+	err = evt.DoneCustomData(healErr, newCont)
+	if err != nil {
+		log.Errorf("Error trying to update unit healing event: %s", err)
+	}
+	return healErr
+}
+
+// listStuckContainers lists the containers in the error, created or starting
+// status. Containers whose status changed before the status change time was
+// recorded are ignored.
+func listStuckContainers(p DockerProvisioner) ([]container.Container, error) {
+	return p.ListContainers(bson.M{
+		"id":          bson.M{"$ne": ""},
+		"appname":     bson.M{"$ne": ""},
+		"status":      bson.M{"$in": stuckStatuses},
+		"statussince": bson.M{"$gt": time.Time{}},
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package healer
+
+import (
+	"time"
+
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	tsuruHealer "github.com/tsuru/tsuru/healer"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+	"github.com/tsuru/tsuru/provision/docker/dockertest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRunUnitHealer(c *check.C) {
+	p, err := dockertest.StartMultipleServersCluster()
+	c.Assert(err, check.IsNil)
+	defer p.Destroy()
+	newFakeAppInDB("app1", "python", 0)
+	enabled, stuckTime, maxHeals := true, 300, 1
+	err = tsuruHealer.UpdateUnitConfig("", tsuruHealer.UnitHealerConfig{
+		Enabled:         &enabled,
+		MaxStuckTime:    &stuckTime,
+		MaxHealsPerHour: &maxHeals,
+	})
+	c.Assert(err, check.IsNil)
+	stuckSince := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+	recentSince := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	p.SetContainers("localhost", []container.Container{
+		{ID: "cont1", AppName: "app1", Status: provision.StatusError.String(), StatusSince: stuckSince},
+		{ID: "cont2", AppName: "app1", Status: provision.StatusStarting.String(), StatusSince: stuckSince},
+		{ID: "cont3", AppName: "app1", Status: provision.StatusError.String(), StatusSince: recentSince},
+		{ID: "cont4", AppName: "app1", Status: provision.StatusStarted.String(), StatusSince: stuckSince},
+		{ID: "cont5", AppName: "app1", Status: provision.StatusError.String()},
+	})
+	healer := NewUnitHealer(UnitHealerArgs{Provisioner: p, Locker: dockertest.NewFakeLocker()})
+	healer.runUnitHealerOnce()
+	movings := p.Movings()
+	c.Assert(movings, check.HasLen, 1)
+	c.Assert(movings[0].ContainerID == "cont1" || movings[0].ContainerID == "cont2", check.Equals, true)
+	c.Assert(eventtest.EventDesc{
+		Target: event.Target{Type: event.TargetTypeContainer, Value: movings[0].ContainerID},
+		Kind:   "unit-healer",
+	}, eventtest.HasEvent)
+	healer.runUnitHealerOnce()
+	c.Assert(p.Movings(), check.HasLen, 1)
+}
+
+func (s *S) TestRunUnitHealerDisabled(c *check.C) {
+	p, err := dockertest.StartMultipleServersCluster()
+	c.Assert(err, check.IsNil)
+	defer p.Destroy()
+	newFakeAppInDB("app1", "python", 0)
+	p.SetContainers("localhost", []container.Container{
+		{ID: "cont1", AppName: "app1", Status: provision.StatusError.String(), StatusSince: time.Now().UTC().Add(-time.Hour)},
+	})
+	healer := NewUnitHealer(UnitHealerArgs{Provisioner: p, Locker: dockertest.NewFakeLocker()})
+	healer.runUnitHealerOnce()
+	c.Assert(p.Movings(), check.HasLen, 0)
+	c.Assert(p.Queries(), check.HasLen, 0)
+}
@@ -11,44 +11,131 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/docker/container"
+	"gopkg.in/mgo.v2/bson"
 )
 
-func runHealthcheck(cont *container.Container, w io.Writer) error {
-	yamlData, err := image.GetImageTsuruYamlData(cont.Image)
+const (
+	defaultHealthcheckHistorySize = 10
+	maxConcurrentHealthchecks     = 10
+)
+
+type healthcheckConfig struct {
+	path            string
+	method          string
+	status          int
+	match           string
+	matchRE         *regexp.Regexp
+	allowedFailures int
+}
+
+// healthcheckConfigForImage returns the health check described in the
+// tsuru.yaml of the image, or nil if the image has no health check.
+func healthcheckConfigForImage(imageID string) (*healthcheckConfig, error) {
+	yamlData, err := image.GetImageTsuruYamlData(imageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	path := yamlData.Healthcheck.Path
-	method := yamlData.Healthcheck.Method
-	match := yamlData.Healthcheck.Match
-	status := yamlData.Healthcheck.Status
-	allowedFailures := yamlData.Healthcheck.AllowedFailures
-	if path == "" {
-		return nil
+	hc := healthcheckConfig{
+		path:            yamlData.Healthcheck.Path,
+		method:          yamlData.Healthcheck.Method,
+		match:           yamlData.Healthcheck.Match,
+		status:          yamlData.Healthcheck.Status,
+		allowedFailures: yamlData.Healthcheck.AllowedFailures,
 	}
-	path = strings.TrimSpace(strings.TrimLeft(path, "/"))
-	if method == "" {
-		method = "get"
+	if hc.path == "" {
+		return nil, nil
 	}
-	method = strings.ToUpper(method)
-	if status == 0 && match == "" {
-		status = 200
+	hc.path = strings.TrimSpace(strings.TrimLeft(hc.path, "/"))
+	if hc.method == "" {
+		hc.method = "get"
 	}
-	var matchRE *regexp.Regexp
-	if match != "" {
-		match = "(?s)" + match
-		matchRE, err = regexp.Compile(match)
+	hc.method = strings.ToUpper(hc.method)
+	if hc.status == 0 && hc.match == "" {
+		hc.status = 200
+	}
+	if hc.match != "" {
+		hc.match = "(?s)" + hc.match
+		hc.matchRE, err = regexp.Compile(hc.match)
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+	return &hc, nil
+}
+
+// check sends a single health check request to the container, returning the
+// result of the request and the reason of the failure, if any.
+func (hc *healthcheckConfig) check(cont *container.Container) (provision.UnitHealthcheck, error) {
+	result := provision.UnitHealthcheck{Date: time.Now().UTC()}
+	url := fmt.Sprintf("http://%s:%s/%s", cont.HostAddr, cont.HostPort, hc.path)
+	req, err := http.NewRequest(hc.method, url, nil)
+	if err != nil {
+		return result, err
+	}
+	rsp, err := net.Dial5Full60ClientNoKeepAlive.Do(req)
+	result.Latency = time.Since(result.Date)
+	if err != nil {
+		err = errors.Wrapf(err, "healthcheck fail(%s)", cont.ShortID())
+		result.Error = err.Error()
+		return result, err
+	}
+	defer rsp.Body.Close()
+	result.StatusCode = rsp.StatusCode
+	if hc.status != 0 && rsp.StatusCode != hc.status {
+		err = errors.Errorf("healthcheck fail(%s): wrong status code, expected %d, got: %d", cont.ShortID(), hc.status, rsp.StatusCode)
+	} else if hc.matchRE != nil {
+		body, readErr := ioutil.ReadAll(rsp.Body)
+		if readErr != nil {
+			err = readErr
+		} else if !hc.matchRE.Match(body) {
+			err = errors.Errorf("healthcheck fail(%s): unexpected result, expected %q, got: %s", cont.ShortID(), hc.match, string(body))
 		}
 	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, err
+}
+
+func healthcheckHistorySize() int {
+	size, _ := config.GetInt("docker:healthcheck:history-size")
+	if size <= 0 {
+		size = defaultHealthcheckHistorySize
+	}
+	return size
+}
+
+// recordHealthcheck stores the result in the health check history of the
+// container. Failing to store it doesn't affect the health check.
+func (p *dockerProvisioner) recordHealthcheck(cont *container.Container, result provision.UnitHealthcheck) {
+	if cont.ID == "" {
+		return
+	}
+	err := cont.AddHealthcheck(p, result, healthcheckHistorySize())
+	if err != nil {
+		log.Errorf("[healthcheck] unable to record health check of container %s: %s", cont.ShortID(), err)
+	}
+}
+
+func (p *dockerProvisioner) runHealthcheck(cont *container.Container, w io.Writer) error {
+	hc, err := healthcheckConfigForImage(cont.Image)
+	if err != nil {
+		return err
+	}
+	if hc == nil {
+		return nil
+	}
+	allowedFailures := hc.allowedFailures
 	maxWaitTime, _ := config.GetInt("docker:healthcheck:max-time")
 	if maxWaitTime == 0 {
 		maxWaitTime = 120
@@ -56,36 +143,14 @@ func runHealthcheck(cont *container.Container, w io.Writer) error {
 	maxWaitTime = maxWaitTime * int(time.Second)
 	sleepTime := 3 * time.Second
 	startedTime := time.Now()
-	url := fmt.Sprintf("http://%s:%s/%s", cont.HostAddr, cont.HostPort, path)
 	for {
-		var lastError error = nil
-		req, err := http.NewRequest(method, url, nil)
-		if err != nil {
-			return err
-		}
-		rsp, err := net.Dial5Full60ClientNoKeepAlive.Do(req)
-		if err != nil {
-			lastError = errors.Wrapf(err, "healthcheck fail(%s)", cont.ShortID())
-		} else {
-			defer rsp.Body.Close()
-			if status != 0 && rsp.StatusCode != status {
-				lastError = errors.Errorf("healthcheck fail(%s): wrong status code, expected %d, got: %d", cont.ShortID(), status, rsp.StatusCode)
-			} else if matchRE != nil {
-				result, err := ioutil.ReadAll(rsp.Body)
-
-				if err != nil {
-					lastError = err
-				}
-				if !matchRE.Match(result) {
-					lastError = errors.Errorf("healthcheck fail(%s): unexpected result, expected %q, got: %s", cont.ShortID(), match, string(result))
-				}
-			}
-			if lastError != nil {
-				if allowedFailures == 0 {
-					return lastError
-				}
-				allowedFailures--
+		result, lastError := hc.check(cont)
+		p.recordHealthcheck(cont, result)
+		if lastError != nil && result.StatusCode != 0 {
+			if allowedFailures == 0 {
+				return lastError
 			}
+			allowedFailures--
 		}
 		if lastError == nil {
 			fmt.Fprintf(w, " ---> healthcheck successful(%s)\n", cont.ShortID())
@@ -98,3 +163,98 @@ func runHealthcheck(cont *container.Container, w io.Writer) error {
 		time.Sleep(sleepTime)
 	}
 }
+
+// healthcheckMonitor periodically sends the health check request to the
+// started web units of all apps, recording the results in their history.
+// Units checked by another API instance during the current interval are
+// skipped.
+type healthcheckMonitor struct {
+	provisioner *dockerProvisioner
+	interval    time.Duration
+	quit        chan bool
+	wg          sync.WaitGroup
+}
+
+func newHealthcheckMonitor(p *dockerProvisioner, interval time.Duration) *healthcheckMonitor {
+	return &healthcheckMonitor{
+		provisioner: p,
+		interval:    interval,
+		quit:        make(chan bool),
+	}
+}
+
+func (m *healthcheckMonitor) start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-m.quit:
+				return
+			case <-time.After(m.interval):
+			}
+			err := m.runOnce()
+			if err != nil {
+				log.Errorf("[healthcheck] unable to check units: %s", err)
+			}
+		}
+	}()
+}
+
+func (m *healthcheckMonitor) Shutdown() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *healthcheckMonitor) String() string {
+	return "unit healthcheck monitor"
+}
+
+type imageHealthcheck struct {
+	config     *healthcheckConfig
+	webProcess string
+}
+
+func (m *healthcheckMonitor) runOnce() error {
+	containers, err := m.provisioner.ListContainers(bson.M{"status": provision.StatusStarted.String()})
+	if err != nil {
+		return err
+	}
+	images := make(map[string]*imageHealthcheck)
+	sem := make(chan struct{}, maxConcurrentHealthchecks)
+	var wg sync.WaitGroup
+	for i := range containers {
+		c := &containers[i]
+		if n := len(c.Healthchecks); n > 0 && time.Since(c.Healthchecks[n-1].Date) < m.interval/2 {
+			continue
+		}
+		img, ok := images[c.Image]
+		if !ok {
+			img = &imageHealthcheck{}
+			img.config, err = healthcheckConfigForImage(c.Image)
+			if err != nil {
+				log.Errorf("[healthcheck] unable to get healthcheck of image %s: %s", c.Image, err)
+			}
+			img.webProcess, err = image.GetImageWebProcessName(c.Image)
+			if err != nil {
+				log.Errorf("[healthcheck] unable to get web process of image %s: %s", c.Image, err)
+			}
+			images[c.Image] = img
+		}
+		if img.config == nil || c.ProcessName != img.webProcess || !c.ValidAddr() {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(hc *healthcheckConfig) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result, _ := hc.check(c)
+			m.provisioner.recordHealthcheck(c, result)
+		}(img.config)
+	}
+	wg.Wait()
+	return nil
+}
@@ -6,16 +6,19 @@ package docker
 
 import (
 	"bytes"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tsuru/config"
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/app/image"
+	"github.com/tsuru/tsuru/provision"
 	"github.com/tsuru/tsuru/provision/docker/container"
 	"gopkg.in/check.v1"
 	"gopkg.in/mgo.v2/bson"
@@ -46,7 +49,7 @@ func (s *S) TestHealthcheck(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(requests, check.HasLen, 1)
 	c.Assert(requests[0].URL.Path, check.Equals, "/x/y")
@@ -84,11 +87,11 @@ func (s *S) TestHealthcheckWithMatch(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.ErrorMatches, ".*unexpected result, expected \"(?s).*some.*\", got: invalid")
 	c.Assert(requests, check.HasLen, 1)
 	c.Assert(requests[0].Method, check.Equals, "GET")
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(requests, check.HasLen, 2)
 	c.Assert(requests[1].URL.Path, check.Equals, "/x/y")
@@ -118,7 +121,7 @@ func (s *S) TestHealthcheckDefaultCheck(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(requests, check.HasLen, 1)
 	c.Assert(requests[0].Method, check.Equals, "GET")
@@ -140,7 +143,7 @@ func (s *S) TestHealthcheckNoHealthcheck(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(requests, check.HasLen, 0)
 }
@@ -169,7 +172,7 @@ func (s *S) TestHealthcheckNoPath(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(requests, check.HasLen, 0)
 }
@@ -208,7 +211,7 @@ func (s *S) TestHealthcheckKeepsTryingWithServerDown(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(buf.String(), check.Matches, `(?s).*---> healthcheck fail.*?Trying again in 3s.*---> healthcheck successful.*`)
 	c.Assert(requests, check.HasLen, 2)
@@ -239,7 +242,7 @@ func (s *S) TestHealthcheckErrorsAfterMaxTime(c *check.C) {
 	defer config.Unset("docker:healthcheck:max-time")
 	done := make(chan struct{})
 	go func() {
-		err = runHealthcheck(&cont, &buf)
+		err = s.p.runHealthcheck(&cont, &buf)
 		close(done)
 	}()
 	select {
@@ -287,7 +290,7 @@ func (s *S) TestHealthcheckSuccessfulWithAllowedFailures(c *check.C) {
 	host, port, _ := net.SplitHostPort(url.Host)
 	cont := container.Container{AppName: a.Name, HostAddr: host, HostPort: port, Image: imageName}
 	buf := bytes.Buffer{}
-	err = runHealthcheck(&cont, &buf)
+	err = s.p.runHealthcheck(&cont, &buf)
 	c.Assert(err, check.IsNil)
 	c.Assert(buf.String(), check.Matches, `(?s).*---> healthcheck fail.*?Trying again in 3s.*---> healthcheck fail.*?Trying again in 3s.*---> healthcheck successful.*`)
 	c.Assert(requests, check.HasLen, 3)
@@ -298,3 +301,76 @@ func (s *S) TestHealthcheckSuccessfulWithAllowedFailures(c *check.C) {
 	c.Assert(requests[2].Method, check.Equals, "GET")
 	c.Assert(requests[2].URL.Path, check.Equals, "/x/y")
 }
+
+func (s *S) TestHealthcheckRecordsHistory(c *check.C) {
+	config.Set("docker:healthcheck:history-size", 2)
+	defer config.Unset("docker:healthcheck:history-size")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	imageName := "tsuru/app"
+	err := image.SaveImageCustomData(imageName, map[string]interface{}{
+		"healthcheck": map[string]interface{}{"path": "/hc"},
+	})
+	c.Assert(err, check.IsNil)
+	url, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(url.Host)
+	cont := container.Container{ID: "c1", AppName: "myapp1", HostAddr: host, HostPort: port, Image: imageName}
+	coll := s.p.Collection()
+	defer coll.Close()
+	err = coll.Insert(cont)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 3; i++ {
+		err = s.p.runHealthcheck(&cont, ioutil.Discard)
+		c.Assert(err, check.IsNil)
+	}
+	dbCont, err := s.p.GetContainer("c1")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Healthchecks, check.HasLen, 2)
+	c.Assert(dbCont.Healthchecks[0].StatusCode, check.Equals, http.StatusOK)
+	c.Assert(dbCont.Healthchecks[0].Error, check.Equals, "")
+	c.Assert(dbCont.Healthchecks[1].Date.After(dbCont.Healthchecks[0].Date), check.Equals, true)
+	c.Assert(cont.Healthchecks, check.HasLen, 2)
+}
+
+func (s *S) TestHealthcheckMonitorRunOnce(c *check.C) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	imageName := "tsuru/app"
+	err := image.SaveImageCustomData(imageName, map[string]interface{}{
+		"healthcheck": map[string]interface{}{"path": "/hc"},
+		"processes":   map[string]interface{}{"web": "python app.py", "worker": "python worker.py"},
+	})
+	c.Assert(err, check.IsNil)
+	url, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(url.Host)
+	coll := s.p.Collection()
+	defer coll.Close()
+	err = coll.Insert(
+		container.Container{ID: "c1", AppName: "myapp1", ProcessName: "web", HostAddr: host, HostPort: port, Image: imageName, Status: provision.StatusStarted.String()},
+		container.Container{ID: "c2", AppName: "myapp1", ProcessName: "worker", HostAddr: host, HostPort: port, Image: imageName, Status: provision.StatusStarted.String()},
+		container.Container{ID: "c3", AppName: "myapp1", ProcessName: "web", HostAddr: host, HostPort: port, Image: imageName, Status: provision.StatusStopped.String()},
+	)
+	c.Assert(err, check.IsNil)
+	monitor := newHealthcheckMonitor(s.p, time.Minute)
+	err = monitor.runOnce()
+	c.Assert(err, check.IsNil)
+	err = monitor.runOnce()
+	c.Assert(err, check.IsNil)
+	c.Assert(atomic.LoadInt32(&requests), check.Equals, int32(1))
+	dbCont, err := s.p.GetContainer("c1")
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Healthchecks, check.HasLen, 1)
+	c.Assert(dbCont.Healthchecks[0].StatusCode, check.Equals, http.StatusInternalServerError)
+	c.Assert(dbCont.Healthchecks[0].Error, check.Matches, "healthcheck fail.*wrong status code, expected 200, got: 500")
+	for _, id := range []string{"c2", "c3"} {
+		dbCont, err = s.p.GetContainer(id)
+		c.Assert(err, check.IsNil)
+		c.Assert(dbCont.Healthchecks, check.HasLen, 0)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+	"github.com/tsuru/docker-cluster/cluster"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+)
+
+const statsTimeout = 10 * time.Second
+
+// UnitsMetrics samples the cgroup stats of the running containers of the app
+// in the docker nodes. When docker:metrics:cadvisor-port is set, containers
+// are sampled from the cAdvisor agents running in the nodes, falling back to
+// the docker daemon when the agent is unavailable. Containers whose stats
+// can't be read are skipped.
+func (p *dockerProvisioner) UnitsMetrics(app provision.App) ([]provision.UnitMetrics, error) {
+	containers, err := p.listRunnableContainersByApp(app.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	nodes, err := p.Cluster().UnfilteredNodes()
+	if err != nil {
+		return nil, err
+	}
+	nodesByHost := make(map[string]cluster.Node, len(nodes))
+	for _, n := range nodes {
+		nodesByHost[net.URLToHost(n.Address)] = n
+	}
+	cadvisor := cadvisorPort()
+	var wg sync.WaitGroup
+	results := make([]*provision.UnitMetrics, len(containers))
+	for i := range containers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := &containers[i]
+			if cadvisor > 0 {
+				m, err := cadvisorUnitMetrics(cadvisor, c)
+				if err == nil {
+					results[i] = m
+					return
+				}
+				log.Errorf("[metrics] unable to get stats of container %s from cadvisor: %s", c.ShortID(), err)
+			}
+			node, ok := nodesByHost[c.HostAddr]
+			if !ok {
+				log.Errorf("[metrics] node %s of container %s not found", c.HostAddr, c.ShortID())
+				return
+			}
+			stats, err := containerStats(&node, c)
+			if err != nil {
+				log.Errorf("[metrics] unable to get stats of container %s: %s", c.ShortID(), err)
+				return
+			}
+			results[i] = statsToUnitMetrics(c, stats)
+		}(i)
+	}
+	wg.Wait()
+	metrics := make([]provision.UnitMetrics, 0, len(results))
+	for _, m := range results {
+		if m != nil {
+			metrics = append(metrics, *m)
+		}
+	}
+	return metrics, nil
+}
+
+// NodeMetrics samples the docker node, using the information reported by the
+// docker daemon for the number of CPUs, total memory, containers and disk
+// usage of the storage driver, when available. The CPU and used memory are
+// the sum of the usage of the running units in the node.
+func (p *dockerProvisioner) NodeMetrics(n provision.Node) (*provision.NodeMetrics, error) {
+	node, err := p.Cluster().GetNode(n.Address())
+	if err != nil {
+		return nil, err
+	}
+	client, err := node.Client()
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.Info()
+	if err != nil {
+		return nil, err
+	}
+	metrics := provision.NodeMetrics{
+		CPUs:              info.NCPU,
+		MemoryTotal:       uint64(info.MemTotal),
+		Containers:        info.Containers,
+		ContainersRunning: info.ContainersRunning,
+	}
+	metrics.DiskUsed, metrics.DiskTotal = driverDiskUsage(info.DriverStatus)
+	containers, err := p.listRunningContainersByHost(net.URLToHost(n.Address()))
+	if err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	results := make([]*provision.UnitMetrics, len(containers))
+	for i := range containers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := &containers[i]
+			stats, err := containerStats(&node, c)
+			if err != nil {
+				log.Errorf("[metrics] unable to get stats of container %s: %s", c.ShortID(), err)
+				return
+			}
+			results[i] = statsToUnitMetrics(c, stats)
+		}(i)
+	}
+	wg.Wait()
+	for _, m := range results {
+		if m != nil {
+			metrics.CPU += m.CPU
+			metrics.MemoryUsed += m.Memory
+		}
+	}
+	return &metrics, nil
+}
+
+// driverDiskUsage returns the used and total space reported by storage
+// drivers that manage their own pool, like devicemapper.
+func driverDiskUsage(status [][2]string) (uint64, uint64) {
+	var used, total int64
+	for _, entry := range status {
+		switch entry[0] {
+		case "Data Space Used":
+			used, _ = units.FromHumanSize(entry[1])
+		case "Data Space Total":
+			total, _ = units.FromHumanSize(entry[1])
+		}
+	}
+	return uint64(used), uint64(total)
+}
+
+func containerStats(node *cluster.Node, c *container.Container) (*docker.Stats, error) {
+	client, err := node.Client()
+	if err != nil {
+		return nil, err
+	}
+	statsCh := make(chan *docker.Stats, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.Stats(docker.StatsOptions{
+			ID:                c.ID,
+			Stats:             statsCh,
+			Stream:            false,
+			Timeout:           statsTimeout,
+			InactivityTimeout: statsTimeout,
+		})
+	}()
+	var stats *docker.Stats
+	for s := range statsCh {
+		if stats == nil {
+			stats = s
+		}
+	}
+	err = <-errCh
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, errors.New("no stats returned")
+	}
+	return stats, nil
+}
+
+func statsToUnitMetrics(c *container.Container, stats *docker.Stats) *provision.UnitMetrics {
+	m := provision.UnitMetrics{
+		ID:      c.ID,
+		Process: c.ProcessName,
+		CPU:     cpuPercent(stats),
+		Memory:  stats.MemoryStats.Usage,
+	}
+	if stats.MemoryStats.Stats.Cache < m.Memory {
+		m.Memory -= stats.MemoryStats.Stats.Cache
+	}
+	if len(stats.Networks) == 0 {
+		m.NetRx = stats.Network.RxBytes
+		m.NetTx = stats.Network.TxBytes
+	}
+	for _, n := range stats.Networks {
+		m.NetRx += n.RxBytes
+		m.NetTx += n.TxBytes
+	}
+	return &m
+}
+
+// cpuPercent calculates the usage of the container between the two CPU
+// samples returned by docker, as a percentage of one CPU.
+func cpuPercent(stats *docker.Stats) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemCPUUsage) - float64(stats.PreCPUStats.SystemCPUUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	cpus := len(stats.CPUStats.CPUUsage.PercpuUsage)
+	if cpus == 0 {
+		cpus = 1
+	}
+	return cpuDelta / systemDelta * float64(cpus) * 100
+}
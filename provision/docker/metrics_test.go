@@ -0,0 +1,100 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"github.com/fsouza/go-dockerclient"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestProvisionerUnitsMetrics(c *check.C) {
+	app := provisiontest.NewFakeApp("myapp", "python", 1)
+	cont, err := s.newContainer(&newContainerOpts{
+		AppName:     app.GetName(),
+		ProcessName: "web",
+		Status:      provision.StatusStarted.String(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	s.server.PrepareStats(cont.ID, func(string) docker.Stats {
+		var stats docker.Stats
+		stats.PreCPUStats.CPUUsage.TotalUsage = 1000
+		stats.PreCPUStats.SystemCPUUsage = 10000
+		stats.CPUStats.CPUUsage.TotalUsage = 2000
+		stats.CPUStats.CPUUsage.PercpuUsage = []uint64{1000, 1000}
+		stats.CPUStats.SystemCPUUsage = 20000
+		stats.MemoryStats.Usage = 300
+		stats.MemoryStats.Stats.Cache = 100
+		stats.Networks = map[string]docker.NetworkStats{
+			"eth0": {RxBytes: 10, TxBytes: 20},
+			"eth1": {RxBytes: 1, TxBytes: 2},
+		}
+		return stats
+	})
+	metrics, err := s.p.UnitsMetrics(app)
+	c.Assert(err, check.IsNil)
+	c.Assert(metrics, check.DeepEquals, []provision.UnitMetrics{
+		{ID: cont.ID, Process: "web", CPU: 20, Memory: 200, NetRx: 11, NetTx: 22},
+	})
+}
+
+func (s *S) TestProvisionerUnitsMetricsNoContainers(c *check.C) {
+	app := provisiontest.NewFakeApp("myapp", "python", 1)
+	metrics, err := s.p.UnitsMetrics(app)
+	c.Assert(err, check.IsNil)
+	c.Assert(metrics, check.HasLen, 0)
+}
+
+func (s *S) TestCPUPercentWithoutPreviousSample(c *check.C) {
+	var stats docker.Stats
+	stats.CPUStats.CPUUsage.TotalUsage = 2000
+	stats.CPUStats.SystemCPUUsage = 20000
+	c.Assert(cpuPercent(&stats), check.Equals, 10.0)
+	c.Assert(cpuPercent(&docker.Stats{}), check.Equals, 0.0)
+}
+
+func (s *S) TestProvisionerNodeMetrics(c *check.C) {
+	cont, err := s.newContainer(&newContainerOpts{
+		AppName:     "myapp",
+		ProcessName: "web",
+		Status:      provision.StatusStarted.String(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	s.server.PrepareStats(cont.ID, func(string) docker.Stats {
+		var stats docker.Stats
+		stats.PreCPUStats.CPUUsage.TotalUsage = 1000
+		stats.PreCPUStats.SystemCPUUsage = 10000
+		stats.CPUStats.CPUUsage.TotalUsage = 2000
+		stats.CPUStats.CPUUsage.PercpuUsage = []uint64{1000, 1000}
+		stats.CPUStats.SystemCPUUsage = 20000
+		stats.MemoryStats.Usage = 300
+		stats.MemoryStats.Stats.Cache = 100
+		return stats
+	})
+	nodes, err := s.p.ListNodes(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(nodes, check.HasLen, 1)
+	metrics, err := s.p.NodeMetrics(nodes[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(metrics.CPU, check.Equals, 20.0)
+	c.Assert(metrics.MemoryUsed, check.Equals, uint64(200))
+	c.Assert(metrics.Containers, check.Equals, 1)
+}
+
+func (s *S) TestDriverDiskUsage(c *check.C) {
+	used, total := driverDiskUsage([][2]string{
+		{"Pool Name", "docker-pool"},
+		{"Data Space Used", "1.5 GB"},
+		{"Data Space Total", "107.4 GB"},
+	})
+	c.Assert(used, check.Equals, uint64(1500000000))
+	c.Assert(total, check.Equals, uint64(107400000000))
+	used, total = driverDiskUsage([][2]string{{"Backing Filesystem", "extfs"}})
+	c.Assert(used, check.Equals, uint64(0))
+	c.Assert(total, check.Equals, uint64(0))
+}
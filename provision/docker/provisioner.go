@@ -33,6 +33,7 @@ import (
 	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"github.com/tsuru/tsuru/event"
 	tsuruHealer "github.com/tsuru/tsuru/healer"
+	tsuruIo "github.com/tsuru/tsuru/io"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/provision"
@@ -161,6 +162,35 @@ func (p *dockerProvisioner) initDockerCluster() error {
 		shutdown.Register(contHealerInst)
 		go contHealerInst.RunContainerHealer()
 	}
+	if healUnits, _ := config.GetBool("docker:healing:unit-healer"); healUnits {
+		unitHealerInst := healer.NewUnitHealer(healer.UnitHealerArgs{
+			Provisioner: p,
+			Done:        make(chan bool),
+			Locker:      &appLocker{},
+		})
+		shutdown.Register(unitHealerInst)
+		go unitHealerInst.RunUnitHealer()
+	}
+	healthcheckInterval, _ := config.GetInt("docker:healthcheck:interval")
+	if healthcheckInterval > 0 {
+		monitor := newHealthcheckMonitor(p, time.Duration(healthcheckInterval)*time.Second)
+		shutdown.Register(monitor)
+		monitor.start()
+	}
+	diskThreshold, _ := config.GetFloat("docker:disk-pressure:threshold")
+	if diskThreshold > 0 {
+		diskInterval, _ := config.GetInt("docker:disk-pressure:interval")
+		imageGC, _ := config.GetBool("docker:disk-pressure:image-gc")
+		monitor := newDiskPressureMonitor(p, diskThreshold, time.Duration(diskInterval)*time.Second, imageGC)
+		shutdown.Register(monitor)
+		monitor.start()
+	}
+	unitEvents, _ := config.GetBool("docker:unit-events:enabled")
+	if unitEvents {
+		monitor := newUnitEventsMonitor(p)
+		shutdown.Register(monitor)
+		monitor.start()
+	}
 	activeMonitoring, _ := config.GetInt("docker:healing:active-monitoring-interval")
 	if activeMonitoring > 0 {
 		p.cluster.StartActiveMonitoring(time.Duration(activeMonitoring) * time.Second)
@@ -336,6 +366,68 @@ func (p *dockerProvisioner) Restart(a provision.App, process string, w io.Writer
 	return err
 }
 
+// appContainer returns the container of the app with the given id or name.
+func (p *dockerProvisioner) appContainer(a provision.App, unit string) (*container.Container, error) {
+	cont, err := p.GetContainer(unit)
+	if _, ok := err.(*provision.UnitNotFoundError); ok {
+		cont, err = p.GetContainerByName(unit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cont.AppName != a.GetName() {
+		return nil, &provision.UnitNotFoundError{ID: unit}
+	}
+	return cont, nil
+}
+
+func (p *dockerProvisioner) RestartUnit(a provision.App, unit string, w io.Writer) error {
+	cont, err := p.appContainer(a, unit)
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		w = ioutil.Discard
+	}
+	fmt.Fprintf(w, "---- Restarting unit %s ----\n", cont.ShortID())
+	err = cont.Stop(p)
+	if err != nil {
+		return err
+	}
+	err = cont.Start(&container.StartArgs{
+		Provisioner: p,
+		App:         a,
+	})
+	if err != nil {
+		return err
+	}
+	cont.SetStatus(p, provision.StatusStarting, true)
+	if info, infoErr := cont.NetworkInfo(p); infoErr == nil {
+		p.fixContainer(cont, info)
+	}
+	return nil
+}
+
+func (p *dockerProvisioner) ReplaceUnit(a provision.App, unit string, w io.Writer) error {
+	cont, err := p.appContainer(a, unit)
+	if err != nil {
+		return err
+	}
+	imageId, err := image.AppCurrentImageName(a.GetName())
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		w = ioutil.Discard
+	}
+	writer := io.MultiWriter(w, &app.LogWriter{App: a})
+	toAdd := map[string]*containersToAdd{
+		cont.ProcessName: {Quantity: 1, Status: provision.StatusStarted},
+	}
+	_, err = p.runReplaceUnitsPipeline(writer, a, toAdd, []container.Container{*cont}, imageId)
+	return err
+}
+
 func (p *dockerProvisioner) Start(app provision.App, process string) error {
 	containers, err := p.listContainersByProcess(app.GetName(), process)
 	if err != nil {
@@ -412,6 +504,7 @@ func (p *dockerProvisioner) ImageDeploy(app provision.App, imageId string, evt *
 		imageId = fmt.Sprintf("%s:latest", imageId)
 	}
 	w := evt
+	tsuruIo.WritePhase(w, tsuruIo.PhaseBuild)
 	fmt.Fprintln(w, "---- Pulling image to tsuru ----")
 	pullOpts := docker.PullImageOptions{
 		Repository:        imageId,
@@ -432,7 +525,8 @@ func (p *dockerProvisioner) ImageDeploy(app provision.App, imageId string, evt *
 	}
 	fmt.Fprintln(w, "---- Getting process from image ----")
 	cmd := "cat /home/application/current/Procfile || cat /app/user/Procfile || cat /Procfile"
-	output, _ := p.runCommandInContainer(imageId, cmd, app)
+	var output bytes.Buffer
+	p.runCommandInContainer(imageId, app, nil, &output, nil, cmd)
 	procfile := image.GetProcessesFromProcfile(output.String())
 	imageInspect, err := cluster.InspectImage(imageId)
 	if err != nil {
@@ -465,6 +559,7 @@ func (p *dockerProvisioner) ImageDeploy(app provision.App, imageId string, evt *
 	if err != nil {
 		return "", err
 	}
+	tsuruIo.WritePhase(w, tsuruIo.PhaseDistribute)
 	fmt.Fprintln(w, "---- Pushing image to tsuru ----")
 	pushOpts := docker.PushImageOptions{
 		Name:              strings.Join(imageInfo[:len(imageInfo)-1], ":"),
@@ -511,6 +606,9 @@ func (p *dockerProvisioner) UploadDeploy(app provision.App, archiveFile io.ReadC
 		user, _ = config.GetString("docker:ssh:user")
 	}
 	defer archiveFile.Close()
+	if evt != nil {
+		tsuruIo.WritePhase(evt, tsuruIo.PhaseUpload)
+	}
 	imageName := image.GetBuildImage(app)
 	options := docker.CreateContainerOptions{
 		Config: &docker.Config{
@@ -625,6 +723,9 @@ func (p *dockerProvisioner) deploy(a provision.App, imageId string, evt *event.E
 	if err != nil {
 		return err
 	}
+	if evt != nil {
+		tsuruIo.WritePhase(evt, tsuruIo.PhaseStart)
+	}
 	if len(containers) == 0 {
 		toAdd := make(map[string]*containersToAdd, len(imageData.Processes))
 		for processName := range imageData.Processes {
@@ -964,17 +1065,35 @@ func (p *dockerProvisioner) ExecuteCommand(stdout, stderr io.Writer, app provisi
 	return nil
 }
 
+func (p *dockerProvisioner) ExecuteCommandInUnits(stdout, stderr io.Writer, app provision.App, units []string, cmd string, args ...string) error {
+	containers := make([]*container.Container, len(units))
+	for i, unit := range units {
+		cont, err := p.appContainer(app, unit)
+		if err != nil {
+			return err
+		}
+		containers[i] = cont
+	}
+	for _, c := range containers {
+		err := c.Exec(p, stdout, stderr, cmd, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *dockerProvisioner) ExecuteCommandIsolated(stdout, stderr io.Writer, app provision.App, cmd string, args ...string) error {
 	imageID, err := image.AppCurrentImageName(app.GetName())
 	if err != nil {
 		return err
 	}
-	output, err := p.runCommandInContainer(imageID, cmd, app)
-	if err != nil {
-		return err
+	host, _ := config.GetString("host")
+	env := []string{fmt.Sprintf("%s=%s", "TSURU_HOST", host)}
+	for _, envData := range app.Envs() {
+		env = append(env, fmt.Sprintf("%s=%s", envData.Name, envData.Value))
 	}
-	stdout.Write(output.Bytes())
-	return nil
+	return p.runCommandInContainer(imageID, app, env, stdout, stderr, cmd, args...)
 }
 
 func (p *dockerProvisioner) AdminCommands() []cmd.Command {
@@ -982,6 +1101,11 @@ func (p *dockerProvisioner) AdminCommands() []cmd.Command {
 		&moveContainerCmd{},
 		&moveContainersCmd{},
 		&rebalanceContainersCmd{},
+		&nodeAddCmd{},
+		&nodeListCmd{},
+		&nodeUpdateCmd{},
+		&nodeRemoveCmd{},
+		&nodeDrainCmd{},
 		&healer.ListHealingHistoryCmd{},
 		&autoScaleRunCmd{},
 		&listAutoScaleHistoryCmd{},
@@ -1164,7 +1288,10 @@ func (p *dockerProvisioner) Shell(opts provision.ShellOptions) error {
 	if err != nil {
 		return err
 	}
-	return c.Shell(p, opts.Conn, opts.Conn, opts.Conn, container.Pty{Width: opts.Width, Height: opts.Height, Term: opts.Term})
+	if c.AppName != opts.App.GetName() {
+		return &provision.UnitNotFoundError{ID: opts.Unit}
+	}
+	return c.Shell(p, opts.Conn, opts.Conn, opts.Conn, container.Pty{Width: opts.Width, Height: opts.Height, Term: opts.Term, Resize: opts.Resize})
 }
 
 func (p *dockerProvisioner) Nodes(app provision.App) ([]cluster.Node, error) {
@@ -1435,6 +1562,25 @@ func (p *dockerProvisioner) RemoveNode(opts provision.RemoveNodeOptions) error {
 	return p.Cluster().Unregister(opts.Address)
 }
 
+// DrainNode disables the node, so no new units are scheduled to it, and moves
+// all its units to other nodes. Unlike RemoveNode, the node is kept
+// registered and may be enabled again with UpdateNode.
+func (p *dockerProvisioner) DrainNode(address string, w io.Writer) error {
+	node, err := p.Cluster().GetNode(address)
+	if err != nil {
+		if err == clusterStorage.ErrNoSuchNode {
+			return provision.ErrNodeNotFound
+		}
+		return err
+	}
+	node.CreationStatus = cluster.NodeCreationStatusDisabled
+	_, err = p.Cluster().UpdateNode(node)
+	if err != nil {
+		return err
+	}
+	return p.rebalanceContainersByHost(net.URLToHost(address), w)
+}
+
 func (p *dockerProvisioner) UpgradeNodeContainer(name string, pool string, writer io.Writer) error {
 	return internalNodeContainer.RecreateNamedContainers(p, writer, name)
 }
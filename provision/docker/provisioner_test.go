@@ -24,6 +24,7 @@ import (
 	"github.com/tsuru/docker-cluster/cluster"
 	"github.com/tsuru/docker-cluster/storage"
 	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/app/bind"
 	"github.com/tsuru/tsuru/app/image"
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/db"
@@ -162,6 +163,94 @@ func (s *S) TestProvisionerRestartStoppedContainer(c *check.C) {
 	c.Assert(dbConts[0].HostPort, check.Equals, expectedPort)
 }
 
+func (s *S) TestProvisionerRestartUnit(c *check.C) {
+	app := provisiontest.NewFakeApp("almah", "static", 1)
+	customData := map[string]interface{}{
+		"processes": map[string]interface{}{
+			"web":    "python web.py",
+			"worker": "python worker.py",
+		},
+	}
+	cont1, err := s.newContainer(&newContainerOpts{
+		AppName:         app.GetName(),
+		ProcessName:     "web",
+		ImageCustomData: customData,
+		Image:           "tsuru/app-" + app.GetName(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont1)
+	cont2, err := s.newContainer(&newContainerOpts{
+		AppName:         app.GetName(),
+		ProcessName:     "worker",
+		ImageCustomData: customData,
+		Image:           "tsuru/app-" + app.GetName(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont2)
+	err = s.p.Stop(app, "")
+	c.Assert(err, check.IsNil)
+	err = s.p.RestartUnit(app, cont1.ID, nil)
+	c.Assert(err, check.IsNil)
+	dockerContainer, err := s.p.Cluster().InspectContainer(cont1.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dockerContainer.State.Running, check.Equals, true)
+	dockerContainer, err = s.p.Cluster().InspectContainer(cont2.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dockerContainer.State.Running, check.Equals, false)
+	dbCont, err := s.p.GetContainer(cont1.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Status, check.Equals, provision.StatusStarting.String())
+}
+
+func (s *S) TestProvisionerRestartUnitNotFound(c *check.C) {
+	app := provisiontest.NewFakeApp("almah", "static", 1)
+	cont, err := s.newContainer(&newContainerOpts{AppName: "otherapp"}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	err = s.p.RestartUnit(app, cont.ID, nil)
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+	err = s.p.RestartUnit(app, "notfound", nil)
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+}
+
+func (s *S) TestProvisionerReplaceUnit(c *check.C) {
+	app := provisiontest.NewFakeApp("almah", "static", 1)
+	customData := map[string]interface{}{
+		"processes": map[string]interface{}{
+			"web":    "python web.py",
+			"worker": "python worker.py",
+		},
+	}
+	cont1, err := s.newContainer(&newContainerOpts{
+		AppName:         app.GetName(),
+		ProcessName:     "web",
+		ImageCustomData: customData,
+		Image:           "tsuru/app-" + app.GetName(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont1)
+	cont2, err := s.newContainer(&newContainerOpts{
+		AppName:         app.GetName(),
+		ProcessName:     "worker",
+		ImageCustomData: customData,
+		Image:           "tsuru/app-" + app.GetName(),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont2)
+	err = s.p.ReplaceUnit(app, cont2.Name, nil)
+	c.Assert(err, check.IsNil)
+	dbConts, err := s.p.listAllContainers()
+	c.Assert(err, check.IsNil)
+	c.Assert(dbConts, check.HasLen, 2)
+	ids := map[string]string{}
+	for _, cont := range dbConts {
+		ids[cont.ProcessName] = cont.ID
+	}
+	c.Assert(ids["web"], check.Equals, cont1.ID)
+	c.Assert(ids["worker"], check.Not(check.Equals), cont2.ID)
+	c.Assert(ids["worker"], check.Not(check.Equals), "")
+}
+
 type containerByProcessList []container.Container
 
 func (l containerByProcessList) Len() int           { return len(l) }
@@ -1975,6 +2064,34 @@ func (s *S) TestProvisionerExecuteCommandOnceNoContainers(c *check.C) {
 	c.Assert(err, check.Equals, provision.ErrEmptyApp)
 }
 
+func (s *S) TestProvisionerExecuteCommandInUnits(c *check.C) {
+	a := provisiontest.NewFakeApp("almah", "static", 2)
+	container1, err := s.newContainer(&newContainerOpts{AppName: a.GetName()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(container1)
+	container2, err := s.newContainer(&newContainerOpts{AppName: a.GetName()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(container2)
+	var executed []string
+	s.server.PrepareExec("*", func() {
+		executed = append(executed, "exec")
+	})
+	var stdout, stderr bytes.Buffer
+	err = s.p.ExecuteCommandInUnits(&stdout, &stderr, a, []string{container2.ID}, "ls", "-l")
+	c.Assert(err, check.IsNil)
+	c.Assert(executed, check.HasLen, 1)
+}
+
+func (s *S) TestProvisionerExecuteCommandInUnitsOtherApp(c *check.C) {
+	a := provisiontest.NewFakeApp("almah", "static", 1)
+	container, err := s.newContainer(&newContainerOpts{AppName: "otherapp"}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(container)
+	var buf bytes.Buffer
+	err = s.p.ExecuteCommandInUnits(&buf, &buf, a, []string{container.ID}, "ls")
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+}
+
 func (s *S) TestProvisionerExecuteCommandIsolated(c *check.C) {
 	err := s.newFakeImage(s.p, "tsuru/app-almah", nil)
 	c.Assert(err, check.IsNil)
@@ -2006,6 +2123,37 @@ func (s *S) TestProvisionerExecuteCommandIsolated(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, "test")
 }
 
+func (s *S) TestProvisionerExecuteCommandIsolatedWithEnvs(c *check.C) {
+	err := s.newFakeImage(s.p, "tsuru/app-almah", nil)
+	c.Assert(err, check.IsNil)
+	a := provisiontest.NewFakeApp("almah", "static", 1)
+	a.SetEnv(bind.EnvVar{Name: "DATABASE_URL", Value: "mysql://db"})
+	config.Set("host", "http://tsuru.io:8080")
+	defer config.Unset("host")
+	var contConfig *docker.Config
+	s.server.CustomHandler("/containers/.*/attach", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Split(r.URL.Path, "/")[2]
+		cont, inspectErr := s.p.Cluster().InspectContainer(id)
+		if inspectErr == nil {
+			contConfig = cont.Config
+		}
+		hijacker := w.(http.Hijacker)
+		w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+		w.WriteHeader(http.StatusOK)
+		conn, _, cErr := hijacker.Hijack()
+		if cErr != nil {
+			return
+		}
+		conn.Close()
+	}))
+	var buf bytes.Buffer
+	err = s.p.ExecuteCommandIsolated(&buf, &buf, a, "./migrate", "--all")
+	c.Assert(err, check.IsNil)
+	c.Assert(contConfig, check.NotNil)
+	c.Assert(contConfig.Cmd, check.DeepEquals, []string{"./migrate", "--all"})
+	c.Assert(contConfig.Env, check.DeepEquals, []string{"TSURU_HOST=http://tsuru.io:8080", "DATABASE_URL=mysql://db"})
+}
+
 func (s *S) TestProvisionerExecuteCommandIsolatedNoImage(c *check.C) {
 	a := provisiontest.NewFakeApp("almah", "static", 2)
 	var buf bytes.Buffer
@@ -2024,6 +2172,11 @@ func (s *S) TestAdminCommands(c *check.C) {
 		&moveContainerCmd{},
 		&moveContainersCmd{},
 		&rebalanceContainersCmd{},
+		&nodeAddCmd{},
+		&nodeListCmd{},
+		&nodeUpdateCmd{},
+		&nodeRemoveCmd{},
+		&nodeDrainCmd{},
 		&healer.ListHealingHistoryCmd{},
 		&autoScaleRunCmd{},
 		&listAutoScaleHistoryCmd{},
@@ -2913,6 +3066,21 @@ func (s *S) TestShellToAnAppByContainerID(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *S) TestShellToAnAppByContainerIDFromAnotherApp(c *check.C) {
+	err := s.newFakeImage(s.p, "tsuru/app-almah", nil)
+	c.Assert(err, check.IsNil)
+	a := provisiontest.NewFakeApp("almah", "static", 1)
+	other := provisiontest.NewFakeApp("other", "static", 1)
+	cont, err := s.newContainer(&newContainerOpts{AppName: other.GetName()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	buf := safe.NewBuffer([]byte("echo test"))
+	conn := &provisiontest.FakeConn{Buf: buf}
+	opts := provision.ShellOptions{App: a, Conn: conn, Width: 10, Height: 10, Unit: cont.ID}
+	err = s.p.Shell(opts)
+	c.Assert(err, check.DeepEquals, &provision.UnitNotFoundError{ID: cont.ID})
+}
+
 func (s *S) TestShellToAnAppByAppName(c *check.C) {
 	err := s.newFakeImage(s.p, "tsuru/app-almah", nil)
 	c.Assert(err, check.IsNil)
@@ -9,6 +9,7 @@ import (
 	"math"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/pkg/errors"
@@ -17,6 +18,7 @@ import (
 	"github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/log"
 	"github.com/tsuru/tsuru/net"
+	"github.com/tsuru/tsuru/prom"
 	"github.com/tsuru/tsuru/provision/docker/container"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -33,30 +35,58 @@ type segregatedScheduler struct {
 	ignoredContainers []string
 }
 
+var (
+	schedulerDecisions = prom.NewCounterVec("tsuru_scheduler_decisions_total", "Number of containers scheduled to docker nodes.", "pool", "node", "result")
+	schedulerDuration  = prom.NewHistogramVec("tsuru_scheduler_duration_seconds", "Time taken to choose a node for a container.", prom.DefBuckets, "result")
+)
+
 func (s *segregatedScheduler) Schedule(c *cluster.Cluster, opts docker.CreateContainerOptions, schedulerOpts cluster.SchedulerOptions) (cluster.Node, error) {
+	start := time.Now()
+	pool, node, err := s.schedule(c, opts, schedulerOpts)
+	result := "success"
+	var host string
+	if err != nil {
+		result = "error"
+	} else {
+		host = net.URLToHost(node.Address)
+	}
+	schedulerDuration.Observe(time.Since(start).Seconds(), result)
+	schedulerDecisions.Inc(pool, host, result)
+	return node, err
+}
+
+func (s *segregatedScheduler) schedule(c *cluster.Cluster, opts docker.CreateContainerOptions, schedulerOpts cluster.SchedulerOptions) (string, cluster.Node, error) {
 	schedOpts, ok := schedulerOpts.(*container.SchedulerOpts)
 	if !ok {
-		return cluster.Node{}, &container.SchedulerError{
+		return "", cluster.Node{}, &container.SchedulerError{
 			Base: errors.Errorf("invalid scheduler opts: %#v", schedulerOpts),
 		}
 	}
 	a, _ := app.GetByName(schedOpts.AppName)
+	var pool string
+	if a != nil {
+		pool = a.Pool
+	}
 	nodes, err := s.provisioner.Nodes(a)
 	if err != nil {
-		return cluster.Node{}, &container.SchedulerError{Base: err}
+		return pool, cluster.Node{}, &container.SchedulerError{Base: err}
+	}
+	nodes, err = filterByDiskPressure(nodes)
+	if err != nil {
+		return pool, cluster.Node{}, &container.SchedulerError{Base: err}
 	}
 	nodes, err = s.filterByMemoryUsage(a, nodes, s.maxMemoryRatio, s.TotalMemoryMetadata)
 	if err != nil {
-		return cluster.Node{}, &container.SchedulerError{Base: err}
+		return pool, cluster.Node{}, &container.SchedulerError{Base: err}
 	}
 	node, err := s.chooseNodeToAdd(nodes, opts.Name, schedOpts.AppName, schedOpts.ProcessName)
 	if err != nil {
-		return cluster.Node{}, &container.SchedulerError{Base: err}
+		return pool, cluster.Node{}, &container.SchedulerError{Base: err}
 	}
 	if schedOpts.ActionLimiter != nil {
 		schedOpts.LimiterDone = schedOpts.ActionLimiter.Start(net.URLToHost(node))
 	}
-	return cluster.Node{Address: node}, nil
+	return pool, cluster.Node{Address: node}, nil
 }
 
 func (s *segregatedScheduler) filterByMemoryUsage(a *app.App, nodes []cluster.Node, maxMemoryRatio float32, TotalMemoryMetadata string) ([]cluster.Node, error) {
@@ -0,0 +1,280 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/permission"
+	"github.com/tsuru/tsuru/provision"
+	"github.com/tsuru/tsuru/provision/docker/container"
+)
+
+const (
+	unitEventsRefreshInterval = time.Minute
+	unitEventsKillWindow      = time.Minute
+)
+
+// unitEventsMonitor listens to the events of all docker nodes, detecting
+// units that crashed or were killed by the kernel for running out of memory.
+// Such units have their status set to error and the termination recorded,
+// and an event is created for each termination.
+type unitEventsMonitor struct {
+	provisioner *dockerProvisioner
+	mu          sync.Mutex
+	listeners   map[string]*nodeEventsListener
+	// pending holds what happened to each container before it died, as
+	// docker reports the oom and kill events before the die event.
+	pending   map[string]*containerEvents
+	pendingMu sync.Mutex
+	quit      chan bool
+	wg        sync.WaitGroup
+}
+
+type containerEvents struct {
+	oom    bool
+	killed time.Time
+}
+
+type nodeEventsListener struct {
+	client *docker.Client
+	events chan *docker.APIEvents
+	done   chan bool
+	closed chan bool
+}
+
+func newUnitEventsMonitor(p *dockerProvisioner) *unitEventsMonitor {
+	return &unitEventsMonitor{
+		provisioner: p,
+		listeners:   make(map[string]*nodeEventsListener),
+		pending:     make(map[string]*containerEvents),
+		quit:        make(chan bool),
+	}
+}
+
+func (m *unitEventsMonitor) start() {
+	m.wg.Add(1)
+	go m.run()
+}
+
+func (m *unitEventsMonitor) run() {
+	defer m.wg.Done()
+	for {
+		err := m.refreshListeners()
+		if err != nil {
+			log.Errorf("[unit events] unable to refresh node listeners: %s", err)
+		}
+		select {
+		case <-m.quit:
+			return
+		case <-time.After(unitEventsRefreshInterval):
+		}
+	}
+}
+
+func (m *unitEventsMonitor) Shutdown() {
+	close(m.quit)
+	m.wg.Wait()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for addr, l := range m.listeners {
+		l.stop()
+		delete(m.listeners, addr)
+	}
+}
+
+func (m *unitEventsMonitor) String() string {
+	return "unit events monitor"
+}
+
+// refreshListeners starts listening to the events of new nodes, and of nodes
+// whose connection was lost, and stops listening to removed nodes.
+func (m *unitEventsMonitor) refreshListeners() error {
+	nodes, err := m.provisioner.Cluster().UnfilteredNodes()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		current[node.Address] = true
+		if l, ok := m.listeners[node.Address]; ok {
+			select {
+			case <-l.closed:
+				l.stop()
+				delete(m.listeners, node.Address)
+			default:
+				continue
+			}
+		}
+		client, err := node.Client()
+		if err != nil {
+			log.Errorf("[unit events] unable to get client for node %s: %s", node.Address, err)
+			continue
+		}
+		l := &nodeEventsListener{
+			client: client,
+			events: make(chan *docker.APIEvents, 100),
+			done:   make(chan bool),
+			closed: make(chan bool),
+		}
+		err = client.AddEventListener(l.events)
+		if err != nil {
+			log.Errorf("[unit events] unable to listen to events of node %s: %s", node.Address, err)
+			continue
+		}
+		m.listeners[node.Address] = l
+		go l.listen(m.handleEvent)
+	}
+	for addr, l := range m.listeners {
+		if !current[addr] {
+			l.stop()
+			delete(m.listeners, addr)
+		}
+	}
+	return nil
+}
+
+func (l *nodeEventsListener) listen(handle func(*docker.APIEvents)) {
+	defer close(l.closed)
+	for {
+		select {
+		case evt, ok := <-l.events:
+			if !ok {
+				return
+			}
+			handle(evt)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// stop removes the listener from the docker client. The events channel is
+// drained while the listener is removed, as the client blocks sending events
+// to it.
+func (l *nodeEventsListener) stop() {
+	close(l.done)
+	<-l.closed
+	removed := make(chan bool)
+	go func() {
+		l.client.RemoveEventListener(l.events)
+		close(removed)
+	}()
+	for {
+		select {
+		case <-l.events:
+		case <-removed:
+			return
+		}
+	}
+}
+
+func (m *unitEventsMonitor) handleEvent(evt *docker.APIEvents) {
+	if evt.Type != "" && evt.Type != "container" {
+		return
+	}
+	action := evt.Action
+	if action == "" {
+		action = evt.Status
+	}
+	id := evt.Actor.ID
+	if id == "" {
+		id = evt.ID
+	}
+	m.pendingMu.Lock()
+	pending := m.pending[id]
+	switch action {
+	case "oom", "kill":
+		if pending == nil {
+			pending = &containerEvents{}
+			m.pending[id] = pending
+		}
+		if action == "oom" {
+			pending.oom = true
+		} else {
+			pending.killed = time.Now()
+		}
+		m.pendingMu.Unlock()
+		return
+	case "die":
+		delete(m.pending, id)
+	case "destroy":
+		delete(m.pending, id)
+		m.pendingMu.Unlock()
+		return
+	default:
+		m.pendingMu.Unlock()
+		return
+	}
+	m.pendingMu.Unlock()
+	termination := provision.UnitTermination{Reason: provision.TerminationCrash}
+	if pending != nil && pending.oom {
+		termination.Reason = provision.TerminationOOM
+	} else if pending != nil && time.Since(pending.killed) < unitEventsKillWindow {
+		// units stopped or removed by tsuru are killed by docker before
+		// dying, which isn't a crash.
+		return
+	}
+	termination.ExitCode, _ = strconv.Atoi(evt.Actor.Attributes["exitCode"])
+	if evt.TimeNano > 0 {
+		termination.Date = time.Unix(0, evt.TimeNano).UTC()
+	} else {
+		termination.Date = time.Unix(evt.Time, 0).UTC()
+	}
+	err := m.recordTermination(id, termination)
+	if err != nil {
+		log.Errorf("[unit events] unable to record termination of container %s: %s", id, err)
+	}
+}
+
+func (m *unitEventsMonitor) recordTermination(id string, termination provision.UnitTermination) error {
+	cont, err := m.provisioner.GetContainer(id)
+	if err != nil {
+		if _, ok := err.(*provision.UnitNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+	if cont.Status == provision.StatusBuilding.String() || cont.Status == provision.StatusStopped.String() {
+		return nil
+	}
+	recorded, err := cont.SetLastTermination(m.provisioner, termination)
+	if err != nil || !recorded {
+		return err
+	}
+	return m.terminationEvent(cont)
+}
+
+func (m *unitEventsMonitor) terminationEvent(cont *container.Container) error {
+	a, err := app.GetByName(cont.AppName)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("unit %s %s", cont.ShortID(), cont.LastTermination)
+	a.Log(msg, "tsuru", cont.ShortID())
+	evt, err := event.NewInternal(&event.Opts{
+		Target:       event.Target{Type: event.TargetTypeContainer, Value: cont.ID},
+		InternalKind: "unit-" + cont.LastTermination.Reason,
+		CustomData:   cont.LastTermination,
+		Allowed: event.Allowed(permission.PermAppReadEvents, append(permission.Contexts(permission.CtxTeam, a.Teams),
+			permission.Context(permission.CtxApp, a.Name),
+			permission.Context(permission.CtxPool, a.Pool),
+		)...),
+	})
+	if err != nil {
+		return err
+	}
+	return evt.Done(errors.New(msg))
+}
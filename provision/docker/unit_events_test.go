@@ -0,0 +1,97 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/event"
+	"github.com/tsuru/tsuru/event/eventtest"
+	"github.com/tsuru/tsuru/provision"
+	"gopkg.in/check.v1"
+)
+
+func containerEvent(action, id string, attributes map[string]string, date time.Time) *docker.APIEvents {
+	return &docker.APIEvents{
+		Type:     "container",
+		Action:   action,
+		Actor:    docker.APIActor{ID: id, Attributes: attributes},
+		Time:     date.Unix(),
+		TimeNano: date.UnixNano(),
+	}
+}
+
+func (s *S) TestUnitEventsMonitorOOM(c *check.C) {
+	err := s.storage.Apps().Insert(app.App{Name: "myapp"})
+	c.Assert(err, check.IsNil)
+	cont, err := s.newContainer(&newContainerOpts{AppName: "myapp", Status: provision.StatusStarted.String()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	m := newUnitEventsMonitor(s.p)
+	date := time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC)
+	m.handleEvent(containerEvent("oom", cont.ID, nil, date))
+	m.handleEvent(containerEvent("die", cont.ID, map[string]string{"exitCode": "137"}, date))
+	dbCont, err := s.p.GetContainer(cont.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Status, check.Equals, provision.StatusError.String())
+	c.Assert(dbCont.LastTermination, check.NotNil)
+	c.Assert(*dbCont.LastTermination, check.DeepEquals, provision.UnitTermination{
+		Reason:   provision.TerminationOOM,
+		ExitCode: 137,
+		Date:     date,
+	})
+	c.Assert(eventtest.EventDesc{
+		Target:       event.Target{Type: event.TargetTypeContainer, Value: cont.ID},
+		Kind:         "unit-oom",
+		ErrorMatches: `unit .* killed by OOM`,
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestUnitEventsMonitorCrash(c *check.C) {
+	err := s.storage.Apps().Insert(app.App{Name: "myapp"})
+	c.Assert(err, check.IsNil)
+	cont, err := s.newContainer(&newContainerOpts{AppName: "myapp", Status: provision.StatusStarted.String()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	m := newUnitEventsMonitor(s.p)
+	date := time.Date(2016, 10, 1, 10, 0, 0, 0, time.UTC)
+	m.handleEvent(containerEvent("die", cont.ID, map[string]string{"exitCode": "1"}, date))
+	m.handleEvent(containerEvent("die", cont.ID, map[string]string{"exitCode": "1"}, date))
+	dbCont, err := s.p.GetContainer(cont.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Status, check.Equals, provision.StatusError.String())
+	c.Assert(dbCont.LastTermination.Reason, check.Equals, provision.TerminationCrash)
+	c.Assert(dbCont.LastTermination.ExitCode, check.Equals, 1)
+	n, err := s.storage.Events().Find(nil).Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 1)
+	c.Assert(eventtest.EventDesc{
+		Target:       event.Target{Type: event.TargetTypeContainer, Value: cont.ID},
+		Kind:         "unit-crash",
+		ErrorMatches: `unit .* crashed with exit code 1`,
+	}, eventtest.HasEvent)
+}
+
+func (s *S) TestUnitEventsMonitorIgnoresKilledUnits(c *check.C) {
+	cont, err := s.newContainer(&newContainerOpts{AppName: "myapp", Status: provision.StatusStarted.String()}, nil)
+	c.Assert(err, check.IsNil)
+	defer s.removeTestContainer(cont)
+	m := newUnitEventsMonitor(s.p)
+	m.handleEvent(containerEvent("kill", cont.ID, map[string]string{"signal": "15"}, time.Now()))
+	m.handleEvent(containerEvent("die", cont.ID, map[string]string{"exitCode": "143"}, time.Now()))
+	dbCont, err := s.p.GetContainer(cont.ID)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbCont.Status, check.Equals, provision.StatusStarted.String())
+	c.Assert(dbCont.LastTermination, check.IsNil)
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
+
+func (s *S) TestUnitEventsMonitorIgnoresUnknownContainers(c *check.C) {
+	m := newUnitEventsMonitor(s.p)
+	m.handleEvent(containerEvent("die", "unknown", map[string]string{"exitCode": "1"}, time.Now()))
+	c.Assert(eventtest.EventDesc{IsEmpty: true}, eventtest.HasEvent)
+}
@@ -29,6 +29,7 @@ var (
 )
 
 type NodeContainerList struct {
+	cmd.OutputCommand
 	fs        *gnuflag.FlagSet
 	namesOnly bool
 }
@@ -36,7 +37,7 @@ type NodeContainerList struct {
 func (c *NodeContainerList) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "node-container-list",
-		Usage: "node-container-list",
+		Usage: "node-container-list [-q] [--json] [--format template]",
 		Desc:  "List all existing node containers.",
 	}
 }
@@ -59,6 +60,9 @@ func (c *NodeContainerList) Run(context *cmd.Context, client *cmd.Client) error
 	if err != nil {
 		return err
 	}
+	if rendered, err := c.Render(context.Stdout, all); rendered {
+		return err
+	}
 	if c.namesOnly {
 		for _, entry := range all {
 			fmt.Fprintln(context.Stdout, entry.Name)
@@ -96,6 +100,7 @@ func (c *NodeContainerList) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
 		c.fs = gnuflag.NewFlagSet("flags", gnuflag.ExitOnError)
 		c.fs.BoolVar(&c.namesOnly, "q", false, "Show only names of existing node containers.")
+		c.fs = cmd.MergeFlagSet(c.fs, c.OutputCommand.Flags())
 	}
 	return c.fs
 }
@@ -91,6 +91,20 @@ func (s *S) TestNodeContainerListRun(c *check.C) {
 `)
 }
 
+func (s *S) TestNodeContainerListRunFormat(c *check.C) {
+	var buf bytes.Buffer
+	context := cmd.Context{Args: []string{}, Stdout: &buf}
+	body := `[{"name": "big-sibling", "configpools": {"": {"config": {"image": "img1"}}}}, {"name": "c2"}]`
+	trans := &cmdtest.Transport{Message: body, Status: http.StatusOK}
+	manager := cmd.Manager{}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, &manager)
+	command := NodeContainerList{}
+	command.Flags().Parse(true, []string{"--format", "{{.Name}}"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "big-sibling\nc2\n")
+}
+
 func (s *S) TestNodeContainerAddRun(c *check.C) {
 	var buf bytes.Buffer
 	context := cmd.Context{Args: []string{"n1"}, Stdout: &buf}
@@ -56,6 +56,16 @@ func (e ProvisionerNotSupported) Error() string {
 	return fmt.Sprintf("provisioner %q does not support %s", e.Prov.GetName(), e.Action)
 }
 
+// ExitError is returned by ExecutableProvisioner when the command exits with
+// a non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
 // Status represents the status of a unit in tsuru.
 type Status string
 
@@ -144,6 +154,43 @@ type Unit struct {
 	Ip          string
 	Status      Status
 	Address     *url.URL
+	// LastTermination is the last unexpected termination of the unit, if
+	// any.
+	LastTermination *UnitTermination `json:",omitempty"`
+	// Healthchecks holds the results of the last health checks of the
+	// unit, from the oldest to the newest.
+	Healthchecks []UnitHealthcheck `json:",omitempty"`
+}
+
+const (
+	TerminationCrash = "crash"
+	TerminationOOM   = "oom"
+)
+
+// UnitTermination describes an unexpected termination of a unit, either
+// because its process crashed or because it was killed by the kernel for
+// running out of memory.
+type UnitTermination struct {
+	Reason   string
+	ExitCode int
+	Date     time.Time
+}
+
+func (t *UnitTermination) String() string {
+	if t.Reason == TerminationOOM {
+		return "killed by OOM"
+	}
+	return fmt.Sprintf("crashed with exit code %d", t.ExitCode)
+}
+
+// UnitHealthcheck is the result of a health check request sent to a unit.
+// StatusCode is zero when the unit didn't respond, and Error describes why
+// the check failed, if it did.
+type UnitHealthcheck struct {
+	Date       time.Time
+	Latency    time.Duration
+	StatusCode int    `json:",omitempty"`
+	Error      string `json:",omitempty"`
 }
 
 // GetName returns the name of the unit.
@@ -191,6 +238,11 @@ type Named interface {
 type RunArgs struct {
 	Once     bool
 	Isolated bool
+	// Units limits the command to the units with the given ids or names.
+	Units []string
+	// UnitsPercentage limits the command to a random sample with this
+	// percentage of the units, at least one.
+	UnitsPercentage int
 }
 
 // App represents a tsuru app.
@@ -269,6 +321,15 @@ type ShellOptions struct {
 	Height int
 	Unit   string
 	Term   string
+	// Resize receives the new size of the terminal of the client whenever
+	// it changes. It may be nil.
+	Resize <-chan TerminalSize
+}
+
+// TerminalSize is the size of a terminal, in columns and rows.
+type TerminalSize struct {
+	Width  int
+	Height int
 }
 
 // ArchiveDeployer is a provisioner that can deploy archives.
@@ -353,6 +414,48 @@ type MetricsProvisioner interface {
 	MetricEnvs(App) map[string]string
 }
 
+// UnitMetrics is a sample of the resources used by a unit. CPU is the
+// percentage of one CPU used since the previous sample, Memory is the memory
+// usage in bytes and NetRx and NetTx are the total bytes received and sent by
+// the unit. DiskUsed and DiskTotal are the usage and size, in bytes, of the
+// filesystems of the unit, left empty when the provisioner can't sample them.
+type UnitMetrics struct {
+	ID        string
+	Process   string
+	CPU       float64
+	Memory    uint64
+	NetRx     uint64
+	NetTx     uint64
+	DiskUsed  uint64
+	DiskTotal uint64
+}
+
+// UnitMetricsProvisioner is a provisioner able to sample the resources used
+// by the units of an app.
+type UnitMetricsProvisioner interface {
+	UnitsMetrics(App) ([]UnitMetrics, error)
+}
+
+// NodeMetrics is the resource usage of a node. CPU is the sum of the usage
+// of the containers in the node, as a percentage of one CPU, and memory and
+// disk are in bytes. Fields the provisioner can't sample are left empty.
+type NodeMetrics struct {
+	CPUs              int
+	CPU               float64
+	MemoryTotal       uint64
+	MemoryUsed        uint64
+	DiskTotal         uint64
+	DiskUsed          uint64
+	Containers        int
+	ContainersRunning int
+}
+
+// NodeMetricsProvisioner is a provisioner able to sample the resources used
+// in its nodes.
+type NodeMetricsProvisioner interface {
+	NodeMetrics(Node) (*NodeMetrics, error)
+}
+
 // ShellProvisioner is a provisioner that allows opening a shell to existing
 // units.
 type ShellProvisioner interface {
@@ -369,10 +472,31 @@ type ExecutableProvisioner interface {
 	// ExecuteCommandOnce runs a command in one unit of the app.
 	ExecuteCommandOnce(stdout, stderr io.Writer, app App, cmd string, args ...string) error
 
-	// ExecuteCommandIsolated runs a command in an new and ephemeral container.
+	// ExecuteCommandIsolated runs a command in an new and ephemeral container,
+	// created from the current image of the app with its environment
+	// variables.
 	ExecuteCommandIsolated(stdout, stderr io.Writer, app App, cmd string, args ...string) error
 }
 
+// UnitExecutableProvisioner is a provisioner that allows running commands in
+// some of the units of an app.
+type UnitExecutableProvisioner interface {
+	// ExecuteCommandInUnits runs a command in the units of the app with the
+	// given ids or names.
+	ExecuteCommandInUnits(stdout, stderr io.Writer, app App, units []string, cmd string, args ...string) error
+}
+
+// UnitRestarterProvisioner is a provisioner that allows restarting or
+// replacing a single unit of an app, identified by its id or name.
+type UnitRestarterProvisioner interface {
+	// RestartUnit restarts the unit, keeping it in the same node.
+	RestartUnit(app App, unit string, w io.Writer) error
+
+	// ReplaceUnit removes the unit, adding a new one for the same process in
+	// its place.
+	ReplaceUnit(app App, unit string, w io.Writer) error
+}
+
 // SleepableProvisioner is a provisioner that allows putting applications to
 // sleep.
 type SleepableProvisioner interface {
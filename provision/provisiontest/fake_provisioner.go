@@ -32,7 +32,8 @@ var (
 	errNotProvisioned       = &provision.Error{Reason: "App is not provisioned."}
 	uniqueIpCounter   int32 = 0
 
-	_ provision.NodeProvisioner = &FakeProvisioner{}
+	_ provision.NodeProvisioner          = &FakeProvisioner{}
+	_ provision.UnitRestarterProvisioner = &FakeProvisioner{}
 )
 
 func init() {
@@ -356,9 +357,10 @@ func (app *FakeApp) GetTeamOwner() string {
 }
 
 type Cmd struct {
-	Cmd  string
-	Args []string
-	App  provision.App
+	Cmd   string
+	Args  []string
+	App   provision.App
+	Units []string
 }
 
 type failure struct {
@@ -409,6 +411,7 @@ type FakeNode struct {
 	p          *FakeProvisioner
 	failures   int
 	hasSuccess bool
+	metrics    *provision.NodeMetrics
 }
 
 func (n *FakeNode) Pool() string {
@@ -561,6 +564,72 @@ func (p *FakeProvisioner) MetricEnvs(app provision.App) map[string]string {
 	}
 }
 
+// SetUnitsMetrics sets the metrics returned by UnitsMetrics for the app.
+func (p *FakeProvisioner) SetUnitsMetrics(app provision.App, metrics []provision.UnitMetrics) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return errNotProvisioned
+	}
+	pApp.metrics = metrics
+	p.apps[app.GetName()] = pApp
+	return nil
+}
+
+// UnitsMetrics returns the metrics set with SetUnitsMetrics, or empty metrics
+// for each unit of the app.
+func (p *FakeProvisioner) UnitsMetrics(app provision.App) ([]provision.UnitMetrics, error) {
+	if err := p.getError("UnitsMetrics"); err != nil {
+		return nil, err
+	}
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return nil, errNotProvisioned
+	}
+	if pApp.metrics != nil {
+		return pApp.metrics, nil
+	}
+	metrics := make([]provision.UnitMetrics, len(pApp.units))
+	for i, u := range pApp.units {
+		metrics[i] = provision.UnitMetrics{ID: u.ID, Process: u.ProcessName}
+	}
+	return metrics, nil
+}
+
+// SetNodeMetrics sets the metrics returned by NodeMetrics for the node.
+func (p *FakeProvisioner) SetNodeMetrics(address string, metrics provision.NodeMetrics) error {
+	n, ok := p.nodes[address]
+	if !ok {
+		return provision.ErrNodeNotFound
+	}
+	n.metrics = &metrics
+	p.nodes[address] = n
+	return nil
+}
+
+// NodeMetrics returns the metrics set with SetNodeMetrics, or metrics with
+// only the number of units in the node.
+func (p *FakeProvisioner) NodeMetrics(node provision.Node) (*provision.NodeMetrics, error) {
+	if err := p.getError("NodeMetrics"); err != nil {
+		return nil, err
+	}
+	n, ok := p.nodes[node.Address()]
+	if !ok {
+		return nil, provision.ErrNodeNotFound
+	}
+	if n.metrics != nil {
+		return n.metrics, nil
+	}
+	units, err := n.Units()
+	if err != nil {
+		return nil, err
+	}
+	return &provision.NodeMetrics{Containers: len(units), ContainersRunning: len(units)}, nil
+}
+
 // Restarts returns the number of restarts for a given app.
 func (p *FakeProvisioner) Restarts(a provision.App, process string) int {
 	p.mut.RLock()
@@ -568,6 +637,13 @@ func (p *FakeProvisioner) Restarts(a provision.App, process string) int {
 	return p.apps[a.GetName()].restarts[process]
 }
 
+// UnitRestarts returns the number of restarts of the unit with the given id.
+func (p *FakeProvisioner) UnitRestarts(a provision.App, unitID string) int {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.apps[a.GetName()].unitRestart[unitID]
+}
+
 // Starts returns the number of starts for a given app.
 func (p *FakeProvisioner) Starts(app provision.App, process string) int {
 	p.mut.RLock()
@@ -943,6 +1019,82 @@ func (p *FakeProvisioner) AddUnitsToNode(app provision.App, n uint, process stri
 	return result, nil
 }
 
+func findFakeUnit(units []provision.Unit, unit string) int {
+	for i, u := range units {
+		if u.ID == unit || (u.Name != "" && u.Name == unit) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *FakeProvisioner) RestartUnit(app provision.App, unit string, w io.Writer) error {
+	if err := p.getError("RestartUnit"); err != nil {
+		return err
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return errNotProvisioned
+	}
+	i := findFakeUnit(pApp.units, unit)
+	if i < 0 {
+		return &provision.UnitNotFoundError{ID: unit}
+	}
+	if pApp.unitRestart == nil {
+		pApp.unitRestart = make(map[string]int)
+	}
+	pApp.unitRestart[pApp.units[i].ID]++
+	pApp.units[i].Status = provision.StatusStarted
+	p.apps[app.GetName()] = pApp
+	if w != nil {
+		fmt.Fprintf(w, "restarting unit %s", pApp.units[i].ID)
+	}
+	return nil
+}
+
+func (p *FakeProvisioner) ReplaceUnit(app provision.App, unit string, w io.Writer) error {
+	if err := p.getError("ReplaceUnit"); err != nil {
+		return err
+	}
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	pApp, ok := p.apps[app.GetName()]
+	if !ok {
+		return errNotProvisioned
+	}
+	i := findFakeUnit(pApp.units, unit)
+	if i < 0 {
+		return &provision.UnitNotFoundError{ID: unit}
+	}
+	old := pApp.units[i]
+	err := routertest.FakeRouter.RemoveRoute(app.GetName(), old.Address)
+	if err != nil {
+		return err
+	}
+	val := atomic.AddInt32(&uniqueIpCounter, 1)
+	newUnit := old
+	newUnit.ID = fmt.Sprintf("%s-%d", app.GetName(), pApp.unitLen)
+	newUnit.Name = ""
+	newUnit.Status = provision.StatusStarted
+	newUnit.Address = &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s:%d", old.Ip, val),
+	}
+	err = routertest.FakeRouter.AddRoute(app.GetName(), newUnit.Address)
+	if err != nil {
+		return err
+	}
+	pApp.units[i] = newUnit
+	pApp.unitLen++
+	p.apps[app.GetName()] = pApp
+	if w != nil {
+		fmt.Fprintf(w, "replacing unit %s", old.ID)
+	}
+	return nil
+}
+
 func (p *FakeProvisioner) RemoveUnits(app provision.App, n uint, process string, w io.Writer) error {
 	if err := p.getError("RemoveUnits"); err != nil {
 		return err
@@ -1069,6 +1221,43 @@ func (p *FakeProvisioner) ExecuteCommandOnce(stdout, stderr io.Writer, app provi
 	return nil
 }
 
+// ExecuteCommandInUnits runs the command in the given units, which must exist
+// in the app, writing one of the prepared outputs for each unit.
+func (p *FakeProvisioner) ExecuteCommandInUnits(stdout, stderr io.Writer, app provision.App, units []string, cmd string, args ...string) error {
+	if err := p.getError("ExecuteCommandInUnits"); err != nil {
+		return err
+	}
+	p.mut.RLock()
+	pApp, ok := p.apps[app.GetName()]
+	p.mut.RUnlock()
+	if !ok {
+		return errNotProvisioned
+	}
+	for _, unit := range units {
+		if findFakeUnit(pApp.units, unit) < 0 {
+			return &provision.UnitNotFoundError{ID: unit}
+		}
+	}
+	command := Cmd{
+		Cmd:   cmd,
+		Args:  args,
+		App:   app,
+		Units: units,
+	}
+	p.cmdMut.Lock()
+	p.cmds = append(p.cmds, command)
+	p.cmdMut.Unlock()
+	for range units {
+		select {
+		case output := <-p.outputs:
+			stdout.Write(output)
+		case <-time.After(2e9):
+			return errors.New("FakeProvisioner timed out waiting for output.")
+		}
+	}
+	return nil
+}
+
 func (p *FakeProvisioner) ExecuteCommandIsolated(stdout, stderr io.Writer, app provision.App, cmd string, args ...string) error {
 	var output []byte
 	command := Cmd{
@@ -1414,6 +1603,7 @@ type provisionedApp struct {
 	units       []provision.Unit
 	app         provision.App
 	restarts    map[string]int
+	unitRestart map[string]int
 	starts      map[string]int
 	stops       map[string]int
 	sleeps      map[string]int
@@ -1422,6 +1612,7 @@ type provisionedApp struct {
 	cnames      []string
 	unitLen     int
 	lastData    map[string]interface{}
+	metrics     []provision.UnitMetrics
 	image       string
 }
 
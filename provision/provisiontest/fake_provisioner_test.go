@@ -1028,6 +1028,24 @@ func (s *S) TestExecuteCommandOnce(c *check.C) {
 	c.Assert(buf.String(), check.Equals, string(output))
 }
 
+func (s *S) TestExecuteCommandInUnits(c *check.C) {
+	var buf bytes.Buffer
+	app := NewFakeApp("grand-designs", "rush", 1)
+	p := NewFakeProvisioner()
+	p.Provision(app)
+	p.AddUnits(app, 3, "web", nil)
+	p.PrepareOutput([]byte("first"))
+	p.PrepareOutput([]byte("second"))
+	err := p.ExecuteCommandInUnits(&buf, nil, app, []string{"grand-designs-0", "grand-designs-2"}, "ls", "-l")
+	c.Assert(err, check.IsNil)
+	cmds := p.GetCmds("ls", app)
+	c.Assert(cmds, check.HasLen, 1)
+	c.Assert(cmds[0].Units, check.DeepEquals, []string{"grand-designs-0", "grand-designs-2"})
+	c.Assert(buf.String(), check.Equals, "firstsecond")
+	err = p.ExecuteCommandInUnits(&buf, nil, app, []string{"grand-designs-9"}, "ls")
+	c.Assert(err, check.FitsTypeOf, &provision.UnitNotFoundError{})
+}
+
 func (s *S) TestExtensiblePlatformAdd(c *check.C) {
 	p := ExtensibleFakeProvisioner{FakeProvisioner: NewFakeProvisioner()}
 	args := map[string]string{"dockerfile": "mydockerfile.txt"}
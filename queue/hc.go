@@ -0,0 +1,25 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import "github.com/tsuru/tsuru/hc"
+
+func init() {
+	hc.AddChecker("Queue", queueHealthCheck)
+	hc.AddChecker("Redis pubsub", pubSubHealthCheck)
+}
+
+func queueHealthCheck() error {
+	_, err := Queue()
+	return err
+}
+
+func pubSubHealthCheck() error {
+	conn, err := factoryInstance.getConn()
+	if err != nil {
+		return err
+	}
+	return conn.Ping().Err()
+}
@@ -73,3 +73,8 @@ func (s *S) TestQueue(c *check.C) {
 	c.Assert(result, check.Equals, "result")
 	c.Assert(shutdown.All(), check.HasLen, 1)
 }
+
+func (s *S) TestPubSubHealthCheck(c *check.C) {
+	err := pubSubHealthCheck()
+	c.Assert(err, check.IsNil)
+}
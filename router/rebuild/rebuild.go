@@ -19,6 +19,7 @@ type RebuildApp interface {
 	GetRouterOpts() map[string]string
 	GetName() string
 	GetCname() []string
+	GetRouter() (string, error)
 	Router() (router.Router, error)
 	RoutableUnits() ([]*url.URL, error)
 	UpdateAddr() error
@@ -27,6 +28,15 @@ type RebuildApp interface {
 }
 
 func RebuildRoutes(app RebuildApp) (*RebuildRoutesResult, error) {
+	result, err := rebuildRoutes(app)
+	if err != nil {
+		routerName, _ := app.GetRouter()
+		router.ObserveFailure(routerName, "rebuild")
+	}
+	return result, err
+}
+
+func rebuildRoutes(app RebuildApp) (*RebuildRoutesResult, error) {
 	r, err := app.Router()
 	if err != nil {
 		return nil, err
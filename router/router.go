@@ -17,6 +17,7 @@ import (
 	"github.com/tsuru/tsuru/db"
 	"github.com/tsuru/tsuru/db/storage"
 	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/prom"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -38,6 +39,14 @@ const HttpScheme = "http"
 
 var routers = make(map[string]routerFactory)
 
+var operationFailures = prom.NewCounterVec("tsuru_router_operation_failures_total", "Number of failed operations in routers.", "router", "operation")
+
+// ObserveFailure counts a failed operation in the given router, exposing it
+// in the tsuru_router_operation_failures_total metric.
+func ObserveFailure(routerName, operation string) {
+	operationFailures.Inc(routerName, operation)
+}
+
 // Register registers a new router.
 func Register(name string, r routerFactory) {
 	routers[name] = r
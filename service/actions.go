@@ -29,7 +29,7 @@ var createServiceInstance = action.Action{
 		if !ok {
 			return nil, errors.New("First parameter must be a Service.")
 		}
-		endpoint, err := service.getClient("production")
+		endpoint, err := service.getServiceClient("production")
 		if err != nil {
 			return nil, err
 		}
@@ -56,7 +56,7 @@ var createServiceInstance = action.Action{
 		if !ok {
 			return
 		}
-		endpoint, err := service.getClient("production")
+		endpoint, err := service.getServiceClient("production")
 		if err != nil {
 			return
 		}
@@ -151,7 +151,7 @@ var bindAppEndpointAction = &action.Action{
 		if args == nil {
 			return nil, errors.New("invalid arguments for pipeline, expected *bindPipelineArgs")
 		}
-		endpoint, err := args.serviceInstance.Service().getClient("production")
+		endpoint, err := args.serviceInstance.Service().getServiceClient("production")
 		if err != nil {
 			return nil, err
 		}
@@ -159,7 +159,7 @@ var bindAppEndpointAction = &action.Action{
 	},
 	Backward: func(ctx action.BWContext) {
 		args, _ := ctx.Params[0].(*bindPipelineArgs)
-		endpoint, err := args.serviceInstance.Service().getClient("production")
+		endpoint, err := args.serviceInstance.Service().getServiceClient("production")
 		if err != nil {
 			log.Errorf("[bind-app-endpoint backward] could not get endpoint: %s", err)
 			return
@@ -335,7 +335,7 @@ var unbindAppEndpoint = action.Action{
 		if args == nil {
 			return nil, errors.New("invalid arguments for pipeline, expected *bindPipelineArgs")
 		}
-		if endpoint, err := args.serviceInstance.Service().getClient("production"); err == nil {
+		if endpoint, err := args.serviceInstance.Service().getServiceClient("production"); err == nil {
 			err := endpoint.UnbindApp(args.serviceInstance, args.app)
 			if err != nil && err != ErrInstanceNotFoundInAPI {
 				return nil, err
@@ -345,7 +345,7 @@ var unbindAppEndpoint = action.Action{
 	},
 	Backward: func(ctx action.BWContext) {
 		args, _ := ctx.Params[0].(*bindPipelineArgs)
-		if endpoint, err := args.serviceInstance.Service().getClient("production"); err == nil {
+		if endpoint, err := args.serviceInstance.Service().getServiceClient("production"); err == nil {
 			_, err := endpoint.BindApp(args.serviceInstance, args.app)
 			if err != nil {
 				log.Errorf("[unbind-app-endpoint backward] failed to rebind app in endpoint: %s", err)
@@ -0,0 +1,261 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"github.com/tsuru/tsuru/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	ErrBrokerAlreadyExists = errors.New("service broker already exists")
+	ErrBrokerNotFound      = errors.New("service broker not found")
+	ErrBrokerHasInstances  = errors.New("service broker has service instances, remove them before removing the broker")
+
+	brokerNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+)
+
+// Broker is an external service broker implementing the Open Service Broker
+// API. Every service in the catalog of the broker is registered as a tsuru
+// service, owned by the team of the broker.
+type Broker struct {
+	Name          string          `bson:"_id" json:"name"`
+	URL           string          `json:"url"`
+	Username      string          `json:"username"`
+	Password      string          `json:"-"`
+	SigningSecret string          `bson:"signing_secret,omitempty" json:"-"`
+	Team          string          `json:"team"`
+	Catalog       []BrokerService `json:"catalog"`
+}
+
+// BrokerService is a service offered by a broker, as described in its
+// catalog.
+type BrokerService struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Bindable    bool         `json:"bindable"`
+	Plans       []BrokerPlan `json:"plans"`
+}
+
+// BrokerPlan is a plan of a service offered by a broker.
+type BrokerPlan struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (b *Broker) validate() error {
+	if !brokerNameRegexp.MatchString(b.Name) {
+		return &tsuruErrors.ValidationError{Message: "invalid broker name, it must start with a letter and contain only lowercase letters, numbers and dashes"}
+	}
+	if b.URL == "" {
+		return &tsuruErrors.ValidationError{Message: "broker url is required"}
+	}
+	if b.Team == "" {
+		return &tsuruErrors.ValidationError{Message: "broker team is required"}
+	}
+	return nil
+}
+
+func (b *Broker) findService(name string) (BrokerService, bool) {
+	for _, s := range b.Catalog {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return BrokerService{}, false
+}
+
+// fetchCatalog loads the catalog of services from the broker API.
+func (b *Broker) fetchCatalog() error {
+	cli := &brokerClient{broker: b}
+	resp, err := cli.do("GET", "/v2/catalog", nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get the catalog of broker %q", b.Name)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrapf(brokerError(resp), "unable to get the catalog of broker %q", b.Name)
+	}
+	var catalog struct {
+		Services []BrokerService `json:"services"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&catalog)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse the catalog of broker %q", b.Name)
+	}
+	b.Catalog = catalog.Services
+	return nil
+}
+
+// syncServices registers the services in the catalog of the broker as tsuru
+// services. Services removed from the catalog are kept, as they may still
+// have instances.
+func (b *Broker) syncServices() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for _, bs := range b.Catalog {
+		var existing Service
+		err = conn.Services().FindId(bs.Name).One(&existing)
+		if err == mgo.ErrNotFound {
+			s := Service{
+				Name:       bs.Name,
+				Broker:     b.Name,
+				Endpoint:   map[string]string{"production": b.URL},
+				OwnerTeams: []string{b.Team},
+				Doc:        bs.Description,
+			}
+			err = conn.Services().Insert(s)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Broker != b.Name {
+			log.Errorf("[service broker] ignoring service %q of broker %q: a service with the same name already exists", bs.Name, b.Name)
+			continue
+		}
+		err = conn.Services().UpdateId(bs.Name, bson.M{"$set": bson.M{
+			"endpoint": map[string]string{"production": b.URL},
+			"doc":      bs.Description,
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddBroker registers a new service broker, importing the services in its
+// catalog.
+func AddBroker(b *Broker) error {
+	err := b.validate()
+	if err != nil {
+		return err
+	}
+	err = b.fetchCatalog()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.ServiceBrokers().Insert(b)
+	if mgo.IsDup(err) {
+		return ErrBrokerAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	return b.syncServices()
+}
+
+// UpdateBroker updates the address and credentials of a service broker and
+// reloads its catalog.
+func UpdateBroker(b *Broker) error {
+	err := b.validate()
+	if err != nil {
+		return err
+	}
+	err = b.fetchCatalog()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.ServiceBrokers().UpdateId(b.Name, b)
+	if err == mgo.ErrNotFound {
+		return ErrBrokerNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return b.syncServices()
+}
+
+// GetBroker returns the service broker with the given name.
+func GetBroker(name string) (*Broker, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var b Broker
+	err = conn.ServiceBrokers().FindId(name).One(&b)
+	if err == mgo.ErrNotFound {
+		return nil, ErrBrokerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListBrokers returns all registered service brokers.
+func ListBrokers() ([]Broker, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var brokers []Broker
+	err = conn.ServiceBrokers().Find(nil).Sort("_id").All(&brokers)
+	if err != nil {
+		return nil, err
+	}
+	return brokers, nil
+}
+
+// RemoveBroker removes a service broker and the services imported from it.
+// Brokers whose services still have instances can't be removed.
+func RemoveBroker(name string) error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var services []Service
+	err = conn.Services().Find(bson.M{"broker": name}).All(&services)
+	if err != nil {
+		return err
+	}
+	names := GetServicesNames(services)
+	n, err := conn.ServiceInstances().Find(bson.M{"service_name": bson.M{"$in": names}}).Count()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrBrokerHasInstances
+	}
+	err = conn.ServiceBrokers().RemoveId(name)
+	if err == mgo.ErrNotFound {
+		return ErrBrokerNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = conn.Services().RemoveAll(bson.M{"broker": name})
+	return err
+}
@@ -0,0 +1,306 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/tsuru/app/bind"
+	"github.com/tsuru/tsuru/log"
+	"github.com/tsuru/tsuru/net"
+)
+
+const (
+	brokerAPIVersion = "2.13"
+
+	// statusUnprocessableEntity is returned by brokers when a binding is
+	// requested before the instance is ready. It's not defined in net/http
+	// before Go 1.7.
+	statusUnprocessableEntity = 422
+)
+
+var invalidEnvCharsRegexp = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// brokerClient talks to service brokers implementing the Open Service Broker
+// API. Brokers have no concept of units, so binding and unbinding units are
+// no-ops.
+type brokerClient struct {
+	broker  *Broker
+	service BrokerService
+}
+
+func brokerError(resp *http.Response) error {
+	var data struct {
+		Error       string `json:"error"`
+		Description string `json:"description"`
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if json.Unmarshal(body, &data) == nil && data.Description != "" {
+		return errors.Errorf("invalid response: %s", data.Description)
+	}
+	return errors.Errorf("invalid response: %s", string(body))
+}
+
+func (c *brokerClient) do(method, path string, params url.Values, data interface{}) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := strings.TrimRight(c.broker.URL, "/") + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Broker-API-Version", brokerAPIVersion)
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.broker.Username != "" {
+		req.SetBasicAuth(c.broker.Username, c.broker.Password)
+	}
+	req.Close = true
+	return net.Dial5Full300ClientNoKeepAlive.Do(req)
+}
+
+func (c *brokerClient) instanceID(instance *ServiceInstance) string {
+	return instance.ServiceName + "-" + instance.GetIdentifier()
+}
+
+func (c *brokerClient) bindingID(instance *ServiceInstance, app bind.App) string {
+	return c.instanceID(instance) + "-" + app.GetName()
+}
+
+func (c *brokerClient) planID(instance *ServiceInstance) (string, error) {
+	if len(c.service.Plans) == 0 {
+		return "", errors.Errorf("service %q has no plans", c.service.Name)
+	}
+	if instance.PlanName == "" {
+		return c.service.Plans[0].ID, nil
+	}
+	for _, p := range c.service.Plans {
+		if p.Name == instance.PlanName {
+			return p.ID, nil
+		}
+	}
+	return "", errors.Errorf("plan %q not found in service %q", instance.PlanName, c.service.Name)
+}
+
+func (c *brokerClient) params(instance *ServiceInstance) (url.Values, error) {
+	planID, err := c.planID(instance)
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"service_id": {c.service.ID}, "plan_id": {planID}}, nil
+}
+
+func (c *brokerClient) Create(instance *ServiceInstance, user, requestID string) error {
+	planID, err := c.planID(instance)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"service_id":        c.service.ID,
+		"plan_id":           planID,
+		"organization_guid": instance.TeamOwner,
+		"space_guid":        instance.TeamOwner,
+		"context": map[string]string{
+			"platform":     "tsuru",
+			"team":         instance.TeamOwner,
+			"user":         user,
+			"instanceName": instance.Name,
+		},
+	}
+	params := url.Values{"accepts_incomplete": {"true"}}
+	resp, err := c.do("PUT", "/v2/service_instances/"+c.instanceID(instance), params, data)
+	if err != nil {
+		return log.WrapError(errors.Wrapf(err, "Failed to create the instance %s", instance.Name))
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return nil
+	case http.StatusConflict:
+		return ErrInstanceAlreadyExistsInAPI
+	}
+	return log.WrapError(errors.Wrapf(brokerError(resp), "Failed to create the instance %s", instance.Name))
+}
+
+func (c *brokerClient) Destroy(instance *ServiceInstance, requestID string) error {
+	params, err := c.params(instance)
+	if err != nil {
+		return err
+	}
+	params.Set("accepts_incomplete", "true")
+	resp, err := c.do("DELETE", "/v2/service_instances/"+c.instanceID(instance), params, nil)
+	if err != nil {
+		return log.WrapError(errors.Wrapf(err, "Failed to destroy the instance %s", instance.Name))
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusGone:
+		return ErrInstanceNotFoundInAPI
+	}
+	return log.WrapError(errors.Wrapf(brokerError(resp), "Failed to destroy the instance %s", instance.Name))
+}
+
+// BindApp creates a binding in the broker, returning its credentials as
+// environment variables. Credential names are converted to upper case and
+// values that aren't strings are encoded as JSON.
+func (c *brokerClient) BindApp(instance *ServiceInstance, app bind.App) (map[string]string, error) {
+	planID, err := c.planID(instance)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"service_id": c.service.ID,
+		"plan_id":    planID,
+		"app_guid":   app.GetName(),
+		"bind_resource": map[string]string{
+			"app_guid": app.GetName(),
+		},
+	}
+	path := "/v2/service_instances/" + c.instanceID(instance) + "/service_bindings/" + c.bindingID(instance, app)
+	resp, err := c.do("PUT", path, nil, data)
+	if err != nil {
+		return nil, log.WrapError(errors.Wrapf(err, `Failed to bind app %q to service instance "%s/%s"`, app.GetName(), instance.ServiceName, instance.Name))
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var result struct {
+			Credentials map[string]interface{} `json:"credentials"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			return nil, err
+		}
+		return credentialsToEnvs(result.Credentials)
+	case statusUnprocessableEntity:
+		return nil, ErrInstanceNotReady
+	case http.StatusNotFound, http.StatusGone:
+		return nil, ErrInstanceNotFoundInAPI
+	}
+	err = errors.Wrapf(brokerError(resp), `Failed to bind the instance "%s/%s" to the app %q`, instance.ServiceName, instance.Name, app.GetName())
+	return nil, log.WrapError(err)
+}
+
+func credentialsToEnvs(credentials map[string]interface{}) (map[string]string, error) {
+	envs := make(map[string]string, len(credentials))
+	for k, v := range credentials {
+		name := invalidEnvCharsRegexp.ReplaceAllString(strings.ToUpper(k), "_")
+		if str, ok := v.(string); ok {
+			envs[name] = str
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		envs[name] = string(data)
+	}
+	return envs, nil
+}
+
+func (c *brokerClient) BindUnit(instance *ServiceInstance, app bind.App, unit bind.Unit) error {
+	return nil
+}
+
+func (c *brokerClient) UnbindApp(instance *ServiceInstance, app bind.App) error {
+	params, err := c.params(instance)
+	if err != nil {
+		return err
+	}
+	path := "/v2/service_instances/" + c.instanceID(instance) + "/service_bindings/" + c.bindingID(instance, app)
+	resp, err := c.do("DELETE", path, params, nil)
+	if err != nil {
+		return log.WrapError(errors.Wrapf(err, "Failed to unbind (%q)", path))
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusGone:
+		return ErrInstanceNotFoundInAPI
+	}
+	return log.WrapError(errors.Wrapf(brokerError(resp), "Failed to unbind (%q)", path))
+}
+
+func (c *brokerClient) UnbindUnit(instance *ServiceInstance, app bind.App, unit bind.Unit) error {
+	return nil
+}
+
+// Status reports the state of the last asynchronous operation on the
+// instance. Instances provisioned synchronously have no operations, and are
+// reported as up.
+func (c *brokerClient) Status(instance *ServiceInstance, requestID string) (string, error) {
+	params, err := c.params(instance)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do("GET", "/v2/service_instances/"+c.instanceID(instance)+"/last_operation", params, nil)
+	if err != nil {
+		return "", log.WrapError(errors.Wrapf(err, "Failed to get status of instance %s", instance.Name))
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone:
+		return "", ErrInstanceNotFoundInAPI
+	default:
+		return "up", nil
+	}
+	var operation struct {
+		State       string `json:"state"`
+		Description string `json:"description"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&operation)
+	if err != nil {
+		return "", err
+	}
+	switch operation.State {
+	case "in progress":
+		return "pending", nil
+	case "failed":
+		if operation.Description != "" {
+			return "down: " + operation.Description, nil
+		}
+		return "down", nil
+	}
+	return "up", nil
+}
+
+func (c *brokerClient) Info(instance *ServiceInstance, requestID string) ([]map[string]string, error) {
+	return nil, nil
+}
+
+func (c *brokerClient) Plans(requestID string) ([]Plan, error) {
+	plans := make([]Plan, len(c.service.Plans))
+	for i, p := range c.service.Plans {
+		plans[i] = Plan{Name: p.Name, Description: p.Description}
+	}
+	return plans, nil
+}
+
+func (c *brokerClient) Proxy(path string, w http.ResponseWriter, r *http.Request) error {
+	return errors.Errorf("service %q is provided by a service broker and does not support proxied requests", c.service.Name)
+}
@@ -0,0 +1,209 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/tsuru/tsuru/provision/provisiontest"
+	"gopkg.in/check.v1"
+)
+
+const fakeCatalog = `{"services": [{
+	"id": "mysql-id",
+	"name": "mysql",
+	"description": "MySQL databases",
+	"bindable": true,
+	"plans": [
+		{"id": "small-id", "name": "small", "description": "Small database"},
+		{"id": "large-id", "name": "large", "description": "Large database"}
+	]
+}]}`
+
+type fakeBroker struct {
+	sync.Mutex
+	requests []*http.Request
+	bodies   []map[string]interface{}
+}
+
+func (b *fakeBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.Lock()
+	defer b.Unlock()
+	var body map[string]interface{}
+	data, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(data, &body)
+	b.requests = append(b.requests, r)
+	b.bodies = append(b.bodies, body)
+	switch {
+	case r.URL.Path == "/v2/catalog":
+		w.Write([]byte(fakeCatalog))
+	case r.Method == "PUT" && r.URL.Path == "/v2/service_instances/mysql-mydb":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	case r.Method == "PUT" && r.URL.Path == "/v2/service_instances/mysql-mydb/service_bindings/mysql-mydb-myapp":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"credentials": {"uri": "mysql://db", "port": 3306, "db-name": "mydb"}}`))
+	case r.URL.Path == "/v2/service_instances/mysql-mydb/last_operation":
+		w.Write([]byte(`{"state": "in progress"}`))
+	case r.Method == "DELETE":
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusGone)
+	}
+}
+
+func (s *S) TestAddBroker(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	b := Broker{Name: "mybroker", URL: ts.URL, Username: "user", Password: "secret", Team: s.team.Name}
+	err := AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	c.Assert(fake.requests, check.HasLen, 1)
+	c.Assert(fake.requests[0].Header.Get("X-Broker-API-Version"), check.Equals, brokerAPIVersion)
+	user, password, _ := fake.requests[0].BasicAuth()
+	c.Assert(user, check.Equals, "user")
+	c.Assert(password, check.Equals, "secret")
+	stored, err := GetBroker("mybroker")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Catalog, check.HasLen, 1)
+	c.Assert(stored.Catalog[0].Plans, check.HasLen, 2)
+	srv := Service{Name: "mysql"}
+	err = srv.Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(srv.Broker, check.Equals, "mybroker")
+	c.Assert(srv.OwnerTeams, check.DeepEquals, []string{s.team.Name})
+	c.Assert(srv.Doc, check.Equals, "MySQL databases")
+	plans, err := GetPlansByServiceName("mysql", "")
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.DeepEquals, []Plan{
+		{Name: "small", Description: "Small database"},
+		{Name: "large", Description: "Large database"},
+	})
+}
+
+func (s *S) TestAddBrokerDuplicated(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	b := Broker{Name: "mybroker", URL: ts.URL, Team: s.team.Name}
+	err := AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	err = AddBroker(&b)
+	c.Assert(err, check.Equals, ErrBrokerAlreadyExists)
+}
+
+func (s *S) TestAddBrokerDoesNotOverrideServices(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	srv := Service{Name: "mysql", Endpoint: map[string]string{"production": "http://mysql.api.com"}}
+	err := srv.Create()
+	c.Assert(err, check.IsNil)
+	b := Broker{Name: "mybroker", URL: ts.URL, Team: s.team.Name}
+	err = AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	err = srv.Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(srv.Broker, check.Equals, "")
+	c.Assert(srv.Endpoint["production"], check.Equals, "http://mysql.api.com")
+}
+
+func (s *S) TestRemoveBroker(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	b := Broker{Name: "mybroker", URL: ts.URL, Team: s.team.Name}
+	err := AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	err = RemoveBroker("mybroker")
+	c.Assert(err, check.IsNil)
+	_, err = GetBroker("mybroker")
+	c.Assert(err, check.Equals, ErrBrokerNotFound)
+	n, err := s.conn.Services().FindId("mysql").Count()
+	c.Assert(err, check.IsNil)
+	c.Assert(n, check.Equals, 0)
+}
+
+func (s *S) TestRemoveBrokerWithInstances(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	b := Broker{Name: "mybroker", URL: ts.URL, Team: s.team.Name}
+	err := AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	err = s.conn.ServiceInstances().Insert(ServiceInstance{Name: "mydb", ServiceName: "mysql"})
+	c.Assert(err, check.IsNil)
+	err = RemoveBroker("mybroker")
+	c.Assert(err, check.Equals, ErrBrokerHasInstances)
+}
+
+func (s *S) TestBrokerClient(c *check.C) {
+	var fake fakeBroker
+	ts := httptest.NewServer(&fake)
+	defer ts.Close()
+	b := Broker{Name: "mybroker", URL: ts.URL, Team: s.team.Name}
+	err := AddBroker(&b)
+	c.Assert(err, check.IsNil)
+	srv := Service{Name: "mysql"}
+	err = srv.Get()
+	c.Assert(err, check.IsNil)
+	cli, err := srv.getServiceClient("production")
+	c.Assert(err, check.IsNil)
+	instance := ServiceInstance{Name: "mydb", ServiceName: "mysql", PlanName: "large", TeamOwner: s.team.Name}
+	err = cli.Create(&instance, s.user.Email, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(fake.bodies[1]["service_id"], check.Equals, "mysql-id")
+	c.Assert(fake.bodies[1]["plan_id"], check.Equals, "large-id")
+	c.Assert(fake.requests[1].URL.Query().Get("accepts_incomplete"), check.Equals, "true")
+	a := provisiontest.NewFakeApp("myapp", "python", 1)
+	envs, err := cli.BindApp(&instance, a)
+	c.Assert(err, check.IsNil)
+	c.Assert(envs, check.DeepEquals, map[string]string{
+		"URI":     "mysql://db",
+		"PORT":    "3306",
+		"DB_NAME": "mydb",
+	})
+	status, err := cli.Status(&instance, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, "pending")
+	err = cli.UnbindApp(&instance, a)
+	c.Assert(err, check.IsNil)
+	err = cli.Destroy(&instance, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(fake.requests, check.HasLen, 6)
+	c.Assert(fake.requests[5].Method, check.Equals, "DELETE")
+	c.Assert(fake.requests[5].URL.Path, check.Equals, "/v2/service_instances/mysql-mydb")
+	c.Assert(fake.requests[5].URL.Query().Get("plan_id"), check.Equals, "large-id")
+}
+
+func (s *S) TestBrokerClientInvalidPlan(c *check.C) {
+	cli := &brokerClient{
+		broker:  &Broker{Name: "mybroker"},
+		service: BrokerService{Name: "mysql", Plans: []BrokerPlan{{ID: "small-id", Name: "small"}}},
+	}
+	instance := ServiceInstance{Name: "mydb", ServiceName: "mysql", PlanName: "huge"}
+	err := cli.Create(&instance, "", "")
+	c.Assert(err, check.ErrorMatches, `plan "huge" not found in service "mysql"`)
+}
+
+func (s *S) TestBrokerClientBindAppNotReady(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(422)
+		w.Write([]byte(`{"error": "ConcurrencyError"}`))
+	}))
+	defer ts.Close()
+	cli := &brokerClient{
+		broker:  &Broker{Name: "mybroker", URL: ts.URL},
+		service: BrokerService{ID: "mysql-id", Name: "mysql", Plans: []BrokerPlan{{ID: "small-id", Name: "small"}}},
+	}
+	instance := ServiceInstance{Name: "mydb", ServiceName: "mysql", PlanName: "small"}
+	_, err := cli.BindApp(&instance, provisiontest.NewFakeApp("myapp", "python", 1))
+	c.Assert(err, check.Equals, ErrInstanceNotReady)
+}
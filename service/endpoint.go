@@ -20,16 +20,39 @@ import (
 	"github.com/tsuru/tsuru/net"
 )
 
+// defaultRequestIDHeader is the header used to send the request ID to
+// service APIs when request-id-header is not set.
+const defaultRequestIDHeader = "X-Request-ID"
+
 var (
 	ErrInstanceAlreadyExistsInAPI = errors.New("instance already exists in the service API")
 	ErrInstanceNotFoundInAPI      = errors.New("instance does not exist in the service API")
 	ErrInstanceNotReady           = errors.New("instance is not ready yet")
 )
 
+// ServiceClient is the interface implemented by clients of service APIs.
+type ServiceClient interface {
+	Create(instance *ServiceInstance, user, requestID string) error
+	Destroy(instance *ServiceInstance, requestID string) error
+	BindApp(instance *ServiceInstance, app bind.App) (map[string]string, error)
+	BindUnit(instance *ServiceInstance, app bind.App, unit bind.Unit) error
+	UnbindApp(instance *ServiceInstance, app bind.App) error
+	UnbindUnit(instance *ServiceInstance, app bind.App, unit bind.Unit) error
+	Status(instance *ServiceInstance, requestID string) (string, error)
+	Info(instance *ServiceInstance, requestID string) ([]map[string]string, error)
+	Plans(requestID string) ([]Plan, error)
+	Proxy(path string, w http.ResponseWriter, r *http.Request) error
+}
+
+// Client is the client of service APIs implementing the tsuru service API
+// protocol.
 type Client struct {
 	endpoint string
 	username string
 	password string
+	// signingSecret signs the requests with HMAC-SHA256. Requests are not
+	// signed when it's empty.
+	signingSecret string
 }
 
 func (c *Client) buildErrorMessage(err error, resp *http.Response) error {
@@ -55,12 +78,13 @@ func (c *Client) issueRequest(path, method string, params map[string][]string) (
 		delete(params, "requestID")
 	}
 	v := url.Values(params)
-	var suffix string
+	var suffix, encoded string
 	var body io.Reader
 	if method == "GET" {
 		suffix = "?" + v.Encode()
 	} else {
-		body = strings.NewReader(v.Encode())
+		encoded = v.Encode()
+		body = strings.NewReader(encoded)
 	}
 	url := strings.TrimRight(c.endpoint, "/") + "/" + strings.Trim(path, "/") + suffix
 	req, err := http.NewRequest(method, url, body)
@@ -70,11 +94,17 @@ func (c *Client) issueRequest(path, method string, params map[string][]string) (
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Accept", "application/json")
-	requestIDHeader, err := config.GetString("request-id-header")
-	if err == nil && requestIDHeader != "" {
+	if requestID != "" {
+		requestIDHeader, _ := config.GetString("request-id-header")
+		if requestIDHeader == "" {
+			requestIDHeader = defaultRequestIDHeader
+		}
 		req.Header.Add(requestIDHeader, requestID)
 	}
 	req.SetBasicAuth(c.username, c.password)
+	if c.signingSecret != "" {
+		net.SignRequest(req, []byte(encoded), c.signingSecret)
+	}
 	req.Close = true
 	return net.Dial5Full300ClientNoKeepAlive.Do(req)
 }
@@ -14,8 +14,10 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/net"
 	"github.com/tsuru/tsuru/provision/provisiontest"
 	"gopkg.in/check.v1"
 )
@@ -95,6 +97,38 @@ func (s *S) TestEndpointCreate(c *check.C) {
 	c.Assert("application/json", check.Equals, h.request.Header.Get("Accept"))
 	c.Assert("Basic dXNlcjphYmNkZQ==", check.Equals, h.request.Header.Get("Authorization"))
 	c.Assert("close", check.Equals, h.request.Header.Get("Connection"))
+	c.Assert(h.request.Header.Get("X-Tsuru-Signature"), check.Equals, "")
+}
+
+func (s *S) TestEndpointSignsRequestsWithSigningSecret(c *check.C) {
+	h := TestHandler{}
+	ts := httptest.NewServer(&h)
+	defer ts.Close()
+	instance := ServiceInstance{Name: "my-redis", ServiceName: "redis", TeamOwner: "theteam"}
+	client := &Client{endpoint: ts.URL, username: "user", password: "abcde", signingSecret: "s3cr3t"}
+	err := client.Create(&instance, "my@user", "")
+	c.Assert(err, check.IsNil)
+	h.Lock()
+	defer h.Unlock()
+	c.Assert(h.request.Header.Get("Authorization"), check.Equals, "Basic dXNlcjphYmNkZQ==")
+	err = net.VerifyRequest(h.request, h.body, "s3cr3t", time.Minute)
+	c.Assert(err, check.IsNil)
+	err = net.VerifyRequest(h.request, h.body, "abcde", time.Minute)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestEndpointSendsRequestIDInDefaultHeader(c *check.C) {
+	config.Unset("request-id-header")
+	h := TestHandler{}
+	ts := httptest.NewServer(&h)
+	defer ts.Close()
+	instance := ServiceInstance{Name: "my-redis", ServiceName: "redis", TeamOwner: "theteam"}
+	client := &Client{endpoint: ts.URL, username: "user", password: "abcde"}
+	err := client.Create(&instance, "my@user", "my-rid")
+	c.Assert(err, check.IsNil)
+	h.Lock()
+	defer h.Unlock()
+	c.Assert(h.request.Header.Get("X-Request-ID"), check.Equals, "my-rid")
 }
 
 func (s *S) TestEndpointCreateEndpointDown(c *check.C) {
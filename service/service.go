@@ -15,14 +15,16 @@ import (
 )
 
 type Service struct {
-	Name         string `bson:"_id"`
-	Username     string
-	Password     string
-	Endpoint     map[string]string
-	OwnerTeams   []string `bson:"owner_teams"`
-	Teams        []string
-	Doc          string
-	IsRestricted bool `bson:"is_restricted"`
+	Name          string `bson:"_id"`
+	Username      string
+	Password      string
+	SigningSecret string `bson:"signing_secret,omitempty" json:"-"`
+	Endpoint      map[string]string
+	OwnerTeams    []string `bson:"owner_teams"`
+	Teams         []string
+	Doc           string
+	IsRestricted  bool   `bson:"is_restricted"`
+	Broker        string `bson:",omitempty"`
 }
 
 var (
@@ -79,13 +81,49 @@ func (s *Service) getClient(endpoint string) (cli *Client, err error) {
 		if p, _ := regexp.MatchString("^https?://", e); !p {
 			e = "http://" + e
 		}
-		cli = &Client{endpoint: e, username: s.GetUsername(), password: s.Password}
+		cli = &Client{endpoint: e, username: s.GetUsername(), password: s.Password, signingSecret: s.SigningSecret}
 	} else {
 		err = errors.New("Unknown endpoint: " + endpoint)
 	}
 	return
 }
 
+// getServiceClient returns the client for the service API, which is a
+// service broker client when the service was imported from a broker.
+func (s *Service) getServiceClient(endpoint string) (ServiceClient, error) {
+	if s.Broker == "" {
+		cli, err := s.getClient(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return cli, nil
+	}
+	b, err := GetBroker(s.Broker)
+	if err != nil {
+		return nil, err
+	}
+	bs, ok := b.findService(s.Name)
+	if !ok {
+		return nil, errors.Errorf("service %q is not in the catalog of broker %q", s.Name, s.Broker)
+	}
+	return &brokerClient{broker: b, service: bs}, nil
+}
+
+// CallbackSecret returns the secret used to verify the signature of the
+// callbacks sent by the service API, which is the signing secret of the broker
+// when the service was imported from one. An empty secret means callbacks are
+// not accepted for the service.
+func (s *Service) CallbackSecret() (string, error) {
+	if s.Broker == "" {
+		return s.SigningSecret, nil
+	}
+	b, err := GetBroker(s.Broker)
+	if err != nil {
+		return "", err
+	}
+	return b.SigningSecret, nil
+}
+
 func (s *Service) GetUsername() string {
 	if s.Username != "" {
 		return s.Username
@@ -179,7 +217,7 @@ type ServiceModel struct {
 // Proxy is a proxy between tsuru and the service.
 // This method allow customized service methods.
 func Proxy(service *Service, path string, w http.ResponseWriter, r *http.Request) error {
-	endpoint, err := service.getClient("production")
+	endpoint, err := service.getServiceClient("production")
 	if err != nil {
 		return err
 	}
@@ -7,8 +7,11 @@ package service
 import (
 	"encoding/json"
 	"io"
+	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tsuru/tsuru/action"
@@ -44,6 +47,11 @@ type ServiceInstance struct {
 	Teams       []string
 	TeamOwner   string
 	Description string
+	CreatedAt   time.Time
+	// LastStatus is the result of the last status check of the instance,
+	// performed at LastStatusAt.
+	LastStatus   string
+	LastStatusAt time.Time
 }
 
 // DeleteInstance deletes the service instance from the database.
@@ -51,7 +59,7 @@ func DeleteInstance(si *ServiceInstance, requestID string) error {
 	if len(si.Apps) > 0 {
 		return ErrServiceInstanceBound
 	}
-	endpoint, err := si.Service().getClient("production")
+	endpoint, err := si.Service().getServiceClient("production")
 	if err == nil {
 		endpoint.Destroy(si, requestID)
 	}
@@ -90,7 +98,7 @@ func (si *ServiceInstance) MarshalJSON() ([]byte, error) {
 }
 
 func (si *ServiceInstance) Info(requestID string) (map[string]string, error) {
-	endpoint, err := si.Service().getClient("production")
+	endpoint, err := si.Service().getServiceClient("production")
 	if err != nil {
 		return nil, errors.New("endpoint does not exists")
 	}
@@ -166,7 +174,7 @@ func (si *ServiceInstance) BindApp(app bind.App, shouldRestart bool, writer io.W
 
 // BindUnit makes the bind between the binder and an unit.
 func (si *ServiceInstance) BindUnit(app bind.App, unit bind.Unit) error {
-	endpoint, err := si.Service().getClient("production")
+	endpoint, err := si.Service().getServiceClient("production")
 	if err != nil {
 		return err
 	}
@@ -217,7 +225,7 @@ func (si *ServiceInstance) UnbindApp(app bind.App, shouldRestart bool, writer io
 
 // UnbindUnit makes the unbind between the service instance and an unit.
 func (si *ServiceInstance) UnbindUnit(app bind.App, unit bind.Unit) error {
-	endpoint, err := si.Service().getClient("production")
+	endpoint, err := si.Service().getServiceClient("production")
 	if err != nil {
 		return err
 	}
@@ -247,11 +255,37 @@ func (si *ServiceInstance) UnbindUnit(app bind.App, unit bind.Unit) error {
 
 // Status returns the service instance status.
 func (si *ServiceInstance) Status(requestID string) (string, error) {
-	endpoint, err := si.Service().getClient("production")
+	endpoint, err := si.Service().getServiceClient("production")
 	if err != nil {
 		return "", err
 	}
-	return endpoint.Status(si, requestID)
+	status, err := endpoint.Status(si, requestID)
+	if err != nil {
+		return "", err
+	}
+	err = si.SetStatus(status)
+	if err != nil {
+		log.Errorf("[service instance status] unable to store status of %q: %s", si.Name, err)
+	}
+	return status, nil
+}
+
+// SetStatus stores the status of the instance, either checked by tsuru or
+// reported by the service API in a callback.
+func (si *ServiceInstance) SetStatus(status string) error {
+	si.LastStatus = status
+	si.LastStatusAt = time.Now().UTC()
+	return si.update(bson.M{"$set": bson.M{"laststatus": si.LastStatus, "laststatusat": si.LastStatusAt}})
+}
+
+// ProxyDashboard proxies the request to the dashboard of the instance, served
+// by the service API under /resources/<instance>/dashboard.
+func (si *ServiceInstance) ProxyDashboard(path string, w http.ResponseWriter, r *http.Request) error {
+	endpoint, err := si.Service().getServiceClient("production")
+	if err != nil {
+		return err
+	}
+	return endpoint.Proxy("/resources/"+si.GetIdentifier()+"/dashboard/"+strings.TrimLeft(path, "/"), w, r)
 }
 
 func (si *ServiceInstance) Grant(teamName string) error {
@@ -315,6 +349,7 @@ func CreateServiceInstance(instance ServiceInstance, service *Service, user *aut
 		return ErrTeamMandatory
 	}
 	instance.Teams = []string{instance.TeamOwner}
+	instance.CreatedAt = time.Now().UTC()
 	actions := []*action.Action{&createServiceInstance, &insertServiceInstance}
 	pipeline := action.NewPipeline(actions...)
 	return pipeline.Execute(*service, instance, user.Email, requestID)
@@ -369,6 +369,7 @@ func (s *InstanceSuite) TestCreateServiceInstance(c *check.C) {
 	c.Assert(si.PlanName, check.Equals, "small")
 	c.Assert(si.TeamOwner, check.Equals, s.team.Name)
 	c.Assert(si.Teams, check.DeepEquals, []string{s.team.Name})
+	c.Assert(si.CreatedAt.IsZero(), check.Equals, false)
 }
 
 func (s *InstanceSuite) TestCreateServiceInstanceWithSameInstanceName(c *check.C) {
@@ -555,6 +556,28 @@ func (s *InstanceSuite) TestStatus(c *check.C) {
 	c.Assert(status, check.Equals, "up")
 }
 
+func (s *InstanceSuite) TestStatusStoresLastStatus(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	srv := Service{Name: "mongodb", Endpoint: map[string]string{"production": ts.URL}}
+	err := s.conn.Services().Insert(&srv)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Services().RemoveId(srv.Name)
+	si := ServiceInstance{Name: "instance", ServiceName: srv.Name}
+	err = s.conn.ServiceInstances().Insert(&si)
+	c.Assert(err, check.IsNil)
+	defer s.conn.ServiceInstances().Remove(bson.M{"name": si.Name})
+	status, err := si.Status("")
+	c.Assert(err, check.IsNil)
+	c.Assert(status, check.Equals, "down")
+	dbInstance, err := GetServiceInstance(srv.Name, si.Name)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbInstance.LastStatus, check.Equals, "down")
+	c.Assert(dbInstance.LastStatusAt.IsZero(), check.Equals, false)
+}
+
 func (s *InstanceSuite) TestGetServiceInstance(c *check.C) {
 	s.conn.ServiceInstances().Insert(
 		ServiceInstance{Name: "mongo-1", ServiceName: "mongodb", Teams: []string{s.team.Name}},
@@ -967,3 +990,24 @@ func (s *InstanceSuite) TestUnbindAppMultipleApps(c *check.C) {
 	sort.Strings(siDB.Apps)
 	c.Assert(siDB.Apps, check.DeepEquals, []string{})
 }
+
+func (s *InstanceSuite) TestProxyDashboard(c *check.C) {
+	var proxyedPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyedPath = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	srv := Service{Name: "mongodb", Endpoint: map[string]string{"production": ts.URL}}
+	err := s.conn.Services().Insert(&srv)
+	c.Assert(err, check.IsNil)
+	defer s.conn.Services().RemoveId(srv.Name)
+	si := ServiceInstance{Name: "mymongo", ServiceName: srv.Name}
+	request, err := http.NewRequest("GET", "/something", nil)
+	c.Assert(err, check.IsNil)
+	recorder := httptest.NewRecorder()
+	err = si.ProxyDashboard("/collections", recorder, request)
+	c.Assert(err, check.IsNil)
+	c.Assert(recorder.Code, check.Equals, http.StatusNoContent)
+	c.Assert(proxyedPath, check.Equals, "/resources/mymongo/dashboard/collections")
+}
@@ -68,12 +68,13 @@ func (s *S) TestGetClient(c *check.C) {
 	endpoints := map[string]string{
 		"production": "http://mysql.api.com",
 	}
-	service := Service{Name: "redis", Password: "abcde", Endpoint: endpoints}
+	service := Service{Name: "redis", Password: "abcde", SigningSecret: "s3cr3t", Endpoint: endpoints}
 	cli, err := service.getClient("production")
 	expected := &Client{
-		endpoint: endpoints["production"],
-		username: "redis",
-		password: "abcde",
+		endpoint:      endpoints["production"],
+		username:      "redis",
+		password:      "abcde",
+		signingSecret: "s3cr3t",
 	}
 	c.Assert(err, check.IsNil)
 	c.Assert(cli, check.DeepEquals, expected)
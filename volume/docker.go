@@ -0,0 +1,53 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package volume
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// DockerBinds returns the binds, in the source:destination:mode format used
+// by docker, of the volumes bound to the app, along with the volume driver
+// required by them. Docker accepts a single volume driver per container, so
+// apps can't mix nfs volumes using different drivers.
+func DockerBinds(app string) ([]string, string, error) {
+	volumes, err := ListByApp(app)
+	if err != nil {
+		return nil, "", err
+	}
+	var binds []string
+	var driver string
+	for _, v := range volumes {
+		plan, err := GetPlan(v.Plan)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "unable to get plan of volume %q", v.Name)
+		}
+		var source string
+		switch plan.Type {
+		case PlanTypeHost:
+			source = path.Join(plan.Path, v.Name)
+		case PlanTypeNFS:
+			if driver != "" && driver != plan.Driver {
+				return nil, "", errors.Errorf("app %q has volumes using different drivers: %q and %q", app, driver, plan.Driver)
+			}
+			driver = plan.Driver
+			source = plan.Server + path.Join(plan.Path, v.Name)
+		}
+		for _, b := range v.Binds {
+			if b.App != app {
+				continue
+			}
+			mode := "rw"
+			if b.ReadOnly {
+				mode = "ro"
+			}
+			binds = append(binds, fmt.Sprintf("%s:%s:%s", source, b.MountPoint, mode))
+		}
+	}
+	return binds, driver, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package volume
+
+import (
+	"testing"
+
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	"github.com/tsuru/tsuru/db/dbtest"
+	"gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct {
+	conn *db.Storage
+}
+
+var _ = check.Suite(&S{})
+
+func (s *S) SetUpSuite(c *check.C) {
+	config.Set("database:url", "127.0.0.1:27017")
+	config.Set("database:name", "tsuru_volume_tests")
+	var err error
+	s.conn, err = db.Conn()
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TearDownSuite(c *check.C) {
+	s.conn.Volumes().Database.DropDatabase()
+	s.conn.Close()
+}
+
+func (s *S) SetUpTest(c *check.C) {
+	err := dbtest.ClearAllCollections(s.conn.Volumes().Database)
+	c.Assert(err, check.IsNil)
+	config.Set("volume-plans", map[interface{}]interface{}{
+		"local": map[interface{}]interface{}{
+			"type": "host",
+			"path": "/var/lib/tsuru/volumes",
+		},
+		"shared": map[interface{}]interface{}{
+			"type":   "nfs",
+			"path":   "/exports",
+			"server": "nfs.example.com",
+		},
+	})
+}
+
+func (s *S) TearDownTest(c *check.C) {
+	config.Unset("volume-plans")
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package volume implements persistent volumes, created by users from plans
+// registered by administrators and bound to apps at a mount point.
+package volume
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/tsuru/config"
+	"github.com/tsuru/tsuru/db"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	PlanTypeHost = "host"
+	PlanTypeNFS  = "nfs"
+
+	defaultNFSDriver = "nfs"
+)
+
+var (
+	ErrVolumeNotFound      = errors.New("volume not found")
+	ErrVolumeAlreadyExists = errors.New("volume already exists")
+	ErrVolumeBound         = errors.New("volume is bound to apps, unbind them before removing it")
+	ErrBindAlreadyExists   = errors.New("volume is already bound to this app at this mount point")
+	ErrBindNotFound        = errors.New("volume is not bound to this app at this mount point")
+	ErrPlanNotFound        = errors.New("volume plan not found")
+
+	volumeNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+)
+
+// Plan is a kind of volume, registered by administrators in the
+// volume-plans:<name> config entry.
+//
+// Volumes of host plans are directories under Path in the docker nodes,
+// while volumes of nfs plans are directories under Path in the NFS Server,
+// mounted through the Driver docker volume plugin.
+type Plan struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Server string `json:"server,omitempty"`
+	Driver string `json:"driver,omitempty"`
+}
+
+func planFromConfig(name string, data interface{}) (Plan, error) {
+	props, _ := data.(map[interface{}]interface{})
+	p := Plan{Name: name}
+	p.Type, _ = props["type"].(string)
+	p.Path, _ = props["path"].(string)
+	p.Server, _ = props["server"].(string)
+	p.Driver, _ = props["driver"].(string)
+	switch p.Type {
+	case PlanTypeHost:
+	case PlanTypeNFS:
+		if p.Server == "" {
+			return p, errors.Errorf("volume plan %q: server is required for nfs plans", name)
+		}
+		if p.Driver == "" {
+			p.Driver = defaultNFSDriver
+		}
+	default:
+		return p, errors.Errorf("volume plan %q: invalid type %q", name, p.Type)
+	}
+	if !path.IsAbs(p.Path) {
+		return p, errors.Errorf("volume plan %q: path must be absolute", name)
+	}
+	return p, nil
+}
+
+// ListPlans returns the volume plans defined in the config file.
+func ListPlans() ([]Plan, error) {
+	data, err := config.Get("volume-plans")
+	if err != nil {
+		return nil, nil
+	}
+	plansConfig, _ := data.(map[interface{}]interface{})
+	var names []string
+	for name := range plansConfig {
+		if n, ok := name.(string); ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	plans := make([]Plan, 0, len(names))
+	for _, name := range names {
+		p, err := planFromConfig(name, plansConfig[name])
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+// GetPlan returns the volume plan with the given name.
+func GetPlan(name string) (*Plan, error) {
+	data, err := config.Get("volume-plans:" + name)
+	if err != nil {
+		return nil, ErrPlanNotFound
+	}
+	p, err := planFromConfig(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Bind is the binding of a volume to an app at a mount point.
+type Bind struct {
+	App        string `json:"app"`
+	MountPoint string `json:"mountPoint"`
+	ReadOnly   bool   `json:"readOnly"`
+}
+
+type Volume struct {
+	Name      string `bson:"_id" json:"name"`
+	Plan      string `json:"plan"`
+	TeamOwner string `json:"teamOwner"`
+	Binds     []Bind `json:"binds"`
+}
+
+// Create validates and stores a new volume.
+func (v *Volume) Create() error {
+	if !volumeNameRegexp.MatchString(v.Name) {
+		return &tsuruErrors.ValidationError{Message: "invalid volume name, it must start with a letter and contain only lowercase letters, numbers and dashes, with at most 40 characters"}
+	}
+	if v.TeamOwner == "" {
+		return &tsuruErrors.ValidationError{Message: "volume team owner is required"}
+	}
+	if _, err := GetPlan(v.Plan); err != nil {
+		if err == ErrPlanNotFound {
+			return &tsuruErrors.ValidationError{Message: fmt.Sprintf("volume plan %q not found", v.Plan)}
+		}
+		return err
+	}
+	v.Binds = nil
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Volumes().Insert(v)
+	if mgo.IsDup(err) {
+		return ErrVolumeAlreadyExists
+	}
+	return err
+}
+
+// Get returns the volume with the given name.
+func Get(name string) (*Volume, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var v Volume
+	err = conn.Volumes().FindId(name).One(&v)
+	if err == mgo.ErrNotFound {
+		return nil, ErrVolumeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// List returns the volumes owned by the given teams, or by any team if teams
+// is nil.
+func List(teams []string) ([]Volume, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var query bson.M
+	if teams != nil {
+		query = bson.M{"teamowner": bson.M{"$in": teams}}
+	}
+	var volumes []Volume
+	err = conn.Volumes().Find(query).Sort("_id").All(&volumes)
+	if err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+// ListByApp returns the volumes bound to the app.
+func ListByApp(app string) ([]Volume, error) {
+	conn, err := db.Conn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var volumes []Volume
+	err = conn.Volumes().Find(bson.M{"binds.app": app}).Sort("_id").All(&volumes)
+	if err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+// Delete removes the volume, which must not be bound to any app. Data in
+// the volume is kept in the storage of the plan.
+func (v *Volume) Delete() error {
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = conn.Volumes().Remove(bson.M{"_id": v.Name, "binds.0": bson.M{"$exists": false}})
+	if err == mgo.ErrNotFound {
+		if _, getErr := Get(v.Name); getErr != nil {
+			return getErr
+		}
+		return ErrVolumeBound
+	}
+	return err
+}
+
+// BindApp binds the volume to the app at the given mount point.
+func (v *Volume) BindApp(app, mountPoint string, readOnly bool) error {
+	if !path.IsAbs(mountPoint) {
+		return &tsuruErrors.ValidationError{Message: "mount point must be an absolute path"}
+	}
+	mountPoint = path.Clean(mountPoint)
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bind := Bind{App: app, MountPoint: mountPoint, ReadOnly: readOnly}
+	query := bson.M{
+		"_id":   v.Name,
+		"binds": bson.M{"$not": bson.M{"$elemMatch": bson.M{"app": app, "mountpoint": mountPoint}}},
+	}
+	err = conn.Volumes().Update(query, bson.M{"$push": bson.M{"binds": bind}})
+	if err == mgo.ErrNotFound {
+		if _, getErr := Get(v.Name); getErr != nil {
+			return getErr
+		}
+		return ErrBindAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	v.Binds = append(v.Binds, bind)
+	return nil
+}
+
+// UnbindApp removes the binding of the volume to the app at the given mount
+// point.
+func (v *Volume) UnbindApp(app, mountPoint string) error {
+	mountPoint = path.Clean(mountPoint)
+	conn, err := db.Conn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	query := bson.M{"_id": v.Name, "binds": bson.M{"$elemMatch": bson.M{"app": app, "mountpoint": mountPoint}}}
+	err = conn.Volumes().Update(query, bson.M{"$pull": bson.M{"binds": bson.M{"app": app, "mountpoint": mountPoint}}})
+	if err == mgo.ErrNotFound {
+		if _, getErr := Get(v.Name); getErr != nil {
+			return getErr
+		}
+		return ErrBindNotFound
+	}
+	if err != nil {
+		return err
+	}
+	for i, b := range v.Binds {
+		if b.App == app && b.MountPoint == mountPoint {
+			v.Binds = append(v.Binds[:i], v.Binds[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2016 tsuru authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package volume
+
+import (
+	"github.com/tsuru/config"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestListPlans(c *check.C) {
+	plans, err := ListPlans()
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.DeepEquals, []Plan{
+		{Name: "local", Type: "host", Path: "/var/lib/tsuru/volumes"},
+		{Name: "shared", Type: "nfs", Path: "/exports", Server: "nfs.example.com", Driver: "nfs"},
+	})
+}
+
+func (s *S) TestListPlansNotConfigured(c *check.C) {
+	config.Unset("volume-plans")
+	plans, err := ListPlans()
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.HasLen, 0)
+}
+
+func (s *S) TestGetPlanInvalid(c *check.C) {
+	config.Set("volume-plans:broken", map[interface{}]interface{}{"type": "nfs", "path": "/exports"})
+	_, err := GetPlan("broken")
+	c.Assert(err, check.ErrorMatches, `volume plan "broken": server is required for nfs plans`)
+	config.Set("volume-plans:broken", map[interface{}]interface{}{"type": "host", "path": "relative"})
+	_, err = GetPlan("broken")
+	c.Assert(err, check.ErrorMatches, `volume plan "broken": path must be absolute`)
+	_, err = GetPlan("unknown")
+	c.Assert(err, check.Equals, ErrPlanNotFound)
+}
+
+func (s *S) TestCreate(c *check.C) {
+	v := Volume{Name: "data", Plan: "local", TeamOwner: "myteam"}
+	err := v.Create()
+	c.Assert(err, check.IsNil)
+	stored, err := Get("data")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored, check.DeepEquals, &Volume{Name: "data", Plan: "local", TeamOwner: "myteam"})
+	err = v.Create()
+	c.Assert(err, check.Equals, ErrVolumeAlreadyExists)
+}
+
+func (s *S) TestCreateValidation(c *check.C) {
+	tests := []Volume{
+		{Name: "Data", Plan: "local", TeamOwner: "myteam"},
+		{Name: "data", Plan: "local"},
+		{Name: "data", Plan: "unknown", TeamOwner: "myteam"},
+	}
+	for _, v := range tests {
+		err := v.Create()
+		_, ok := err.(*tsuruErrors.ValidationError)
+		c.Check(ok, check.Equals, true, check.Commentf("%#v: %v", v, err))
+	}
+}
+
+func (s *S) TestGetNotFound(c *check.C) {
+	_, err := Get("data")
+	c.Assert(err, check.Equals, ErrVolumeNotFound)
+}
+
+func (s *S) TestList(c *check.C) {
+	for _, v := range []Volume{
+		{Name: "v2", Plan: "local", TeamOwner: "team1"},
+		{Name: "v1", Plan: "local", TeamOwner: "team2"},
+	} {
+		err := v.Create()
+		c.Assert(err, check.IsNil)
+	}
+	volumes, err := List(nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(volumes, check.HasLen, 2)
+	c.Assert(volumes[0].Name, check.Equals, "v1")
+	volumes, err = List([]string{"team1"})
+	c.Assert(err, check.IsNil)
+	c.Assert(volumes, check.HasLen, 1)
+	c.Assert(volumes[0].Name, check.Equals, "v2")
+}
+
+func (s *S) TestBindAndUnbindApp(c *check.C) {
+	v := Volume{Name: "data", Plan: "local", TeamOwner: "myteam"}
+	err := v.Create()
+	c.Assert(err, check.IsNil)
+	err = v.BindApp("myapp", "/mnt/data/", false)
+	c.Assert(err, check.IsNil)
+	err = v.BindApp("myapp", "/mnt/data", true)
+	c.Assert(err, check.Equals, ErrBindAlreadyExists)
+	err = v.BindApp("otherapp", "/mnt/data", true)
+	c.Assert(err, check.IsNil)
+	stored, err := Get("data")
+	c.Assert(err, check.IsNil)
+	c.Assert(stored.Binds, check.DeepEquals, []Bind{
+		{App: "myapp", MountPoint: "/mnt/data"},
+		{App: "otherapp", MountPoint: "/mnt/data", ReadOnly: true},
+	})
+	volumes, err := ListByApp("otherapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(volumes, check.HasLen, 1)
+	err = v.Delete()
+	c.Assert(err, check.Equals, ErrVolumeBound)
+	err = v.UnbindApp("otherapp", "/mnt/data")
+	c.Assert(err, check.IsNil)
+	err = v.UnbindApp("otherapp", "/mnt/data")
+	c.Assert(err, check.Equals, ErrBindNotFound)
+	err = v.UnbindApp("myapp", "/mnt/data")
+	c.Assert(err, check.IsNil)
+	err = v.Delete()
+	c.Assert(err, check.IsNil)
+	_, err = Get("data")
+	c.Assert(err, check.Equals, ErrVolumeNotFound)
+}
+
+func (s *S) TestBindAppInvalidMountPoint(c *check.C) {
+	v := Volume{Name: "data", Plan: "local", TeamOwner: "myteam"}
+	err := v.Create()
+	c.Assert(err, check.IsNil)
+	err = v.BindApp("myapp", "mnt", false)
+	c.Assert(err, check.FitsTypeOf, &tsuruErrors.ValidationError{})
+}
+
+func (s *S) TestDeleteNotFound(c *check.C) {
+	v := Volume{Name: "data"}
+	c.Assert(v.Delete(), check.Equals, ErrVolumeNotFound)
+}
+
+func (s *S) TestDockerBinds(c *check.C) {
+	local := Volume{Name: "cache", Plan: "local", TeamOwner: "myteam"}
+	shared := Volume{Name: "uploads", Plan: "shared", TeamOwner: "myteam"}
+	for _, v := range []*Volume{&local, &shared} {
+		err := v.Create()
+		c.Assert(err, check.IsNil)
+	}
+	err := local.BindApp("myapp", "/cache", false)
+	c.Assert(err, check.IsNil)
+	err = shared.BindApp("myapp", "/uploads", true)
+	c.Assert(err, check.IsNil)
+	err = shared.BindApp("otherapp", "/data", false)
+	c.Assert(err, check.IsNil)
+	binds, driver, err := DockerBinds("myapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(binds, check.DeepEquals, []string{
+		"/var/lib/tsuru/volumes/cache:/cache:rw",
+		"nfs.example.com/exports/uploads:/uploads:ro",
+	})
+	c.Assert(driver, check.Equals, "nfs")
+	binds, driver, err = DockerBinds("noapp")
+	c.Assert(err, check.IsNil)
+	c.Assert(binds, check.IsNil)
+	c.Assert(driver, check.Equals, "")
+}
+
+func (s *S) TestDockerBindsMixedDrivers(c *check.C) {
+	config.Set("volume-plans:other", map[interface{}]interface{}{
+		"type":   "nfs",
+		"path":   "/exports",
+		"server": "nfs2.example.com",
+		"driver": "netshare",
+	})
+	v1 := Volume{Name: "v1", Plan: "shared", TeamOwner: "myteam"}
+	v2 := Volume{Name: "v2", Plan: "other", TeamOwner: "myteam"}
+	for _, v := range []*Volume{&v1, &v2} {
+		err := v.Create()
+		c.Assert(err, check.IsNil)
+		err = v.BindApp("myapp", "/"+v.Name, false)
+		c.Assert(err, check.IsNil)
+	}
+	_, _, err := DockerBinds("myapp")
+	c.Assert(err, check.ErrorMatches, `app "myapp" has volumes using different drivers: "nfs" and "netshare"`)
+}